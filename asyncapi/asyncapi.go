@@ -0,0 +1,29 @@
+// Package asyncapi embeds the AsyncAPI specification of the pipeline's NATS
+// channels, so the service can serve the contracts it publishes against.
+package asyncapi
+
+import (
+	_ "embed"
+	"fmt"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is asyncapi.yaml
+//
+//go:embed asyncapi.yaml
+var Spec []byte
+
+// Version returns the spec's info.version, the version of the contracts
+var Version = sync.OnceValues(func() (string, error) {
+	var spec struct {
+		Info struct {
+			Version string `yaml:"version"`
+		} `yaml:"info"`
+	}
+	if err := yaml.Unmarshal(Spec, &spec); err != nil {
+		return "", fmt.Errorf("parsing AsyncAPI spec: %w", err)
+	}
+	return spec.Info.Version, nil
+})
@@ -0,0 +1,106 @@
+package asyncapi
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// channelRefPrefix prefixes references to the spec's channels
+const channelRefPrefix = "#/channels/"
+
+// Channel is a NATS channel of the spec
+type Channel struct {
+	// Name is the channel's key in the spec, e.g. orders/ingest
+	Name string
+	// Address is its subject, with {parameter} placeholders
+	Address string
+	// Core is set for channels on core NATS, which aren't persisted in
+	// JetStream; the spec marks them with x-nats-core
+	Core bool
+}
+
+// Operation is an operation of the spec on one of its channels
+type Operation struct {
+	// ID is the operation's key in the spec, e.g. validateOrder
+	ID string
+	// Action is send or receive
+	Action  string
+	Channel Channel
+}
+
+// parameter matches a {parameter} placeholder of an address
+var parameter = regexp.MustCompile(`\{[^}]+\}`)
+
+// Subject returns the NATS subject of the channel's messages, with a *
+// wildcard standing for each parameter
+func (c Channel) Subject() string {
+	return parameter.ReplaceAllString(c.Address, "*")
+}
+
+// channels parses the channels and operations of Spec once
+var channels = sync.OnceValues(func() (*parsedChannels, error) {
+	var spec struct {
+		Channels map[string]struct {
+			Address string `yaml:"address"`
+			Core    bool   `yaml:"x-nats-core"`
+		} `yaml:"channels"`
+		Operations map[string]struct {
+			Action  string `yaml:"action"`
+			Channel struct {
+				Ref string `yaml:"$ref"`
+			} `yaml:"channel"`
+		} `yaml:"operations"`
+	}
+	if err := yaml.Unmarshal(Spec, &spec); err != nil {
+		return nil, fmt.Errorf("parsing AsyncAPI spec: %w", err)
+	}
+
+	p := &parsedChannels{byName: make(map[string]Channel, len(spec.Channels))}
+	for name, ch := range spec.Channels {
+		c := Channel{Name: name, Address: ch.Address, Core: ch.Core}
+		p.byName[name] = c
+		p.channels = append(p.channels, c)
+	}
+	slices.SortFunc(p.channels, func(a, b Channel) int { return strings.Compare(a.Name, b.Name) })
+	for id, op := range spec.Operations {
+		// Channel names are escaped as JSON pointer tokens
+		name := strings.TrimPrefix(op.Channel.Ref, channelRefPrefix)
+		name = strings.NewReplacer("~1", "/", "~0", "~").Replace(name)
+		ch, ok := p.byName[name]
+		if !ok {
+			return nil, fmt.Errorf("AsyncAPI operation %s refers to unknown channel %s", id, op.Channel.Ref)
+		}
+		p.operations = append(p.operations, Operation{ID: id, Action: op.Action, Channel: ch})
+	}
+	slices.SortFunc(p.operations, func(a, b Operation) int { return strings.Compare(a.ID, b.ID) })
+	return p, nil
+})
+
+type parsedChannels struct {
+	channels   []Channel
+	byName     map[string]Channel
+	operations []Operation
+}
+
+// Channels returns the spec's channels, sorted by name
+func Channels() ([]Channel, error) {
+	p, err := channels()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(p.channels), nil
+}
+
+// Operations returns the spec's operations, sorted by ID
+func Operations() ([]Operation, error) {
+	p, err := channels()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(p.operations), nil
+}
@@ -0,0 +1,129 @@
+package asyncapi
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// schemaRefPrefix prefixes references to the spec's component schemas
+const schemaRefPrefix = "#/components/schemas/"
+
+// schemas parses the component schemas of Spec once
+var schemas = sync.OnceValues(func() (map[string]any, error) {
+	var spec struct {
+		Components struct {
+			Schemas map[string]any `yaml:"schemas"`
+		} `yaml:"components"`
+	}
+	if err := yaml.Unmarshal(Spec, &spec); err != nil {
+		return nil, fmt.Errorf("parsing AsyncAPI spec: %w", err)
+	}
+	return spec.Components.Schemas, nil
+})
+
+// Sample returns an example payload of the named component schema, e.g.
+// OrderRoutedPayload. Every property is filled in: examples and the first
+// enum value are used where given, UUIDs are random and timestamps are the
+// current time.
+func Sample(schema string) (map[string]any, error) {
+	all, err := schemas()
+	if err != nil {
+		return nil, err
+	}
+	def, ok := all[schema]
+	if !ok {
+		return nil, fmt.Errorf("unknown AsyncAPI schema %s", schema)
+	}
+	sample, err := sampleValue(all, def, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("sampling %s: %w", schema, err)
+	}
+	obj, ok := sample.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("AsyncAPI schema %s is not an object", schema)
+	}
+	return obj, nil
+}
+
+func sampleValue(all map[string]any, def any, now time.Time) (any, error) {
+	schema, ok := def.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid schema %v", def)
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		name, found := strings.CutPrefix(ref, schemaRefPrefix)
+		target, known := all[name]
+		if !found || !known {
+			return nil, fmt.Errorf("unresolvable reference %s", ref)
+		}
+		return sampleValue(all, target, now)
+	}
+	if example, ok := schema["example"]; ok {
+		return example, nil
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0], nil
+	}
+	if allOf, ok := schema["allOf"].([]any); ok {
+		merged := make(map[string]any)
+		for _, part := range allOf {
+			v, err := sampleValue(all, part, now)
+			if err != nil {
+				return nil, err
+			}
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("allOf combines a non-object schema")
+			}
+			for k, pv := range obj {
+				merged[k] = pv
+			}
+		}
+		return merged, nil
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj := make(map[string]any)
+		props, _ := schema["properties"].(map[string]any)
+		for name, prop := range props {
+			v, err := sampleValue(all, prop, now)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			obj[name] = v
+		}
+		return obj, nil
+	case "array":
+		item, err := sampleValue(all, schema["items"], now)
+		if err != nil {
+			return nil, err
+		}
+		return []any{item}, nil
+	case "string":
+		switch schema["format"] {
+		case "uuid":
+			return uuid.NewString(), nil
+		case "date-time":
+			return now.Format(time.RFC3339), nil
+		case "uri":
+			return "https://example.com", nil
+		}
+		return "string", nil
+	case "integer", "number":
+		// The minimum keeps values such as quantities valid
+		if minimum, ok := schema["minimum"]; ok {
+			return minimum, nil
+		}
+		return 1, nil
+	case "boolean":
+		return true, nil
+	}
+	return nil, fmt.Errorf("unsupported schema type %v", schema["type"])
+}
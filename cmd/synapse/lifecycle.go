@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// lifecycle stops the service's components in order on shutdown
+type lifecycle struct {
+	steps []shutdownStep
+}
+
+type shutdownStep struct {
+	name string
+	stop func(context.Context) error
+}
+
+// onShutdown registers how to stop a component. Components are stopped in
+// the reverse order of registration, so registering each as it starts stops
+// what depends on another before it.
+func (l *lifecycle) onShutdown(name string, stop func(context.Context) error) {
+	l.steps = append(l.steps, shutdownStep{name: name, stop: stop})
+}
+
+// shutdown stops the components until ctx is done. A component that fails
+// or doesn't stop in time doesn't keep the others from stopping; the errors
+// are returned together.
+func (l *lifecycle) shutdown(ctx context.Context) error {
+	var errs []error
+	for i := len(l.steps) - 1; i >= 0; i-- {
+		step := l.steps[i]
+		start := time.Now()
+		if err := stopBounded(ctx, step.stop); err != nil {
+			slog.Error("shutdown step failed", "step", step.name, "duration", time.Since(start), "error", err)
+			errs = append(errs, fmt.Errorf("stopping %s: %w", step.name, err))
+			continue
+		}
+		slog.Info("shutdown step done", "step", step.name, "duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// stopBounded runs stop, giving up on it once ctx is done
+func stopBounded(ctx context.Context, stop func(context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- stop(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycle_Shutdown(t *testing.T) {
+	var (
+		lc      lifecycle
+		stopped []string
+	)
+	stopper := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			stopped = append(stopped, name)
+			return err
+		}
+	}
+	lc.onShutdown("infra", stopper("infra", nil))
+	lc.onShutdown("pipeline", stopper("pipeline", errors.New("router busy")))
+	lc.onShutdown("http", stopper("http", nil))
+
+	err := lc.shutdown(context.Background())
+	// Components stop in the reverse order they started, and a failure
+	// doesn't keep the rest from stopping
+	assert.Equal(t, []string{"http", "pipeline", "infra"}, stopped)
+	assert.EqualError(t, err, "stopping pipeline: router busy")
+}
+
+func TestLifecycle_ShutdownDeadline(t *testing.T) {
+	var lc lifecycle
+	lc.onShutdown("http", func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := lc.shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
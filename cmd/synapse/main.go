@@ -0,0 +1,216 @@
+// Command synapse runs the order processing service: the HTTP and gRPC APIs
+// and the event pipeline behind them. "synapse provision" creates or updates
+// the JetStream streams and consumers instead, and exits; "synapse reencrypt"
+// encrypts the order columns under the active key, e.g. after a rotation.
+//
+// On SIGINT or SIGTERM it shuts down gracefully within SHUTDOWN_TIMEOUT_MS:
+// it stops accepting requests and drains the ones in flight, drains the
+// pipeline, then closes its connections to NATS, Postgres and Redis.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/synapse/synapse/internal/buildinfo"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/grpcapi"
+	"github.com/synapse/synapse/internal/handler"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/logging"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/requestid"
+	"github.com/synapse/synapse/internal/service"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exportSweepInterval is how often expired order exports are deleted
+const exportSweepInterval = 10 * time.Minute
+
+func main() {
+	// Replaced by the configured logger once the configuration is loaded
+	slog.SetDefault(slog.New(requestid.NewLogHandler(slog.NewJSONHandler(os.Stderr, nil))))
+	var err error
+	args := os.Args[1:]
+	switch {
+	case len(args) > 0 && args[0] == "provision":
+		err = provision(args[1:], os.Stdout)
+	case len(args) > 0 && args[0] == "reencrypt":
+		err = reencrypt(args[1:], os.Stdout)
+	default:
+		err = run(args)
+	}
+	if err != nil {
+		slog.Error("synapse failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	cfg, err := config.Load(args...)
+	if err != nil {
+		return err
+	}
+	logger, err := logging.New(cfg, os.Stderr)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	build := buildinfo.Get()
+	slog.Info("starting synapse", "version", build.Version, "commit", build.Commit, "build_time", build.BuildTime)
+
+	// A signal stops the servers; the components are then stopped by the
+	// lifecycle, within the shutdown timeout, rather than by ctx
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var lc lifecycle
+	timeout := time.Duration(cfg.ShutdownTimeoutMs) * time.Millisecond
+	shutdown := func(err error) error {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return errors.Join(err, lc.shutdown(shutdownCtx))
+	}
+
+	// Telemetry is stopped last, flushing the spans of the shutdown
+	stopTelemetry, err := infra.SetupTelemetry(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("setting up telemetry: %w", err)
+	}
+	lc.onShutdown("telemetry", stopTelemetry)
+
+	inf, err := infra.New(ctx, cfg)
+	if err != nil {
+		return shutdown(fmt.Errorf("connecting to infrastructure: %w", err))
+	}
+	// Registered before what uses them, the connections are closed once the
+	// servers, the pipeline and the jobs have stopped
+	lc.onShutdown("infra", inf.Close)
+
+	// Singleton background jobs run on the replica leading each of them
+	jobsCtx, stopJobs := context.WithCancel(context.WithoutCancel(ctx))
+	var jobs sync.WaitGroup
+	svc := service.New(inf, nil, inf.Store())
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		inf.Elector("export-retention").Run(jobsCtx, func(ctx context.Context) {
+			svc.PurgeExpiredExports(ctx, exportSweepInterval)
+		})
+	}()
+	lc.onShutdown("leader jobs", func(ctx context.Context) error {
+		stopJobs()
+		jobs.Wait()
+		return nil
+	})
+
+	runner, err := pipeline.New(ctx, cfg, inf)
+	if err != nil {
+		return shutdown(fmt.Errorf("creating pipeline: %w", err))
+	}
+	// The pipeline runs until it is closed, so that it keeps processing
+	// what the draining requests publish
+	errc := make(chan error, 3)
+	go func() {
+		if err := runner.Run(context.WithoutCancel(ctx)); err != nil {
+			errc <- fmt.Errorf("pipeline: %w", err)
+		}
+	}()
+	select {
+	case <-runner.Running():
+	case err := <-errc:
+		return shutdown(err)
+	}
+	// Closing the router waits for the messages being handled
+	lc.onShutdown("pipeline", func(context.Context) error {
+		return runner.Close()
+	})
+
+	h := handler.New(inf, runner)
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+	var httpHandler http.Handler = router
+	if cfg.TracingEnabled {
+		// Spans are named by route pattern, known once chi has routed the
+		// request through the route context given to it
+		httpHandler = otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.NewRouteContext()
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+			router.ServeHTTP(w, r)
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				trace.SpanFromContext(r.Context()).SetName(r.Method + " " + pattern)
+			}
+		}), "http.server")
+	}
+	httpServer := &http.Server{
+		Addr:              net.JoinHostPort("", strconv.Itoa(cfg.HTTPPort)),
+		Handler:           httpHandler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		slog.Info("HTTP server listening", "addr", httpServer.Addr)
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			errc <- fmt.Errorf("HTTP server: %w", err)
+		}
+	}()
+	// Shutdown stops accepting connections and waits for the requests in
+	// flight; event streams that outlast it are cut off
+	lc.onShutdown("http", func(ctx context.Context) error {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			httpServer.Close()
+			return err
+		}
+		return nil
+	})
+
+	if cfg.GRPCPort > 0 {
+		lis, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(cfg.GRPCPort)))
+		if err != nil {
+			return shutdown(fmt.Errorf("listening for gRPC: %w", err))
+		}
+		grpcServer := grpcapi.New(inf, runner)
+		go func() {
+			slog.Info("gRPC server listening", "addr", lis.Addr().String())
+			if err := grpcServer.Serve(lis); err != nil {
+				errc <- fmt.Errorf("gRPC server: %w", err)
+			}
+		}()
+		lc.onShutdown("grpc", func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				grpcServer.Stop()
+				return ctx.Err()
+			}
+		})
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down", "timeout", timeout)
+	case runErr = <-errc:
+		slog.Error("shutting down after a failure", "error", runErr)
+	}
+	// A second signal kills the process rather than waiting for shutdown
+	stop()
+	return shutdown(runErr)
+}
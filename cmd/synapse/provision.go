@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/logging"
+)
+
+// provisionTimeout bounds the provision command
+const provisionTimeout = time.Minute
+
+// provision runs "synapse provision": it creates or updates the JetStream
+// streams and durable consumers the AsyncAPI channels call for, writing each
+// change to out. With --jetstream-provision=check it only reports the
+// drift, failing if there is any.
+func provision(args []string, out io.Writer) error {
+	cfg, err := config.Load(args...)
+	if err != nil {
+		return err
+	}
+	logger, err := logging.New(cfg, os.Stderr)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), provisionTimeout)
+	defer cancel()
+	nc, err := infra.ConnectNATS(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("connecting to NATS: %w", err)
+	}
+	defer nc.Close()
+
+	check := cfg.JetStreamProvision == infra.ProvisionCheck
+	drift, err := infra.ProvisionJetStream(ctx, nc, cfg, !check)
+	for _, d := range drift {
+		fmt.Fprintln(out, d)
+	}
+	if err != nil {
+		return err
+	}
+	if check && len(drift) > 0 {
+		return fmt.Errorf("JetStream has drifted from the AsyncAPI spec in %d places", len(drift))
+	}
+	return nil
+}
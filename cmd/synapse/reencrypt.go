@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/crypto"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/logging"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// reencryptBatch is how many orders are re-encrypted per transaction
+const reencryptBatch = 500
+
+// reencrypt runs "synapse reencrypt": it encrypts the configured order
+// columns under the active key, where they are stored in plaintext or under
+// an older key, writing the number of orders updated to out. Once it is
+// done, keys no longer active can be dropped from STORE_ENCRYPTION_KEYS.
+func reencrypt(args []string, out io.Writer) error {
+	cfg, err := config.Load(args...)
+	if err != nil {
+		return err
+	}
+	logger, err := logging.New(cfg, os.Stderr)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	if cfg.StoreEncryptionKeys == "" {
+		return errors.New("no keys to encrypt with in STORE_ENCRYPTION_KEYS")
+	}
+	keys, err := crypto.ParseKeyring(cfg.StoreEncryptionKeys, cfg.StoreEncryptionKeyID)
+	if err != nil {
+		return fmt.Errorf("configuring store encryption keys: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	pool, db, err := infra.OpenPostgres(ctx, cfg.PostgresDSN(), cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	defer db.Close()
+
+	orders := store.New(db).WithQueryTimeout(time.Duration(cfg.PostgresQueryTimeoutMs)*time.Millisecond).WithEncryption(keys, cfg.StoreEncryptedColumns)
+	total := 0
+	for {
+		n, err := orders.ReencryptOrders(ctx, reencryptBatch)
+		total += n
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+	}
+	fmt.Fprintf(out, "%d orders re-encrypted under key %s\n", total, keys.ActiveKeyID())
+	return nil
+}
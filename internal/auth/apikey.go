@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/synapse/synapse/internal/keyspace"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// APIKeyHeader carries API keys. Keys are also accepted as bearer tokens.
+const APIKeyHeader = "X-API-Key"
+
+const (
+	// keyPrefix marks Synapse API keys, so bearer tokens can be told apart
+	keyPrefix = "syn_"
+	// displayPrefixLen is how much of a key is kept for display
+	displayPrefixLen = len(keyPrefix) + 8
+)
+
+// APIKeyStore persists API keys
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, k *store.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (*store.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]store.APIKey, error)
+	RevokeAPIKey(ctx context.Context, keyID string, at time.Time) (*store.APIKey, error)
+	TouchAPIKey(ctx context.Context, keyID string, at time.Time) error
+}
+
+// APIKeys issues and authenticates API keys. Keys are stored hashed;
+// authenticated keys are cached in Redis so most requests skip Postgres.
+type APIKeys struct {
+	store    APIKeyStore
+	cache    redis.UniversalClient
+	keys     keyspace.Keyspace
+	cacheTTL time.Duration
+}
+
+// NewAPIKeys creates APIKeys on s, caching keys in cache under keys. A nil
+// cache or zero cacheTTL disables caching.
+func NewAPIKeys(s APIKeyStore, cache redis.UniversalClient, keys keyspace.Keyspace, cacheTTL time.Duration) *APIKeys {
+	return &APIKeys{store: s, cache: cache, keys: keys, cacheTTL: cacheTTL}
+}
+
+// HashAPIKey returns the hex SHA-256 of key. Keys are random, so a fast hash
+// is enough; there's nothing to brute-force.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey returns a new random key
+func GenerateAPIKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generating API key: %w", err)
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// Create issues a key. The returned plaintext key is not stored and can't be
+// recovered later.
+func (a *APIKeys) Create(ctx context.Context, name string, scopes []string) (*store.APIKey, string, error) {
+	key, err := GenerateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	k, err := a.insert(ctx, key, name, scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	return k, key, nil
+}
+
+// Bootstrap ensures key exists as an admin key, so the first keys can be
+// issued through the API. It's a no-op if the key is already stored.
+func (a *APIKeys) Bootstrap(ctx context.Context, key string) error {
+	if !strings.HasPrefix(key, keyPrefix) || len(key) < displayPrefixLen {
+		return fmt.Errorf("bootstrap API key must start with %q and be at least %d characters", keyPrefix, displayPrefixLen)
+	}
+	_, err := a.store.GetAPIKeyByHash(ctx, HashAPIKey(key))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	_, err = a.insert(ctx, key, "bootstrap", []string{ScopeAdmin})
+	return err
+}
+
+func (a *APIKeys) insert(ctx context.Context, key, name string, scopes []string) (*store.APIKey, error) {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	k := &store.APIKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Prefix:    key[:displayPrefixLen],
+		Hash:      HashAPIKey(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := a.store.CreateAPIKey(ctx, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// List returns the active keys
+func (a *APIKeys) List(ctx context.Context) ([]store.APIKey, error) {
+	return a.store.ListAPIKeys(ctx)
+}
+
+// Revoke revokes a key and evicts it from the cache. It returns
+// store.ErrNotFound if no active key has the ID.
+func (a *APIKeys) Revoke(ctx context.Context, keyID string) error {
+	k, err := a.store.RevokeAPIKey(ctx, keyID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if a.caching() {
+		if err := a.cache.Del(ctx, a.keys.Key("apikey", k.Hash)).Err(); err != nil {
+			return fmt.Errorf("evicting API key %s from cache: %w", keyID, err)
+		}
+	}
+	return nil
+}
+
+// Authenticate implements Authenticator, reading the key from the X-API-Key
+// header or an Authorization bearer token
+func (a *APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !strings.HasPrefix(token, keyPrefix) {
+			return nil, ErrNoCredentials
+		}
+		key = token
+	}
+	return a.Verify(r.Context(), key)
+}
+
+// Verify returns the principal for key, or ErrInvalidCredentials
+func (a *APIKeys) Verify(ctx context.Context, key string) (*Principal, error) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return nil, ErrInvalidCredentials
+	}
+	hash := HashAPIKey(key)
+
+	if p := a.cached(ctx, hash); p != nil {
+		return p, nil
+	}
+
+	k, err := a.store.GetAPIKeyByHash(ctx, hash)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up API key: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if err := a.store.TouchAPIKey(ctx, k.ID, now); err != nil {
+		logger.WarnContext(ctx, "recording API key use failed", "keyId", k.ID, "error", err)
+	}
+
+	p := &Principal{ID: k.ID, Name: k.Name, Method: MethodAPIKey, Scopes: k.Scopes}
+	a.cachePrincipal(ctx, hash, p)
+	return p, nil
+}
+
+func (a *APIKeys) caching() bool {
+	return a.cache != nil && a.cacheTTL > 0
+}
+
+// cached returns the cached principal for hash. Cache failures fall back to Postgres.
+func (a *APIKeys) cached(ctx context.Context, hash string) *Principal {
+	if !a.caching() {
+		return nil
+	}
+	data, err := a.cache.Get(ctx, a.keys.Key("apikey", hash)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.WarnContext(ctx, "reading API key cache failed", "error", err)
+		}
+		return nil
+	}
+	var p Principal
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil
+	}
+	return &p
+}
+
+func (a *APIKeys) cachePrincipal(ctx context.Context, hash string, p *Principal) {
+	if !a.caching() {
+		return
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+	if err := a.cache.Set(ctx, a.keys.Key("apikey", hash), data, a.cacheTTL).Err(); err != nil {
+		logger.WarnContext(ctx, "writing API key cache failed", "error", err)
+	}
+}
@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksRefreshInterval limits how often an unknown kid triggers a JWKS
+	// refetch, failed or not, so forged tokens can't hammer the identity
+	// provider, nor requests one that is down
+	jwksRefreshInterval = 30 * time.Second
+	defaultJWKSTTL      = time.Hour
+	defaultLeeway       = 30 * time.Second
+)
+
+// OIDCConfig configures bearer-token validation against an OIDC provider
+type OIDCConfig struct {
+	// Issuer must match the token's iss claim
+	Issuer string
+	// JWKSURL serves the provider's signing keys. If empty, it's discovered
+	// from the issuer's /.well-known/openid-configuration.
+	JWKSURL string
+	// Audience, if set, must be among the token's aud claim
+	Audience string
+	// TenantClaim names the claim holding the caller's tenant
+	TenantClaim string
+	// Leeway tolerates clock skew when checking exp and nbf
+	Leeway time.Duration
+	// JWKSTTL is how long fetched keys are trusted before refetching
+	JWKSTTL    time.Duration
+	HTTPClient *http.Client
+}
+
+// OIDC authenticates JWT bearer tokens issued by an OIDC provider
+type OIDC struct {
+	cfg OIDCConfig
+	now func() time.Time
+
+	// fetches runs one JWKS fetch at a time, its result shared by the
+	// requests waiting for it
+	fetches singleflight.Group
+
+	mu      sync.Mutex
+	jwksURL string
+	keys    map[string]crypto.PublicKey
+	// fetchedAt is when keys were fetched, attemptedAt when the last fetch
+	// started, and fetchErr why it failed
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetchErr    error
+}
+
+// NewOIDC creates an OIDC authenticator. Signing keys are fetched on first use.
+func NewOIDC(cfg OIDCConfig) *OIDC {
+	if cfg.Leeway == 0 {
+		cfg.Leeway = defaultLeeway
+	}
+	if cfg.JWKSTTL == 0 {
+		cfg.JWKSTTL = defaultJWKSTTL
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	return &OIDC{cfg: cfg, now: time.Now, jwksURL: cfg.JWKSURL}
+}
+
+// Authenticate implements Authenticator for Authorization bearer tokens.
+// API keys sent as bearer tokens are left to APIKeys.
+func (o *OIDC) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" || strings.HasPrefix(token, keyPrefix) {
+		return nil, ErrNoCredentials
+	}
+	return o.Verify(r.Context(), token)
+}
+
+// Verify validates token's signature and claims and returns its principal.
+// Tokens that fail validation return an error wrapping ErrInvalidCredentials;
+// expired ones return ErrTokenExpired.
+func (o *OIDC) Verify(ctx context.Context, token string) (*Principal, error) {
+	t, err := parseJWT(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if _, ok := signingAlgs[t.header.Alg]; !ok {
+		return nil, fmt.Errorf("%w: unsupported signing algorithm %q", ErrInvalidCredentials, t.header.Alg)
+	}
+
+	key, err := o.key(ctx, t.header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.verify(key); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return o.principal(t.claims)
+}
+
+// principal checks the registered claims and maps the token to a Principal
+func (o *OIDC) principal(claims map[string]any) (*Principal, error) {
+	now := o.now()
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != o.cfg.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidCredentials, iss)
+	}
+	if o.cfg.Audience != "" && !slices.Contains(stringList(claims["aud"]), o.cfg.Audience) {
+		return nil, fmt.Errorf("%w: token is not intended for audience %q", ErrInvalidCredentials, o.cfg.Audience)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: token has no expiry", ErrInvalidCredentials)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(o.cfg.Leeway)) {
+		return nil, ErrTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0).Add(-o.cfg.Leeway)) {
+		return nil, fmt.Errorf("%w: token is not valid yet", ErrInvalidCredentials)
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	p := &Principal{ID: sub, Method: MethodJWT}
+	p.Name, _ = claims["name"].(string)
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		p.Scopes = stringList(claims["scp"])
+	}
+	if o.cfg.TenantClaim != "" {
+		p.Tenant, _ = claims[o.cfg.TenantClaim].(string)
+	}
+	return p, nil
+}
+
+// stringList reads a claim that may be a string or an array of strings
+func stringList(v any) []string {
+	switch val := v.(type) {
+	case string:
+		return strings.Fields(val)
+	case []any:
+		list := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+// key returns the signing key with kid, refetching the JWKS when the keys
+// are stale or kid is unknown (e.g. after the provider rotated keys). Keys
+// are fetched at most once per jwksRefreshInterval, whether or not that
+// succeeds, and without holding o.mu.
+func (o *OIDC) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	o.mu.Lock()
+	now := o.now()
+	key, ok := o.lookup(kid)
+	stale := now.Sub(o.fetchedAt) > o.cfg.JWKSTTL
+	recent := now.Sub(o.attemptedAt) < jwksRefreshInterval
+	fetchErr := o.fetchErr
+	o.mu.Unlock()
+
+	switch {
+	case ok && (!stale || recent):
+		return key, nil
+	case recent && fetchErr != nil:
+		return nil, fetchErr
+	case recent:
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidCredentials, kid)
+	}
+
+	// The fetch is shared, so it outlives the request that started it
+	_, err, _ := o.fetches.Do("jwks", func() (any, error) {
+		return nil, o.refresh(context.WithoutCancel(ctx))
+	})
+	if err != nil {
+		if ok {
+			// Keep trusting the keys we have if the provider is unreachable
+			logger.Warn("refreshing OIDC signing keys failed", "error", err)
+			return key, nil
+		}
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if key, ok = o.lookup(kid); !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidCredentials, kid)
+	}
+	return key, nil
+}
+
+// lookup finds kid among the cached keys. A token without a kid matches the
+// provider's only key.
+func (o *OIDC) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(o.keys) == 1 {
+		for _, k := range o.keys {
+			return k, true
+		}
+	}
+	k, ok := o.keys[kid]
+	return k, ok
+}
+
+// refresh fetches the JWKS and records the attempt
+func (o *OIDC) refresh(ctx context.Context) error {
+	o.mu.Lock()
+	jwksURL := o.jwksURL
+	o.mu.Unlock()
+	attempted := o.now()
+
+	keys, jwksURL, err := o.fetch(ctx, jwksURL)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.attemptedAt, o.fetchErr = attempted, err
+	if err != nil {
+		return err
+	}
+	o.jwksURL, o.keys, o.fetchedAt = jwksURL, keys, attempted
+	return nil
+}
+
+// fetch returns the keys served at jwksURL, discovering it first if empty
+func (o *OIDC) fetch(ctx context.Context, jwksURL string) (map[string]crypto.PublicKey, string, error) {
+	if jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := o.getJSON(ctx, o.cfg.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, "", fmt.Errorf("discovering OIDC configuration: %w", err)
+		}
+		if discovery.JWKSURI == "" {
+			return nil, "", errors.New("OIDC discovery document has no jwks_uri")
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := o.getJSON(ctx, jwksURL, &jwks); err != nil {
+		return nil, "", fmt.Errorf("fetching JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			logger.Warn("skipping unusable JWKS key", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, jwksURL, nil
+}
+
+func (o *OIDC) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := o.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
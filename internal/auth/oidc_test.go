@@ -0,0 +1,233 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/auth"
+)
+
+// provider is a fake OIDC provider serving discovery and JWKS documents
+type provider struct {
+	*httptest.Server
+	rsaKey     *rsa.PrivateKey
+	ecKey      *ecdsa.PrivateKey
+	jwksHits   atomic.Int32
+	discovered atomic.Bool
+	// failing makes the JWKS endpoint fail
+	failing atomic.Bool
+}
+
+func newProvider(t *testing.T) *provider {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	p := &provider{rsaKey: rsaKey, ecKey: ecKey}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		p.discovered.Store(true)
+		_ = json.NewEncoder(w).Encode(map[string]string{"jwks_uri": p.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.jwksHits.Add(1)
+		if p.failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{
+				"kty": "RSA", "kid": "rsa-1", "use": "sig",
+				"n": b64(rsaKey.N.Bytes()),
+				"e": b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC", "kid": "ec-1", "crv": "P-256",
+				"x": b64(ecKey.X.FillBytes(make([]byte, 32))),
+				"y": b64(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		}})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// sign issues a token signed with the RSA key, or the EC key for ES256
+func (p *provider) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	input := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch alg {
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, p.rsaKey, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, p.ecKey, digest[:])
+		require.NoError(t, err)
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return input + "." + b64(sig)
+}
+
+func (p *provider) claims(overrides map[string]any) map[string]any {
+	claims := map[string]any{
+		"iss":       p.URL,
+		"sub":       "user-42",
+		"aud":       []string{"synapse-api", "other"},
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"iat":       time.Now().Unix(),
+		"scope":     "orders:read admin",
+		"tenant_id": "acme",
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	return claims
+}
+
+func newOIDC(p *provider) *auth.OIDC {
+	return auth.NewOIDC(auth.OIDCConfig{
+		Issuer:      p.URL,
+		Audience:    "synapse-api",
+		TenantClaim: "tenant_id",
+	})
+}
+
+func TestOIDC_ValidTokens(t *testing.T) {
+	p := newProvider(t)
+	o := newOIDC(p)
+	ctx := context.Background()
+
+	principal, err := o.Verify(ctx, p.sign(t, "RS256", "rsa-1", p.claims(nil)))
+	require.NoError(t, err)
+	assert.True(t, p.discovered.Load(), "JWKS URL is discovered from the issuer")
+	assert.Equal(t, "user-42", principal.ID)
+	assert.Equal(t, auth.MethodJWT, principal.Method)
+	assert.Equal(t, "acme", principal.Tenant)
+	assert.True(t, principal.HasScope(auth.ScopeAdmin))
+
+	principal, err = o.Verify(ctx, p.sign(t, "ES256", "ec-1", p.claims(map[string]any{
+		"scope": nil,
+		"scp":   []string{"orders:read"},
+	})))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders:read"}, principal.Scopes)
+
+	assert.Equal(t, int32(1), p.jwksHits.Load(), "keys are cached")
+}
+
+func TestOIDC_RejectsInvalidTokens(t *testing.T) {
+	p := newProvider(t)
+	o := newOIDC(p)
+	ctx := context.Background()
+
+	tests := map[string]string{
+		"wrong issuer":   p.sign(t, "RS256", "rsa-1", p.claims(map[string]any{"iss": "https://evil.example.com"})),
+		"wrong audience": p.sign(t, "RS256", "rsa-1", p.claims(map[string]any{"aud": "someone-else"})),
+		"no expiry":      p.sign(t, "RS256", "rsa-1", p.claims(map[string]any{"exp": nil})),
+		"not yet valid":  p.sign(t, "RS256", "rsa-1", p.claims(map[string]any{"nbf": time.Now().Add(time.Hour).Unix()})),
+		"no subject":     p.sign(t, "RS256", "rsa-1", p.claims(map[string]any{"sub": nil})),
+		"unknown kid":    p.sign(t, "RS256", "rsa-2", p.claims(nil)),
+		"wrong key type": p.sign(t, "RS256", "ec-1", p.claims(nil)),
+		"alg none":       p.sign(t, "none", "rsa-1", p.claims(nil)),
+		"malformed":      "not.a.jwt",
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := o.Verify(ctx, token)
+			assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		})
+	}
+
+	// Swapping in other claims invalidates the signature
+	token := strings.Split(p.sign(t, "RS256", "rsa-1", p.claims(nil)), ".")
+	forged := strings.Split(p.sign(t, "RS256", "rsa-1", p.claims(map[string]any{"sub": "admin"})), ".")
+	_, err := o.Verify(ctx, token[0]+"."+forged[1]+"."+token[2])
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+}
+
+func TestOIDC_FailingProvider(t *testing.T) {
+	p := newProvider(t)
+	p.failing.Store(true)
+	o := newOIDC(p)
+	token := p.sign(t, "RS256", "rsa-1", p.claims(nil))
+
+	// Concurrent requests share a fetch, and its failure holds off the next
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := o.Verify(context.Background(), token)
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+	_, err := o.Verify(context.Background(), token)
+	assert.ErrorContains(t, err, "fetching JWKS")
+	assert.EqualValues(t, 1, p.jwksHits.Load())
+}
+
+func TestOIDC_ExpiredToken(t *testing.T) {
+	p := newProvider(t)
+	o := newOIDC(p)
+
+	_, err := o.Verify(context.Background(), p.sign(t, "RS256", "rsa-1", p.claims(map[string]any{
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	})))
+	assert.ErrorIs(t, err, auth.ErrTokenExpired)
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+}
+
+func TestOIDC_Authenticate(t *testing.T) {
+	p := newProvider(t)
+	o := newOIDC(p)
+
+	r := httptest.NewRequest("GET", "/api/v1/orders", nil)
+	_, err := o.Authenticate(r)
+	assert.ErrorIs(t, err, auth.ErrNoCredentials)
+
+	// API keys sent as bearer tokens are left to the API key authenticator
+	r.Header.Set("Authorization", "Bearer syn_abcdefghijkl")
+	_, err = o.Authenticate(r)
+	assert.ErrorIs(t, err, auth.ErrNoCredentials)
+
+	r.Header.Set("Authorization", "Bearer "+p.sign(t, "RS256", "rsa-1", p.claims(nil)))
+	principal, err := o.Authenticate(r)
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", principal.ID)
+}
@@ -0,0 +1,53 @@
+// Package buildinfo reports which build of the service is running
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version, Commit and BuildTime (RFC 3339) are set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/synapse/synapse/internal/buildinfo.Version=1.2.0"
+//
+// When unset, they are taken from the build information Go embeds in the
+// binary, where available.
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// Info describes the running build
+type Info struct {
+	Version string
+	Commit  string
+	// BuildTime is when the binary was built, or else when its commit was
+	// made; empty when unknown
+	BuildTime string
+	// GoVersion is the Go release the binary was built with
+	GoVersion string
+}
+
+// Get returns the running build's information. Version is "dev" when
+// unknown; Commit and BuildTime are empty.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
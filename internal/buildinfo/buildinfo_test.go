@@ -0,0 +1,23 @@
+package buildinfo_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/synapse/synapse/internal/buildinfo"
+)
+
+func TestGet(t *testing.T) {
+	// Test binaries carry no module version
+	assert.Equal(t, "dev", buildinfo.Get().Version)
+
+	buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime = "1.2.0", "4969af4", "2024-01-15T10:30:00Z"
+	t.Cleanup(func() { buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime = "", "", "" })
+	assert.Equal(t, buildinfo.Info{
+		Version:   "1.2.0",
+		Commit:    "4969af4",
+		BuildTime: "2024-01-15T10:30:00Z",
+		GoVersion: runtime.Version(),
+	}, buildinfo.Get())
+}
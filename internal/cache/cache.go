@@ -0,0 +1,89 @@
+// Package cache keeps JSON copies of hot API reads in Redis, shared across
+// replicas. It fails open: Redis errors are logged and treated as misses, so
+// an outage only costs the reads it would have saved.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/synapse/synapse/internal/keyspace"
+	"github.com/synapse/synapse/internal/logging"
+)
+
+// logger logs the package's records
+var logger = logging.Module("cache")
+
+// Cache stores values as JSON in Redis. A nil *Cache caches nothing.
+type Cache struct {
+	client redis.UniversalClient
+	keys   keyspace.Keyspace
+}
+
+// New creates a Cache on client, with its entries in keys. A nil client
+// disables caching.
+func New(client redis.UniversalClient, keys keyspace.Keyspace) *Cache {
+	if client == nil {
+		return nil
+	}
+	return &Cache{client: client, keys: keys}
+}
+
+// key returns the Redis key of an entry
+func (c *Cache) key(key string) string {
+	return c.keys.Key("cache", key)
+}
+
+// Get decodes the value cached under key into v, reporting whether there was one
+func (c *Cache) Get(ctx context.Context, key string, v any) bool {
+	if c == nil {
+		return false
+	}
+	data, err := c.client.Get(ctx, c.key(key)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.WarnContext(ctx, "reading response cache failed", "key", key, "error", err)
+		}
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		logger.WarnContext(ctx, "decoding cached response failed", "key", key, "error", err)
+		return false
+	}
+	return true
+}
+
+// Set caches v under key for ttl
+func (c *Cache) Set(ctx context.Context, key string, v any, ttl time.Duration) {
+	if c == nil || ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := c.client.Set(ctx, c.key(key), data, ttl).Err(); err != nil {
+		logger.WarnContext(ctx, "writing response cache failed", "key", key, "error", err)
+	}
+}
+
+// Delete evicts keys. A failed eviction leaves the entries to expire. Keys
+// are deleted one by one, in a pipeline, as a Redis Cluster may hold them on
+// different nodes.
+func (c *Cache) Delete(ctx context.Context, keys ...string) {
+	if c == nil || len(keys) == 0 {
+		return
+	}
+	_, err := c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, key := range keys {
+			p.Del(ctx, c.key(key))
+		}
+		return nil
+	})
+	if err != nil {
+		logger.WarnContext(ctx, "evicting from response cache failed", "keys", keys, "error", err)
+	}
+}
@@ -0,0 +1,59 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/cache"
+	"github.com/synapse/synapse/internal/keyspace"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+type entry struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestCache_Disabled(t *testing.T) {
+	c := cache.New(nil, keyspace.Keyspace{})
+	ctx := context.Background()
+
+	c.Set(ctx, "key", entry{Name: "a"}, time.Minute)
+	var got entry
+	assert.False(t, c.Get(ctx, "key", &got))
+	c.Delete(ctx, "key")
+}
+
+func TestCache_Redis(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.StartContainers(ctx, t, &testutil.ContainerConfig{Services: []testutil.Service{testutil.ServiceRedis}})
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	c := cache.New(infra.Redis, infra.Keyspace())
+	var got entry
+	assert.False(t, c.Get(ctx, "stages", &got))
+
+	c.Set(ctx, "stages", entry{Name: "validate", Count: 3}, time.Minute)
+	require.True(t, c.Get(ctx, "stages", &got))
+	assert.Equal(t, entry{Name: "validate", Count: 3}, got)
+
+	c.Delete(ctx, "stages", "stage:validate")
+	assert.False(t, c.Get(ctx, "stages", &got))
+
+	// Entries expire after their TTL
+	c.Set(ctx, "order:1", entry{Name: "order"}, 100*time.Millisecond)
+	require.NoError(t, testutil.WaitForCondition(ctx, func() bool {
+		return !c.Get(ctx, "order:1", &got)
+	}), "entry expires")
+}
@@ -0,0 +1,44 @@
+// Package clock abstracts reading and waiting on the time, so retention,
+// backoff and schedules can be tested with a fake clock instead of sleeping.
+package clock
+
+import "time"
+
+// Clock tells the time and waits on it, as the time package does
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks on C until stopped, like a time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// System is the clock of the time package
+var System Clock = system{}
+
+// Or returns c, or System when c is nil
+func Or(c Clock) Clock {
+	if c == nil {
+		return System
+	}
+	return c
+}
+
+type system struct{}
+
+func (system) Now() time.Time                         { return time.Now() }
+func (system) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (system) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (system) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+type systemTicker struct {
+	t *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time { return t.t.C }
+func (t systemTicker) Stop()               { t.t.Stop() }
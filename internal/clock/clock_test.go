@@ -0,0 +1,29 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOr(t *testing.T) {
+	assert.Equal(t, System, Or(nil))
+
+	c := fixed{}
+	assert.Equal(t, Clock(c), Or(c))
+}
+
+func TestSystem_Ticker(t *testing.T) {
+	ticker := System.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+
+	select {
+	case <-ticker.C():
+	case <-time.After(time.Second):
+		t.Fatal("ticker didn't tick")
+	}
+	assert.False(t, System.Now().Before(time.Now().Add(-time.Second)))
+}
+
+type fixed struct{ Clock }
@@ -1,18 +1,125 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"maps"
+	"net"
+	"net/url"
+	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all application configuration
 type Config struct {
+	// Env is the SYNAPSE_ENV profile ("dev", "test", "staging" or "prod")
+	// whose presets replace the defaults of the settings left unset
+	Env string
+
 	// HTTP server
 	HTTPPort int
+	// gRPC server for internal callers; 0 disables it
+	GRPCPort int
+	// OpenAPI spec that incoming requests are validated against
+	OpenAPISpecPath string
+	// Compression of HTTP JSON responses of at least the given size
+	HTTPCompressionEnabled  bool
+	HTTPCompressionMinBytes int
+	// Request body limits: the size of /api request bodies (imports have
+	// their own) and the nesting depth of JSON bodies. Strict decoding
+	// rejects JSON bodies with unknown fields.
+	MaxRequestBodyBytes int
+	ImportMaxBodyBytes  int
+	MaxJSONDepth        int
+	StrictJSONDecoding  bool
+	// Request timeouts. RequestTimeoutMs applies to every route unless
+	// RouteTimeouts, keyed by route pattern, overrides it; 0 disables a
+	// timeout.
+	RequestTimeoutMs int
+	RouteTimeouts    map[string]time.Duration
+	// ShutdownTimeoutMs bounds the graceful shutdown on SIGTERM: draining
+	// requests and the pipeline, then closing connections
+	ShutdownTimeoutMs int
+	// StartupWaitSeconds is how long startup retries, with backoff, to reach
+	// the required dependencies when they aren't up yet, e.g. when started
+	// alongside them; 0 fails at once
+	StartupWaitSeconds int
+
+	// API key authentication of /api/v1 routes
+	AuthEnabled           bool
+	APIKeyCacheTTLSeconds int
+	// APIKeyBootstrap is an admin key seeded at startup, to issue the first keys
+	APIKeyBootstrap string
+
+	// OIDC bearer-token authentication, enabled when OIDCIssuer is set. The
+	// JWKS URL is discovered from the issuer unless given.
+	OIDCIssuer      string
+	OIDCJWKSURL     string
+	OIDCAudience    string
+	OIDCTenantClaim string
+
+	// Per-client rate limit of /api/v1 routes (token bucket in Redis)
+	RateLimitEnabled   bool
+	RateLimitPerSecond int
+	RateLimitBurst     int
+
+	// Audit log of POST, PUT, PATCH and DELETE calls to /api routes
+	AuditLogEnabled bool
 
-	// NATS
-	NATSURL string
+	// Redis cache of pipeline stage and order reads, evicted when the
+	// pipeline changes them
+	ResponseCacheEnabled          bool
+	ResponseCacheStagesTTLSeconds int
+	ResponseCacheOrdersTTLSeconds int
+
+	// Bulk order imports
+	ImportConcurrency int
+	ImportMaxErrors   int
+
+	// Background order exports, kept for download until they expire
+	ExportRetentionHours int
+
+	// NATS: the servers, comma separated, which may be in several
+	// clusters; the other servers of their clusters are discovered from
+	// those connected to. Servers are tried in random order or, with
+	// NATSPreserveServerOrder, in the order given, e.g. the local region's
+	// first.
+	NATSURL                 string
+	NATSPreserveServerOrder bool
+	// Reconnection after the connection drops: attempts (negative retries
+	// forever) and the wait between them
+	NATSMaxReconnects   int
+	NATSReconnectWaitMs int
+	// NATSReconnectBufBytes bounds what is buffered for publishing while
+	// reconnecting; past it publishes fail. -1 disables the buffer.
+	NATSReconnectBufBytes int
+	NATSConnectTimeoutMs  int
+	// Authentication: a user and password, or a credentials (JWT and
+	// NKey seed) file
+	NATSUser      string
+	NATSPassword  string
+	NATSCredsFile string
+	// TLS: the CA verifying the server, and a client certificate when the
+	// server requires one
+	NATSTLSCAFile   string
+	NATSTLSCertFile string
+	NATSTLSKeyFile  string
+	// NATSRequired fails startup, and readiness, while NATS is down. Unset,
+	// the service starts without it, reconnecting in the background.
+	NATSRequired bool
+	// JetStreamProvision sets what startup does with the JetStream streams
+	// and durable consumers the AsyncAPI channels call for: "off", "check"
+	// that they match, or "apply" the differences. Streams keep messages for
+	// JetStreamMaxAgeHours on JetStreamReplicas servers of the cluster the
+	// first JetStreamPlacement matching one of their subjects names, or of
+	// the cluster they are created through when none does.
+	JetStreamProvision   string
+	JetStreamReplicas    int
+	JetStreamMaxAgeHours int
+	JetStreamPlacement   []StreamPlacement
 
 	// PostgreSQL
 	PostgresHost     string
@@ -20,63 +127,461 @@ type Config struct {
 	PostgresUser     string
 	PostgresPassword string
 	PostgresDB       string
+	// TLS: the sslmode ("disable", "allow", "prefer", "require",
+	// "verify-ca" or "verify-full"), the CA verifying the server and a
+	// client certificate when the server requires one
+	PostgresSSLMode     string
+	PostgresSSLRootCert string
+	PostgresSSLCert     string
+	PostgresSSLKey      string
+	// PostgresParams are further connection parameters, e.g.
+	// application_name or search_path
+	PostgresParams map[string]string
+	// Connection pool: its size and how long connections are kept open
+	PostgresMaxConns               int
+	PostgresMinConns               int
+	PostgresMaxConnLifetimeSeconds int
+	PostgresMaxConnIdleSeconds     int
+	// PostgresQueryTimeoutMs bounds each query, and transaction, the service
+	// runs. It is also the sessions' statement_timeout, so that the server
+	// stops a query even when the client has gone. 0 disables both.
+	PostgresQueryTimeoutMs int
+	// PostgresTransactionPooling is set when Postgres is reached through a
+	// pooler in transaction mode, such as PgBouncer, that hands a session
+	// to another client between transactions. Statements are then neither
+	// prepared nor cached, statement_timeout is left to the pooler's role
+	// settings and leader election holds its lock in a transaction.
+	PostgresTransactionPooling bool
+	// PostgresReplicaDSN is a read replica's DSN, with its own credentials
+	// and TLS settings. Order lists, searches, exports and statistics read
+	// from it while it answers its health check, every
+	// PostgresReplicaCheckMs, and from the primary while it doesn't.
+	PostgresReplicaDSN     string
+	PostgresReplicaCheckMs int
 
-	// Redis
-	RedisAddr     string
-	RedisPassword string
-	RedisDB       int
+	// Redis: "single" server at RedisAddr, "sentinel" monitored master,
+	// found through the Sentinels at RedisAddrs, or "cluster" seeded from
+	// RedisAddrs. Clusters only have DB 0.
+	RedisMode             string
+	RedisAddr             string
+	RedisAddrs            []string
+	RedisSentinelMaster   string
+	RedisSentinelPassword string
+	RedisUsername         string
+	RedisPassword         string
+	RedisDB               int
+	// Connection pool and command timeouts; zero values keep go-redis's
+	// defaults
+	RedisPoolSize       int
+	RedisMinIdleConns   int
+	RedisPoolTimeoutMs  int
+	RedisDialTimeoutMs  int
+	RedisReadTimeoutMs  int
+	RedisWriteTimeoutMs int
+	// TLS, verifying the server against RedisTLSCAFile if set, or else the
+	// system roots
+	RedisTLSEnabled bool
+	RedisTLSCAFile  string
+	// RedisRequired fails startup, and readiness, while Redis is down. Unset,
+	// the service runs degraded without it until it is back.
+	RedisRequired bool
+	// Namespace of the keys in Redis, so environments and tenants can share
+	// one: service:tenant:vN: prefixes them, without an empty tenant or
+	// version 0. Raising the version moves to new keys, leaving the old
+	// ones to expire, e.g. when their layout changes.
+	RedisKeyService string
+	RedisKeyTenant  string
+	RedisKeyVersion int
 
 	// Pipeline
 	PipelineConcurrency int
 	RetryMaxAttempts    int
 	RetryBackoffMs      int
+
+	// Pipeline payload compression ("none", "gzip", "zstd")
+	PipelineCompression         string
+	PipelineCompressionMinBytes int
+
+	// Pipeline PII encryption ("id:base64key,..." key encryption keys)
+	PipelineEncryptionKeys  string
+	PipelineEncryptionKeyID string
+	PipelineEncryptedFields []string
+
+	// Encryption at rest of the order columns StoreEncryptedColumns names,
+	// by default customer_id ("id:base64key,..." keys); encrypting
+	// shipping_address too disables the shipping country filter and text
+	// search. Values are sealed under StoreEncryptionKeyID, or the first
+	// key; the others still read what they sealed, so keys can be rotated.
+	StoreEncryptionKeys   string
+	StoreEncryptionKeyID  string
+	StoreEncryptedColumns []string
+
+	// Webhook notifications for routed orders ("id|url|secret,...")
+	WebhookSubscribers string
+	WebhookTimeoutMs   int
+	WebhookMaxAttempts int
+	WebhookBackoffMs   int
+	// Also notify the subscriptions managed through the API
+	WebhookSubscriptionsEnabled bool
+
+	// LeaderRetryMs is how often replicas try to become the leader running
+	// a singleton background job, and how often the leader checks it still is
+	LeaderRetryMs int
+	// LockTTLMs is how long a Redis lock on an operation outlives its holder
+	// if the holder stops renewing it, e.g. because it crashed
+	LockTTLMs int
+
+	// Logging: "json" or "text" records at LogLevel ("debug", "info",
+	// "warn" or "error"), or the level LogModuleLevels sets for a module,
+	// e.g. "pipeline=debug". Past LogDebugSamplePerSecond debug records with
+	// the same message in a second, they are dropped; 0 keeps them all.
+	LogFormat               string
+	LogLevel                string
+	LogModuleLevels         map[string]string
+	LogDebugSamplePerSecond int
+
+	// Dependency health checks: how long each may take, and how long their
+	// results are reused by /health, /health/ready and /metrics
+	HealthCheckTimeoutMs int
+	HealthCheckCacheMs   int
+
+	// OpenTelemetry traces and metrics of HTTP requests and Postgres, Redis
+	// and NATS calls, exported over OTLP/HTTP to the collector at
+	// TracingEndpoint. TracingSamplePercent of new traces are sampled.
+	TracingEnabled       bool
+	TracingEndpoint      string
+	TracingServiceName   string
+	TracingSamplePercent int
 }
 
-// Load loads configuration from environment variables with sensible defaults
-func Load() (*Config, error) {
+// Load loads the configuration. Each setting is named by its environment
+// variable and may also be set in a YAML file named by the --config flag, or
+// by a flag in args, e.g. --http-port=9000 for HTTP_PORT. Flags override the
+// environment, which overrides the file; unset settings take the presets of
+// the SYNAPSE_ENV profile, if any, or else sensible defaults. Unknown flags
+// and file settings are rejected.
+func Load(args ...string) (*Config, error) {
+	src, err := newSource(args)
+	if err != nil {
+		return nil, err
+	}
+	// The profile's presets must be in place before any other lookup
+	env := src.applyProfile()
 	cfg := &Config{
-		HTTPPort:            getEnvInt("HTTP_PORT", 8080),
-		NATSURL:             getEnv("NATS_URL", "nats://localhost:4222"),
-		PostgresHost:        getEnv("POSTGRES_HOST", "localhost"),
-		PostgresPort:        getEnvInt("POSTGRES_PORT", 5432),
-		PostgresUser:        getEnv("POSTGRES_USER", "synapse"),
-		PostgresPassword:    getEnv("POSTGRES_PASSWORD", "synapse"),
-		PostgresDB:          getEnv("POSTGRES_DB", "synapse"),
-		RedisAddr:           getEnv("REDIS_ADDR", "localhost:6379"),
-		RedisPassword:       getEnv("REDIS_PASSWORD", ""),
-		RedisDB:             getEnvInt("REDIS_DB", 0),
-		PipelineConcurrency: getEnvInt("PIPELINE_CONCURRENCY", 10),
-		RetryMaxAttempts:    getEnvInt("RETRY_MAX_ATTEMPTS", 3),
-		RetryBackoffMs:      getEnvInt("RETRY_BACKOFF_MS", 1000),
+		Env: env,
+
+		HTTPPort:            src.getEnvInt("HTTP_PORT", 8080),
+		GRPCPort:            src.getEnvInt("GRPC_PORT", 9090),
+		OpenAPISpecPath:     src.getEnv("OPENAPI_SPEC_PATH", "openapi/openapi.yaml"),
+		NATSURL:             src.getEnv("NATS_URL", "nats://localhost:4222"),
+		PostgresHost:        src.getEnv("POSTGRES_HOST", "localhost"),
+		PostgresPort:        src.getEnvInt("POSTGRES_PORT", 5432),
+		PostgresUser:        src.getEnv("POSTGRES_USER", "synapse"),
+		PostgresPassword:    src.getEnv("POSTGRES_PASSWORD", "synapse"),
+		PostgresDB:          src.getEnv("POSTGRES_DB", "synapse"),
+		RedisAddr:           src.getEnv("REDIS_ADDR", "localhost:6379"),
+		RedisPassword:       src.getEnv("REDIS_PASSWORD", ""),
+		RedisDB:             src.getEnvInt("REDIS_DB", 0),
+		PipelineConcurrency: src.getEnvInt("PIPELINE_CONCURRENCY", 10),
+		RetryMaxAttempts:    src.getEnvInt("RETRY_MAX_ATTEMPTS", 3),
+		RetryBackoffMs:      src.getEnvInt("RETRY_BACKOFF_MS", 1000),
+
+		PostgresSSLMode:     src.getEnv("POSTGRES_SSLMODE", "disable"),
+		PostgresSSLRootCert: src.getEnv("POSTGRES_SSLROOTCERT", ""),
+		PostgresSSLCert:     src.getEnv("POSTGRES_SSLCERT", ""),
+		PostgresSSLKey:      src.getEnv("POSTGRES_SSLKEY", ""),
+
+		PostgresMaxConns:               src.getEnvInt("POSTGRES_MAX_CONNS", 20),
+		PostgresMinConns:               src.getEnvInt("POSTGRES_MIN_CONNS", 2),
+		PostgresMaxConnLifetimeSeconds: src.getEnvInt("POSTGRES_MAX_CONN_LIFETIME_SECONDS", 3600),
+		PostgresMaxConnIdleSeconds:     src.getEnvInt("POSTGRES_MAX_CONN_IDLE_SECONDS", 1800),
+		PostgresQueryTimeoutMs:         src.getEnvInt("POSTGRES_QUERY_TIMEOUT_MS", 30000),
+		PostgresTransactionPooling:     src.getEnvBool("POSTGRES_TRANSACTION_POOLING", false),
+		PostgresReplicaDSN:             src.getEnv("POSTGRES_REPLICA_DSN", ""),
+		PostgresReplicaCheckMs:         src.getEnvInt("POSTGRES_REPLICA_CHECK_MS", 5000),
+
+		NATSMaxReconnects:     src.getEnvInt("NATS_MAX_RECONNECTS", -1),
+		NATSReconnectWaitMs:   src.getEnvInt("NATS_RECONNECT_WAIT_MS", 2000),
+		NATSReconnectBufBytes: src.getEnvInt("NATS_RECONNECT_BUF_BYTES", 8<<20),
+		NATSConnectTimeoutMs:  src.getEnvInt("NATS_CONNECT_TIMEOUT_MS", 2000),
+		NATSUser:              src.getEnv("NATS_USER", ""),
+		NATSPassword:          src.getEnv("NATS_PASSWORD", ""),
+		NATSCredsFile:         src.getEnv("NATS_CREDS_FILE", ""),
+		NATSTLSCAFile:         src.getEnv("NATS_TLS_CA_FILE", ""),
+		NATSTLSCertFile:       src.getEnv("NATS_TLS_CERT_FILE", ""),
+		NATSTLSKeyFile:        src.getEnv("NATS_TLS_KEY_FILE", ""),
+		NATSRequired:          src.getEnvBool("NATS_REQUIRED", true),
+		JetStreamProvision:    src.getEnv("JETSTREAM_PROVISION", "off"),
+		JetStreamReplicas:     src.getEnvInt("JETSTREAM_REPLICAS", 1),
+		JetStreamMaxAgeHours:  src.getEnvInt("JETSTREAM_MAX_AGE_HOURS", 168),
+
+		NATSPreserveServerOrder: src.getEnvBool("NATS_PRESERVE_SERVER_ORDER", false),
+
+		RedisMode:             src.getEnv("REDIS_MODE", "single"),
+		RedisAddrs:            src.getEnvList("REDIS_ADDRS", nil),
+		RedisSentinelMaster:   src.getEnv("REDIS_SENTINEL_MASTER", ""),
+		RedisSentinelPassword: src.getEnv("REDIS_SENTINEL_PASSWORD", ""),
+		RedisUsername:         src.getEnv("REDIS_USERNAME", ""),
+		RedisPoolSize:         src.getEnvInt("REDIS_POOL_SIZE", 0),
+		RedisMinIdleConns:     src.getEnvInt("REDIS_MIN_IDLE_CONNS", 0),
+		RedisPoolTimeoutMs:    src.getEnvInt("REDIS_POOL_TIMEOUT_MS", 0),
+		RedisDialTimeoutMs:    src.getEnvInt("REDIS_DIAL_TIMEOUT_MS", 0),
+		RedisReadTimeoutMs:    src.getEnvInt("REDIS_READ_TIMEOUT_MS", 0),
+		RedisWriteTimeoutMs:   src.getEnvInt("REDIS_WRITE_TIMEOUT_MS", 0),
+		RedisTLSEnabled:       src.getEnvBool("REDIS_TLS_ENABLED", false),
+		RedisTLSCAFile:        src.getEnv("REDIS_TLS_CA_FILE", ""),
+		RedisRequired:         src.getEnvBool("REDIS_REQUIRED", false),
+		RedisKeyService:       src.getEnv("REDIS_KEY_SERVICE", "synapse"),
+		RedisKeyTenant:        src.getEnv("REDIS_KEY_TENANT", ""),
+		RedisKeyVersion:       src.getEnvInt("REDIS_KEY_VERSION", 0),
+
+		HTTPCompressionEnabled:  src.getEnvBool("HTTP_COMPRESSION_ENABLED", true),
+		HTTPCompressionMinBytes: src.getEnvInt("HTTP_COMPRESSION_MIN_BYTES", 1024),
+
+		MaxRequestBodyBytes: src.getEnvInt("MAX_REQUEST_BODY_BYTES", 1<<20),
+		ImportMaxBodyBytes:  src.getEnvInt("IMPORT_MAX_BODY_BYTES", 256<<20),
+		MaxJSONDepth:        src.getEnvInt("MAX_JSON_DEPTH", 32),
+		StrictJSONDecoding:  src.getEnvBool("STRICT_JSON_DECODING", false),
+
+		RequestTimeoutMs:   src.getEnvInt("REQUEST_TIMEOUT_MS", 30000),
+		ShutdownTimeoutMs:  src.getEnvInt("SHUTDOWN_TIMEOUT_MS", 30000),
+		StartupWaitSeconds: src.getEnvInt("STARTUP_WAIT_SECONDS", 60),
+
+		AuthEnabled:           src.getEnvBool("AUTH_ENABLED", true),
+		APIKeyCacheTTLSeconds: src.getEnvInt("API_KEY_CACHE_TTL_SECONDS", 60),
+		APIKeyBootstrap:       src.getEnv("API_KEY_BOOTSTRAP", ""),
+
+		OIDCIssuer:      src.getEnv("OIDC_ISSUER", ""),
+		OIDCJWKSURL:     src.getEnv("OIDC_JWKS_URL", ""),
+		OIDCAudience:    src.getEnv("OIDC_AUDIENCE", ""),
+		OIDCTenantClaim: src.getEnv("OIDC_TENANT_CLAIM", "tenant_id"),
+
+		RateLimitEnabled:   src.getEnvBool("RATE_LIMIT_ENABLED", true),
+		RateLimitPerSecond: src.getEnvInt("RATE_LIMIT_PER_SECOND", 50),
+		RateLimitBurst:     src.getEnvInt("RATE_LIMIT_BURST", 100),
+
+		AuditLogEnabled: src.getEnvBool("AUDIT_LOG_ENABLED", true),
+
+		ResponseCacheEnabled:          src.getEnvBool("RESPONSE_CACHE_ENABLED", true),
+		ResponseCacheStagesTTLSeconds: src.getEnvInt("RESPONSE_CACHE_STAGES_TTL_SECONDS", 2),
+		ResponseCacheOrdersTTLSeconds: src.getEnvInt("RESPONSE_CACHE_ORDERS_TTL_SECONDS", 30),
+
+		ImportConcurrency: src.getEnvInt("IMPORT_CONCURRENCY", 8),
+		ImportMaxErrors:   src.getEnvInt("IMPORT_MAX_ERRORS", 100),
+
+		ExportRetentionHours: src.getEnvInt("EXPORT_RETENTION_HOURS", 24),
+
+		PipelineCompression:         src.getEnv("PIPELINE_COMPRESSION", "none"),
+		PipelineCompressionMinBytes: src.getEnvInt("PIPELINE_COMPRESSION_MIN_BYTES", 4096),
+
+		PipelineEncryptionKeys:  src.getEnv("PIPELINE_ENCRYPTION_KEYS", ""),
+		PipelineEncryptionKeyID: src.getEnv("PIPELINE_ENCRYPTION_KEY_ID", ""),
+		PipelineEncryptedFields: src.getEnvList("PIPELINE_ENCRYPTED_FIELDS", nil),
+
+		StoreEncryptionKeys:   src.getEnv("STORE_ENCRYPTION_KEYS", ""),
+		StoreEncryptionKeyID:  src.getEnv("STORE_ENCRYPTION_KEY_ID", ""),
+		StoreEncryptedColumns: src.getEnvList("STORE_ENCRYPTED_COLUMNS", nil),
+
+		WebhookSubscribers: src.getEnv("WEBHOOK_SUBSCRIBERS", ""),
+		WebhookTimeoutMs:   src.getEnvInt("WEBHOOK_TIMEOUT_MS", 5000),
+		WebhookMaxAttempts: src.getEnvInt("WEBHOOK_MAX_ATTEMPTS", 3),
+		WebhookBackoffMs:   src.getEnvInt("WEBHOOK_BACKOFF_MS", 500),
+
+		WebhookSubscriptionsEnabled: src.getEnvBool("WEBHOOK_SUBSCRIPTIONS_ENABLED", true),
+
+		LeaderRetryMs: src.getEnvInt("LEADER_RETRY_MS", 5000),
+		LockTTLMs:     src.getEnvInt("LOCK_TTL_MS", 30000),
+
+		LogFormat:               src.getEnv("LOG_FORMAT", "json"),
+		LogLevel:                src.getEnv("LOG_LEVEL", "info"),
+		LogDebugSamplePerSecond: src.getEnvInt("LOG_DEBUG_SAMPLE_PER_SECOND", 100),
+
+		HealthCheckTimeoutMs: src.getEnvInt("HEALTH_CHECK_TIMEOUT_MS", 1000),
+		HealthCheckCacheMs:   src.getEnvInt("HEALTH_CHECK_CACHE_MS", 1000),
+
+		TracingEnabled:       src.getEnvBool("TRACING_ENABLED", false),
+		TracingEndpoint:      src.getEnv("TRACING_ENDPOINT", "http://localhost:4318"),
+		TracingServiceName:   src.getEnv("TRACING_SERVICE_NAME", "synapse"),
+		TracingSamplePercent: src.getEnvInt("TRACING_SAMPLE_PERCENT", 100),
+	}
+
+	// Every problem is reported at once, rather than one per restart
+	errs := src.errs
+	routeTimeouts, err := parseRouteTimeouts(src.getEnvList("ROUTE_TIMEOUTS", nil))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("ROUTE_TIMEOUTS: %w", err))
+	}
+	cfg.RouteTimeouts = routeTimeouts
+
+	postgresParams, err := parsePostgresParams(src.getEnvList("POSTGRES_PARAMS", nil))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("POSTGRES_PARAMS: %w", err))
+	}
+	cfg.PostgresParams = postgresParams
+
+	placement, err := parseStreamPlacement(src.getEnvList("JETSTREAM_PLACEMENT", nil))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("JETSTREAM_PLACEMENT: %w", err))
+	}
+	cfg.JetStreamPlacement = placement
+
+	logModuleLevels, err := parseLogModuleLevels(src.getEnvList("LOG_MODULE_LEVELS", nil))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("LOG_MODULE_LEVELS: %w", err))
 	}
+	cfg.LogModuleLevels = logModuleLevels
 
+	if unknown := src.unknown(); len(unknown) > 0 {
+		errs = append(errs, fmt.Errorf("unknown settings: %s", strings.Join(unknown, ", ")))
+	}
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
 	return cfg, nil
 }
 
+// defaultRouteTimeouts are the route timeouts ROUTE_TIMEOUTS adds to or
+// overrides. Probes must answer quickly, imports stream large uploads,
+// long-polled orders wait up to a minute and event streams don't end.
+var defaultRouteTimeouts = map[string]time.Duration{
+	"/health":                         2 * time.Second,
+	"/health/live":                    2 * time.Second,
+	"/health/ready":                   2 * time.Second,
+	"/api/v1/orders/import":           10 * time.Minute,
+	"/api/v1/orders/{orderId}":        75 * time.Second,
+	"/api/v1/orders/{orderId}/stream": 0,
+	"/api/v1/pipeline/events":         0,
+}
+
+// parseRouteTimeouts parses "pattern=duration" items, e.g.
+// "/api/v1/graphql=5s", over the default route timeouts
+func parseRouteTimeouts(items []string) (map[string]time.Duration, error) {
+	timeouts := maps.Clone(defaultRouteTimeouts)
+	for _, item := range items {
+		pattern, value, ok := strings.Cut(item, "=")
+		if !ok || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("%q isn't a route pattern=duration", item)
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("%q has an invalid duration", item)
+		}
+		timeouts[pattern] = d
+	}
+	return timeouts, nil
+}
+
 // PostgresDSN returns the PostgreSQL connection string
 func (c *Config) PostgresDSN() string {
-	return fmt.Sprintf(
-		"postgres://%s:%s@%s:%d/%s?sslmode=disable",
-		c.PostgresUser,
-		c.PostgresPassword,
-		c.PostgresHost,
-		c.PostgresPort,
-		c.PostgresDB,
-	)
+	params := url.Values{}
+	for k, v := range c.PostgresParams {
+		params.Set(k, v)
+	}
+	sslMode := c.PostgresSSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	params.Set("sslmode", sslMode)
+	for k, v := range map[string]string{
+		"sslrootcert": c.PostgresSSLRootCert,
+		"sslcert":     c.PostgresSSLCert,
+		"sslkey":      c.PostgresSSLKey,
+	} {
+		if v != "" {
+			params.Set(k, v)
+		}
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.PostgresUser, c.PostgresPassword),
+		Host:     net.JoinHostPort(c.PostgresHost, strconv.Itoa(c.PostgresPort)),
+		Path:     "/" + c.PostgresDB,
+		RawQuery: params.Encode(),
+	}
+	return dsn.String()
+}
+
+// parsePostgresParams parses "key=value" connection parameters. The TLS
+// settings have their own variables.
+func parsePostgresParams(items []string) (map[string]string, error) {
+	params := make(map[string]string, len(items))
+	for _, item := range items {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q isn't a key=value parameter", item)
+		}
+		if strings.HasPrefix(key, "ssl") {
+			return nil, fmt.Errorf("set %s with its POSTGRES_ variable", key)
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
+// secretFields are the settings Dump redacts
+var secretFields = []string{
+	"APIKeyBootstrap",
+	"NATSPassword",
+	"PostgresPassword",
+	"PostgresReplicaDSN",
+	"RedisSentinelPassword",
+	"RedisPassword",
+	"PipelineEncryptionKeys",
+	"StoreEncryptionKeys",
+	"WebhookSubscribers",
+}
+
+// Dump returns the settings by field name, with secrets redacted, to log the
+// configuration a server started with
+func (c *Config) Dump() map[string]any {
+	v := reflect.ValueOf(c).Elem()
+	settings := make(map[string]any, v.NumField())
+	for _, field := range reflect.VisibleFields(v.Type()) {
+		fv := v.FieldByIndex(field.Index)
+		value := fv.Interface()
+		if slices.Contains(secretFields, field.Name) && !fv.IsZero() {
+			value = "[redacted]"
+		}
+		settings[field.Name] = value
+	}
+	return settings
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// StreamPlacement places the JetStream streams of the subjects matching
+// Subject, a subject with wildcards such as orders.>, in a cluster of a
+// supercluster
+type StreamPlacement struct {
+	Subject string
+	Cluster string
+}
+
+// parseStreamPlacement parses "subject=cluster" items, e.g. "orders.>=east",
+// keeping their order
+func parseStreamPlacement(items []string) ([]StreamPlacement, error) {
+	var placement []StreamPlacement
+	for _, item := range items {
+		subject, cluster, ok := strings.Cut(item, "=")
+		if !ok || subject == "" || cluster == "" {
+			return nil, fmt.Errorf("%q isn't a subject=cluster item", item)
+		}
+		placement = append(placement, StreamPlacement{Subject: subject, Cluster: cluster})
 	}
-	return defaultValue
+	return placement, nil
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intVal, err := strconv.Atoi(value); err == nil {
-			return intVal
+// parseLogModuleLevels parses "module=level" items, e.g. "pipeline=debug"
+func parseLogModuleLevels(items []string) (map[string]string, error) {
+	levels := make(map[string]string, len(items))
+	for _, item := range items {
+		module, level, ok := strings.Cut(item, "=")
+		if !ok || module == "" {
+			return nil, fmt.Errorf("%q isn't a module=level item", item)
 		}
+		levels[module] = level
 	}
-	return defaultValue
+	return levels, nil
 }
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// profiles are the presets SYNAPSE_ENV selects, by setting name. A profile
+// only changes defaults: flags, the environment and the config file still
+// override each setting.
+var profiles = map[string]map[string]string{
+	// dev runs on a laptop, possibly without NATS or Redis started
+	"dev": {
+		"LOG_FORMAT":           "text",
+		"LOG_LEVEL":            "debug",
+		"RETRY_MAX_ATTEMPTS":   "1",
+		"NATS_REQUIRED":        "false",
+		"REDIS_REQUIRED":       "false",
+		"STARTUP_WAIT_SECONDS": "0",
+		"RATE_LIMIT_ENABLED":   "false",
+	},
+	// test runs the suites: quick retries, and bodies checked strictly
+	"test": {
+		"LOG_FORMAT":           "text",
+		"LOG_LEVEL":            "warn",
+		"STRICT_JSON_DECODING": "true",
+		"RETRY_MAX_ATTEMPTS":   "1",
+		"RETRY_BACKOFF_MS":     "10",
+		"WEBHOOK_BACKOFF_MS":   "10",
+		"NATS_REQUIRED":        "false",
+		"REDIS_REQUIRED":       "false",
+		"RATE_LIMIT_ENABLED":   "false",
+	},
+	// staging mirrors prod, applying JetStream changes and tracing everything
+	"staging": {
+		"LOG_FORMAT":             "json",
+		"LOG_LEVEL":              "info",
+		"STRICT_JSON_DECODING":   "true",
+		"RETRY_MAX_ATTEMPTS":     "3",
+		"NATS_REQUIRED":          "true",
+		"REDIS_REQUIRED":         "true",
+		"JETSTREAM_PROVISION":    "apply",
+		"TRACING_ENABLED":        "true",
+		"TRACING_SAMPLE_PERCENT": "100",
+	},
+	// prod fails fast without its dependencies and only checks JetStream,
+	// whose changes are applied by the provision command
+	"prod": {
+		"LOG_FORMAT":             "json",
+		"LOG_LEVEL":              "info",
+		"STRICT_JSON_DECODING":   "true",
+		"RETRY_MAX_ATTEMPTS":     "5",
+		"NATS_REQUIRED":          "true",
+		"REDIS_REQUIRED":         "true",
+		"JETSTREAM_PROVISION":    "check",
+		"TRACING_ENABLED":        "true",
+		"TRACING_SAMPLE_PERCENT": "10",
+	},
+}
+
+// Profiles returns the names of the SYNAPSE_ENV profiles
+func Profiles() []string {
+	return slices.Sorted(maps.Keys(profiles))
+}
+
+// applyProfile makes the presets of the SYNAPSE_ENV profile the source's
+// defaults, returning its name. No profile keeps the built-in defaults.
+func (s *source) applyProfile() string {
+	name := s.lookup("SYNAPSE_ENV")
+	if name == "" {
+		return ""
+	}
+	presets, ok := profiles[name]
+	if !ok {
+		s.errs = append(s.errs, fmt.Errorf("SYNAPSE_ENV must be one of %s, got %q", strings.Join(Profiles(), ", "), name))
+		return name
+	}
+	s.profile = presets
+	return name
+}
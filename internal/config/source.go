@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// source resolves settings by their environment variable name, e.g.
+// HTTP_PORT. Command line flags (--http-port=9000) override the environment,
+// which overrides the config file (http_port: 9000), which overrides the
+// SYNAPSE_ENV profile's presets.
+type source struct {
+	flags   map[string]string
+	file    map[string]string
+	profile map[string]string
+	// used records the settings Load looked up, to catch misspelled ones
+	used map[string]bool
+	// errs are the values that didn't parse
+	errs []error
+}
+
+// newSource parses args: --config (or -config) names a YAML config file,
+// and any other --name=value or --name value flag sets a setting
+func newSource(args []string) (*source, error) {
+	s := &source{flags: make(map[string]string), file: make(map[string]string), used: make(map[string]bool)}
+	var configFile string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			return nil, fmt.Errorf("unexpected argument %q", arg)
+		}
+		name, value, ok := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !ok {
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("flag %s needs a value", arg)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "config" {
+			configFile = value
+			continue
+		}
+		s.flags[settingName(name)] = value
+	}
+
+	if configFile != "" {
+		if err := s.readFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// readFile reads the settings of a YAML config file. Lists are read as
+// comma-separated values and maps as comma-separated key=value items, as the
+// environment variables spell them.
+func (s *source) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	var settings map[string]any
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	for name, v := range settings {
+		value, err := settingValue(v)
+		if err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, name, err)
+		}
+		s.file[settingName(name)] = value
+	}
+	return nil
+}
+
+// settingName converts a flag or config file key to its environment
+// variable name
+func settingName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// settingValue renders a config file value as its environment variable would
+func settingValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			value, err := settingValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = value
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		items := make([]string, 0, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			value, err := settingValue(v[key])
+			if err != nil {
+				return "", err
+			}
+			items = append(items, key+"="+value)
+		}
+		return strings.Join(items, ","), nil
+	case string:
+		return v, nil
+	case int, bool, float64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// lookup returns a setting's value, or "" if it isn't set
+func (s *source) lookup(key string) string {
+	s.used[key] = true
+	if value, ok := s.flags[key]; ok {
+		return value
+	}
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	if value, ok := s.file[key]; ok {
+		return value
+	}
+	return s.profile[key]
+}
+
+// unknown returns the flags and config file settings Load didn't look up
+func (s *source) unknown() []string {
+	var names []string
+	for _, m := range []map[string]string{s.flags, s.file} {
+		for name := range m {
+			if !s.used[name] && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (s *source) getEnv(key, defaultValue string) string {
+	if value := s.lookup(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func (s *source) getEnvInt(key string, defaultValue int) int {
+	if value := s.lookup(key); value != "" {
+		intVal, err := strconv.Atoi(value)
+		if err == nil {
+			return intVal
+		}
+		s.errs = append(s.errs, fmt.Errorf("%s must be a whole number, got %q", key, value))
+	}
+	return defaultValue
+}
+
+func (s *source) getEnvBool(key string, defaultValue bool) bool {
+	if value := s.lookup(key); value != "" {
+		boolVal, err := strconv.ParseBool(value)
+		if err == nil {
+			return boolVal
+		}
+		s.errs = append(s.errs, fmt.Errorf("%s must be true or false, got %q", key, value))
+	}
+	return defaultValue
+}
+
+func (s *source) getEnvList(key string, defaultValue []string) []string {
+	value := s.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,369 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// postgresSSLModes are the sslmode values libpq and pgx accept
+var postgresSSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// redisModes are the Redis topologies, as infra.NewRedis names them
+var redisModes = []string{"single", "sentinel", "cluster"}
+
+// redisKeySegment matches a service or tenant in a Redis key: it may not
+// hold the separator or braces, which would change a key's cluster slot
+var redisKeySegment = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// pipelineCompressions are the pipeline payload compressions
+var pipelineCompressions = []string{"none", "gzip", "zstd"}
+
+// storeEncryptableColumns are the order columns that can be encrypted at rest
+var storeEncryptableColumns = []string{"customer_id", "shipping_address", "billing_address"}
+
+// natsSchemes are the URL schemes of NATS servers
+var natsSchemes = []string{"nats", "tls", "ws", "wss"}
+
+// Validate checks that the settings are complete and consistent, so that a
+// misconfigured server fails at startup, naming the settings to fix, rather
+// than later with a connection error. It reports every problem it finds,
+// one per line.
+func (c *Config) Validate() error {
+	var errs []error
+	errs = append(errs, c.validateRequired()...)
+	errs = append(errs, c.validateAddresses()...)
+	errs = append(errs, c.validateBounds()...)
+	errs = append(errs, c.validatePools()...)
+	errs = append(errs, c.validateNATS()...)
+	errs = append(errs, c.validateRedis()...)
+	errs = append(errs, c.validatePostgresTLS()...)
+	errs = append(errs, c.validatePipeline()...)
+	errs = append(errs, c.validateStoreEncryption()...)
+	errs = append(errs, c.validateLogging()...)
+	return errors.Join(errs...)
+}
+
+// validateRequired checks the settings that have no usable empty value
+func (c *Config) validateRequired() []error {
+	required := map[string]string{
+		"OPENAPI_SPEC_PATH": c.OpenAPISpecPath,
+		"NATS_URL":          c.NATSURL,
+		"POSTGRES_HOST":     c.PostgresHost,
+		"POSTGRES_USER":     c.PostgresUser,
+		"POSTGRES_DB":       c.PostgresDB,
+	}
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(required)) {
+		if strings.TrimSpace(required[name]) == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+	return errs
+}
+
+// validateAddresses checks the ports and URLs
+func (c *Config) validateAddresses() []error {
+	var errs []error
+	port := func(name string, port int, optional bool) {
+		if optional && port == 0 {
+			return
+		}
+		if port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("%s must be a port from 1 to 65535, got %d", name, port))
+		}
+	}
+	port("HTTP_PORT", c.HTTPPort, false)
+	port("GRPC_PORT", c.GRPCPort, true)
+	port("POSTGRES_PORT", c.PostgresPort, false)
+	if c.HTTPPort != 0 && c.HTTPPort == c.GRPCPort {
+		errs = append(errs, fmt.Errorf("HTTP_PORT and GRPC_PORT must differ, both are %d", c.HTTPPort))
+	}
+
+	for _, server := range strings.Split(c.NATSURL, ",") {
+		if server = strings.TrimSpace(server); server == "" {
+			continue
+		}
+		u, err := url.Parse(server)
+		if err != nil || u.Host == "" || !slices.Contains(natsSchemes, u.Scheme) {
+			errs = append(errs, fmt.Errorf("NATS_URL has %q, want a URL such as nats://host:4222 (schemes %s)",
+				server, strings.Join(natsSchemes, ", ")))
+		}
+	}
+
+	httpURL := func(name, value string) {
+		if value == "" {
+			return
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			errs = append(errs, fmt.Errorf("%s must be an http(s) URL, got %q", name, value))
+		}
+	}
+	httpURL("OIDC_ISSUER", c.OIDCIssuer)
+	httpURL("OIDC_JWKS_URL", c.OIDCJWKSURL)
+	if c.TracingEnabled {
+		httpURL("TRACING_ENDPOINT", c.TracingEndpoint)
+		if c.TracingEndpoint == "" {
+			errs = append(errs, fmt.Errorf("TRACING_ENABLED needs the collector in TRACING_ENDPOINT"))
+		}
+		if c.TracingSamplePercent < 0 || c.TracingSamplePercent > 100 {
+			errs = append(errs, fmt.Errorf("TRACING_SAMPLE_PERCENT must be from 0 to 100, got %d", c.TracingSamplePercent))
+		}
+	}
+	if c.OIDCIssuer == "" && (c.OIDCJWKSURL != "" || c.OIDCAudience != "") {
+		errs = append(errs, fmt.Errorf("OIDC_JWKS_URL and OIDC_AUDIENCE have no effect without OIDC_ISSUER"))
+	}
+	return errs
+}
+
+// validateBounds checks the counts, sizes and durations: retries and
+// concurrency need at least one, and nothing may be negative
+func (c *Config) validateBounds() []error {
+	atLeastOne := map[string]int{
+		"PIPELINE_CONCURRENCY":    c.PipelineConcurrency,
+		"RETRY_MAX_ATTEMPTS":      c.RetryMaxAttempts,
+		"WEBHOOK_MAX_ATTEMPTS":    c.WebhookMaxAttempts,
+		"WEBHOOK_TIMEOUT_MS":      c.WebhookTimeoutMs,
+		"IMPORT_CONCURRENCY":      c.ImportConcurrency,
+		"MAX_JSON_DEPTH":          c.MaxJSONDepth,
+		"SHUTDOWN_TIMEOUT_MS":     c.ShutdownTimeoutMs,
+		"HEALTH_CHECK_TIMEOUT_MS": c.HealthCheckTimeoutMs,
+		"LEADER_RETRY_MS":         c.LeaderRetryMs,
+		"LOCK_TTL_MS":             c.LockTTLMs,
+	}
+	if c.PostgresReplicaDSN != "" {
+		atLeastOne["POSTGRES_REPLICA_CHECK_MS"] = c.PostgresReplicaCheckMs
+	}
+	if c.RateLimitEnabled {
+		atLeastOne["RATE_LIMIT_PER_SECOND"] = c.RateLimitPerSecond
+		atLeastOne["RATE_LIMIT_BURST"] = c.RateLimitBurst
+	}
+	nonNegative := map[string]int{
+		"RETRY_BACKOFF_MS":                  c.RetryBackoffMs,
+		"WEBHOOK_BACKOFF_MS":                c.WebhookBackoffMs,
+		"REQUEST_TIMEOUT_MS":                c.RequestTimeoutMs,
+		"MAX_REQUEST_BODY_BYTES":            c.MaxRequestBodyBytes,
+		"IMPORT_MAX_BODY_BYTES":             c.ImportMaxBodyBytes,
+		"IMPORT_MAX_ERRORS":                 c.ImportMaxErrors,
+		"HTTP_COMPRESSION_MIN_BYTES":        c.HTTPCompressionMinBytes,
+		"PIPELINE_COMPRESSION_MIN_BYTES":    c.PipelineCompressionMinBytes,
+		"API_KEY_CACHE_TTL_SECONDS":         c.APIKeyCacheTTLSeconds,
+		"RESPONSE_CACHE_STAGES_TTL_SECONDS": c.ResponseCacheStagesTTLSeconds,
+		"RESPONSE_CACHE_ORDERS_TTL_SECONDS": c.ResponseCacheOrdersTTLSeconds,
+		"EXPORT_RETENTION_HOURS":            c.ExportRetentionHours,
+		"JETSTREAM_MAX_AGE_HOURS":           c.JetStreamMaxAgeHours,
+		"HEALTH_CHECK_CACHE_MS":             c.HealthCheckCacheMs,
+		"STARTUP_WAIT_SECONDS":              c.StartupWaitSeconds,
+	}
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(atLeastOne)) {
+		if atLeastOne[name] < 1 {
+			errs = append(errs, fmt.Errorf("%s must be at least 1, got %d", name, atLeastOne[name]))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(nonNegative)) {
+		if nonNegative[name] < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %d", name, nonNegative[name]))
+		}
+	}
+	return errs
+}
+
+// validatePools checks the connection pool and client settings
+func (c *Config) validatePools() []error {
+	nonNegative := map[string]int{
+		"POSTGRES_MAX_CONNS":                 c.PostgresMaxConns,
+		"POSTGRES_MIN_CONNS":                 c.PostgresMinConns,
+		"POSTGRES_MAX_CONN_LIFETIME_SECONDS": c.PostgresMaxConnLifetimeSeconds,
+		"POSTGRES_MAX_CONN_IDLE_SECONDS":     c.PostgresMaxConnIdleSeconds,
+		"POSTGRES_QUERY_TIMEOUT_MS":          c.PostgresQueryTimeoutMs,
+		"REDIS_POOL_SIZE":                    c.RedisPoolSize,
+		"REDIS_MIN_IDLE_CONNS":               c.RedisMinIdleConns,
+		"REDIS_POOL_TIMEOUT_MS":              c.RedisPoolTimeoutMs,
+		"REDIS_DIAL_TIMEOUT_MS":              c.RedisDialTimeoutMs,
+		"REDIS_READ_TIMEOUT_MS":              c.RedisReadTimeoutMs,
+		"REDIS_WRITE_TIMEOUT_MS":             c.RedisWriteTimeoutMs,
+		"NATS_CONNECT_TIMEOUT_MS":            c.NATSConnectTimeoutMs,
+		"NATS_RECONNECT_WAIT_MS":             c.NATSReconnectWaitMs,
+	}
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(nonNegative)) {
+		if nonNegative[name] < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %d", name, nonNegative[name]))
+		}
+	}
+	if c.PostgresMaxConns > 0 && c.PostgresMinConns > c.PostgresMaxConns {
+		errs = append(errs, fmt.Errorf("POSTGRES_MIN_CONNS (%d) exceeds POSTGRES_MAX_CONNS (%d)", c.PostgresMinConns, c.PostgresMaxConns))
+	}
+	if c.RedisPoolSize > 0 && c.RedisMinIdleConns > c.RedisPoolSize {
+		errs = append(errs, fmt.Errorf("REDIS_MIN_IDLE_CONNS (%d) exceeds REDIS_POOL_SIZE (%d)", c.RedisMinIdleConns, c.RedisPoolSize))
+	}
+	if c.NATSReconnectBufBytes < -1 {
+		errs = append(errs, fmt.Errorf("NATS_RECONNECT_BUF_BYTES must be -1 or more, got %d", c.NATSReconnectBufBytes))
+	}
+	return errs
+}
+
+// validateNATS checks the NATS authentication and TLS settings
+func (c *Config) validateNATS() []error {
+	var errs []error
+	if c.NATSCredsFile != "" && (c.NATSUser != "" || c.NATSPassword != "") {
+		errs = append(errs, fmt.Errorf("set either NATS_CREDS_FILE or NATS_USER and NATS_PASSWORD, not both"))
+	}
+	if c.NATSPassword != "" && c.NATSUser == "" {
+		errs = append(errs, fmt.Errorf("NATS_PASSWORD needs NATS_USER"))
+	}
+	if (c.NATSTLSCertFile == "") != (c.NATSTLSKeyFile == "") {
+		errs = append(errs, fmt.Errorf("a NATS client certificate needs both NATS_TLS_CERT_FILE and NATS_TLS_KEY_FILE"))
+	}
+	switch c.JetStreamProvision {
+	case "off", "check", "apply", "":
+	default:
+		errs = append(errs, fmt.Errorf("JETSTREAM_PROVISION must be off, check or apply, got %q", c.JetStreamProvision))
+	}
+	// JetStream replicates a stream on at most 5 servers
+	if c.JetStreamReplicas < 1 || c.JetStreamReplicas > 5 {
+		errs = append(errs, fmt.Errorf("JETSTREAM_REPLICAS must be between 1 and 5, got %d", c.JetStreamReplicas))
+	}
+	return errs
+}
+
+// validateRedis checks the Redis topology
+func (c *Config) validateRedis() []error {
+	var errs []error
+	switch c.RedisMode {
+	case "single", "":
+		if c.RedisAddr == "" {
+			errs = append(errs, fmt.Errorf("REDIS_ADDR is required"))
+		}
+	case "sentinel":
+		if c.RedisSentinelMaster == "" {
+			errs = append(errs, fmt.Errorf("REDIS_MODE=sentinel needs the master's name in REDIS_SENTINEL_MASTER"))
+		}
+		if len(c.RedisAddrs) == 0 {
+			errs = append(errs, fmt.Errorf("REDIS_MODE=sentinel needs the Sentinels in REDIS_ADDRS"))
+		}
+	case "cluster":
+		if len(c.RedisAddrs) == 0 {
+			errs = append(errs, fmt.Errorf("REDIS_MODE=cluster needs the seed nodes in REDIS_ADDRS"))
+		}
+		if c.RedisDB != 0 {
+			errs = append(errs, fmt.Errorf("REDIS_MODE=cluster only has DB 0, got REDIS_DB=%d", c.RedisDB))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("REDIS_MODE must be one of %s, got %q", strings.Join(redisModes, ", "), c.RedisMode))
+	}
+	if c.RedisDB < 0 {
+		errs = append(errs, fmt.Errorf("REDIS_DB must not be negative, got %d", c.RedisDB))
+	}
+	if c.RedisSentinelPassword != "" && c.RedisMode != "sentinel" {
+		errs = append(errs, fmt.Errorf("REDIS_SENTINEL_PASSWORD is only used with REDIS_MODE=sentinel"))
+	}
+	if c.RedisTLSCAFile != "" && !c.RedisTLSEnabled {
+		errs = append(errs, fmt.Errorf("REDIS_TLS_CA_FILE needs REDIS_TLS_ENABLED=true"))
+	}
+	if c.RedisKeyService != "" && !redisKeySegment.MatchString(c.RedisKeyService) {
+		errs = append(errs, fmt.Errorf("REDIS_KEY_SERVICE must be letters, digits, '_', '.' or '-', got %q", c.RedisKeyService))
+	}
+	if c.RedisKeyTenant != "" && !redisKeySegment.MatchString(c.RedisKeyTenant) {
+		errs = append(errs, fmt.Errorf("REDIS_KEY_TENANT must be letters, digits, '_', '.' or '-', got %q", c.RedisKeyTenant))
+	}
+	if c.RedisKeyVersion < 0 {
+		errs = append(errs, fmt.Errorf("REDIS_KEY_VERSION must not be negative, got %d", c.RedisKeyVersion))
+	}
+	return errs
+}
+
+// validatePostgresTLS checks the Postgres TLS settings
+func (c *Config) validatePostgresTLS() []error {
+	var errs []error
+	if !slices.Contains(postgresSSLModes, c.PostgresSSLMode) {
+		errs = append(errs, fmt.Errorf("POSTGRES_SSLMODE must be one of %s, got %q", strings.Join(postgresSSLModes, ", "), c.PostgresSSLMode))
+	}
+	if (c.PostgresSSLCert == "") != (c.PostgresSSLKey == "") {
+		errs = append(errs, fmt.Errorf("a Postgres client certificate needs both POSTGRES_SSLCERT and POSTGRES_SSLKEY"))
+	}
+	if c.PostgresSSLMode == "verify-ca" || c.PostgresSSLMode == "verify-full" {
+		if c.PostgresSSLRootCert == "" {
+			errs = append(errs, fmt.Errorf("POSTGRES_SSLMODE=%s needs the CA in POSTGRES_SSLROOTCERT", c.PostgresSSLMode))
+		}
+	}
+	return errs
+}
+
+// validatePipeline checks the pipeline payload compression and encryption
+func (c *Config) validatePipeline() []error {
+	var errs []error
+	if c.PipelineCompression != "" && !slices.Contains(pipelineCompressions, c.PipelineCompression) {
+		errs = append(errs, fmt.Errorf("PIPELINE_COMPRESSION must be one of %s, got %q",
+			strings.Join(pipelineCompressions, ", "), c.PipelineCompression))
+	}
+	if c.PipelineEncryptionKeys == "" {
+		if len(c.PipelineEncryptedFields) > 0 || c.PipelineEncryptionKeyID != "" {
+			errs = append(errs, fmt.Errorf("PIPELINE_ENCRYPTED_FIELDS and PIPELINE_ENCRYPTION_KEY_ID need the keys in PIPELINE_ENCRYPTION_KEYS"))
+		}
+	} else if c.PipelineEncryptionKeyID != "" && !slices.Contains(keyIDs(c.PipelineEncryptionKeys), c.PipelineEncryptionKeyID) {
+		errs = append(errs, fmt.Errorf("PIPELINE_ENCRYPTION_KEY_ID %q isn't one of the keys in PIPELINE_ENCRYPTION_KEYS", c.PipelineEncryptionKeyID))
+	}
+	return errs
+}
+
+// validateStoreEncryption checks the encryption of order columns at rest
+func (c *Config) validateStoreEncryption() []error {
+	var errs []error
+	if c.StoreEncryptionKeys == "" {
+		if len(c.StoreEncryptedColumns) > 0 || c.StoreEncryptionKeyID != "" {
+			errs = append(errs, fmt.Errorf("STORE_ENCRYPTED_COLUMNS and STORE_ENCRYPTION_KEY_ID need the keys in STORE_ENCRYPTION_KEYS"))
+		}
+	} else if c.StoreEncryptionKeyID != "" && !slices.Contains(keyIDs(c.StoreEncryptionKeys), c.StoreEncryptionKeyID) {
+		errs = append(errs, fmt.Errorf("STORE_ENCRYPTION_KEY_ID %q isn't one of the keys in STORE_ENCRYPTION_KEYS", c.StoreEncryptionKeyID))
+	}
+	for _, column := range c.StoreEncryptedColumns {
+		if !slices.Contains(storeEncryptableColumns, column) {
+			errs = append(errs, fmt.Errorf("STORE_ENCRYPTED_COLUMNS may only name %s, got %q", strings.Join(storeEncryptableColumns, ", "), column))
+		}
+	}
+	return errs
+}
+
+// keyIDs returns the IDs of an "id:base64key,..." key spec
+func keyIDs(spec string) []string {
+	var ids []string
+	for _, entry := range strings.Split(spec, ",") {
+		if id, _, ok := strings.Cut(strings.TrimSpace(entry), ":"); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// logFormats are the log record formats
+var logFormats = []string{"json", "text"}
+
+// validateLogging checks the log format and levels
+func (c *Config) validateLogging() []error {
+	var errs []error
+	if !slices.Contains(logFormats, c.LogFormat) {
+		errs = append(errs, fmt.Errorf("LOG_FORMAT must be one of %s, got %q", strings.Join(logFormats, ", "), c.LogFormat))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", c.LogLevel))
+	}
+	for _, module := range slices.Sorted(maps.Keys(c.LogModuleLevels)) {
+		if err := level.UnmarshalText([]byte(c.LogModuleLevels[module])); err != nil {
+			errs = append(errs, fmt.Errorf("LOG_MODULE_LEVELS: %s's level must be debug, info, warn or error, got %q", module, c.LogModuleLevels[module]))
+		}
+	}
+	if c.LogDebugSamplePerSecond < 0 {
+		errs = append(errs, fmt.Errorf("LOG_DEBUG_SAMPLE_PER_SECOND must not be negative, got %d", c.LogDebugSamplePerSecond))
+	}
+	return errs
+}
@@ -1,26 +1,38 @@
 package conformance_test
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/asyncapi"
+	"github.com/synapse/synapse/internal/config"
 	"github.com/synapse/synapse/internal/conformance"
 	"github.com/synapse/synapse/internal/handler"
+	"github.com/synapse/synapse/internal/infra"
 	"github.com/synapse/synapse/internal/pipeline"
 	"github.com/synapse/synapse/internal/testutil"
+	"github.com/synapse/synapse/internal/testutil/factory"
+	"golang.org/x/net/websocket"
 )
 
 const (
-	openAPISpecPath  = "../../openapi/openapi.yaml"
-	asyncAPISpecPath = "../../asyncapi/asyncapi.yaml"
+	openAPISpecPath   = "../../openapi/openapi.yaml"
+	openAPIV2SpecPath = "../../openapi/v2/openapi.yaml"
+	asyncAPISpecPath  = "../../asyncapi/asyncapi.yaml"
 )
 
 func TestOpenAPI_HealthEndpoint_ConformsToSpec(t *testing.T) {
@@ -31,8 +43,8 @@ func TestOpenAPI_HealthEndpoint_ConformsToSpec(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	// Start containers
-	tc, err := testutil.StartContainers(ctx, t, nil)
+	// Use the package's shared containers
+	tc, err := testutil.SharedContainers(ctx, t)
 	require.NoError(t, err)
 
 	infra, cfg := testutil.TestInfra(ctx, t, tc)
@@ -51,6 +63,7 @@ func TestOpenAPI_HealthEndpoint_ConformsToSpec(t *testing.T) {
 	// Create contract test suite
 	suite, err := conformance.NewContractTestSuite(openAPISpecPath)
 	require.NoError(t, err)
+	testutil.ReportOpenAPI(t, openAPISpecPath, suite)
 
 	// Test health endpoint
 	result := suite.RunTest(ctx, srv.Client(), srv.URL,
@@ -71,7 +84,7 @@ func TestOpenAPI_LivenessEndpoint_ConformsToSpec(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	tc, err := testutil.StartContainers(ctx, t, nil)
+	tc, err := testutil.SharedContainers(ctx, t)
 	require.NoError(t, err)
 
 	infra, cfg := testutil.TestInfra(ctx, t, tc)
@@ -108,7 +121,7 @@ func TestOpenAPI_PipelineStagesEndpoint_ConformsToSpec(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	tc, err := testutil.StartContainers(ctx, t, nil)
+	tc, err := testutil.SharedContainers(ctx, t)
 	require.NoError(t, err)
 
 	infra, cfg := testutil.TestInfra(ctx, t, tc)
@@ -125,6 +138,7 @@ func TestOpenAPI_PipelineStagesEndpoint_ConformsToSpec(t *testing.T) {
 
 	suite, err := conformance.NewContractTestSuite(openAPISpecPath)
 	require.NoError(t, err)
+	testutil.ReportOpenAPI(t, openAPISpecPath, suite)
 
 	result := suite.RunTest(ctx, srv.Client(), srv.URL,
 		"GET", "/api/v1/pipeline/stages",
@@ -136,28 +150,782 @@ func TestOpenAPI_PipelineStagesEndpoint_ConformsToSpec(t *testing.T) {
 	assert.True(t, result.Passed, "pipeline stages endpoint should conform to spec: %s", result.Error)
 }
 
-func TestAsyncAPI_OrderReceivedPayload_ConformsToSpec(t *testing.T) {
-	suite, err := conformance.NewEventContractTestSuite(asyncAPISpecPath)
+func TestOpenAPI_ListOrdersEndpoint_ConformsToSpec(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping conformance test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
 	require.NoError(t, err)
 
-	// Valid payload
-	validPayload := map[string]any{
+	infra, cfg := testutil.TestInfra(ctx, t, tc)
+
+	runner, err := pipeline.New(ctx, cfg, infra)
+	require.NoError(t, err)
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	h := handler.New(infra, runner)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	// Seed orders so the list is not trivially empty
+	for i := 0; i < 3; i++ {
+		body := factory.Order().JSON()
+		resp, err := srv.Client().Post(srv.URL+"/api/v1/orders", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	}
+
+	suite, err := conformance.NewContractTestSuite(openAPISpecPath)
+	require.NoError(t, err)
+	testutil.ReportOpenAPI(t, openAPISpecPath, suite)
+
+	result := suite.RunTest(ctx, srv.Client(), srv.URL,
+		"GET", "/api/v1/orders?limit=2",
+		nil,
+		http.StatusOK,
+		"OrderListResponse",
+	)
+	assert.True(t, result.Passed, "orders list endpoint should conform to spec: %s", result.Error)
+
+	resp, err := srv.Client().Get(srv.URL + "/api/v1/orders?limit=2")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var list struct {
+		Orders     []map[string]any `json:"orders"`
+		Pagination map[string]any   `json:"pagination"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Len(t, list.Orders, 2)
+	assert.Equal(t, true, list.Pagination["hasMore"])
+	assert.Equal(t, "3", resp.Header.Get("X-Total-Count"))
+	assert.Contains(t, resp.Header.Get("Link"), `rel="next"`)
+
+	resp, err = srv.Client().Get(srv.URL + "/api/v1/orders?limit=0")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestOpenAPI_GetOrderEndpoint_ConformsToSpec(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping conformance test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+
+	infra, cfg := testutil.TestInfra(ctx, t, tc)
+
+	runner, err := pipeline.New(ctx, cfg, infra)
+	require.NoError(t, err)
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	h := handler.New(infra, runner)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	body := factory.Order().JSON()
+	resp, err := srv.Client().Post(srv.URL+"/api/v1/orders", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	var accepted struct {
+		OrderID string `json:"orderId"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&accepted))
+	resp.Body.Close()
+
+	// Wait for the order to reach the end of the pipeline
+	require.Eventually(t, func() bool {
+		resp, err := srv.Client().Get(srv.URL + "/api/v1/orders/" + accepted.OrderID)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		var order map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&order)
+		return order["status"] == "routed"
+	}, 10*time.Second, 100*time.Millisecond)
+
+	suite, err := conformance.NewContractTestSuite(openAPISpecPath)
+	require.NoError(t, err)
+	testutil.ReportOpenAPI(t, openAPISpecPath, suite)
+
+	result := suite.RunTest(ctx, srv.Client(), srv.URL,
+		"GET", "/api/v1/orders/"+accepted.OrderID,
+		nil,
+		http.StatusOK,
+		"OrderResponse",
+	)
+	assert.True(t, result.Passed, "order endpoint should conform to spec: %s", result.Error)
+
+	result = suite.RunTest(ctx, srv.Client(), srv.URL,
+		"GET", "/api/v1/orders/550e8400-e29b-41d4-a716-446655440000",
+		nil,
+		http.StatusNotFound,
+		"ProblemDetails",
+	)
+	assert.True(t, result.Passed, "unknown order should return problem details: %s", result.Error)
+}
+
+func TestOpenAPIV2_Order_ConformsToSpec(t *testing.T) {
+	validator, err := conformance.NewOpenAPIValidator(openAPIV2SpecPath)
+	require.NoError(t, err)
+
+	order := map[string]any{
 		"orderId":    "550e8400-e29b-41d4-a716-446655440000",
 		"customerId": "a1b2c3d4-e5f6-7890-abcd-ef1234567890",
-		"items": []map[string]any{
-			{
-				"sku":       "WIDGET-001",
-				"quantity":  2,
-				"unitPrice": 29.99,
-			},
-		},
-		"totalAmount": 59.98,
-		"currency":    "USD",
-		"createdAt":   "2024-01-15T10:30:00.000Z",
+		"status":     "routed",
+		"total":      map[string]any{"amount": "59.98", "currency": "USD"},
+		"items": []map[string]any{{
+			"sku":       "WIDGET-001",
+			"quantity":  2,
+			"unitPrice": map[string]any{"amount": "29.99", "currency": "USD"},
+			"lineTotal": map[string]any{"amount": "59.98", "currency": "USD"},
+		}},
+		"createdAt": "2024-01-15T10:30:00Z",
+		"updatedAt": "2024-01-15T10:30:02Z",
+		"links":     map[string]any{"self": "/api/v2/orders/550e8400-e29b-41d4-a716-446655440000"},
 	}
+	body, _ := json.Marshal(order)
+	assert.NoError(t, validator.ValidateResponse("Order", body))
 
-	payloadBytes, _ := json.Marshal(validPayload)
-	result := suite.ValidateEvent("orders/ingest", "OrderReceivedPayload", payloadBytes)
+	// Shared schemas are still available to the v2 spec
+	assert.NoError(t, validator.ValidateResponse("ProblemDetails", []byte(`{"type":"about:blank","title":"Not Found","status":404}`)))
+
+	// v1 amounts are numbers; v2 requires Money objects
+	order["total"] = 59.98
+	body, _ = json.Marshal(order)
+	assert.Error(t, validator.ValidateResponse("Order", body))
+}
+
+func TestOpenAPIV2_OrderEndpoints_ConformToSpec(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping conformance test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+
+	infra, cfg := testutil.TestInfra(ctx, t, tc)
+
+	runner, err := pipeline.New(ctx, cfg, infra)
+	require.NoError(t, err)
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	h := handler.New(infra, runner)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	body := factory.Order().
+		Items(factory.Item().SKU("WIDGET-001").Quantity(2).UnitPrice(29.99).Build()).
+		JSON()
+	resp, err := srv.Client().Post(srv.URL+"/api/v1/orders", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	var accepted struct {
+		OrderID string `json:"orderId"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&accepted))
+	resp.Body.Close()
+
+	suite, err := conformance.NewContractTestSuite(openAPIV2SpecPath)
+	require.NoError(t, err)
+	testutil.ReportOpenAPI(t, openAPIV2SpecPath, suite)
+
+	require.Eventually(t, func() bool {
+		result := suite.RunTest(ctx, srv.Client(), srv.URL,
+			"GET", "/api/v2/orders/"+accepted.OrderID,
+			nil,
+			http.StatusOK,
+			"Order",
+		)
+		return result.Passed
+	}, 10*time.Second, 100*time.Millisecond, "v2 order should conform to spec")
+
+	resp, err = srv.Client().Get(srv.URL + "/api/v2/orders/" + accepted.OrderID)
+	require.NoError(t, err)
+	var order struct {
+		Total struct {
+			Amount string `json:"amount"`
+		} `json:"total"`
+		Items []struct {
+			LineTotal struct {
+				Amount string `json:"amount"`
+			} `json:"lineTotal"`
+		} `json:"items"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&order))
+	resp.Body.Close()
+	assert.Equal(t, "59.98", order.Total.Amount)
+	require.Len(t, order.Items, 1)
+	assert.Equal(t, "59.98", order.Items[0].LineTotal.Amount)
+
+	result := suite.RunTest(ctx, srv.Client(), srv.URL,
+		"GET", "/api/v2/orders?limit=10",
+		nil,
+		http.StatusOK,
+		"OrderList",
+	)
+	assert.True(t, result.Passed, "v2 order list should conform to spec: %s", result.Error)
+
+	result = suite.RunTest(ctx, srv.Client(), srv.URL,
+		"GET", "/api/v2/orders/550e8400-e29b-41d4-a716-446655440000",
+		nil,
+		http.StatusNotFound,
+		"ProblemDetails",
+	)
+	assert.True(t, result.Passed, "unknown order should return problem details: %s", result.Error)
+}
+
+func TestOpenAPI_GraphQL_ResolvesOrderWithEvents(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping conformance test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+
+	infra, cfg := testutil.TestInfra(ctx, t, tc)
+
+	runner, err := pipeline.New(ctx, cfg, infra)
+	require.NoError(t, err)
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	h := handler.New(infra, runner)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	body := factory.Order().JSON()
+	resp, err := srv.Client().Post(srv.URL+"/api/v1/orders", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	var accepted struct {
+		OrderID string `json:"orderId"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&accepted))
+	resp.Body.Close()
+
+	query, err := json.Marshal(map[string]any{
+		"query": `query($id: ID!) {
+			order(id: $id) { status routing { destination } events { totalCount nodes { type stage status } } }
+			orders(first: 1) { totalCount nodes { id } }
+		}`,
+		"variables": map[string]any{"id": accepted.OrderID},
+	})
+	require.NoError(t, err)
+
+	var result struct {
+		Data struct {
+			Order struct {
+				Status  string `json:"status"`
+				Routing struct {
+					Destination string `json:"destination"`
+				} `json:"routing"`
+				Events struct {
+					TotalCount int              `json:"totalCount"`
+					Nodes      []map[string]any `json:"nodes"`
+				} `json:"events"`
+			} `json:"order"`
+			Orders struct {
+				TotalCount int `json:"totalCount"`
+				Nodes      []struct {
+					ID string `json:"id"`
+				} `json:"nodes"`
+			} `json:"orders"`
+		} `json:"data"`
+		Errors []map[string]any `json:"errors"`
+	}
+	// One request returns the order, its history and the list it's in
+	require.Eventually(t, func() bool {
+		resp, err := srv.Client().Post(srv.URL+"/api/v1/graphql", "application/json", strings.NewReader(string(query)))
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return result.Data.Order.Status == "ROUTED"
+	}, 10*time.Second, 100*time.Millisecond)
+
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, "fulfillment", result.Data.Order.Routing.Destination)
+	assert.Equal(t, len(result.Data.Order.Events.Nodes), result.Data.Order.Events.TotalCount)
+	assert.NotEmpty(t, result.Data.Order.Events.Nodes)
+	assert.Equal(t, 1, result.Data.Orders.TotalCount)
+	require.Len(t, result.Data.Orders.Nodes, 1)
+	assert.Equal(t, accepted.OrderID, result.Data.Orders.Nodes[0].ID)
+
+	suite, err := conformance.NewContractTestSuite(openAPISpecPath)
+	require.NoError(t, err)
+	testutil.ReportOpenAPI(t, openAPISpecPath, suite)
+
+	contract := suite.RunTest(ctx, srv.Client(), srv.URL,
+		"POST", "/api/v1/graphql",
+		query,
+		http.StatusOK,
+		"GraphQLResponse",
+	)
+	assert.True(t, contract.Passed, "graphql endpoint should conform to spec: %s", contract.Error)
+}
+
+func TestOpenAPI_OrderStream_PushesStatusUpdates(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping conformance test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+
+	infra, cfg := testutil.TestInfra(ctx, t, tc)
+
+	runner, err := pipeline.New(ctx, cfg, infra)
+	require.NoError(t, err)
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	h := handler.New(infra, runner)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	body := factory.Order().JSON()
+	resp, err := srv.Client().Post(srv.URL+"/api/v1/orders", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	var accepted struct {
+		OrderID string `json:"orderId"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&accepted))
+	resp.Body.Close()
+
+	// The stream opens once the order is in the projection
+	streamPath := "/api/v1/orders/" + accepted.OrderID + "/stream"
+	var ws *websocket.Conn
+	require.Eventually(t, func() bool {
+		ws, err = websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+streamPath, "", srv.URL)
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond)
+	defer ws.Close()
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(10*time.Second)))
+
+	events, err := conformance.NewEventContractTestSuite(asyncAPISpecPath)
+	require.NoError(t, err)
+	testutil.ReportAsyncAPI(t, asyncAPISpecPath, events)
+
+	// Frames arrive until the order is routed, then the server closes the stream
+	var statuses []string
+	for {
+		var frame []byte
+		if err := websocket.Message.Receive(ws, &frame); err != nil {
+			assert.ErrorIs(t, err, io.EOF)
+			break
+		}
+		result := events.ValidateEvent("orders/status", "OrderStatusUpdatePayload", frame)
+		assert.True(t, result.Passed, "status update should conform to spec: %s", result.Error)
+
+		var update struct {
+			OrderID string `json:"orderId"`
+			Status  string `json:"status"`
+		}
+		require.NoError(t, json.Unmarshal(frame, &update))
+		assert.Equal(t, accepted.OrderID, update.OrderID)
+		statuses = append(statuses, update.Status)
+	}
+	require.NotEmpty(t, statuses)
+	assert.Equal(t, "routed", statuses[len(statuses)-1])
+
+	suite, err := conformance.NewContractTestSuite(openAPISpecPath)
+	require.NoError(t, err)
+	testutil.ReportOpenAPI(t, openAPISpecPath, suite)
+
+	result := suite.RunTest(ctx, srv.Client(), srv.URL, "GET", streamPath, nil, http.StatusUpgradeRequired, "ProblemDetails")
+	assert.True(t, result.Passed, "plain GET should ask for an upgrade: %s", result.Error)
+}
+
+func TestOpenAPI_GetOrder_LongPollsForFinalStatus(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping conformance test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+
+	infra, cfg := testutil.TestInfra(ctx, t, tc)
+
+	runner, err := pipeline.New(ctx, cfg, infra)
+	require.NoError(t, err)
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	h := handler.New(infra, runner)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	body := factory.Order().JSON()
+	resp, err := srv.Client().Post(srv.URL+"/api/v1/orders", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	var accepted struct {
+		OrderID string `json:"orderId"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&accepted))
+	resp.Body.Close()
+
+	// The order is in the projection once it can be fetched at all
+	orderPath := "/api/v1/orders/" + accepted.OrderID
+	require.Eventually(t, func() bool {
+		resp, err := srv.Client().Get(srv.URL + orderPath)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 10*time.Second, 50*time.Millisecond)
+
+	suite, err := conformance.NewContractTestSuite(openAPISpecPath)
+	require.NoError(t, err)
+	testutil.ReportOpenAPI(t, openAPISpecPath, suite)
+
+	result := suite.RunTest(ctx, srv.Client(), srv.URL, "GET", orderPath+"?wait=20s", nil, http.StatusOK, "OrderResponse")
+	require.True(t, result.Passed, "long-polled order should conform to spec: %s", result.Error)
+	var order struct {
+		Status string `json:"status"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Response), &order))
+	assert.Equal(t, "routed", order.Status)
+
+	result = suite.RunTest(ctx, srv.Client(), srv.URL, "GET", orderPath+"?wait=5m", nil, http.StatusBadRequest, "ProblemDetails")
+	assert.True(t, result.Passed, "waits over the limit should be rejected: %s", result.Error)
+}
+
+func TestOpenAPI_PipelineEvents_StreamsStageEvents(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping conformance test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+
+	infra, cfg := testutil.TestInfra(ctx, t, tc)
+
+	runner, err := pipeline.New(ctx, cfg, infra)
+	require.NoError(t, err)
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	h := handler.New(infra, runner)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	streamCtx, stopStream := context.WithTimeout(ctx, 10*time.Second)
+	defer stopStream()
+	req, err := http.NewRequestWithContext(streamCtx, "GET", srv.URL+"/api/v1/pipeline/events?stage=route", nil)
+	require.NoError(t, err)
+	stream, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer stream.Body.Close()
+	require.Equal(t, http.StatusOK, stream.StatusCode)
+	assert.Equal(t, "text/event-stream", stream.Header.Get("Content-Type"))
+
+	body := factory.Order().JSON()
+	resp, err := srv.Client().Post(srv.URL+"/api/v1/orders", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// The first event is the route stage completing for the order
+	var event, data string
+	scanner := bufio.NewScanner(stream.Body)
+	for scanner.Scan() && data == "" {
+		line := scanner.Text()
+		if v, ok := strings.CutPrefix(line, "event: "); ok {
+			event = v
+		}
+		if v, ok := strings.CutPrefix(line, "data: "); ok {
+			data = v
+		}
+	}
+	require.NotEmpty(t, data, "no event received: %v", scanner.Err())
+	assert.Equal(t, "StageComplete", event)
+
+	events, err := conformance.NewEventContractTestSuite(asyncAPISpecPath)
+	require.NoError(t, err)
+	testutil.ReportAsyncAPI(t, asyncAPISpecPath, events)
+	result := events.ValidateEvent("pipeline/stage-complete", "StageCompletePayload", []byte(data))
+	assert.True(t, result.Passed, "stage event should conform to spec: %s", result.Error)
+
+	var complete struct {
+		StageID string `json:"stageId"`
+		Status  string `json:"status"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), &complete))
+	assert.Equal(t, "route", complete.StageID)
+	assert.Equal(t, "success", complete.Status)
+
+	suite, err := conformance.NewContractTestSuite(openAPISpecPath)
+	require.NoError(t, err)
+	testutil.ReportOpenAPI(t, openAPISpecPath, suite)
+
+	rejected := suite.RunTest(ctx, srv.Client(), srv.URL, "GET", "/api/v1/pipeline/events?errorType=bogus", nil, http.StatusBadRequest, "ProblemDetails")
+	assert.True(t, rejected.Passed, "unknown error types should be rejected: %s", rejected.Error)
+}
+
+func TestOpenAPI_AsyncAPISpec_ServedFromService(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping conformance test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+
+	infra, cfg := testutil.TestInfra(ctx, t, tc)
+
+	runner, err := pipeline.New(ctx, cfg, infra)
+	require.NoError(t, err)
+
+	h := handler.New(infra, runner)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	want, err := os.ReadFile(asyncAPISpecPath)
+	require.NoError(t, err)
+
+	// The served spec is the repository's, and needs no credentials
+	resp, err := srv.Client().Get(srv.URL + "/api/v1/asyncapi.yaml")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+	assert.Equal(t, string(want), string(body))
+
+	etag := resp.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/v1/asyncapi.yaml", nil)
+	require.NoError(t, err)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, err = srv.Client().Get(srv.URL + "/api/v1/asyncapi.html")
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/html")
+	assert.Contains(t, string(body), "/api/v1/asyncapi.yaml")
+}
+
+// TestOpenAPI_Routes_MatchSpec checks that the server registers exactly the
+// operations the specs declare. The server's routes are registered by
+// hand-maintained code, so this is what keeps them in step with the specs
+func TestOpenAPI_Routes_MatchSpec(t *testing.T) {
+	h := handler.New(&infra.Infra{}, nil)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	var routes []string
+	require.NoError(t, chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, method+" "+route)
+		return nil
+	}))
+
+	var ops []string
+	for _, specPath := range []string{openAPISpecPath, openAPIV2SpecPath} {
+		paths, err := conformance.Operations(specPath)
+		require.NoError(t, err)
+		for path, methods := range paths {
+			for _, method := range methods {
+				ops = append(ops, method+" "+path)
+			}
+		}
+	}
+	assert.ElementsMatch(t, ops, routes)
+}
+
+// TestOpenAPI_Methods_MatchSpec checks that OPTIONS lists exactly the methods
+// the specs declare for each path, plus HEAD for GET paths, and that GET
+// paths answer HEAD. Routing needs no infrastructure, so it runs in -short.
+func TestOpenAPI_Methods_MatchSpec(t *testing.T) {
+	h := handler.New(&infra.Infra{}, nil)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	pathParam := regexp.MustCompile(`\{[^}]+\}`)
+	for _, specPath := range []string{openAPISpecPath, openAPIV2SpecPath} {
+		ops, err := conformance.Operations(specPath)
+		require.NoError(t, err)
+		require.NotEmpty(t, ops)
+
+		for path, methods := range ops {
+			url := srv.URL + pathParam.ReplaceAllString(path, "550e8400-e29b-41d4-a716-446655440000")
+			want := append([]string{http.MethodOptions}, methods...)
+			if slices.Contains(methods, http.MethodGet) {
+				want = append(want, http.MethodHead)
+			}
+			slices.Sort(want)
+
+			req, err := http.NewRequest(http.MethodOptions, url, nil)
+			require.NoError(t, err)
+			resp, err := srv.Client().Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusNoContent, resp.StatusCode, "OPTIONS %s", path)
+			got := strings.Split(resp.Header.Get("Allow"), ", ")
+			slices.Sort(got)
+			assert.Equal(t, want, got, "Allow of %s", path)
+		}
+	}
+
+	resp, err := srv.Client().Head(srv.URL + "/health/live")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Empty(t, body)
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/orders", nil)
+	require.NoError(t, err)
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "GET, HEAD, POST, OPTIONS", resp.Header.Get("Allow"))
+}
+
+func TestOpenAPI_ProblemResponses_MatchGolden(t *testing.T) {
+	h := handler.New(&infra.Infra{}, nil)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	api := testutil.NewAPIClient(t, r)
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+	}{
+		{"invalid-limit", http.MethodGet, "/api/v1/orders?limit=many", nil, http.StatusBadRequest},
+		{"malformed-order", http.MethodPost, "/api/v1/orders", `{"customerId":`, http.StatusBadRequest},
+		{"method-not-allowed", http.MethodPut, "/api/v1/orders", nil, http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := api.WithT(t).Do(tc.method, tc.path, tc.body)
+			assert.Equal(t, tc.status, resp.StatusCode)
+			testutil.AssertGoldenJSON(t, "problems/"+tc.name, resp.Body)
+		})
+	}
+}
+
+func TestOpenAPI_IngestOrder_RejectsInvalidOrders(t *testing.T) {
+	h := handler.New(&infra.Infra{Config: &config.Config{OpenAPISpecPath: openAPISpecPath}}, nil)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	api := testutil.NewAPIClient(t, r)
+
+	for _, tc := range []struct {
+		name   string
+		body   any
+		fields []string
+	}{
+		{"empty", map[string]any{}, []string{"currency", "customerId", "items", "totalAmount"}},
+		{"invalid fields", map[string]any{
+			"customerId":  "not-a-uuid",
+			"items":       []any{},
+			"totalAmount": -1,
+			"currency":    "USD",
+		}, []string{"customerId", "items", "totalAmount"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := api.WithT(t).Post("/api/v1/orders", tc.body)
+			assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+			problem := testutil.Decode[struct {
+				Title  string `json:"title"`
+				Errors []struct {
+					Field string `json:"field"`
+				} `json:"errors"`
+			}](resp, http.StatusBadRequest)
+			assert.Equal(t, "Validation Error", problem.Title)
+
+			var fields []string
+			for _, e := range problem.Errors {
+				fields = append(fields, e.Field)
+			}
+			assert.Equal(t, tc.fields, slices.Compact(fields))
+		})
+	}
+}
+
+func TestAsyncAPI_OrderReceivedPayload_ConformsToSpec(t *testing.T) {
+	suite, err := conformance.NewEventContractTestSuite(asyncAPISpecPath)
+	require.NoError(t, err)
+	testutil.ReportAsyncAPI(t, asyncAPISpecPath, suite)
+
+	// Valid payload
+	result := suite.ValidateEvent("orders/ingest", "OrderReceivedPayload", factory.Order().Payload())
 
 	assert.True(t, result.Passed, "valid OrderReceivedPayload should conform to spec: %s", result.Error)
 }
@@ -182,6 +950,7 @@ func TestAsyncAPI_OrderReceivedPayload_FailsOnInvalidPayload(t *testing.T) {
 func TestAsyncAPI_StageCompletePayload_ConformsToSpec(t *testing.T) {
 	suite, err := conformance.NewEventContractTestSuite(asyncAPISpecPath)
 	require.NoError(t, err)
+	testutil.ReportAsyncAPI(t, asyncAPISpecPath, suite)
 
 	validPayload := map[string]any{
 		"stageId":    "validate",
@@ -199,6 +968,7 @@ func TestAsyncAPI_StageCompletePayload_ConformsToSpec(t *testing.T) {
 func TestAsyncAPI_PipelineErrorPayload_ConformsToSpec(t *testing.T) {
 	suite, err := conformance.NewEventContractTestSuite(asyncAPISpecPath)
 	require.NoError(t, err)
+	testutil.ReportAsyncAPI(t, asyncAPISpecPath, suite)
 
 	validPayload := map[string]any{
 		"errorId":   "661f9511-f3ac-52e5-b827-557766551111",
@@ -215,6 +985,62 @@ func TestAsyncAPI_PipelineErrorPayload_ConformsToSpec(t *testing.T) {
 	assert.True(t, result.Passed, "valid PipelineErrorPayload should conform to spec: %s", result.Error)
 }
 
+func TestAsyncAPI_OrderStatusUpdatePayload_ConformsToSpec(t *testing.T) {
+	suite, err := conformance.NewEventContractTestSuite(asyncAPISpecPath)
+	require.NoError(t, err)
+	testutil.ReportAsyncAPI(t, asyncAPISpecPath, suite)
+
+	snapshot := []byte(`{"orderId":"550e8400-e29b-41d4-a716-446655440000","status":"validated","timestamp":"2024-01-15T10:30:00Z"}`)
+	result := suite.ValidateEvent("orders/status", "OrderStatusUpdatePayload", snapshot)
+	assert.True(t, result.Passed, "snapshot should conform to spec: %s", result.Error)
+
+	update := []byte(`{
+		"orderId": "550e8400-e29b-41d4-a716-446655440000",
+		"status": "routed",
+		"eventId": "evt_004",
+		"eventType": "OrderRouted",
+		"stage": "route",
+		"stageStatus": "completed",
+		"timestamp": "2024-01-15T10:30:00.080Z",
+		"durationMs": 8,
+		"metadata": {"destination": "fulfillment"}
+	}`)
+	result = suite.ValidateEvent("orders/status", "OrderStatusUpdatePayload", update)
+	assert.True(t, result.Passed, "stage update should conform to spec: %s", result.Error)
+
+	// Validated directly, as the report's results are of conforming payloads
+	err = suite.Validator().ValidateMessage("OrderStatusUpdatePayload", []byte(`{"orderId":"550e8400-e29b-41d4-a716-446655440000","status":"shipped","timestamp":"2024-01-15T10:30:00Z"}`))
+	assert.Error(t, err, "unknown statuses should fail validation")
+}
+
+func TestAsyncAPI_Samples_ConformToSpec(t *testing.T) {
+	suite, err := conformance.NewEventContractTestSuite(asyncAPISpecPath)
+	require.NoError(t, err)
+	testutil.ReportAsyncAPI(t, asyncAPISpecPath, suite)
+
+	for _, schema := range []string{
+		"OrderReceivedPayload",
+		"OrderRoutedPayload",
+		"OrderCancelledPayload",
+		"OrderStatusUpdatePayload",
+		"OrderFailedPayload",
+		"StageCompletePayload",
+		"PipelineErrorPayload",
+	} {
+		sample, err := asyncapi.Sample(schema)
+		require.NoError(t, err, schema)
+		payload, err := json.Marshal(sample)
+		require.NoError(t, err)
+
+		result := suite.ValidateEvent("", schema, payload)
+		assert.True(t, result.Passed, "%s sample should conform to spec: %s", schema, result.Error)
+		testutil.AssertGoldenJSON(t, "samples/"+schema, payload)
+	}
+
+	_, err = asyncapi.Sample("NoSuchPayload")
+	assert.Error(t, err)
+}
+
 func TestConformance_FullSuite(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping full conformance suite")
@@ -224,7 +1050,7 @@ func TestConformance_FullSuite(t *testing.T) {
 	defer cancel()
 
 	// Start infrastructure
-	tc, err := testutil.StartContainers(ctx, t, nil)
+	tc, err := testutil.SharedContainers(ctx, t)
 	require.NoError(t, err)
 
 	infra, cfg := testutil.TestInfra(ctx, t, tc)
@@ -320,6 +1146,7 @@ func TestConformance_FullSuite(t *testing.T) {
 	t.Run("AsyncAPI_EventSchemas", func(t *testing.T) {
 		suite, err := conformance.NewEventContractTestSuite(asyncAPISpecPath)
 		require.NoError(t, err)
+		testutil.ReportAsyncAPI(t, asyncAPISpecPath, suite)
 
 		// Test all event payloads
 		eventTests := []struct {
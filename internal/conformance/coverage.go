@@ -0,0 +1,135 @@
+package conformance
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SpecCoverage is which operations or messages of a spec contract tests
+// exercised
+type SpecCoverage struct {
+	Spec      string   `json:"spec"`
+	Covered   []string `json:"covered"`
+	Uncovered []string `json:"uncovered"`
+	Percent   float64  `json:"percent"`
+}
+
+func newSpecCoverage(specPath string, declared []string, covered func(string) bool) SpecCoverage {
+	c := SpecCoverage{Spec: specName(specPath), Covered: []string{}, Uncovered: []string{}}
+	sort.Strings(declared)
+	for _, item := range declared {
+		if covered(item) {
+			c.Covered = append(c.Covered, item)
+		} else {
+			c.Uncovered = append(c.Uncovered, item)
+		}
+	}
+	if len(declared) > 0 {
+		c.Percent = float64(len(c.Covered)) * 100 / float64(len(declared))
+	}
+	return c
+}
+
+// specName is specPath relative to the repository, e.g. openapi/openapi.yaml
+func specName(specPath string) string {
+	name := filepath.ToSlash(filepath.Clean(specPath))
+	for strings.HasPrefix(name, "../") {
+		name = strings.TrimPrefix(name, "../")
+	}
+	return name
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+// OperationCoverage returns which operations of the OpenAPI spec at
+// specPath, e.g. "GET /api/v1/orders/{orderId}", results called
+func OperationCoverage(specPath string, results []ContractTestResult) (SpecCoverage, error) {
+	ops, err := Operations(specPath)
+	if err != nil {
+		return SpecCoverage{}, err
+	}
+
+	var declared []string
+	patterns := make(map[string]*regexp.Regexp)
+	for path, methods := range ops {
+		// Path parameters match a segment
+		var pattern strings.Builder
+		last := 0
+		for _, loc := range pathParam.FindAllStringIndex(path, -1) {
+			pattern.WriteString(regexp.QuoteMeta(path[last:loc[0]]))
+			pattern.WriteString("[^/]+")
+			last = loc[1]
+		}
+		pattern.WriteString(regexp.QuoteMeta(path[last:]))
+		re := regexp.MustCompile("^" + pattern.String() + "$")
+
+		for _, method := range methods {
+			op := method + " " + path
+			declared = append(declared, op)
+			patterns[op] = re
+		}
+	}
+
+	return newSpecCoverage(specPath, declared, func(op string) bool {
+		method, _, _ := strings.Cut(op, " ")
+		for _, r := range results {
+			path, _, _ := strings.Cut(r.Endpoint, "?")
+			if strings.EqualFold(r.Method, method) && patterns[op].MatchString(path) {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// Messages returns the payload schema of each message the AsyncAPI spec at
+// specPath declares, e.g. "OrderReceived" → "OrderReceivedPayload"
+func Messages(specPath string) (map[string]string, error) {
+	var spec struct {
+		Components struct {
+			Messages map[string]struct {
+				Payload struct {
+					Ref string `yaml:"$ref"`
+				} `yaml:"payload"`
+			} `yaml:"messages"`
+		} `yaml:"components"`
+	}
+	if err := readYAML(specPath, &spec); err != nil {
+		return nil, err
+	}
+
+	msgs := make(map[string]string, len(spec.Components.Messages))
+	for name, msg := range spec.Components.Messages {
+		ref := msg.Payload.Ref
+		if ref == "" {
+			return nil, fmt.Errorf("message %s has no payload reference", name)
+		}
+		msgs[name] = ref[strings.LastIndex(ref, "/")+1:]
+	}
+	return msgs, nil
+}
+
+// MessageCoverage returns which messages of the AsyncAPI spec at specPath
+// results validated payloads of
+func MessageCoverage(specPath string, results []EventTestResult) (SpecCoverage, error) {
+	msgs, err := Messages(specPath)
+	if err != nil {
+		return SpecCoverage{}, err
+	}
+
+	declared := make([]string, 0, len(msgs))
+	for name := range msgs {
+		declared = append(declared, name)
+	}
+	return newSpecCoverage(specPath, declared, func(name string) bool {
+		for _, r := range results {
+			if r.Schema == msgs[name] {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
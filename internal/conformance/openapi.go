@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/santhosh-tekuri/jsonschema/v5"
@@ -59,57 +60,74 @@ func (v *OpenAPIValidator) loadSpec() error {
 	return nil
 }
 
-func (v *OpenAPIValidator) loadComponentSchemas(baseDir string) error {
-	schemasDir := filepath.Join(baseDir, "components", "schemas")
+// versionDir matches the directory of a versioned spec, e.g. openapi/v2
+var versionDir = regexp.MustCompile(`^v[0-9]+$`)
 
-	files, err := os.ReadDir(schemasDir)
-	if err != nil {
-		return fmt.Errorf("reading schemas dir: %w", err)
+// schemaDirs returns the directories to load component schemas from. A
+// versioned spec (<root>/vN/openapi.yaml) shares the root spec's schemas,
+// replacing those it redefines.
+func schemaDirs(baseDir string) []string {
+	var dirs []string
+	if versionDir.MatchString(filepath.Base(baseDir)) {
+		dirs = append(dirs, filepath.Join(filepath.Dir(baseDir), "components", "schemas"))
 	}
+	return append(dirs, filepath.Join(baseDir, "components", "schemas"))
+}
 
-	// First pass: add all schema resources
-	schemaNames := []string{}
-	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".yaml") {
-			continue
-		}
-		if file.Name() == "_index.yaml" {
-			continue
-		}
-
-		filePath := filepath.Join(schemasDir, file.Name())
-		data, err := os.ReadFile(filePath)
+func (v *OpenAPIValidator) loadComponentSchemas(baseDir string) error {
+	// First pass: read all schemas, later directories replacing earlier ones
+	schemas := make(map[string]map[string]any)
+	for _, schemasDir := range schemaDirs(baseDir) {
+		files, err := os.ReadDir(schemasDir)
 		if err != nil {
-			return fmt.Errorf("reading schema file %s: %w", file.Name(), err)
+			return fmt.Errorf("reading schemas dir: %w", err)
 		}
 
-		var schemas map[string]any
-		if err := yaml.Unmarshal(data, &schemas); err != nil {
-			return fmt.Errorf("parsing schema file %s: %w", file.Name(), err)
-		}
-
-		for name, schema := range schemas {
-			schemaMap, ok := schema.(map[string]any)
-			if !ok {
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), ".yaml") {
+				continue
+			}
+			if file.Name() == "_index.yaml" {
 				continue
 			}
 
-			// Convert to JSON Schema format
-			jsonSchema := v.toJSONSchema(schemaMap)
-			jsonBytes, err := json.Marshal(jsonSchema)
+			filePath := filepath.Join(schemasDir, file.Name())
+			data, err := os.ReadFile(filePath)
 			if err != nil {
-				continue
+				return fmt.Errorf("reading schema file %s: %w", file.Name(), err)
+			}
+
+			var fileSchemas map[string]any
+			if err := yaml.Unmarshal(data, &fileSchemas); err != nil {
+				return fmt.Errorf("parsing schema file %s: %w", file.Name(), err)
 			}
 
-			schemaID := fmt.Sprintf("synapse://schemas/%s", name)
-			if err := v.compiler.AddResource(schemaID, bytes.NewReader(jsonBytes)); err != nil {
-				return fmt.Errorf("adding schema %s: %w", name, err)
+			for name, schema := range fileSchemas {
+				if schemaMap, ok := schema.(map[string]any); ok {
+					schemas[name] = schemaMap
+				}
 			}
-			schemaNames = append(schemaNames, name)
 		}
 	}
 
-	// Second pass: compile all schemas after all resources are added
+	// Second pass: add all schema resources
+	schemaNames := []string{}
+	for name, schemaMap := range schemas {
+		// Convert to JSON Schema format
+		jsonSchema := v.toJSONSchema(schemaMap)
+		jsonBytes, err := json.Marshal(jsonSchema)
+		if err != nil {
+			continue
+		}
+
+		schemaID := fmt.Sprintf("synapse://schemas/%s", name)
+		if err := v.compiler.AddResource(schemaID, bytes.NewReader(jsonBytes)); err != nil {
+			return fmt.Errorf("adding schema %s: %w", name, err)
+		}
+		schemaNames = append(schemaNames, name)
+	}
+
+	// Third pass: compile all schemas after all resources are added
 	for _, name := range schemaNames {
 		schemaID := fmt.Sprintf("synapse://schemas/%s", name)
 		compiled, err := v.compiler.Compile(schemaID)
@@ -142,6 +160,12 @@ func (v *OpenAPIValidator) toJSONSchema(schema map[string]any) map[string]any {
 			if items, ok := val.(map[string]any); ok {
 				result["items"] = v.toJSONSchema(items)
 			}
+		case "additionalProperties":
+			if additional, ok := val.(map[string]any); ok {
+				result["additionalProperties"] = v.toJSONSchema(additional)
+			} else {
+				result["additionalProperties"] = val
+			}
 		case "allOf":
 			if allOf, ok := val.([]any); ok {
 				converted := make([]any, len(allOf))
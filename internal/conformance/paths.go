@@ -0,0 +1,89 @@
+package conformance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// operationMethods are the path item keys that declare operations
+var operationMethods = []string{"get", "head", "post", "put", "patch", "delete", "options"}
+
+// Operations returns the HTTP methods the spec at specPath declares for each
+// of its paths, e.g. "/api/v1/orders" → [GET POST]. Path items split into
+// files with $ref, like this repo's spec, are followed.
+func Operations(specPath string) (map[string][]string, error) {
+	var spec struct {
+		Paths map[string]any `yaml:"paths"`
+	}
+	if err := readYAML(specPath, &spec); err != nil {
+		return nil, err
+	}
+
+	paths, dir := spec.Paths, filepath.Dir(specPath)
+	if ref, ok := paths["$ref"].(string); ok {
+		paths = nil
+		if err := readYAML(filepath.Join(dir, ref), &paths); err != nil {
+			return nil, err
+		}
+		dir = filepath.Dir(filepath.Join(dir, ref))
+	}
+
+	ops := make(map[string][]string, len(paths))
+	for path, item := range paths {
+		item, err := resolvePathItem(dir, item)
+		if err != nil {
+			return nil, fmt.Errorf("path %s: %w", path, err)
+		}
+		var methods []string
+		for _, method := range operationMethods {
+			if _, ok := item[method]; ok {
+				methods = append(methods, strings.ToUpper(method))
+			}
+		}
+		sort.Strings(methods)
+		ops[path] = methods
+	}
+	return ops, nil
+}
+
+// resolvePathItem follows a path item's $ref, of the form file.yaml#/key,
+// relative to dir
+func resolvePathItem(dir string, item any) (map[string]any, error) {
+	m, ok := item.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid path item %v", item)
+	}
+	ref, ok := m["$ref"].(string)
+	if !ok {
+		return m, nil
+	}
+	file, key, _ := strings.Cut(ref, "#/")
+	var items map[string]any
+	if err := readYAML(filepath.Join(dir, file), &items); err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return items, nil
+	}
+	resolved, ok := items[key].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unresolvable reference %s", ref)
+	}
+	return resolved, nil
+}
+
+func readYAML(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package conformance
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Report collects the results of contract test suites, to be written as
+// JUnit XML, JSON and HTML with the coverage of the specs they test, e.g. as
+// CI artifacts
+type Report struct {
+	mu      sync.Mutex
+	sources []reportSource
+}
+
+// reportSource is a suite added to a report
+type reportSource struct {
+	test     string
+	specPath string
+	openAPI  *ContractTestSuite
+	asyncAPI *EventContractTestSuite
+}
+
+// NewReport creates an empty report
+func NewReport() *Report {
+	return &Report{}
+}
+
+// AddOpenAPI adds the results suite, run by test against the OpenAPI spec at
+// specPath, records, including those recorded after the call
+func (r *Report) AddOpenAPI(test, specPath string, suite *ContractTestSuite) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = append(r.sources, reportSource{test: test, specPath: specPath, openAPI: suite})
+}
+
+// AddAsyncAPI adds the results suite, run by test against the AsyncAPI spec
+// at specPath, records, including those recorded after the call
+func (r *Report) AddAsyncAPI(test, specPath string, suite *EventContractTestSuite) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = append(r.sources, reportSource{test: test, specPath: specPath, asyncAPI: suite})
+}
+
+// Empty reports whether no suite was added
+func (r *Report) Empty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sources) == 0
+}
+
+// ReportCase is the result of a contract test
+type ReportCase struct {
+	Spec string `json:"spec"`
+	// Test is the Go test that ran it
+	Test string `json:"test"`
+	// Name is the operation or the message tested, e.g. "GET /health" or
+	// "orders/ingest OrderReceivedPayload"
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ReportSummary is a report's results and coverage, as written
+type ReportSummary struct {
+	Passed   int            `json:"passed"`
+	Failed   int            `json:"failed"`
+	Cases    []ReportCase   `json:"cases"`
+	Coverage []SpecCoverage `json:"coverage"`
+}
+
+// Summary returns the results of the suites added, and the coverage of each
+// of their specs
+func (r *Report) Summary() (*ReportSummary, error) {
+	r.mu.Lock()
+	sources := append([]reportSource(nil), r.sources...)
+	r.mu.Unlock()
+
+	s := &ReportSummary{Cases: []ReportCase{}, Coverage: []SpecCoverage{}}
+	add := func(c ReportCase) {
+		if c.Passed {
+			s.Passed++
+		} else {
+			s.Failed++
+		}
+		s.Cases = append(s.Cases, c)
+	}
+
+	// Coverage is of every suite testing a spec
+	openAPI := make(map[string][]ContractTestResult)
+	asyncAPI := make(map[string][]EventTestResult)
+	for _, src := range sources {
+		spec := specName(src.specPath)
+		if src.openAPI != nil {
+			for _, res := range src.openAPI.Results() {
+				add(ReportCase{Spec: spec, Test: src.test, Name: res.Method + " " + res.Endpoint, Passed: res.Passed, Error: res.Error})
+			}
+			openAPI[src.specPath] = append(openAPI[src.specPath], src.openAPI.Results()...)
+		}
+		if src.asyncAPI != nil {
+			for _, res := range src.asyncAPI.Results() {
+				name := res.Schema
+				if res.Channel != "" {
+					name = res.Channel + " " + name
+				}
+				add(ReportCase{Spec: spec, Test: src.test, Name: name, Passed: res.Passed, Error: res.Error})
+			}
+			asyncAPI[src.specPath] = append(asyncAPI[src.specPath], src.asyncAPI.Results()...)
+		}
+	}
+
+	for specPath, results := range openAPI {
+		c, err := OperationCoverage(specPath, results)
+		if err != nil {
+			return nil, fmt.Errorf("computing coverage of %s: %w", specPath, err)
+		}
+		s.Coverage = append(s.Coverage, c)
+	}
+	for specPath, results := range asyncAPI {
+		c, err := MessageCoverage(specPath, results)
+		if err != nil {
+			return nil, fmt.Errorf("computing coverage of %s: %w", specPath, err)
+		}
+		s.Coverage = append(s.Coverage, c)
+	}
+	sort.Slice(s.Coverage, func(i, j int) bool { return s.Coverage[i].Spec < s.Coverage[j].Spec })
+	return s, nil
+}
+
+// WriteDir writes the report to dir, created if needed, as junit.xml,
+// report.json and report.html, and the specs' coverage as coverage.json
+func (r *Report) WriteDir(dir string) error {
+	s, err := r.Summary()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating report directory: %w", err)
+	}
+
+	for name, write := range map[string]func(io.Writer) error{
+		"junit.xml":     s.WriteJUnit,
+		"report.json":   s.WriteJSON,
+		"report.html":   s.WriteHTML,
+		"coverage.json": s.writeCoverage,
+	} {
+		if err := writeFile(filepath.Join(dir, name), write); err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteJSON writes the summary as JSON
+func (s *ReportSummary) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+func (s *ReportSummary) writeCoverage(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s.Coverage)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the summary as JUnit XML, with a test suite per spec and
+// the Go tests as the test cases' classes
+func (s *ReportSummary) WriteJUnit(w io.Writer) error {
+	out := junitSuites{Name: "conformance", Tests: len(s.Cases), Failures: s.Failed}
+	index := make(map[string]int)
+	for _, c := range s.Cases {
+		i, ok := index[c.Spec]
+		if !ok {
+			i = len(out.Suites)
+			index[c.Spec] = i
+			out.Suites = append(out.Suites, junitSuite{Name: c.Spec})
+		}
+		suite := &out.Suites[i]
+		jc := junitCase{ClassName: c.Test, Name: c.Name}
+		if !c.Passed {
+			jc.Failure = &junitFailure{Message: c.Error}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, jc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(p float64) string { return fmt.Sprintf("%.0f%%", p) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Conformance report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+.passed { color: #1a7f37; }
+.failed { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>Conformance report</h1>
+<p><span class="passed">{{.Passed}} passed</span>, <span class="failed">{{.Failed}} failed</span></p>
+<h2>Spec coverage</h2>
+<table>
+<tr><th>Spec</th><th>Covered</th><th>Not covered</th></tr>
+{{range .Coverage}}<tr><td>{{.Spec}}</td><td>{{len .Covered}} ({{percent .Percent}})</td><td>{{range .Uncovered}}{{.}}<br>{{end}}</td></tr>
+{{end}}</table>
+<h2>Results</h2>
+<table>
+<tr><th>Spec</th><th>Test</th><th>Case</th><th>Result</th></tr>
+{{range .Cases}}<tr><td>{{.Spec}}</td><td>{{.Test}}</td><td>{{.Name}}</td>{{if .Passed}}<td class="passed">passed</td>{{else}}<td class="failed">{{.Error}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the summary as an HTML page
+func (s *ReportSummary) WriteHTML(w io.Writer) error {
+	return reportHTML.Execute(w, s)
+}
@@ -0,0 +1,75 @@
+package conformance_test
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/conformance"
+	"github.com/synapse/synapse/internal/handler"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/testutil/factory"
+)
+
+func TestReport(t *testing.T) {
+	h := handler.New(&infra.Infra{}, nil)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	api, err := conformance.NewContractTestSuite(openAPISpecPath)
+	require.NoError(t, err)
+	api.RunTest(context.Background(), srv.Client(), srv.URL, "GET", "/health/live", nil, 200, "")
+	api.RunTest(context.Background(), srv.Client(), srv.URL, "GET", "/api/v1/orders?limit=many", nil, 200, "")
+	events, err := conformance.NewEventContractTestSuite(asyncAPISpecPath)
+	require.NoError(t, err)
+	events.ValidateEvent("orders/ingest", "OrderReceivedPayload", factory.Order().Payload())
+
+	report := conformance.NewReport()
+	assert.True(t, report.Empty())
+	report.AddOpenAPI("TestAPI", openAPISpecPath, api)
+	report.AddAsyncAPI("TestEvents", asyncAPISpecPath, events)
+
+	summary, err := report.Summary()
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Passed)
+	assert.Equal(t, 1, summary.Failed)
+	require.Len(t, summary.Cases, 3)
+	assert.Equal(t, conformance.ReportCase{Spec: "openapi/openapi.yaml", Test: "TestAPI", Name: "GET /health/live", Passed: true}, summary.Cases[0])
+	assert.Equal(t, "expected status 200, got 400", summary.Cases[1].Error)
+
+	require.Len(t, summary.Coverage, 2)
+	assert.Equal(t, "asyncapi/asyncapi.yaml", summary.Coverage[0].Spec)
+	assert.Equal(t, []string{"OrderReceived"}, summary.Coverage[0].Covered)
+	assert.Contains(t, summary.Coverage[0].Uncovered, "OrderRouted")
+	assert.Equal(t, "openapi/openapi.yaml", summary.Coverage[1].Spec)
+	assert.Equal(t, []string{"GET /api/v1/orders", "GET /health/live"}, summary.Coverage[1].Covered)
+	assert.Contains(t, summary.Coverage[1].Uncovered, "GET /api/v1/orders/{orderId}")
+	assert.Greater(t, summary.Coverage[1].Percent, 0.0)
+
+	dir := filepath.Join(t.TempDir(), "reports")
+	require.NoError(t, report.WriteDir(dir))
+	for _, name := range []string{"report.json", "report.html", "coverage.json"} {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "junit.xml"))
+	require.NoError(t, err)
+	var junit struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Suites   []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"testsuite"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &junit))
+	assert.Equal(t, 3, junit.Tests)
+	assert.Equal(t, 1, junit.Failures)
+	assert.Len(t, junit.Suites, 2)
+}
@@ -0,0 +1,200 @@
+// Package crypto seals sensitive values with AES-256-GCM before they are
+// stored. A sealed value names the key it was sealed under, so keys can be
+// rotated: older keys stay in the Keyring to open what they sealed until it
+// is resealed under the active key.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix starts each sealed value, followed by its key ID, a colon and the
+// base64 nonce and ciphertext
+const prefix = "enc:v1:"
+
+// KeySize is the size of the keys in bytes
+const KeySize = 32
+
+// ErrUnknownKey is returned when opening a value sealed under a key the
+// Keyring doesn't hold
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Keyring holds the keys values are sealed under, by ID
+type Keyring struct {
+	keys     map[string]*key
+	activeID string
+}
+
+type key struct {
+	aead cipher.AEAD
+	// nonceKey derives the nonces of deterministic seals
+	nonceKey []byte
+}
+
+// ParseKeyring parses a key spec of the form "id:base64key,id2:base64key"
+// of 32-byte keys. Values are sealed under activeID, or under the first key
+// when it is empty; every key opens the values it sealed.
+func ParseKeyring(spec, activeID string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*key)}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid key entry %q: expected id:base64key", entry)
+		}
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decoding key %s: %w", id, err)
+		}
+		if err := k.add(id, secret); err != nil {
+			return nil, err
+		}
+		if k.activeID == "" {
+			k.activeID = id
+		}
+	}
+
+	if len(k.keys) == 0 {
+		return nil, errors.New("no encryption keys configured")
+	}
+	if activeID != "" {
+		if _, ok := k.keys[activeID]; !ok {
+			return nil, fmt.Errorf("active key %s not found", activeID)
+		}
+		k.activeID = activeID
+	}
+	return k, nil
+}
+
+// NewKeyring returns a Keyring holding the one key secret, e.g. a data key
+// made to seal one message's fields
+func NewKeyring(id string, secret []byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*key), activeID: id}
+	if err := k.add(id, secret); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (k *Keyring) add(id string, secret []byte) error {
+	if len(secret) != KeySize {
+		return fmt.Errorf("key %s must be %d bytes, got %d", id, KeySize, len(secret))
+	}
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return fmt.Errorf("key %s: %w", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("key %s: %w", id, err)
+	}
+	nonceKey := hmac.New(sha256.New, secret)
+	nonceKey.Write([]byte("synapse deterministic nonce"))
+	k.keys[id] = &key{aead: aead, nonceKey: nonceKey.Sum(nil)}
+	return nil
+}
+
+// ActiveKeyID returns the ID of the key values are sealed under
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// Seal seals plaintext under the active key with a random nonce. aad, e.g.
+// the column, must be given again to open it.
+func (k *Keyring) Seal(plaintext, aad []byte) (string, error) {
+	nonce := make([]byte, k.keys[k.activeID].aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating nonce: %w", err)
+	}
+	return k.seal(k.activeID, nonce, plaintext, aad), nil
+}
+
+// SealDeterministic seals plaintext under the active key with a nonce
+// derived from it and aad, so equal values seal alike and can be looked up.
+// It reveals which sealed values are equal, so it suits identifiers rather
+// than free text.
+func (k *Keyring) SealDeterministic(plaintext, aad []byte) string {
+	return k.sealDeterministic(k.activeID, plaintext, aad)
+}
+
+// SealedForms returns plaintext sealed deterministically under each key, to
+// look up a value that may have been sealed before the last rotation
+func (k *Keyring) SealedForms(plaintext, aad []byte) []string {
+	forms := make([]string, 0, len(k.keys))
+	forms = append(forms, k.sealDeterministic(k.activeID, plaintext, aad))
+	for id := range k.keys {
+		if id != k.activeID {
+			forms = append(forms, k.sealDeterministic(id, plaintext, aad))
+		}
+	}
+	return forms
+}
+
+func (k *Keyring) sealDeterministic(id string, plaintext, aad []byte) string {
+	mac := hmac.New(sha256.New, k.keys[id].nonceKey)
+	mac.Write(aad)
+	mac.Write([]byte{0})
+	mac.Write(plaintext)
+	return k.seal(id, mac.Sum(nil)[:k.keys[id].aead.NonceSize()], plaintext, aad)
+}
+
+func (k *Keyring) seal(id string, nonce, plaintext, aad []byte) string {
+	sealed := k.keys[id].aead.Seal(nonce, nonce, plaintext, aad)
+	return prefix + id + ":" + base64.RawURLEncoding.EncodeToString(sealed)
+}
+
+// Open returns the plaintext of a sealed value. Values that aren't sealed,
+// e.g. stored before encryption was turned on, are returned as they are.
+func (k *Keyring) Open(value string, aad []byte) ([]byte, error) {
+	if !IsSealed(value) {
+		return []byte(value), nil
+	}
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return nil, errors.New("malformed sealed value")
+	}
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding sealed value: %w", err)
+	}
+	nonceSize := key.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("malformed sealed value")
+	}
+	plaintext, err := key.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], aad)
+	if err != nil {
+		return nil, fmt.Errorf("opening value sealed under key %s: %w", id, err)
+	}
+	return plaintext, nil
+}
+
+// Stale reports whether value is to be resealed under the active key:
+// whether it isn't sealed, or is sealed under an older key
+func (k *Keyring) Stale(value string) bool {
+	return !strings.HasPrefix(value, k.ActivePrefix())
+}
+
+// ActivePrefix returns the prefix of the values sealed under the active key
+func (k *Keyring) ActivePrefix() string {
+	return prefix + k.activeID + ":"
+}
+
+// IsSealed reports whether value was sealed by a Keyring
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
@@ -0,0 +1,97 @@
+package crypto_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/crypto"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestKeyring_SealAndOpen(t *testing.T) {
+	keys, err := crypto.ParseKeyring("k1:"+testKey('a'), "")
+	require.NoError(t, err)
+	aad := []byte("orders.shipping_address")
+
+	sealed, err := keys.Seal([]byte("221B Baker Street"), aad)
+	require.NoError(t, err)
+	assert.True(t, crypto.IsSealed(sealed))
+	assert.NotContains(t, sealed, "Baker")
+	again, err := keys.Seal([]byte("221B Baker Street"), aad)
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "nonces are random")
+
+	plaintext, err := keys.Open(sealed, aad)
+	require.NoError(t, err)
+	assert.Equal(t, "221B Baker Street", string(plaintext))
+
+	_, err = keys.Open(sealed, []byte("orders.customer_id"))
+	assert.Error(t, err, "a value only opens for its own column")
+
+	plaintext, err = keys.Open("cust-1", aad)
+	require.NoError(t, err)
+	assert.Equal(t, "cust-1", string(plaintext), "values stored before encryption are kept")
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	aad := []byte("orders.customer_id")
+	old, err := crypto.ParseKeyring("k1:"+testKey('a'), "")
+	require.NoError(t, err)
+	sealed := old.SealDeterministic([]byte("cust-1"), aad)
+	assert.Equal(t, sealed, old.SealDeterministic([]byte("cust-1"), aad), "deterministic seals can be looked up")
+
+	keys, err := crypto.ParseKeyring("k1:"+testKey('a')+",k2:"+testKey('b'), "k2")
+	require.NoError(t, err)
+	assert.Equal(t, "k2", keys.ActiveKeyID())
+	assert.True(t, keys.Stale(sealed))
+	assert.True(t, keys.Stale("cust-1"))
+
+	plaintext, err := keys.Open(sealed, aad)
+	require.NoError(t, err, "older keys still open their values")
+	assert.Equal(t, "cust-1", string(plaintext))
+
+	resealed := keys.SealDeterministic(plaintext, aad)
+	assert.False(t, keys.Stale(resealed))
+	assert.Equal(t, []string{resealed, sealed}, keys.SealedForms([]byte("cust-1"), aad))
+
+	_, err = old.Open(resealed, aad)
+	assert.ErrorIs(t, err, crypto.ErrUnknownKey)
+}
+
+func TestParseKeyring_RejectsInvalidSpecs(t *testing.T) {
+	for name, spec := range map[string]string{
+		"empty":       "",
+		"no id":       ":" + testKey('a'),
+		"not base64":  "k1:%%%",
+		"short key":   "k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"no such key": "k1:" + testKey('a'),
+	} {
+		t.Run(name, func(t *testing.T) {
+			active := ""
+			if name == "no such key" {
+				active = "k9"
+			}
+			_, err := crypto.ParseKeyring(spec, active)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	keys, err := crypto.NewKeyring("data", []byte(strings.Repeat("a", crypto.KeySize)))
+	require.NoError(t, err)
+	assert.Equal(t, "data", keys.ActiveKeyID())
+	sealed, err := keys.Seal([]byte("cust-1"), []byte("customerId"))
+	require.NoError(t, err)
+	_, err = keys.Open(sealed, []byte("billingAddress"))
+	assert.Error(t, err, "the aad must match")
+
+	_, err = crypto.NewKeyring("data", []byte("short"))
+	assert.Error(t, err)
+}
@@ -89,11 +89,41 @@ func (c *Client) ListOrders(ctx context.Context) error {
 	return c.doRequest(ctx, "GET", "/api/v1/orders", nil, nil)
 }
 
+// SearchOrders Search orders
+func (c *Client) SearchOrders(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/orders/search", nil, nil)
+}
+
 // IngestOrder Ingest a new order
 func (c *Client) IngestOrder(ctx context.Context) error {
 	return c.doRequest(ctx, "POST", "/api/v1/orders", nil, nil)
 }
 
+// ImportOrders Import orders in bulk
+func (c *Client) ImportOrders(ctx context.Context) error {
+	return c.doRequest(ctx, "POST", "/api/v1/orders/import", nil, nil)
+}
+
+// GetImportJob Get import job progress
+func (c *Client) GetImportJob(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/orders/import/{jobId}", nil, nil)
+}
+
+// ExportOrders Export orders in the background
+func (c *Client) ExportOrders(ctx context.Context) error {
+	return c.doRequest(ctx, "POST", "/api/v1/orders/export", nil, nil)
+}
+
+// GetOrderExport Get export job progress
+func (c *Client) GetOrderExport(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/orders/export/{jobId}", nil, nil)
+}
+
+// DownloadOrderExport Download an export's file
+func (c *Client) DownloadOrderExport(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/orders/export/{jobId}/download", nil, nil)
+}
+
 // CancelOrder Cancel an order
 func (c *Client) CancelOrder(ctx context.Context) error {
 	return c.doRequest(ctx, "DELETE", "/api/v1/orders/{orderId}", nil, nil)
@@ -109,16 +139,56 @@ func (c *Client) GetOrderEvents(ctx context.Context) error {
 	return c.doRequest(ctx, "GET", "/api/v1/orders/{orderId}/events", nil, nil)
 }
 
+// StreamOrder Stream order status updates
+func (c *Client) StreamOrder(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/orders/{orderId}/stream", nil, nil)
+}
+
+// PurgeDLQ Purge dead letter queue items
+func (c *Client) PurgeDLQ(ctx context.Context) error {
+	return c.doRequest(ctx, "DELETE", "/api/v1/pipeline/dlq", nil, nil)
+}
+
 // ListDLQItems List dead letter queue items
 func (c *Client) ListDLQItems(ctx context.Context) error {
 	return c.doRequest(ctx, "GET", "/api/v1/pipeline/dlq", nil, nil)
 }
 
+// RetryDLQItems Retry dead letter queue items in bulk
+func (c *Client) RetryDLQItems(ctx context.Context) error {
+	return c.doRequest(ctx, "POST", "/api/v1/pipeline/dlq/retry", nil, nil)
+}
+
+// GetDLQJob Get bulk DLQ job progress
+func (c *Client) GetDLQJob(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/pipeline/dlq/jobs/{jobId}", nil, nil)
+}
+
 // RetryDLQItem Retry a DLQ item
 func (c *Client) RetryDLQItem(ctx context.Context) error {
 	return c.doRequest(ctx, "POST", "/api/v1/pipeline/dlq/{eventId}/retry", nil, nil)
 }
 
+// StreamPipelineEvents Stream pipeline events
+func (c *Client) StreamPipelineEvents(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/pipeline/events", nil, nil)
+}
+
+// GetRoutingStats Get routing destination statistics
+func (c *Client) GetRoutingStats(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/pipeline/routing/stats", nil, nil)
+}
+
+// GetPipelineStats Get pipeline statistics over a time window
+func (c *Client) GetPipelineStats(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/pipeline/stats", nil, nil)
+}
+
+// ListPipelineErrors List pipeline stage failures
+func (c *Client) ListPipelineErrors(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/pipeline/errors", nil, nil)
+}
+
 // ListPipelineStages List pipeline stages
 func (c *Client) ListPipelineStages(ctx context.Context) error {
 	return c.doRequest(ctx, "GET", "/api/v1/pipeline/stages", nil, nil)
@@ -134,6 +204,76 @@ func (c *Client) UpdatePipelineStage(ctx context.Context) error {
 	return c.doRequest(ctx, "PATCH", "/api/v1/pipeline/stages/{stageId}", nil, nil)
 }
 
+// ListAPIKeys List API keys
+func (c *Client) ListAPIKeys(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/api-keys", nil, nil)
+}
+
+// CreateAPIKey Create an API key
+func (c *Client) CreateAPIKey(ctx context.Context) error {
+	return c.doRequest(ctx, "POST", "/api/v1/api-keys", nil, nil)
+}
+
+// RevokeAPIKey Revoke an API key
+func (c *Client) RevokeAPIKey(ctx context.Context) error {
+	return c.doRequest(ctx, "DELETE", "/api/v1/api-keys/{keyId}", nil, nil)
+}
+
+// ListAuditEntries List audit log entries
+func (c *Client) ListAuditEntries(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/audit", nil, nil)
+}
+
+// ListWebhookSubscriptions List webhook subscriptions
+func (c *Client) ListWebhookSubscriptions(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/webhooks", nil, nil)
+}
+
+// CreateWebhookSubscription Create a webhook subscription
+func (c *Client) CreateWebhookSubscription(ctx context.Context) error {
+	return c.doRequest(ctx, "POST", "/api/v1/webhooks", nil, nil)
+}
+
+// DeleteWebhookSubscription Delete a webhook subscription
+func (c *Client) DeleteWebhookSubscription(ctx context.Context) error {
+	return c.doRequest(ctx, "DELETE", "/api/v1/webhooks/{subscriptionId}", nil, nil)
+}
+
+// GetWebhookSubscription Get a webhook subscription
+func (c *Client) GetWebhookSubscription(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/webhooks/{subscriptionId}", nil, nil)
+}
+
+// UpdateWebhookSubscription Update a webhook subscription
+func (c *Client) UpdateWebhookSubscription(ctx context.Context) error {
+	return c.doRequest(ctx, "PATCH", "/api/v1/webhooks/{subscriptionId}", nil, nil)
+}
+
+// ListWebhookDeliveries List webhook delivery attempts
+func (c *Client) ListWebhookDeliveries(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/webhooks/{subscriptionId}/deliveries", nil, nil)
+}
+
+// TestWebhookSubscription Send a test notification
+func (c *Client) TestWebhookSubscription(ctx context.Context) error {
+	return c.doRequest(ctx, "POST", "/api/v1/webhooks/{subscriptionId}/test", nil, nil)
+}
+
+// QueryGraphQL Run a GraphQL query
+func (c *Client) QueryGraphQL(ctx context.Context) error {
+	return c.doRequest(ctx, "POST", "/api/v1/graphql", nil, nil)
+}
+
+// GetAsyncAPISpec Get the AsyncAPI specification
+func (c *Client) GetAsyncAPISpec(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/asyncapi.yaml", nil, nil)
+}
+
+// GetAsyncAPIDocs View the AsyncAPI specification
+func (c *Client) GetAsyncAPIDocs(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/asyncapi.html", nil, nil)
+}
+
 // GetHealth Get service health
 func (c *Client) GetHealth(ctx context.Context) error {
 	return c.doRequest(ctx, "GET", "/health", nil, nil)
@@ -153,3 +293,8 @@ func (c *Client) GetReadiness(ctx context.Context) error {
 func (c *Client) GetMetrics(ctx context.Context) error {
 	return c.doRequest(ctx, "GET", "/metrics", nil, nil)
 }
+
+// GetVersion Get the running build
+func (c *Client) GetVersion(ctx context.Context) error {
+	return c.doRequest(ctx, "GET", "/api/v1/version", nil, nil)
+}
@@ -1,4 +1,6 @@
-// Code generated by synctl. DO NOT EDIT.
+// This file is maintained by hand, not generated: synctl doesn't emit the
+// route registration. Keep it in step with openapi/openapi.yaml;
+// TestOpenAPI_Routes_MatchSpec in internal/conformance fails when they differ.
 package generated
 
 import (
@@ -10,24 +12,80 @@ import (
 type ServerInterface interface {
 	// listOrders List orders
 	ListOrders(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// searchOrders Search orders
+	SearchOrders(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// ingestOrder Ingest a new order
 	IngestOrder(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// importOrders Import orders in bulk
+	ImportOrders(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// getImportJob Get import job progress
+	GetImportJob(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// exportOrders Export orders in the background
+	ExportOrders(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// getOrderExport Get export job progress
+	GetOrderExport(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// downloadOrderExport Download an export's file
+	DownloadOrderExport(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// cancelOrder Cancel an order
 	CancelOrder(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// getOrder Get order by ID
 	GetOrder(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// getOrderEvents Get order event history
 	GetOrderEvents(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// streamOrder Stream order status updates
+	StreamOrder(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// purgeDLQ Purge dead letter queue items
+	PurgeDLQ(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// listDLQItems List dead letter queue items
 	ListDLQItems(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// retryDLQItems Retry dead letter queue items in bulk
+	RetryDLQItems(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// getDLQJob Get bulk DLQ job progress
+	GetDLQJob(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// retryDLQItem Retry a DLQ item
 	RetryDLQItem(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// streamPipelineEvents Stream pipeline events
+	StreamPipelineEvents(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// getRoutingStats Get routing destination statistics
+	GetRoutingStats(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// getPipelineStats Get pipeline statistics over a time window
+	GetPipelineStats(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// listPipelineErrors List pipeline stage failures
+	ListPipelineErrors(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// listPipelineStages List pipeline stages
 	ListPipelineStages(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// getPipelineStage Get pipeline stage details
 	GetPipelineStage(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// updatePipelineStage Update pipeline stage configuration
 	UpdatePipelineStage(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// listAPIKeys List API keys
+	ListAPIKeys(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// createAPIKey Create an API key
+	CreateAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// revokeAPIKey Revoke an API key
+	RevokeAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// listAuditEntries List audit log entries
+	ListAuditEntries(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// listWebhookSubscriptions List webhook subscriptions
+	ListWebhookSubscriptions(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// createWebhookSubscription Create a webhook subscription
+	CreateWebhookSubscription(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// deleteWebhookSubscription Delete a webhook subscription
+	DeleteWebhookSubscription(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// getWebhookSubscription Get a webhook subscription
+	GetWebhookSubscription(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// updateWebhookSubscription Update a webhook subscription
+	UpdateWebhookSubscription(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// listWebhookDeliveries List webhook delivery attempts
+	ListWebhookDeliveries(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// testWebhookSubscription Send a test notification
+	TestWebhookSubscription(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// queryGraphQL Run a GraphQL query
+	QueryGraphQL(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// getAsyncAPISpec Get the AsyncAPI specification
+	GetAsyncAPISpec(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// getAsyncAPIDocs View the AsyncAPI specification
+	GetAsyncAPIDocs(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// getHealth Get service health
 	GetHealth(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// getLiveness Kubernetes liveness probe
@@ -36,29 +94,75 @@ type ServerInterface interface {
 	GetReadiness(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 	// getMetrics Prometheus metrics
 	GetMetrics(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+	// getVersion Get the running build
+	GetVersion(ctx context.Context, w http.ResponseWriter, r *http.Request) error
 }
 
 // ServerInterfaceWrapper wraps a ServerInterface with HTTP routing
 type ServerInterfaceWrapper struct {
 	Handler ServerInterface
+	// ErrorHandlerFunc writes the response for an error a handler returns.
+	// When nil, the error is written as a plain-text 500.
+	ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
 }
 
 // RegisterRoutes registers all routes with a Chi router
 func (siw *ServerInterfaceWrapper) RegisterRoutes(r Router) {
+	siw.RegisterSecuredRoutes(r)
+	siw.RegisterPublicRoutes(r)
+}
+
+// RegisterSecuredRoutes registers the routes of operations that require
+// authentication
+func (siw *ServerInterfaceWrapper) RegisterSecuredRoutes(r Router) {
 	r.Get("/api/v1/orders", siw.wrapListOrders)
+	r.Get("/api/v1/orders/search", siw.wrapSearchOrders)
 	r.Post("/api/v1/orders", siw.wrapIngestOrder)
+	r.Post("/api/v1/orders/import", siw.wrapImportOrders)
+	r.Get("/api/v1/orders/import/{jobId}", siw.wrapGetImportJob)
+	r.Post("/api/v1/orders/export", siw.wrapExportOrders)
+	r.Get("/api/v1/orders/export/{jobId}", siw.wrapGetOrderExport)
+	r.Get("/api/v1/orders/export/{jobId}/download", siw.wrapDownloadOrderExport)
 	r.Delete("/api/v1/orders/{orderId}", siw.wrapCancelOrder)
 	r.Get("/api/v1/orders/{orderId}", siw.wrapGetOrder)
 	r.Get("/api/v1/orders/{orderId}/events", siw.wrapGetOrderEvents)
+	r.Get("/api/v1/orders/{orderId}/stream", siw.wrapStreamOrder)
+	r.Delete("/api/v1/pipeline/dlq", siw.wrapPurgeDLQ)
 	r.Get("/api/v1/pipeline/dlq", siw.wrapListDLQItems)
+	r.Post("/api/v1/pipeline/dlq/retry", siw.wrapRetryDLQItems)
+	r.Get("/api/v1/pipeline/dlq/jobs/{jobId}", siw.wrapGetDLQJob)
 	r.Post("/api/v1/pipeline/dlq/{eventId}/retry", siw.wrapRetryDLQItem)
+	r.Get("/api/v1/pipeline/events", siw.wrapStreamPipelineEvents)
+	r.Get("/api/v1/pipeline/routing/stats", siw.wrapGetRoutingStats)
+	r.Get("/api/v1/pipeline/stats", siw.wrapGetPipelineStats)
+	r.Get("/api/v1/pipeline/errors", siw.wrapListPipelineErrors)
 	r.Get("/api/v1/pipeline/stages", siw.wrapListPipelineStages)
 	r.Get("/api/v1/pipeline/stages/{stageId}", siw.wrapGetPipelineStage)
 	r.Patch("/api/v1/pipeline/stages/{stageId}", siw.wrapUpdatePipelineStage)
+	r.Get("/api/v1/api-keys", siw.wrapListAPIKeys)
+	r.Post("/api/v1/api-keys", siw.wrapCreateAPIKey)
+	r.Delete("/api/v1/api-keys/{keyId}", siw.wrapRevokeAPIKey)
+	r.Get("/api/v1/audit", siw.wrapListAuditEntries)
+	r.Get("/api/v1/webhooks", siw.wrapListWebhookSubscriptions)
+	r.Post("/api/v1/webhooks", siw.wrapCreateWebhookSubscription)
+	r.Delete("/api/v1/webhooks/{subscriptionId}", siw.wrapDeleteWebhookSubscription)
+	r.Get("/api/v1/webhooks/{subscriptionId}", siw.wrapGetWebhookSubscription)
+	r.Patch("/api/v1/webhooks/{subscriptionId}", siw.wrapUpdateWebhookSubscription)
+	r.Get("/api/v1/webhooks/{subscriptionId}/deliveries", siw.wrapListWebhookDeliveries)
+	r.Post("/api/v1/webhooks/{subscriptionId}/test", siw.wrapTestWebhookSubscription)
+	r.Post("/api/v1/graphql", siw.wrapQueryGraphQL)
+}
+
+// RegisterPublicRoutes registers the routes of operations declared with
+// "security: []"
+func (siw *ServerInterfaceWrapper) RegisterPublicRoutes(r Router) {
+	r.Get("/api/v1/asyncapi.yaml", siw.wrapGetAsyncAPISpec)
+	r.Get("/api/v1/asyncapi.html", siw.wrapGetAsyncAPIDocs)
 	r.Get("/health", siw.wrapGetHealth)
 	r.Get("/health/live", siw.wrapGetLiveness)
 	r.Get("/health/ready", siw.wrapGetReadiness)
 	r.Get("/metrics", siw.wrapGetMetrics)
+	r.Get("/api/v1/version", siw.wrapGetVersion)
 }
 
 // Router interface for registering routes (compatible with Chi)
@@ -73,97 +177,308 @@ type Router interface {
 func (siw *ServerInterfaceWrapper) wrapListOrders(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.ListOrders(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapSearchOrders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.SearchOrders(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapIngestOrder(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.IngestOrder(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapImportOrders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.ImportOrders(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapGetImportJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.GetImportJob(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapExportOrders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.ExportOrders(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapGetOrderExport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.GetOrderExport(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapDownloadOrderExport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.DownloadOrderExport(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapCancelOrder(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.CancelOrder(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapGetOrder(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.GetOrder(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapGetOrderEvents(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.GetOrderEvents(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapStreamOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.StreamOrder(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapPurgeDLQ(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.PurgeDLQ(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapListDLQItems(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.ListDLQItems(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapRetryDLQItems(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.RetryDLQItems(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapGetDLQJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.GetDLQJob(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapRetryDLQItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.RetryDLQItem(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapStreamPipelineEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.StreamPipelineEvents(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapGetRoutingStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.GetRoutingStats(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapGetPipelineStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.GetPipelineStats(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapListPipelineErrors(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.ListPipelineErrors(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapListPipelineStages(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.ListPipelineStages(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapGetPipelineStage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.GetPipelineStage(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapUpdatePipelineStage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.UpdatePipelineStage(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.ListAPIKeys(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.CreateAPIKey(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.RevokeAPIKey(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.ListAuditEntries(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapListWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.ListWebhookSubscriptions(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapCreateWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.CreateWebhookSubscription(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapDeleteWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.DeleteWebhookSubscription(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapGetWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.GetWebhookSubscription(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapUpdateWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.UpdateWebhookSubscription(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.ListWebhookDeliveries(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapTestWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.TestWebhookSubscription(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapQueryGraphQL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.QueryGraphQL(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapGetAsyncAPISpec(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.GetAsyncAPISpec(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapGetAsyncAPIDocs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.GetAsyncAPIDocs(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapGetHealth(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.GetHealth(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapGetLiveness(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.GetLiveness(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapGetReadiness(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.GetReadiness(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
 	}
 }
 
 func (siw *ServerInterfaceWrapper) wrapGetMetrics(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	if err := siw.Handler.GetMetrics(ctx, w, r); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) wrapGetVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := siw.Handler.GetVersion(ctx, w, r); err != nil {
+		siw.handleError(w, r, err)
+	}
+}
+
+func (siw *ServerInterfaceWrapper) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if siw.ErrorHandlerFunc != nil {
+		siw.ErrorHandlerFunc(w, r, err)
+		return
 	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
 }
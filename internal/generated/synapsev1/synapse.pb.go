@@ -0,0 +1,2188 @@
+// Synapse gRPC API
+//
+// Mirrors the order and pipeline-stage operations of the REST API for
+// internal callers. Messages are derived from the OpenAPI schemas in
+// openapi/components/schemas; field names follow proto conventions but carry
+// the same meaning and constraints. Errors use the gRPC status codes that
+// correspond to the REST API's problem types.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: synapse/v1/synapse.proto
+
+package synapsev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Order processing status
+type OrderStatus int32
+
+const (
+	OrderStatus_ORDER_STATUS_UNSPECIFIED OrderStatus = 0
+	OrderStatus_ORDER_STATUS_ACCEPTED    OrderStatus = 1
+	OrderStatus_ORDER_STATUS_VALIDATING  OrderStatus = 2
+	OrderStatus_ORDER_STATUS_VALIDATED   OrderStatus = 3
+	OrderStatus_ORDER_STATUS_ENRICHING   OrderStatus = 4
+	OrderStatus_ORDER_STATUS_ENRICHED    OrderStatus = 5
+	OrderStatus_ORDER_STATUS_ROUTING     OrderStatus = 6
+	OrderStatus_ORDER_STATUS_ROUTED      OrderStatus = 7
+	OrderStatus_ORDER_STATUS_FAILED      OrderStatus = 8
+	OrderStatus_ORDER_STATUS_CANCELLED   OrderStatus = 9
+)
+
+// Enum value maps for OrderStatus.
+var (
+	OrderStatus_name = map[int32]string{
+		0: "ORDER_STATUS_UNSPECIFIED",
+		1: "ORDER_STATUS_ACCEPTED",
+		2: "ORDER_STATUS_VALIDATING",
+		3: "ORDER_STATUS_VALIDATED",
+		4: "ORDER_STATUS_ENRICHING",
+		5: "ORDER_STATUS_ENRICHED",
+		6: "ORDER_STATUS_ROUTING",
+		7: "ORDER_STATUS_ROUTED",
+		8: "ORDER_STATUS_FAILED",
+		9: "ORDER_STATUS_CANCELLED",
+	}
+	OrderStatus_value = map[string]int32{
+		"ORDER_STATUS_UNSPECIFIED": 0,
+		"ORDER_STATUS_ACCEPTED":    1,
+		"ORDER_STATUS_VALIDATING":  2,
+		"ORDER_STATUS_VALIDATED":   3,
+		"ORDER_STATUS_ENRICHING":   4,
+		"ORDER_STATUS_ENRICHED":    5,
+		"ORDER_STATUS_ROUTING":     6,
+		"ORDER_STATUS_ROUTED":      7,
+		"ORDER_STATUS_FAILED":      8,
+		"ORDER_STATUS_CANCELLED":   9,
+	}
+)
+
+func (x OrderStatus) Enum() *OrderStatus {
+	p := new(OrderStatus)
+	*p = x
+	return p
+}
+
+func (x OrderStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_synapse_v1_synapse_proto_enumTypes[0].Descriptor()
+}
+
+func (OrderStatus) Type() protoreflect.EnumType {
+	return &file_synapse_v1_synapse_proto_enumTypes[0]
+}
+
+func (x OrderStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderStatus.Descriptor instead.
+func (OrderStatus) EnumDescriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{0}
+}
+
+// Pipeline stage health
+type StageStatus int32
+
+const (
+	StageStatus_STAGE_STATUS_UNSPECIFIED StageStatus = 0
+	StageStatus_STAGE_STATUS_HEALTHY     StageStatus = 1
+	StageStatus_STAGE_STATUS_DEGRADED    StageStatus = 2
+	StageStatus_STAGE_STATUS_UNHEALTHY   StageStatus = 3
+	StageStatus_STAGE_STATUS_PAUSED      StageStatus = 4
+)
+
+// Enum value maps for StageStatus.
+var (
+	StageStatus_name = map[int32]string{
+		0: "STAGE_STATUS_UNSPECIFIED",
+		1: "STAGE_STATUS_HEALTHY",
+		2: "STAGE_STATUS_DEGRADED",
+		3: "STAGE_STATUS_UNHEALTHY",
+		4: "STAGE_STATUS_PAUSED",
+	}
+	StageStatus_value = map[string]int32{
+		"STAGE_STATUS_UNSPECIFIED": 0,
+		"STAGE_STATUS_HEALTHY":     1,
+		"STAGE_STATUS_DEGRADED":    2,
+		"STAGE_STATUS_UNHEALTHY":   3,
+		"STAGE_STATUS_PAUSED":      4,
+	}
+)
+
+func (x StageStatus) Enum() *StageStatus {
+	p := new(StageStatus)
+	*p = x
+	return p
+}
+
+func (x StageStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StageStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_synapse_v1_synapse_proto_enumTypes[1].Descriptor()
+}
+
+func (StageStatus) Type() protoreflect.EnumType {
+	return &file_synapse_v1_synapse_proto_enumTypes[1]
+}
+
+func (x StageStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StageStatus.Descriptor instead.
+func (StageStatus) EnumDescriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{1}
+}
+
+// Postal address
+type Address struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Street     string                 `protobuf:"bytes,1,opt,name=street,proto3" json:"street,omitempty"`
+	Street2    string                 `protobuf:"bytes,2,opt,name=street2,proto3" json:"street2,omitempty"`
+	City       string                 `protobuf:"bytes,3,opt,name=city,proto3" json:"city,omitempty"`
+	State      string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	PostalCode string                 `protobuf:"bytes,5,opt,name=postal_code,json=postalCode,proto3" json:"postal_code,omitempty"`
+	// ISO 3166-1 alpha-2 country code
+	Country       string `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Address) Reset() {
+	*x = Address{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Address) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Address) ProtoMessage() {}
+
+func (x *Address) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Address.ProtoReflect.Descriptor instead.
+func (*Address) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Address) GetStreet() string {
+	if x != nil {
+		return x.Street
+	}
+	return ""
+}
+
+func (x *Address) GetStreet2() string {
+	if x != nil {
+		return x.Street2
+	}
+	return ""
+}
+
+func (x *Address) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *Address) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Address) GetPostalCode() string {
+	if x != nil {
+		return x.PostalCode
+	}
+	return ""
+}
+
+func (x *Address) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+// Order line item
+type OrderItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sku           string                 `protobuf:"bytes,1,opt,name=sku,proto3" json:"sku,omitempty"`
+	ProductName   string                 `protobuf:"bytes,2,opt,name=product_name,json=productName,proto3" json:"product_name,omitempty"`
+	Quantity      int32                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	UnitPrice     float64                `protobuf:"fixed64,4,opt,name=unit_price,json=unitPrice,proto3" json:"unit_price,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderItem) Reset() {
+	*x = OrderItem{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderItem) ProtoMessage() {}
+
+func (x *OrderItem) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderItem.ProtoReflect.Descriptor instead.
+func (*OrderItem) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *OrderItem) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *OrderItem) GetProductName() string {
+	if x != nil {
+		return x.ProductName
+	}
+	return ""
+}
+
+func (x *OrderItem) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *OrderItem) GetUnitPrice() float64 {
+	if x != nil {
+		return x.UnitPrice
+	}
+	return 0
+}
+
+// Data added during the enrichment stage
+type OrderEnrichment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Customer      *structpb.Struct       `protobuf:"bytes,1,opt,name=customer,proto3" json:"customer,omitempty"`
+	Inventory     *structpb.Struct       `protobuf:"bytes,2,opt,name=inventory,proto3" json:"inventory,omitempty"`
+	Fraud         *structpb.Struct       `protobuf:"bytes,3,opt,name=fraud,proto3" json:"fraud,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderEnrichment) Reset() {
+	*x = OrderEnrichment{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderEnrichment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderEnrichment) ProtoMessage() {}
+
+func (x *OrderEnrichment) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderEnrichment.ProtoReflect.Descriptor instead.
+func (*OrderEnrichment) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OrderEnrichment) GetCustomer() *structpb.Struct {
+	if x != nil {
+		return x.Customer
+	}
+	return nil
+}
+
+func (x *OrderEnrichment) GetInventory() *structpb.Struct {
+	if x != nil {
+		return x.Inventory
+	}
+	return nil
+}
+
+func (x *OrderEnrichment) GetFraud() *structpb.Struct {
+	if x != nil {
+		return x.Fraud
+	}
+	return nil
+}
+
+// Routing decision
+type OrderRouting struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Destination   string                 `protobuf:"bytes,1,opt,name=destination,proto3" json:"destination,omitempty"`
+	Priority      string                 `protobuf:"bytes,2,opt,name=priority,proto3" json:"priority,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	RoutedAt      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=routed_at,json=routedAt,proto3" json:"routed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderRouting) Reset() {
+	*x = OrderRouting{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderRouting) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderRouting) ProtoMessage() {}
+
+func (x *OrderRouting) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderRouting.ProtoReflect.Descriptor instead.
+func (*OrderRouting) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *OrderRouting) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *OrderRouting) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *OrderRouting) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *OrderRouting) GetRoutedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RoutedAt
+	}
+	return nil
+}
+
+// Full order representation
+type Order struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	OrderId         string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	CustomerId      string                 `protobuf:"bytes,2,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	Status          OrderStatus            `protobuf:"varint,3,opt,name=status,proto3,enum=synapse.v1.OrderStatus" json:"status,omitempty"`
+	CurrentStage    string                 `protobuf:"bytes,4,opt,name=current_stage,json=currentStage,proto3" json:"current_stage,omitempty"`
+	Items           []*OrderItem           `protobuf:"bytes,5,rep,name=items,proto3" json:"items,omitempty"`
+	TotalAmount     float64                `protobuf:"fixed64,6,opt,name=total_amount,json=totalAmount,proto3" json:"total_amount,omitempty"`
+	Currency        string                 `protobuf:"bytes,7,opt,name=currency,proto3" json:"currency,omitempty"`
+	ShippingAddress *Address               `protobuf:"bytes,8,opt,name=shipping_address,json=shippingAddress,proto3" json:"shipping_address,omitempty"`
+	Enrichment      *OrderEnrichment       `protobuf:"bytes,9,opt,name=enrichment,proto3" json:"enrichment,omitempty"`
+	Routing         *OrderRouting          `protobuf:"bytes,10,opt,name=routing,proto3" json:"routing,omitempty"`
+	CreatedAt       *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt       *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Order) ProtoMessage() {}
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Order) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *Order) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
+}
+
+func (x *Order) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *Order) GetCurrentStage() string {
+	if x != nil {
+		return x.CurrentStage
+	}
+	return ""
+}
+
+func (x *Order) GetItems() []*OrderItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *Order) GetTotalAmount() float64 {
+	if x != nil {
+		return x.TotalAmount
+	}
+	return 0
+}
+
+func (x *Order) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Order) GetShippingAddress() *Address {
+	if x != nil {
+		return x.ShippingAddress
+	}
+	return nil
+}
+
+func (x *Order) GetEnrichment() *OrderEnrichment {
+	if x != nil {
+		return x.Enrichment
+	}
+	return nil
+}
+
+func (x *Order) GetRouting() *OrderRouting {
+	if x != nil {
+		return x.Routing
+	}
+	return nil
+}
+
+func (x *Order) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Order) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// Order as returned in lists
+type OrderSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	CustomerId    string                 `protobuf:"bytes,2,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	Status        OrderStatus            `protobuf:"varint,3,opt,name=status,proto3,enum=synapse.v1.OrderStatus" json:"status,omitempty"`
+	TotalAmount   float64                `protobuf:"fixed64,4,opt,name=total_amount,json=totalAmount,proto3" json:"total_amount,omitempty"`
+	Currency      string                 `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	ItemCount     int32                  `protobuf:"varint,6,opt,name=item_count,json=itemCount,proto3" json:"item_count,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderSummary) Reset() {
+	*x = OrderSummary{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderSummary) ProtoMessage() {}
+
+func (x *OrderSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderSummary.ProtoReflect.Descriptor instead.
+func (*OrderSummary) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *OrderSummary) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *OrderSummary) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
+}
+
+func (x *OrderSummary) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *OrderSummary) GetTotalAmount() float64 {
+	if x != nil {
+		return x.TotalAmount
+	}
+	return 0
+}
+
+func (x *OrderSummary) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *OrderSummary) GetItemCount() int32 {
+	if x != nil {
+		return x.ItemCount
+	}
+	return 0
+}
+
+func (x *OrderSummary) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type IngestOrderRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	CustomerId  string                 `protobuf:"bytes,1,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	Items       []*OrderItem           `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	TotalAmount float64                `protobuf:"fixed64,3,opt,name=total_amount,json=totalAmount,proto3" json:"total_amount,omitempty"`
+	// ISO 4217 currency code
+	Currency        string           `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	ShippingAddress *Address         `protobuf:"bytes,5,opt,name=shipping_address,json=shippingAddress,proto3" json:"shipping_address,omitempty"`
+	BillingAddress  *Address         `protobuf:"bytes,6,opt,name=billing_address,json=billingAddress,proto3" json:"billing_address,omitempty"`
+	Metadata        *structpb.Struct `protobuf:"bytes,7,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *IngestOrderRequest) Reset() {
+	*x = IngestOrderRequest{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestOrderRequest) ProtoMessage() {}
+
+func (x *IngestOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestOrderRequest.ProtoReflect.Descriptor instead.
+func (*IngestOrderRequest) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *IngestOrderRequest) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
+}
+
+func (x *IngestOrderRequest) GetItems() []*OrderItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *IngestOrderRequest) GetTotalAmount() float64 {
+	if x != nil {
+		return x.TotalAmount
+	}
+	return 0
+}
+
+func (x *IngestOrderRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *IngestOrderRequest) GetShippingAddress() *Address {
+	if x != nil {
+		return x.ShippingAddress
+	}
+	return nil
+}
+
+func (x *IngestOrderRequest) GetBillingAddress() *Address {
+	if x != nil {
+		return x.BillingAddress
+	}
+	return nil
+}
+
+func (x *IngestOrderRequest) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type IngestOrderResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Status        OrderStatus            `protobuf:"varint,2,opt,name=status,proto3,enum=synapse.v1.OrderStatus" json:"status,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestOrderResponse) Reset() {
+	*x = IngestOrderResponse{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestOrderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestOrderResponse) ProtoMessage() {}
+
+func (x *IngestOrderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestOrderResponse.ProtoReflect.Descriptor instead.
+func (*IngestOrderResponse) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *IngestOrderResponse) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *IngestOrderResponse) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *IngestOrderResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type GetOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOrderRequest) Reset() {
+	*x = GetOrderRequest{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrderRequest) ProtoMessage() {}
+
+func (x *GetOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrderRequest.ProtoReflect.Descriptor instead.
+func (*GetOrderRequest) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetOrderRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+type ListOrdersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Maximum orders to return, 1 to 100; defaults to 20
+	PageSize int32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page
+	PageToken string `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// Only orders in one of these statuses
+	Statuses   []OrderStatus `protobuf:"varint,3,rep,packed,name=statuses,proto3,enum=synapse.v1.OrderStatus" json:"statuses,omitempty"`
+	CustomerId string        `protobuf:"bytes,4,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	// Only orders routed to fulfillment, manual-review or rejected
+	Destination   string                 `protobuf:"bytes,5,opt,name=destination,proto3" json:"destination,omitempty"`
+	CreatedAfter  *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"`
+	CreatedBefore *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListOrdersRequest) Reset() {
+	*x = ListOrdersRequest{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOrdersRequest) ProtoMessage() {}
+
+func (x *ListOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOrdersRequest.ProtoReflect.Descriptor instead.
+func (*ListOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListOrdersRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListOrdersRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListOrdersRequest) GetStatuses() []OrderStatus {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+func (x *ListOrdersRequest) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
+}
+
+func (x *ListOrdersRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *ListOrdersRequest) GetCreatedAfter() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return nil
+}
+
+func (x *ListOrdersRequest) GetCreatedBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return nil
+}
+
+type ListOrdersResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Orders []*OrderSummary        `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	// Token of the next page; empty on the last page
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	// Orders matching the filter across all pages
+	TotalSize     int32 `protobuf:"varint,3,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListOrdersResponse) Reset() {
+	*x = ListOrdersResponse{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListOrdersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOrdersResponse) ProtoMessage() {}
+
+func (x *ListOrdersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOrdersResponse.ProtoReflect.Descriptor instead.
+func (*ListOrdersResponse) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListOrdersResponse) GetOrders() []*OrderSummary {
+	if x != nil {
+		return x.Orders
+	}
+	return nil
+}
+
+func (x *ListOrdersResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+func (x *ListOrdersResponse) GetTotalSize() int32 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+type CancelOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelOrderRequest) Reset() {
+	*x = CancelOrderRequest{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelOrderRequest) ProtoMessage() {}
+
+func (x *CancelOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelOrderRequest.ProtoReflect.Descriptor instead.
+func (*CancelOrderRequest) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CancelOrderRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+type CancelOrderResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	OrderId        string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Status         OrderStatus            `protobuf:"varint,2,opt,name=status,proto3,enum=synapse.v1.OrderStatus" json:"status,omitempty"`
+	PreviousStatus OrderStatus            `protobuf:"varint,3,opt,name=previous_status,json=previousStatus,proto3,enum=synapse.v1.OrderStatus" json:"previous_status,omitempty"`
+	CancelledAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=cancelled_at,json=cancelledAt,proto3" json:"cancelled_at,omitempty"`
+	Message        string                 `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CancelOrderResponse) Reset() {
+	*x = CancelOrderResponse{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelOrderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelOrderResponse) ProtoMessage() {}
+
+func (x *CancelOrderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelOrderResponse.ProtoReflect.Descriptor instead.
+func (*CancelOrderResponse) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CancelOrderResponse) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *CancelOrderResponse) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *CancelOrderResponse) GetPreviousStatus() OrderStatus {
+	if x != nil {
+		return x.PreviousStatus
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *CancelOrderResponse) GetCancelledAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CancelledAt
+	}
+	return nil
+}
+
+func (x *CancelOrderResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type WatchOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchOrderRequest) Reset() {
+	*x = WatchOrderRequest{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchOrderRequest) ProtoMessage() {}
+
+func (x *WatchOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchOrderRequest.ProtoReflect.Descriptor instead.
+func (*WatchOrderRequest) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WatchOrderRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+// Order status change, as published on orders.{orderId}.status
+type OrderStatusUpdate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Status        OrderStatus            `protobuf:"varint,2,opt,name=status,proto3,enum=synapse.v1.OrderStatus" json:"status,omitempty"`
+	Stage         string                 `protobuf:"bytes,3,opt,name=stage,proto3" json:"stage,omitempty"`
+	StageStatus   string                 `protobuf:"bytes,4,opt,name=stage_status,json=stageStatus,proto3" json:"stage_status,omitempty"`
+	EventId       string                 `protobuf:"bytes,5,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	EventType     string                 `protobuf:"bytes,6,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,8,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	Error         *structpb.Struct       `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	Metadata      *structpb.Struct       `protobuf:"bytes,10,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderStatusUpdate) Reset() {
+	*x = OrderStatusUpdate{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderStatusUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderStatusUpdate) ProtoMessage() {}
+
+func (x *OrderStatusUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderStatusUpdate.ProtoReflect.Descriptor instead.
+func (*OrderStatusUpdate) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *OrderStatusUpdate) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *OrderStatusUpdate) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *OrderStatusUpdate) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *OrderStatusUpdate) GetStageStatus() string {
+	if x != nil {
+		return x.StageStatus
+	}
+	return ""
+}
+
+func (x *OrderStatusUpdate) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *OrderStatusUpdate) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *OrderStatusUpdate) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *OrderStatusUpdate) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *OrderStatusUpdate) GetError() *structpb.Struct {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+func (x *OrderStatusUpdate) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type RetryPolicy struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	MaxAttempts       int32                  `protobuf:"varint,1,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"`
+	BackoffMs         int64                  `protobuf:"varint,2,opt,name=backoff_ms,json=backoffMs,proto3" json:"backoff_ms,omitempty"`
+	BackoffMultiplier float64                `protobuf:"fixed64,3,opt,name=backoff_multiplier,json=backoffMultiplier,proto3" json:"backoff_multiplier,omitempty"`
+	MaxBackoffMs      int64                  `protobuf:"varint,4,opt,name=max_backoff_ms,json=maxBackoffMs,proto3" json:"max_backoff_ms,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *RetryPolicy) Reset() {
+	*x = RetryPolicy{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetryPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetryPolicy) ProtoMessage() {}
+
+func (x *RetryPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetryPolicy.ProtoReflect.Descriptor instead.
+func (*RetryPolicy) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RetryPolicy) GetMaxAttempts() int32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetBackoffMs() int64 {
+	if x != nil {
+		return x.BackoffMs
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetBackoffMultiplier() float64 {
+	if x != nil {
+		return x.BackoffMultiplier
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetMaxBackoffMs() int64 {
+	if x != nil {
+		return x.MaxBackoffMs
+	}
+	return 0
+}
+
+type StageConfig struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Concurrency int32                  `protobuf:"varint,1,opt,name=concurrency,proto3" json:"concurrency,omitempty"`
+	// Go duration, e.g. "30s"
+	Timeout       string       `protobuf:"bytes,2,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	RetryPolicy   *RetryPolicy `protobuf:"bytes,3,opt,name=retry_policy,json=retryPolicy,proto3" json:"retry_policy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StageConfig) Reset() {
+	*x = StageConfig{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StageConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StageConfig) ProtoMessage() {}
+
+func (x *StageConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StageConfig.ProtoReflect.Descriptor instead.
+func (*StageConfig) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *StageConfig) GetConcurrency() int32 {
+	if x != nil {
+		return x.Concurrency
+	}
+	return 0
+}
+
+func (x *StageConfig) GetTimeout() string {
+	if x != nil {
+		return x.Timeout
+	}
+	return ""
+}
+
+func (x *StageConfig) GetRetryPolicy() *RetryPolicy {
+	if x != nil {
+		return x.RetryPolicy
+	}
+	return nil
+}
+
+type StageMetrics struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	ProcessedTotal    int64                  `protobuf:"varint,1,opt,name=processed_total,json=processedTotal,proto3" json:"processed_total,omitempty"`
+	ProcessedLastHour int64                  `protobuf:"varint,2,opt,name=processed_last_hour,json=processedLastHour,proto3" json:"processed_last_hour,omitempty"`
+	AvgLatencyMs      float64                `protobuf:"fixed64,3,opt,name=avg_latency_ms,json=avgLatencyMs,proto3" json:"avg_latency_ms,omitempty"`
+	P99LatencyMs      float64                `protobuf:"fixed64,4,opt,name=p99_latency_ms,json=p99LatencyMs,proto3" json:"p99_latency_ms,omitempty"`
+	ErrorRate         float64                `protobuf:"fixed64,5,opt,name=error_rate,json=errorRate,proto3" json:"error_rate,omitempty"`
+	QueueDepth        int64                  `protobuf:"varint,6,opt,name=queue_depth,json=queueDepth,proto3" json:"queue_depth,omitempty"`
+	// Routed orders per destination (route stage only)
+	Destinations  map[string]int64 `protobuf:"bytes,7,rep,name=destinations,proto3" json:"destinations,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StageMetrics) Reset() {
+	*x = StageMetrics{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StageMetrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StageMetrics) ProtoMessage() {}
+
+func (x *StageMetrics) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StageMetrics.ProtoReflect.Descriptor instead.
+func (*StageMetrics) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *StageMetrics) GetProcessedTotal() int64 {
+	if x != nil {
+		return x.ProcessedTotal
+	}
+	return 0
+}
+
+func (x *StageMetrics) GetProcessedLastHour() int64 {
+	if x != nil {
+		return x.ProcessedLastHour
+	}
+	return 0
+}
+
+func (x *StageMetrics) GetAvgLatencyMs() float64 {
+	if x != nil {
+		return x.AvgLatencyMs
+	}
+	return 0
+}
+
+func (x *StageMetrics) GetP99LatencyMs() float64 {
+	if x != nil {
+		return x.P99LatencyMs
+	}
+	return 0
+}
+
+func (x *StageMetrics) GetErrorRate() float64 {
+	if x != nil {
+		return x.ErrorRate
+	}
+	return 0
+}
+
+func (x *StageMetrics) GetQueueDepth() int64 {
+	if x != nil {
+		return x.QueueDepth
+	}
+	return 0
+}
+
+func (x *StageMetrics) GetDestinations() map[string]int64 {
+	if x != nil {
+		return x.Destinations
+	}
+	return nil
+}
+
+type StageError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventId       string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	ErrorType     string                 `protobuf:"bytes,2,opt,name=error_type,json=errorType,proto3" json:"error_type,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StageError) Reset() {
+	*x = StageError{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StageError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StageError) ProtoMessage() {}
+
+func (x *StageError) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StageError.ProtoReflect.Descriptor instead.
+func (*StageError) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *StageError) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *StageError) GetErrorType() string {
+	if x != nil {
+		return x.ErrorType
+	}
+	return ""
+}
+
+func (x *StageError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *StageError) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+// Pipeline stage as returned in lists
+type StageSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StageId       string                 `protobuf:"bytes,1,opt,name=stage_id,json=stageId,proto3" json:"stage_id,omitempty"`
+	Status        StageStatus            `protobuf:"varint,2,opt,name=status,proto3,enum=synapse.v1.StageStatus" json:"status,omitempty"`
+	Metrics       *StageMetrics          `protobuf:"bytes,3,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StageSummary) Reset() {
+	*x = StageSummary{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StageSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StageSummary) ProtoMessage() {}
+
+func (x *StageSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StageSummary.ProtoReflect.Descriptor instead.
+func (*StageSummary) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *StageSummary) GetStageId() string {
+	if x != nil {
+		return x.StageId
+	}
+	return ""
+}
+
+func (x *StageSummary) GetStatus() StageStatus {
+	if x != nil {
+		return x.Status
+	}
+	return StageStatus_STAGE_STATUS_UNSPECIFIED
+}
+
+func (x *StageSummary) GetMetrics() *StageMetrics {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+// Full pipeline stage representation
+type Stage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StageId       string                 `protobuf:"bytes,1,opt,name=stage_id,json=stageId,proto3" json:"stage_id,omitempty"`
+	Status        StageStatus            `protobuf:"varint,2,opt,name=status,proto3,enum=synapse.v1.StageStatus" json:"status,omitempty"`
+	Config        *StageConfig           `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	Metrics       *StageMetrics          `protobuf:"bytes,4,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	RecentErrors  []*StageError          `protobuf:"bytes,5,rep,name=recent_errors,json=recentErrors,proto3" json:"recent_errors,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Stage) Reset() {
+	*x = Stage{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stage) ProtoMessage() {}
+
+func (x *Stage) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stage.ProtoReflect.Descriptor instead.
+func (*Stage) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Stage) GetStageId() string {
+	if x != nil {
+		return x.StageId
+	}
+	return ""
+}
+
+func (x *Stage) GetStatus() StageStatus {
+	if x != nil {
+		return x.Status
+	}
+	return StageStatus_STAGE_STATUS_UNSPECIFIED
+}
+
+func (x *Stage) GetConfig() *StageConfig {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *Stage) GetMetrics() *StageMetrics {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+func (x *Stage) GetRecentErrors() []*StageError {
+	if x != nil {
+		return x.RecentErrors
+	}
+	return nil
+}
+
+func (x *Stage) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListStagesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListStagesRequest) Reset() {
+	*x = ListStagesRequest{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListStagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStagesRequest) ProtoMessage() {}
+
+func (x *ListStagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStagesRequest.ProtoReflect.Descriptor instead.
+func (*ListStagesRequest) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{21}
+}
+
+type ListStagesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stages        []*StageSummary        `protobuf:"bytes,1,rep,name=stages,proto3" json:"stages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListStagesResponse) Reset() {
+	*x = ListStagesResponse{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListStagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStagesResponse) ProtoMessage() {}
+
+func (x *ListStagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStagesResponse.ProtoReflect.Descriptor instead.
+func (*ListStagesResponse) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ListStagesResponse) GetStages() []*StageSummary {
+	if x != nil {
+		return x.Stages
+	}
+	return nil
+}
+
+type GetStageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StageId       string                 `protobuf:"bytes,1,opt,name=stage_id,json=stageId,proto3" json:"stage_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStageRequest) Reset() {
+	*x = GetStageRequest{}
+	mi := &file_synapse_v1_synapse_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStageRequest) ProtoMessage() {}
+
+func (x *GetStageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_synapse_v1_synapse_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStageRequest.ProtoReflect.Descriptor instead.
+func (*GetStageRequest) Descriptor() ([]byte, []int) {
+	return file_synapse_v1_synapse_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GetStageRequest) GetStageId() string {
+	if x != nil {
+		return x.StageId
+	}
+	return ""
+}
+
+var File_synapse_v1_synapse_proto protoreflect.FileDescriptor
+
+const file_synapse_v1_synapse_proto_rawDesc = "" +
+	"\n" +
+	"\x18synapse/v1/synapse.proto\x12\n" +
+	"synapse.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa0\x01\n" +
+	"\aAddress\x12\x16\n" +
+	"\x06street\x18\x01 \x01(\tR\x06street\x12\x18\n" +
+	"\astreet2\x18\x02 \x01(\tR\astreet2\x12\x12\n" +
+	"\x04city\x18\x03 \x01(\tR\x04city\x12\x14\n" +
+	"\x05state\x18\x04 \x01(\tR\x05state\x12\x1f\n" +
+	"\vpostal_code\x18\x05 \x01(\tR\n" +
+	"postalCode\x12\x18\n" +
+	"\acountry\x18\x06 \x01(\tR\acountry\"{\n" +
+	"\tOrderItem\x12\x10\n" +
+	"\x03sku\x18\x01 \x01(\tR\x03sku\x12!\n" +
+	"\fproduct_name\x18\x02 \x01(\tR\vproductName\x12\x1a\n" +
+	"\bquantity\x18\x03 \x01(\x05R\bquantity\x12\x1d\n" +
+	"\n" +
+	"unit_price\x18\x04 \x01(\x01R\tunitPrice\"\xac\x01\n" +
+	"\x0fOrderEnrichment\x123\n" +
+	"\bcustomer\x18\x01 \x01(\v2\x17.google.protobuf.StructR\bcustomer\x125\n" +
+	"\tinventory\x18\x02 \x01(\v2\x17.google.protobuf.StructR\tinventory\x12-\n" +
+	"\x05fraud\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x05fraud\"\x9d\x01\n" +
+	"\fOrderRouting\x12 \n" +
+	"\vdestination\x18\x01 \x01(\tR\vdestination\x12\x1a\n" +
+	"\bpriority\x18\x02 \x01(\tR\bpriority\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x127\n" +
+	"\trouted_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\broutedAt\"\xac\x04\n" +
+	"\x05Order\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12\x1f\n" +
+	"\vcustomer_id\x18\x02 \x01(\tR\n" +
+	"customerId\x12/\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x17.synapse.v1.OrderStatusR\x06status\x12#\n" +
+	"\rcurrent_stage\x18\x04 \x01(\tR\fcurrentStage\x12+\n" +
+	"\x05items\x18\x05 \x03(\v2\x15.synapse.v1.OrderItemR\x05items\x12!\n" +
+	"\ftotal_amount\x18\x06 \x01(\x01R\vtotalAmount\x12\x1a\n" +
+	"\bcurrency\x18\a \x01(\tR\bcurrency\x12>\n" +
+	"\x10shipping_address\x18\b \x01(\v2\x13.synapse.v1.AddressR\x0fshippingAddress\x12;\n" +
+	"\n" +
+	"enrichment\x18\t \x01(\v2\x1b.synapse.v1.OrderEnrichmentR\n" +
+	"enrichment\x122\n" +
+	"\arouting\x18\n" +
+	" \x01(\v2\x18.synapse.v1.OrderRoutingR\arouting\x129\n" +
+	"\n" +
+	"created_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\x94\x02\n" +
+	"\fOrderSummary\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12\x1f\n" +
+	"\vcustomer_id\x18\x02 \x01(\tR\n" +
+	"customerId\x12/\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x17.synapse.v1.OrderStatusR\x06status\x12!\n" +
+	"\ftotal_amount\x18\x04 \x01(\x01R\vtotalAmount\x12\x1a\n" +
+	"\bcurrency\x18\x05 \x01(\tR\bcurrency\x12\x1d\n" +
+	"\n" +
+	"item_count\x18\x06 \x01(\x05R\titemCount\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xd4\x02\n" +
+	"\x12IngestOrderRequest\x12\x1f\n" +
+	"\vcustomer_id\x18\x01 \x01(\tR\n" +
+	"customerId\x12+\n" +
+	"\x05items\x18\x02 \x03(\v2\x15.synapse.v1.OrderItemR\x05items\x12!\n" +
+	"\ftotal_amount\x18\x03 \x01(\x01R\vtotalAmount\x12\x1a\n" +
+	"\bcurrency\x18\x04 \x01(\tR\bcurrency\x12>\n" +
+	"\x10shipping_address\x18\x05 \x01(\v2\x13.synapse.v1.AddressR\x0fshippingAddress\x12<\n" +
+	"\x0fbilling_address\x18\x06 \x01(\v2\x13.synapse.v1.AddressR\x0ebillingAddress\x123\n" +
+	"\bmetadata\x18\a \x01(\v2\x17.google.protobuf.StructR\bmetadata\"{\n" +
+	"\x13IngestOrderResponse\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12/\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x17.synapse.v1.OrderStatusR\x06status\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\",\n" +
+	"\x0fGetOrderRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\"\xcb\x02\n" +
+	"\x11ListOrdersRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x123\n" +
+	"\bstatuses\x18\x03 \x03(\x0e2\x17.synapse.v1.OrderStatusR\bstatuses\x12\x1f\n" +
+	"\vcustomer_id\x18\x04 \x01(\tR\n" +
+	"customerId\x12 \n" +
+	"\vdestination\x18\x05 \x01(\tR\vdestination\x12?\n" +
+	"\rcreated_after\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\fcreatedAfter\x12A\n" +
+	"\x0ecreated_before\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\rcreatedBefore\"\x8d\x01\n" +
+	"\x12ListOrdersResponse\x120\n" +
+	"\x06orders\x18\x01 \x03(\v2\x18.synapse.v1.OrderSummaryR\x06orders\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x03 \x01(\x05R\ttotalSize\"/\n" +
+	"\x12CancelOrderRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\"\xfc\x01\n" +
+	"\x13CancelOrderResponse\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12/\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x17.synapse.v1.OrderStatusR\x06status\x12@\n" +
+	"\x0fprevious_status\x18\x03 \x01(\x0e2\x17.synapse.v1.OrderStatusR\x0epreviousStatus\x12=\n" +
+	"\fcancelled_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\vcancelledAt\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\".\n" +
+	"\x11WatchOrderRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\"\x91\x03\n" +
+	"\x11OrderStatusUpdate\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12/\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x17.synapse.v1.OrderStatusR\x06status\x12\x14\n" +
+	"\x05stage\x18\x03 \x01(\tR\x05stage\x12!\n" +
+	"\fstage_status\x18\x04 \x01(\tR\vstageStatus\x12\x19\n" +
+	"\bevent_id\x18\x05 \x01(\tR\aeventId\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x06 \x01(\tR\teventType\x128\n" +
+	"\ttimestamp\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1f\n" +
+	"\vduration_ms\x18\b \x01(\x03R\n" +
+	"durationMs\x12-\n" +
+	"\x05error\x18\t \x01(\v2\x17.google.protobuf.StructR\x05error\x123\n" +
+	"\bmetadata\x18\n" +
+	" \x01(\v2\x17.google.protobuf.StructR\bmetadata\"\xa4\x01\n" +
+	"\vRetryPolicy\x12!\n" +
+	"\fmax_attempts\x18\x01 \x01(\x05R\vmaxAttempts\x12\x1d\n" +
+	"\n" +
+	"backoff_ms\x18\x02 \x01(\x03R\tbackoffMs\x12-\n" +
+	"\x12backoff_multiplier\x18\x03 \x01(\x01R\x11backoffMultiplier\x12$\n" +
+	"\x0emax_backoff_ms\x18\x04 \x01(\x03R\fmaxBackoffMs\"\x85\x01\n" +
+	"\vStageConfig\x12 \n" +
+	"\vconcurrency\x18\x01 \x01(\x05R\vconcurrency\x12\x18\n" +
+	"\atimeout\x18\x02 \x01(\tR\atimeout\x12:\n" +
+	"\fretry_policy\x18\x03 \x01(\v2\x17.synapse.v1.RetryPolicyR\vretryPolicy\"\x84\x03\n" +
+	"\fStageMetrics\x12'\n" +
+	"\x0fprocessed_total\x18\x01 \x01(\x03R\x0eprocessedTotal\x12.\n" +
+	"\x13processed_last_hour\x18\x02 \x01(\x03R\x11processedLastHour\x12$\n" +
+	"\x0eavg_latency_ms\x18\x03 \x01(\x01R\favgLatencyMs\x12$\n" +
+	"\x0ep99_latency_ms\x18\x04 \x01(\x01R\fp99LatencyMs\x12\x1d\n" +
+	"\n" +
+	"error_rate\x18\x05 \x01(\x01R\terrorRate\x12\x1f\n" +
+	"\vqueue_depth\x18\x06 \x01(\x03R\n" +
+	"queueDepth\x12N\n" +
+	"\fdestinations\x18\a \x03(\v2*.synapse.v1.StageMetrics.DestinationsEntryR\fdestinations\x1a?\n" +
+	"\x11DestinationsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x9a\x01\n" +
+	"\n" +
+	"StageError\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12\x1d\n" +
+	"\n" +
+	"error_type\x18\x02 \x01(\tR\terrorType\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x128\n" +
+	"\ttimestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"\x8e\x01\n" +
+	"\fStageSummary\x12\x19\n" +
+	"\bstage_id\x18\x01 \x01(\tR\astageId\x12/\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x17.synapse.v1.StageStatusR\x06status\x122\n" +
+	"\ametrics\x18\x03 \x01(\v2\x18.synapse.v1.StageMetricsR\ametrics\"\xb0\x02\n" +
+	"\x05Stage\x12\x19\n" +
+	"\bstage_id\x18\x01 \x01(\tR\astageId\x12/\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x17.synapse.v1.StageStatusR\x06status\x12/\n" +
+	"\x06config\x18\x03 \x01(\v2\x17.synapse.v1.StageConfigR\x06config\x122\n" +
+	"\ametrics\x18\x04 \x01(\v2\x18.synapse.v1.StageMetricsR\ametrics\x12;\n" +
+	"\rrecent_errors\x18\x05 \x03(\v2\x16.synapse.v1.StageErrorR\frecentErrors\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\x13\n" +
+	"\x11ListStagesRequest\"F\n" +
+	"\x12ListStagesResponse\x120\n" +
+	"\x06stages\x18\x01 \x03(\v2\x18.synapse.v1.StageSummaryR\x06stages\",\n" +
+	"\x0fGetStageRequest\x12\x19\n" +
+	"\bstage_id\x18\x01 \x01(\tR\astageId*\x9e\x02\n" +
+	"\vOrderStatus\x12\x1c\n" +
+	"\x18ORDER_STATUS_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15ORDER_STATUS_ACCEPTED\x10\x01\x12\x1b\n" +
+	"\x17ORDER_STATUS_VALIDATING\x10\x02\x12\x1a\n" +
+	"\x16ORDER_STATUS_VALIDATED\x10\x03\x12\x1a\n" +
+	"\x16ORDER_STATUS_ENRICHING\x10\x04\x12\x19\n" +
+	"\x15ORDER_STATUS_ENRICHED\x10\x05\x12\x18\n" +
+	"\x14ORDER_STATUS_ROUTING\x10\x06\x12\x17\n" +
+	"\x13ORDER_STATUS_ROUTED\x10\a\x12\x17\n" +
+	"\x13ORDER_STATUS_FAILED\x10\b\x12\x1a\n" +
+	"\x16ORDER_STATUS_CANCELLED\x10\t*\x95\x01\n" +
+	"\vStageStatus\x12\x1c\n" +
+	"\x18STAGE_STATUS_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14STAGE_STATUS_HEALTHY\x10\x01\x12\x19\n" +
+	"\x15STAGE_STATUS_DEGRADED\x10\x02\x12\x1a\n" +
+	"\x16STAGE_STATUS_UNHEALTHY\x10\x03\x12\x17\n" +
+	"\x13STAGE_STATUS_PAUSED\x10\x042\x85\x03\n" +
+	"\fOrderService\x12N\n" +
+	"\vIngestOrder\x12\x1e.synapse.v1.IngestOrderRequest\x1a\x1f.synapse.v1.IngestOrderResponse\x12:\n" +
+	"\bGetOrder\x12\x1b.synapse.v1.GetOrderRequest\x1a\x11.synapse.v1.Order\x12K\n" +
+	"\n" +
+	"ListOrders\x12\x1d.synapse.v1.ListOrdersRequest\x1a\x1e.synapse.v1.ListOrdersResponse\x12N\n" +
+	"\vCancelOrder\x12\x1e.synapse.v1.CancelOrderRequest\x1a\x1f.synapse.v1.CancelOrderResponse\x12L\n" +
+	"\n" +
+	"WatchOrder\x12\x1d.synapse.v1.WatchOrderRequest\x1a\x1d.synapse.v1.OrderStatusUpdate0\x012\x9a\x01\n" +
+	"\x0fPipelineService\x12K\n" +
+	"\n" +
+	"ListStages\x12\x1d.synapse.v1.ListStagesRequest\x1a\x1e.synapse.v1.ListStagesResponse\x12:\n" +
+	"\bGetStage\x12\x1b.synapse.v1.GetStageRequest\x1a\x11.synapse.v1.StageBCZAgithub.com/synapse/synapse/internal/generated/synapsev1;synapsev1b\x06proto3"
+
+var (
+	file_synapse_v1_synapse_proto_rawDescOnce sync.Once
+	file_synapse_v1_synapse_proto_rawDescData []byte
+)
+
+func file_synapse_v1_synapse_proto_rawDescGZIP() []byte {
+	file_synapse_v1_synapse_proto_rawDescOnce.Do(func() {
+		file_synapse_v1_synapse_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_synapse_v1_synapse_proto_rawDesc), len(file_synapse_v1_synapse_proto_rawDesc)))
+	})
+	return file_synapse_v1_synapse_proto_rawDescData
+}
+
+var file_synapse_v1_synapse_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_synapse_v1_synapse_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
+var file_synapse_v1_synapse_proto_goTypes = []any{
+	(OrderStatus)(0),              // 0: synapse.v1.OrderStatus
+	(StageStatus)(0),              // 1: synapse.v1.StageStatus
+	(*Address)(nil),               // 2: synapse.v1.Address
+	(*OrderItem)(nil),             // 3: synapse.v1.OrderItem
+	(*OrderEnrichment)(nil),       // 4: synapse.v1.OrderEnrichment
+	(*OrderRouting)(nil),          // 5: synapse.v1.OrderRouting
+	(*Order)(nil),                 // 6: synapse.v1.Order
+	(*OrderSummary)(nil),          // 7: synapse.v1.OrderSummary
+	(*IngestOrderRequest)(nil),    // 8: synapse.v1.IngestOrderRequest
+	(*IngestOrderResponse)(nil),   // 9: synapse.v1.IngestOrderResponse
+	(*GetOrderRequest)(nil),       // 10: synapse.v1.GetOrderRequest
+	(*ListOrdersRequest)(nil),     // 11: synapse.v1.ListOrdersRequest
+	(*ListOrdersResponse)(nil),    // 12: synapse.v1.ListOrdersResponse
+	(*CancelOrderRequest)(nil),    // 13: synapse.v1.CancelOrderRequest
+	(*CancelOrderResponse)(nil),   // 14: synapse.v1.CancelOrderResponse
+	(*WatchOrderRequest)(nil),     // 15: synapse.v1.WatchOrderRequest
+	(*OrderStatusUpdate)(nil),     // 16: synapse.v1.OrderStatusUpdate
+	(*RetryPolicy)(nil),           // 17: synapse.v1.RetryPolicy
+	(*StageConfig)(nil),           // 18: synapse.v1.StageConfig
+	(*StageMetrics)(nil),          // 19: synapse.v1.StageMetrics
+	(*StageError)(nil),            // 20: synapse.v1.StageError
+	(*StageSummary)(nil),          // 21: synapse.v1.StageSummary
+	(*Stage)(nil),                 // 22: synapse.v1.Stage
+	(*ListStagesRequest)(nil),     // 23: synapse.v1.ListStagesRequest
+	(*ListStagesResponse)(nil),    // 24: synapse.v1.ListStagesResponse
+	(*GetStageRequest)(nil),       // 25: synapse.v1.GetStageRequest
+	nil,                           // 26: synapse.v1.StageMetrics.DestinationsEntry
+	(*structpb.Struct)(nil),       // 27: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil), // 28: google.protobuf.Timestamp
+}
+var file_synapse_v1_synapse_proto_depIdxs = []int32{
+	27, // 0: synapse.v1.OrderEnrichment.customer:type_name -> google.protobuf.Struct
+	27, // 1: synapse.v1.OrderEnrichment.inventory:type_name -> google.protobuf.Struct
+	27, // 2: synapse.v1.OrderEnrichment.fraud:type_name -> google.protobuf.Struct
+	28, // 3: synapse.v1.OrderRouting.routed_at:type_name -> google.protobuf.Timestamp
+	0,  // 4: synapse.v1.Order.status:type_name -> synapse.v1.OrderStatus
+	3,  // 5: synapse.v1.Order.items:type_name -> synapse.v1.OrderItem
+	2,  // 6: synapse.v1.Order.shipping_address:type_name -> synapse.v1.Address
+	4,  // 7: synapse.v1.Order.enrichment:type_name -> synapse.v1.OrderEnrichment
+	5,  // 8: synapse.v1.Order.routing:type_name -> synapse.v1.OrderRouting
+	28, // 9: synapse.v1.Order.created_at:type_name -> google.protobuf.Timestamp
+	28, // 10: synapse.v1.Order.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 11: synapse.v1.OrderSummary.status:type_name -> synapse.v1.OrderStatus
+	28, // 12: synapse.v1.OrderSummary.created_at:type_name -> google.protobuf.Timestamp
+	3,  // 13: synapse.v1.IngestOrderRequest.items:type_name -> synapse.v1.OrderItem
+	2,  // 14: synapse.v1.IngestOrderRequest.shipping_address:type_name -> synapse.v1.Address
+	2,  // 15: synapse.v1.IngestOrderRequest.billing_address:type_name -> synapse.v1.Address
+	27, // 16: synapse.v1.IngestOrderRequest.metadata:type_name -> google.protobuf.Struct
+	0,  // 17: synapse.v1.IngestOrderResponse.status:type_name -> synapse.v1.OrderStatus
+	0,  // 18: synapse.v1.ListOrdersRequest.statuses:type_name -> synapse.v1.OrderStatus
+	28, // 19: synapse.v1.ListOrdersRequest.created_after:type_name -> google.protobuf.Timestamp
+	28, // 20: synapse.v1.ListOrdersRequest.created_before:type_name -> google.protobuf.Timestamp
+	7,  // 21: synapse.v1.ListOrdersResponse.orders:type_name -> synapse.v1.OrderSummary
+	0,  // 22: synapse.v1.CancelOrderResponse.status:type_name -> synapse.v1.OrderStatus
+	0,  // 23: synapse.v1.CancelOrderResponse.previous_status:type_name -> synapse.v1.OrderStatus
+	28, // 24: synapse.v1.CancelOrderResponse.cancelled_at:type_name -> google.protobuf.Timestamp
+	0,  // 25: synapse.v1.OrderStatusUpdate.status:type_name -> synapse.v1.OrderStatus
+	28, // 26: synapse.v1.OrderStatusUpdate.timestamp:type_name -> google.protobuf.Timestamp
+	27, // 27: synapse.v1.OrderStatusUpdate.error:type_name -> google.protobuf.Struct
+	27, // 28: synapse.v1.OrderStatusUpdate.metadata:type_name -> google.protobuf.Struct
+	17, // 29: synapse.v1.StageConfig.retry_policy:type_name -> synapse.v1.RetryPolicy
+	26, // 30: synapse.v1.StageMetrics.destinations:type_name -> synapse.v1.StageMetrics.DestinationsEntry
+	28, // 31: synapse.v1.StageError.timestamp:type_name -> google.protobuf.Timestamp
+	1,  // 32: synapse.v1.StageSummary.status:type_name -> synapse.v1.StageStatus
+	19, // 33: synapse.v1.StageSummary.metrics:type_name -> synapse.v1.StageMetrics
+	1,  // 34: synapse.v1.Stage.status:type_name -> synapse.v1.StageStatus
+	18, // 35: synapse.v1.Stage.config:type_name -> synapse.v1.StageConfig
+	19, // 36: synapse.v1.Stage.metrics:type_name -> synapse.v1.StageMetrics
+	20, // 37: synapse.v1.Stage.recent_errors:type_name -> synapse.v1.StageError
+	28, // 38: synapse.v1.Stage.updated_at:type_name -> google.protobuf.Timestamp
+	21, // 39: synapse.v1.ListStagesResponse.stages:type_name -> synapse.v1.StageSummary
+	8,  // 40: synapse.v1.OrderService.IngestOrder:input_type -> synapse.v1.IngestOrderRequest
+	10, // 41: synapse.v1.OrderService.GetOrder:input_type -> synapse.v1.GetOrderRequest
+	11, // 42: synapse.v1.OrderService.ListOrders:input_type -> synapse.v1.ListOrdersRequest
+	13, // 43: synapse.v1.OrderService.CancelOrder:input_type -> synapse.v1.CancelOrderRequest
+	15, // 44: synapse.v1.OrderService.WatchOrder:input_type -> synapse.v1.WatchOrderRequest
+	23, // 45: synapse.v1.PipelineService.ListStages:input_type -> synapse.v1.ListStagesRequest
+	25, // 46: synapse.v1.PipelineService.GetStage:input_type -> synapse.v1.GetStageRequest
+	9,  // 47: synapse.v1.OrderService.IngestOrder:output_type -> synapse.v1.IngestOrderResponse
+	6,  // 48: synapse.v1.OrderService.GetOrder:output_type -> synapse.v1.Order
+	12, // 49: synapse.v1.OrderService.ListOrders:output_type -> synapse.v1.ListOrdersResponse
+	14, // 50: synapse.v1.OrderService.CancelOrder:output_type -> synapse.v1.CancelOrderResponse
+	16, // 51: synapse.v1.OrderService.WatchOrder:output_type -> synapse.v1.OrderStatusUpdate
+	24, // 52: synapse.v1.PipelineService.ListStages:output_type -> synapse.v1.ListStagesResponse
+	22, // 53: synapse.v1.PipelineService.GetStage:output_type -> synapse.v1.Stage
+	47, // [47:54] is the sub-list for method output_type
+	40, // [40:47] is the sub-list for method input_type
+	40, // [40:40] is the sub-list for extension type_name
+	40, // [40:40] is the sub-list for extension extendee
+	0,  // [0:40] is the sub-list for field type_name
+}
+
+func init() { file_synapse_v1_synapse_proto_init() }
+func file_synapse_v1_synapse_proto_init() {
+	if File_synapse_v1_synapse_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_synapse_v1_synapse_proto_rawDesc), len(file_synapse_v1_synapse_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   25,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_synapse_v1_synapse_proto_goTypes,
+		DependencyIndexes: file_synapse_v1_synapse_proto_depIdxs,
+		EnumInfos:         file_synapse_v1_synapse_proto_enumTypes,
+		MessageInfos:      file_synapse_v1_synapse_proto_msgTypes,
+	}.Build()
+	File_synapse_v1_synapse_proto = out.File
+	file_synapse_v1_synapse_proto_goTypes = nil
+	file_synapse_v1_synapse_proto_depIdxs = nil
+}
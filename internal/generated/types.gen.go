@@ -8,6 +8,37 @@ import (
 // Ensure time is used
 var _ = time.Time{}
 
+// APIKey represents the APIKey type
+type APIKey struct {
+	CreatedAt  time.Time  `json:"createdAt"`
+	KeyId      string     `json:"keyId"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+}
+
+// APIKeyCreateRequest represents the APIKeyCreateRequest type
+type APIKeyCreateRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// APIKeyCreatedResponse represents the APIKeyCreatedResponse type
+type APIKeyCreatedResponse struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Key       string    `json:"key"`
+	KeyId     string    `json:"keyId"`
+	Name      string    `json:"name"`
+	Prefix    string    `json:"prefix"`
+	Scopes    []string  `json:"scopes"`
+}
+
+// APIKeyListResponse represents the APIKeyListResponse type
+type APIKeyListResponse struct {
+	Keys []APIKey `json:"keys"`
+}
+
 // Address represents the Address type
 type Address struct {
 	City       string `json:"city"`
@@ -18,9 +49,48 @@ type Address struct {
 	Street2    string `json:"street2,omitempty"`
 }
 
+// AuditActor represents the AuditActor type
+type AuditActor struct {
+	Id     string `json:"id"`
+	Method string `json:"method"`
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// AuditEntry represents the AuditEntry type
+type AuditEntry struct {
+	Actor      *AuditActor       `json:"actor,omitempty"`
+	AuditId    string            `json:"auditId"`
+	BodyBytes  int64             `json:"bodyBytes"`
+	DurationMs int               `json:"durationMs"`
+	Method     string            `json:"method"`
+	OccurredAt time.Time         `json:"occurredAt"`
+	Outcome    AuditOutcome      `json:"outcome"`
+	Params     map[string]string `json:"params"`
+	Path       string            `json:"path"`
+	Query      string            `json:"query,omitempty"`
+	RequestId  string            `json:"requestId,omitempty"`
+	Route      string            `json:"route"`
+	StatusCode int               `json:"statusCode"`
+}
+
+// AuditListResponse represents the AuditListResponse type
+type AuditListResponse struct {
+	Entries    []AuditEntry `json:"entries"`
+	Pagination Pagination   `json:"pagination"`
+}
+
+// AuditOutcome represents an enum type
+type AuditOutcome string
+
+const (
+	AuditOutcomeSuccess AuditOutcome = "success"
+	AuditOutcomeFailure AuditOutcome = "failure"
+)
+
 // CommonHeaders represents the CommonHeaders type
 type CommonHeaders struct {
 	CorrelationId string    `json:"correlationId"`
+	SchemaVersion int       `json:"schemaVersion,omitempty"`
 	Source        string    `json:"source"`
 	SpanId        string    `json:"spanId,omitempty"`
 	Timestamp     time.Time `json:"timestamp"`
@@ -29,10 +99,13 @@ type CommonHeaders struct {
 
 // ComponentHealth represents the ComponentHealth type
 type ComponentHealth struct {
-	Details   map[string]any `json:"details,omitempty"`
-	Error     string         `json:"error,omitempty"`
-	LatencyMs float64        `json:"latencyMs,omitempty"`
-	Status    string         `json:"status"`
+	Critical      bool           `json:"critical"`
+	Details       map[string]any `json:"details,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	LastCheckedAt *time.Time     `json:"lastCheckedAt,omitempty"`
+	LastSuccessAt *time.Time     `json:"lastSuccessAt,omitempty"`
+	LatencyMs     float64        `json:"latencyMs,omitempty"`
+	Status        string         `json:"status"`
 }
 
 // CustomerData represents the CustomerData type
@@ -43,22 +116,80 @@ type CustomerData struct {
 	Tier          string  `json:"tier,omitempty"`
 }
 
+// DLQFilter represents the DLQFilter type
+type DLQFilter struct {
+	ErrorType    []string   `json:"errorType,omitempty"`
+	FailedAfter  *time.Time `json:"failedAfter,omitempty"`
+	FailedBefore *time.Time `json:"failedBefore,omitempty"`
+	FailedStage  []string   `json:"failedStage,omitempty"`
+}
+
 // DLQItem represents the DLQItem type
 type DLQItem struct {
-	CanRetry    bool           `json:"canRetry,omitempty"`
-	Error       map[string]any `json:"error"`
-	EventId     string         `json:"eventId"`
-	FailedAt    time.Time      `json:"failedAt"`
-	FailedStage string         `json:"failedStage"`
-	LastRetryAt time.Time      `json:"lastRetryAt,omitempty"`
-	OrderId     string         `json:"orderId"`
-	RetryCount  int            `json:"retryCount"`
+	CanRetry       bool           `json:"canRetry,omitempty"`
+	Error          map[string]any `json:"error"`
+	EventId        string         `json:"eventId"`
+	FailedAt       time.Time      `json:"failedAt"`
+	FailedStage    string         `json:"failedStage"`
+	LastRetryAt    *time.Time     `json:"lastRetryAt,omitempty"`
+	OrderId        string         `json:"orderId"`
+	PayloadPreview string         `json:"payloadPreview,omitempty"`
+	RetryCount     int            `json:"retryCount"`
 }
 
 // DLQListResponse represents the DLQListResponse type
 type DLQListResponse struct {
-	Items      []DLQItem      `json:"items"`
-	Pagination map[string]any `json:"pagination"`
+	Items      []DLQItem  `json:"items"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// DLQJob represents the DLQJob type
+type DLQJob struct {
+	Action      DLQJobAction `json:"action"`
+	CompletedAt *time.Time   `json:"completedAt,omitempty"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	Error       string       `json:"error,omitempty"`
+	Failed      int          `json:"failed"`
+	Filter      DLQFilter    `json:"filter"`
+	JobId       string       `json:"jobId"`
+	Matched     int          `json:"matched"`
+	Processed   int          `json:"processed"`
+	Skipped     int          `json:"skipped"`
+	Status      DLQJobStatus `json:"status"`
+	Succeeded   int          `json:"succeeded"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+}
+
+// DLQJobAction represents an enum type
+type DLQJobAction string
+
+const (
+	DLQJobActionRetry DLQJobAction = "retry"
+	DLQJobActionPurge DLQJobAction = "purge"
+)
+
+// DLQJobStatus represents an enum type
+type DLQJobStatus string
+
+const (
+	DLQJobStatusProcessing DLQJobStatus = "processing"
+	DLQJobStatusCompleted  DLQJobStatus = "completed"
+	DLQJobStatusFailed     DLQJobStatus = "failed"
+)
+
+// DLQRetryRequest represents the DLQRetryRequest type
+type DLQRetryRequest struct {
+	FromStage string `json:"fromStage,omitempty"`
+	Priority  string `json:"priority,omitempty"`
+}
+
+// DLQRetryResponse represents the DLQRetryResponse type
+type DLQRetryResponse struct {
+	EventId   string `json:"eventId"`
+	FromStage string `json:"fromStage,omitempty"`
+	Message   string `json:"message,omitempty"`
+	OrderId   string `json:"orderId,omitempty"`
+	Status    string `json:"status"`
 }
 
 // FraudScore represents the FraudScore type
@@ -68,13 +199,84 @@ type FraudScore struct {
 	Signals   []string `json:"signals,omitempty"`
 }
 
+// GraphQLError represents the GraphQLError type
+type GraphQLError struct {
+	Extensions map[string]any         `json:"extensions,omitempty"`
+	Locations  []GraphQLErrorLocation `json:"locations,omitempty"`
+	Message    string                 `json:"message"`
+	Path       []any                  `json:"path,omitempty"`
+}
+
+// GraphQLErrorLocation represents the GraphQLErrorLocation type
+type GraphQLErrorLocation struct {
+	Column int `json:"column"`
+	Line   int `json:"line"`
+}
+
+// GraphQLRequest represents the GraphQLRequest type
+type GraphQLRequest struct {
+	OperationName string         `json:"operationName,omitempty"`
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// GraphQLResponse represents the GraphQLResponse type
+type GraphQLResponse struct {
+	Data   map[string]any `json:"data,omitempty"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
 // HealthResponse represents the HealthResponse type
 type HealthResponse struct {
-	Components map[string]any `json:"components,omitempty"`
-	Status     string         `json:"status"`
-	Timestamp  time.Time      `json:"timestamp"`
-	Uptime     int            `json:"uptime,omitempty"`
-	Version    string         `json:"version"`
+	BuildTime  string                     `json:"buildTime,omitempty"`
+	Commit     string                     `json:"commit,omitempty"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+	Leadership map[string]bool            `json:"leadership,omitempty"`
+	Stages     []StageHealth              `json:"stages,omitempty"`
+	StartedAt  *time.Time                 `json:"startedAt,omitempty"`
+	Status     string                     `json:"status"`
+	Timestamp  time.Time                  `json:"timestamp"`
+	Uptime     int                        `json:"uptime,omitempty"`
+	Version    string                     `json:"version"`
+}
+
+// ImportJob represents the ImportJob type
+type ImportJob struct {
+	Accepted    int              `json:"accepted"`
+	CompletedAt *time.Time       `json:"completedAt,omitempty"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	Error       string           `json:"error,omitempty"`
+	Errors      []ImportRowError `json:"errors"`
+	Format      string           `json:"format"`
+	JobId       string           `json:"jobId"`
+	Processed   int              `json:"processed"`
+	Rejected    int              `json:"rejected"`
+	Skipped     int              `json:"skipped"`
+	Status      ImportJobStatus  `json:"status"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+}
+
+// ImportJobStatus represents an enum type
+type ImportJobStatus string
+
+const (
+	ImportJobStatusProcessing ImportJobStatus = "processing"
+	ImportJobStatusCompleted  ImportJobStatus = "completed"
+	ImportJobStatusFailed     ImportJobStatus = "failed"
+)
+
+// ImportRowError represents the ImportRowError type
+type ImportRowError struct {
+	Errors  []ValidationError `json:"errors"`
+	OrderId string            `json:"orderId,omitempty"`
+	Row     int               `json:"row"`
+}
+
+// LatencyPercentiles represents the LatencyPercentiles type
+type LatencyPercentiles struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
 }
 
 // OrderAcceptedResponse represents the OrderAcceptedResponse type
@@ -85,6 +287,13 @@ type OrderAcceptedResponse struct {
 	Status  string     `json:"status"`
 }
 
+// OrderCancelledPayload represents the OrderCancelledPayload type
+type OrderCancelledPayload struct {
+	CancelledAt    time.Time `json:"cancelledAt"`
+	OrderId        string    `json:"orderId"`
+	PreviousStatus string    `json:"previousStatus"`
+}
+
 // OrderCancelledResponse represents the OrderCancelledResponse type
 type OrderCancelledResponse struct {
 	CancelledAt    time.Time `json:"cancelledAt"`
@@ -96,13 +305,13 @@ type OrderCancelledResponse struct {
 
 // OrderCreateRequest represents the OrderCreateRequest type
 type OrderCreateRequest struct {
-	BillingAddress  Address        `json:"billingAddress,omitempty"`
+	BillingAddress  *Address       `json:"billingAddress,omitempty"`
 	Currency        string         `json:"currency"`
 	CustomerId      string         `json:"customerId"`
 	Items           []OrderItem    `json:"items"`
 	Metadata        map[string]any `json:"metadata,omitempty"`
 	OrderId         string         `json:"orderId,omitempty"`
-	ShippingAddress Address        `json:"shippingAddress,omitempty"`
+	ShippingAddress *Address       `json:"shippingAddress,omitempty"`
 	TotalAmount     float64        `json:"totalAmount"`
 }
 
@@ -118,6 +327,7 @@ type OrderEvent struct {
 	DurationMs int            `json:"durationMs,omitempty"`
 	Error      map[string]any `json:"error,omitempty"`
 	EventId    string         `json:"eventId"`
+	EventType  string         `json:"eventType,omitempty"`
 	Metadata   map[string]any `json:"metadata,omitempty"`
 	Stage      string         `json:"stage"`
 	Status     string         `json:"status"`
@@ -126,9 +336,51 @@ type OrderEvent struct {
 
 // OrderEventsResponse represents the OrderEventsResponse type
 type OrderEventsResponse struct {
-	Events  []OrderEvent `json:"events"`
-	OrderId string       `json:"orderId"`
-}
+	Events     []OrderEvent `json:"events"`
+	OrderId    string       `json:"orderId"`
+	Pagination *Pagination  `json:"pagination,omitempty"`
+}
+
+// OrderExportFilter represents the OrderExportFilter type
+type OrderExportFilter struct {
+	CreatedAfter    *time.Time `json:"createdAfter,omitempty"`
+	CreatedBefore   *time.Time `json:"createdBefore,omitempty"`
+	Currency        string     `json:"currency,omitempty"`
+	CustomerId      string     `json:"customerId,omitempty"`
+	CustomerTier    string     `json:"customerTier,omitempty"`
+	Destination     string     `json:"destination,omitempty"`
+	MaxAmount       *float64   `json:"maxAmount,omitempty"`
+	MinAmount       *float64   `json:"minAmount,omitempty"`
+	Q               string     `json:"q,omitempty"`
+	ShippingCountry string     `json:"shippingCountry,omitempty"`
+	Sku             string     `json:"sku,omitempty"`
+	Status          []string   `json:"status,omitempty"`
+}
+
+// OrderExportJob represents the OrderExportJob type
+type OrderExportJob struct {
+	CompletedAt *time.Time           `json:"completedAt,omitempty"`
+	CreatedAt   time.Time            `json:"createdAt"`
+	Error       string               `json:"error,omitempty"`
+	ExpiresAt   *time.Time           `json:"expiresAt,omitempty"`
+	Exported    int                  `json:"exported"`
+	Filter      OrderExportFilter    `json:"filter"`
+	Format      string               `json:"format"`
+	JobId       string               `json:"jobId"`
+	Matched     int                  `json:"matched"`
+	SizeBytes   int64                `json:"sizeBytes"`
+	Status      OrderExportJobStatus `json:"status"`
+	UpdatedAt   time.Time            `json:"updatedAt"`
+}
+
+// OrderExportJobStatus represents an enum type
+type OrderExportJobStatus string
+
+const (
+	OrderExportJobStatusProcessing OrderExportJobStatus = "processing"
+	OrderExportJobStatusCompleted  OrderExportJobStatus = "completed"
+	OrderExportJobStatusFailed     OrderExportJobStatus = "failed"
+)
 
 // OrderFailedPayload represents the OrderFailedPayload type
 type OrderFailedPayload struct {
@@ -163,30 +415,35 @@ type OrderListResponse struct {
 
 // OrderReceivedPayload represents the OrderReceivedPayload type
 type OrderReceivedPayload struct {
-	CreatedAt       time.Time   `json:"createdAt"`
-	Currency        string      `json:"currency"`
-	CustomerId      string      `json:"customerId"`
-	Items           []OrderItem `json:"items"`
-	OrderId         string      `json:"orderId"`
-	ShippingAddress Address     `json:"shippingAddress,omitempty"`
-	TotalAmount     float64     `json:"totalAmount"`
+	BillingAddress  *Address       `json:"billingAddress,omitempty"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	Currency        string         `json:"currency"`
+	CustomerId      string         `json:"customerId"`
+	Items           []OrderItem    `json:"items"`
+	Metadata        map[string]any `json:"metadata,omitempty"`
+	OrderId         string         `json:"orderId"`
+	ShippingAddress *Address       `json:"shippingAddress,omitempty"`
+	TotalAmount     float64        `json:"totalAmount"`
 }
 
 // OrderResponse represents the OrderResponse type
 type OrderResponse struct {
-	CreatedAt       time.Time       `json:"createdAt"`
-	Currency        string          `json:"currency"`
-	CurrentStage    string          `json:"currentStage,omitempty"`
-	CustomerId      string          `json:"customerId"`
-	Enrichment      OrderEnrichment `json:"enrichment,omitempty"`
-	Items           []OrderItem     `json:"items"`
-	Links           OrderLinks      `json:"links,omitempty"`
-	OrderId         string          `json:"orderId"`
-	Routing         OrderRouting    `json:"routing,omitempty"`
-	ShippingAddress Address         `json:"shippingAddress,omitempty"`
-	Status          OrderStatus     `json:"status"`
-	TotalAmount     float64         `json:"totalAmount"`
-	UpdatedAt       time.Time       `json:"updatedAt"`
+	BillingAddress  *Address         `json:"billingAddress,omitempty"`
+	CreatedAt       time.Time        `json:"createdAt"`
+	Currency        string           `json:"currency"`
+	CurrentStage    string           `json:"currentStage,omitempty"`
+	CustomerId      string           `json:"customerId"`
+	Enrichment      *OrderEnrichment `json:"enrichment,omitempty"`
+	Items           []OrderItem      `json:"items"`
+	Links           *OrderLinks      `json:"links,omitempty"`
+	Metadata        map[string]any   `json:"metadata,omitempty"`
+	OrderId         string           `json:"orderId"`
+	Routing         *OrderRouting    `json:"routing,omitempty"`
+	ShippingAddress *Address         `json:"shippingAddress,omitempty"`
+	Status          OrderStatus      `json:"status"`
+	TotalAmount     float64          `json:"totalAmount"`
+	UpdatedAt       time.Time        `json:"updatedAt"`
+	Version         int64            `json:"version,omitempty"`
 }
 
 // OrderRouting represents Routing decision details
@@ -212,13 +469,27 @@ const (
 	OrderStatusCancelled  OrderStatus = "cancelled"
 )
 
+// OrderStatusUpdatePayload represents the OrderStatusUpdatePayload type
+type OrderStatusUpdatePayload struct {
+	DurationMs  int            `json:"durationMs,omitempty"`
+	Error       map[string]any `json:"error,omitempty"`
+	EventId     string         `json:"eventId,omitempty"`
+	EventType   string         `json:"eventType,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	OrderId     string         `json:"orderId"`
+	Stage       string         `json:"stage,omitempty"`
+	StageStatus string         `json:"stageStatus,omitempty"`
+	Status      string         `json:"status"`
+	Timestamp   time.Time      `json:"timestamp"`
+}
+
 // OrderSummary represents the OrderSummary type
 type OrderSummary struct {
 	CreatedAt   time.Time   `json:"createdAt"`
 	Currency    string      `json:"currency"`
 	CustomerId  string      `json:"customerId"`
 	ItemCount   int         `json:"itemCount,omitempty"`
-	Links       OrderLinks  `json:"links,omitempty"`
+	Links       *OrderLinks `json:"links,omitempty"`
 	OrderId     string      `json:"orderId"`
 	Status      OrderStatus `json:"status"`
 	TotalAmount float64     `json:"totalAmount"`
@@ -230,6 +501,33 @@ type Pagination struct {
 	HasMore    bool   `json:"hasMore"`
 	Limit      int    `json:"limit"`
 	NextCursor string `json:"nextCursor,omitempty"`
+	Offset     int    `json:"offset,omitempty"`
+	Total      int    `json:"total,omitempty"`
+}
+
+// PipelineError represents the PipelineError type
+type PipelineError struct {
+	ErrorId    string             `json:"errorId"`
+	ErrorType  string             `json:"errorType"`
+	EventId    string             `json:"eventId"`
+	InDlq      bool               `json:"inDlq"`
+	Links      PipelineErrorLinks `json:"links"`
+	Message    string             `json:"message"`
+	OccurredAt time.Time          `json:"occurredAt"`
+	OrderId    string             `json:"orderId,omitempty"`
+	StageId    string             `json:"stageId"`
+}
+
+// PipelineErrorLinks represents the PipelineErrorLinks type
+type PipelineErrorLinks struct {
+	DlqRetry string `json:"dlqRetry,omitempty"`
+	Order    string `json:"order,omitempty"`
+}
+
+// PipelineErrorListResponse represents the PipelineErrorListResponse type
+type PipelineErrorListResponse struct {
+	Items      []PipelineError `json:"items"`
+	Pagination Pagination      `json:"pagination"`
 }
 
 // PipelineErrorPayload represents the PipelineErrorPayload type
@@ -262,10 +560,10 @@ type PipelineStageSummary struct {
 
 // PipelineStageUpdateRequest represents the PipelineStageUpdateRequest type
 type PipelineStageUpdateRequest struct {
-	Concurrency int         `json:"concurrency,omitempty"`
-	RetryPolicy RetryPolicy `json:"retryPolicy,omitempty"`
-	Status      string      `json:"status,omitempty"`
-	Timeout     string      `json:"timeout,omitempty"`
+	Concurrency int          `json:"concurrency,omitempty"`
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+	Status      string       `json:"status,omitempty"`
+	Timeout     string       `json:"timeout,omitempty"`
 }
 
 // PipelineStagesResponse represents the PipelineStagesResponse type
@@ -273,13 +571,38 @@ type PipelineStagesResponse struct {
 	Stages []PipelineStageSummary `json:"stages"`
 }
 
+// PipelineStatsBucket represents the PipelineStatsBucket type
+type PipelineStatsBucket struct {
+	DlqAdded int          `json:"dlqAdded"`
+	Stages   []StageStats `json:"stages"`
+	Start    time.Time    `json:"start"`
+}
+
+// PipelineStatsResponse represents the PipelineStatsResponse type
+type PipelineStatsResponse struct {
+	BucketSeconds int                   `json:"bucketSeconds"`
+	Buckets       []PipelineStatsBucket `json:"buckets"`
+	DlqDepth      int                   `json:"dlqDepth"`
+	From          time.Time             `json:"from"`
+	To            time.Time             `json:"to"`
+}
+
 // ProblemDetails represents Error response format per RFC 9457 (Problem Details for HTTP APIs).  This format provides machine...
 type ProblemDetails struct {
-	Detail   string `json:"detail,omitempty"`
-	Instance string `json:"instance,omitempty"`
-	Status   int    `json:"status"`
-	Title    string `json:"title"`
-	Type     string `json:"type"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestId string `json:"requestId,omitempty"`
+	Status    int    `json:"status"`
+	Title     string `json:"title"`
+	Type      string `json:"type"`
+}
+
+// ReadinessResponse represents the ReadinessResponse type
+type ReadinessResponse struct {
+	Degraded     bool              `json:"degraded,omitempty"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+	Reason       string            `json:"reason,omitempty"`
+	Status       string            `json:"status"`
 }
 
 // RetryPolicy represents the RetryPolicy type
@@ -290,6 +613,33 @@ type RetryPolicy struct {
 	MaxBackoffMs      int     `json:"maxBackoffMs,omitempty"`
 }
 
+// RoutingDestinationStats represents the RoutingDestinationStats type
+type RoutingDestinationStats struct {
+	Count       int                  `json:"count"`
+	Destination string               `json:"destination"`
+	Reasons     []RoutingReasonCount `json:"reasons"`
+	Share       float64              `json:"share"`
+}
+
+// RoutingReasonCount represents the RoutingReasonCount type
+type RoutingReasonCount struct {
+	Count  int    `json:"count"`
+	Reason string `json:"reason"`
+}
+
+// RoutingStatsResponse represents the RoutingStatsResponse type
+type RoutingStatsResponse struct {
+	Destinations []RoutingDestinationStats `json:"destinations"`
+	Since        time.Time                 `json:"since"`
+	TotalRouted  int                       `json:"totalRouted"`
+}
+
+// SpecVersions represents The `info.version` of the specifications the service serves
+type SpecVersions struct {
+	Asyncapi string `json:"asyncapi,omitempty"`
+	Openapi  string `json:"openapi,omitempty"`
+}
+
 // StageCompletePayload represents the StageCompletePayload type
 type StageCompletePayload struct {
 	DurationMs int    `json:"durationMs"`
@@ -300,9 +650,9 @@ type StageCompletePayload struct {
 
 // StageConfig represents the StageConfig type
 type StageConfig struct {
-	Concurrency int         `json:"concurrency,omitempty"`
-	RetryPolicy RetryPolicy `json:"retryPolicy,omitempty"`
-	Timeout     string      `json:"timeout,omitempty"`
+	Concurrency int          `json:"concurrency,omitempty"`
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+	Timeout     string       `json:"timeout,omitempty"`
 }
 
 // StageError represents the StageError type
@@ -313,14 +663,29 @@ type StageError struct {
 	Timestamp time.Time `json:"timestamp,omitempty"`
 }
 
+// StageHealth represents the StageHealth type
+type StageHealth struct {
+	StageId string      `json:"stageId"`
+	Status  StageStatus `json:"status"`
+}
+
 // StageMetrics represents the StageMetrics type
 type StageMetrics struct {
-	AvgLatencyMs      float64 `json:"avgLatencyMs,omitempty"`
-	ErrorRate         float64 `json:"errorRate,omitempty"`
-	P99LatencyMs      float64 `json:"p99LatencyMs,omitempty"`
-	ProcessedLastHour int     `json:"processedLastHour,omitempty"`
-	ProcessedTotal    int     `json:"processedTotal,omitempty"`
-	QueueDepth        int     `json:"queueDepth,omitempty"`
+	AvgLatencyMs      float64        `json:"avgLatencyMs,omitempty"`
+	Destinations      map[string]int `json:"destinations,omitempty"`
+	ErrorRate         float64        `json:"errorRate,omitempty"`
+	P99LatencyMs      float64        `json:"p99LatencyMs,omitempty"`
+	ProcessedLastHour int            `json:"processedLastHour,omitempty"`
+	ProcessedTotal    int            `json:"processedTotal,omitempty"`
+	QueueDepth        int            `json:"queueDepth,omitempty"`
+}
+
+// StageStats represents the StageStats type
+type StageStats struct {
+	Completed int                 `json:"completed"`
+	Failed    int                 `json:"failed"`
+	LatencyMs *LatencyPercentiles `json:"latencyMs,omitempty"`
+	Stage     string              `json:"stage"`
 }
 
 // StageStatus represents an enum type
@@ -340,3 +705,117 @@ type ValidationError struct {
 	Message       string `json:"message"`
 	RejectedValue any    `json:"rejectedValue,omitempty"`
 }
+
+// VersionResponse represents the VersionResponse type
+type VersionResponse struct {
+	BuildTime string       `json:"buildTime,omitempty"`
+	Commit    string       `json:"commit,omitempty"`
+	GoVersion string       `json:"goVersion"`
+	Specs     SpecVersions `json:"specs"`
+	Version   string       `json:"version"`
+}
+
+// WebhookDelivery represents One attempt to deliver a notification
+type WebhookDelivery struct {
+	Attempt     int              `json:"attempt"`
+	AttemptedAt time.Time        `json:"attemptedAt"`
+	DeliveryId  string           `json:"deliveryId"`
+	DurationMs  int              `json:"durationMs"`
+	Error       string           `json:"error,omitempty"`
+	EventId     string           `json:"eventId"`
+	EventType   WebhookEventType `json:"eventType"`
+	Status      string           `json:"status"`
+	StatusCode  int              `json:"statusCode,omitempty"`
+}
+
+// WebhookDeliveryFailedPayload represents the WebhookDeliveryFailedPayload type
+type WebhookDeliveryFailedPayload struct {
+	Attempts     int                 `json:"attempts"`
+	Error        string              `json:"error"`
+	EventId      string              `json:"eventId"`
+	FailedAt     time.Time           `json:"failedAt"`
+	Notification WebhookNotification `json:"notification"`
+	SubscriberId string              `json:"subscriberId"`
+	Url          string              `json:"url"`
+}
+
+// WebhookDeliveryListResponse represents the WebhookDeliveryListResponse type
+type WebhookDeliveryListResponse struct {
+	Deliveries     []WebhookDelivery `json:"deliveries"`
+	Pagination     *Pagination       `json:"pagination,omitempty"`
+	SubscriptionId string            `json:"subscriptionId"`
+}
+
+// WebhookEventType represents an enum type
+type WebhookEventType string
+
+const (
+	WebhookEventTypeOrderRouted   WebhookEventType = "order.routed"
+	WebhookEventTypeOrderRejected WebhookEventType = "order.rejected"
+)
+
+// WebhookNotification represents Notification POSTed to webhook subscribers
+type WebhookNotification struct {
+	Data       map[string]any `json:"data"`
+	EventId    string         `json:"eventId"`
+	EventType  string         `json:"eventType"`
+	OccurredAt time.Time      `json:"occurredAt"`
+}
+
+// WebhookSubscription represents the WebhookSubscription type
+type WebhookSubscription struct {
+	Active         bool               `json:"active"`
+	CreatedAt      time.Time          `json:"createdAt"`
+	Description    string             `json:"description,omitempty"`
+	EventTypes     []WebhookEventType `json:"eventTypes"`
+	SubscriptionId string             `json:"subscriptionId"`
+	UpdatedAt      time.Time          `json:"updatedAt"`
+	Url            string             `json:"url"`
+}
+
+// WebhookSubscriptionCreateRequest represents the WebhookSubscriptionCreateRequest type
+type WebhookSubscriptionCreateRequest struct {
+	Description string             `json:"description,omitempty"`
+	EventTypes  []WebhookEventType `json:"eventTypes"`
+	Secret      string             `json:"secret,omitempty"`
+	Url         string             `json:"url"`
+}
+
+// WebhookSubscriptionCreatedResponse represents the WebhookSubscriptionCreatedResponse type
+type WebhookSubscriptionCreatedResponse struct {
+	Active         bool               `json:"active"`
+	CreatedAt      time.Time          `json:"createdAt"`
+	Description    string             `json:"description,omitempty"`
+	EventTypes     []WebhookEventType `json:"eventTypes"`
+	Secret         string             `json:"secret"`
+	SubscriptionId string             `json:"subscriptionId"`
+	UpdatedAt      time.Time          `json:"updatedAt"`
+	Url            string             `json:"url"`
+}
+
+// WebhookSubscriptionListResponse represents the WebhookSubscriptionListResponse type
+type WebhookSubscriptionListResponse struct {
+	Subscriptions []WebhookSubscription `json:"subscriptions"`
+}
+
+// WebhookSubscriptionUpdateRequest represents the WebhookSubscriptionUpdateRequest type
+type WebhookSubscriptionUpdateRequest struct {
+	Active      *bool              `json:"active,omitempty"`
+	Description *string            `json:"description,omitempty"`
+	EventTypes  []WebhookEventType `json:"eventTypes,omitempty"`
+	Secret      string             `json:"secret,omitempty"`
+	Url         string             `json:"url,omitempty"`
+}
+
+// WebhookTestRequest represents the WebhookTestRequest type
+type WebhookTestRequest struct {
+	EventType WebhookEventType `json:"eventType,omitempty"`
+}
+
+// WebhookTestResult represents Outcome of sending a sample notification to a subscription
+type WebhookTestResult struct {
+	Delivery       WebhookDelivery     `json:"delivery"`
+	Notification   WebhookNotification `json:"notification"`
+	SubscriptionId string              `json:"subscriptionId"`
+	Url            string              `json:"url"`
+}
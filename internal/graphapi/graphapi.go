@@ -0,0 +1,128 @@
+// Package graphapi serves a read-only GraphQL view of the order projection
+// and pipeline state, so a client can fetch an order with its event history,
+// or stage and routing metrics, in a single request. Resolvers query the
+// same service layer as the HTTP and gRPC APIs.
+package graphapi
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/synapse/synapse/internal/logging"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/service"
+)
+
+// logger logs the package's records
+var logger = logging.Module("graphapi")
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// maxDepth bounds query nesting; the deepest meaningful selection is an
+// order list's events' metadata
+const maxDepth = 8
+
+// New parses the GraphQL schema with resolvers backed by svc. It panics if
+// the schema and the resolvers disagree.
+func New(svc *service.Service) *graphql.Schema {
+	return graphql.MustParseSchema(schemaSDL, &resolver{service: svc},
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(maxDepth),
+	)
+}
+
+// queryError is a resolver error as clients see it: the problem's detail as
+// the message, with its type and status as extensions. The cause is left out.
+type queryError struct {
+	problem *problem.Error
+}
+
+// resolverError converts err to a queryError. Server errors are logged.
+func resolverError(ctx context.Context, field string, err error) error {
+	e := problem.From(err)
+	if e.Status >= http.StatusInternalServerError {
+		logger.ErrorContext(ctx, "graphql field failed", "field", field, "status", e.Status, "error", err)
+	}
+	return &queryError{problem: e}
+}
+
+func (e *queryError) Error() string {
+	if e.problem.Detail != "" {
+		return e.problem.Detail
+	}
+	return e.problem.Title
+}
+
+// Extensions implements the graphql-go interface for error extensions
+func (e *queryError) Extensions() map[string]any {
+	ext := map[string]any{
+		"type":   problem.BaseURI + e.problem.Type,
+		"status": e.problem.Status,
+	}
+	if len(e.problem.Errors) > 0 {
+		ext["errors"] = e.problem.Errors
+	}
+	return ext
+}
+
+// isNotFound reports whether err is a not-found problem, which lookups
+// resolve as null
+func isNotFound(err error) bool {
+	var e *problem.Error
+	return errors.As(err, &e) && e.Status == http.StatusNotFound
+}
+
+// JSON is the JSON scalar, an arbitrary object
+type JSON map[string]any
+
+// ImplementsGraphQLType maps JSON to the schema's JSON scalar
+func (JSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+// UnmarshalGraphQL decodes a JSON input value
+func (j *JSON) UnmarshalGraphQL(input any) error {
+	m, ok := input.(map[string]any)
+	if !ok {
+		return fmt.Errorf("JSON must be an object, got %T", input)
+	}
+	*j = m
+	return nil
+}
+
+// optionalJSON converts a JSON object, leaving an empty one null
+func optionalJSON(m map[string]any) *JSON {
+	if len(m) == 0 {
+		return nil
+	}
+	j := JSON(m)
+	return &j
+}
+
+// optionalString leaves an empty string null
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// optionalInt leaves zero null
+func optionalInt(n int) *int32 {
+	if n == 0 {
+		return nil
+	}
+	i := int32(n)
+	return &i
+}
+
+// enumValue converts an API status string to its GraphQL enum value
+func enumValue[S ~string](s S) string {
+	return strings.ToUpper(string(s))
+}
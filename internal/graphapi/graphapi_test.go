@@ -0,0 +1,105 @@
+package graphapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/graphapi"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/service"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// query runs q against a schema backed by a running pipeline and no database
+func query(t *testing.T, q string, vars map[string]any) (map[string]any, []map[string]any) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	runner, err := pipeline.New(ctx, &config.Config{RetryMaxAttempts: 1, RetryBackoffMs: 10}, nil)
+	require.NoError(t, err)
+	go func() { _ = runner.Run(ctx) }()
+	t.Cleanup(func() { _ = runner.Close() })
+	<-runner.Running()
+
+	schema := graphapi.New(service.New(nil, runner, store.New(nil)))
+	resp := schema.Exec(ctx, q, "", vars)
+
+	var data map[string]any
+	if len(resp.Data) > 0 {
+		require.NoError(t, json.Unmarshal(resp.Data, &data))
+	}
+	raw, err := json.Marshal(resp.Errors)
+	require.NoError(t, err)
+	var errs []map[string]any
+	require.NoError(t, json.Unmarshal(raw, &errs))
+	return data, errs
+}
+
+func TestSchema_PipelineStages(t *testing.T) {
+	data, errs := query(t, `{
+		pipelineStages { id status config { concurrency } metrics { processedTotal destinations { destination } } }
+		pipelineStage(id: "route") { id }
+		missing: pipelineStage(id: "bogus") { id }
+	}`, nil)
+	require.Empty(t, errs)
+
+	stages := data["pipelineStages"].([]any)
+	var ids []string
+	for _, s := range stages {
+		stage := s.(map[string]any)
+		ids = append(ids, stage["id"].(string))
+		assert.Equal(t, "HEALTHY", stage["status"])
+	}
+	assert.Equal(t, []string{"validate", "enrich", "route"}, ids)
+	assert.Equal(t, map[string]any{"id": "route"}, data["pipelineStage"])
+	assert.Nil(t, data["missing"])
+}
+
+func TestSchema_RoutingStats(t *testing.T) {
+	data, errs := query(t, `{ routingStats { totalRouted destinations { destination count share reasons { reason } } } }`, nil)
+	require.Empty(t, errs)
+
+	stats := data["routingStats"].(map[string]any)
+	assert.EqualValues(t, 0, stats["totalRouted"])
+	var dests []string
+	for _, d := range stats["destinations"].([]any) {
+		dests = append(dests, d.(map[string]any)["destination"].(string))
+	}
+	assert.ElementsMatch(t, []string{pipeline.DestinationFulfillment, pipeline.DestinationManualReview, pipeline.DestinationRejected}, dests)
+}
+
+func TestSchema_ArgumentErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		vars    map[string]any
+		message string
+	}{
+		{name: "order id not a UUID", query: `{ order(id: "order-1") { id } }`, message: "id must be a UUID"},
+		{name: "page too large", query: `{ orders(first: 500) { totalCount } }`, message: "first must be between 1 and 100"},
+		{name: "malformed cursor", query: `{ orders(after: "nope") { totalCount } }`, message: "after is malformed"},
+		{
+			name:    "filter value",
+			query:   `query($f: OrderFilter) { orders(filter: $f) { totalCount } }`,
+			vars:    map[string]any{"f": map[string]any{"customerId": "cust-1"}},
+			message: "customerId must be a UUID",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := query(t, tt.query, tt.vars)
+			require.Len(t, errs, 1)
+			assert.Equal(t, tt.message, errs[0]["message"])
+			assert.Equal(t, map[string]any{
+				"type":   "https://synapse.example.com/problems/invalid-parameter",
+				"status": float64(400),
+			}, errs[0]["extensions"])
+		})
+	}
+}
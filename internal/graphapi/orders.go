@@ -0,0 +1,269 @@
+package graphapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/service"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// resolver is the Query root
+type resolver struct {
+	service *service.Service
+}
+
+// orderFilterInput is the OrderFilter input type
+type orderFilterInput struct {
+	Statuses      *[]string
+	CustomerID    *graphql.ID
+	Destination   *string
+	CreatedAfter  *graphql.Time
+	CreatedBefore *graphql.Time
+}
+
+// ordersArgs are the arguments of Query.orders. Arguments with a default
+// value are never null.
+type ordersArgs struct {
+	First  int32
+	After  *string
+	Filter *orderFilterInput
+}
+
+// eventsArgs are the arguments of Order.events
+type eventsArgs struct {
+	First int32
+	After *string
+	Order string
+}
+
+// page checks a connection's first and after arguments, returning the page
+// size and the position to start after
+func page(first int32, after *string) (int, *store.Cursor, error) {
+	limit := int(first)
+	if limit < 1 || limit > service.MaxPageLimit {
+		return 0, nil, problem.InvalidParameter(fmt.Sprintf("first must be between 1 and %d", service.MaxPageLimit))
+	}
+	if after == nil || *after == "" {
+		return limit, nil, nil
+	}
+	cursor, err := service.DecodeCursor(*after)
+	if err != nil {
+		return 0, nil, problem.InvalidParameter("after is malformed")
+	}
+	return limit, cursor, nil
+}
+
+// Order resolves Query.order
+func (r *resolver) Order(ctx context.Context, args struct{ ID graphql.ID }) (*orderResolver, error) {
+	if _, err := uuid.Parse(string(args.ID)); err != nil {
+		return nil, resolverError(ctx, "order", problem.InvalidParameter("id must be a UUID"))
+	}
+	order, err := r.service.GetOrder(ctx, string(args.ID))
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolverError(ctx, "order", err)
+	}
+	return &orderResolver{service: r.service, order: order}, nil
+}
+
+// Orders resolves Query.orders
+func (r *resolver) Orders(ctx context.Context, args ordersArgs) (*orderConnectionResolver, error) {
+	limit, after, err := page(args.First, args.After)
+	if err != nil {
+		return nil, resolverError(ctx, "orders", err)
+	}
+	p := service.ListOrdersParams{Limit: limit, After: after}
+	if f := args.Filter; f != nil {
+		if f.Statuses != nil {
+			for _, status := range *f.Statuses {
+				p.Filter.Statuses = append(p.Filter.Statuses, strings.ToLower(status))
+			}
+		}
+		if f.CustomerID != nil {
+			p.Filter.CustomerID = string(*f.CustomerID)
+		}
+		if f.Destination != nil {
+			p.Filter.Destination = *f.Destination
+		}
+		if f.CreatedAfter != nil {
+			p.Filter.CreatedAfter = &f.CreatedAfter.Time
+		}
+		if f.CreatedBefore != nil {
+			p.Filter.CreatedBefore = &f.CreatedBefore.Time
+		}
+	}
+
+	orders, list, err := r.service.ListOrderDetails(ctx, p)
+	if err != nil {
+		return nil, resolverError(ctx, "orders", err)
+	}
+	conn := &orderConnectionResolver{
+		nodes:    make([]*orderResolver, 0, len(orders)),
+		pageInfo: &pageInfoResolver{hasNextPage: list.HasMore, endCursor: list.NextCursor},
+		total:    list.Total,
+	}
+	for _, order := range orders {
+		conn.nodes = append(conn.nodes, &orderResolver{service: r.service, order: order})
+	}
+	return conn, nil
+}
+
+type pageInfoResolver struct {
+	hasNextPage bool
+	endCursor   string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool  { return r.hasNextPage }
+func (r *pageInfoResolver) EndCursor() *string { return optionalString(r.endCursor) }
+
+type orderConnectionResolver struct {
+	nodes    []*orderResolver
+	pageInfo *pageInfoResolver
+	total    int
+}
+
+func (r *orderConnectionResolver) Nodes() []*orderResolver     { return r.nodes }
+func (r *orderConnectionResolver) PageInfo() *pageInfoResolver { return r.pageInfo }
+func (r *orderConnectionResolver) TotalCount() int32           { return int32(r.total) }
+
+type orderResolver struct {
+	service *service.Service
+	order   *generated.OrderResponse
+}
+
+func (r *orderResolver) ID() graphql.ID          { return graphql.ID(r.order.OrderId) }
+func (r *orderResolver) CustomerID() graphql.ID  { return graphql.ID(r.order.CustomerId) }
+func (r *orderResolver) Status() string          { return enumValue(r.order.Status) }
+func (r *orderResolver) CurrentStage() *string   { return optionalString(r.order.CurrentStage) }
+func (r *orderResolver) TotalAmount() float64    { return r.order.TotalAmount }
+func (r *orderResolver) Currency() string        { return r.order.Currency }
+func (r *orderResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.order.CreatedAt} }
+func (r *orderResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.order.UpdatedAt} }
+
+func (r *orderResolver) Items() []*orderItemResolver {
+	items := make([]*orderItemResolver, 0, len(r.order.Items))
+	for i := range r.order.Items {
+		items = append(items, &orderItemResolver{item: &r.order.Items[i]})
+	}
+	return items
+}
+
+func (r *orderResolver) ShippingAddress() *addressResolver {
+	if r.order.ShippingAddress == nil {
+		return nil
+	}
+	return &addressResolver{address: r.order.ShippingAddress}
+}
+
+func (r *orderResolver) Enrichment() *enrichmentResolver {
+	if r.order.Enrichment == nil {
+		return nil
+	}
+	return &enrichmentResolver{enrichment: r.order.Enrichment}
+}
+
+func (r *orderResolver) Routing() *routingResolver {
+	if r.order.Routing == nil {
+		return nil
+	}
+	return &routingResolver{routing: r.order.Routing}
+}
+
+// Events resolves Order.events. Each order's events take a query of their
+// own, so lists of orders should select them sparingly.
+func (r *orderResolver) Events(ctx context.Context, args eventsArgs) (*eventConnectionResolver, error) {
+	limit, after, err := page(args.First, args.After)
+	if err != nil {
+		return nil, resolverError(ctx, "events", err)
+	}
+	events, err := r.service.ListOrderEvents(ctx, r.order.OrderId, service.ListEventsParams{
+		Limit:      limit,
+		Descending: args.Order == "DESC",
+		After:      after,
+	})
+	if err != nil {
+		return nil, resolverError(ctx, "events", err)
+	}
+	conn := &eventConnectionResolver{
+		nodes:    make([]*eventResolver, 0, len(events.Events)),
+		pageInfo: &pageInfoResolver{hasNextPage: events.HasMore, endCursor: events.NextCursor},
+		total:    events.Total,
+	}
+	for i := range events.Events {
+		conn.nodes = append(conn.nodes, &eventResolver{event: &events.Events[i]})
+	}
+	return conn, nil
+}
+
+type orderItemResolver struct {
+	item *generated.OrderItem
+}
+
+func (r *orderItemResolver) Sku() string          { return r.item.Sku }
+func (r *orderItemResolver) ProductName() *string { return optionalString(r.item.ProductName) }
+func (r *orderItemResolver) Quantity() int32      { return int32(r.item.Quantity) }
+func (r *orderItemResolver) UnitPrice() float64   { return r.item.UnitPrice }
+
+type addressResolver struct {
+	address *generated.Address
+}
+
+func (r *addressResolver) Street() string      { return r.address.Street }
+func (r *addressResolver) Street2() *string    { return optionalString(r.address.Street2) }
+func (r *addressResolver) City() string        { return r.address.City }
+func (r *addressResolver) State() *string      { return optionalString(r.address.State) }
+func (r *addressResolver) PostalCode() *string { return optionalString(r.address.PostalCode) }
+func (r *addressResolver) Country() string     { return r.address.Country }
+
+type enrichmentResolver struct {
+	enrichment *generated.OrderEnrichment
+}
+
+func (r *enrichmentResolver) Customer() *JSON  { return optionalJSON(r.enrichment.Customer) }
+func (r *enrichmentResolver) Inventory() *JSON { return optionalJSON(r.enrichment.Inventory) }
+func (r *enrichmentResolver) Fraud() *JSON     { return optionalJSON(r.enrichment.Fraud) }
+
+type routingResolver struct {
+	routing *generated.OrderRouting
+}
+
+func (r *routingResolver) Destination() *string { return optionalString(r.routing.Destination) }
+func (r *routingResolver) Priority() *string    { return optionalString(r.routing.Priority) }
+func (r *routingResolver) Reason() *string      { return optionalString(r.routing.Reason) }
+func (r *routingResolver) RoutedAt() *graphql.Time {
+	if r.routing.RoutedAt.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: r.routing.RoutedAt}
+}
+
+type eventConnectionResolver struct {
+	nodes    []*eventResolver
+	pageInfo *pageInfoResolver
+	total    int
+}
+
+func (r *eventConnectionResolver) Nodes() []*eventResolver     { return r.nodes }
+func (r *eventConnectionResolver) PageInfo() *pageInfoResolver { return r.pageInfo }
+func (r *eventConnectionResolver) TotalCount() int32           { return int32(r.total) }
+
+type eventResolver struct {
+	event *generated.OrderEvent
+}
+
+func (r *eventResolver) ID() graphql.ID          { return graphql.ID(r.event.EventId) }
+func (r *eventResolver) Type() *string           { return optionalString(r.event.EventType) }
+func (r *eventResolver) Stage() string           { return r.event.Stage }
+func (r *eventResolver) Status() string          { return r.event.Status }
+func (r *eventResolver) Timestamp() graphql.Time { return graphql.Time{Time: r.event.Timestamp} }
+func (r *eventResolver) DurationMs() *int32      { return optionalInt(r.event.DurationMs) }
+func (r *eventResolver) Metadata() *JSON         { return optionalJSON(r.event.Metadata) }
+func (r *eventResolver) Error() *JSON            { return optionalJSON(r.event.Error) }
@@ -0,0 +1,181 @@
+package graphapi
+
+import (
+	"context"
+	"sort"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/synapse/synapse/internal/generated"
+)
+
+// PipelineStages resolves Query.pipelineStages
+func (r *resolver) PipelineStages(ctx context.Context) ([]*stageResolver, error) {
+	summaries := r.service.PipelineStages(ctx)
+	stages := make([]*stageResolver, 0, len(summaries))
+	for _, s := range summaries {
+		stage, err := r.service.PipelineStage(ctx, s.StageId)
+		if err != nil {
+			return nil, resolverError(ctx, "pipelineStages", err)
+		}
+		stages = append(stages, &stageResolver{stage: stage})
+	}
+	return stages, nil
+}
+
+// PipelineStage resolves Query.pipelineStage
+func (r *resolver) PipelineStage(ctx context.Context, args struct{ ID graphql.ID }) (*stageResolver, error) {
+	stage, err := r.service.PipelineStage(ctx, string(args.ID))
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolverError(ctx, "pipelineStage", err)
+	}
+	return &stageResolver{stage: stage}, nil
+}
+
+// RoutingStats resolves Query.routingStats
+func (r *resolver) RoutingStats() *routingStatsResolver {
+	return &routingStatsResolver{stats: r.service.RoutingStats()}
+}
+
+type stageResolver struct {
+	stage *generated.PipelineStageResponse
+}
+
+func (r *stageResolver) ID() graphql.ID { return graphql.ID(r.stage.StageId) }
+func (r *stageResolver) Status() string { return enumValue(r.stage.Status) }
+
+func (r *stageResolver) Config() *stageConfigResolver {
+	return &stageConfigResolver{config: &r.stage.Config}
+}
+
+func (r *stageResolver) Metrics() *stageMetricsResolver {
+	return &stageMetricsResolver{metrics: &r.stage.Metrics}
+}
+
+func (r *stageResolver) RecentErrors() []*stageErrorResolver {
+	errs := make([]*stageErrorResolver, 0, len(r.stage.RecentErrors))
+	for i := range r.stage.RecentErrors {
+		errs = append(errs, &stageErrorResolver{err: &r.stage.RecentErrors[i]})
+	}
+	return errs
+}
+
+func (r *stageResolver) UpdatedAt() *graphql.Time {
+	if r.stage.UpdatedAt.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: r.stage.UpdatedAt}
+}
+
+type stageConfigResolver struct {
+	config *generated.StageConfig
+}
+
+func (r *stageConfigResolver) Concurrency() *int32 { return optionalInt(r.config.Concurrency) }
+func (r *stageConfigResolver) Timeout() *string    { return optionalString(r.config.Timeout) }
+
+func (r *stageConfigResolver) RetryPolicy() *retryPolicyResolver {
+	if r.config.RetryPolicy == nil {
+		return nil
+	}
+	return &retryPolicyResolver{policy: r.config.RetryPolicy}
+}
+
+type retryPolicyResolver struct {
+	policy *generated.RetryPolicy
+}
+
+func (r *retryPolicyResolver) MaxAttempts() *int32  { return optionalInt(r.policy.MaxAttempts) }
+func (r *retryPolicyResolver) BackoffMs() *int32    { return optionalInt(r.policy.BackoffMs) }
+func (r *retryPolicyResolver) MaxBackoffMs() *int32 { return optionalInt(r.policy.MaxBackoffMs) }
+
+func (r *retryPolicyResolver) BackoffMultiplier() *float64 {
+	if r.policy.BackoffMultiplier == 0 {
+		return nil
+	}
+	return &r.policy.BackoffMultiplier
+}
+
+type stageMetricsResolver struct {
+	metrics *generated.StageMetrics
+}
+
+func (r *stageMetricsResolver) ProcessedTotal() int32    { return int32(r.metrics.ProcessedTotal) }
+func (r *stageMetricsResolver) ProcessedLastHour() int32 { return int32(r.metrics.ProcessedLastHour) }
+func (r *stageMetricsResolver) AvgLatencyMs() float64    { return r.metrics.AvgLatencyMs }
+func (r *stageMetricsResolver) P99LatencyMs() float64    { return r.metrics.P99LatencyMs }
+func (r *stageMetricsResolver) ErrorRate() float64       { return r.metrics.ErrorRate }
+func (r *stageMetricsResolver) QueueDepth() int32        { return int32(r.metrics.QueueDepth) }
+
+// Destinations lists the destination counts by name, for a stable order
+func (r *stageMetricsResolver) Destinations() []*destinationCountResolver {
+	counts := make([]*destinationCountResolver, 0, len(r.metrics.Destinations))
+	for dest, n := range r.metrics.Destinations {
+		counts = append(counts, &destinationCountResolver{destination: dest, count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].destination < counts[j].destination })
+	return counts
+}
+
+type destinationCountResolver struct {
+	destination string
+	count       int
+}
+
+func (r *destinationCountResolver) Destination() string { return r.destination }
+func (r *destinationCountResolver) Count() int32        { return int32(r.count) }
+
+type stageErrorResolver struct {
+	err *generated.StageError
+}
+
+func (r *stageErrorResolver) EventID() *string   { return optionalString(r.err.EventId) }
+func (r *stageErrorResolver) ErrorType() *string { return optionalString(r.err.ErrorType) }
+func (r *stageErrorResolver) Message() *string   { return optionalString(r.err.Message) }
+
+func (r *stageErrorResolver) Timestamp() *graphql.Time {
+	if r.err.Timestamp.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: r.err.Timestamp}
+}
+
+type routingStatsResolver struct {
+	stats generated.RoutingStatsResponse
+}
+
+func (r *routingStatsResolver) Since() graphql.Time { return graphql.Time{Time: r.stats.Since} }
+func (r *routingStatsResolver) TotalRouted() int32  { return int32(r.stats.TotalRouted) }
+
+func (r *routingStatsResolver) Destinations() []*routingDestinationResolver {
+	dests := make([]*routingDestinationResolver, 0, len(r.stats.Destinations))
+	for i := range r.stats.Destinations {
+		dests = append(dests, &routingDestinationResolver{stats: &r.stats.Destinations[i]})
+	}
+	return dests
+}
+
+type routingDestinationResolver struct {
+	stats *generated.RoutingDestinationStats
+}
+
+func (r *routingDestinationResolver) Destination() string { return r.stats.Destination }
+func (r *routingDestinationResolver) Count() int32        { return int32(r.stats.Count) }
+func (r *routingDestinationResolver) Share() float64      { return r.stats.Share }
+
+func (r *routingDestinationResolver) Reasons() []*routingReasonResolver {
+	reasons := make([]*routingReasonResolver, 0, len(r.stats.Reasons))
+	for i := range r.stats.Reasons {
+		reasons = append(reasons, &routingReasonResolver{reason: &r.stats.Reasons[i]})
+	}
+	return reasons
+}
+
+type routingReasonResolver struct {
+	reason *generated.RoutingReasonCount
+}
+
+func (r *routingReasonResolver) Reason() string { return r.reason.Reason }
+func (r *routingReasonResolver) Count() int32   { return int32(r.reason.Count) }
@@ -0,0 +1,234 @@
+package grpcapi
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/generated/synapsev1"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Enum value prefixes, stripped to get the API's status strings
+const (
+	orderStatusPrefix = "ORDER_STATUS_"
+	stageStatusPrefix = "STAGE_STATUS_"
+)
+
+// orderStatus converts an API order status to its enum value
+func orderStatus[S ~string](s S) synapsev1.OrderStatus {
+	return synapsev1.OrderStatus(synapsev1.OrderStatus_value[orderStatusPrefix+strings.ToUpper(string(s))])
+}
+
+// orderStatusName converts an order status enum value to the API's string.
+// Values outside the API, like ORDER_STATUS_UNSPECIFIED, still convert so the
+// filter validation can reject them.
+func orderStatusName(s synapsev1.OrderStatus) string {
+	return strings.ToLower(strings.TrimPrefix(s.String(), orderStatusPrefix))
+}
+
+// stageStatus converts an API stage status to its enum value
+func stageStatus(s generated.StageStatus) synapsev1.StageStatus {
+	return synapsev1.StageStatus(synapsev1.StageStatus_value[stageStatusPrefix+strings.ToUpper(string(s))])
+}
+
+// timestamp converts t, leaving the zero time unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// newStruct converts a JSON object, leaving an empty one unset
+func newStruct(m map[string]any) (*structpb.Struct, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return structpb.NewStruct(m)
+}
+
+func address(a *synapsev1.Address) *generated.Address {
+	if a == nil {
+		return nil
+	}
+	return &generated.Address{
+		Street:     a.GetStreet(),
+		Street2:    a.GetStreet2(),
+		City:       a.GetCity(),
+		State:      a.GetState(),
+		PostalCode: a.GetPostalCode(),
+		Country:    a.GetCountry(),
+	}
+}
+
+func addressMessage(a *generated.Address) *synapsev1.Address {
+	if a == nil {
+		return nil
+	}
+	return &synapsev1.Address{
+		Street:     a.Street,
+		Street2:    a.Street2,
+		City:       a.City,
+		State:      a.State,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+	}
+}
+
+// orderCreateRequest converts an ingest request to the API's order
+func orderCreateRequest(req *synapsev1.IngestOrderRequest) *generated.OrderCreateRequest {
+	order := &generated.OrderCreateRequest{
+		CustomerId:      req.GetCustomerId(),
+		Items:           make([]generated.OrderItem, 0, len(req.GetItems())),
+		TotalAmount:     req.GetTotalAmount(),
+		Currency:        req.GetCurrency(),
+		ShippingAddress: address(req.GetShippingAddress()),
+		BillingAddress:  address(req.GetBillingAddress()),
+	}
+	for _, item := range req.GetItems() {
+		order.Items = append(order.Items, generated.OrderItem{
+			Sku:         item.GetSku(),
+			ProductName: item.GetProductName(),
+			Quantity:    int(item.GetQuantity()),
+			UnitPrice:   item.GetUnitPrice(),
+		})
+	}
+	if req.Metadata != nil {
+		order.Metadata = req.Metadata.AsMap()
+	}
+	return order
+}
+
+func orderItemMessages(items []generated.OrderItem) []*synapsev1.OrderItem {
+	msgs := make([]*synapsev1.OrderItem, 0, len(items))
+	for _, item := range items {
+		msgs = append(msgs, &synapsev1.OrderItem{
+			Sku:         item.Sku,
+			ProductName: item.ProductName,
+			Quantity:    int32(item.Quantity),
+			UnitPrice:   item.UnitPrice,
+		})
+	}
+	return msgs
+}
+
+func orderMessage(o *generated.OrderResponse) (*synapsev1.Order, error) {
+	msg := &synapsev1.Order{
+		OrderId:         o.OrderId,
+		CustomerId:      o.CustomerId,
+		Status:          orderStatus(o.Status),
+		CurrentStage:    o.CurrentStage,
+		Items:           orderItemMessages(o.Items),
+		TotalAmount:     o.TotalAmount,
+		Currency:        o.Currency,
+		ShippingAddress: addressMessage(o.ShippingAddress),
+		CreatedAt:       timestamp(o.CreatedAt),
+		UpdatedAt:       timestamp(o.UpdatedAt),
+	}
+	if e := o.Enrichment; e != nil {
+		msg.Enrichment = &synapsev1.OrderEnrichment{}
+		var err error
+		if msg.Enrichment.Customer, err = newStruct(e.Customer); err != nil {
+			return nil, fmt.Errorf("converting customer enrichment of order %s: %w", o.OrderId, err)
+		}
+		if msg.Enrichment.Inventory, err = newStruct(e.Inventory); err != nil {
+			return nil, fmt.Errorf("converting inventory enrichment of order %s: %w", o.OrderId, err)
+		}
+		if msg.Enrichment.Fraud, err = newStruct(e.Fraud); err != nil {
+			return nil, fmt.Errorf("converting fraud enrichment of order %s: %w", o.OrderId, err)
+		}
+	}
+	if r := o.Routing; r != nil {
+		msg.Routing = &synapsev1.OrderRouting{
+			Destination: r.Destination,
+			Priority:    r.Priority,
+			Reason:      r.Reason,
+			RoutedAt:    timestamp(r.RoutedAt),
+		}
+	}
+	return msg, nil
+}
+
+func orderSummaryMessage(o *generated.OrderSummary) *synapsev1.OrderSummary {
+	return &synapsev1.OrderSummary{
+		OrderId:     o.OrderId,
+		CustomerId:  o.CustomerId,
+		Status:      orderStatus(o.Status),
+		TotalAmount: o.TotalAmount,
+		Currency:    o.Currency,
+		ItemCount:   int32(o.ItemCount),
+		CreatedAt:   timestamp(o.CreatedAt),
+	}
+}
+
+func statusUpdateMessage(u *generated.OrderStatusUpdatePayload) (*synapsev1.OrderStatusUpdate, error) {
+	msg := &synapsev1.OrderStatusUpdate{
+		OrderId:     u.OrderId,
+		Status:      orderStatus(u.Status),
+		Stage:       u.Stage,
+		StageStatus: u.StageStatus,
+		EventId:     u.EventId,
+		EventType:   u.EventType,
+		Timestamp:   timestamp(u.Timestamp),
+		DurationMs:  int64(u.DurationMs),
+	}
+	var err error
+	if msg.Error, err = newStruct(u.Error); err != nil {
+		return nil, fmt.Errorf("converting error of order %s update: %w", u.OrderId, err)
+	}
+	if msg.Metadata, err = newStruct(u.Metadata); err != nil {
+		return nil, fmt.Errorf("converting metadata of order %s update: %w", u.OrderId, err)
+	}
+	return msg, nil
+}
+
+func stageMetricsMessage(m *generated.StageMetrics) *synapsev1.StageMetrics {
+	msg := &synapsev1.StageMetrics{
+		ProcessedTotal:    int64(m.ProcessedTotal),
+		ProcessedLastHour: int64(m.ProcessedLastHour),
+		AvgLatencyMs:      m.AvgLatencyMs,
+		P99LatencyMs:      m.P99LatencyMs,
+		ErrorRate:         m.ErrorRate,
+		QueueDepth:        int64(m.QueueDepth),
+	}
+	if len(m.Destinations) > 0 {
+		msg.Destinations = make(map[string]int64, len(m.Destinations))
+		for dest, n := range m.Destinations {
+			msg.Destinations[dest] = int64(n)
+		}
+	}
+	return msg
+}
+
+func stageMessage(s *generated.PipelineStageResponse) *synapsev1.Stage {
+	msg := &synapsev1.Stage{
+		StageId: s.StageId,
+		Status:  stageStatus(s.Status),
+		Config: &synapsev1.StageConfig{
+			Concurrency: int32(s.Config.Concurrency),
+			Timeout:     s.Config.Timeout,
+		},
+		Metrics:   stageMetricsMessage(&s.Metrics),
+		UpdatedAt: timestamp(s.UpdatedAt),
+	}
+	if rp := s.Config.RetryPolicy; rp != nil {
+		msg.Config.RetryPolicy = &synapsev1.RetryPolicy{
+			MaxAttempts:       int32(rp.MaxAttempts),
+			BackoffMs:         int64(rp.BackoffMs),
+			BackoffMultiplier: rp.BackoffMultiplier,
+			MaxBackoffMs:      int64(rp.MaxBackoffMs),
+		}
+	}
+	for _, e := range s.RecentErrors {
+		msg.RecentErrors = append(msg.RecentErrors, &synapsev1.StageError{
+			EventId:   e.EventId,
+			ErrorType: e.ErrorType,
+			Message:   e.Message,
+			Timestamp: timestamp(e.Timestamp),
+		})
+	}
+	return msg
+}
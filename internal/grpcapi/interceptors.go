@@ -0,0 +1,84 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/problem"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// unaryInterceptor authenticates calls and converts their errors to statuses
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, toStatus(info.FullMethod, err)
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, toStatus(info.FullMethod, err)
+	}
+	return resp, nil
+}
+
+// streamInterceptor authenticates streams and converts their errors to statuses
+func (s *Server) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return toStatus(info.FullMethod, err)
+	}
+	if err := handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx}); err != nil {
+		return toStatus(info.FullMethod, err)
+	}
+	return nil
+}
+
+// authenticatedStream carries the stream's principal in its context
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate returns ctx carrying the call's principal. Credentials are read
+// from the metadata keys matching the HTTP API's headers: x-api-key, or
+// authorization with a bearer token.
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if len(s.authenticators) == 0 {
+		return ctx, nil
+	}
+
+	// Authenticators read credentials from HTTP headers
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, method, nil)
+	if err != nil {
+		return ctx, problem.Internal(err)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, v := range values {
+			r.Header.Add(key, v)
+		}
+	}
+
+	for _, a := range s.authenticators {
+		p, err := a.Authenticate(r)
+		switch {
+		case errors.Is(err, auth.ErrNoCredentials):
+			continue
+		case errors.Is(err, auth.ErrTokenExpired):
+			return ctx, problem.TokenExpired()
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			return ctx, problem.Unauthorized("The provided credentials are invalid or have been revoked")
+		case err != nil:
+			return ctx, problem.Upstream("auth", err)
+		}
+		return auth.NewContext(ctx, p), nil
+	}
+	return ctx, problem.Unauthorized("Authentication is required: provide an API key in the x-api-key metadata or a bearer token")
+}
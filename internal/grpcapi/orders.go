@@ -0,0 +1,158 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/generated/synapsev1"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/service"
+	"github.com/synapse/synapse/internal/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// IngestOrder implements synapse.v1.OrderService/IngestOrder
+func (s *Server) IngestOrder(ctx context.Context, req *synapsev1.IngestOrderRequest) (*synapsev1.IngestOrderResponse, error) {
+	order := orderCreateRequest(req)
+	if err := s.validateOrder(order); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.service.IngestOrder(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	return &synapsev1.IngestOrderResponse{
+		OrderId: resp.OrderId,
+		Status:  orderStatus(resp.Status),
+		Message: resp.Message,
+	}, nil
+}
+
+// GetOrder implements synapse.v1.OrderService/GetOrder
+func (s *Server) GetOrder(ctx context.Context, req *synapsev1.GetOrderRequest) (*synapsev1.Order, error) {
+	order, err := s.service.GetOrder(ctx, req.GetOrderId())
+	if err != nil {
+		return nil, err
+	}
+	return orderMessage(order)
+}
+
+// ListOrders implements synapse.v1.OrderService/ListOrders
+func (s *Server) ListOrders(ctx context.Context, req *synapsev1.ListOrdersRequest) (*synapsev1.ListOrdersResponse, error) {
+	limit := int(req.GetPageSize())
+	if limit == 0 {
+		limit = service.DefaultPageLimit
+	}
+	if limit < 1 || limit > service.MaxPageLimit {
+		return nil, problem.InvalidParameter(fmt.Sprintf("page_size must be between 1 and %d", service.MaxPageLimit))
+	}
+
+	p := service.ListOrdersParams{
+		Filter: store.OrderFilter{
+			CustomerID:  req.GetCustomerId(),
+			Destination: req.GetDestination(),
+		},
+		Limit: limit,
+	}
+	if token := req.GetPageToken(); token != "" {
+		after, err := service.DecodeCursor(token)
+		if err != nil {
+			return nil, problem.InvalidParameter("page_token is malformed")
+		}
+		p.After = after
+	}
+	for _, st := range req.GetStatuses() {
+		p.Filter.Statuses = append(p.Filter.Statuses, orderStatusName(st))
+	}
+	if req.CreatedAfter != nil {
+		t := req.CreatedAfter.AsTime()
+		p.Filter.CreatedAfter = &t
+	}
+	if req.CreatedBefore != nil {
+		t := req.CreatedBefore.AsTime()
+		p.Filter.CreatedBefore = &t
+	}
+
+	page, err := s.service.ListOrders(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	resp := &synapsev1.ListOrdersResponse{
+		Orders:        make([]*synapsev1.OrderSummary, 0, len(page.Orders)),
+		NextPageToken: page.NextCursor,
+		TotalSize:     int32(page.Total),
+	}
+	for i := range page.Orders {
+		resp.Orders = append(resp.Orders, orderSummaryMessage(&page.Orders[i]))
+	}
+	return resp, nil
+}
+
+// CancelOrder implements synapse.v1.OrderService/CancelOrder
+func (s *Server) CancelOrder(ctx context.Context, req *synapsev1.CancelOrderRequest) (*synapsev1.CancelOrderResponse, error) {
+	resp, err := s.service.CancelOrder(ctx, req.GetOrderId(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &synapsev1.CancelOrderResponse{
+		OrderId:        resp.OrderId,
+		Status:         orderStatus(resp.Status),
+		PreviousStatus: orderStatus(resp.PreviousStatus),
+		CancelledAt:    timestamp(resp.CancelledAt),
+		Message:        resp.Message,
+	}, nil
+}
+
+// WatchOrder implements synapse.v1.OrderService/WatchOrder. It sends the
+// order's current status and then each update, until the order reaches a
+// final status or the caller cancels.
+func (s *Server) WatchOrder(req *synapsev1.WatchOrderRequest, stream grpc.ServerStreamingServer[synapsev1.OrderStatusUpdate]) error {
+	ctx := stream.Context()
+	watch, err := s.service.WatchOrder(ctx, req.GetOrderId())
+	if err != nil {
+		return err
+	}
+	defer watch.Close()
+
+	update := watch.Snapshot
+	for {
+		msg, err := statusUpdateMessage(&update)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+		if pipeline.IsFinalStatus(update.Status) {
+			return nil
+		}
+		if update, err = watch.Next(ctx); err != nil {
+			return status.FromContextError(err).Err()
+		}
+	}
+}
+
+// validateOrder checks an ingested order against the order schema, as the
+// HTTP API's request validation does, when the spec could be loaded
+func (s *Server) validateOrder(order *generated.OrderCreateRequest) error {
+	if s.schemas == nil {
+		return nil
+	}
+	data, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("encoding order: %w", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("decoding order: %w", err)
+	}
+	if errs := s.schemas.Validate("OrderCreateRequest", doc); len(errs) > 0 {
+		return problem.Validation("The order does not match the API specification", errs...)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package grpcapi_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/generated/synapsev1"
+	"github.com/synapse/synapse/internal/grpcapi"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/pipeline"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial serves the gRPC API for cfg over an in-memory listener, with a running
+// pipeline and no database or NATS
+func dial(t *testing.T, cfg *config.Config) *grpc.ClientConn {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	runner, err := pipeline.New(ctx, &config.Config{RetryMaxAttempts: 1, RetryBackoffMs: 10}, nil)
+	require.NoError(t, err)
+	go func() { _ = runner.Run(ctx) }()
+	t.Cleanup(func() { _ = runner.Close() })
+	<-runner.Running()
+
+	srv := grpcapi.New(&infra.Infra{Config: cfg}, runner)
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func validOrder() *synapsev1.IngestOrderRequest {
+	return &synapsev1.IngestOrderRequest{
+		CustomerId:  "550e8400-e29b-41d4-a716-446655440000",
+		Items:       []*synapsev1.OrderItem{{Sku: "SKU-1", Quantity: 2, UnitPrice: 5}},
+		TotalAmount: 10,
+		Currency:    "USD",
+	}
+}
+
+func TestServer_IngestOrder(t *testing.T) {
+	conn := dial(t, &config.Config{OpenAPISpecPath: "../../openapi/openapi.yaml"})
+	client := synapsev1.NewOrderServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := client.IngestOrder(ctx, validOrder())
+	require.NoError(t, err)
+	assert.Equal(t, synapsev1.OrderStatus_ORDER_STATUS_ACCEPTED, resp.Status)
+	_, err = uuid.Parse(resp.OrderId)
+	assert.NoError(t, err)
+
+	// Orders are checked against the same schema as over HTTP
+	invalid := validOrder()
+	invalid.Currency = "usd"
+	_, err = client.IngestOrder(ctx, invalid)
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations = br.FieldViolations
+		}
+	}
+	require.NotEmpty(t, violations)
+	assert.Contains(t, violations[0].Field, "currency")
+}
+
+func TestServer_PipelineStages(t *testing.T) {
+	conn := dial(t, &config.Config{})
+	client := synapsev1.NewPipelineServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	list, err := client.ListStages(ctx, &synapsev1.ListStagesRequest{})
+	require.NoError(t, err)
+	var ids []string
+	for _, s := range list.Stages {
+		ids = append(ids, s.StageId)
+		assert.Equal(t, synapsev1.StageStatus_STAGE_STATUS_HEALTHY, s.Status)
+	}
+	assert.Equal(t, []string{"validate", "enrich", "route"}, ids)
+
+	stage, err := client.GetStage(ctx, &synapsev1.GetStageRequest{StageId: "route"})
+	require.NoError(t, err)
+	assert.Equal(t, "route", stage.StageId)
+
+	_, err = client.GetStage(ctx, &synapsev1.GetStageRequest{StageId: "bogus"})
+	st := status.Convert(err)
+	assert.Equal(t, codes.NotFound, st.Code())
+	require.NotEmpty(t, st.Details())
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, "not-found", info.Reason)
+}
+
+func TestServer_Authentication(t *testing.T) {
+	conn := dial(t, &config.Config{AuthEnabled: true})
+	client := synapsev1.NewPipelineServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.ListStages(ctx, &synapsev1.ListStagesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", "not-a-key")
+	_, err = client.ListStages(ctx, &synapsev1.ListStagesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "invalid or have been revoked")
+}
@@ -0,0 +1,32 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/synapse/synapse/internal/generated/synapsev1"
+)
+
+// ListStages implements synapse.v1.PipelineService/ListStages
+func (s *Server) ListStages(ctx context.Context, req *synapsev1.ListStagesRequest) (*synapsev1.ListStagesResponse, error) {
+	stages := s.service.PipelineStages(ctx)
+	resp := &synapsev1.ListStagesResponse{
+		Stages: make([]*synapsev1.StageSummary, 0, len(stages)),
+	}
+	for _, st := range stages {
+		resp.Stages = append(resp.Stages, &synapsev1.StageSummary{
+			StageId: st.StageId,
+			Status:  stageStatus(st.Status),
+			Metrics: stageMetricsMessage(&st.Metrics),
+		})
+	}
+	return resp, nil
+}
+
+// GetStage implements synapse.v1.PipelineService/GetStage
+func (s *Server) GetStage(ctx context.Context, req *synapsev1.GetStageRequest) (*synapsev1.Stage, error) {
+	stage, err := s.service.PipelineStage(ctx, req.GetStageId())
+	if err != nil {
+		return nil, err
+	}
+	return stageMessage(stage), nil
+}
@@ -0,0 +1,84 @@
+package grpcapi
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/synapse/synapse/internal/problem"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain identifies the service in ErrorInfo details
+const errorDomain = "synapse.example.com"
+
+// statusCodes maps problem HTTP statuses to gRPC codes
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.FailedPrecondition,
+	http.StatusPreconditionFailed:    codes.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: codes.ResourceExhausted,
+	http.StatusUnsupportedMediaType:  codes.InvalidArgument,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusInternalServerError:   codes.Internal,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+}
+
+// toStatus converts err to a gRPC status error. Errors that already are
+// statuses pass through; problem errors keep their type as an ErrorInfo
+// reason, field-level validation failures as BadRequest and Retry-After as
+// RetryInfo. Server errors are logged.
+func toStatus(method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	e := problem.From(err)
+	if e.Status >= http.StatusInternalServerError {
+		logger.Error("rpc failed", "method", method, "status", e.Status, "error", err)
+	}
+
+	code, ok := statusCodes[e.Status]
+	if !ok {
+		code = codes.Unknown
+	}
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Title
+	}
+
+	info := &errdetails.ErrorInfo{Reason: e.Type, Domain: errorDomain}
+	for k, v := range e.Extensions {
+		if info.Metadata == nil {
+			info.Metadata = make(map[string]string, len(e.Extensions))
+		}
+		info.Metadata[k] = fmt.Sprint(v)
+	}
+	details := []protoadapt.MessageV1{info}
+	if len(e.Errors) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, fe := range e.Errors {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fe.Field,
+				Description: fe.Message,
+				Reason:      fe.Code,
+			})
+		}
+		details = append(details, br)
+	}
+	if e.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryAfter)})
+	}
+
+	st, detailErr := status.New(code, msg).WithDetails(details...)
+	if detailErr != nil {
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/synapse/synapse/asyncapi"
+)
+
+// asyncAPIETag identifies the embedded AsyncAPI spec
+var asyncAPIETag = func() string {
+	sum := sha256.Sum256(asyncapi.Spec)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}()
+
+// asyncAPIDocsPage renders the spec with the AsyncAPI React component
+const asyncAPIDocsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Synapse Event Pipeline - AsyncAPI</title>
+  <link rel="stylesheet" href="https://unpkg.com/@asyncapi/react-component@1/styles/default.min.css">
+</head>
+<body>
+  <div id="asyncapi"></div>
+  <script src="https://unpkg.com/@asyncapi/react-component@1/browser/standalone/index.js"></script>
+  <script>
+    AsyncApiStandalone.render({
+      schema: { url: "/api/v1/asyncapi.yaml" },
+      config: { show: { sidebar: true } },
+    }, document.getElementById("asyncapi"));
+  </script>
+</body>
+</html>
+`
+
+// GetAsyncAPISpec handles GET /api/v1/asyncapi.yaml
+func (h *Handler) GetAsyncAPISpec(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", asyncAPIETag)
+	http.ServeContent(w, r, "asyncapi.yaml", time.Time{}, bytes.NewReader(asyncapi.Spec))
+	return nil
+}
+
+// GetAsyncAPIDocs handles GET /api/v1/asyncapi.html
+func (h *Handler) GetAsyncAPIDocs(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte(asyncAPIDocsPage))
+	return err
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/middleware"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/service"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// ListAuditEntries handles GET /api/v1/audit
+func (h *Handler) ListAuditEntries(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+
+	filter, err := auditFilter(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+
+	entries, err := h.service.ListAuditEntries(ctx, service.ListAuditParams{
+		Filter: filter,
+		Limit:  page.limit,
+		Offset: page.offset,
+		After:  page.after,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(entries.Total))
+	if link := paginationLinks(r, page, entries.HasMore, entries.NextCursor); link != "" {
+		w.Header().Add("Link", link)
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
+	return h.writeJSON(w, http.StatusOK, generated.AuditListResponse{
+		Entries: entries.Entries,
+		Pagination: generated.Pagination{
+			Limit:      page.limit,
+			Offset:     page.offset,
+			Cursor:     page.cursor,
+			NextCursor: entries.NextCursor,
+			Total:      entries.Total,
+			HasMore:    entries.HasMore,
+		},
+	})
+}
+
+// auditFilter parses the audit log filter query parameters
+func auditFilter(r *http.Request) (store.AuditFilter, error) {
+	q := r.URL.Query()
+	f := store.AuditFilter{
+		ActorID: q.Get("actorId"),
+		Route:   q.Get("route"),
+		Path:    q.Get("path"),
+		Outcome: q.Get("outcome"),
+	}
+	for _, raw := range q["method"] {
+		f.Methods = append(f.Methods, strings.Split(strings.ToUpper(raw), ",")...)
+	}
+
+	var err error
+	if f.OccurredAfter, err = queryTime(q.Get("occurredAfter"), "occurredAfter"); err != nil {
+		return f, err
+	}
+	if f.OccurredBefore, err = queryTime(q.Get("occurredBefore"), "occurredBefore"); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// auditRecorder stores the API calls recorded by the audit middleware
+type auditRecorder struct {
+	service *service.Service
+}
+
+func (a auditRecorder) RecordAudit(ctx context.Context, rec *middleware.AuditRecord) error {
+	entry := &store.AuditEntry{
+		RequestID:  rec.RequestID,
+		Method:     rec.Method,
+		Route:      rec.Route,
+		Path:       rec.Path,
+		Params:     rec.Params,
+		Query:      rec.Query,
+		BodyBytes:  rec.BodyBytes,
+		StatusCode: rec.Status,
+		DurationMs: int(rec.Duration.Milliseconds()),
+		OccurredAt: rec.At,
+	}
+	if p := rec.Principal; p != nil {
+		entry.ActorID = p.ID
+		entry.ActorMethod = p.Method
+		entry.Tenant = p.Tenant
+	}
+	return a.service.RecordAudit(ctx, entry)
+}
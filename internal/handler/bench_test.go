@@ -0,0 +1,28 @@
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/synapse/synapse/internal/testutil"
+	"github.com/synapse/synapse/internal/testutil/factory"
+)
+
+// BenchmarkHandler_IngestOrder measures accepting orders through the API,
+// on fake infrastructure or on test containers with BENCH_CONTAINERS=1:
+//
+//	go test -run ^$ -bench Handler -benchmem ./internal/handler
+func BenchmarkHandler_IngestOrder(b *testing.B) {
+	env := testutil.NewBenchEnv(b)
+	api := testutil.NewAPIClient(b, env.Handler(env.Pipeline(b)))
+	order := factory.Order().JSON()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if resp := api.Post("/api/v1/orders", order); resp.StatusCode != http.StatusAccepted {
+			b.Fatalf("got status %d: %s", resp.StatusCode, resp.Body)
+		}
+	}
+}
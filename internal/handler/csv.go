@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/service"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// csvFlushEvery is how many CSV rows are written between flushes
+const csvFlushEvery = 100
+
+// wantsCSV reports whether r's Accept header prefers text/csv to JSON. List
+// endpoints answer such requests with a CSV export of every matching row.
+func wantsCSV(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+	return acceptQuality(accept, "text/csv") > acceptQuality(accept, "application/json")
+}
+
+// acceptQuality returns the q-value accept gives mediaType, taken from its
+// most specific matching range (RFC 9110 §12.5.1)
+func acceptQuality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		var s int
+		switch mt {
+		case mediaType:
+			s = 2
+		case typ + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+		quality, specificity = 1, s
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+	}
+	return quality
+}
+
+// csvExport streams rows as a CSV attachment. The response starts with the
+// first row, so a failure before it is still reported as a problem.
+type csvExport struct {
+	w        http.ResponseWriter
+	csv      *csv.Writer
+	filename string
+	header   []string
+	rows     int
+	log      *slog.Logger
+}
+
+func newCSVExport(w http.ResponseWriter, log *slog.Logger, filename string, header ...string) *csvExport {
+	return &csvExport{w: w, csv: csv.NewWriter(w), filename: filename, header: header, log: log}
+}
+
+// Write writes a row, starting the response with the header row first
+func (e *csvExport) Write(row []string) error {
+	if e.rows == 0 {
+		e.start()
+	}
+	e.rows++
+	if err := e.csv.Write(row); err != nil {
+		return err
+	}
+	if e.rows%csvFlushEvery == 0 {
+		e.csv.Flush()
+		_ = http.NewResponseController(e.w).Flush()
+	}
+	return e.csv.Error()
+}
+
+// Close ends the export. err is returned, to be reported as a problem, if the
+// response hasn't started. Otherwise the connection is aborted, so the client
+// sees a truncated download rather than a complete one.
+func (e *csvExport) Close(ctx context.Context, err error) error {
+	if err != nil {
+		if e.rows == 0 {
+			return err
+		}
+		e.log.ErrorContext(ctx, "CSV export failed", "file", e.filename, "rows", e.rows, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+	if e.rows == 0 {
+		e.start()
+	}
+	e.csv.Flush()
+	return nil
+}
+
+func (e *csvExport) start() {
+	e.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	e.w.Header().Set("Content-Disposition", `attachment; filename="`+e.filename+`"`)
+	e.w.Header().Set("Cache-Control", "private, no-cache")
+	e.w.WriteHeader(http.StatusOK)
+	_ = e.csv.Write(e.header)
+}
+
+// exportOrdersCSV writes every order matching filter as CSV
+func (h *Handler) exportOrdersCSV(ctx context.Context, w http.ResponseWriter, filter store.OrderFilter) error {
+	export := newCSVExport(w, h.log, "orders.csv", service.OrderCSVHeader...)
+	err := h.service.EachOrder(ctx, filter, func(o *generated.OrderSummary) error {
+		return export.Write(service.OrderCSVRow(o))
+	})
+	return export.Close(ctx, err)
+}
+
+// exportDLQCSV writes every DLQ item matching filter as CSV
+func (h *Handler) exportDLQCSV(ctx context.Context, w http.ResponseWriter, filter store.DLQFilter) error {
+	export := newCSVExport(w, h.log, "dlq.csv", service.DLQCSVHeader...)
+	err := h.service.EachDLQItem(ctx, filter, func(item *generated.DLQItem) error {
+		return export.Write(service.DLQCSVRow(item))
+	})
+	return export.Close(ctx, err)
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/problem"
+)
+
+// Request body defaults, used when infra.Config doesn't set them
+const (
+	defaultMaxJSONDepth = 32
+)
+
+// errJSONTooDeep reports a JSON body nested deeper than the limit
+var errJSONTooDeep = errors.New("JSON nesting too deep")
+
+// decodeJSON decodes the JSON request body into v. Bodies over the size limit
+// are reported as 413, bodies nested deeper than the depth limit as invalid
+// JSON and, with strict decoding, unknown fields as validation errors.
+func (h *Handler) decodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(&depthLimitReader{r: r.Body, max: h.maxJSONDepth})
+	if h.strictJSON {
+		dec.DisallowUnknownFields()
+	}
+	err := dec.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+		return problem.PayloadTooLarge(tooLarge.Limit)
+	}
+	if errors.Is(err, errJSONTooDeep) {
+		return problem.InvalidJSON(fmt.Errorf("nesting exceeds %d levels", h.maxJSONDepth))
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		return problem.Validation("The request body contains an unknown field", generated.ValidationError{
+			Field:   field,
+			Code:    "unknown_field",
+			Message: fmt.Sprintf("%s is not a known field", field),
+		})
+	}
+	return problem.InvalidJSON(err)
+}
+
+// decodeOptionalJSON is decodeJSON for optional bodies; an empty body leaves v
+// unchanged
+func (h *Handler) decodeOptionalJSON(r *http.Request, v any) error {
+	err := h.decodeJSON(r, v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// depthLimitReader fails reads once the JSON passing through it nests objects
+// and arrays deeper than max, before the decoder allocates for them
+type depthLimitReader struct {
+	r        io.Reader
+	max      int
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+func (d *depthLimitReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if d.max <= 0 {
+		return n, err
+	}
+	for _, c := range p[:n] {
+		switch {
+		case d.escaped:
+			d.escaped = false
+		case d.inString:
+			switch c {
+			case '\\':
+				d.escaped = true
+			case '"':
+				d.inString = false
+			}
+		case c == '"':
+			d.inString = true
+		case c == '{' || c == '[':
+			if d.depth++; d.depth > d.max {
+				return 0, errJSONTooDeep
+			}
+		case c == '}' || c == ']':
+			d.depth--
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// dlqFilter parses the DLQ list's filter query parameters. Their values are
+// checked by service.ValidateDLQFilter.
+func dlqFilter(r *http.Request) (store.DLQFilter, error) {
+	q := r.URL.Query()
+	var f store.DLQFilter
+
+	for _, raw := range q["failedStage"] {
+		f.Stages = append(f.Stages, strings.Split(raw, ",")...)
+	}
+	for _, raw := range q["errorType"] {
+		f.ErrorTypes = append(f.ErrorTypes, strings.Split(raw, ",")...)
+	}
+
+	var err error
+	if f.FailedAfter, err = queryTime(q.Get("failedAfter"), "failedAfter"); err != nil {
+		return f, err
+	}
+	if f.FailedBefore, err = queryTime(q.Get("failedBefore"), "failedBefore"); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// RetryDLQItem handles POST /api/v1/pipeline/dlq/{eventId}/retry
+func (h *Handler) RetryDLQItem(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	// The body is optional
+	var req generated.DLQRetryRequest
+	if err := h.decodeOptionalJSON(r, &req); err != nil {
+		return err
+	}
+	eventID := chi.URLParam(r, "eventId")
+	resp, err := h.service.RetryDLQItem(ctx, eventID, &req)
+	if err != nil {
+		return err
+	}
+
+	h.log.InfoContext(ctx, "DLQ item requeued", "eventId", eventID, "stage", resp.FromStage,
+		"principal", auth.FromContext(ctx))
+	if resp.OrderId != "" {
+		w.Header().Set("Location", "/api/v1/orders/"+resp.OrderId)
+	}
+	return h.writeJSON(w, http.StatusAccepted, resp)
+}
+
+// RetryDLQItems handles POST /api/v1/pipeline/dlq/retry
+func (h *Handler) RetryDLQItems(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	filter, err := dlqFilter(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	job, err := h.service.StartDLQRetry(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	h.log.InfoContext(ctx, "DLQ retry started", "jobId", job.JobId, "matched", job.Matched,
+		"principal", auth.FromContext(ctx))
+	w.Header().Set("Location", "/api/v1/pipeline/dlq/jobs/"+job.JobId)
+	return h.writeJSON(w, http.StatusAccepted, job)
+}
+
+// PurgeDLQ handles DELETE /api/v1/pipeline/dlq
+func (h *Handler) PurgeDLQ(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	filter, err := dlqFilter(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	job, err := h.service.StartDLQPurge(ctx, filter, r.URL.Query().Get("confirmationToken"))
+	if err != nil {
+		return err
+	}
+
+	h.log.InfoContext(ctx, "DLQ purge started", "jobId", job.JobId, "matched", job.Matched,
+		"principal", auth.FromContext(ctx))
+	w.Header().Set("Location", "/api/v1/pipeline/dlq/jobs/"+job.JobId)
+	return h.writeJSON(w, http.StatusAccepted, job)
+}
+
+// GetDLQJob handles GET /api/v1/pipeline/dlq/jobs/{jobId}
+func (h *Handler) GetDLQJob(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	job, err := h.service.DLQJob(ctx, chi.URLParam(r, "jobId"))
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return h.writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/problem"
+)
+
+const (
+	// pipelineEventsBuffer is how many events are buffered per stream
+	pipelineEventsBuffer = 256
+	// sseKeepAlive is how often an idle stream sends a comment line
+	sseKeepAlive = 15 * time.Second
+)
+
+// SSE event names, matching the AsyncAPI messages
+const (
+	sseStageComplete = "StageComplete"
+	ssePipelineError = "PipelineError"
+)
+
+// pipelineEventFilter selects the pipeline events a stream forwards
+type pipelineEventFilter struct {
+	stages     []string
+	errorTypes []string
+}
+
+// StreamPipelineEvents handles GET /api/v1/pipeline/events
+func (h *Handler) StreamPipelineEvents(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	filter, err := h.parsePipelineEventFilter(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	if h.infra.NATS == nil {
+		return problem.Upstream("nats", errors.New("not connected"))
+	}
+	// HEAD gets the stream's headers without waiting for events
+	if r.Method == http.MethodHead {
+		writeSSEHeader(w)
+		return nil
+	}
+
+	events := make(chan *nats.Msg, pipelineEventsBuffer)
+	subjects := []string{pipeline.TopicPipelineErrors}
+	if len(filter.errorTypes) == 0 {
+		subjects = append(subjects, pipeline.TopicStageCompleteAll)
+	}
+	for _, subject := range subjects {
+		sub, err := h.infra.NATS.ChanSubscribe(subject, events)
+		if err != nil {
+			return problem.Upstream("nats", err)
+		}
+		defer sub.Unsubscribe()
+	}
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	writeSSEHeader(w)
+	if err := rc.Flush(); err != nil {
+		return nil
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	var id int
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return nil
+			}
+		case msg := <-events:
+			event, ok := filter.match(msg)
+			if !ok {
+				continue
+			}
+			id++
+			if err := writeSSEEvent(w, id, event, msg.Data); err != nil {
+				return nil
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return nil
+		}
+	}
+}
+
+// writeSSEHeader starts an event stream response
+func writeSSEHeader(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+}
+
+// parsePipelineEventFilter parses the pipeline event stream's filter query
+// parameters
+func (h *Handler) parsePipelineEventFilter(r *http.Request) (pipelineEventFilter, error) {
+	q := r.URL.Query()
+	var f pipelineEventFilter
+
+	for _, raw := range q["stage"] {
+		for _, stage := range strings.Split(raw, ",") {
+			if !h.pipeline.HasStage(stage) {
+				return f, fmt.Errorf("stage %q is not a pipeline stage", stage)
+			}
+			f.stages = append(f.stages, stage)
+		}
+	}
+
+	for _, raw := range q["errorType"] {
+		for _, errorType := range strings.Split(raw, ",") {
+			if !slices.Contains(pipeline.ErrorTypes, errorType) {
+				return f, fmt.Errorf("errorType must be one of %s", strings.Join(pipeline.ErrorTypes, ", "))
+			}
+			f.errorTypes = append(f.errorTypes, errorType)
+		}
+	}
+	return f, nil
+}
+
+// match reports whether a published event passes the filter, and its SSE
+// event name
+func (f pipelineEventFilter) match(msg *nats.Msg) (string, bool) {
+	var event struct {
+		StageID   string `json:"stageId"`
+		ErrorType string `json:"errorType"`
+	}
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return "", false
+	}
+	if len(f.stages) > 0 && !slices.Contains(f.stages, event.StageID) {
+		return "", false
+	}
+	if msg.Subject != pipeline.TopicPipelineErrors {
+		return sseStageComplete, len(f.errorTypes) == 0
+	}
+	if len(f.errorTypes) > 0 && !slices.Contains(f.errorTypes, event.ErrorType) {
+		return "", false
+	}
+	return ssePipelineError, true
+}
+
+// writeSSEEvent writes one Server-Sent Event. data is single-line JSON.
+func writeSSEEvent(w io.Writer, id int, event string, data []byte) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event, data)
+	return err
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/service"
+)
+
+// exportContentTypes are the media types of exported files, by format
+var exportContentTypes = map[string]string{
+	service.ExportFormatCSV:    "text/csv; charset=utf-8",
+	service.ExportFormatNDJSON: "application/x-ndjson",
+}
+
+// ExportOrders handles POST /api/v1/orders/export
+func (h *Handler) ExportOrders(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	filter, err := orderSearch(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	job, err := h.service.StartOrderExport(ctx, filter, r.URL.Query().Get("format"))
+	if err != nil {
+		return err
+	}
+
+	h.log.InfoContext(ctx, "order export started", "jobId", job.JobId, "format", job.Format,
+		"matched", job.Matched, "principal", auth.FromContext(ctx))
+	w.Header().Set("Location", "/api/v1/orders/export/"+job.JobId)
+	return h.writeJSON(w, http.StatusAccepted, job)
+}
+
+// GetOrderExport handles GET /api/v1/orders/export/{jobId}
+func (h *Handler) GetOrderExport(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	job, err := h.service.OrderExportJob(ctx, chi.URLParam(r, "jobId"))
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return h.writeJSON(w, http.StatusOK, job)
+}
+
+// DownloadOrderExport handles GET /api/v1/orders/export/{jobId}/download
+func (h *Handler) DownloadOrderExport(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	jobID := chi.URLParam(r, "jobId")
+	content, format, err := h.service.OrderExportFile(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", `attachment; filename="orders-`+jobID+`.`+format+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(content)
+	return err
+}
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/synapse/synapse/internal/generated"
+)
+
+// QueryGraphQL handles POST /api/v1/graphql. Query and field errors are part
+// of the GraphQL response, so only an unreadable request is a problem.
+func (h *Handler) QueryGraphQL(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req generated.GraphQLRequest
+	if err := h.decodeJSON(r, &req); err != nil {
+		return err
+	}
+
+	resp := h.graphql.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	return h.writeJSON(w, http.StatusOK, resp)
+}
@@ -3,59 +3,218 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/buildinfo"
+	"github.com/synapse/synapse/internal/clock"
 	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/generated/apiv2"
+	"github.com/synapse/synapse/internal/graphapi"
 	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/logging"
+	"github.com/synapse/synapse/internal/metrics"
+	"github.com/synapse/synapse/internal/middleware"
 	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/ratelimit"
+	"github.com/synapse/synapse/internal/service"
+	"github.com/synapse/synapse/internal/store"
 )
 
+// Pagination limits for list endpoints
+const (
+	defaultPageLimit = service.DefaultPageLimit
+	maxPageLimit     = service.MaxPageLimit
+)
+
+// maxStatsBucket is the widest bucket pipeline statistics accept
+const maxStatsBucket = 24 * time.Hour
+
+// Store is the data the handler reads and writes, directly or through the
+// service. *store.Store keeps it in Postgres.
+type Store interface {
+	service.Store
+	store.ImportRepository
+	store.WebhookRepository
+	auth.APIKeyStore
+}
+
+var _ Store = (*store.Store)(nil)
+
+// Option customizes a Handler at construction time
+type Option func(*Handler)
+
+// WithStore sets the store the handler reads and writes, by default the
+// Infra's database, reading lists from its replica when it has one
+func WithStore(s Store) Option {
+	return func(h *Handler) {
+		h.orders = s
+	}
+}
+
 // Handler implements the generated.ServerInterface
 type Handler struct {
 	infra    *infra.Infra
 	pipeline *pipeline.Runner
+	orders   Store
+	service  *service.Service
+	graphql  *graphql.Schema
+	metrics  *metrics.Registry
+	apiKeys  *auth.APIKeys
+	log      *slog.Logger
+	clock    clock.Clock
+	// specVersions are the versions of the specs served, for GET /api/v1/version
+	specVersions generated.SpecVersions
+	// schemas validates imported orders; nil when the spec isn't available
+	schemas           *middleware.SchemaValidator
+	importConcurrency int
+	importMaxErrors   int
+	importMaxBytes    int64
+	// JSON request bodies nest at most maxJSONDepth levels; strictJSON
+	// rejects unknown fields
+	maxJSONDepth int
+	strictJSON   bool
+	// routeMiddleware wraps every route; apiMiddleware the /api routes
+	routeMiddleware []func(http.Handler) http.Handler
+	apiMiddleware   []func(http.Handler) http.Handler
 }
 
-// New creates a new Handler
-func New(infra *infra.Infra, pipeline *pipeline.Runner) *Handler {
-	return &Handler{
+var _ generated.ServerInterface = (*Handler)(nil)
+
+// New creates a new Handler. Requests time out after their route's timeout
+// in infra.Config with a 504. When infra.Config enables auth, /api routes
+// require an API key or, if an OIDC issuer is configured, a bearer token, and
+// are rate limited per client. Mutating /api calls are recorded in the audit
+// log unless it is disabled. Requests that don't match the OpenAPI spec are
+// rejected with a 400 listing the invalid fields, and operations it marks
+// deprecated announce it in their response headers.
+func New(infra *infra.Infra, pipeline *pipeline.Runner, opts ...Option) *Handler {
+	cfg := infra.Config
+	var apiKeyCacheTTL time.Duration
+	if cfg != nil {
+		apiKeyCacheTTL = time.Duration(cfg.APIKeyCacheTTLSeconds) * time.Second
+	}
+
+	h := &Handler{
 		infra:    infra,
 		pipeline: pipeline,
+		metrics:  metrics.New(infra, pipeline),
+		log:      logging.Module("handler"),
+		clock:    clock.Or(infra.Clock),
+
+		importConcurrency: defaultImportConcurrency,
+		importMaxErrors:   defaultImportMaxErrors,
+		maxJSONDepth:      defaultMaxJSONDepth,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.orders == nil {
+		h.orders = infra.Store().WithReplica(infra.ReadDB)
+	}
+	svc := service.New(infra, pipeline, h.orders)
+	h.service = svc
+	h.graphql = graphapi.New(svc)
+	h.apiKeys = auth.NewAPIKeys(h.orders, infra.Redis, infra.Keyspace(), apiKeyCacheTTL)
+	h.routeMiddleware = append(h.routeMiddleware, middleware.RequestID(), middleware.Metrics(h.metrics))
+	if cfg != nil {
+		h.routeMiddleware = append(h.routeMiddleware,
+			middleware.Timeout(time.Duration(cfg.RequestTimeoutMs)*time.Millisecond, cfg.RouteTimeouts))
+	}
+	if cfg != nil && cfg.HTTPCompressionEnabled {
+		h.routeMiddleware = append(h.routeMiddleware, middleware.Compress(cfg.HTTPCompressionMinBytes))
+	}
+	if cfg != nil {
+		if cfg.ImportConcurrency > 0 {
+			h.importConcurrency = cfg.ImportConcurrency
+		}
+		if cfg.ImportMaxErrors > 0 {
+			h.importMaxErrors = cfg.ImportMaxErrors
+		}
+		h.importMaxBytes = int64(cfg.ImportMaxBodyBytes)
+		if cfg.MaxJSONDepth > 0 {
+			h.maxJSONDepth = cfg.MaxJSONDepth
+		}
+		h.strictJSON = cfg.StrictJSONDecoding
+		// Imports are streamed and limited by importMaxBytes instead
+		h.apiMiddleware = append(h.apiMiddleware, middleware.BodyLimit(int64(cfg.MaxRequestBodyBytes), "/api/v1/orders/import"))
+		schemas, err := middleware.NewSchemaValidator(cfg.OpenAPISpecPath)
+		if err != nil {
+			h.log.Warn("imported orders won't be schema-validated", "error", err)
+		}
+		h.schemas = schemas
+		if h.specVersions, err = specVersions(cfg.OpenAPISpecPath); err != nil {
+			h.log.Warn("spec versions won't be reported", "error", err)
+		}
+		deprecations, err := middleware.NewDeprecations(cfg.OpenAPISpecPath)
+		if err != nil {
+			h.log.Warn("deprecated operations won't be announced", "error", err)
+		} else {
+			h.routeMiddleware = append(h.routeMiddleware, deprecations.Middleware)
+		}
+	}
+	if cfg != nil && cfg.AuthEnabled {
+		authenticators := []auth.Authenticator{h.apiKeys}
+		if cfg.OIDCIssuer != "" {
+			authenticators = append(authenticators, auth.NewOIDC(auth.OIDCConfig{
+				Issuer:      cfg.OIDCIssuer,
+				JWKSURL:     cfg.OIDCJWKSURL,
+				Audience:    cfg.OIDCAudience,
+				TenantClaim: cfg.OIDCTenantClaim,
+			}))
+		}
+		h.apiMiddleware = append(h.apiMiddleware, middleware.Authenticate(authenticators...))
+	}
+	if cfg != nil && cfg.RateLimitEnabled && cfg.RateLimitPerSecond > 0 {
+		limiter := ratelimit.NewRedis(infra.Redis, infra.Keyspace(), float64(cfg.RateLimitPerSecond), cfg.RateLimitBurst)
+		h.apiMiddleware = append(h.apiMiddleware, middleware.RateLimit(limiter))
+	}
+	if cfg != nil {
+		// After authentication, so only clients learn what the spec expects
+		validator, err := middleware.NewRequestValidator(cfg.OpenAPISpecPath)
+		if err != nil {
+			h.log.Warn("requests won't be validated against the spec", "error", err)
+		} else {
+			h.apiMiddleware = append(h.apiMiddleware, validator.Middleware)
+		}
+	}
+	if cfg != nil && cfg.AuditLogEnabled {
+		// GraphQL queries are read-only
+		h.apiMiddleware = append(h.apiMiddleware, middleware.Audit(auditRecorder{svc}, "/api/v1/graphql"))
 	}
+	return h
 }
 
-// RegisterRoutes registers all HTTP routes
+// RegisterRoutes registers all HTTP routes. Every route gets a request ID,
+// reports request metrics, including requests rejected by the /api
+// middleware, and has large JSON responses compressed when enabled. GET
+// routes answer HEAD too, and OPTIONS is answered with the path's allowed
+// methods, without authentication, so gateways can probe them. r must have
+// no routes yet.
 func (h *Handler) RegisterRoutes(r chi.Router) {
-	// Orders
-	r.Post("/api/v1/orders", h.wrapHandler(h.IngestOrder))
-	r.Get("/api/v1/orders", h.wrapHandler(h.ListOrders))
-	r.Get("/api/v1/orders/{orderId}", h.wrapHandler(h.GetOrder))
-	r.Delete("/api/v1/orders/{orderId}", h.wrapHandler(h.CancelOrder))
-	r.Get("/api/v1/orders/{orderId}/events", h.wrapHandler(h.GetOrderEvents))
-
-	// Pipeline
-	r.Get("/api/v1/pipeline/stages", h.wrapHandler(h.ListPipelineStages))
-	r.Get("/api/v1/pipeline/stages/{stageId}", h.wrapHandler(h.GetPipelineStage))
-	r.Patch("/api/v1/pipeline/stages/{stageId}", h.wrapHandler(h.UpdatePipelineStage))
-	r.Get("/api/v1/pipeline/dlq", h.wrapHandler(h.ListDLQItems))
-	r.Post("/api/v1/pipeline/dlq/{eventId}/retry", h.wrapHandler(h.RetryDLQItem))
-
-	// Health
-	r.Get("/health", h.wrapHandler(h.GetHealth))
-	r.Get("/health/live", h.wrapHandler(h.GetLiveness))
-	r.Get("/health/ready", h.wrapHandler(h.GetReadiness))
-	r.Get("/metrics", h.wrapHandler(h.GetMetrics))
-}
-
-func (h *Handler) wrapHandler(fn func(context.Context, http.ResponseWriter, *http.Request) error) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if err := fn(r.Context(), w, r); err != nil {
-			h.writeError(w, err)
-		}
-	}
+	r.Use(chimiddleware.GetHead)
+	r.MethodNotAllowed(middleware.MethodNotAllowed(r))
+	r = r.With(h.routeMiddleware...)
+
+	// Routes come from the hand-maintained wrappers in server.gen.go, which
+	// conformance tests check against the specs' operations
+	api := &generated.ServerInterfaceWrapper{Handler: h, ErrorHandlerFunc: problem.Write}
+	v2 := &apiv2.ServerInterfaceWrapper{Handler: v2Handler{h}, ErrorHandlerFunc: problem.Write}
+	r.Group(func(r chi.Router) {
+		r.Use(h.apiMiddleware...)
+		api.RegisterSecuredRoutes(r)
+		v2.RegisterSecuredRoutes(r)
+	})
+	api.RegisterPublicRoutes(r)
+	v2.RegisterPublicRoutes(r)
 }
 
 func (h *Handler) writeJSON(w http.ResponseWriter, status int, v any) error {
@@ -64,153 +223,361 @@ func (h *Handler) writeJSON(w http.ResponseWriter, status int, v any) error {
 	return json.NewEncoder(w).Encode(v)
 }
 
-func (h *Handler) writeError(w http.ResponseWriter, err error) {
-	w.Header().Set("Content-Type", "application/problem+json")
-	w.WriteHeader(http.StatusInternalServerError)
-	json.NewEncoder(w).Encode(map[string]any{
-		"type":   "https://synapse.example.com/problems/internal-error",
-		"title":  "Internal Server Error",
-		"status": 500,
-		"detail": err.Error(),
-	})
+// queryInt parses an optional integer query parameter within [min, max]
+func queryInt(r *http.Request, name string, defaultValue, min, max int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return defaultValue, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("%s must be an integer between %d and %d", name, min, max)
+	}
+	return v, nil
 }
 
 // IngestOrder handles POST /api/v1/orders
 func (h *Handler) IngestOrder(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	var req generated.OrderCreateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return h.writeJSON(w, http.StatusBadRequest, map[string]any{
-			"type":   "https://synapse.example.com/problems/invalid-json",
-			"title":  "Invalid JSON",
-			"status": 400,
-			"detail": err.Error(),
-		})
+	if err := h.decodeJSON(r, &req); err != nil {
+		return err
 	}
 
-	orderID := uuid.New().String()
-
-	// Publish to pipeline
-	if err := h.pipeline.IngestOrder(ctx, orderID, &req); err != nil {
+	resp, err := h.service.IngestOrder(ctx, &req)
+	if err != nil {
 		return err
 	}
 
-	w.Header().Set("Location", "/api/v1/orders/"+orderID)
-	return h.writeJSON(w, http.StatusAccepted, generated.OrderAcceptedResponse{
-		OrderId: orderID,
-		Status:  "accepted",
-		Message: "Order accepted for processing",
-	})
+	w.Header().Set("Location", "/api/v1/orders/"+resp.OrderId)
+	return h.writeJSON(w, http.StatusAccepted, resp)
 }
 
 // ListOrders handles GET /api/v1/orders
 func (h *Handler) ListOrders(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	// TODO: Implement with database query
+	filter, err := orderFilter(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	return h.listOrders(ctx, w, r, filter)
+}
+
+// SearchOrders handles GET /api/v1/orders/search
+func (h *Handler) SearchOrders(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	filter, err := orderSearch(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	return h.listOrders(ctx, w, r, filter)
+}
+
+// listOrders writes the page of orders matching filter that r asks for, or
+// all of them as CSV when r accepts text/csv
+func (h *Handler) listOrders(ctx context.Context, w http.ResponseWriter, r *http.Request, filter store.OrderFilter) error {
+	w.Header().Add("Vary", "Accept")
+	if wantsCSV(r) {
+		return h.exportOrdersCSV(ctx, w, filter)
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+
+	orders, err := h.service.ListOrders(ctx, service.ListOrdersParams{
+		Filter: filter,
+		Limit:  page.limit,
+		Offset: page.offset,
+		After:  page.after,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(orders.Total))
+	if link := paginationLinks(r, page, orders.HasMore, orders.NextCursor); link != "" {
+		w.Header().Add("Link", link)
+	}
 	return h.writeJSON(w, http.StatusOK, generated.OrderListResponse{
-		Orders: []generated.OrderSummary{},
+		Orders: orders.Orders,
+		Pagination: generated.Pagination{
+			Limit:      page.limit,
+			Offset:     page.offset,
+			Cursor:     page.cursor,
+			NextCursor: orders.NextCursor,
+			Total:      orders.Total,
+			HasMore:    orders.HasMore,
+		},
 	})
 }
 
-// GetOrder handles GET /api/v1/orders/{orderId}
+// GetOrder handles GET /api/v1/orders/{orderId}. With wait, it long-polls
+// for the order's final status.
 func (h *Handler) GetOrder(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	orderID := chi.URLParam(r, "orderId")
-	// TODO: Implement with database query
-	return h.writeJSON(w, http.StatusOK, generated.OrderResponse{
-		OrderId: orderID,
-		Status:  "processing",
-	})
+	wait, err := queryDuration(r, "wait", service.MaxOrderWait)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+
+	var resp *generated.OrderResponse
+	if wait > 0 {
+		// The response may be held past the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + orderWaitGrace))
+		resp, err = h.service.WaitForOrder(ctx, orderID, wait)
+	} else {
+		resp, err = h.service.GetOrder(ctx, orderID)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Last-Modified", resp.UpdatedAt.UTC().Format(http.TimeFormat))
+	if resp.Version > 0 {
+		w.Header().Set("ETag", service.OrderETag(resp.Version))
+	}
+	return h.writeJSON(w, http.StatusOK, resp)
 }
 
-// CancelOrder handles DELETE /api/v1/orders/{orderId}
+// CancelOrder handles DELETE /api/v1/orders/{orderId}. With If-Match, only
+// the version of the order it names is cancelled.
 func (h *Handler) CancelOrder(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	orderID := chi.URLParam(r, "orderId")
-	// TODO: Implement cancellation logic
-	return h.writeJSON(w, http.StatusOK, generated.OrderCancelledResponse{
-		OrderId:     orderID,
-		Status:      "cancelled",
-		CancelledAt: time.Now(),
-	})
+	resp, err := h.service.CancelOrder(ctx, chi.URLParam(r, "orderId"), service.ParseIfMatch(r.Header.Get("If-Match")))
+	if err != nil {
+		return err
+	}
+	return h.writeJSON(w, http.StatusOK, resp)
 }
 
 // GetOrderEvents handles GET /api/v1/orders/{orderId}/events
 func (h *Handler) GetOrderEvents(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	orderID := chi.URLParam(r, "orderId")
-	// TODO: Implement with database query
-	return h.writeJSON(w, http.StatusOK, generated.OrderEventsResponse{
-		OrderId: orderID,
-		Events:  []generated.OrderEvent{},
+
+	page, err := parsePage(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	var descending bool
+	switch r.URL.Query().Get("order") {
+	case "", "asc":
+	case "desc":
+		descending = true
+	default:
+		return problem.InvalidParameter("order must be one of asc, desc")
+	}
+
+	events, err := h.service.ListOrderEvents(ctx, orderID, service.ListEventsParams{
+		Limit:      page.limit,
+		Offset:     page.offset,
+		Descending: descending,
+		After:      page.after,
 	})
+	if err != nil {
+		return err
+	}
+
+	resp := generated.OrderEventsResponse{
+		OrderId: orderID,
+		Events:  events.Events,
+		Pagination: &generated.Pagination{
+			Limit:      page.limit,
+			Offset:     page.offset,
+			Cursor:     page.cursor,
+			Total:      events.Total,
+			HasMore:    events.HasMore,
+			NextCursor: events.NextCursor,
+		},
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(events.Total))
+	if link := paginationLinks(r, page, events.HasMore, events.NextCursor); link != "" {
+		w.Header().Add("Link", link)
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
+	return h.writeJSON(w, http.StatusOK, resp)
 }
 
 // ListPipelineStages handles GET /api/v1/pipeline/stages
 func (h *Handler) ListPipelineStages(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	stages := h.pipeline.GetStages()
 	return h.writeJSON(w, http.StatusOK, generated.PipelineStagesResponse{
-		Stages: stages,
+		Stages: h.service.PipelineStages(ctx),
 	})
 }
 
 // GetPipelineStage handles GET /api/v1/pipeline/stages/{stageId}
 func (h *Handler) GetPipelineStage(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	stageID := chi.URLParam(r, "stageId")
-	stage := h.pipeline.GetStage(stageID)
-	if stage == nil {
-		w.WriteHeader(http.StatusNotFound)
-		return nil
+	stage, err := h.service.PipelineStage(ctx, chi.URLParam(r, "stageId"))
+	if err != nil {
+		return err
 	}
 	return h.writeJSON(w, http.StatusOK, stage)
 }
 
 // UpdatePipelineStage handles PATCH /api/v1/pipeline/stages/{stageId}
 func (h *Handler) UpdatePipelineStage(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	// TODO: Implement stage update
-	return h.writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	var req generated.PipelineStageUpdateRequest
+	if err := h.decodeJSON(r, &req); err != nil {
+		return err
+	}
+	stageID := chi.URLParam(r, "stageId")
+	stage, err := h.service.UpdatePipelineStage(ctx, stageID, &req)
+	if err != nil {
+		return err
+	}
+
+	h.log.InfoContext(ctx, "pipeline stage updated", "stage", stageID, "status", stage.Status,
+		"principal", auth.FromContext(ctx))
+	return h.writeJSON(w, http.StatusOK, stage)
 }
 
 // ListDLQItems handles GET /api/v1/pipeline/dlq
 func (h *Handler) ListDLQItems(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	// TODO: Implement DLQ listing
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	filter, err := dlqFilter(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	w.Header().Add("Vary", "Accept")
+	if wantsCSV(r) {
+		return h.exportDLQCSV(ctx, w, filter)
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+
+	items, err := h.service.ListDLQItems(ctx, service.ListDLQParams{
+		Filter: filter,
+		Limit:  page.limit,
+		Offset: page.offset,
+		After:  page.after,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(items.Total))
+	if link := paginationLinks(r, page, items.HasMore, items.NextCursor); link != "" {
+		w.Header().Add("Link", link)
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
 	return h.writeJSON(w, http.StatusOK, generated.DLQListResponse{
-		Items: []generated.DLQItem{},
+		Items: items.Items,
+		Pagination: generated.Pagination{
+			Limit:      page.limit,
+			Offset:     page.offset,
+			Cursor:     page.cursor,
+			NextCursor: items.NextCursor,
+			Total:      items.Total,
+			HasMore:    items.HasMore,
+		},
 	})
 }
 
-// RetryDLQItem handles POST /api/v1/pipeline/dlq/{eventId}/retry
-func (h *Handler) RetryDLQItem(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	eventID := chi.URLParam(r, "eventId")
-	// TODO: Implement retry logic
-	return h.writeJSON(w, http.StatusAccepted, map[string]string{
-		"eventId": eventID,
-		"status":  "requeued",
-	})
+// GetRoutingStats handles GET /api/v1/pipeline/routing/stats
+func (h *Handler) GetRoutingStats(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-cache")
+	return h.writeJSON(w, http.StatusOK, h.service.RoutingStats())
+}
+
+// GetPipelineStats handles GET /api/v1/pipeline/stats
+func (h *Handler) GetPipelineStats(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	var (
+		p   service.StatsParams
+		err error
+	)
+	if p.From, err = queryTime(q.Get("from"), "from"); err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	if p.To, err = queryTime(q.Get("to"), "to"); err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	if p.Bucket, err = queryDuration(r, "bucket", maxStatsBucket); err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+
+	stats, err := h.service.PipelineStats(ctx, p)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return h.writeJSON(w, http.StatusOK, stats)
 }
 
-// GetHealth handles GET /health
+// GetHealth handles GET /health. The service is unhealthy when a critical
+// dependency is down, and degraded when another dependency is or a pipeline
+// stage isn't running normally.
 func (h *Handler) GetHealth(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	health := h.infra.Healthy(ctx)
-	status := "healthy"
-	httpStatus := http.StatusOK
+	now := h.clock.Now().UTC()
+	build := buildinfo.Get()
+	resp := generated.HealthResponse{
+		Status:     "healthy",
+		Version:    build.Version,
+		Commit:     build.Commit,
+		BuildTime:  build.BuildTime,
+		Timestamp:  now,
+		Components: make(map[string]generated.ComponentHealth),
+	}
+	if started := h.infra.StartedAt; !started.IsZero() {
+		resp.StartedAt = &started
+		resp.Uptime = int(now.Sub(started).Seconds())
+	}
 
-	components := make(map[string]any)
-	for name, err := range health {
-		if err != nil {
-			status = "unhealthy"
+	httpStatus := http.StatusOK
+	degraded := false
+	for name, check := range h.infra.Check(ctx) {
+		resp.Components[name] = componentHealth(check)
+		switch {
+		case check.Err == nil:
+		case check.Critical:
+			resp.Status = "unhealthy"
 			httpStatus = http.StatusServiceUnavailable
-			components[name] = map[string]any{
-				"status": "unhealthy",
-				"error":  err.Error(),
-			}
-		} else {
-			components[name] = map[string]any{
-				"status": "healthy",
-			}
+		default:
+			degraded = true
+		}
+	}
+	if degraded && resp.Status == "healthy" {
+		resp.Status = "degraded"
+	}
+	if leadership := h.infra.Leadership(); len(leadership) > 0 {
+		resp.Leadership = leadership
+	}
+	for _, stage := range h.pipeline.GetStages() {
+		resp.Stages = append(resp.Stages, generated.StageHealth{StageId: stage.StageId, Status: stage.Status})
+		if stage.Status != generated.StageStatusHealthy && resp.Status == "healthy" {
+			resp.Status = "degraded"
 		}
 	}
 
-	return h.writeJSON(w, httpStatus, generated.HealthResponse{
-		Status:     status,
-		Components: components,
-	})
+	w.Header().Set("Cache-Control", "no-store")
+	return h.writeJSON(w, httpStatus, resp)
+}
+
+// componentHealth converts a dependency check to its API representation
+func componentHealth(check infra.ComponentCheck) generated.ComponentHealth {
+	c := generated.ComponentHealth{
+		Status:        "healthy",
+		Critical:      check.Critical,
+		LatencyMs:     float64(check.Latency.Microseconds()) / 1000,
+		LastCheckedAt: &check.CheckedAt,
+	}
+	if check.Err != nil {
+		c.Status = "unhealthy"
+		c.Error = check.Err.Error()
+	}
+	if !check.LastSuccess.IsZero() {
+		c.LastSuccessAt = &check.LastSuccess
+	}
+	return c
 }
 
 // GetLiveness handles GET /health/live
@@ -218,21 +585,36 @@ func (h *Handler) GetLiveness(ctx context.Context, w http.ResponseWriter, r *htt
 	return h.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
-// GetReadiness handles GET /health/ready
+// GetReadiness handles GET /health/ready. Only critical dependencies take
+// the service out of rotation; when another dependency is down the service
+// stays ready but reports itself degraded.
 func (h *Handler) GetReadiness(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	health := h.infra.Healthy(ctx)
-	for _, err := range health {
-		if err != nil {
-			return h.writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not_ready"})
+	resp := generated.ReadinessResponse{
+		Status:       "ready",
+		Dependencies: make(map[string]string),
+	}
+	httpStatus := http.StatusOK
+	for name, check := range h.infra.Check(ctx) {
+		if check.Err == nil {
+			resp.Dependencies[name] = "ok"
+			continue
+		}
+		resp.Dependencies[name] = check.Err.Error()
+		if check.Critical {
+			resp.Status = "not_ready"
+			resp.Reason = "dependency unavailable"
+			httpStatus = http.StatusServiceUnavailable
+		} else {
+			resp.Degraded = true
 		}
 	}
-	return h.writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+
+	w.Header().Set("Cache-Control", "no-store")
+	return h.writeJSON(w, httpStatus, resp)
 }
 
 // GetMetrics handles GET /metrics
 func (h *Handler) GetMetrics(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	// TODO: Implement Prometheus metrics
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte("# Synapse metrics\n"))
+	h.metrics.Handler().ServeHTTP(w, r)
 	return nil
 }
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/importer"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// Import defaults, used when infra.Config doesn't set them
+const (
+	defaultImportConcurrency = 8
+	defaultImportMaxErrors   = 100
+	// importProgressEvery is how many rows are read between progress saves
+	importProgressEvery = 500
+	// importLockPrefix is how many leading bytes of an upload tell it apart
+	// from others for locking
+	importLockPrefix = 64 << 10
+)
+
+// ImportOrders handles POST /api/v1/orders/import
+func (h *Handler) ImportOrders(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	format, ok := importer.FormatFor(r.Header.Get("Content-Type"))
+	if !ok {
+		return problem.UnsupportedMediaType("Imports must be sent as application/x-ndjson or text/csv")
+	}
+	// Imports are streamed, so they get a limit of their own
+	if h.importMaxBytes > 0 {
+		if r.ContentLength > h.importMaxBytes {
+			return problem.PayloadTooLarge(h.importMaxBytes)
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, h.importMaxBytes)
+	}
+	// Read errors surface when the rows are read
+	body := bufio.NewReaderSize(r.Body, importLockPrefix)
+	prefix, _ := body.Peek(importLockPrefix)
+	rows, err := importer.NewReader(format, body)
+	if err != nil {
+		return problem.Validation(fmt.Sprintf("The import could not be read: %v", err))
+	}
+
+	// The same upload is imported once at a time across replicas; other
+	// imports run alongside it
+	lock, err := h.infra.Locker().Acquire(ctx, importLockName(format, prefix))
+	if errors.Is(err, infra.ErrLocked) {
+		return problem.Conflict("import-in-progress", "Import In Progress",
+			"The same import is already running; retry once it has completed")
+	}
+	if err != nil {
+		return problem.Upstream("redis", err)
+	}
+	defer func() {
+		if err := lock.Release(context.WithoutCancel(ctx)); err != nil {
+			h.log.WarnContext(ctx, "releasing import lock", "error", err)
+		}
+	}()
+
+	now := h.clock.Now().UTC()
+	job := &store.ImportJob{
+		ID:        uuid.New().String(),
+		Status:    string(generated.ImportJobStatusProcessing),
+		Format:    string(format),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.orders.CreateImportJob(ctx, job); err != nil {
+		return problem.Upstream("postgres", err)
+	}
+
+	// Where the server allows it, send the job's location before reading the
+	// rest of the body so the client can poll progress during a long upload
+	w.Header().Set("Location", "/api/v1/orders/import/"+job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	rc := http.NewResponseController(w)
+	early := rc.EnableFullDuplex() == nil
+	if early {
+		w.WriteHeader(http.StatusAccepted)
+		_ = rc.Flush()
+	}
+
+	// Progress is saved even if the client goes away mid-import
+	saveCtx := context.WithoutCancel(ctx)
+	importCtx, cancel := lock.Guard(ctx)
+	defer cancel()
+	progress, runErr := importer.Run(importCtx, rows, importer.Options{
+		Concurrency:   h.importConcurrency,
+		MaxErrors:     h.importMaxErrors,
+		ProgressEvery: importProgressEvery,
+		Validate:      h.validateImportRow,
+		Ingest:        h.ingestImported,
+		OnProgress: func(p importer.Progress) {
+			if err := h.saveImportJob(saveCtx, job, p, nil); err != nil {
+				h.log.WarnContext(saveCtx, "saving import progress", "job_id", job.ID, "error", err)
+			}
+		},
+	})
+	if runErr != nil && errors.Is(context.Cause(importCtx), infra.ErrLockLost) {
+		runErr = fmt.Errorf("import stopped: %w", infra.ErrLockLost)
+	}
+	if err := h.saveImportJob(saveCtx, job, progress, &runErr); err != nil {
+		h.log.ErrorContext(saveCtx, "saving import job", "job_id", job.ID, "error", err)
+	}
+	if runErr != nil {
+		h.log.ErrorContext(ctx, "import failed", "job_id", job.ID, "processed", progress.Processed, "error", runErr)
+	} else {
+		h.log.InfoContext(ctx, "import completed", "job_id", job.ID, "format", job.Format,
+			"accepted", progress.Accepted, "rejected", progress.Rejected, "skipped", progress.Skipped)
+	}
+
+	resp, err := importJobResponse(job)
+	if err != nil {
+		return err
+	}
+	if early {
+		return json.NewEncoder(w).Encode(resp)
+	}
+	return h.writeJSON(w, http.StatusAccepted, resp)
+}
+
+// importLockName names the lock of an upload after its format and first
+// bytes, which identify the file being imported without reading it all
+func importLockName(format importer.Format, prefix []byte) string {
+	sum := sha256.Sum256(append([]byte(string(format)+"\n"), prefix...))
+	return "orders-import:" + hex.EncodeToString(sum[:16])
+}
+
+// GetImportJob handles GET /api/v1/orders/import/{jobId}
+func (h *Handler) GetImportJob(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	jobID := chi.URLParam(r, "jobId")
+
+	job, err := h.orders.GetImportJob(ctx, jobID)
+	if errors.Is(err, store.ErrNotFound) {
+		return problem.NotFound("Import job %s not found", jobID)
+	}
+	if err != nil {
+		return problem.Upstream("postgres", err)
+	}
+
+	resp, err := importJobResponse(job)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return h.writeJSON(w, http.StatusOK, resp)
+}
+
+// validateImportRow checks an imported order against the order schema, when
+// the spec could be loaded
+func (h *Handler) validateImportRow(order map[string]any) []generated.ValidationError {
+	if h.schemas == nil {
+		return nil
+	}
+	return h.schemas.Validate("OrderCreateRequest", order)
+}
+
+// ingestImported publishes an imported order. Orders that already exist are
+// skipped, so an interrupted import can be re-run.
+func (h *Handler) ingestImported(ctx context.Context, req *generated.OrderCreateRequest) (importer.Outcome, error) {
+	orderID := req.OrderId
+	if orderID == "" {
+		orderID = uuid.New().String()
+	} else if _, err := h.orders.GetOrder(ctx, orderID); err == nil {
+		return importer.Skipped, nil
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return importer.Accepted, err
+	}
+	if err := h.pipeline.IngestOrder(ctx, orderID, req); err != nil {
+		return importer.Accepted, err
+	}
+	return importer.Accepted, nil
+}
+
+// saveImportJob records an import's progress. When runErr is given the import
+// has finished, failed if *runErr is set.
+func (h *Handler) saveImportJob(ctx context.Context, job *store.ImportJob, p importer.Progress, runErr *error) error {
+	errs, err := json.Marshal(p.Errors)
+	if err != nil {
+		return fmt.Errorf("encoding import errors: %w", err)
+	}
+	now := h.clock.Now().UTC()
+	job.Processed = p.Processed
+	job.Accepted = p.Accepted
+	job.Rejected = p.Rejected
+	job.Skipped = p.Skipped
+	job.Errors = errs
+	job.UpdatedAt = now
+	if runErr != nil {
+		job.Status = string(generated.ImportJobStatusCompleted)
+		if *runErr != nil {
+			job.Status = string(generated.ImportJobStatusFailed)
+			job.Error = (*runErr).Error()
+		}
+		job.CompletedAt = &now
+	}
+	return h.orders.UpdateImportJob(ctx, job)
+}
+
+// importJobResponse converts a stored import job to its representation
+func importJobResponse(j *store.ImportJob) (*generated.ImportJob, error) {
+	resp := &generated.ImportJob{
+		JobId:       j.ID,
+		Status:      generated.ImportJobStatus(j.Status),
+		Format:      j.Format,
+		Processed:   j.Processed,
+		Accepted:    j.Accepted,
+		Rejected:    j.Rejected,
+		Skipped:     j.Skipped,
+		Errors:      []generated.ImportRowError{},
+		Error:       j.Error,
+		CreatedAt:   j.CreatedAt,
+		UpdatedAt:   j.UpdatedAt,
+		CompletedAt: j.CompletedAt,
+	}
+	if len(j.Errors) > 0 {
+		if err := json.Unmarshal(j.Errors, &resp.Errors); err != nil {
+			return nil, problem.Internal(fmt.Errorf("decoding errors of import job %s: %w", j.ID, err))
+		}
+		if resp.Errors == nil {
+			resp.Errors = []generated.ImportRowError{}
+		}
+	}
+	return resp, nil
+}
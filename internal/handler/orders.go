@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/synapse/synapse/internal/store"
+)
+
+// orderWaitGrace is how long a long-polled order may take to write, past the
+// wait
+const orderWaitGrace = 10 * time.Second
+
+// orderFilter parses the order list's filter query parameters. Their values
+// are checked by service.ValidateOrderFilter.
+func orderFilter(r *http.Request) (store.OrderFilter, error) {
+	q := r.URL.Query()
+	var f store.OrderFilter
+
+	for _, raw := range q["status"] {
+		f.Statuses = append(f.Statuses, strings.Split(raw, ",")...)
+	}
+
+	f.CustomerID = q.Get("customerId")
+	f.Destination = q.Get("destination")
+
+	var err error
+	if f.CreatedAfter, err = queryTime(q.Get("createdAfter"), "createdAfter"); err != nil {
+		return f, err
+	}
+	if f.CreatedBefore, err = queryTime(q.Get("createdBefore"), "createdBefore"); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// orderSearch parses the order search's query parameters: the order list
+// filters plus the search criteria. Their values are checked by
+// service.ValidateOrderFilter.
+func orderSearch(r *http.Request) (store.OrderFilter, error) {
+	f, err := orderFilter(r)
+	if err != nil {
+		return f, err
+	}
+	q := r.URL.Query()
+
+	f.SKU = q.Get("sku")
+	f.Currency = q.Get("currency")
+	f.CustomerTier = q.Get("customerTier")
+	f.ShippingCountry = q.Get("shippingCountry")
+	f.Text = strings.TrimSpace(q.Get("q"))
+
+	if f.MinAmount, err = queryFloat(q.Get("minAmount"), "minAmount"); err != nil {
+		return f, err
+	}
+	if f.MaxAmount, err = queryFloat(q.Get("maxAmount"), "maxAmount"); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// queryFloat parses an optional number parameter
+func queryFloat(raw, name string) (*float64, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("%s must be a number", name)
+	}
+	return &v, nil
+}
+
+// queryTime parses an optional RFC 3339 timestamp parameter
+func queryTime(raw, name string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+// queryDuration parses an optional duration query parameter of at most max,
+// given like 30s or as a number of seconds
+func queryDuration(r *http.Request, name string, max time.Duration) (time.Duration, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if secs, serr := strconv.Atoi(raw); serr == nil {
+		d, err = time.Duration(secs)*time.Second, nil
+	}
+	if err != nil || d < 0 || d > max {
+		return 0, fmt.Errorf("%s must be a duration of at most %s, e.g. 30s", name, max)
+	}
+	return d, nil
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/synapse/synapse/internal/service"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// pageRequest is a parsed limit/offset/cursor page selection
+type pageRequest struct {
+	limit  int
+	offset int
+	cursor string
+	after  *store.Cursor
+}
+
+// parsePage reads the limit, offset and cursor query parameters. A cursor
+// and a non-zero offset are mutually exclusive.
+func parsePage(r *http.Request) (pageRequest, error) {
+	var (
+		p   pageRequest
+		err error
+	)
+	if p.limit, err = queryInt(r, "limit", defaultPageLimit, 1, maxPageLimit); err != nil {
+		return p, err
+	}
+	if p.offset, err = queryInt(r, "offset", 0, 0, math.MaxInt32); err != nil {
+		return p, err
+	}
+	if p.cursor = r.URL.Query().Get("cursor"); p.cursor != "" {
+		if p.offset > 0 {
+			return p, errors.New("cursor and offset cannot be combined")
+		}
+		if p.after, err = service.DecodeCursor(p.cursor); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// paginationLinks builds an RFC 8288 Link header with next/prev pages,
+// preserving the request's other query parameters. The next page follows
+// nextCursor when there is one; prev is only available for offset paging.
+func paginationLinks(r *http.Request, p pageRequest, hasMore bool, nextCursor string) string {
+	page := func(rel string, set map[string]string) string {
+		q := r.URL.Query()
+		q.Del("offset")
+		q.Del("cursor")
+		q.Set("limit", strconv.Itoa(p.limit))
+		for k, v := range set {
+			q.Set(k, v)
+		}
+		return "<" + r.URL.Path + "?" + q.Encode() + `>; rel="` + rel + `"`
+	}
+
+	var links []string
+	if hasMore {
+		if nextCursor != "" {
+			links = append(links, page("next", map[string]string{"cursor": nextCursor}))
+		} else {
+			links = append(links, page("next", map[string]string{"offset": strconv.Itoa(p.offset + p.limit)}))
+		}
+	}
+	if p.cursor == "" && p.offset > 0 {
+		links = append(links, page("prev", map[string]string{"offset": strconv.Itoa(max(p.offset-p.limit, 0))}))
+	}
+	return strings.Join(links, ", ")
+}
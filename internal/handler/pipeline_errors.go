@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/service"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// pipelineErrorFilter parses the pipeline error list's filter query
+// parameters. Their values are checked by service.ValidatePipelineErrorFilter.
+func pipelineErrorFilter(r *http.Request) (store.PipelineErrorFilter, error) {
+	q := r.URL.Query()
+	var f store.PipelineErrorFilter
+
+	for _, raw := range q["stage"] {
+		f.Stages = append(f.Stages, strings.Split(raw, ",")...)
+	}
+	for _, raw := range q["errorType"] {
+		f.ErrorTypes = append(f.ErrorTypes, strings.Split(raw, ",")...)
+	}
+
+	var err error
+	if f.OccurredAfter, err = queryTime(q.Get("occurredAfter"), "occurredAfter"); err != nil {
+		return f, err
+	}
+	if f.OccurredBefore, err = queryTime(q.Get("occurredBefore"), "occurredBefore"); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// ListPipelineErrors handles GET /api/v1/pipeline/errors
+func (h *Handler) ListPipelineErrors(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	filter, err := pipelineErrorFilter(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+
+	errs, err := h.service.ListPipelineErrors(ctx, service.ListPipelineErrorsParams{
+		Filter: filter,
+		Limit:  page.limit,
+		Offset: page.offset,
+		After:  page.after,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(errs.Total))
+	if link := paginationLinks(r, page, errs.HasMore, errs.NextCursor); link != "" {
+		w.Header().Add("Link", link)
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
+	return h.writeJSON(w, http.StatusOK, generated.PipelineErrorListResponse{
+		Items: errs.Items,
+		Pagination: generated.Pagination{
+			Limit:      page.limit,
+			Offset:     page.offset,
+			Cursor:     page.cursor,
+			NextCursor: errs.NextCursor,
+			Total:      errs.Total,
+			HasMore:    errs.HasMore,
+		},
+	})
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/service"
+	"golang.org/x/net/websocket"
+)
+
+// StreamOrder handles GET /api/v1/orders/{orderId}/stream
+func (h *Handler) StreamOrder(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	orderID := chi.URLParam(r, "orderId")
+
+	if !isWebSocketUpgrade(r) {
+		w.Header().Set("Upgrade", "websocket")
+		w.Header().Set("Connection", "Upgrade")
+		return problem.UpgradeRequired("Order status updates are streamed over WebSocket")
+	}
+	watch, err := h.service.WatchOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	defer watch.Close()
+
+	websocket.Server{
+		// Streams authenticate with API keys or bearer tokens rather than
+		// cookies, so cross-origin clients are allowed
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			streamOrderUpdates(ws, watch)
+		},
+	}.ServeHTTP(w, r)
+	return nil
+}
+
+// streamOrderUpdates sends the snapshot and then each update as a text frame,
+// until the order reaches a final status or the client disconnects
+func streamOrderUpdates(ws *websocket.Conn, watch *service.OrderWatch) {
+	// Clients aren't expected to send anything; reading notices when they go away
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	if err := websocket.JSON.Send(ws, watch.Snapshot); err != nil || pipeline.IsFinalStatus(watch.Snapshot.Status) {
+		return
+	}
+	for {
+		update, err := watch.Next(ctx)
+		if err != nil {
+			return
+		}
+		if err := websocket.JSON.Send(ws, update); err != nil || pipeline.IsFinalStatus(update.Status) {
+			return
+		}
+	}
+}
+
+// isWebSocketUpgrade reports whether r asks to upgrade to WebSocket
+func isWebSocketUpgrade(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, v := range strings.Split(r.Header.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
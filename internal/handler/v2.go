@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/generated/apiv2"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/service"
+)
+
+// v2Handler implements apiv2.ServerInterface on the same service layer as
+// the v1 handlers, converting v1 representations to their v2 shape.
+type v2Handler struct {
+	*Handler
+}
+
+var _ apiv2.ServerInterface = v2Handler{}
+
+// ListOrders handles GET /api/v2/orders
+func (h v2Handler) ListOrders(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	filter, err := orderFilter(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+
+	orders, err := h.service.ListOrders(ctx, service.ListOrdersParams{
+		Filter: filter,
+		Limit:  page.limit,
+		Offset: page.offset,
+		After:  page.after,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp := apiv2.OrderList{
+		Orders: make([]apiv2.OrderSummary, 0, len(orders.Orders)),
+		Pagination: generated.Pagination{
+			Limit:      page.limit,
+			Offset:     page.offset,
+			Cursor:     page.cursor,
+			NextCursor: orders.NextCursor,
+			Total:      orders.Total,
+			HasMore:    orders.HasMore,
+		},
+	}
+	for _, o := range orders.Orders {
+		resp.Orders = append(resp.Orders, apiv2.OrderSummary{
+			OrderId:    o.OrderId,
+			CustomerId: o.CustomerId,
+			Status:     o.Status,
+			Total:      money(o.TotalAmount, o.Currency),
+			ItemCount:  o.ItemCount,
+			CreatedAt:  o.CreatedAt,
+			Links:      v2OrderLinks(o.OrderId),
+		})
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(orders.Total))
+	if link := paginationLinks(r, page, orders.HasMore, orders.NextCursor); link != "" {
+		w.Header().Add("Link", link)
+	}
+	return h.writeJSON(w, http.StatusOK, resp)
+}
+
+// GetOrder handles GET /api/v2/orders/{orderId}
+func (h v2Handler) GetOrder(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	o, err := h.service.GetOrder(ctx, chi.URLParam(r, "orderId"))
+	if err != nil {
+		return err
+	}
+
+	resp := apiv2.Order{
+		OrderId:         o.OrderId,
+		CustomerId:      o.CustomerId,
+		Status:          o.Status,
+		CurrentStage:    o.CurrentStage,
+		Total:           money(o.TotalAmount, o.Currency),
+		Items:           make([]apiv2.OrderLine, 0, len(o.Items)),
+		ShippingAddress: o.ShippingAddress,
+		Enrichment:      o.Enrichment,
+		Routing:         o.Routing,
+		CreatedAt:       o.CreatedAt,
+		UpdatedAt:       o.UpdatedAt,
+		Links:           v2OrderLinks(o.OrderId),
+	}
+	for _, item := range o.Items {
+		resp.Items = append(resp.Items, apiv2.OrderLine{
+			Sku:         item.Sku,
+			ProductName: item.ProductName,
+			Quantity:    item.Quantity,
+			UnitPrice:   money(item.UnitPrice, o.Currency),
+			LineTotal:   money(item.UnitPrice*float64(item.Quantity), o.Currency),
+		})
+	}
+
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Last-Modified", o.UpdatedAt.UTC().Format(http.TimeFormat))
+	return h.writeJSON(w, http.StatusOK, resp)
+}
+
+// money formats amount as a decimal string with two fractional digits
+func money(amount float64, currency string) apiv2.Money {
+	return apiv2.Money{
+		Amount:   strconv.FormatFloat(amount, 'f', 2, 64),
+		Currency: currency,
+	}
+}
+
+// v2OrderLinks returns the links of a v2 order resource. Operations without
+// a v2 version yet link to v1.
+func v2OrderLinks(orderID string) *generated.OrderLinks {
+	return &generated.OrderLinks{
+		Self:   "/api/v2/orders/" + orderID,
+		Events: "/api/v1/orders/" + orderID + "/events",
+	}
+}
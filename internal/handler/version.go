@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/synapse/synapse/asyncapi"
+	"github.com/synapse/synapse/internal/buildinfo"
+	"github.com/synapse/synapse/internal/generated"
+	"gopkg.in/yaml.v3"
+)
+
+// GetVersion handles GET /api/v1/version
+func (h *Handler) GetVersion(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	build := buildinfo.Get()
+	resp := generated.VersionResponse{
+		Version:   build.Version,
+		Commit:    build.Commit,
+		BuildTime: build.BuildTime,
+		GoVersion: build.GoVersion,
+		Specs:     h.specVersions,
+	}
+	return h.writeJSON(w, http.StatusOK, resp)
+}
+
+// specVersions returns the versions of the OpenAPI spec at openAPIPath and
+// of the embedded AsyncAPI spec; one that can't be read is left empty
+func specVersions(openAPIPath string) (generated.SpecVersions, error) {
+	var versions generated.SpecVersions
+	var err error
+	versions.Asyncapi, err = asyncapi.Version()
+	if err != nil {
+		return versions, err
+	}
+	if openAPIPath == "" {
+		return versions, nil
+	}
+	data, err := os.ReadFile(openAPIPath)
+	if err != nil {
+		return versions, fmt.Errorf("reading OpenAPI spec: %w", err)
+	}
+	var spec struct {
+		Info struct {
+			Version string `yaml:"version"`
+		} `yaml:"info"`
+	}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return versions, fmt.Errorf("parsing OpenAPI spec: %w", err)
+	}
+	versions.Openapi = spec.Info.Version
+	return versions, nil
+}
@@ -0,0 +1,459 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/asyncapi"
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/service"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// webhookEventTypes are the event types a subscription can receive
+var webhookEventTypes = map[generated.WebhookEventType]bool{
+	generated.WebhookEventTypeOrderRouted:   true,
+	generated.WebhookEventTypeOrderRejected: true,
+}
+
+// Webhook subscription field limits
+const (
+	minWebhookSecretLen      = 16
+	maxWebhookSecretLen      = 256
+	maxWebhookURLLen         = 2048
+	maxWebhookDescriptionLen = 200
+)
+
+// defaultWebhookTimeout bounds test deliveries when no configuration is loaded
+const defaultWebhookTimeout = 5 * time.Second
+
+// ListWebhookSubscriptions handles GET /api/v1/webhooks
+func (h *Handler) ListWebhookSubscriptions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	subs, err := h.orders.ListWebhookSubscriptions(ctx, false)
+	if err != nil {
+		return problem.Upstream("postgres", err)
+	}
+
+	resp := generated.WebhookSubscriptionListResponse{Subscriptions: make([]generated.WebhookSubscription, 0, len(subs))}
+	for i := range subs {
+		resp.Subscriptions = append(resp.Subscriptions, webhookSubscriptionResponse(&subs[i]))
+	}
+	return h.writeJSON(w, http.StatusOK, resp)
+}
+
+// CreateWebhookSubscription handles POST /api/v1/webhooks
+func (h *Handler) CreateWebhookSubscription(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	var req generated.WebhookSubscriptionCreateRequest
+	if err := h.decodeJSON(r, &req); err != nil {
+		return err
+	}
+	if err := validateWebhookURL(req.Url); err != nil {
+		return err
+	}
+	if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+		return err
+	}
+	if err := validateWebhookDescription(req.Description); err != nil {
+		return err
+	}
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = pipeline.GenerateWebhookSecret(); err != nil {
+			return problem.Internal(err)
+		}
+	} else if err := validateWebhookSecret(secret); err != nil {
+		return err
+	}
+
+	now := h.clock.Now().UTC()
+	sub := &store.WebhookSubscription{
+		ID:          uuid.New().String(),
+		URL:         req.Url,
+		EventTypes:  eventTypeStrings(req.EventTypes),
+		Secret:      secret,
+		Description: req.Description,
+		Active:      true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := h.orders.CreateWebhookSubscription(ctx, sub); err != nil {
+		return problem.Upstream("postgres", err)
+	}
+
+	h.log.InfoContext(ctx, "webhook subscription created", "subscriptionId", sub.ID, "url", sub.URL, "eventTypes", sub.EventTypes, "principal", auth.FromContext(ctx))
+
+	created := webhookSubscriptionResponse(sub)
+	w.Header().Set("Location", "/api/v1/webhooks/"+sub.ID)
+	return h.writeJSON(w, http.StatusCreated, generated.WebhookSubscriptionCreatedResponse{
+		Active:         created.Active,
+		CreatedAt:      created.CreatedAt,
+		Description:    created.Description,
+		EventTypes:     created.EventTypes,
+		Secret:         sub.Secret,
+		SubscriptionId: created.SubscriptionId,
+		UpdatedAt:      created.UpdatedAt,
+		Url:            created.Url,
+	})
+}
+
+// GetWebhookSubscription handles GET /api/v1/webhooks/{subscriptionId}
+func (h *Handler) GetWebhookSubscription(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	sub, err := h.webhookSubscription(ctx, chi.URLParam(r, "subscriptionId"))
+	if err != nil {
+		return err
+	}
+	return h.writeJSON(w, http.StatusOK, webhookSubscriptionResponse(sub))
+}
+
+// UpdateWebhookSubscription handles PATCH /api/v1/webhooks/{subscriptionId}
+func (h *Handler) UpdateWebhookSubscription(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	var req generated.WebhookSubscriptionUpdateRequest
+	if err := h.decodeJSON(r, &req); err != nil {
+		return err
+	}
+	if req.Url == "" && req.EventTypes == nil && req.Secret == "" && req.Description == nil && req.Active == nil {
+		return problem.Validation("At least one field must be updated")
+	}
+
+	sub, err := h.webhookSubscription(ctx, chi.URLParam(r, "subscriptionId"))
+	if err != nil {
+		return err
+	}
+	if req.Url != "" {
+		if err := validateWebhookURL(req.Url); err != nil {
+			return err
+		}
+		sub.URL = req.Url
+	}
+	if req.EventTypes != nil {
+		if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+			return err
+		}
+		sub.EventTypes = eventTypeStrings(req.EventTypes)
+	}
+	if req.Secret != "" {
+		if err := validateWebhookSecret(req.Secret); err != nil {
+			return err
+		}
+		sub.Secret = req.Secret
+	}
+	if req.Description != nil {
+		if err := validateWebhookDescription(*req.Description); err != nil {
+			return err
+		}
+		sub.Description = *req.Description
+	}
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+	sub.UpdatedAt = h.clock.Now().UTC()
+
+	err = h.orders.UpdateWebhookSubscription(ctx, sub)
+	if errors.Is(err, store.ErrNotFound) {
+		return problem.NotFound("Webhook subscription with ID %s not found", sub.ID)
+	}
+	if err != nil {
+		return problem.Upstream("postgres", err)
+	}
+
+	h.log.InfoContext(ctx, "webhook subscription updated", "subscriptionId", sub.ID, "active", sub.Active,
+		"secretRotated", req.Secret != "", "principal", auth.FromContext(ctx))
+	return h.writeJSON(w, http.StatusOK, webhookSubscriptionResponse(sub))
+}
+
+// DeleteWebhookSubscription handles DELETE /api/v1/webhooks/{subscriptionId}
+func (h *Handler) DeleteWebhookSubscription(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	subID := chi.URLParam(r, "subscriptionId")
+	if _, err := uuid.Parse(subID); err != nil {
+		return problem.NotFound("Webhook subscription with ID %s not found", subID)
+	}
+
+	err := h.orders.DeleteWebhookSubscription(ctx, subID)
+	if errors.Is(err, store.ErrNotFound) {
+		return problem.NotFound("Webhook subscription with ID %s not found", subID)
+	}
+	if err != nil {
+		return problem.Upstream("postgres", err)
+	}
+	h.log.InfoContext(ctx, "webhook subscription deleted", "subscriptionId", subID, "principal", auth.FromContext(ctx))
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// ListWebhookDeliveries handles GET /api/v1/webhooks/{subscriptionId}/deliveries
+func (h *Handler) ListWebhookDeliveries(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+	sub, err := h.webhookSubscription(ctx, chi.URLParam(r, "subscriptionId"))
+	if err != nil {
+		return err
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	deliveries, err := h.orders.ListWebhookDeliveries(ctx, sub.ID, store.ListDeliveriesParams{
+		Limit:  page.limit + 1,
+		Offset: page.offset,
+		After:  page.after,
+	})
+	if err != nil {
+		return problem.Upstream("postgres", err)
+	}
+	hasMore := len(deliveries) > page.limit
+	if hasMore {
+		deliveries = deliveries[:page.limit]
+	}
+
+	resp := generated.WebhookDeliveryListResponse{
+		SubscriptionId: sub.ID,
+		Deliveries:     make([]generated.WebhookDelivery, 0, len(deliveries)),
+		Pagination: &generated.Pagination{
+			Limit:   page.limit,
+			Offset:  page.offset,
+			Cursor:  page.cursor,
+			HasMore: hasMore,
+		},
+	}
+	if hasMore {
+		last := deliveries[len(deliveries)-1]
+		resp.Pagination.NextCursor = service.EncodeCursor(store.Cursor{
+			Time: last.AttemptedAt,
+			Key:  strconv.FormatInt(last.Seq, 10),
+		})
+	}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, generated.WebhookDelivery{
+			Attempt:     d.Attempt,
+			AttemptedAt: d.AttemptedAt,
+			DeliveryId:  d.ID,
+			DurationMs:  d.DurationMs,
+			Error:       d.Error,
+			EventId:     d.EventID,
+			EventType:   generated.WebhookEventType(d.EventType),
+			Status:      d.Status,
+			StatusCode:  d.StatusCode,
+		})
+	}
+
+	if link := paginationLinks(r, page, hasMore, resp.Pagination.NextCursor); link != "" {
+		w.Header().Add("Link", link)
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
+	return h.writeJSON(w, http.StatusOK, resp)
+}
+
+// TestWebhookSubscription handles POST /api/v1/webhooks/{subscriptionId}/test
+func (h *Handler) TestWebhookSubscription(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := requireScope(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+
+	// The body is optional
+	var req generated.WebhookTestRequest
+	if err := h.decodeOptionalJSON(r, &req); err != nil {
+		return err
+	}
+	sub, err := h.webhookSubscription(ctx, chi.URLParam(r, "subscriptionId"))
+	if err != nil {
+		return err
+	}
+	eventType := req.EventType
+	if eventType == "" {
+		eventType = generated.WebhookEventTypeOrderRouted
+		if len(sub.EventTypes) > 0 {
+			eventType = generated.WebhookEventType(sub.EventTypes[0])
+		}
+	} else if !webhookEventTypes[eventType] {
+		return problem.Validation("Unknown webhook event type", generated.ValidationError{
+			Field:         "eventType",
+			Code:          "invalid_value",
+			Message:       "unknown event type",
+			RejectedValue: eventType,
+		})
+	}
+
+	// The sample is a routed order, sent to the destination of its event type
+	data, err := asyncapi.Sample("OrderRoutedPayload")
+	if err != nil {
+		return problem.Internal(err)
+	}
+	if eventType == generated.WebhookEventTypeOrderRejected {
+		data["destination"] = pipeline.DestinationRejected
+	}
+	notification := generated.WebhookNotification{
+		EventId:    uuid.NewString(),
+		EventType:  string(eventType),
+		OccurredAt: h.clock.Now().UTC(),
+		Data:       data,
+	}
+
+	timeout := defaultWebhookTimeout
+	if cfg := h.infra.Config; cfg != nil {
+		timeout = time.Duration(cfg.WebhookTimeoutMs) * time.Millisecond
+	}
+	emitter := pipeline.NewWebhookEmitter(nil, nil, pipeline.WebhookEmitterConfig{Timeout: timeout})
+	d, err := emitter.Test(ctx, pipeline.WebhookSubscriber{ID: sub.ID, URL: sub.URL, Secret: sub.Secret}, notification)
+	if err != nil {
+		return problem.Internal(err)
+	}
+
+	h.log.InfoContext(ctx, "webhook test notification sent", "subscriptionId", sub.ID, "eventType", eventType,
+		"status", d.Status, "statusCode", d.StatusCode, "principal", auth.FromContext(ctx))
+	return h.writeJSON(w, http.StatusOK, generated.WebhookTestResult{
+		SubscriptionId: sub.ID,
+		Url:            sub.URL,
+		Delivery: generated.WebhookDelivery{
+			Attempt:     d.Attempt,
+			AttemptedAt: d.AttemptedAt,
+			DeliveryId:  d.ID,
+			DurationMs:  int(d.Duration.Milliseconds()),
+			Error:       d.Error,
+			EventId:     d.EventID,
+			EventType:   eventType,
+			Status:      d.Status,
+			StatusCode:  d.StatusCode,
+		},
+		Notification: notification,
+	})
+}
+
+// webhookSubscription loads a subscription, reporting unknown IDs as 404
+func (h *Handler) webhookSubscription(ctx context.Context, subID string) (*store.WebhookSubscription, error) {
+	if _, err := uuid.Parse(subID); err != nil {
+		return nil, problem.NotFound("Webhook subscription with ID %s not found", subID)
+	}
+	sub, err := h.orders.GetWebhookSubscription(ctx, subID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, problem.NotFound("Webhook subscription with ID %s not found", subID)
+	}
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	return sub, nil
+}
+
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(raw) > maxWebhookURLLen {
+		return problem.Validation("Invalid webhook URL", generated.ValidationError{
+			Field:         "url",
+			Code:          "invalid_value",
+			Message:       fmt.Sprintf("url must be an absolute http or https URL of at most %d characters", maxWebhookURLLen),
+			RejectedValue: raw,
+		})
+	}
+	return nil
+}
+
+func validateWebhookEventTypes(eventTypes []generated.WebhookEventType) error {
+	if len(eventTypes) == 0 {
+		return problem.Validation("At least one event type is required", generated.ValidationError{
+			Field:   "eventTypes",
+			Code:    "required",
+			Message: "eventTypes must not be empty",
+		})
+	}
+	for i, eventType := range eventTypes {
+		if !webhookEventTypes[eventType] {
+			return problem.Validation("Unknown webhook event type", generated.ValidationError{
+				Field:         fmt.Sprintf("eventTypes[%d]", i),
+				Code:          "invalid_value",
+				Message:       "unknown event type",
+				RejectedValue: eventType,
+			})
+		}
+		if slices.Contains(eventTypes[:i], eventType) {
+			return problem.Validation("Duplicate webhook event type", generated.ValidationError{
+				Field:         fmt.Sprintf("eventTypes[%d]", i),
+				Code:          "duplicate",
+				Message:       "event type is listed more than once",
+				RejectedValue: eventType,
+			})
+		}
+	}
+	return nil
+}
+
+func validateWebhookSecret(secret string) error {
+	if len(secret) < minWebhookSecretLen || len(secret) > maxWebhookSecretLen {
+		return problem.Validation("Invalid webhook secret", generated.ValidationError{
+			Field:   "secret",
+			Code:    "invalid_length",
+			Message: fmt.Sprintf("secret must be %d to %d characters", minWebhookSecretLen, maxWebhookSecretLen),
+		})
+	}
+	return nil
+}
+
+func validateWebhookDescription(description string) error {
+	if len(description) > maxWebhookDescriptionLen {
+		return problem.Validation("Invalid webhook description", generated.ValidationError{
+			Field:   "description",
+			Code:    "invalid_length",
+			Message: fmt.Sprintf("description must be at most %d characters", maxWebhookDescriptionLen),
+		})
+	}
+	return nil
+}
+
+func eventTypeStrings(eventTypes []generated.WebhookEventType) []string {
+	out := make([]string, len(eventTypes))
+	for i, eventType := range eventTypes {
+		out[i] = string(eventType)
+	}
+	return out
+}
+
+func webhookSubscriptionResponse(sub *store.WebhookSubscription) generated.WebhookSubscription {
+	eventTypes := make([]generated.WebhookEventType, len(sub.EventTypes))
+	for i, eventType := range sub.EventTypes {
+		eventTypes[i] = generated.WebhookEventType(eventType)
+	}
+	return generated.WebhookSubscription{
+		Active:         sub.Active,
+		CreatedAt:      sub.CreatedAt,
+		Description:    sub.Description,
+		EventTypes:     eventTypes,
+		SubscriptionId: sub.ID,
+		UpdatedAt:      sub.UpdatedAt,
+		Url:            sub.URL,
+	}
+}
@@ -0,0 +1,218 @@
+// Package importer ingests bulk order imports. Orders are read from an NDJSON
+// or CSV stream one at a time, validated, and handed to a bounded pool of
+// workers; reading blocks while the workers are busy, so a slow pipeline
+// pushes back on the uploader instead of buffering the import in memory.
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"sync"
+
+	"github.com/synapse/synapse/internal/generated"
+)
+
+// Format is an import's encoding
+type Format string
+
+// Import formats
+const (
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+)
+
+// FormatFor returns the format for a Content-Type header value
+func FormatFor(contentType string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return FormatNDJSON, true
+	case "text/csv":
+		return FormatCSV, true
+	default:
+		return "", false
+	}
+}
+
+// Outcome is what happened to an order handed to the ingest function
+type Outcome int
+
+// Ingest outcomes
+const (
+	Accepted Outcome = iota
+	// Skipped orders already exist, e.g. when an import is re-run
+	Skipped
+)
+
+// Options configures an import run
+type Options struct {
+	// Concurrency is the number of orders ingested in parallel
+	Concurrency int
+	// MaxErrors caps the row errors kept in the progress report
+	MaxErrors int
+	// ProgressEvery is how many orders are processed between OnProgress calls
+	ProgressEvery int
+	// Validate returns the schema violations of a row's order, if any
+	Validate func(order map[string]any) []generated.ValidationError
+	// Ingest submits a valid order. An error aborts the import.
+	Ingest func(ctx context.Context, order *generated.OrderCreateRequest) (Outcome, error)
+	// OnProgress is called periodically from the reading goroutine
+	OnProgress func(Progress)
+}
+
+// Progress counts the orders of an import
+type Progress struct {
+	Processed int
+	Accepted  int
+	Rejected  int
+	Skipped   int
+	// Errors are up to MaxErrors rejected rows, sorted by line
+	Errors []generated.ImportRowError
+}
+
+// Run reads every row from rows and ingests the valid ones. It returns the
+// final progress, and an error if reading or ingesting failed part way.
+func Run(ctx context.Context, rows RowReader, opts Options) (Progress, error) {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		mu       sync.Mutex
+		progress Progress
+		wg       sync.WaitGroup
+		queue    = make(chan *Row, opts.Concurrency)
+	)
+
+	reject := func(row *Row, orderID string, errs []generated.ValidationError) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress.Processed++
+		progress.Rejected++
+		if len(progress.Errors) < opts.MaxErrors {
+			progress.Errors = append(progress.Errors, generated.ImportRowError{
+				Row:     row.Line,
+				OrderId: orderID,
+				Errors:  errs,
+			})
+		}
+	}
+
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range queue {
+				// Drain rows queued before the import was aborted
+				if ctx.Err() != nil {
+					continue
+				}
+				order, errs := decode(row, opts.Validate)
+				if len(errs) > 0 {
+					reject(row, orderID(row), errs)
+					continue
+				}
+				outcome, err := opts.Ingest(ctx, order)
+				if err != nil {
+					cancel(fmt.Errorf("ingesting order on line %d: %w", row.Line, err))
+					continue
+				}
+				mu.Lock()
+				progress.Processed++
+				if outcome == Skipped {
+					progress.Skipped++
+				} else {
+					progress.Accepted++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var readErr error
+	for queued := 0; ; queued++ {
+		if opts.OnProgress != nil && opts.ProgressEvery > 0 && queued > 0 && queued%opts.ProgressEvery == 0 {
+			opts.OnProgress(snapshot(&mu, &progress))
+		}
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+		select {
+		case queue <- row:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	final := snapshot(&mu, &progress)
+	sort.SliceStable(final.Errors, func(i, j int) bool { return final.Errors[i].Row < final.Errors[j].Row })
+	if readErr != nil {
+		return final, fmt.Errorf("reading import: %w", readErr)
+	}
+	if err := context.Cause(ctx); err != nil {
+		return final, err
+	}
+	return final, nil
+}
+
+// decode validates a row and converts it to an order request
+func decode(row *Row, validate func(map[string]any) []generated.ValidationError) (*generated.OrderCreateRequest, []generated.ValidationError) {
+	if len(row.Errors) > 0 {
+		return nil, row.Errors
+	}
+	if row.Order == nil {
+		return nil, []generated.ValidationError{{Code: "required", Message: "row has no order"}}
+	}
+	if validate != nil {
+		if errs := validate(row.Order); len(errs) > 0 {
+			return nil, errs
+		}
+	}
+
+	data, err := json.Marshal(row.Order)
+	if err != nil {
+		return nil, []generated.ValidationError{{Code: "invalid", Message: err.Error()}}
+	}
+	var order generated.OrderCreateRequest
+	if err := json.Unmarshal(data, &order); err != nil {
+		field := ""
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			field = typeErr.Field
+		}
+		return nil, []generated.ValidationError{{Field: field, Code: "invalid_type", Message: err.Error()}}
+	}
+	return &order, nil
+}
+
+func orderID(row *Row) string {
+	id, _ := row.Order["orderId"].(string)
+	return id
+}
+
+func snapshot(mu *sync.Mutex, p *Progress) Progress {
+	mu.Lock()
+	defer mu.Unlock()
+	s := *p
+	s.Errors = append([]generated.ImportRowError(nil), p.Errors...)
+	return s
+}
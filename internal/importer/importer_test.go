@@ -0,0 +1,313 @@
+package importer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/importer"
+)
+
+func readAll(t *testing.T, r importer.RowReader) []*importer.Row {
+	t.Helper()
+	var rows []*importer.Row
+	for {
+		row, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		require.NoError(t, err)
+		rows = append(rows, row)
+	}
+}
+
+func TestFormatFor(t *testing.T) {
+	tests := map[string]importer.Format{
+		"application/x-ndjson":    importer.FormatNDJSON,
+		"application/jsonl":       importer.FormatNDJSON,
+		"text/csv; charset=utf-8": importer.FormatCSV,
+	}
+	for contentType, want := range tests {
+		got, ok := importer.FormatFor(contentType)
+		assert.True(t, ok, contentType)
+		assert.Equal(t, want, got, contentType)
+	}
+	for _, contentType := range []string{"", "application/json", "text/plain"} {
+		_, ok := importer.FormatFor(contentType)
+		assert.False(t, ok, contentType)
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	input := `{"orderId":"a","customerId":"c1"}
+
+not json
+[1, 2]
+{"orderId":"b"}
+`
+	rows := readAll(t, importer.NewNDJSONReader(strings.NewReader(input)))
+	require.Len(t, rows, 4)
+
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, "c1", rows[0].Order["customerId"])
+	assert.Empty(t, rows[0].Errors)
+
+	assert.Equal(t, 3, rows[1].Line, "blank lines are skipped but counted")
+	require.Len(t, rows[1].Errors, 1)
+	assert.Equal(t, "invalid_json", rows[1].Errors[0].Code)
+
+	assert.Equal(t, 4, rows[2].Line)
+	assert.NotEmpty(t, rows[2].Errors, "lines must be objects")
+
+	assert.Equal(t, 5, rows[3].Line)
+	assert.Equal(t, "b", rows[3].Order["orderId"])
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "orderId,customerId,currency,totalAmount,sku,productName,quantity,unitPrice,shippingCity,shippingCountry\n" +
+		"o-1,c-1,USD,25,SKU-1,Widget,2,10,Berlin,DE\n" +
+		"o-1,c-1,USD,25,SKU-2,,1,5,Berlin,DE\n" +
+		"o-2,c-2,EUR,abc,SKU-3,,x,7,,\n" +
+		",c-3,GBP,4,SKU-4,,1,4,,\n" +
+		",c-3,GBP,4,SKU-5,,1,4,,\n"
+
+	r, err := importer.NewCSVReader(strings.NewReader(input))
+	require.NoError(t, err)
+	rows := readAll(t, r)
+	require.Len(t, rows, 4)
+
+	// Records with the same orderId are grouped into one order
+	first := rows[0]
+	assert.Equal(t, 2, first.Line)
+	assert.Empty(t, first.Errors)
+	assert.Equal(t, map[string]any{
+		"orderId":         "o-1",
+		"customerId":      "c-1",
+		"currency":        "USD",
+		"totalAmount":     25.0,
+		"shippingAddress": map[string]any{"city": "Berlin", "country": "DE"},
+		"items": []any{
+			map[string]any{"sku": "SKU-1", "productName": "Widget", "quantity": 2.0, "unitPrice": 10.0},
+			map[string]any{"sku": "SKU-2", "quantity": 1.0, "unitPrice": 5.0},
+		},
+	}, first.Order)
+
+	// Values that aren't numbers are reported by field
+	second := rows[1]
+	assert.Equal(t, 4, second.Line)
+	fields := make([]string, 0, len(second.Errors))
+	for _, e := range second.Errors {
+		assert.Equal(t, "invalid_type", e.Code)
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{"totalAmount", "items[0].quantity"}, fields)
+
+	// Records without an orderId are separate orders
+	assert.Equal(t, 5, rows[2].Line)
+	assert.Equal(t, 6, rows[3].Line)
+	assert.Len(t, rows[3].Order["items"], 1)
+	assert.NotContains(t, rows[3].Order, "orderId")
+}
+
+func TestCSVReader_MissingColumns(t *testing.T) {
+	_, err := importer.NewCSVReader(strings.NewReader("customerId,currency,sku\nc-1,USD,SKU-1\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "totalAmount, quantity, unitPrice")
+
+	_, err = importer.NewReader(importer.FormatCSV, strings.NewReader(""))
+	assert.Error(t, err)
+}
+
+// rows is a RowReader over a fixed set of rows
+type rows struct {
+	rows []*importer.Row
+	err  error
+	read atomic.Int32
+}
+
+func (r *rows) Next() (*importer.Row, error) {
+	r.read.Add(1)
+	if len(r.rows) == 0 {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, io.EOF
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	return row, nil
+}
+
+func orderRows(n int) *rows {
+	r := &rows{}
+	for i := 1; i <= n; i++ {
+		r.rows = append(r.rows, &importer.Row{Line: i, Order: map[string]any{
+			"orderId":     fmt.Sprintf("order-%d", i),
+			"customerId":  "c-1",
+			"currency":    "USD",
+			"totalAmount": 10.0,
+			"items":       []any{map[string]any{"sku": "SKU-1", "quantity": 1.0, "unitPrice": 10.0}},
+		}})
+	}
+	return r
+}
+
+func TestRun(t *testing.T) {
+	input := orderRows(10)
+	input.rows[6].Errors = []generated.ValidationError{{Code: "invalid_json", Message: "bad"}}
+	input.rows[6].Order = nil
+
+	var (
+		mu       sync.Mutex
+		ingested []string
+		updates  []importer.Progress
+	)
+	progress, err := importer.Run(context.Background(), input, importer.Options{
+		Concurrency:   3,
+		MaxErrors:     10,
+		ProgressEvery: 4,
+		Validate: func(order map[string]any) []generated.ValidationError {
+			if order["orderId"] == "order-3" {
+				return []generated.ValidationError{{Field: "currency", Code: "pattern", Message: "bad currency"}}
+			}
+			return nil
+		},
+		Ingest: func(ctx context.Context, order *generated.OrderCreateRequest) (importer.Outcome, error) {
+			if order.OrderId == "order-5" {
+				return importer.Skipped, nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			ingested = append(ingested, order.OrderId)
+			assert.Equal(t, "c-1", order.CustomerId)
+			assert.Len(t, order.Items, 1)
+			return importer.Accepted, nil
+		},
+		OnProgress: func(p importer.Progress) { updates = append(updates, p) },
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 10, progress.Processed)
+	assert.Equal(t, 7, progress.Accepted)
+	assert.Equal(t, 2, progress.Rejected)
+	assert.Equal(t, 1, progress.Skipped)
+	assert.Len(t, ingested, 7)
+	assert.Len(t, updates, 2)
+
+	require.Len(t, progress.Errors, 2)
+	assert.Equal(t, 3, progress.Errors[0].Row, "errors are sorted by row")
+	assert.Equal(t, "order-3", progress.Errors[0].OrderId)
+	assert.Equal(t, "currency", progress.Errors[0].Errors[0].Field)
+	assert.Equal(t, 7, progress.Errors[1].Row)
+}
+
+func TestRun_MaxErrors(t *testing.T) {
+	progress, err := importer.Run(context.Background(), orderRows(5), importer.Options{
+		MaxErrors: 2,
+		Validate: func(map[string]any) []generated.ValidationError {
+			return []generated.ValidationError{{Code: "invalid", Message: "no"}}
+		},
+		Ingest: func(context.Context, *generated.OrderCreateRequest) (importer.Outcome, error) {
+			t.Error("invalid orders aren't ingested")
+			return importer.Accepted, nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, progress.Rejected)
+	assert.Len(t, progress.Errors, 2)
+}
+
+func TestRun_TypeErrors(t *testing.T) {
+	input := orderRows(1)
+	input.rows[0].Order["totalAmount"] = "ten"
+
+	progress, err := importer.Run(context.Background(), input, importer.Options{
+		MaxErrors: 1,
+		Ingest: func(context.Context, *generated.OrderCreateRequest) (importer.Outcome, error) {
+			return importer.Accepted, nil
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, progress.Errors, 1)
+	assert.Equal(t, "invalid_type", progress.Errors[0].Errors[0].Code)
+	assert.Equal(t, "totalAmount", progress.Errors[0].Errors[0].Field)
+}
+
+func TestRun_Backpressure(t *testing.T) {
+	const concurrency = 2
+	var inFlight, maxInFlight atomic.Int32
+	release := make(chan struct{})
+
+	input := orderRows(20)
+	done := make(chan importer.Progress)
+	go func() {
+		progress, err := importer.Run(context.Background(), input, importer.Options{
+			Concurrency: concurrency,
+			Ingest: func(context.Context, *generated.OrderCreateRequest) (importer.Outcome, error) {
+				n := inFlight.Add(1)
+				for {
+					m := maxInFlight.Load()
+					if n <= m || maxInFlight.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				<-release
+				inFlight.Add(-1)
+				return importer.Accepted, nil
+			},
+		})
+		assert.NoError(t, err)
+		done <- progress
+	}()
+
+	// While ingestion is blocked, reading stops once the workers and the
+	// queue are full
+	require.Eventually(t, func() bool { return inFlight.Load() == concurrency }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.LessOrEqual(t, int(input.read.Load()), 2*concurrency+1, "reader is held back")
+
+	close(release)
+	progress := <-done
+	assert.Equal(t, 20, progress.Accepted)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(concurrency))
+}
+
+func TestRun_IngestErrorAborts(t *testing.T) {
+	var calls atomic.Int32
+	progress, err := importer.Run(context.Background(), orderRows(100), importer.Options{
+		Concurrency: 1,
+		Ingest: func(ctx context.Context, order *generated.OrderCreateRequest) (importer.Outcome, error) {
+			if calls.Add(1) == 3 {
+				return importer.Accepted, errors.New("nats: connection closed")
+			}
+			return importer.Accepted, nil
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 3")
+	assert.Equal(t, 2, progress.Accepted)
+	assert.Less(t, int(calls.Load()), 100, "the rest of the import isn't read")
+}
+
+func TestRun_ReadError(t *testing.T) {
+	input := orderRows(2)
+	input.err = errors.New("connection reset")
+
+	progress, err := importer.Run(context.Background(), input, importer.Options{
+		Ingest: func(context.Context, *generated.OrderCreateRequest) (importer.Outcome, error) {
+			return importer.Accepted, nil
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection reset")
+	assert.Equal(t, 2, progress.Accepted)
+}
@@ -0,0 +1,281 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/synapse/synapse/internal/generated"
+)
+
+// maxLineBytes bounds a single NDJSON line
+const maxLineBytes = 1 << 20
+
+// Row is one order read from an import. Rows that couldn't be parsed carry
+// Errors and no Order.
+type Row struct {
+	// Line is the 1-based line the order starts on
+	Line   int
+	Order  map[string]any
+	Errors []generated.ValidationError
+}
+
+// RowReader reads orders from an import stream. Next returns io.EOF when the
+// stream is exhausted; any other error aborts the import.
+type RowReader interface {
+	Next() (*Row, error)
+}
+
+// NewReader returns a RowReader for the import format
+func NewReader(format Format, r io.Reader) (RowReader, error) {
+	switch format {
+	case FormatNDJSON:
+		return NewNDJSONReader(r), nil
+	case FormatCSV:
+		cr, err := NewCSVReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return cr, nil
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// NDJSONReader reads one order object per line. Blank lines are skipped.
+type NDJSONReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewNDJSONReader creates an NDJSONReader on r
+func NewNDJSONReader(r io.Reader) *NDJSONReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	return &NDJSONReader{scanner: scanner}
+}
+
+// Next implements RowReader
+func (r *NDJSONReader) Next() (*Row, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := strings.TrimSpace(r.scanner.Text())
+		if data == "" {
+			continue
+		}
+		row := &Row{Line: r.line}
+		var order map[string]any
+		if err := json.Unmarshal([]byte(data), &order); err != nil || order == nil {
+			row.Errors = []generated.ValidationError{{
+				Code:    "invalid_json",
+				Message: "line is not a JSON object",
+			}}
+			return row, nil
+		}
+		row.Order = order
+		return row, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading line %d: %w", r.line+1, err)
+	}
+	return nil, io.EOF
+}
+
+// CSV columns. Each record is one line item; consecutive records with the
+// same non-empty orderId are one order.
+const (
+	colOrderID     = "orderId"
+	colCustomerID  = "customerId"
+	colCurrency    = "currency"
+	colTotalAmount = "totalAmount"
+	colSKU         = "sku"
+	colProductName = "productName"
+	colQuantity    = "quantity"
+	colUnitPrice   = "unitPrice"
+)
+
+// csvRequired are the columns a CSV import must have
+var csvRequired = []string{colCustomerID, colCurrency, colTotalAmount, colSKU, colQuantity, colUnitPrice}
+
+// csvAddressFields maps shipping* columns to Address fields
+var csvAddressFields = map[string]string{
+	"shippingStreet":     "street",
+	"shippingStreet2":    "street2",
+	"shippingCity":       "city",
+	"shippingState":      "state",
+	"shippingPostalCode": "postalCode",
+	"shippingCountry":    "country",
+}
+
+// CSVReader reads orders from CSV with a header row
+type CSVReader struct {
+	csv     *csv.Reader
+	columns map[string]int
+	// pending is a record read ahead while grouping line items
+	pending *csvRecord
+}
+
+// csvRecord is a CSV record and the line it starts on. Fields is nil for a
+// record that couldn't be parsed.
+type csvRecord struct {
+	fields []string
+	line   int
+}
+
+// NewCSVReader reads the header row and creates a CSVReader. It fails if
+// required columns are missing.
+func NewCSVReader(r io.Reader) (*CSVReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	var missing []string
+	for _, name := range csvRequired {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("CSV header is missing columns: %s", strings.Join(missing, ", "))
+	}
+	return &CSVReader{csv: cr, columns: columns}, nil
+}
+
+// Next implements RowReader
+func (r *CSVReader) Next() (*Row, error) {
+	rec, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	row := &Row{Line: rec.line}
+	if rec.fields == nil {
+		row.Errors = []generated.ValidationError{{
+			Code:    "invalid_csv",
+			Message: "record could not be parsed",
+		}}
+		return row, nil
+	}
+
+	record := rec.fields
+	order := map[string]any{
+		colCustomerID: r.field(record, colCustomerID),
+		colCurrency:   r.field(record, colCurrency),
+	}
+	orderID := r.field(record, colOrderID)
+	if orderID != "" {
+		order[colOrderID] = orderID
+	}
+	if v, ok := r.number(record, colTotalAmount, colTotalAmount, row); ok {
+		order[colTotalAmount] = v
+	}
+	if address := r.address(record); address != nil {
+		order["shippingAddress"] = address
+	}
+
+	items := []any{r.item(record, 0, row)}
+	for orderID != "" {
+		next, err := r.read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if next.fields == nil || r.field(next.fields, colOrderID) != orderID {
+			r.pending = next
+			break
+		}
+		items = append(items, r.item(next.fields, len(items), row))
+	}
+	order["items"] = items
+	row.Order = order
+	return row, nil
+}
+
+// read returns the next record, including ones that failed to parse
+func (r *CSVReader) read() (*csvRecord, error) {
+	if r.pending != nil {
+		rec := r.pending
+		r.pending = nil
+		return rec, nil
+	}
+	fields, err := r.csv.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &csvRecord{line: parseErr.StartLine}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	line, _ := r.csv.FieldPos(0)
+	return &csvRecord{fields: fields, line: line}, nil
+}
+
+func (r *CSVReader) field(record []string, name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// number parses a numeric column, recording an error on row if it isn't one.
+// Empty values are left out so the schema reports them as missing.
+func (r *CSVReader) number(record []string, column, field string, row *Row) (float64, bool) {
+	raw := r.field(record, column)
+	if raw == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		row.Errors = append(row.Errors, generated.ValidationError{
+			Field:         field,
+			Code:          "invalid_type",
+			Message:       field + " must be a number",
+			RejectedValue: raw,
+		})
+		return 0, false
+	}
+	return v, true
+}
+
+func (r *CSVReader) item(record []string, index int, row *Row) map[string]any {
+	prefix := fmt.Sprintf("items[%d].", index)
+	item := map[string]any{colSKU: r.field(record, colSKU)}
+	if name := r.field(record, colProductName); name != "" {
+		item[colProductName] = name
+	}
+	if v, ok := r.number(record, colQuantity, prefix+colQuantity, row); ok {
+		item[colQuantity] = v
+	}
+	if v, ok := r.number(record, colUnitPrice, prefix+colUnitPrice, row); ok {
+		item[colUnitPrice] = v
+	}
+	return item
+}
+
+func (r *CSVReader) address(record []string) map[string]any {
+	address := make(map[string]any)
+	for column, field := range csvAddressFields {
+		if v := r.field(record, column); v != "" {
+			address[field] = v
+		}
+	}
+	if len(address) == 0 {
+		return nil
+	}
+	return address
+}
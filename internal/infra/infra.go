@@ -3,99 +3,338 @@ package infra
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"maps"
+	"sync"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/clock"
 	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/crypto"
+	"github.com/synapse/synapse/internal/keyspace"
+	"github.com/synapse/synapse/internal/logging"
+	"github.com/synapse/synapse/internal/store"
+	"golang.org/x/sync/singleflight"
 )
 
+// logger logs the package's records
+var logger = logging.Module("infra")
+
 // Infra holds all infrastructure connections
 type Infra struct {
-	NATS   *nats.Conn
+	NATS *nats.Conn
+	// Pool is the Postgres connection pool; DB runs on its connections
+	Pool   *pgxpool.Pool
 	DB     *sql.DB
-	Redis  *redis.Client
+	Redis  redis.UniversalClient
 	Config *config.Config
+	// StartedAt is when the connections were set up, i.e. service startup
+	StartedAt time.Time
+	// Clock tells the time to the pipeline, services and schedulers built
+	// on the Infra; the system's when nil
+	Clock clock.Clock
+
+	mu          sync.Mutex
+	lastSuccess map[string]time.Time
+	// natsDisconnectedAt and natsDisconnectErr describe the NATS
+	// connection's outage while it reconnects
+	natsDisconnectedAt time.Time
+	natsDisconnectErr  error
+	// checks shares a run of the dependency checks among concurrent
+	// callers; its results are reused until checkedAt is older than the
+	// configured cache TTL
+	checks    singleflight.Group
+	checked   map[string]ComponentCheck
+	checkedAt time.Time
+	// electors are the singleton jobs' elections, by job
+	electors map[string]*Elector
+	// replica is the Postgres read replica, if one is configured
+	replica *replica
+	// columnKeys encrypt order columns at rest, if keys are configured
+	columnKeys *crypto.Keyring
+}
+
+// defaultHealthCheckTimeout bounds each dependency check when no Config sets
+// a timeout
+const defaultHealthCheckTimeout = time.Second
+
+// ComponentCheck is the outcome of checking one dependency
+type ComponentCheck struct {
+	Err     error
+	Latency time.Duration
+	// Critical is set for dependencies the service can't work without
+	Critical bool
+	// CheckedAt is when the check ran
+	CheckedAt time.Time
+	// LastSuccess is when a check of the dependency last passed; zero if
+	// none has since startup
+	LastSuccess time.Time
 }
 
-// New creates a new Infra instance with all connections
-func New(ctx context.Context, cfg *config.Config) (*Infra, error) {
+// Option configures an Infra made by New
+type Option func(*Infra)
+
+// WithClock sets the Infra's Clock, which also dates its startup and
+// dependency checks
+func WithClock(c clock.Clock) Option {
+	return func(i *Infra) {
+		i.Clock = c
+	}
+}
+
+// New creates a new Infra instance with all connections. Required
+// dependencies are waited for, up to cfg.StartupWaitSeconds, and it fails
+// if one is still unavailable; optional ones are connected to in the
+// background and reported degraded until they are.
+func New(ctx context.Context, cfg *config.Config, opts ...Option) (*Infra, error) {
 	infra := &Infra{Config: cfg}
+	for _, opt := range opts {
+		opt(infra)
+	}
+	infra.StartedAt = clock.Or(infra.Clock).Now().UTC()
+	if cfg.StoreEncryptionKeys != "" {
+		keys, err := crypto.ParseKeyring(cfg.StoreEncryptionKeys, cfg.StoreEncryptionKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("configuring store encryption keys: %w", err)
+		}
+		infra.columnKeys = keys
+	}
 
 	// Connect to NATS
-	nc, err := nats.Connect(cfg.NATSURL)
+	nc, err := infra.connectNATS(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("connecting to NATS: %w", err)
 	}
 	infra.NATS = nc
+	if err := infra.provisionJetStream(ctx); err != nil {
+		if cfg.NATSRequired {
+			nc.Close()
+			return nil, err
+		}
+		logger.Warn("JetStream not provisioned, starting degraded", "error", err)
+	}
 
 	// Connect to PostgreSQL
-	db, err := sql.Open("postgres", cfg.PostgresDSN())
+	pool, err := newPostgresPool(ctx, cfg.PostgresDSN(), cfg)
 	if err != nil {
 		nc.Close()
-		return nil, fmt.Errorf("opening postgres connection: %w", err)
+		return nil, err
 	}
-	if err := db.PingContext(ctx); err != nil {
+	if err := waitFor(ctx, "postgres", startupWait(cfg), pool.Ping); err != nil {
+		pool.Close()
 		nc.Close()
-		db.Close()
 		return nil, fmt.Errorf("pinging postgres: %w", err)
 	}
-	infra.DB = db
+	db := stdlib.OpenDBFromPool(pool)
+	infra.Pool, infra.DB = pool, db
+	if err := store.Migrate(ctx, db); err != nil {
+		infra.Close(context.WithoutCancel(ctx))
+		return nil, fmt.Errorf("migrating postgres: %w", err)
+	}
+	if cfg.PostgresReplicaDSN != "" {
+		if infra.replica, err = openReplica(ctx, cfg); err != nil {
+			infra.Close(context.WithoutCancel(ctx))
+			return nil, fmt.Errorf("opening postgres read replica: %w", err)
+		}
+	}
+	if cfg.APIKeyBootstrap != "" {
+		if err := auth.NewAPIKeys(store.New(db), nil, keyspace.Keyspace{}, 0).Bootstrap(ctx, cfg.APIKeyBootstrap); err != nil {
+			infra.Close(context.WithoutCancel(ctx))
+			return nil, fmt.Errorf("bootstrapping API key: %w", err)
+		}
+	}
 
-	// Connect to Redis
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     cfg.RedisAddr,
-		Password: cfg.RedisPassword,
-		DB:       cfg.RedisDB,
-	})
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		nc.Close()
-		db.Close()
-		return nil, fmt.Errorf("pinging redis: %w", err)
+	// Connect to Redis. The client dials as commands need connections, so
+	// when Redis is optional and down it is used once it comes back.
+	rdb, err := NewRedis(cfg)
+	if err != nil {
+		infra.Close(context.WithoutCancel(ctx))
+		return nil, err
+	}
+	pingRedis := func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	}
+	if cfg.RedisRequired {
+		if err := waitFor(ctx, "redis", startupWait(cfg), pingRedis); err != nil {
+			rdb.Close()
+			infra.Close(context.WithoutCancel(ctx))
+			return nil, fmt.Errorf("pinging redis: %w", err)
+		}
+	} else if err := pingRedis(ctx); err != nil {
+		logger.Warn("Redis unavailable, starting degraded", "error", err)
 	}
 	infra.Redis = rdb
 
 	return infra, nil
 }
 
-// Close closes all infrastructure connections
-func (i *Infra) Close() {
-	if i.NATS != nil {
-		i.NATS.Close()
+// Close closes the connections in the reverse order they were opened:
+// Redis, the Postgres read replica and primary, then NATS, once what was
+// published has been flushed. It is to be called once what uses them has
+// stopped; Postgres connections still in use and NATS messages not yet
+// flushed are waited for until ctx is done. The errors are returned
+// together.
+func (i *Infra) Close(ctx context.Context) error {
+	var errs []error
+	if i.Redis != nil {
+		if err := i.Redis.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing redis: %w", err))
+		}
 	}
-	if i.DB != nil {
-		i.DB.Close()
+	if i.replica != nil {
+		if err := closePostgres(ctx, i.replica.pool, i.replica.db); err != nil {
+			errs = append(errs, fmt.Errorf("closing postgres read replica: %w", err))
+		}
 	}
-	if i.Redis != nil {
-		i.Redis.Close()
+	if i.Pool != nil || i.DB != nil {
+		if err := closePostgres(ctx, i.Pool, i.DB); err != nil {
+			errs = append(errs, fmt.Errorf("closing postgres: %w", err))
+		}
 	}
+	if i.NATS != nil {
+		if err := drainNATS(ctx, i.NATS); err != nil {
+			errs = append(errs, fmt.Errorf("closing NATS: %w", err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
-// Healthy returns true if all connections are healthy
+// Healthy checks each dependency, returning nil for the healthy ones
 func (i *Infra) Healthy(ctx context.Context) map[string]error {
 	results := make(map[string]error)
+	for name, check := range i.Check(ctx) {
+		results[name] = check.Err
+	}
+	return results
+}
 
-	// Check NATS
-	if i.NATS != nil && i.NATS.IsConnected() {
-		results["nats"] = nil
-	} else {
-		results["nats"] = fmt.Errorf("not connected")
+// Check checks each dependency, timing the check. The dependencies are
+// checked concurrently, each within the configured timeout, so a slow or
+// hung one doesn't delay the others. Results are cached briefly and
+// concurrent calls share a run, so a burst of health checks doesn't turn
+// into a burst of pings.
+func (i *Infra) Check(ctx context.Context) map[string]ComponentCheck {
+	timeout, ttl := defaultHealthCheckTimeout, time.Duration(0)
+	if i.Config != nil {
+		if i.Config.HealthCheckTimeoutMs > 0 {
+			timeout = millis(i.Config.HealthCheckTimeoutMs)
+		}
+		ttl = millis(i.Config.HealthCheckCacheMs)
 	}
 
-	// Check PostgreSQL
-	if i.DB != nil {
-		results["postgres"] = i.DB.PingContext(ctx)
-	} else {
-		results["postgres"] = fmt.Errorf("not connected")
+	clk := clock.Or(i.Clock)
+	i.mu.Lock()
+	if i.checked != nil && clk.Since(i.checkedAt) < ttl {
+		results := maps.Clone(i.checked)
+		i.mu.Unlock()
+		return results
 	}
+	i.mu.Unlock()
 
-	// Check Redis
-	if i.Redis != nil {
-		results["redis"] = i.Redis.Ping(ctx).Err()
-	} else {
-		results["redis"] = fmt.Errorf("not connected")
+	// The shared run mustn't end with the caller that started it
+	v, _, _ := i.checks.Do("check", func() (any, error) {
+		results := i.runChecks(context.WithoutCancel(ctx), timeout)
+		i.mu.Lock()
+		i.checked, i.checkedAt = results, clk.Now()
+		i.mu.Unlock()
+		return results, nil
+	})
+	return maps.Clone(v.(map[string]ComponentCheck))
+}
+
+// runChecks checks each dependency concurrently, within timeout
+func (i *Infra) runChecks(ctx context.Context, timeout time.Duration) map[string]ComponentCheck {
+	checks := map[string]func(context.Context) error{
+		"nats": func(context.Context) error {
+			return i.checkNATS()
+		},
+		"postgres": func(ctx context.Context) error {
+			if i.DB == nil {
+				return fmt.Errorf("not connected")
+			}
+			return i.DB.PingContext(ctx)
+		},
+		"redis": func(ctx context.Context) error {
+			if i.Redis == nil {
+				return fmt.Errorf("not connected")
+			}
+			return i.Redis.Ping(ctx).Err()
+		},
+	}
+	if i.replica != nil {
+		checks["postgres-replica"] = func(ctx context.Context) error {
+			return i.replica.pool.Ping(ctx)
+		}
 	}
 
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[string]ComponentCheck, len(checks))
+		clk     = clock.Or(i.Clock)
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := clk.Now()
+			err := check(ctx)
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = fmt.Errorf("no answer within %s", timeout)
+			}
+			result := ComponentCheck{
+				Err:       err,
+				Latency:   clk.Since(start),
+				Critical:  i.Critical(name),
+				CheckedAt: start.UTC(),
+			}
+			result.LastSuccess = i.recordCheck(name, result)
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
 	return results
 }
+
+// Critical reports whether the service can't work without a dependency.
+// Postgres is always required, unlike its read replica, which reads fall
+// back from. Redis only caches API keys and backs rate limiting, which both
+// fail open without it, so it is optional unless REDIS_REQUIRED is set; NATS
+// is required unless NATS_REQUIRED is unset.
+func (i *Infra) Critical(name string) bool {
+	switch name {
+	case "postgres-replica":
+		return false
+	case "redis":
+		return i.Config != nil && i.Config.RedisRequired
+	case "nats":
+		return i.Config == nil || i.Config.NATSRequired
+	default:
+		return true
+	}
+}
+
+// recordCheck notes a passing check of a dependency, returning when one
+// last passed
+func (i *Infra) recordCheck(name string, check ComponentCheck) time.Time {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if check.Err == nil {
+		if i.lastSuccess == nil {
+			i.lastSuccess = make(map[string]time.Time)
+		}
+		i.lastSuccess[name] = check.CheckedAt
+	}
+	return i.lastSuccess[name]
+}
@@ -0,0 +1,375 @@
+package infra_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestInfra_Check(t *testing.T) {
+	checks := (&infra.Infra{}).Check(context.Background())
+
+	require.Len(t, checks, 3)
+	for name, check := range checks {
+		assert.Error(t, check.Err, name)
+		assert.True(t, check.LastSuccess.IsZero(), name)
+		assert.False(t, check.CheckedAt.IsZero(), name)
+	}
+	assert.True(t, checks["nats"].Critical)
+	assert.True(t, checks["postgres"].Critical)
+	assert.False(t, checks["redis"].Critical, "the service works without Redis")
+}
+
+func TestInfra_CheckCachesResults(t *testing.T) {
+	i := &infra.Infra{Config: &config.Config{HealthCheckCacheMs: 60000}}
+	first := i.Check(context.Background())
+	second := i.Check(context.Background())
+	assert.Equal(t, first["postgres"].CheckedAt, second["postgres"].CheckedAt, "cached")
+
+	i = &infra.Infra{Config: &config.Config{}}
+	first = i.Check(context.Background())
+	time.Sleep(time.Millisecond)
+	second = i.Check(context.Background())
+	assert.NotEqual(t, first["postgres"].CheckedAt, second["postgres"].CheckedAt, "caching disabled")
+}
+
+func TestInfra_Critical(t *testing.T) {
+	i := &infra.Infra{Config: &config.Config{RedisRequired: true}}
+	assert.True(t, i.Critical("postgres"))
+	assert.True(t, i.Critical("redis"))
+	assert.False(t, i.Critical("nats"), "NATS_REQUIRED is unset")
+	assert.False(t, i.Critical("postgres-replica"), "reads fall back to the primary")
+}
+
+func TestInfra_ReadDBWithoutReplica(t *testing.T) {
+	db := &sql.DB{}
+	assert.Same(t, db, (&infra.Infra{DB: db}).ReadDB())
+}
+
+func TestInfra_Close(t *testing.T) {
+	assert.NoError(t, (&infra.Infra{}).Close(context.Background()))
+
+	// Connections that never came up close at once
+	nc, err := nats.Connect("nats://127.0.0.1:1", nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+	require.NoError(t, err)
+	pool, err := pgxpool.New(context.Background(), "postgres://synapse@127.0.0.1:1/synapse")
+	require.NoError(t, err)
+	i := &infra.Infra{NATS: nc, Pool: pool, Redis: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})}
+	require.NoError(t, i.Close(context.Background()))
+	assert.True(t, nc.IsClosed())
+
+	// Errors are returned together
+	err = i.Close(context.Background())
+	assert.ErrorContains(t, err, "closing redis")
+}
+
+func TestInfra_Leadership(t *testing.T) {
+	i := &infra.Infra{Config: &config.Config{LeaderRetryMs: 10}}
+	assert.Empty(t, i.Leadership())
+
+	e := i.Elector("export-retention")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ran := false
+	e.Run(ctx, func(context.Context) { ran = true })
+
+	assert.False(t, ran, "not elected without Postgres")
+	assert.False(t, e.IsLeader())
+	assert.Equal(t, map[string]bool{"export-retention": false}, i.Leadership())
+}
+
+func TestLocker_WithoutRedis(t *testing.T) {
+	_, err := (&infra.Infra{}).Locker().Acquire(context.Background(), "orders-import")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, infra.ErrLocked)
+
+	client, err := infra.NewRedis(&config.Config{RedisAddr: "127.0.0.1:1"})
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = (&infra.Infra{Redis: client}).Locker().Acquire(context.Background(), "orders-import")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, infra.ErrLocked)
+}
+
+func TestLocker_FakeClock(t *testing.T) {
+	ctx := context.Background()
+	clk := testutil.NewFakeClock(time.Time{})
+	rdb := testutil.NewMemRedis(clk)
+	defer rdb.Close()
+	locker := (&infra.Infra{Redis: rdb, Clock: clk, Config: &config.Config{LockTTLMs: 300}}).Locker()
+
+	// Locks are taken per name, so other tasks run alongside
+	lock, err := locker.Acquire(ctx, "orders-import:a")
+	require.NoError(t, err)
+	_, err = locker.Acquire(ctx, "orders-import:a")
+	assert.ErrorIs(t, err, infra.ErrLocked)
+	other, err := locker.Acquire(ctx, "orders-import:b")
+	require.NoError(t, err)
+	defer other.Release(ctx)
+
+	// MemRedis can't run the renewal script, so the lock is lost once its
+	// TTL has passed on the clock
+	clk.BlockUntil(2)
+	select {
+	case <-lock.Lost():
+		t.Fatal("lock lost before its TTL passed")
+	default:
+	}
+	clk.Advance(300 * time.Millisecond)
+	select {
+	case <-lock.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock not lost")
+	}
+	_ = lock.Release(ctx)
+}
+
+func TestLocker_Redis(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	tc, err := testutil.StartContainers(ctx, t, &testutil.ContainerConfig{Services: []testutil.Service{testutil.ServiceRedis}})
+	require.NoError(t, err)
+	addr, err := tc.RedisConnectionString(ctx)
+	require.NoError(t, err)
+	client, err := infra.NewRedis(&config.Config{RedisAddr: addr})
+	require.NoError(t, err)
+	defer client.Close()
+	locker := (&infra.Infra{Redis: client, Config: &config.Config{LockTTLMs: 300}}).Locker()
+
+	first, err := locker.Acquire(ctx, "dlq-retry")
+	require.NoError(t, err)
+	_, err = locker.Acquire(ctx, "dlq-retry")
+	assert.ErrorIs(t, err, infra.ErrLocked)
+
+	// The lock is renewed past its TTL until released
+	time.Sleep(500 * time.Millisecond)
+	_, err = locker.Acquire(ctx, "dlq-retry")
+	assert.ErrorIs(t, err, infra.ErrLocked)
+	require.NoError(t, first.Release(ctx))
+
+	second, err := locker.Acquire(ctx, "dlq-retry")
+	require.NoError(t, err)
+	assert.Greater(t, second.Token, first.Token, "tokens increase")
+
+	// A lock taken over is lost, cancelling the guarded context
+	guarded, stop := second.Guard(ctx)
+	defer stop()
+	require.NoError(t, client.Set(ctx, "synapse:lock:{dlq-retry}", "other", 0).Err())
+	select {
+	case <-second.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lock not lost")
+	}
+	<-guarded.Done()
+	assert.ErrorIs(t, context.Cause(guarded), infra.ErrLockLost)
+	require.NoError(t, second.Release(ctx))
+	assert.Equal(t, "other", client.Get(ctx, "synapse:lock:{dlq-retry}").Val(), "another holder's lock is kept")
+}
+
+func TestOpenPostgres_StatementTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	tc, err := testutil.StartContainers(ctx, t, &testutil.ContainerConfig{Services: []testutil.Service{testutil.ServicePostgres}})
+	require.NoError(t, err)
+	dsn, err := tc.PostgresConnectionString(ctx)
+	require.NoError(t, err)
+	pool, db, err := infra.OpenPostgres(ctx, dsn, &config.Config{PostgresQueryTimeoutMs: 100})
+	require.NoError(t, err)
+	defer pool.Close()
+	defer db.Close()
+
+	// The server cancels the statement, whatever the client's context
+	_, err = db.ExecContext(context.Background(), "SELECT pg_sleep(2)")
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, "57014", pgErr.Code, "query_canceled")
+}
+
+func TestOpenPostgres_TransactionPooling(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	tc, err := testutil.StartContainers(ctx, t, &testutil.ContainerConfig{Services: []testutil.Service{testutil.ServicePostgres}})
+	require.NoError(t, err)
+	dsn, err := tc.PostgresConnectionString(ctx)
+	require.NoError(t, err)
+	pool, db, err := infra.OpenPostgres(ctx, dsn, &config.Config{PostgresQueryTimeoutMs: 100, PostgresTransactionPooling: true})
+	require.NoError(t, err)
+	defer pool.Close()
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// No session parameter is set at startup, and nothing is prepared
+	var timeout string
+	require.NoError(t, conn.QueryRowContext(ctx, "SHOW statement_timeout").Scan(&timeout))
+	assert.Equal(t, "0", timeout)
+	var n int
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT $1::int", 1).Scan(&n))
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT count(*) FROM pg_prepared_statements").Scan(&n))
+	assert.Zero(t, n)
+}
+
+func TestProvisionJetStream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	tc, err := testutil.StartContainers(ctx, t, &testutil.ContainerConfig{Services: []testutil.Service{testutil.ServiceNATS}})
+	require.NoError(t, err)
+	url, err := tc.NATSConnectionString(ctx)
+	require.NoError(t, err)
+	cfg := &config.Config{NATSURL: url, JetStreamReplicas: 1, JetStreamMaxAgeHours: 24}
+	nc, err := infra.ConnectNATS(ctx, cfg)
+	require.NoError(t, err)
+	defer nc.Close()
+
+	drift, err := infra.ProvisionJetStream(ctx, nc, cfg, false)
+	require.NoError(t, err)
+	assert.Contains(t, drift, infra.Drift{Stream: "ORDERS", Detail: "missing"})
+	assert.Contains(t, drift, infra.Drift{Stream: "ORDERS", Consumer: "validateOrder", Detail: "missing"})
+	assert.NotContains(t, drift, infra.Drift{Stream: "PIPELINE", Detail: "missing"}, "core NATS channels aren't persisted")
+
+	// Applying creates everything once; then nothing has drifted
+	_, err = infra.ProvisionJetStream(ctx, nc, cfg, true)
+	require.NoError(t, err)
+	drift, err = infra.ProvisionJetStream(ctx, nc, cfg, true)
+	require.NoError(t, err)
+	assert.Empty(t, drift)
+	stream, err := testutil.InspectJetStream(t, nc).Stream(ctx, "ORDERS")
+	require.NoError(t, err)
+	assert.Equal(t, jetstream.FileStorage, stream.Config.Storage)
+	assert.Equal(t, 24*time.Hour, stream.Config.MaxAge)
+
+	cfg.JetStreamMaxAgeHours = 48
+	drift, err = infra.ProvisionJetStream(ctx, nc, cfg, false)
+	require.NoError(t, err)
+	assert.Contains(t, drift, infra.Drift{Stream: "ORDERS", Detail: "max age is 24h0m0s, want 48h0m0s"})
+}
+
+func TestNew_StartsWithoutOptionalNATS(t *testing.T) {
+	// With NATS optional, startup gets past it to the unreachable Postgres
+	_, err := infra.New(context.Background(), &config.Config{
+		NATSURL:      "nats://127.0.0.1:1",
+		PostgresHost: "127.0.0.1",
+		PostgresPort: 1,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "postgres")
+
+	_, err = infra.New(context.Background(), &config.Config{
+		NATSURL:      "nats://127.0.0.1:1",
+		NATSRequired: true,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connecting to NATS")
+}
+
+func TestNew_WaitsForRequiredDependencies(t *testing.T) {
+	cfg := &config.Config{NATSURL: "nats://127.0.0.1:1", NATSRequired: true, StartupWaitSeconds: 1}
+	start := time.Now()
+	_, err := infra.New(context.Background(), cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "still unavailable after")
+	assert.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond, "retried with backoff")
+
+	// Waiting ends with the context, e.g. on SIGTERM
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cfg.StartupWaitSeconds = 60
+	start = time.Now()
+	_, err = infra.New(ctx, cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stopped waiting")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestNew_RejectsIncompleteNATSClientCert(t *testing.T) {
+	_, err := infra.New(context.Background(), &config.Config{
+		NATSURL:         "nats://127.0.0.1:1",
+		NATSTLSCertFile: "client.crt",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "NATS_TLS_KEY_FILE")
+}
+
+func TestNewRedis(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr string
+	}{
+		{"single", config.Config{RedisAddr: "localhost:6379"}, ""},
+		{"sentinel", config.Config{RedisMode: "sentinel", RedisSentinelMaster: "mymaster", RedisAddrs: []string{"s1:26379", "s2:26379"}}, ""},
+		{"sentinel without master", config.Config{RedisMode: "sentinel", RedisAddrs: []string{"s1:26379"}}, "REDIS_SENTINEL_MASTER"},
+		{"cluster", config.Config{RedisMode: "cluster", RedisAddrs: []string{"n1:6379", "n2:6379"}}, ""},
+		{"cluster with DB", config.Config{RedisMode: "cluster", RedisAddrs: []string{"n1:6379"}, RedisDB: 2}, "DB 0"},
+		{"unknown mode", config.Config{RedisMode: "ring"}, "unknown redis mode"},
+		{"missing CA", config.Config{RedisTLSEnabled: true, RedisTLSCAFile: "testdata/missing.pem"}, "CA file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := infra.NewRedis(&tt.cfg)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, client.Close())
+		})
+	}
+}
+
+func TestNewRedis_TracesCommands(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	client, err := infra.NewRedis(&config.Config{RedisAddr: "127.0.0.1:1"})
+	require.NoError(t, err)
+	defer client.Close()
+	require.Error(t, client.Get(context.Background(), "key").Err())
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, "redis get", ended[0].Name())
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+}
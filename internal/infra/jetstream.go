@@ -0,0 +1,278 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/synapse/synapse/asyncapi"
+	"github.com/synapse/synapse/internal/config"
+)
+
+// JetStream provisioning modes
+const (
+	ProvisionOff   = "off"
+	ProvisionCheck = "check"
+	ProvisionApply = "apply"
+)
+
+// Drift is a difference between a JetStream stream or durable consumer and
+// what the AsyncAPI spec calls for
+type Drift struct {
+	Stream string
+	// Consumer is set for a consumer's drift
+	Consumer string
+	// Detail says what differs, e.g. "missing"
+	Detail string
+}
+
+func (d Drift) String() string {
+	if d.Consumer != "" {
+		return fmt.Sprintf("consumer %s of stream %s: %s", d.Consumer, d.Stream, d.Detail)
+	}
+	return fmt.Sprintf("stream %s: %s", d.Stream, d.Detail)
+}
+
+// jetStreamPlan derives the streams and durable consumers from the AsyncAPI
+// channels. The persisted channels are grouped in a stream per first subject
+// token, e.g. ORDERS for orders.*, and each operation receiving from one gets
+// a durable consumer named after it.
+func jetStreamPlan(cfg *config.Config) ([]jetstream.StreamConfig, map[string][]jetstream.ConsumerConfig, error) {
+	channels, err := asyncapi.Channels()
+	if err != nil {
+		return nil, nil, err
+	}
+	operations, err := asyncapi.Operations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var streams []jetstream.StreamConfig
+	index := make(map[string]int)
+	for _, ch := range channels {
+		if ch.Core {
+			continue
+		}
+		name := streamName(ch)
+		i, ok := index[name]
+		if !ok {
+			i = len(streams)
+			index[name] = i
+			streams = append(streams, jetstream.StreamConfig{
+				Name:     name,
+				Storage:  jetstream.FileStorage,
+				Replicas: max(cfg.JetStreamReplicas, 1),
+				MaxAge:   time.Duration(cfg.JetStreamMaxAgeHours) * time.Hour,
+			})
+		}
+		streams[i].Subjects = append(streams[i].Subjects, ch.Subject())
+	}
+	for i := range streams {
+		if cluster := placementCluster(cfg.JetStreamPlacement, streams[i].Subjects); cluster != "" {
+			streams[i].Placement = &jetstream.Placement{Cluster: cluster}
+		}
+	}
+
+	consumers := make(map[string][]jetstream.ConsumerConfig)
+	for _, op := range operations {
+		if op.Action != "receive" || op.Channel.Core {
+			continue
+		}
+		name := streamName(op.Channel)
+		consumers[name] = append(consumers[name], jetstream.ConsumerConfig{
+			Durable:       op.ID,
+			FilterSubject: op.Channel.Subject(),
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			DeliverPolicy: jetstream.DeliverAllPolicy,
+		})
+	}
+	return streams, consumers, nil
+}
+
+// placementCluster returns the cluster of the first placement whose subject
+// matches one of subjects, or "" to leave the stream where it is created
+func placementCluster(placement []config.StreamPlacement, subjects []string) string {
+	for _, p := range placement {
+		for _, subject := range subjects {
+			if subjectMatches(p.Subject, subject) {
+				return p.Cluster
+			}
+		}
+	}
+	return ""
+}
+
+// subjectMatches reports whether pattern, a subject with * and >
+// wildcards, matches every subject subject does
+func subjectMatches(pattern, subject string) bool {
+	patternTokens, tokens := strings.Split(pattern, "."), strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		switch {
+		case token == ">":
+			return i < len(tokens)
+		case i >= len(tokens):
+			return false
+		case token != "*" && token != tokens[i]:
+			return false
+		}
+	}
+	return len(patternTokens) == len(tokens)
+}
+
+func streamName(ch asyncapi.Channel) string {
+	token, _, _ := strings.Cut(ch.Subject(), ".")
+	return strings.ToUpper(token)
+}
+
+// ProvisionJetStream compares the JetStream streams and durable consumers on
+// nc with what the AsyncAPI channels call for, returning the drift found.
+// With apply, missing ones are created and drifted ones updated; it is
+// idempotent, so every replica can run it at startup.
+func ProvisionJetStream(ctx context.Context, nc *nats.Conn, cfg *config.Config, apply bool) ([]Drift, error) {
+	streams, consumers, err := jetStreamPlan(cfg)
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("opening JetStream: %w", err)
+	}
+
+	var drift []Drift
+	for _, want := range streams {
+		stream, err := js.Stream(ctx, want.Name)
+		switch {
+		case errors.Is(err, jetstream.ErrStreamNotFound):
+			drift = append(drift, Drift{Stream: want.Name, Detail: "missing"})
+			if apply {
+				if stream, err = js.CreateStream(ctx, want); err != nil {
+					return drift, fmt.Errorf("creating stream %s: %w", want.Name, err)
+				}
+			}
+		case err != nil:
+			return drift, fmt.Errorf("reading stream %s: %w", want.Name, err)
+		default:
+			if details := streamDrift(stream.CachedInfo().Config, want); len(details) > 0 {
+				drift = append(drift, Drift{Stream: want.Name, Detail: strings.Join(details, "; ")})
+				if apply {
+					// Settings the spec doesn't derive are kept as they are
+					have := stream.CachedInfo().Config
+					have.Subjects, have.Replicas, have.MaxAge = want.Subjects, want.Replicas, want.MaxAge
+					if want.Placement != nil {
+						have.Placement = want.Placement
+					}
+					if stream, err = js.UpdateStream(ctx, have); err != nil {
+						return drift, fmt.Errorf("updating stream %s: %w", want.Name, err)
+					}
+				}
+			}
+		}
+
+		for _, wantConsumer := range consumers[want.Name] {
+			d, err := provisionConsumer(ctx, stream, want.Name, wantConsumer, apply)
+			if err != nil {
+				return drift, err
+			}
+			drift = append(drift, d...)
+		}
+	}
+	return drift, nil
+}
+
+// provisionConsumer provisions a durable consumer of stream, which is nil
+// when the stream is missing
+func provisionConsumer(ctx context.Context, stream jetstream.Stream, streamName string, want jetstream.ConsumerConfig, apply bool) ([]Drift, error) {
+	if stream == nil {
+		return []Drift{{Stream: streamName, Consumer: want.Durable, Detail: "missing"}}, nil
+	}
+	var drift []Drift
+	consumer, err := stream.Consumer(ctx, want.Durable)
+	switch {
+	case errors.Is(err, jetstream.ErrConsumerNotFound):
+		drift = append(drift, Drift{Stream: streamName, Consumer: want.Durable, Detail: "missing"})
+	case err != nil:
+		return nil, fmt.Errorf("reading consumer %s of stream %s: %w", want.Durable, streamName, err)
+	default:
+		details := consumerDrift(consumer.CachedInfo().Config, want)
+		if len(details) == 0 {
+			return nil, nil
+		}
+		drift = append(drift, Drift{Stream: streamName, Consumer: want.Durable, Detail: strings.Join(details, "; ")})
+	}
+	if apply {
+		if _, err := stream.CreateOrUpdateConsumer(ctx, want); err != nil {
+			return drift, fmt.Errorf("provisioning consumer %s of stream %s: %w", want.Durable, streamName, err)
+		}
+	}
+	return drift, nil
+}
+
+func streamDrift(have, want jetstream.StreamConfig) []string {
+	var details []string
+	if !slices.Equal(slices.Sorted(slices.Values(have.Subjects)), slices.Sorted(slices.Values(want.Subjects))) {
+		details = append(details, fmt.Sprintf("subjects are %v, want %v", have.Subjects, want.Subjects))
+	}
+	if have.Replicas != want.Replicas {
+		details = append(details, fmt.Sprintf("replicas are %d, want %d", have.Replicas, want.Replicas))
+	}
+	if have.MaxAge != want.MaxAge {
+		details = append(details, fmt.Sprintf("max age is %s, want %s", have.MaxAge, want.MaxAge))
+	}
+	// Streams without a placement stay in whichever cluster they are
+	if want.Placement != nil && (have.Placement == nil || have.Placement.Cluster != want.Placement.Cluster) {
+		var cluster string
+		if have.Placement != nil {
+			cluster = have.Placement.Cluster
+		}
+		details = append(details, fmt.Sprintf("placed in cluster %q, want %q", cluster, want.Placement.Cluster))
+	}
+	return details
+}
+
+func consumerDrift(have, want jetstream.ConsumerConfig) []string {
+	var details []string
+	if have.FilterSubject != want.FilterSubject {
+		details = append(details, fmt.Sprintf("filter subject is %q, want %q", have.FilterSubject, want.FilterSubject))
+	}
+	if have.AckPolicy != want.AckPolicy {
+		details = append(details, fmt.Sprintf("ack policy is %s, want %s", have.AckPolicy, want.AckPolicy))
+	}
+	return details
+}
+
+// provisionJetStream runs the provisioning cfg.JetStreamProvision sets up
+// at startup, logging the drift found
+func (i *Infra) provisionJetStream(ctx context.Context) error {
+	mode := i.Config.JetStreamProvision
+	if mode != ProvisionCheck && mode != ProvisionApply {
+		return nil
+	}
+	if !i.NATS.IsConnected() {
+		return fmt.Errorf("provisioning JetStream: not connected to NATS")
+	}
+	drift, err := ProvisionJetStream(ctx, i.NATS, i.Config, mode == ProvisionApply)
+	for _, d := range drift {
+		if mode == ProvisionApply {
+			logger.Info("JetStream provisioned", "change", d.String())
+		} else {
+			logger.Warn("JetStream drift", "drift", d.String())
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("provisioning JetStream: %w", err)
+	}
+	return nil
+}
+
+// ConnectNATS connects to NATS as cfg configures, waiting for it as long as
+// cfg allows, for commands that only need NATS
+func ConnectNATS(ctx context.Context, cfg *config.Config) (*nats.Conn, error) {
+	c := *cfg
+	c.NATSRequired = true
+	return (&Infra{Config: &c}).connectNATS(ctx, &c)
+}
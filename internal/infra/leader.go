@@ -0,0 +1,159 @@
+package infra
+
+import (
+	"context"
+	"hash/fnv"
+	"maps"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/synapse/synapse/internal/clock"
+)
+
+// defaultLeaderRetry is how often a replica tries to become leader, and
+// how often the leader checks it still is
+const defaultLeaderRetry = 5 * time.Second
+
+// Elector elects the replica that runs a singleton background job, such as
+// a retention sweeper. The leader holds a Postgres session advisory lock,
+// named after the job, on a connection taken from the pool: the lock is
+// released when the leader stops or its connection drops, and another
+// replica takes over on its next try. Behind a transaction pooler, the
+// leader holds a transaction open instead, and the lock with it.
+type Elector struct {
+	name  string
+	key   int64
+	pool  *pgxpool.Pool
+	retry time.Duration
+	clock clock.Clock
+	// inTransaction takes the lock in a transaction, for transaction pooling
+	inTransaction bool
+	leader        atomic.Bool
+}
+
+// Elector returns the elector of the named job, reported by Leadership. The
+// returned elector's Run is to be called once.
+func (i *Infra) Elector(name string) *Elector {
+	h := fnv.New64a()
+	h.Write([]byte("synapse:leader:" + name))
+	e := &Elector{name: name, key: int64(h.Sum64()), pool: i.Pool, retry: defaultLeaderRetry, clock: clock.Or(i.Clock)}
+	if i.Config != nil && i.Config.LeaderRetryMs > 0 {
+		e.retry = millis(i.Config.LeaderRetryMs)
+	}
+	if i.Config != nil {
+		e.inTransaction = i.Config.PostgresTransactionPooling
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.electors == nil {
+		i.electors = make(map[string]*Elector)
+	}
+	i.electors[name] = e
+	return e
+}
+
+// Leadership reports, by job, whether this replica leads it
+func (i *Infra) Leadership() map[string]bool {
+	i.mu.Lock()
+	electors := maps.Clone(i.electors)
+	i.mu.Unlock()
+
+	leadership := make(map[string]bool, len(electors))
+	for name, e := range electors {
+		leadership[name] = e.IsLeader()
+	}
+	return leadership
+}
+
+// IsLeader reports whether this replica currently leads the job
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run runs job while this replica leads, until ctx is done. job's context
+// is cancelled when leadership is lost; a job that returns gives up
+// leadership, to be elected again.
+func (e *Elector) Run(ctx context.Context, job func(context.Context)) {
+	for {
+		e.lead(ctx, job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.clock.After(e.retry):
+		}
+	}
+}
+
+// lead runs job if the advisory lock is free, for as long as it is held
+func (e *Elector) lead(ctx context.Context, job func(context.Context)) {
+	if e.pool == nil {
+		return
+	}
+	conn, err := e.pool.Acquire(ctx)
+	if err != nil {
+		logger.DebugContext(ctx, "leader election: acquiring connection", "job", e.name, "error", err)
+		return
+	}
+	lock := "SELECT pg_try_advisory_lock($1)"
+	if e.inTransaction {
+		if _, err := conn.Exec(ctx, "BEGIN"); err != nil {
+			logger.WarnContext(ctx, "leader election: beginning transaction", "job", e.name, "error", err)
+			conn.Release()
+			return
+		}
+		lock = "SELECT pg_try_advisory_xact_lock($1)"
+	}
+	var locked bool
+	if err := conn.QueryRow(ctx, lock, e.key).Scan(&locked); err != nil || !locked {
+		if err != nil {
+			logger.WarnContext(ctx, "leader election: taking lock", "job", e.name, "error", err)
+		}
+		if e.inTransaction {
+			// The pool discards connections returned mid-transaction
+			_, _ = conn.Exec(ctx, "ROLLBACK")
+		}
+		conn.Release()
+		return
+	}
+	// Closing the session releases the lock, even if it can't be unlocked
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), e.retry)
+		defer cancel()
+		conn.Conn().Close(closeCtx)
+		conn.Release()
+	}()
+
+	e.leader.Store(true)
+	defer e.leader.Store(false)
+	logger.InfoContext(ctx, "became leader", "job", e.name)
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job(jobCtx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	ticker := e.clock.NewTicker(e.retry)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			logger.InfoContext(ctx, "leader job ended, giving up leadership", "job", e.name)
+			return
+		case <-ticker.C():
+			if err := conn.Ping(ctx); err != nil {
+				logger.WarnContext(ctx, "lost leadership", "job", e.name, "error", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/synapse/synapse/internal/clock"
+	"github.com/synapse/synapse/internal/keyspace"
+)
+
+// defaultLockTTL is how long a lock outlives a holder that stops renewing it
+// when no Config sets it
+const defaultLockTTL = 30 * time.Second
+
+// ErrLocked is returned by Acquire when another holder has the lock
+var ErrLocked = errors.New("locked by another holder")
+
+// ErrLockLost is the cause of a guarded context's cancellation when its lock
+// couldn't be renewed before it expired
+var ErrLockLost = errors.New("lock lost")
+
+// The scripts only touch the lock while its value is the holder's token
+var (
+	renewLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Locker takes named locks in Redis, so that an expensive operation, such as
+// an import or a DLQ replay, runs once at a time across replicas. A lock
+// expires unless its holder renews it, which Lock does in the background,
+// so a crashed holder doesn't keep it.
+type Locker struct {
+	client redis.UniversalClient
+	keys   keyspace.Keyspace
+	ttl    time.Duration
+	// clock paces the renewals and times out failing ones
+	clock clock.Clock
+}
+
+// Locker returns a locker on the Redis connection
+func (i *Infra) Locker() *Locker {
+	l := &Locker{client: i.Redis, keys: i.Keyspace(), ttl: defaultLockTTL, clock: clock.Or(i.Clock)}
+	if i.Config != nil && i.Config.LockTTLMs > 0 {
+		l.ttl = millis(i.Config.LockTTLMs)
+	}
+	return l
+}
+
+// Lock is a held lock, renewed until released or lost
+type Lock struct {
+	// Name is the locked operation
+	Name string
+	// Token identifies this acquisition of the lock; each one gets a greater
+	// token. The lock is only renewed and released while it holds the token.
+	// Nothing checks it at the guarded writes, so it doesn't fence a holder
+	// that stalls past the TTL: guarded work must stop once Lost is closed.
+	Token int64
+
+	locker *Locker
+	key    string
+	// lost is closed when the lock couldn't be renewed before it expired
+	lost chan struct{}
+	// stop ends the renewal, which closes stopped
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// Acquire takes the named lock, returning ErrLocked if another holder has
+// it. The lock must be released.
+func (l *Locker) Acquire(ctx context.Context, name string) (*Lock, error) {
+	if l.client == nil {
+		return nil, fmt.Errorf("locking %s: not connected to Redis", name)
+	}
+	// The hash tag keeps a lock's keys on one cluster node
+	key := l.keys.Key("lock", "{"+name+"}")
+	token, err := l.client.Incr(ctx, key+":fence").Result()
+	if err != nil {
+		return nil, fmt.Errorf("locking %s: %w", name, err)
+	}
+	ok, err := l.client.SetNX(ctx, key, strconv.FormatInt(token, 10), l.ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("locking %s: %w", name, err)
+	}
+	if !ok {
+		return nil, ErrLocked
+	}
+
+	lock := &Lock{
+		Name:    name,
+		Token:   token,
+		locker:  l,
+		key:     key,
+		lost:    make(chan struct{}),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go lock.renew()
+	return lock, nil
+}
+
+// Lost is closed when the lock couldn't be renewed before it expired;
+// another holder may have it since
+func (lk *Lock) Lost() <-chan struct{} {
+	return lk.lost
+}
+
+// Guard returns a context cancelled, with ErrLockLost as its cause, if the
+// lock is lost
+func (lk *Lock) Guard(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-lk.lost:
+			cancel(ErrLockLost)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() { cancel(nil) }
+}
+
+// Release stops renewing the lock and releases it, unless it was lost
+func (lk *Lock) Release(ctx context.Context) error {
+	close(lk.stop)
+	<-lk.stopped
+	if err := releaseLock.Run(ctx, lk.locker.client, []string{lk.key}, lk.token()).Err(); err != nil {
+		return fmt.Errorf("unlocking %s: %w", lk.Name, err)
+	}
+	return nil
+}
+
+func (lk *Lock) token() string {
+	return strconv.FormatInt(lk.Token, 10)
+}
+
+// renew extends the lock every third of its TTL. Failed renewals are retried
+// until the lock would have expired; a lock found with another holder's
+// token is lost at once.
+func (lk *Lock) renew() {
+	defer close(lk.stopped)
+	ttl, clk := lk.locker.ttl, lk.locker.clock
+	ticker := clk.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	renewed := clk.Now()
+	for {
+		select {
+		case <-lk.stop:
+			return
+		case <-ticker.C():
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+		n, err := renewLock.Run(ctx, lk.locker.client, []string{lk.key}, lk.token(), ttl.Milliseconds()).Int()
+		cancel()
+		switch {
+		case err == nil && n == 1:
+			renewed = clk.Now()
+			continue
+		case err == nil:
+			logger.Warn("lock taken over", "lock", lk.Name, "token", lk.Token)
+		case clk.Since(renewed) < ttl:
+			logger.Warn("renewing lock", "lock", lk.Name, "token", lk.Token, "error", err)
+			continue
+		default:
+			logger.Warn("lock expired before it could be renewed", "lock", lk.Name, "token", lk.Token, "error", err)
+		}
+		close(lk.lost)
+		return
+	}
+}
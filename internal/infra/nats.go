@@ -0,0 +1,178 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/synapse/synapse/internal/clock"
+	"github.com/synapse/synapse/internal/config"
+)
+
+// connectNATS connects to NATS as cfg configures. Dropped connections are
+// retried in the background; disconnections and reconnections are logged and
+// reported by the NATS health check until the connection is back. When NATS
+// is optional and down at startup, the first connection is retried the same
+// way and publishes are buffered meanwhile; when it is required, the first
+// connection is waited for as long as cfg allows.
+func (i *Infra) connectNATS(ctx context.Context, cfg *config.Config) (*nats.Conn, error) {
+	opts, err := i.natsOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var nc *nats.Conn
+	connect := func(context.Context) error {
+		nc, err = nats.Connect(cfg.NATSURL, opts...)
+		return err
+	}
+	if cfg.NATSRequired {
+		err = waitFor(ctx, "nats", startupWait(cfg), connect)
+	} else {
+		err = connect(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Checked under the lock so that a connection made meanwhile clears
+	// the outage natsConnected finds
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if !nc.IsConnected() {
+		logger.Warn("NATS unavailable, starting degraded", "url", cfg.NATSURL, "error", nc.LastError())
+		i.natsDisconnectedAt = clock.Or(i.Clock).Now().UTC()
+		i.natsDisconnectErr = nc.LastError()
+	}
+	return nc, nil
+}
+
+// natsOptions returns the options of connections to NATS
+func (i *Infra) natsOptions(cfg *config.Config) ([]nats.Option, error) {
+	opts := []nats.Option{
+		nats.Name("synapse"),
+		nats.MaxReconnects(cfg.NATSMaxReconnects),
+		nats.DisconnectErrHandler(i.natsDisconnected),
+		nats.ReconnectHandler(i.natsReconnected),
+		nats.ConnectHandler(i.natsConnected),
+		nats.DiscoveredServersHandler(func(nc *nats.Conn) {
+			logger.Info("NATS servers discovered", "servers", nc.DiscoveredServers())
+		}),
+		nats.ClosedHandler(func(nc *nats.Conn) {
+			logger.Error("NATS connection closed", "error", nc.LastError())
+		}),
+	}
+	if cfg.NATSReconnectWaitMs > 0 {
+		opts = append(opts, nats.ReconnectWait(millis(cfg.NATSReconnectWaitMs)))
+	}
+	if cfg.NATSReconnectBufBytes != 0 {
+		opts = append(opts, nats.ReconnectBufSize(cfg.NATSReconnectBufBytes))
+	}
+	if cfg.NATSConnectTimeoutMs > 0 {
+		opts = append(opts, nats.Timeout(millis(cfg.NATSConnectTimeoutMs)))
+	}
+	switch {
+	case cfg.NATSCredsFile != "":
+		opts = append(opts, nats.UserCredentials(cfg.NATSCredsFile))
+	case cfg.NATSUser != "":
+		opts = append(opts, nats.UserInfo(cfg.NATSUser, cfg.NATSPassword))
+	}
+	if cfg.NATSTLSCAFile != "" {
+		opts = append(opts, nats.RootCAs(cfg.NATSTLSCAFile))
+	}
+	if cfg.NATSTLSCertFile != "" || cfg.NATSTLSKeyFile != "" {
+		if cfg.NATSTLSCertFile == "" || cfg.NATSTLSKeyFile == "" {
+			return nil, fmt.Errorf("NATS client certificate needs both NATS_TLS_CERT_FILE and NATS_TLS_KEY_FILE")
+		}
+		opts = append(opts, nats.ClientCert(cfg.NATSTLSCertFile, cfg.NATSTLSKeyFile))
+	}
+	if cfg.NATSPreserveServerOrder {
+		opts = append(opts, nats.DontRandomize())
+	}
+	if !cfg.NATSRequired {
+		opts = append(opts, nats.RetryOnFailedConnect(true))
+	}
+	return opts, nil
+}
+
+func (i *Infra) natsDisconnected(nc *nats.Conn, err error) {
+	logger.Warn("NATS disconnected, reconnecting", "error", err)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.natsDisconnectedAt = clock.Or(i.Clock).Now().UTC()
+	i.natsDisconnectErr = err
+}
+
+// natsConnected notes the first connection, which is only late when NATS
+// was down at startup
+func (i *Infra) natsConnected(nc *nats.Conn) {
+	i.mu.Lock()
+	late := !i.natsDisconnectedAt.IsZero()
+	i.mu.Unlock()
+	if late {
+		i.natsReconnected(nc)
+	}
+}
+
+func (i *Infra) natsReconnected(nc *nats.Conn) {
+	i.mu.Lock()
+	down := clock.Or(i.Clock).Since(i.natsDisconnectedAt)
+	i.natsDisconnectedAt = time.Time{}
+	i.natsDisconnectErr = nil
+	i.mu.Unlock()
+	logger.Info("NATS reconnected", "url", nc.ConnectedUrlRedacted(), "downtime", down, "reconnects", nc.Stats().Reconnects)
+}
+
+// checkNATS reports whether the NATS connection is up and, if it isn't,
+// since when and why
+func (i *Infra) checkNATS() error {
+	if i.NATS == nil {
+		return fmt.Errorf("not connected")
+	}
+	if i.NATS.IsConnected() {
+		return nil
+	}
+
+	msg := strings.ToLower(i.NATS.Status().String())
+	i.mu.Lock()
+	since, cause := i.natsDisconnectedAt, i.natsDisconnectErr
+	i.mu.Unlock()
+	if !since.IsZero() {
+		msg += " since " + since.Format(time.RFC3339)
+	}
+	if cause != nil {
+		msg += ": " + cause.Error()
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// natsDrainPoll is how often drainNATS checks whether the drain is done
+const natsDrainPoll = 10 * time.Millisecond
+
+// drainNATS closes nc once its subscriptions have handled what they
+// received and what was published has been flushed, or at once when ctx is
+// done or NATS is unreachable
+func drainNATS(ctx context.Context, nc *nats.Conn) error {
+	if nc.IsClosed() {
+		return nil
+	}
+	if !nc.IsConnected() {
+		nc.Close()
+		return nil
+	}
+	if err := nc.Drain(); err != nil {
+		nc.Close()
+		return fmt.Errorf("draining: %w", err)
+	}
+	ticker := time.NewTicker(natsDrainPoll)
+	defer ticker.Stop()
+	for !nc.IsClosed() {
+		select {
+		case <-ctx.Done():
+			nc.Close()
+			return fmt.Errorf("draining: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
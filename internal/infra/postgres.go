@@ -0,0 +1,113 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// OpenPostgres opens a pgx connection pool to the database at dsn, sized and
+// recycled as cfg configures, and a database/sql handle that runs on the
+// pool's connections. Closing the handle leaves the pool open. Queries are
+// traced.
+func OpenPostgres(ctx context.Context, dsn string, cfg *config.Config) (*pgxpool.Pool, *sql.DB, error) {
+	pool, err := newPostgresPool(ctx, dsn, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, nil, fmt.Errorf("pinging postgres: %w", err)
+	}
+	return pool, stdlib.OpenDBFromPool(pool), nil
+}
+
+// newPostgresPool creates a pool to the database at dsn without connecting
+func newPostgresPool(ctx context.Context, dsn string, cfg *config.Config) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parsing postgres DSN: %w", err)
+	}
+	// Zero values keep pgxpool's defaults
+	if cfg.PostgresMaxConns > 0 {
+		poolCfg.MaxConns = int32(cfg.PostgresMaxConns)
+	}
+	if cfg.PostgresMinConns > 0 {
+		poolCfg.MinConns = int32(min(cfg.PostgresMinConns, int(poolCfg.MaxConns)))
+	}
+	if cfg.PostgresMaxConnLifetimeSeconds > 0 {
+		poolCfg.MaxConnLifetime = time.Duration(cfg.PostgresMaxConnLifetimeSeconds) * time.Second
+	}
+	if cfg.PostgresMaxConnIdleSeconds > 0 {
+		poolCfg.MaxConnIdleTime = time.Duration(cfg.PostgresMaxConnIdleSeconds) * time.Second
+	}
+	// The server stops statements the client gave up on, whose connection
+	// it may not notice is gone until it has finished them. A transaction
+	// pooler rejects the startup parameter, so its role sets the timeout.
+	if cfg.PostgresQueryTimeoutMs > 0 && !cfg.PostgresTransactionPooling {
+		poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.Itoa(cfg.PostgresQueryTimeoutMs)
+	}
+	// A transaction pooler may run each statement on another server session,
+	// which hasn't prepared it, so statements are sent with their arguments
+	if cfg.PostgresTransactionPooling {
+		poolCfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+		poolCfg.ConnConfig.StatementCacheCapacity = 0
+		poolCfg.ConnConfig.DescriptionCacheCapacity = 0
+	}
+	poolCfg.ConnConfig.Tracer = postgresTracer{}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("opening postgres pool: %w", err)
+	}
+	return pool, nil
+}
+
+// closePostgres closes the handle, then the pool, either of which may be
+// nil. Both wait for the connections in use, until ctx is done.
+func closePostgres(ctx context.Context, pool *pgxpool.Pool, handle *sql.DB) error {
+	closed := make(chan error, 1)
+	go func() {
+		var err error
+		if handle != nil {
+			err = handle.Close()
+		}
+		if pool != nil {
+			pool.Close()
+		}
+		closed <- err
+	}()
+	select {
+	case err := <-closed:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for connections in use: %w", ctx.Err())
+	}
+}
+
+// Store returns a Store on the primary, whose queries are bounded by the
+// query timeout and which encrypts the configured order columns
+func (i *Infra) Store() *store.Store {
+	s := store.New(i.DB).WithQueryTimeout(i.QueryTimeout())
+	if i.Config != nil {
+		s = s.WithEncryption(i.columnKeys, i.Config.StoreEncryptedColumns)
+	}
+	return s
+}
+
+// QueryTimeout returns how long a query may run, or 0 to leave it to its
+// context
+func (i *Infra) QueryTimeout() time.Duration {
+	if i.Config == nil {
+		return 0
+	}
+	return millis(i.Config.PostgresQueryTimeoutMs)
+}
@@ -0,0 +1,130 @@
+package infra
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/keyspace"
+)
+
+// Redis topologies
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+// NewRedis creates a client for the Redis topology cfg selects, with its pool
+// and timeouts. It doesn't connect until used. Commands are traced.
+func NewRedis(cfg *config.Config) (redis.UniversalClient, error) {
+	client, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client.AddHook(redisTracer{})
+	return client, nil
+}
+
+// Keyspace returns the namespace of the service's Redis keys
+func (i *Infra) Keyspace() keyspace.Keyspace {
+	if i.Config == nil {
+		return keyspace.Keyspace{}
+	}
+	return keyspace.New(i.Config.RedisKeyService, i.Config.RedisKeyTenant, i.Config.RedisKeyVersion)
+}
+
+func newRedisClient(cfg *config.Config) (redis.UniversalClient, error) {
+	tlsConfig, err := redisTLS(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.RedisMode {
+	case RedisModeSingle, "":
+		return redis.NewClient(&redis.Options{
+			Addr:         cfg.RedisAddr,
+			Username:     cfg.RedisUsername,
+			Password:     cfg.RedisPassword,
+			DB:           cfg.RedisDB,
+			TLSConfig:    tlsConfig,
+			PoolSize:     cfg.RedisPoolSize,
+			MinIdleConns: cfg.RedisMinIdleConns,
+			PoolTimeout:  millis(cfg.RedisPoolTimeoutMs),
+			DialTimeout:  millis(cfg.RedisDialTimeoutMs),
+			ReadTimeout:  millis(cfg.RedisReadTimeoutMs),
+			WriteTimeout: millis(cfg.RedisWriteTimeoutMs),
+		}), nil
+	case RedisModeSentinel:
+		if cfg.RedisSentinelMaster == "" || len(cfg.RedisAddrs) == 0 {
+			return nil, fmt.Errorf("redis sentinel mode needs REDIS_SENTINEL_MASTER and the Sentinels in REDIS_ADDRS")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.RedisSentinelMaster,
+			SentinelAddrs:    cfg.RedisAddrs,
+			SentinelPassword: cfg.RedisSentinelPassword,
+			Username:         cfg.RedisUsername,
+			Password:         cfg.RedisPassword,
+			DB:               cfg.RedisDB,
+			TLSConfig:        tlsConfig,
+			PoolSize:         cfg.RedisPoolSize,
+			MinIdleConns:     cfg.RedisMinIdleConns,
+			PoolTimeout:      millis(cfg.RedisPoolTimeoutMs),
+			DialTimeout:      millis(cfg.RedisDialTimeoutMs),
+			ReadTimeout:      millis(cfg.RedisReadTimeoutMs),
+			WriteTimeout:     millis(cfg.RedisWriteTimeoutMs),
+		}), nil
+	case RedisModeCluster:
+		if len(cfg.RedisAddrs) == 0 {
+			return nil, fmt.Errorf("redis cluster mode needs the seed nodes in REDIS_ADDRS")
+		}
+		if cfg.RedisDB != 0 {
+			return nil, fmt.Errorf("redis cluster only has DB 0, got REDIS_DB=%d", cfg.RedisDB)
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.RedisAddrs,
+			Username:     cfg.RedisUsername,
+			Password:     cfg.RedisPassword,
+			TLSConfig:    tlsConfig,
+			PoolSize:     cfg.RedisPoolSize,
+			MinIdleConns: cfg.RedisMinIdleConns,
+			PoolTimeout:  millis(cfg.RedisPoolTimeoutMs),
+			DialTimeout:  millis(cfg.RedisDialTimeoutMs),
+			ReadTimeout:  millis(cfg.RedisReadTimeoutMs),
+			WriteTimeout: millis(cfg.RedisWriteTimeoutMs),
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q, want %s, %s or %s",
+			cfg.RedisMode, RedisModeSingle, RedisModeSentinel, RedisModeCluster)
+	}
+}
+
+// millis converts a millisecond setting to a duration
+func millis(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+// redisTLS returns the TLS configuration of Redis connections, or nil when
+// TLS is disabled
+func redisTLS(cfg *config.Config) (*tls.Config, error) {
+	if !cfg.RedisTLSEnabled {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.RedisTLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.RedisTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading redis CA file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis CA file %s has no PEM certificates", cfg.RedisTLSCAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,95 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/synapse/synapse/internal/config"
+)
+
+// defaultReplicaCheck is how often a read replica's health is checked when
+// no Config sets it
+const defaultReplicaCheck = 5 * time.Second
+
+// replica is a Postgres read replica, read from while it answers its health
+// check. The check runs in the background when a read finds the last one
+// stale, so reads never wait on it.
+type replica struct {
+	pool     *pgxpool.Pool
+	db       *sql.DB
+	interval time.Duration
+	timeout  time.Duration
+
+	up        atomic.Bool
+	checking  atomic.Bool
+	checkedAt atomic.Int64
+}
+
+// openReplica opens a pool to the read replica cfg configures. A replica
+// that is down is reported and read from once it answers.
+func openReplica(ctx context.Context, cfg *config.Config) (*replica, error) {
+	pool, err := newPostgresPool(ctx, cfg.PostgresReplicaDSN, cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &replica{
+		pool:     pool,
+		db:       stdlib.OpenDBFromPool(pool),
+		interval: defaultReplicaCheck,
+		timeout:  defaultHealthCheckTimeout,
+	}
+	if cfg.PostgresReplicaCheckMs > 0 {
+		r.interval = millis(cfg.PostgresReplicaCheckMs)
+	}
+	if cfg.HealthCheckTimeoutMs > 0 {
+		r.timeout = millis(cfg.HealthCheckTimeoutMs)
+	}
+	r.checking.Store(true)
+	r.check(ctx)
+	return r, nil
+}
+
+// reader returns the replica's handle while it is healthy, nil otherwise
+func (r *replica) reader() *sql.DB {
+	if time.Since(time.Unix(0, r.checkedAt.Load())) >= r.interval && r.checking.CompareAndSwap(false, true) {
+		go r.check(context.Background())
+	}
+	if !r.up.Load() {
+		return nil
+	}
+	return r.db
+}
+
+// check pings the replica, logging when it goes down or comes back. The
+// caller has set checking.
+func (r *replica) check(ctx context.Context) {
+	defer r.checking.Store(false)
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	first := r.checkedAt.Load() == 0
+	err := r.pool.Ping(ctx)
+	r.checkedAt.Store(time.Now().UnixNano())
+	wasUp := r.up.Swap(err == nil)
+	switch {
+	case err != nil && (wasUp || first):
+		logger.Warn("Postgres read replica unavailable, reading from the primary", "error", err)
+	case err == nil && !wasUp:
+		logger.Info("Postgres read replica available, reading from it")
+	}
+}
+
+// ReadDB returns the handle for queries that tolerate replication lag: the
+// read replica's while it is healthy, the primary's otherwise or without a
+// replica
+func (i *Infra) ReadDB() *sql.DB {
+	if i.replica != nil {
+		if db := i.replica.reader(); db != nil {
+			return db
+		}
+	}
+	return i.DB
+}
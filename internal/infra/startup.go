@@ -0,0 +1,52 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/synapse/synapse/internal/config"
+)
+
+// Backoff between attempts to reach a dependency at startup
+const (
+	startupBackoffMin = 250 * time.Millisecond
+	startupBackoffMax = 5 * time.Second
+)
+
+// startupWait is how long startup waits for a dependency, from cfg
+func startupWait(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.StartupWaitSeconds) * time.Second
+}
+
+// waitFor calls reach until it succeeds, backing off exponentially between
+// attempts, for up to wait: a service started alongside its dependencies,
+// e.g. by docker compose or in a pod, then waits for them rather than
+// crash-looping. It gives up early when ctx is done. reach's errors should
+// be ones that can pass, as a configuration error is retried all the same.
+func waitFor(ctx context.Context, dependency string, wait time.Duration, reach func(context.Context) error) error {
+	deadline := time.Now().Add(wait)
+	backoff := startupBackoffMin
+	for attempt := 1; ; attempt++ {
+		err := reach(ctx)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("dependency available", "dependency", dependency, "attempts", attempt)
+			}
+			return nil
+		}
+		if time.Until(deadline) < backoff {
+			if attempt > 1 {
+				return fmt.Errorf("still unavailable after %d attempts in %s: %w", attempt, wait, err)
+			}
+			return err
+		}
+		logger.Warn("waiting for dependency", "dependency", dependency, "attempt", attempt, "retry_in", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting: %w", err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, startupBackoffMax)
+	}
+}
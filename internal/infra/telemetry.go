@@ -0,0 +1,187 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	"github.com/synapse/synapse/internal/buildinfo"
+	"github.com/synapse/synapse/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/synapse/synapse/internal/infra"
+
+// The clients report through the global providers, which do nothing until
+// SetupTelemetry installs exporting ones
+var (
+	tracer = otel.Tracer(instrumentationName)
+	meter  = otel.Meter(instrumentationName)
+
+	dbDuration, _ = meter.Float64Histogram("db.client.operation.duration",
+		metric.WithDescription("Duration of Postgres queries and Redis commands"), metric.WithUnit("s"))
+	messagingDuration, _ = meter.Float64Histogram("messaging.client.operation.duration",
+		metric.WithDescription("Duration of NATS publishes"), metric.WithUnit("s"))
+)
+
+// SetupTelemetry exports traces and metrics over OTLP/HTTP to
+// cfg.TracingEndpoint when tracing is enabled, sampling the given share of
+// traces that don't continue a sampled caller's. The returned function
+// flushes and stops the exporters.
+func SetupTelemetry(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	if !cfg.TracingEnabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", cfg.TracingServiceName),
+		attribute.String("service.version", buildinfo.Get().Version),
+	)
+	endpoint := strings.TrimSuffix(cfg.TracingEndpoint, "/")
+	traceExporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"))
+	if err != nil {
+		return nil, fmt.Errorf("creating trace exporter: %w", err)
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(endpoint+"/v1/metrics"))
+	if err != nil {
+		return nil, fmt.Errorf("creating metric exporter: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(float64(cfg.TracingSamplePercent)/100))),
+	)
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		return errors.Join(tp.Shutdown(ctx), mp.Shutdown(ctx))
+	}, nil
+}
+
+// endSpan records the outcome of a client call on its span and duration
+// histogram
+func endSpan(ctx context.Context, span trace.Span, hist metric.Float64Histogram, start time.Time, err error, attrs ...attribute.KeyValue) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		attrs = append(attrs, attribute.String("error.type", fmt.Sprintf("%T", err)))
+	}
+	span.End()
+	hist.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+}
+
+// postgresTracer traces the queries run on pool connections
+type postgresTracer struct{}
+
+type queryStartKey struct{}
+
+var _ pgx.QueryTracer = postgresTracer{}
+
+func (postgresTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = tracer.Start(ctx, "postgres query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.query.text", data.SQL),
+		))
+	return context.WithValue(ctx, queryStartKey{}, time.Now())
+}
+
+func (postgresTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, _ := ctx.Value(queryStartKey{}).(time.Time)
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.response.returned_rows", data.CommandTag.RowsAffected()))
+	endSpan(ctx, span, dbDuration, start, data.Err, attribute.String("db.system.name", "postgresql"))
+}
+
+// redisTracer traces Redis commands and pipelines
+type redisTracer struct{}
+
+var _ redis.Hook = redisTracer{}
+
+func (redisTracer) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (redisTracer) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		return traceRedis(ctx, cmd.Name(), func(ctx context.Context) error {
+			return next(ctx, cmd)
+		})
+	}
+}
+
+func (redisTracer) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		return traceRedis(ctx, "pipeline", func(ctx context.Context) error {
+			return next(ctx, cmds)
+		})
+	}
+}
+
+func traceRedis(ctx context.Context, operation string, call func(context.Context) error) error {
+	start := time.Now()
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system.name", "redis"),
+		attribute.String("db.operation.name", operation),
+	}
+	ctx, span := tracer.Start(ctx, "redis "+operation,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	err := call(ctx)
+	// A missing key is an answer, not a failure
+	if errors.Is(err, redis.Nil) {
+		endSpan(ctx, span, dbDuration, start, nil, attrs...)
+	} else {
+		endSpan(ctx, span, dbDuration, start, err, attrs...)
+	}
+	return err
+}
+
+// PublishNATS publishes data on a NATS subject, tracing the publish and
+// passing the trace on to subscribers in the message headers
+func (i *Infra) PublishNATS(ctx context.Context, subject string, data []byte) error {
+	if i.NATS == nil {
+		return fmt.Errorf("not connected to NATS")
+	}
+	start := time.Now()
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", "nats"),
+		attribute.String("messaging.operation.name", "publish"),
+	}
+	ctx, span := tracer.Start(ctx, "nats publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(append(attrs, attribute.String("messaging.destination.name", subject))...))
+
+	msg := &nats.Msg{Subject: subject, Data: data}
+	header := http.Header{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	if len(header) > 0 {
+		msg.Header = nats.Header(header)
+	}
+	err := i.NATS.PublishMsg(msg)
+	endSpan(ctx, span, messagingDuration, start, err, attrs...)
+	return err
+}
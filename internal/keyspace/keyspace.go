@@ -0,0 +1,51 @@
+// Package keyspace namespaces the service's Redis keys by service, tenant
+// and key schema version. Environments or tenants sharing a Redis each get
+// their own keys, and a changed key layout can be rolled out under a new
+// version while the keys of the old one expire.
+package keyspace
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DefaultService is the service keys are namespaced by when none is set
+const DefaultService = "synapse"
+
+// Keyspace builds the Redis keys of a namespace. The zero value is the
+// default service's, without tenant or version.
+type Keyspace struct {
+	prefix string
+}
+
+// New returns the keyspace of service, tenant and version. An empty tenant
+// is left out, as is version 0, the layout keys had before they were
+// versioned, so that the default keyspace keeps the existing keys.
+func New(service, tenant string, version int) Keyspace {
+	if service == "" {
+		service = DefaultService
+	}
+	segments := []string{service}
+	if tenant != "" {
+		segments = append(segments, tenant)
+	}
+	if version > 0 {
+		segments = append(segments, "v"+strconv.Itoa(version))
+	}
+	return Keyspace{prefix: strings.Join(segments, ":") + ":"}
+}
+
+// Prefix returns what every key of the keyspace starts with, e.g.
+// synapse:acme:v2:
+func (k Keyspace) Prefix() string {
+	if k.prefix == "" {
+		return DefaultService + ":"
+	}
+	return k.prefix
+}
+
+// Key joins parts into a key of the keyspace, e.g. Key("cache", "stages")
+// is synapse:acme:v2:cache:stages
+func (k Keyspace) Key(parts ...string) string {
+	return k.Prefix() + strings.Join(parts, ":")
+}
@@ -0,0 +1,27 @@
+package keyspace_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/synapse/synapse/internal/keyspace"
+)
+
+func TestKeyspace(t *testing.T) {
+	tests := []struct {
+		name string
+		keys keyspace.Keyspace
+		want string
+	}{
+		{"zero value", keyspace.Keyspace{}, "synapse:cache:order:1"},
+		{"default", keyspace.New("", "", 0), "synapse:cache:order:1"},
+		{"tenant", keyspace.New("synapse", "acme", 0), "synapse:acme:cache:order:1"},
+		{"version", keyspace.New("synapse", "", 2), "synapse:v2:cache:order:1"},
+		{"everything", keyspace.New("orders", "acme", 3), "orders:acme:v3:cache:order:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.keys.Key("cache", "order:1"))
+		})
+	}
+}
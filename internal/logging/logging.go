@@ -0,0 +1,228 @@
+// Package logging sets up the service's structured logs: text or JSON
+// records, a level per module, and sampling of high-volume debug records.
+//
+// Packages log through a module logger, so that their level can be set on its
+// own:
+//
+//	var logger = logging.Module("pipeline")
+//
+//	logger.Debug("validating order", "orderId", id)
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/requestid"
+)
+
+// ModuleKey is the attribute naming the module a record comes from
+const ModuleKey = "module"
+
+// Log formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New returns the logger cfg configures, writing to w: records are JSON or
+// text, as LogFormat says, and carry the request's ID. They are kept at
+// LogLevel or above, or the level LogModuleLevels sets for their module.
+// Past LogDebugSamplePerSecond records with the same message in a second,
+// debug records are dropped.
+func New(cfg *config.Config, w io.Writer) (*slog.Logger, error) {
+	levels, err := parseLevels(cfg.LogLevel, cfg.LogModuleLevels)
+	if err != nil {
+		return nil, err
+	}
+
+	// The inner handler keeps everything the module levels let through
+	opts := &slog.HandlerOptions{Level: levels.lowest()}
+	var next slog.Handler
+	switch cfg.LogFormat {
+	case FormatJSON, "":
+		next = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		next = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, want %s or %s", cfg.LogFormat, FormatJSON, FormatText)
+	}
+
+	h := &handler{next: next, levels: levels}
+	if cfg.LogDebugSamplePerSecond > 0 {
+		h.sampler = &sampler{perSecond: cfg.LogDebugSamplePerSecond}
+	}
+	return slog.New(requestid.NewLogHandler(h)), nil
+}
+
+// Module returns the logger for a module's records. They go to the default
+// logger as it is when they're logged, so packages can make theirs before
+// main sets the default up:
+//
+//	var logger = logging.Module("infra")
+func Module(name string) *slog.Logger {
+	return slog.New(&moduleHandler{attrs: []slog.Attr{slog.String(ModuleKey, name)}})
+}
+
+// moduleHandler hands records to the default logger's handler, with the
+// attributes and groups its logger was given
+type moduleHandler struct {
+	// attrs are added to the default's handler, then the groups that follow
+	attrs []slog.Attr
+	rest  []moduleStep
+
+	// derived is the handler made from the default logger last used
+	derived atomic.Pointer[derivedHandler]
+}
+
+// moduleStep adds a group, then attributes in it
+type moduleStep struct {
+	group string
+	attrs []slog.Attr
+}
+
+type derivedHandler struct {
+	from *slog.Logger
+	h    slog.Handler
+}
+
+func (h *moduleHandler) handler() slog.Handler {
+	def := slog.Default()
+	if d := h.derived.Load(); d != nil && d.from == def {
+		return d.h
+	}
+	next := def.Handler().WithAttrs(h.attrs)
+	for _, s := range h.rest {
+		next = next.WithGroup(s.group)
+		if len(s.attrs) > 0 {
+			next = next.WithAttrs(s.attrs)
+		}
+	}
+	h.derived.Store(&derivedHandler{from: def, h: next})
+	return next
+}
+
+func (h *moduleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler().Enabled(ctx, level)
+}
+
+func (h *moduleHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler().Handle(ctx, r)
+}
+
+func (h *moduleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := &moduleHandler{attrs: h.attrs, rest: slices.Clone(h.rest)}
+	if n := len(c.rest); n > 0 {
+		c.rest[n-1].attrs = append(slices.Clip(c.rest[n-1].attrs), attrs...)
+	} else {
+		c.attrs = append(slices.Clip(c.attrs), attrs...)
+	}
+	return c
+}
+
+func (h *moduleHandler) WithGroup(name string) slog.Handler {
+	return &moduleHandler{attrs: h.attrs, rest: append(slices.Clip(h.rest), moduleStep{group: name})}
+}
+
+// levels are the minimum levels of records, by module
+type levels struct {
+	base    slog.Level
+	modules map[string]slog.Level
+}
+
+func parseLevels(base string, modules map[string]string) (*levels, error) {
+	l := &levels{modules: make(map[string]slog.Level, len(modules))}
+	if err := l.base.UnmarshalText([]byte(base)); err != nil {
+		return nil, fmt.Errorf("log level: %w", err)
+	}
+	for module, level := range modules {
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("log level of %s: %w", module, err)
+		}
+		l.modules[module] = lvl
+	}
+	return l, nil
+}
+
+func (l *levels) of(module string) slog.Level {
+	if lvl, ok := l.modules[module]; ok {
+		return lvl
+	}
+	return l.base
+}
+
+func (l *levels) lowest() slog.Level {
+	lowest := l.base
+	for _, lvl := range l.modules {
+		lowest = min(lowest, lvl)
+	}
+	return lowest
+}
+
+// handler filters records by their module's level and samples debug ones
+type handler struct {
+	next    slog.Handler
+	levels  *levels
+	sampler *sampler
+	// module is the module of the logger's records, set by its ModuleKey
+	// attribute
+	module string
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.levels.of(h.module)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelInfo && h.sampler != nil && !h.sampler.allow(h.module, r.Message) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.next = h.next.WithAttrs(attrs)
+	for _, a := range attrs {
+		if a.Key == ModuleKey {
+			c.module = a.Value.String()
+		}
+	}
+	return &c
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.next = h.next.WithGroup(name)
+	return &c
+}
+
+// sampler lets through the first perSecond records with the same module and
+// message each second
+type sampler struct {
+	perSecond int
+
+	mu     sync.Mutex
+	window time.Time
+	counts map[string]int
+}
+
+func (s *sampler) allow(module, message string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.window) >= time.Second {
+		s.window, s.counts = now, make(map[string]int)
+	}
+	key := module + "\x00" + message
+	s.counts[key]++
+	return s.counts[key] <= s.perSecond
+}
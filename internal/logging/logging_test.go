@@ -0,0 +1,114 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/logging"
+	"github.com/synapse/synapse/internal/requestid"
+)
+
+// records decodes the JSON records written to buf
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var recs []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func TestNew_ModuleLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&config.Config{
+		LogFormat:       "json",
+		LogLevel:        "info",
+		LogModuleLevels: map[string]string{"pipeline": "debug", "handler": "error"},
+	}, &buf)
+	require.NoError(t, err)
+
+	logger.Debug("dropped: below the default level")
+	logger.Info("kept")
+	logger.With(logging.ModuleKey, "pipeline").Debug("kept: pipeline logs debug")
+	logger.With(logging.ModuleKey, "handler").Warn("dropped: handler logs errors only")
+
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	logger.InfoContext(ctx, "with request")
+
+	recs := records(t, &buf)
+	require.Len(t, recs, 3)
+	assert.Equal(t, "kept", recs[0]["msg"])
+	assert.Equal(t, "pipeline", recs[1][logging.ModuleKey])
+	assert.Equal(t, "req-1", recs[2][requestid.LogKey])
+}
+
+func TestNew_SamplesDebugRecords(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&config.Config{LogLevel: "debug", LogDebugSamplePerSecond: 2}, &buf)
+	require.NoError(t, err)
+
+	for range 5 {
+		logger.Debug("hot path")
+		logger.Info("not sampled")
+	}
+	logger.Debug("other message")
+
+	var debug, info int
+	for _, rec := range records(t, &buf) {
+		switch rec["level"] {
+		case slog.LevelDebug.String():
+			debug++
+		case slog.LevelInfo.String():
+			info++
+		}
+	}
+	assert.Equal(t, 3, debug, "two of the hot path's, and the other message")
+	assert.Equal(t, 5, info)
+}
+
+func TestNew_TextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&config.Config{LogFormat: "text", LogLevel: "info"}, &buf)
+	require.NoError(t, err)
+	logger.Info("hello", "orderId", "o-1")
+	assert.Contains(t, buf.String(), "msg=hello orderId=o-1")
+
+	_, err = logging.New(&config.Config{LogFormat: "xml", LogLevel: "info"}, &buf)
+	assert.Error(t, err)
+}
+
+func TestModule_FollowsTheDefault(t *testing.T) {
+	logger := logging.Module("pipeline").With("orderId", "o1").WithGroup("stage")
+
+	var buf bytes.Buffer
+	def, err := logging.New(&config.Config{
+		LogFormat:       "json",
+		LogLevel:        "info",
+		LogModuleLevels: map[string]string{"pipeline": "debug"},
+	}, &buf)
+	require.NoError(t, err)
+	prev := slog.Default()
+	slog.SetDefault(def)
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	// The module logger was made before the default was set, and still
+	// logs through it at its module's level
+	logger.Debug("validated", "name", "validate")
+
+	recs := records(t, &buf)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "pipeline", recs[0][logging.ModuleKey])
+	assert.Equal(t, "o1", recs[0]["orderId"])
+	assert.Equal(t, map[string]any{"name": "validate"}, recs[0]["stage"])
+}
@@ -0,0 +1,348 @@
+// Package metrics exposes the service's Prometheus metrics: HTTP requests,
+// pipeline stage metrics, dependency health, connection statistics and the
+// running build, plus the Go runtime and process collectors. Pipeline,
+// dependency and connection metrics are read at scrape time, so they are
+// only as fresh as the scrape.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"github.com/synapse/synapse/internal/buildinfo"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/pipeline"
+)
+
+// namespace prefixes every Synapse metric
+const namespace = "synapse"
+
+// healthTimeout bounds the dependency checks made during a scrape
+const healthTimeout = 2 * time.Second
+
+// Registry holds the service's metrics
+type Registry struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+}
+
+// New creates a Registry. Pipeline metrics are collected when runner is set;
+// dependency health and connection pool metrics when infra is.
+func New(infra *infra.Infra, runner *pipeline.Runner) *Registry {
+	m := &Registry{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests served, by route and status",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency, by route",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+
+	build := buildinfo.Get()
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "build_info",
+		Help:        "The running build; always 1",
+		ConstLabels: prometheus.Labels{"version": build.Version, "commit": build.Commit, "go_version": build.GoVersion},
+	})
+	buildInfo.Set(1)
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		buildInfo,
+		m.requests,
+		m.requestDuration,
+	)
+	if runner != nil {
+		m.registry.MustRegister(&pipelineCollector{runner: runner})
+	}
+	if infra != nil {
+		m.registry.MustRegister(&healthCollector{infra: infra}, &leaderCollector{infra: infra})
+		if infra.Pool != nil {
+			m.registry.MustRegister(&poolCollector{pool: infra.Pool})
+		}
+		if infra.Redis != nil {
+			m.registry.MustRegister(&redisPoolCollector{client: infra.Redis})
+		}
+		if infra.NATS != nil {
+			m.registry.MustRegister(&natsCollector{conn: infra.NATS})
+		}
+	}
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest records a served HTTP request. route is the matched route
+// pattern, not the request path, to keep label cardinality bounded.
+func (m *Registry) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.requestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+var (
+	stageProcessedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pipeline", "stage_processed_total"),
+		"Messages processed by a pipeline stage",
+		[]string{"stage"}, nil)
+	stageLatencyDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pipeline", "stage_avg_latency_seconds"),
+		"Average processing time of a pipeline stage",
+		[]string{"stage"}, nil)
+	stageErrorRateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pipeline", "stage_error_rate"),
+		"Fraction of a pipeline stage's messages that failed",
+		[]string{"stage"}, nil)
+	stageQueueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pipeline", "stage_queue_depth"),
+		"Messages waiting for a pipeline stage",
+		[]string{"stage"}, nil)
+	stageStatusDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pipeline", "stage_status"),
+		"Pipeline stage status; 1 for the stage's current status",
+		[]string{"stage", "status"}, nil)
+	ordersRoutedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pipeline", "orders_routed_total"),
+		"Orders routed, by destination",
+		[]string{"destination"}, nil)
+	poolConnsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "postgres_pool", "connections"),
+		"Connections in the Postgres pool, by state",
+		[]string{"state"}, nil)
+	poolMaxConnsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "postgres_pool", "max_connections"),
+		"Maximum size of the Postgres pool",
+		nil, nil)
+	poolAcquiresDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "postgres_pool", "acquires_total"),
+		"Connections acquired from the Postgres pool, by outcome: immediately from an idle one, after waiting, or cancelled while waiting",
+		[]string{"outcome"}, nil)
+	poolAcquireWaitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "postgres_pool", "acquire_duration_seconds_total"),
+		"Time spent acquiring connections from the Postgres pool",
+		nil, nil)
+	poolNewConnsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "postgres_pool", "new_connections_total"),
+		"Connections the Postgres pool opened",
+		nil, nil)
+	poolClosedConnsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "postgres_pool", "closed_connections_total"),
+		"Connections the Postgres pool closed for exceeding their lifetime or idle time, by reason",
+		[]string{"reason"}, nil)
+	redisConnsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "redis_pool", "connections"),
+		"Connections in the Redis pool, by state",
+		[]string{"state"}, nil)
+	redisAcquiresDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "redis_pool", "acquires_total"),
+		"Connections taken from the Redis pool, by outcome: an idle one, a new one, or timed out waiting for one",
+		[]string{"outcome"}, nil)
+	redisWaitsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "redis_pool", "waits_total"),
+		"Times a command waited for a connection from the full Redis pool",
+		nil, nil)
+	redisWaitDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "redis_pool", "wait_duration_seconds_total"),
+		"Time spent waiting for connections from the Redis pool",
+		nil, nil)
+	redisStaleConnsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "redis_pool", "stale_connections_total"),
+		"Stale connections the Redis pool closed",
+		nil, nil)
+	natsReconnectsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "nats", "reconnects_total"),
+		"Times the NATS connection was re-established",
+		nil, nil)
+	natsPendingBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "nats", "pending_bytes"),
+		"Bytes buffered for NATS but not yet flushed, which grow while it is disconnected",
+		nil, nil)
+	natsMessagesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "nats", "messages_total"),
+		"Messages sent and received over the NATS connection, by direction",
+		[]string{"direction"}, nil)
+	natsBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "nats", "bytes_total"),
+		"Payload bytes sent and received over the NATS connection, by direction",
+		[]string{"direction"}, nil)
+	dependencyUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "dependency_up"),
+		"Whether a dependency is reachable (1) or not (0)",
+		[]string{"dependency"}, nil)
+	leaderDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "leader"),
+		"Whether this replica leads a singleton background job (1) or not (0)",
+		[]string{"job"}, nil)
+)
+
+// pipelineCollector reports the pipeline's stage and routing metrics
+type pipelineCollector struct {
+	runner *pipeline.Runner
+}
+
+func (c *pipelineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- stageProcessedDesc
+	ch <- stageLatencyDesc
+	ch <- stageErrorRateDesc
+	ch <- stageQueueDepthDesc
+	ch <- stageStatusDesc
+	ch <- ordersRoutedDesc
+}
+
+func (c *pipelineCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.runner.GetStages() {
+		ch <- prometheus.MustNewConstMetric(stageProcessedDesc, prometheus.CounterValue, float64(s.Metrics.ProcessedTotal), s.StageId)
+		ch <- prometheus.MustNewConstMetric(stageLatencyDesc, prometheus.GaugeValue, s.Metrics.AvgLatencyMs/1000, s.StageId)
+		ch <- prometheus.MustNewConstMetric(stageErrorRateDesc, prometheus.GaugeValue, s.Metrics.ErrorRate, s.StageId)
+		ch <- prometheus.MustNewConstMetric(stageQueueDepthDesc, prometheus.GaugeValue, float64(s.Metrics.QueueDepth), s.StageId)
+		ch <- prometheus.MustNewConstMetric(stageStatusDesc, prometheus.GaugeValue, 1, s.StageId, string(s.Status))
+	}
+	for _, d := range c.runner.RoutingStats().Destinations {
+		ch <- prometheus.MustNewConstMetric(ordersRoutedDesc, prometheus.CounterValue, float64(d.Count), d.Destination)
+	}
+}
+
+// poolCollector reports the Postgres connection pool's statistics
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolConnsDesc
+	ch <- poolMaxConnsDesc
+	ch <- poolAcquiresDesc
+	ch <- poolAcquireWaitDesc
+	ch <- poolNewConnsDesc
+	ch <- poolClosedConnsDesc
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	st := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(st.AcquiredConns()), "acquired")
+	ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(st.IdleConns()), "idle")
+	ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(st.ConstructingConns()), "constructing")
+	ch <- prometheus.MustNewConstMetric(poolMaxConnsDesc, prometheus.GaugeValue, float64(st.MaxConns()))
+
+	// AcquireCount counts the successful acquires, EmptyAcquireCount those
+	// that had to wait
+	waited := st.EmptyAcquireCount()
+	ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(st.AcquireCount()-waited), "immediate")
+	ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(waited), "waited")
+	ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(st.CanceledAcquireCount()), "cancelled")
+	ch <- prometheus.MustNewConstMetric(poolAcquireWaitDesc, prometheus.CounterValue, st.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolNewConnsDesc, prometheus.CounterValue, float64(st.NewConnsCount()))
+	ch <- prometheus.MustNewConstMetric(poolClosedConnsDesc, prometheus.CounterValue, float64(st.MaxLifetimeDestroyCount()), "max_lifetime")
+	ch <- prometheus.MustNewConstMetric(poolClosedConnsDesc, prometheus.CounterValue, float64(st.MaxIdleDestroyCount()), "max_idle")
+}
+
+// redisPoolCollector reports the Redis connection pool's statistics
+type redisPoolCollector struct {
+	client redis.UniversalClient
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- redisConnsDesc
+	ch <- redisAcquiresDesc
+	ch <- redisWaitsDesc
+	ch <- redisWaitDurationDesc
+	ch <- redisStaleConnsDesc
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	st := c.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(redisConnsDesc, prometheus.GaugeValue, float64(st.TotalConns-min(st.IdleConns, st.TotalConns)), "in_use")
+	ch <- prometheus.MustNewConstMetric(redisConnsDesc, prometheus.GaugeValue, float64(st.IdleConns), "idle")
+	ch <- prometheus.MustNewConstMetric(redisAcquiresDesc, prometheus.CounterValue, float64(st.Hits), "idle")
+	ch <- prometheus.MustNewConstMetric(redisAcquiresDesc, prometheus.CounterValue, float64(st.Misses), "new")
+	ch <- prometheus.MustNewConstMetric(redisAcquiresDesc, prometheus.CounterValue, float64(st.Timeouts), "timeout")
+	ch <- prometheus.MustNewConstMetric(redisWaitsDesc, prometheus.CounterValue, float64(st.WaitCount))
+	ch <- prometheus.MustNewConstMetric(redisWaitDurationDesc, prometheus.CounterValue, time.Duration(st.WaitDurationNs).Seconds())
+	ch <- prometheus.MustNewConstMetric(redisStaleConnsDesc, prometheus.CounterValue, float64(st.StaleConns))
+}
+
+// natsCollector reports the NATS connection's statistics
+type natsCollector struct {
+	conn *nats.Conn
+}
+
+func (c *natsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- natsReconnectsDesc
+	ch <- natsPendingBytesDesc
+	ch <- natsMessagesDesc
+	ch <- natsBytesDesc
+}
+
+func (c *natsCollector) Collect(ch chan<- prometheus.Metric) {
+	st := c.conn.Stats()
+	ch <- prometheus.MustNewConstMetric(natsReconnectsDesc, prometheus.CounterValue, float64(st.Reconnects))
+	// A closed connection has nothing left to flush
+	if pending, err := c.conn.Buffered(); err == nil {
+		ch <- prometheus.MustNewConstMetric(natsPendingBytesDesc, prometheus.GaugeValue, float64(pending))
+	}
+	ch <- prometheus.MustNewConstMetric(natsMessagesDesc, prometheus.CounterValue, float64(st.InMsgs), "in")
+	ch <- prometheus.MustNewConstMetric(natsMessagesDesc, prometheus.CounterValue, float64(st.OutMsgs), "out")
+	ch <- prometheus.MustNewConstMetric(natsBytesDesc, prometheus.CounterValue, float64(st.InBytes), "in")
+	ch <- prometheus.MustNewConstMetric(natsBytesDesc, prometheus.CounterValue, float64(st.OutBytes), "out")
+}
+
+// healthCollector reports whether each dependency is reachable
+type healthCollector struct {
+	infra *infra.Infra
+}
+
+func (c *healthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dependencyUpDesc
+}
+
+func (c *healthCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+	defer cancel()
+
+	for name, err := range c.infra.Healthy(ctx) {
+		up := 1.0
+		if err != nil {
+			up = 0
+		}
+		ch <- prometheus.MustNewConstMetric(dependencyUpDesc, prometheus.GaugeValue, up, name)
+	}
+}
+
+// leaderCollector reports which singleton jobs this replica leads
+type leaderCollector struct {
+	infra *infra.Infra
+}
+
+func (c *leaderCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- leaderDesc
+}
+
+func (c *leaderCollector) Collect(ch chan<- prometheus.Metric) {
+	for job, leader := range c.infra.Leadership() {
+		v := 0.0
+		if leader {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(leaderDesc, prometheus.GaugeValue, v, job)
+	}
+}
@@ -0,0 +1,85 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/metrics"
+	"github.com/synapse/synapse/internal/pipeline"
+)
+
+// scrape returns the registry's exposition output
+func scrape(t *testing.T, m *metrics.Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestRegistry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runner, err := pipeline.New(ctx, &config.Config{RetryMaxAttempts: 1, RetryBackoffMs: 10}, nil)
+	require.NoError(t, err)
+
+	// No connections, so every dependency reports down
+	m := metrics.New(&infra.Infra{}, runner)
+	m.ObserveRequest("GET", "/api/v1/orders/{orderId}", http.StatusNotFound, 20*time.Millisecond)
+	m.ObserveRequest("GET", "/api/v1/orders/{orderId}", http.StatusNotFound, 30*time.Millisecond)
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `synapse_http_requests_total{method="GET",route="/api/v1/orders/{orderId}",status="404"} 2`)
+	assert.Contains(t, out, `synapse_http_request_duration_seconds_count{method="GET",route="/api/v1/orders/{orderId}"} 2`)
+	assert.Contains(t, out, `synapse_pipeline_stage_processed_total{stage="validate"} 0`)
+	assert.Contains(t, out, `synapse_pipeline_stage_status{stage="route",status="healthy"} 1`)
+	assert.Contains(t, out, `synapse_pipeline_orders_routed_total{destination="fulfillment"} 0`)
+	assert.Contains(t, out, `synapse_dependency_up{dependency="postgres"} 0`)
+	assert.Contains(t, out, `synapse_dependency_up{dependency="nats"} 0`)
+	assert.Contains(t, out, `synapse_build_info{commit="",go_version="`+runtime.Version()+`",version="dev"} 1`)
+	assert.Contains(t, out, "go_goroutines")
+}
+
+func TestRegistry_PostgresPool(t *testing.T) {
+	// The pool connects lazily, so it needs no server until used
+	pool, err := pgxpool.New(context.Background(), "postgres://synapse@127.0.0.1:1/synapse?pool_max_conns=7")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	out := scrape(t, metrics.New(&infra.Infra{Pool: pool}, nil))
+	assert.Contains(t, out, `synapse_postgres_pool_max_connections 7`)
+	assert.Contains(t, out, `synapse_postgres_pool_connections{state="idle"} 0`)
+	assert.Contains(t, out, `synapse_postgres_pool_acquires_total{outcome="immediate"} 0`)
+	assert.Contains(t, out, `synapse_postgres_pool_closed_connections_total{reason="max_lifetime"} 0`)
+}
+
+func TestRegistry_RedisAndNATS(t *testing.T) {
+	// Redis connects lazily and NATS keeps retrying, so neither needs a server
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer rdb.Close()
+	nc, err := nats.Connect("nats://127.0.0.1:1", nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+	require.NoError(t, err)
+	defer nc.Close()
+
+	out := scrape(t, metrics.New(&infra.Infra{Redis: rdb, NATS: nc}, nil))
+	assert.Contains(t, out, `synapse_redis_pool_connections{state="idle"} 0`)
+	assert.Contains(t, out, `synapse_redis_pool_acquires_total{outcome="timeout"} 0`)
+	assert.Contains(t, out, `synapse_nats_reconnects_total 0`)
+	assert.Contains(t, out, `synapse_nats_pending_bytes 0`)
+	assert.Contains(t, out, `synapse_nats_messages_total{direction="out"} 0`)
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/logging"
+	"github.com/synapse/synapse/internal/requestid"
+)
+
+// logger logs the package's records
+var logger = logging.Module("middleware")
+
+// auditTimeout bounds recording an audit entry once the response is written
+const auditTimeout = 5 * time.Second
+
+// AuditRecord is a mutating API call
+type AuditRecord struct {
+	RequestID string
+	// Principal is the authenticated client, nil for anonymous calls
+	Principal *auth.Principal
+	Method    string
+	Route     string
+	Path      string
+	// Params are the route's path parameters, e.g. orderId
+	Params    map[string]string
+	Query     string
+	BodyBytes int64
+	Status    int
+	Duration  time.Duration
+	At        time.Time
+}
+
+// AuditRecorder records audited API calls
+type AuditRecorder interface {
+	RecordAudit(ctx context.Context, rec *AuditRecord) error
+}
+
+// Audit records every POST, PUT, PATCH and DELETE request to rec once it has
+// been served, with the principal, route, path parameters and response
+// status. Request bodies are only measured, as they may carry secrets.
+// Requests to the skipped route patterns, e.g. read-only POST endpoints,
+// aren't recorded. It must run after Authenticate, and wrap handlers of a
+// chi router so the route pattern is known. Failing to record a call is
+// logged but doesn't fail the request.
+func Audit(rec AuditRecorder, skipRoutes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			body := &countingReader{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			record := &AuditRecord{
+				RequestID: requestid.FromContext(r.Context()),
+				Principal: auth.FromContext(r.Context()),
+				Method:    r.Method,
+				Route:     "unmatched",
+				Path:      r.URL.Path,
+				Params:    map[string]string{},
+				Query:     r.URL.RawQuery,
+				BodyBytes: body.n,
+				Status:    ww.Status(),
+				Duration:  time.Since(start),
+				At:        start,
+			}
+			if record.Status == 0 {
+				record.Status = http.StatusOK
+			}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					record.Route = pattern
+				}
+				for i, key := range rctx.URLParams.Keys {
+					if key != "*" {
+						record.Params[key] = rctx.URLParams.Values[i]
+					}
+				}
+			}
+			if slices.Contains(skipRoutes, record.Route) {
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), auditTimeout)
+			defer cancel()
+			if err := rec.RecordAudit(ctx, record); err != nil {
+				logger.ErrorContext(ctx, "recording audit entry", "error", err,
+					"method", record.Method, "path", record.Path, "principal", record.Principal)
+			}
+		})
+	}
+}
+
+// countingReader counts the bytes read from a request body
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
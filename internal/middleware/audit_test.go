@@ -0,0 +1,75 @@
+package middleware_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/middleware"
+)
+
+// recordingAuditor keeps every audit record
+type recordingAuditor struct {
+	records []*middleware.AuditRecord
+}
+
+func (a *recordingAuditor) RecordAudit(_ context.Context, rec *middleware.AuditRecord) error {
+	a.records = append(a.records, rec)
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	rec := &recordingAuditor{}
+	principal := &auth.Principal{ID: "key-1", Method: auth.MethodAPIKey}
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+		})
+	})
+	r.Use(middleware.Audit(rec, "/api/v1/graphql"))
+	r.Delete("/api/v1/orders/{orderId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+	r.Post("/api/v1/pipeline/dlq/{eventId}/retry", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+	})
+	r.Get("/api/v1/orders/{orderId}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Post("/api/v1/graphql", func(w http.ResponseWriter, r *http.Request) {})
+
+	requests := []*http.Request{
+		httptest.NewRequest("DELETE", "/api/v1/orders/order-1", nil),
+		httptest.NewRequest("POST", "/api/v1/pipeline/dlq/event-1/retry?dryRun=true", strings.NewReader(`{"priority":"high"}`)),
+		httptest.NewRequest("GET", "/api/v1/orders/order-1", nil),
+		httptest.NewRequest("POST", "/api/v1/graphql", strings.NewReader(`{"query":"{ stages { id } }"}`)),
+	}
+	for _, req := range requests {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Reads and skipped routes aren't recorded
+	require.Len(t, rec.records, 2)
+
+	cancel := rec.records[0]
+	assert.Equal(t, "DELETE", cancel.Method)
+	assert.Equal(t, "/api/v1/orders/{orderId}", cancel.Route)
+	assert.Equal(t, "/api/v1/orders/order-1", cancel.Path)
+	assert.Equal(t, map[string]string{"orderId": "order-1"}, cancel.Params)
+	assert.Equal(t, http.StatusConflict, cancel.Status)
+	assert.Same(t, principal, cancel.Principal)
+
+	retry := rec.records[1]
+	assert.Equal(t, "/api/v1/pipeline/dlq/{eventId}/retry", retry.Route)
+	assert.Equal(t, map[string]string{"eventId": "event-1"}, retry.Params)
+	assert.Equal(t, "dryRun=true", retry.Query)
+	assert.Equal(t, int64(len(`{"priority":"high"}`)), retry.BodyBytes)
+	assert.Equal(t, http.StatusOK, retry.Status)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/problem"
+)
+
+// Authenticate rejects requests that no authenticator accepts with 401
+// problem+json, and stores the authenticated principal in the request
+// context. Authenticators are tried in order until one recognizes the
+// request's credentials.
+func Authenticate(authenticators ...auth.Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, a := range authenticators {
+				p, err := a.Authenticate(r)
+				switch {
+				case errors.Is(err, auth.ErrNoCredentials):
+					continue
+				case errors.Is(err, auth.ErrTokenExpired):
+					problem.Write(w, r, problem.TokenExpired())
+					return
+				case errors.Is(err, auth.ErrInvalidCredentials):
+					problem.Write(w, r, problem.Unauthorized("The provided credentials are invalid or have been revoked"))
+					return
+				case err != nil:
+					problem.Write(w, r, problem.Upstream("auth", err))
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
+				return
+			}
+			problem.Write(w, r, problem.Unauthorized("Authentication is required: provide an API key in the "+auth.APIKeyHeader+" header or a bearer token"))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/middleware"
+)
+
+// headerAuth accepts requests whose X-Test-Auth header is "valid"
+type headerAuth struct{}
+
+func (headerAuth) Authenticate(r *http.Request) (*auth.Principal, error) {
+	switch r.Header.Get("X-Test-Auth") {
+	case "":
+		return nil, auth.ErrNoCredentials
+	case "valid":
+		return &auth.Principal{ID: "key-1", Method: auth.MethodAPIKey}, nil
+	case "expired":
+		return nil, auth.ErrTokenExpired
+	case "down":
+		return nil, errors.New("connection refused")
+	default:
+		return nil, auth.ErrInvalidCredentials
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	var principal *auth.Principal
+	h := middleware.Authenticate(headerAuth{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = auth.FromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		header string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"revoked", http.StatusUnauthorized},
+		{"expired", http.StatusUnauthorized},
+		{"down", http.StatusServiceUnavailable},
+		{"valid", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		principal = nil
+		req := httptest.NewRequest("GET", "/api/v1/orders", nil)
+		if tt.header != "" {
+			req.Header.Set("X-Test-Auth", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, tt.status, rec.Code, "header %q", tt.header)
+		if tt.status == http.StatusUnauthorized {
+			assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `Bearer realm="synapse"`)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+		}
+	}
+	if assert.NotNil(t, principal) {
+		assert.Equal(t, "key-1", principal.ID)
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/synapse/synapse/internal/problem"
+)
+
+// BodyLimit rejects request bodies larger than maxBytes with 413 problem
+// details. Requests declaring a larger Content-Length are rejected up front;
+// other bodies fail to read past the limit, with an *http.MaxBytesError that
+// handlers report as problem.PayloadTooLarge. Requests to the skipped route
+// patterns, e.g. streamed imports with their own limit, aren't limited. It
+// must be added to a chi route group (With or Group), where the route
+// pattern is known when it runs. A maxBytes of 0 or less disables the limit.
+func BodyLimit(maxBytes int64, skipRoutes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if maxBytes <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && slices.Contains(skipRoutes, rctx.RoutePattern()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > maxBytes {
+				problem.Write(w, r, problem.PayloadTooLarge(maxBytes))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/middleware"
+)
+
+func TestBodyLimit(t *testing.T) {
+	readBody := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			var tooLarge *http.MaxBytesError
+			require.True(t, errors.As(err, &tooLarge))
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.BodyLimit(8, "/api/v1/orders/import"))
+		r.Post("/api/v1/orders", readBody)
+		r.Post("/api/v1/orders/import", readBody)
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusNoContent, serve(httptest.NewRequest("POST", "/api/v1/orders", strings.NewReader(`{"a":1}`))).Code)
+
+	// Declared too large: rejected before the handler runs
+	rec := serve(httptest.NewRequest("POST", "/api/v1/orders", strings.NewReader(`{"a":12345}`)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	var body problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "https://synapse.example.com/problems/payload-too-large", body.Type)
+
+	// Undeclared length: cut off while reading
+	req := httptest.NewRequest("POST", "/api/v1/orders", strings.NewReader(`{"a":12345}`))
+	req.ContentLength = -1
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(req).Code)
+
+	// Skipped routes aren't limited
+	assert.Equal(t, http.StatusNoContent, serve(httptest.NewRequest("POST", "/api/v1/orders/import", strings.NewReader(`{"a":12345}`))).Code)
+}
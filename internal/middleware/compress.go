@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Content codings the Compress middleware can apply, in order of preference
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+var (
+	// gzip and zlib writers allocate large internal state, so they are reused
+	gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+	zlibWriters = sync.Pool{New: func() any { return zlib.NewWriter(nil) }}
+)
+
+// Compress compresses JSON and CSV responses of at least minBytes with gzip
+// or deflate, whichever the client's Accept-Encoding prefers. Smaller
+// responses, other media types (such as event streams), and responses that
+// already carry a Content-Encoding are sent as they are. JSON and CSV
+// responses get "Vary: Accept-Encoding" whether or not they were compressed.
+//
+// A response flushed before reaching minBytes is sent uncompressed, so
+// streaming handlers aren't held back by buffering.
+func Compress(minBytes int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if r.Method == http.MethodHead {
+				encoding = ""
+			}
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minBytes: minBytes}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header, or
+// "" when the client accepts neither
+func negotiateEncoding(header string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		weight := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				weight = v
+			}
+		}
+		q[coding] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{encodingGzip, encodingDeflate} {
+		weight, ok := q[coding]
+		if !ok {
+			weight, ok = q["*"]
+		}
+		if ok && weight > bestQ {
+			best, bestQ = coding, weight
+		}
+	}
+	return best
+}
+
+// compressible reports whether a response of contentType is worth compressing
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" ||
+		mediaType == "application/x-ndjson" ||
+		mediaType == "text/csv" ||
+		strings.HasSuffix(mediaType, "+json")
+}
+
+// compressWriter holds back a compressible response until it reaches
+// minBytes, then compresses the rest of it. States: before WriteHeader;
+// buffering (eligible, below minBytes); compressing (enc set); or passing
+// through (header sent, enc nil).
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minBytes int
+
+	status      int
+	wroteHeader bool
+	buffering   bool
+	buf         []byte
+	enc         io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+
+	h := w.Header()
+	if status == http.StatusNoContent || status == http.StatusNotModified ||
+		h.Get("Content-Encoding") != "" || !compressible(h.Get("Content-Type")) {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	h.Add("Vary", "Accept-Encoding")
+	if w.encoding == "" {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.buffering = true
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case w.enc != nil:
+		return w.enc.Write(p)
+	case !w.buffering:
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) < w.minBytes {
+		return len(p), nil
+	}
+	if err := w.startCompression(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// startCompression sends the header for a compressed response and
+// compresses what was buffered
+func (w *compressWriter) startCompression() error {
+	h := w.Header()
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	switch w.encoding {
+	case encodingGzip:
+		zw := gzipWriters.Get().(*gzip.Writer)
+		zw.Reset(w.ResponseWriter)
+		w.enc = zw
+	case encodingDeflate:
+		zw := zlibWriters.Get().(*zlib.Writer)
+		zw.Reset(w.ResponseWriter)
+		w.enc = zw
+	}
+	w.buffering = false
+	buf := w.buf
+	w.buf = nil
+	_, err := w.enc.Write(buf)
+	return err
+}
+
+// passThrough sends the header and whatever was buffered uncompressed
+func (w *compressWriter) passThrough() error {
+	w.buffering = false
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// Flush sends buffered output to the client. A response still below minBytes
+// is sent uncompressed from then on.
+func (w *compressWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError is Flush for http.ResponseController
+func (w *compressWriter) FlushError() error {
+	if w.buffering {
+		if err := w.passThrough(); err != nil {
+			return err
+		}
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close finishes the response once the handler has returned
+func (w *compressWriter) close() {
+	if w.buffering {
+		_ = w.passThrough()
+		return
+	}
+	if w.enc == nil {
+		return
+	}
+	_ = w.enc.Close()
+	switch zw := w.enc.(type) {
+	case *gzip.Writer:
+		zw.Reset(nil)
+		gzipWriters.Put(zw)
+	case *zlib.Writer:
+		zw.Reset(nil)
+		zlibWriters.Put(zw)
+	}
+	w.enc = nil
+}
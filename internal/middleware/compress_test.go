@@ -0,0 +1,126 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/middleware"
+)
+
+const compressMinBytes = 64
+
+// serveCompressed runs a request with acceptEncoding through Compress wrapping h
+func serveCompressed(t *testing.T, acceptEncoding string, h http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/api/v1/orders", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	middleware.Compress(compressMinBytes)(h).ServeHTTP(rec, req)
+	return rec
+}
+
+// writeChunked responds with body, written in pieces as encoders do
+func writeChunked(contentType, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		for _, chunk := range strings.SplitAfter(body, ",") {
+			_, _ = io.WriteString(w, chunk)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"items":[` + strings.Repeat(`{"sku":"SKU-1"},`, 20) + `{}]}`
+	small := `{"ok":true}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		encoding       string
+		vary           bool
+	}{
+		{name: "gzip", acceptEncoding: "gzip, deflate", contentType: "application/json", body: large, encoding: "gzip", vary: true},
+		{name: "deflate", acceptEncoding: "deflate", contentType: "application/json", body: large, encoding: "deflate", vary: true},
+		{name: "preferred by weight", acceptEncoding: "gzip;q=0.5, deflate", contentType: "application/json", body: large, encoding: "deflate", vary: true},
+		{name: "gzip refused", acceptEncoding: "*, gzip;q=0", contentType: "application/json", body: large, encoding: "deflate", vary: true},
+		{name: "problem details", acceptEncoding: "gzip", contentType: "application/problem+json", body: large, encoding: "gzip", vary: true},
+		{name: "below threshold", acceptEncoding: "gzip", contentType: "application/json", body: small, vary: true},
+		{name: "not accepted", contentType: "application/json", body: large, vary: true},
+		{name: "unsupported coding", acceptEncoding: "br", contentType: "application/json", body: large, vary: true},
+		{name: "CSV", acceptEncoding: "gzip", contentType: "text/csv; charset=utf-8", body: large, encoding: "gzip", vary: true},
+		{name: "not JSON", acceptEncoding: "gzip", contentType: "text/plain", body: large},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveCompressed(t, tt.acceptEncoding, writeChunked(tt.contentType, tt.body))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.encoding, rec.Header().Get("Content-Encoding"))
+			if tt.vary {
+				assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+			} else {
+				assert.Empty(t, rec.Header().Get("Vary"))
+			}
+
+			var body io.Reader = rec.Body
+			switch tt.encoding {
+			case "gzip":
+				zr, err := gzip.NewReader(rec.Body)
+				require.NoError(t, err)
+				body = zr
+			case "deflate":
+				zr, err := zlib.NewReader(rec.Body)
+				require.NoError(t, err)
+				body = zr
+			}
+			got, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(got))
+		})
+	}
+}
+
+func TestCompress_AlreadyEncoded(t *testing.T) {
+	rec := serveCompressed(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "identity")
+		_, _ = io.WriteString(w, strings.Repeat("x", 2*compressMinBytes))
+	})
+	assert.Equal(t, "identity", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, strings.Repeat("x", 2*compressMinBytes), rec.Body.String())
+}
+
+func TestCompress_FlushBeforeThreshold(t *testing.T) {
+	rec := serveCompressed(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		require.NoError(t, http.NewResponseController(w).Flush())
+		_, _ = io.WriteString(w, strings.Repeat("x", 2*compressMinBytes))
+	})
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.True(t, rec.Flushed)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, strings.Repeat("x", 2*compressMinBytes), rec.Body.String())
+}
+
+func TestCompress_NoBody(t *testing.T) {
+	rec := serveCompressed(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+	})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Empty(t, rec.Body.String())
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// OpenAPI extensions describing a deprecated operation
+const (
+	// extDeprecatedAt is the date the operation was deprecated
+	extDeprecatedAt = "x-deprecated-at"
+	// extSunset is the date the operation stops being served
+	extSunset = "x-sunset"
+	// extDeprecationLink is a document describing the deprecation
+	extDeprecationLink = "x-deprecation-link"
+	// extSuccessor is the path of the operation replacing it. Its template
+	// parameters are filled in from the request's.
+	extSuccessor = "x-successor-version"
+)
+
+// deprecation holds the response headers of a deprecated operation
+type deprecation struct {
+	header string
+	sunset string
+	links  []string
+}
+
+// parseDeprecation reads the deprecation extensions of an operation marked
+// deprecated. Dates are RFC 3339 timestamps or plain dates, taken as UTC
+// midnight.
+func parseDeprecation(def map[string]any) (*deprecation, error) {
+	// Without a date, the header takes the value of the original draft
+	d := &deprecation{header: "true"}
+	if at, ok, err := extDate(def, extDeprecatedAt); err != nil {
+		return nil, err
+	} else if ok {
+		d.header = "@" + strconv.FormatInt(at.Unix(), 10)
+	}
+	if at, ok, err := extDate(def, extSunset); err != nil {
+		return nil, err
+	} else if ok {
+		d.sunset = at.UTC().Format(http.TimeFormat)
+	}
+	if link, ok := def[extDeprecationLink].(string); ok {
+		d.links = append(d.links, "<"+link+`>; rel="deprecation"`)
+	}
+	if link, ok := def[extSuccessor].(string); ok {
+		d.links = append(d.links, "<"+link+`>; rel="successor-version"`)
+	}
+	return d, nil
+}
+
+// extDate reads a date extension, which YAML may already have parsed
+func extDate(def map[string]any, name string) (time.Time, bool, error) {
+	switch v := def[name].(type) {
+	case nil:
+		return time.Time{}, false, nil
+	case time.Time:
+		return v, true, nil
+	case string:
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true, nil
+			}
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("%s must be a date, got %v", name, def[name])
+}
+
+// expandParams replaces the {name} templates in s with the route's URL
+// parameters
+func expandParams(s string, rctx *chi.Context) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	for i, key := range rctx.URLParams.Keys {
+		s = strings.ReplaceAll(s, "{"+key+"}", url.PathEscape(rctx.URLParams.Values[i]))
+	}
+	return s
+}
+
+// Deprecations adds the headers that signal deprecation to the responses of
+// operations the OpenAPI spec marks `deprecated: true`: Deprecation (RFC
+// 9745), Sunset (RFC 8594) from x-sunset, and Link with rel="deprecation"
+// and rel="successor-version" from x-deprecation-link and
+// x-successor-version.
+type Deprecations struct {
+	// operations maps "METHOD template" to the deprecated operations
+	operations map[string]*deprecation
+}
+
+// NewDeprecations loads the deprecated operations of the OpenAPI spec at
+// specPath
+func NewDeprecations(specPath string) (*Deprecations, error) {
+	ops, err := loadOperations(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading OpenAPI spec: %w", err)
+	}
+	d := &Deprecations{operations: make(map[string]*deprecation)}
+	for _, op := range ops {
+		if op.deprecation != nil {
+			d.operations[op.method+" "+op.template] = op.deprecation
+		}
+	}
+	return d, nil
+}
+
+// Middleware returns the chi middleware. Operations are matched by route
+// pattern, which must be spelled like the spec's path template, so it must
+// be added to a chi route group (With or Group). Handlers must add, not set,
+// Link headers to keep these.
+func (d *Deprecations) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rctx := chi.RouteContext(r.Context())
+		if rctx == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		method := r.Method
+		if method == http.MethodHead {
+			method = http.MethodGet
+		}
+		if dep, ok := d.operations[method+" "+rctx.RoutePattern()]; ok {
+			w.Header().Set("Deprecation", dep.header)
+			if dep.sunset != "" {
+				w.Header().Set("Sunset", dep.sunset)
+			}
+			for _, link := range dep.links {
+				w.Header().Add("Link", expandParams(link, rctx))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,67 @@
+package middleware_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/middleware"
+)
+
+const deprecatedSpec = `openapi: 3.1.0
+paths:
+  /api/v1/orders/{orderId}:
+    get:
+      operationId: getOrder
+      deprecated: true
+      x-deprecated-at: 2025-01-01
+      x-sunset: "2026-06-30T00:00:00Z"
+      x-deprecation-link: https://synapse.example.com/docs/migrating-to-v2
+      x-successor-version: /api/v2/orders/{orderId}
+    delete:
+      operationId: cancelOrder
+  /api/v1/orders:
+    get:
+      operationId: listOrders
+      deprecated: true
+`
+
+func TestDeprecations(t *testing.T) {
+	specPath := filepath.Join(t.TempDir(), "openapi.yaml")
+	require.NoError(t, os.WriteFile(specPath, []byte(deprecatedSpec), 0o600))
+	d, err := middleware.NewDeprecations(specPath)
+	require.NoError(t, err)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `</api/v1/orders?cursor=abc>; rel="next"`)
+		w.WriteHeader(http.StatusNoContent)
+	}
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Use(d.Middleware)
+		r.Get("/api/v1/orders", ok)
+		r.Get("/api/v1/orders/{orderId}", ok)
+		r.Delete("/api/v1/orders/{orderId}", ok)
+	})
+
+	rec := serve(r, "GET", "/api/v1/orders/123", "", "")
+	assert.Equal(t, "@1735689600", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Tue, 30 Jun 2026 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, []string{
+		`<https://synapse.example.com/docs/migrating-to-v2>; rel="deprecation"`,
+		`</api/v2/orders/123>; rel="successor-version"`,
+		`</api/v1/orders?cursor=abc>; rel="next"`,
+	}, rec.Header().Values("Link"))
+
+	// Undated deprecations
+	rec = serve(r, "GET", "/api/v1/orders", "", "")
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Empty(t, rec.Header().Get("Sunset"))
+
+	rec = serve(r, "DELETE", "/api/v1/orders/123", "", "")
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/synapse/synapse/internal/problem"
+)
+
+// routeMethods are the methods probed when listing a path's allowed methods
+var routeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// AllowedMethods returns the methods routes serves path with. HEAD is allowed
+// wherever GET is, as the router answers it with chi's GetHead, and OPTIONS
+// always is; it's answered by MethodNotAllowed. Methods the router only
+// serves through a less specific pattern, e.g. GET /orders/import through
+// /orders/{orderId}, belong to another resource and aren't listed.
+func AllowedMethods(routes chi.Routes, path string) []string {
+	patterns := make(map[string]string)
+	var resource string
+	for _, method := range routeMethods {
+		pattern := routes.Find(chi.NewRouteContext(), method, path)
+		if pattern == "" {
+			continue
+		}
+		patterns[method] = pattern
+		if resource == "" || strings.Count(pattern, "{") < strings.Count(resource, "{") {
+			resource = pattern
+		}
+	}
+
+	var allowed []string
+	for _, method := range routeMethods {
+		if patterns[method] == resource && resource != "" ||
+			method == http.MethodHead && slices.Contains(allowed, http.MethodGet) {
+			allowed = append(allowed, method)
+		}
+	}
+	return append(allowed, http.MethodOptions)
+}
+
+// MethodNotAllowed handles requests whose path routes serves, but not with
+// the request's method. OPTIONS is answered with 204 and an Allow header
+// listing the path's methods; any other method gets 405 problem details with
+// the same header (RFC 9110 §15.5.6). Set it as the router's
+// MethodNotAllowed handler.
+func MethodNotAllowed(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+			path = rctx.RoutePath
+		}
+		w.Header().Set("Allow", strings.Join(AllowedMethods(routes, path), ", "))
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		problem.Write(w, r, problem.MethodNotAllowed(r.Method))
+	}
+}
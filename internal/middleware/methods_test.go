@@ -0,0 +1,68 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/middleware"
+)
+
+func TestMethodNotAllowed(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Method", r.Method)
+		_, _ = w.Write([]byte("ok"))
+	}
+
+	r := chi.NewRouter()
+	r.Use(chimiddleware.GetHead)
+	r.MethodNotAllowed(middleware.MethodNotAllowed(r))
+	r.Group(func(r chi.Router) {
+		r.Get("/api/v1/orders/{orderId}", ok)
+		r.Delete("/api/v1/orders/{orderId}", ok)
+		r.Post("/api/v1/orders/import", ok)
+		r.Post("/api/v1/graphql", ok)
+	})
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := serve("OPTIONS", "/api/v1/orders/123")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "GET, HEAD, DELETE, OPTIONS", rec.Header().Get("Allow"))
+
+	// GET /api/v1/orders/import would be routed to /api/v1/orders/{orderId},
+	// another resource
+	rec = serve("OPTIONS", "/api/v1/orders/import")
+	assert.Equal(t, "POST, OPTIONS", rec.Header().Get("Allow"))
+
+	rec = serve("OPTIONS", "/api/v1/graphql")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "POST, OPTIONS", rec.Header().Get("Allow"))
+
+	// HEAD is served by the GET route
+	rec = serve("HEAD", "/api/v1/orders/123")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "HEAD", rec.Header().Get("X-Method"))
+
+	rec = serve("PUT", "/api/v1/orders/123")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD, DELETE, OPTIONS", rec.Header().Get("Allow"))
+	var body problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "https://synapse.example.com/problems/method-not-allowed", body.Type)
+
+	rec = serve("HEAD", "/api/v1/graphql")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "POST, OPTIONS", rec.Header().Get("Allow"))
+
+	assert.Equal(t, http.StatusNotFound, serve("OPTIONS", "/api/v1/unknown").Code)
+}
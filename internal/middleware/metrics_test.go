@@ -0,0 +1,50 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/middleware"
+)
+
+type observation struct {
+	method, route string
+	status        int
+}
+
+// recordingObserver keeps every observed request
+type recordingObserver struct {
+	observed []observation
+}
+
+func (o *recordingObserver) ObserveRequest(method, route string, status int, _ time.Duration) {
+	o.observed = append(o.observed, observation{method: method, route: route, status: status})
+}
+
+func TestMetrics(t *testing.T) {
+	obs := &recordingObserver{}
+	r := chi.NewRouter()
+	r.Use(middleware.Metrics(obs))
+	r.Get("/api/v1/orders/{orderId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	for _, path := range []string{"/api/v1/orders/order-1", "/api/v1/orders/order-2", "/health", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	require.Len(t, obs.observed, 4)
+	// Requests are labelled by route pattern, not path
+	assert.Equal(t, observation{"GET", "/api/v1/orders/{orderId}", http.StatusNotFound}, obs.observed[0])
+	assert.Equal(t, obs.observed[0], obs.observed[1])
+	assert.Equal(t, observation{"GET", "/health", http.StatusOK}, obs.observed[2])
+	assert.Equal(t, observation{"GET", "unmatched", http.StatusNotFound}, obs.observed[3])
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/ratelimit"
+)
+
+// RateLimit limits requests per client: the authenticated principal when
+// there is one, otherwise the client IP. It must run after Authenticate.
+// Responses carry RateLimit-* headers; rejected requests get 429
+// problem+json with Retry-After. If the limiter fails, requests are let
+// through rather than failing the API.
+func RateLimit(limiter ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := limiter.Allow(r.Context(), clientKey(r))
+			if err != nil {
+				logger.WarnContext(r.Context(), "rate limiter unavailable, allowing request", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(res.ResetAfter.Seconds()))))
+
+			if !res.Allowed {
+				problem.Write(w, r, problem.RateLimited(
+					fmt.Sprintf("Rate limit of %d requests exceeded", res.Limit), res.RetryAfter))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey identifies the client a request counts against
+func clientKey(r *http.Request) string {
+	if p := auth.FromContext(r.Context()); p != nil {
+		return p.Method + ":" + p.ID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
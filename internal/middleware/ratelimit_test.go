@@ -0,0 +1,73 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/middleware"
+	"github.com/synapse/synapse/internal/ratelimit"
+)
+
+// countingLimiter allows the first n requests per key
+type countingLimiter struct {
+	n      int
+	counts map[string]int
+	err    error
+}
+
+func (l *countingLimiter) Allow(_ context.Context, key string) (ratelimit.Result, error) {
+	if l.err != nil {
+		return ratelimit.Result{}, l.err
+	}
+	l.counts[key]++
+	if l.counts[key] > l.n {
+		return ratelimit.Result{Limit: l.n, RetryAfter: 1500 * time.Millisecond, ResetAfter: 3 * time.Second}, nil
+	}
+	return ratelimit.Result{Allowed: true, Limit: l.n, Remaining: l.n - l.counts[key], ResetAfter: time.Second}, nil
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := &countingLimiter{n: 2, counts: make(map[string]int)}
+	h := middleware.RateLimit(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := func(principal *auth.Principal, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/orders", nil)
+		req.RemoteAddr = remoteAddr
+		if principal != nil {
+			req = req.WithContext(auth.NewContext(req.Context(), principal))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	key := &auth.Principal{ID: "key-1", Method: auth.MethodAPIKey}
+	rec := request(key, "10.0.0.1:1234")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusNoContent, request(key, "10.0.0.2:1234").Code)
+	rec = request(key, "10.0.0.3:1234")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+	// Limits are per client: other keys and anonymous IPs have their own buckets
+	assert.Equal(t, http.StatusNoContent, request(&auth.Principal{ID: "key-2", Method: auth.MethodAPIKey}, "10.0.0.1:1234").Code)
+	assert.Equal(t, http.StatusNoContent, request(nil, "10.0.0.1:1234").Code)
+	assert.Equal(t, 1, limiter.counts["ip:10.0.0.1"])
+
+	// A failing limiter lets requests through
+	limiter.err = errors.New("connection refused")
+	assert.Equal(t, http.StatusNoContent, request(key, "10.0.0.1:1234").Code)
+}
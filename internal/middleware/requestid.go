@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/internal/requestid"
+)
+
+// RequestID gives every request an ID: the client's X-Request-Id when it is
+// a UUID, as the API specification requires, or a generated one otherwise.
+// The ID is stored in the request context and echoed in the X-Request-Id
+// response header.
+func RequestID() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestid.Header)
+			if _, err := uuid.Parse(id); err != nil {
+				id = requestid.New()
+			}
+			w.Header().Set(requestid.Header, id)
+			next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/synapse/synapse/internal/middleware"
+	"github.com/synapse/synapse/internal/requestid"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := middleware.RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestid.FromContext(r.Context())
+	}))
+
+	request := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/orders", nil)
+		if id != "" {
+			req.Header.Set("X-Request-Id", id)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A client ID is propagated
+	clientID := "7c4d89e0-3b4a-4f2a-9c1d-8e7f6a5b4c3d"
+	rec := request(clientID)
+	assert.Equal(t, clientID, rec.Header().Get("X-Request-Id"))
+	assert.Equal(t, clientID, seen)
+
+	// Missing or malformed IDs are replaced
+	for _, id := range []string{"", "not-a-uuid\nforged log line"} {
+		rec = request(id)
+		got := rec.Header().Get("X-Request-Id")
+		_, err := uuid.Parse(got)
+		assert.NoError(t, err)
+		assert.NotEqual(t, id, got)
+		assert.Equal(t, got, seen)
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/synapse/synapse/internal/generated"
+)
+
+// SchemaValidator validates values against the OpenAPI spec's component
+// schemas, for payloads that don't arrive as a single request body (e.g. the
+// rows of a bulk import)
+type SchemaValidator struct {
+	mu      sync.Mutex
+	loader  *specLoader
+	schemas map[string]*jsonschema.Schema
+}
+
+// NewSchemaValidator loads the component schemas of the OpenAPI spec at specPath
+func NewSchemaValidator(specPath string) (*SchemaValidator, error) {
+	l, _, err := newSpecLoader(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading OpenAPI schemas: %w", err)
+	}
+	return &SchemaValidator{loader: l, schemas: make(map[string]*jsonschema.Schema)}, nil
+}
+
+// Validate checks value against the named component schema, e.g.
+// OrderCreateRequest, returning one error per invalid field
+func (v *SchemaValidator) Validate(name string, value any) []generated.ValidationError {
+	schema, err := v.schema(name)
+	if err != nil {
+		return []generated.ValidationError{{Code: "invalid", Message: err.Error()}}
+	}
+	if err := schema.Validate(value); err != nil {
+		return schemaErrors(err, "", value)
+	}
+	return nil
+}
+
+func (v *SchemaValidator) schema(name string) (*jsonschema.Schema, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.schemas[name]; ok {
+		return s, nil
+	}
+	s, err := v.loader.compiler.Compile("synapse://schemas/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("compiling schema %s: %w", name, err)
+	}
+	v.schemas[name] = s
+	return s, nil
+}
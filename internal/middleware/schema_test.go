@@ -0,0 +1,39 @@
+package middleware_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/middleware"
+)
+
+func TestSchemaValidator(t *testing.T) {
+	v, err := middleware.NewSchemaValidator(openAPISpecPath)
+	require.NoError(t, err)
+
+	valid := map[string]any{
+		"customerId":  "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+		"items":       []any{map[string]any{"sku": "SKU-1", "quantity": 1.0, "unitPrice": 10.0}},
+		"totalAmount": 10.0,
+		"currency":    "USD",
+	}
+	assert.Empty(t, v.Validate("OrderCreateRequest", valid))
+
+	invalid := map[string]any{
+		"customerId":  "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+		"items":       []any{map[string]any{"sku": "SKU-1", "quantity": 0.0, "unitPrice": 10.0}},
+		"totalAmount": 10.0,
+		"currency":    "usd",
+	}
+	errs := v.Validate("OrderCreateRequest", invalid)
+	fields := make([]string, 0, len(errs))
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	assert.ElementsMatch(t, []string{"currency", "items[0].quantity"}, fields)
+
+	errs = v.Validate("NoSuchSchema", valid)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "invalid", errs[0].Code)
+}
@@ -0,0 +1,346 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// operation is an OpenAPI operation's request contract
+type operation struct {
+	method   string
+	template string
+	segments []string
+	params   []*parameter
+	body     *requestBody
+	// deprecation is set for operations marked deprecated
+	deprecation *deprecation
+}
+
+// match reports whether path matches the operation's path template
+func (op *operation) match(path string) bool {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) != len(op.segments) {
+		return false
+	}
+	for i, s := range op.segments {
+		if !isTemplate(s) && s != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pathParams extracts the template parameters of a matched path
+func (op *operation) pathParams(path string) map[string]string {
+	params := make(map[string]string)
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, s := range op.segments {
+		if isTemplate(s) {
+			params[strings.Trim(s, "{}")] = segments[i]
+		}
+	}
+	return params
+}
+
+func isTemplate(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// parameter is a path, query or header parameter
+type parameter struct {
+	name     string
+	in       string
+	required bool
+	kind     string
+	itemKind string
+	explode  bool
+	schema   *jsonschema.Schema
+}
+
+// requestBody maps the accepted media types to their schemas
+type requestBody struct {
+	required bool
+	schemas  map[string]*jsonschema.Schema
+}
+
+// specLoader reads a multi-file OpenAPI document and compiles its schemas
+type specLoader struct {
+	files    map[string]map[string]any
+	compiler *jsonschema.Compiler
+	inline   int
+}
+
+var httpMethods = []string{"get", "put", "post", "delete", "patch", "head", "options"}
+
+// newSpecLoader creates a loader for the spec at specPath with its component
+// schemas registered, returning the spec's absolute path
+func newSpecLoader(specPath string) (*specLoader, string, error) {
+	l := &specLoader{
+		files:    make(map[string]map[string]any),
+		compiler: jsonschema.NewCompiler(),
+	}
+	l.compiler.AssertFormat = true
+
+	specPath, err := filepath.Abs(specPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("resolving spec path: %w", err)
+	}
+	if err := l.addComponentSchemas(filepath.Join(filepath.Dir(specPath), "components", "schemas")); err != nil {
+		return nil, "", err
+	}
+	return l, specPath, nil
+}
+
+// loadOperations reads the operations of the OpenAPI spec at specPath
+func loadOperations(specPath string) ([]*operation, error) {
+	l, specPath, err := newSpecLoader(specPath)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := l.file(specPath)
+	if err != nil {
+		return nil, err
+	}
+	paths, pathsFile, err := l.deref(spec["paths"], specPath)
+	if err != nil {
+		return nil, fmt.Errorf("resolving paths: %w", err)
+	}
+
+	templates := make([]string, 0, len(paths))
+	for template := range paths {
+		templates = append(templates, template)
+	}
+	sort.Strings(templates)
+
+	var ops []*operation
+	for _, template := range templates {
+		item, itemFile, err := l.deref(paths[template], pathsFile)
+		if err != nil {
+			return nil, fmt.Errorf("resolving path %s: %w", template, err)
+		}
+		for _, method := range httpMethods {
+			def, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			op, err := l.operation(strings.ToUpper(method), template, def, itemFile)
+			if err != nil {
+				return nil, fmt.Errorf("loading %s %s: %w", strings.ToUpper(method), template, err)
+			}
+			ops = append(ops, op)
+		}
+	}
+	return ops, nil
+}
+
+func (l *specLoader) operation(method, template string, def map[string]any, file string) (*operation, error) {
+	op := &operation{
+		method:   method,
+		template: template,
+		segments: strings.Split(strings.Trim(template, "/"), "/"),
+	}
+	if deprecated, _ := def["deprecated"].(bool); deprecated {
+		d, err := parseDeprecation(def)
+		if err != nil {
+			return nil, err
+		}
+		op.deprecation = d
+	}
+
+	params, _ := def["parameters"].([]any)
+	for _, p := range params {
+		param, paramFile, err := l.deref(p, file)
+		if err != nil {
+			return nil, err
+		}
+		compiled, err := l.parameter(param, paramFile)
+		if err != nil {
+			return nil, err
+		}
+		op.params = append(op.params, compiled)
+	}
+
+	if def["requestBody"] != nil {
+		body, _, err := l.deref(def["requestBody"], file)
+		if err != nil {
+			return nil, err
+		}
+		op.body = &requestBody{schemas: make(map[string]*jsonschema.Schema)}
+		op.body.required, _ = body["required"].(bool)
+		content, _ := body["content"].(map[string]any)
+		for mediaType, m := range content {
+			media, _ := m.(map[string]any)
+			schema, _ := media["schema"].(map[string]any)
+			if op.body.schemas[mediaType], err = l.compile(schema); err != nil {
+				return nil, fmt.Errorf("compiling %s body schema: %w", mediaType, err)
+			}
+		}
+	}
+	return op, nil
+}
+
+func (l *specLoader) parameter(def map[string]any, file string) (*parameter, error) {
+	p := &parameter{}
+	p.name, _ = def["name"].(string)
+	p.in, _ = def["in"].(string)
+	p.required, _ = def["required"].(bool)
+
+	schema, _ := def["schema"].(map[string]any)
+	p.kind, _ = schema["type"].(string)
+	if items, ok := schema["items"].(map[string]any); ok {
+		p.itemKind, _ = items["type"].(string)
+	}
+	// Form-style query parameters explode by default
+	p.explode = true
+	if explode, ok := def["explode"].(bool); ok {
+		p.explode = explode
+	}
+
+	var err error
+	if p.schema, err = l.compile(schema); err != nil {
+		return nil, fmt.Errorf("compiling parameter %s schema in %s: %w", p.name, filepath.Base(file), err)
+	}
+	return p, nil
+}
+
+// addComponentSchemas registers every schema under the components/schemas
+// directory by name, the way $refs to them are resolved
+func (l *specLoader) addComponentSchemas(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return fmt.Errorf("listing schemas: %w", err)
+	}
+	for _, path := range files {
+		if filepath.Base(path) == "_index.yaml" {
+			continue
+		}
+		schemas, err := l.file(path)
+		if err != nil {
+			return err
+		}
+		for name, schema := range schemas {
+			schemaMap, ok := schema.(map[string]any)
+			if !ok {
+				continue
+			}
+			if err := l.addResource("synapse://schemas/"+name, schemaMap); err != nil {
+				return fmt.Errorf("adding schema %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// compile compiles an inline schema. A nil schema accepts any value.
+func (l *specLoader) compile(schema map[string]any) (*jsonschema.Schema, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	l.inline++
+	id := fmt.Sprintf("synapse://inline/%d", l.inline)
+	if err := l.addResource(id, schema); err != nil {
+		return nil, err
+	}
+	return l.compiler.Compile(id)
+}
+
+func (l *specLoader) addResource(id string, schema map[string]any) error {
+	data, err := json.Marshal(toJSONSchema(schema))
+	if err != nil {
+		return err
+	}
+	return l.compiler.AddResource(id, bytes.NewReader(data))
+}
+
+// toJSONSchema rewrites $refs to the component schema IDs, which are keyed by
+// schema name (the last segment of the reference)
+func toJSONSchema(schema map[string]any) map[string]any {
+	result := make(map[string]any, len(schema))
+	for k, v := range schema {
+		switch val := v.(type) {
+		case map[string]any:
+			result[k] = toJSONSchema(val)
+		case []any:
+			items := make([]any, len(val))
+			for i, item := range val {
+				if m, ok := item.(map[string]any); ok {
+					items[i] = toJSONSchema(m)
+				} else {
+					items[i] = item
+				}
+			}
+			result[k] = items
+		case string:
+			if k == "$ref" {
+				parts := strings.Split(val, "/")
+				result[k] = "synapse://schemas/" + parts[len(parts)-1]
+			} else {
+				result[k] = val
+			}
+		default:
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// deref follows a {$ref: "file#/key"} object relative to file, returning the
+// target and the file it lives in. Non-reference objects are returned as is.
+func (l *specLoader) deref(node any, file string) (map[string]any, string, error) {
+	m, ok := node.(map[string]any)
+	if !ok {
+		return nil, "", fmt.Errorf("expected an object in %s", filepath.Base(file))
+	}
+	ref, ok := m["$ref"].(string)
+	if !ok {
+		return m, file, nil
+	}
+
+	target, pointer, _ := strings.Cut(ref, "#")
+	if target != "" {
+		file = filepath.Join(filepath.Dir(file), target)
+	}
+	doc, err := l.file(file)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var cur any = doc
+	for _, key := range strings.Split(strings.Trim(pointer, "/"), "/") {
+		if key == "" {
+			continue
+		}
+		key = strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~")
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, "", fmt.Errorf("unresolvable reference %s", ref)
+		}
+		cur = obj[key]
+	}
+	return l.deref(cur, file)
+}
+
+func (l *specLoader) file(path string) (map[string]any, error) {
+	if doc, ok := l.files[path]; ok {
+		return doc, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	l.files[path] = doc
+	return doc, nil
+}
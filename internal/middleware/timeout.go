@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/synapse/synapse/internal/problem"
+)
+
+// Timeout cancels the context of requests still running after their route's
+// timeout, so that the queries and calls made with it are abandoned. Requests
+// that time out before writing a response are answered with 504 problem
+// details; handlers that return a server error once the context is done are
+// too, by problem.Write. Routes take their timeout from routes, by route
+// pattern, or else defaultTimeout; a timeout of 0 or less disables it, e.g.
+// for event streams. It must be added to a chi route group (With or Group),
+// where the route pattern is known when it runs.
+func Timeout(defaultTimeout time.Duration, routes map[string]time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := defaultTimeout
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if d, ok := routes[rctx.RoutePattern()]; ok {
+					timeout = d
+				}
+			}
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+			tw := &timeoutWriter{ResponseWriter: w}
+			next.ServeHTTP(tw, r)
+
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				logger.WarnContext(ctx, "request timed out", "method", r.Method, "path", r.URL.Path,
+					"timeout", timeout, "responded", tw.wroteHeader)
+				if !tw.wroteHeader {
+					problem.Write(w, r, problem.GatewayTimeout())
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter records whether a response was started
+type timeoutWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	// Informational responses, e.g. 103 Early Hints, don't start one
+	if code >= http.StatusOK {
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
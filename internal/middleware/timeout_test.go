@@ -0,0 +1,67 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/middleware"
+)
+
+func TestTimeout(t *testing.T) {
+	// waitForCancel blocks until the request is cancelled, as a query would
+	waitForCancel := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+	deadline := func(w http.ResponseWriter, r *http.Request) {
+		_, ok := r.Context().Deadline()
+		assert.False(t, ok)
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(10*time.Millisecond, map[string]time.Duration{
+			"/slow":   2 * time.Second,
+			"/stream": 0,
+		}))
+		r.Get("/fast", waitForCancel)
+		r.Get("/slow", waitForCancel)
+		r.Get("/stream", deadline)
+		r.Get("/responded", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			<-r.Context().Done()
+		})
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := serve("/fast")
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	var body problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "https://synapse.example.com/problems/gateway-timeout", body.Type)
+
+	// Route timeouts override the default one, and 0 disables it
+	assert.Equal(t, http.StatusNoContent, serve("/slow").Code)
+	assert.Equal(t, http.StatusNoContent, serve("/stream").Code)
+
+	// A started response is left as it is
+	rec = serve("/responded")
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
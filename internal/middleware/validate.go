@@ -0,0 +1,373 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/problem"
+)
+
+// RequestValidator checks requests against the OpenAPI spec before they reach
+// the handlers, rejecting invalid ones with 400 problem+json that lists every
+// invalid field. Requests for paths or methods not in the spec pass through.
+type RequestValidator struct {
+	operations []*operation
+}
+
+// NewRequestValidator loads the OpenAPI spec at specPath, e.g. openapi/openapi.yaml
+func NewRequestValidator(specPath string) (*RequestValidator, error) {
+	ops, err := loadOperations(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading OpenAPI spec: %w", err)
+	}
+	return &RequestValidator{operations: ops}, nil
+}
+
+// Middleware returns the chi middleware
+func (v *RequestValidator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := v.find(r.Method, r.URL.Path)
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		errs := op.validateParams(r)
+
+		if op.body != nil {
+			bodyErrs, err := op.validateBody(r)
+			if err != nil {
+				problem.Write(w, r, err)
+				return
+			}
+			errs = append(errs, bodyErrs...)
+		}
+
+		if len(errs) > 0 {
+			problem.Write(w, r, problem.Validation("The request does not match the API specification", errs...))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (v *RequestValidator) find(method, path string) *operation {
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	// Prefer literal segments over templates, e.g. /dlq/retry over /dlq/{eventId}
+	var best *operation
+	bestLiterals := -1
+	for _, op := range v.operations {
+		if op.method != method || !op.match(path) {
+			continue
+		}
+		literals := 0
+		for _, s := range op.segments {
+			if !isTemplate(s) {
+				literals++
+			}
+		}
+		if literals > bestLiterals {
+			best, bestLiterals = op, literals
+		}
+	}
+	return best
+}
+
+func (op *operation) validateParams(r *http.Request) []generated.ValidationError {
+	var (
+		errs       []generated.ValidationError
+		pathParams = op.pathParams(r.URL.Path)
+		query      = r.URL.Query()
+	)
+	for _, p := range op.params {
+		var values []string
+		switch p.in {
+		case "path":
+			if v, ok := pathParams[p.name]; ok {
+				values = []string{v}
+			}
+		case "query":
+			values = query[p.name]
+		case "header":
+			values = r.Header.Values(p.name)
+		}
+
+		if len(values) == 0 || (len(values) == 1 && values[0] == "" && p.in != "query") {
+			if p.required {
+				errs = append(errs, generated.ValidationError{
+					Field:   p.name,
+					Code:    "required",
+					Message: fmt.Sprintf("%s %s parameter is required", p.name, p.in),
+				})
+			}
+			continue
+		}
+
+		value, err := p.coerce(values)
+		if err != nil {
+			errs = append(errs, generated.ValidationError{
+				Field:         p.name,
+				Code:          "invalid_type",
+				Message:       err.Error(),
+				RejectedValue: strings.Join(values, ","),
+			})
+			continue
+		}
+		if p.schema == nil {
+			continue
+		}
+		if err := p.schema.Validate(value); err != nil {
+			errs = append(errs, schemaErrors(err, p.name, value)...)
+		}
+	}
+	return errs
+}
+
+// coerce converts raw parameter values to the schema's type
+func (p *parameter) coerce(values []string) (any, error) {
+	if p.kind != "array" {
+		return coerceScalar(p.name, p.kind, values[0])
+	}
+
+	if !p.explode {
+		values = strings.Split(values[0], ",")
+	}
+	items := make([]any, len(values))
+	for i, raw := range values {
+		item, err := coerceScalar(p.name, p.itemKind, raw)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func coerceScalar(name, kind, raw string) (any, error) {
+	switch kind {
+	case "integer":
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer", name)
+		}
+		return v, nil
+	case "number":
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", name)
+		}
+		return v, nil
+	case "boolean":
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a boolean", name)
+		}
+		return v, nil
+	default:
+		return raw, nil
+	}
+}
+
+// validateBody checks the request body's media type and content. It returns
+// a problem instead of field errors when the body can't be checked at all.
+func (op *operation) validateBody(r *http.Request) ([]generated.ValidationError, *problem.Error) {
+	// Non-JSON bodies (e.g. NDJSON or CSV imports) are streamed by the
+	// handler, which validates them as it reads; buffering them here would
+	// defeat that
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && !isJSON(mediaType) {
+		if _, ok := op.body.schemas[mediaType]; ok {
+			return nil, nil
+		}
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+		return nil, problem.PayloadTooLarge(tooLarge.Limit)
+	}
+	if err != nil {
+		return nil, problem.Validation(fmt.Sprintf("Reading request body: %v", err))
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if op.body.required {
+			return []generated.ValidationError{{
+				Field:   "body",
+				Code:    "required",
+				Message: "request body is required",
+			}}, nil
+		}
+		return nil, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	schema, ok := op.body.schemas[mediaType]
+	if err != nil || !ok {
+		supported := make([]string, 0, len(op.body.schemas))
+		for t := range op.body.schemas {
+			supported = append(supported, t)
+		}
+		sort.Strings(supported)
+		return nil, problem.UnsupportedMediaType(fmt.Sprintf("Content-Type must be one of: %s", strings.Join(supported, ", ")))
+	}
+
+	var body any
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, problem.InvalidJSON(err)
+	}
+	if schema == nil {
+		return nil, nil
+	}
+	if err := schema.Validate(body); err != nil {
+		return schemaErrors(err, "", body), nil
+	}
+	return nil, nil
+}
+
+// isJSON reports whether mediaType is JSON, e.g. application/json or
+// application/merge-patch+json
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+var quotedName = regexp.MustCompile(`'([^']*)'`)
+
+// schemaErrors flattens a schema validation error into one entry per
+// invalid field. Field names use dot notation and brackets for arrays,
+// prefixed with root for parameters.
+func schemaErrors(err error, root string, instance any) []generated.ValidationError {
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return []generated.ValidationError{{Field: root, Code: "invalid", Message: err.Error()}}
+	}
+
+	var errs []generated.ValidationError
+	var walk func(*jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) > 0 {
+			for _, c := range e.Causes {
+				walk(c)
+			}
+			return
+		}
+
+		keyword := e.KeywordLocation[strings.LastIndex(e.KeywordLocation, "/")+1:]
+		field := fieldName(root, e.InstanceLocation)
+
+		if keyword == "required" {
+			for _, m := range quotedName.FindAllStringSubmatch(e.Message, -1) {
+				errs = append(errs, generated.ValidationError{
+					Field:   joinField(field, m[1]),
+					Code:    "required",
+					Message: m[1] + " is required",
+				})
+			}
+			return
+		}
+
+		errs = append(errs, generated.ValidationError{
+			Field:         field,
+			Code:          errorCode(keyword),
+			Message:       e.Message,
+			RejectedValue: lookup(instance, e.InstanceLocation),
+		})
+	}
+	walk(ve)
+
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// errorCode maps JSON Schema keywords to the ValidationError codes
+func errorCode(keyword string) string {
+	switch keyword {
+	case "minimum", "exclusiveMinimum":
+		return "min_value"
+	case "maximum", "exclusiveMaximum":
+		return "max_value"
+	case "minLength":
+		return "min_length"
+	case "maxLength":
+		return "max_length"
+	case "minItems":
+		return "min_items"
+	case "maxItems":
+		return "max_items"
+	case "maxProperties":
+		return "max_properties"
+	case "pattern", "format":
+		return "invalid_format"
+	case "type":
+		return "invalid_type"
+	case "enum", "const":
+		return "invalid_value"
+	case "additionalProperties":
+		return "unknown_field"
+	default:
+		return keyword
+	}
+}
+
+// fieldName converts a JSON pointer like /items/0/quantity to items[0].quantity
+func fieldName(root, pointer string) string {
+	field := root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(token); err == nil {
+			field += "[" + token + "]"
+			continue
+		}
+		field = joinField(field, strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~"))
+	}
+	return field
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// lookup returns the value at a JSON pointer within instance
+func lookup(instance any, pointer string) any {
+	cur := instance
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		switch v := cur.(type) {
+		case map[string]any:
+			cur = v[strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")]
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			cur = v[i]
+		default:
+			return nil
+		}
+	}
+	// Only echo scalars back to the client
+	switch cur.(type) {
+	case map[string]any, []any:
+		return nil
+	}
+	return cur
+}
@@ -0,0 +1,161 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/middleware"
+)
+
+const openAPISpecPath = "../../openapi/openapi.yaml"
+
+type problem struct {
+	Type   string `json:"type"`
+	Status int    `json:"status"`
+	Errors []struct {
+		Field         string `json:"field"`
+		Code          string `json:"code"`
+		Message       string `json:"message"`
+		RejectedValue any    `json:"rejectedValue"`
+	} `json:"errors"`
+}
+
+func newValidatedRouter(t *testing.T) http.Handler {
+	t.Helper()
+
+	v, err := middleware.NewRequestValidator(openAPISpecPath)
+	require.NoError(t, err)
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	r := chi.NewRouter()
+	r.Use(v.Middleware)
+	r.Post("/api/v1/orders", ok)
+	r.Get("/api/v1/orders", ok)
+	r.Get("/api/v1/orders/{orderId}", ok)
+	r.Post("/api/v1/orders/import", func(w http.ResponseWriter, r *http.Request) {
+		// Streamed bodies reach the handler unread
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Body-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.Get("/not-in-spec", ok)
+	return r
+}
+
+func serve(h http.Handler, method, target, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) problem {
+	t.Helper()
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	var p problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	return p
+}
+
+const validOrder = `{
+	"customerId": "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+	"items": [{"sku": "SKU-1", "quantity": 1, "unitPrice": 10}],
+	"totalAmount": 10,
+	"currency": "USD"
+}`
+
+func TestRequestValidator_ValidRequestsPassThrough(t *testing.T) {
+	h := newValidatedRouter(t)
+
+	assert.Equal(t, http.StatusNoContent, serve(h, "POST", "/api/v1/orders", "application/json", validOrder).Code)
+	assert.Equal(t, http.StatusNoContent, serve(h, "GET", "/api/v1/orders?limit=5&status=accepted,routed", "", "").Code)
+	assert.Equal(t, http.StatusNoContent, serve(h, "GET", "/api/v1/orders/550e8400-e29b-41d4-a716-446655440000", "", "").Code)
+	assert.Equal(t, http.StatusNoContent, serve(h, "GET", "/not-in-spec?limit=abc", "", "").Code)
+}
+
+func TestRequestValidator_BodyFieldErrors(t *testing.T) {
+	h := newValidatedRouter(t)
+
+	rec := serve(h, "POST", "/api/v1/orders", "application/json", `{
+		"customerId": "not-a-uuid",
+		"items": [{"sku": "SKU-1", "quantity": 0, "unitPrice": 10}],
+		"totalAmount": 10
+	}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	p := decodeProblem(t, rec)
+	assert.Equal(t, "https://synapse.example.com/problems/validation-error", p.Type)
+
+	codes := make(map[string]string)
+	for _, e := range p.Errors {
+		codes[e.Field] = e.Code
+	}
+	assert.Equal(t, map[string]string{
+		"currency":          "required",
+		"customerId":        "invalid_format",
+		"items[0].quantity": "min_value",
+	}, codes)
+}
+
+func TestRequestValidator_ParamErrors(t *testing.T) {
+	h := newValidatedRouter(t)
+
+	rec := serve(h, "GET", "/api/v1/orders?limit=500&status=accepted,bogus&createdAfter=yesterday", "", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	fields := make(map[string]string)
+	for _, e := range decodeProblem(t, rec).Errors {
+		fields[e.Field] = e.Code
+	}
+	assert.Equal(t, map[string]string{
+		"limit":        "max_value",
+		"status[1]":    "invalid_value",
+		"createdAfter": "invalid_format",
+	}, fields)
+
+	rec = serve(h, "GET", "/api/v1/orders?limit=ten", "", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "invalid_type", decodeProblem(t, rec).Errors[0].Code)
+
+	rec = serve(h, "GET", "/api/v1/orders/not-a-uuid", "", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "orderId", decodeProblem(t, rec).Errors[0].Field)
+}
+
+func TestRequestValidator_ContentType(t *testing.T) {
+	h := newValidatedRouter(t)
+
+	rec := serve(h, "POST", "/api/v1/orders", "text/plain", validOrder)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	rec = serve(h, "POST", "/api/v1/orders", "application/json", `{"customerId":`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "https://synapse.example.com/problems/invalid-json", decodeProblem(t, rec).Type)
+
+	rec = serve(h, "POST", "/api/v1/orders", "application/json", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "required", decodeProblem(t, rec).Errors[0].Code)
+}
+
+func TestRequestValidator_StreamedBodies(t *testing.T) {
+	h := newValidatedRouter(t)
+
+	ndjson := `{"customerId":"not-validated-here"}` + "\n"
+	rec := serve(h, "POST", "/api/v1/orders/import", "application/x-ndjson", ndjson)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, strconv.Itoa(len(ndjson)), rec.Header().Get("X-Body-Length"))
+
+	assert.Equal(t, http.StatusNoContent, serve(h, "POST", "/api/v1/orders/import", "text/csv; charset=utf-8", "customerId\n").Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, serve(h, "POST", "/api/v1/orders/import", "application/xml", "<orders/>").Code)
+}
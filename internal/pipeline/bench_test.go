@@ -0,0 +1,82 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+// BenchmarkPipeline_Throughput measures end-to-end throughput of the
+// validate→enrich→route stages on the in-memory pub/sub and fake
+// infrastructure, or on test containers with BENCH_CONTAINERS=1:
+//
+//	go test -run ^$ -bench Pipeline -benchmem ./internal/pipeline
+func BenchmarkPipeline_Throughput(b *testing.B) {
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+
+	configs := map[string]func(*config.Config){
+		"plain": func(*config.Config) {},
+		"gzip": func(cfg *config.Config) {
+			cfg.PipelineCompression, cfg.PipelineCompressionMinBytes = pipeline.CompressionGzip, 1
+		},
+		"zstd": func(cfg *config.Config) {
+			cfg.PipelineCompression, cfg.PipelineCompressionMinBytes = pipeline.CompressionZstd, 1
+		},
+		"encrypted": func(cfg *config.Config) {
+			cfg.PipelineEncryptionKeys = "bench:" + key
+		},
+	}
+
+	for _, name := range []string{"plain", "gzip", "zstd", "encrypted"} {
+		b.Run(name, func(b *testing.B) {
+			env := testutil.NewBenchEnv(b)
+			configs[name](env.Config)
+			env.Config.RetryMaxAttempts = 1
+			env.Config.RetryBackoffMs = 10
+			benchmarkPipeline(b, env)
+		})
+	}
+}
+
+func benchmarkPipeline(b *testing.B, env *testutil.BenchEnv) {
+	ctx := context.Background()
+	runner := env.Pipeline(b)
+
+	order := &generated.OrderCreateRequest{
+		CustomerId:  "bench-customer",
+		TotalAmount: 59.98,
+		Currency:    "USD",
+		Items: []generated.OrderItem{
+			{Sku: "SKU-001", Quantity: 1, UnitPrice: 29.99},
+			{Sku: "SKU-002", Quantity: 1, UnitPrice: 29.99},
+		},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := runner.IngestOrder(ctx, "order-"+strconv.Itoa(i), order); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Minute)
+	for runner.RoutingStats().TotalRouted < b.N {
+		if time.Now().After(deadline) {
+			b.Fatalf("routed %d of %d orders", runner.RoutingStats().TotalRouted, b.N)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "orders/s")
+}
@@ -0,0 +1,40 @@
+package pipeline
+
+import "context"
+
+// ChangeListener is told when state served by the API changes, e.g. to drop
+// cached copies of it. Calls are made synchronously from the pipeline, so
+// they must be quick and must not fail the change.
+type ChangeListener interface {
+	// OrderChanged is called once an order's projection and event history
+	// have been updated
+	OrderChanged(ctx context.Context, orderID string)
+	// StageChanged is called when a stage is started, stopped or reconfigured
+	StageChanged(ctx context.Context, stageID string)
+}
+
+// AddChangeListener registers l for order and stage changes
+func (r *Runner) AddChangeListener(l ChangeListener) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, l)
+}
+
+// changeListeners returns the registered listeners
+func (r *Runner) changeListeners() []ChangeListener {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.listeners
+}
+
+func (r *Runner) orderChanged(ctx context.Context, orderID string) {
+	for _, l := range r.changeListeners() {
+		l.OrderChanged(ctx, orderID)
+	}
+}
+
+func (r *Runner) stageChanged(ctx context.Context, stageID string) {
+	for _, l := range r.changeListeners() {
+		l.StageChanged(ctx, stageID)
+	}
+}
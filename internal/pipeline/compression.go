@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression codecs for pipeline message payloads
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// MetadataContentEncoding flags a compressed payload and names its codec
+const MetadataContentEncoding = "contentEncoding"
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+
+	// gzip writers allocate large internal state, so they are reused
+	gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+)
+
+// compressingPublisher compresses payloads at or above minBytes before publishing
+type compressingPublisher struct {
+	message.Publisher
+	codec    string
+	minBytes int
+}
+
+// CompressPublisher wraps pub so that payloads of at least minBytes are
+// compressed with codec and flagged via MetadataContentEncoding. Smaller
+// payloads, and all payloads when codec is "none" or empty, pass through.
+func CompressPublisher(pub message.Publisher, codec string, minBytes int) (message.Publisher, error) {
+	switch codec {
+	case "", CompressionNone:
+		return pub, nil
+	case CompressionGzip, CompressionZstd:
+		return &compressingPublisher{Publisher: pub, codec: codec, minBytes: minBytes}, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression codec: %s", codec)
+	}
+}
+
+// Publish compresses eligible messages and forwards them to the wrapped publisher
+func (p *compressingPublisher) Publish(topic string, msgs ...*message.Message) error {
+	for _, msg := range msgs {
+		if msg.Metadata.Get(MetadataContentEncoding) != "" || len(msg.Payload) < p.minBytes {
+			continue
+		}
+		data, err := compress(p.codec, msg.Payload)
+		if err != nil {
+			return fmt.Errorf("compressing message %s: %w", msg.UUID, err)
+		}
+		msg.Payload = data
+		msg.Metadata.Set(MetadataContentEncoding, p.codec)
+	}
+	return p.Publisher.Publish(topic, msgs...)
+}
+
+// Decompress is a router middleware that restores compressed payloads before
+// the handler runs. Messages without MetadataContentEncoding are untouched.
+func Decompress(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		if codec := msg.Metadata.Get(MetadataContentEncoding); codec != "" {
+			data, err := decompress(codec, msg.Payload)
+			if err != nil {
+				return nil, fmt.Errorf("decompressing message %s: %w", msg.UUID, err)
+			}
+			msg.Payload = data
+			delete(msg.Metadata, MetadataContentEncoding)
+		}
+		return h(msg)
+	}
+}
+
+func compress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		zw := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(zw)
+		zw.Reset(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression codec: %s", codec)
+	}
+}
+
+func decompress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case CompressionGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case CompressionZstd:
+		return zstdDecoder.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("unsupported compression codec: %s", codec)
+	}
+}
@@ -0,0 +1,74 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/pipeline"
+)
+
+type capturePublisher struct {
+	topics   []string
+	messages []*message.Message
+}
+
+func (p *capturePublisher) Publish(topic string, msgs ...*message.Message) error {
+	for range msgs {
+		p.topics = append(p.topics, topic)
+	}
+	p.messages = append(p.messages, msgs...)
+	return nil
+}
+
+func (p *capturePublisher) Close() error { return nil }
+
+func TestCompressPublisher_RoundTrip(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"sku":"WIDGET-001","quantity":2,"unitPrice":29.99},`), 200)
+
+	for _, codec := range []string{pipeline.CompressionGzip, pipeline.CompressionZstd} {
+		t.Run(codec, func(t *testing.T) {
+			capture := &capturePublisher{}
+			pub, err := pipeline.CompressPublisher(capture, codec, 1024)
+			require.NoError(t, err)
+
+			require.NoError(t, pub.Publish("orders.ingest", message.NewMessage(watermill.NewUUID(), payload)))
+			require.Len(t, capture.messages, 1)
+
+			published := capture.messages[0]
+			assert.Equal(t, codec, published.Metadata.Get(pipeline.MetadataContentEncoding))
+			assert.Less(t, len(published.Payload), len(payload), "payload should shrink")
+
+			var seen []byte
+			handler := pipeline.Decompress(func(msg *message.Message) ([]*message.Message, error) {
+				seen = msg.Payload
+				return nil, nil
+			})
+			_, err = handler(published)
+			require.NoError(t, err)
+
+			assert.Equal(t, payload, seen)
+			assert.Empty(t, published.Metadata.Get(pipeline.MetadataContentEncoding))
+		})
+	}
+}
+
+func TestCompressPublisher_SkipsSmallPayloads(t *testing.T) {
+	capture := &capturePublisher{}
+	pub, err := pipeline.CompressPublisher(capture, pipeline.CompressionGzip, 1024)
+	require.NoError(t, err)
+
+	payload := []byte(`{"orderId":"small"}`)
+	require.NoError(t, pub.Publish("orders.ingest", message.NewMessage(watermill.NewUUID(), payload)))
+
+	assert.Equal(t, payload, []byte(capture.messages[0].Payload))
+	assert.Empty(t, capture.messages[0].Metadata.Get(pipeline.MetadataContentEncoding))
+}
+
+func TestCompressPublisher_RejectsUnknownCodec(t *testing.T) {
+	_, err := pipeline.CompressPublisher(&capturePublisher{}, "lz4", 0)
+	assert.Error(t, err)
+}
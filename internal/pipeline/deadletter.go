@@ -0,0 +1,185 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// Metadata added to OrderFailed events published on TopicOrdersDLQ
+const (
+	MetadataFailedStage = "failedStage"
+	MetadataErrorType   = "errorType"
+)
+
+// ErrStageNotRunning is returned when requeuing to a stopped stage
+var ErrStageNotRunning = errors.New("stage is not running")
+
+// dlqPreviewBytes bounds the payload preview kept with a dead-lettered message
+const dlqPreviewBytes = 256
+
+// Retryable reports whether a message that failed with errorType may succeed
+// when retried. Validation failures fail again.
+func Retryable(errorType string) bool {
+	return errorType != ErrorTypeValidation
+}
+
+// deadLetter is router middleware that moves a message to the dead letter
+// queue once its stage gives up on it, rather than nacking it for endless
+// redelivery. An OrderFailed event is published on TopicOrdersDLQ and, with
+// a database, the message is stored as it was received for listing and
+// retrying. A message that can't be
+// dead-lettered is nacked; one that failed because the pipeline is stopping
+// is nacked as well.
+func (r *Runner) deadLetter(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		// Inner middleware decompresses and decrypts the payload in place
+		payload := slices.Clone(msg.Payload)
+		metadata := maps.Clone(msg.Metadata)
+
+		out, err := h(msg)
+		if err == nil || errors.Is(err, context.Canceled) {
+			return out, err
+		}
+		def, ok := r.stageForHandler(message.HandlerNameFromCtx(msg.Context()))
+		if !ok {
+			return out, err
+		}
+		if dlqErr := r.sendToDLQ(msg.Context(), def, msg.UUID, payload, metadata, err); dlqErr != nil {
+			r.logger.Error("Dead-lettering message failed", dlqErr, watermill.LogFields{
+				"message_uuid": msg.UUID,
+				"stage":        def.id,
+			})
+			return nil, err
+		}
+		r.log.WarnContext(msg.Context(), "message dead-lettered", "eventId", msg.UUID, "stage", def.id, "error", err)
+		return nil, nil
+	}
+}
+
+// Requeue publishes a dead-lettered message, as it was stored, to a stage's
+// topic so the stage processes it again. The stage must be running: nothing
+// would receive the message otherwise.
+func (r *Runner) Requeue(stageID, eventID string, payload []byte, metadata map[string]string) error {
+	def, ok := r.stageDef(stageID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrStageNotFound, stageID)
+	}
+	if !r.StageRunning(stageID) {
+		return fmt.Errorf("%w: %s", ErrStageNotRunning, stageID)
+	}
+
+	// The payload is still compressed and encrypted, so it bypasses the
+	// encoding publisher
+	msg := message.NewMessage(eventID, payload)
+	msg.Metadata = maps.Clone(metadata)
+	if msg.Metadata == nil {
+		msg.Metadata = make(message.Metadata)
+	}
+	if err := r.requeue.Publish(def.subscribeTopic, msg); err != nil {
+		return fmt.Errorf("requeuing message %s: %w", eventID, err)
+	}
+	return nil
+}
+
+// sendToDLQ records a message that failed at a stage with cause
+func (r *Runner) sendToDLQ(ctx context.Context, def stageDef, eventID string, payload []byte, metadata message.Metadata, cause error) error {
+	item := &store.DLQItem{
+		EventID:      eventID,
+		OrderID:      metadata.Get("correlationId"),
+		Stage:        def.id,
+		Topic:        def.subscribeTopic,
+		ErrorType:    errorType(def.id, cause),
+		ErrorMessage: cause.Error(),
+		Payload:      payload,
+		Metadata:     metadata,
+		Preview:      payloadPreview(payload, metadata),
+		RetryCount:   r.settingsFor(def.id).retry.MaxAttempts,
+		FailedAt:     r.clock.Now().UTC(),
+	}
+	if r.orders != nil {
+		if err := r.orders.SaveDLQItem(ctx, item); err != nil {
+			return err
+		}
+	}
+
+	failed := generated.OrderFailedPayload{
+		Error: map[string]any{
+			"code":    item.ErrorType,
+			"message": item.ErrorMessage,
+		},
+		FailedAt:     item.FailedAt,
+		FailureStage: item.Stage,
+		OrderId:      item.OrderID,
+		RetryCount:   item.RetryCount,
+	}
+	if data, err := decodePayload(payload, metadata); err == nil {
+		// Encrypted fields stay encrypted
+		_ = json.Unmarshal(data, &failed.OriginalPayload)
+	}
+	data, err := json.Marshal(failed)
+	if err != nil {
+		return fmt.Errorf("marshaling DLQ entry: %w", err)
+	}
+
+	dlqMsg := message.NewMessage(watermill.NewUUID(), data)
+	dlqMsg.Metadata.Set("correlationId", item.OrderID)
+	dlqMsg.Metadata.Set(MetadataFailedStage, item.Stage)
+	dlqMsg.Metadata.Set(MetadataErrorType, item.ErrorType)
+	if err := r.publisher.Publish(TopicOrdersDLQ, dlqMsg); err != nil {
+		return fmt.Errorf("publishing DLQ entry: %w", err)
+	}
+	return nil
+}
+
+// decodePayload returns a message's payload as published, before compression
+func decodePayload(payload []byte, metadata message.Metadata) ([]byte, error) {
+	if codec := metadata.Get(MetadataContentEncoding); codec != "" {
+		return decompress(codec, payload)
+	}
+	return payload, nil
+}
+
+// payloadPreview renders the start of a payload for people looking through
+// the DLQ. Compressed payloads are decompressed; encrypted fields stay hidden.
+func payloadPreview(payload []byte, metadata message.Metadata) string {
+	data, err := decodePayload(payload, metadata)
+	if err != nil {
+		return ""
+	}
+	if fieldList := metadata.Get(MetadataEncryptedFields); fieldList != "" {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err == nil {
+			for _, field := range strings.Split(fieldList, ",") {
+				if _, ok := fields[field]; ok {
+					fields[field] = json.RawMessage(`"[encrypted]"`)
+				}
+			}
+			data, _ = json.Marshal(fields)
+		}
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err == nil {
+		data = compact.Bytes()
+	}
+
+	if len(data) <= dlqPreviewBytes {
+		return strings.ToValidUTF8(string(data), "�")
+	}
+	cut := dlqPreviewBytes
+	for cut > 0 && !utf8.RuneStart(data[cut]) {
+		cut--
+	}
+	return strings.ToValidUTF8(string(data[:cut]), "�") + "…"
+}
@@ -0,0 +1,86 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+)
+
+func TestRunner_DeadLetter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runner, err := pipeline.New(ctx, &config.Config{RetryMaxAttempts: 2, RetryBackoffMs: 10}, nil)
+	require.NoError(t, err)
+
+	var attempts atomic.Int32
+	require.NoError(t, runner.UseStage("enrich", func(h message.HandlerFunc) message.HandlerFunc {
+		return func(msg *message.Message) ([]*message.Message, error) {
+			attempts.Add(1)
+			return nil, errors.New("customer service unavailable")
+		}
+	}))
+
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	err = runner.IngestOrder(ctx, "order-1", &generated.OrderCreateRequest{
+		CustomerId:  "cust-1",
+		TotalAmount: 10,
+		Currency:    "USD",
+		Items:       []generated.OrderItem{{Sku: "SKU-1", Quantity: 1, UnitPrice: 10}},
+	})
+	require.NoError(t, err)
+
+	// The first attempt and two retries, after which the message is
+	// dead-lettered instead of redelivered
+	require.Eventually(t, func() bool { return attempts.Load() == 3 }, 5*time.Second, 10*time.Millisecond)
+	assert.Never(t, func() bool { return attempts.Load() > 3 }, 300*time.Millisecond, 10*time.Millisecond)
+	assert.Zero(t, runner.RoutingStats().TotalRouted)
+}
+
+func TestRunner_Requeue(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runner, err := pipeline.New(ctx, &config.Config{RetryMaxAttempts: 1, RetryBackoffMs: 10}, nil)
+	require.NoError(t, err)
+
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	payload, err := json.Marshal(map[string]any{
+		"orderId":     "order-1",
+		"customerId":  "cust-1",
+		"items":       []generated.OrderItem{{Sku: "SKU-1", Quantity: 1, UnitPrice: 10}},
+		"totalAmount": 10,
+		"currency":    "USD",
+		"createdAt":   time.Now().UTC(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, runner.Requeue("validate", "event-1", payload, map[string]string{"correlationId": "order-1"}))
+	require.Eventually(t, func() bool { return runner.RoutingStats().TotalRouted == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, runner.StopStage(ctx, "enrich"))
+	assert.ErrorIs(t, runner.Requeue("enrich", "event-2", payload, nil), pipeline.ErrStageNotRunning)
+	assert.ErrorIs(t, runner.Requeue("unknown", "event-3", payload, nil), pipeline.ErrStageNotFound)
+}
+
+func TestRetryable(t *testing.T) {
+	assert.False(t, pipeline.Retryable(pipeline.ErrorTypeValidation))
+	assert.True(t, pipeline.Retryable(pipeline.ErrorTypeTimeout))
+	assert.True(t, pipeline.Retryable(pipeline.ErrorTypeExternalService))
+}
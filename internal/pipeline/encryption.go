@@ -0,0 +1,233 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/synapse/synapse/internal/crypto"
+)
+
+// Metadata keys carrying the envelope for encrypted payload fields
+const (
+	MetadataEncryptionKeyID = "encryptionKeyId"
+	MetadataEncryptedKey    = "encryptedKey"
+	MetadataEncryptedFields = "encryptedFields"
+)
+
+// dataKeyID names the data key in the fields it seals
+const dataKeyID = "data"
+
+// DefaultEncryptedFields are the customer-identifying fields encrypted when
+// no explicit field list is configured
+var DefaultEncryptedFields = []string{"customerId", "shippingAddress", "billingAddress"}
+
+// KeyProvider wraps and unwraps per-message data keys. Implementations
+// typically delegate to a KMS; StaticKeyProvider covers keys from config.
+type KeyProvider interface {
+	// WrapKey encrypts a data key with the active key encryption key
+	WrapKey(ctx context.Context, dataKey []byte) (wrapped []byte, keyID string, err error)
+	// UnwrapKey decrypts a data key previously wrapped under keyID
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// StaticKeyProvider wraps data keys with the keys of a crypto.Keyring held
+// in memory
+type StaticKeyProvider struct {
+	keys *crypto.Keyring
+}
+
+// NewStaticKeyProvider parses a key spec of the form "id:base64key,id2:base64key".
+// activeID selects the key used for wrapping; when empty, the first key is used.
+// All listed keys remain available for unwrapping, which allows rotation.
+func NewStaticKeyProvider(spec, activeID string) (*StaticKeyProvider, error) {
+	keys, err := crypto.ParseKeyring(spec, activeID)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticKeyProvider{keys: keys}, nil
+}
+
+// wrappedKeyAAD binds wrapped data keys to their use
+var wrappedKeyAAD = []byte("pipeline data key")
+
+// WrapKey encrypts dataKey with the active key
+func (p *StaticKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	wrapped, err := p.keys.Seal(dataKey, wrappedKeyAAD)
+	if err != nil {
+		return nil, "", err
+	}
+	return []byte(wrapped), p.keys.ActiveKeyID(), nil
+}
+
+// UnwrapKey decrypts a data key wrapped under keyID. The wrapped key names
+// the key it was wrapped under too, and that's the one used.
+func (p *StaticKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	return openSealed(p.keys, string(wrapped), wrappedKeyAAD)
+}
+
+// FieldEncryptor applies envelope encryption to selected top-level payload fields
+type FieldEncryptor struct {
+	keys   KeyProvider
+	fields []string
+}
+
+// NewFieldEncryptor creates an encryptor for the given fields. When fields
+// is empty, DefaultEncryptedFields is used.
+func NewFieldEncryptor(keys KeyProvider, fields []string) *FieldEncryptor {
+	if len(fields) == 0 {
+		fields = DefaultEncryptedFields
+	}
+	return &FieldEncryptor{keys: keys, fields: fields}
+}
+
+// Publisher wraps pub so that outgoing payloads have their fields encrypted
+func (e *FieldEncryptor) Publisher(pub message.Publisher) message.Publisher {
+	return &encryptingPublisher{Publisher: pub, encryptor: e}
+}
+
+// Decrypt is a router middleware that restores encrypted fields before the handler runs
+func (e *FieldEncryptor) Decrypt(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		if err := e.decryptMessage(msg); err != nil {
+			return nil, fmt.Errorf("decrypting message %s: %w", msg.UUID, err)
+		}
+		return h(msg)
+	}
+}
+
+type encryptingPublisher struct {
+	message.Publisher
+	encryptor *FieldEncryptor
+}
+
+// Publish encrypts each message's configured fields and forwards it
+func (p *encryptingPublisher) Publish(topic string, msgs ...*message.Message) error {
+	for _, msg := range msgs {
+		if err := p.encryptor.encryptMessage(msg); err != nil {
+			return fmt.Errorf("encrypting message %s: %w", msg.UUID, err)
+		}
+	}
+	return p.Publisher.Publish(topic, msgs...)
+}
+
+func (e *FieldEncryptor) encryptMessage(msg *message.Message) error {
+	if msg.Metadata.Get(MetadataEncryptedFields) != "" {
+		return nil
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return fmt.Errorf("unmarshaling payload: %w", err)
+	}
+
+	present := make([]string, 0, len(e.fields))
+	for _, field := range e.fields {
+		if _, ok := payload[field]; ok {
+			present = append(present, field)
+		}
+	}
+	if len(present) == 0 {
+		return nil
+	}
+
+	dataKey := make([]byte, crypto.KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return fmt.Errorf("generating data key: %w", err)
+	}
+	fieldKeys, err := crypto.NewKeyring(dataKeyID, dataKey)
+	if err != nil {
+		return fmt.Errorf("generating data key: %w", err)
+	}
+
+	for _, field := range present {
+		sealed, err := fieldKeys.Seal(payload[field], []byte(field))
+		if err != nil {
+			return fmt.Errorf("encrypting %s: %w", field, err)
+		}
+		encoded, _ := json.Marshal(sealed)
+		payload[field] = encoded
+	}
+
+	wrapped, keyID, err := e.keys.WrapKey(msg.Context(), dataKey)
+	if err != nil {
+		return fmt.Errorf("wrapping data key: %w", err)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshaling payload: %w", err)
+	}
+
+	msg.Payload = data
+	msg.Metadata.Set(MetadataEncryptionKeyID, keyID)
+	msg.Metadata.Set(MetadataEncryptedKey, base64.StdEncoding.EncodeToString(wrapped))
+	msg.Metadata.Set(MetadataEncryptedFields, strings.Join(present, ","))
+	return nil
+}
+
+func (e *FieldEncryptor) decryptMessage(msg *message.Message) error {
+	fieldList := msg.Metadata.Get(MetadataEncryptedFields)
+	if fieldList == "" {
+		return nil
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(msg.Metadata.Get(MetadataEncryptedKey))
+	if err != nil {
+		return fmt.Errorf("decoding data key: %w", err)
+	}
+	dataKey, err := e.keys.UnwrapKey(msg.Context(), msg.Metadata.Get(MetadataEncryptionKeyID), wrapped)
+	if err != nil {
+		return fmt.Errorf("unwrapping data key: %w", err)
+	}
+	fieldKeys, err := crypto.NewKeyring(dataKeyID, dataKey)
+	if err != nil {
+		return fmt.Errorf("unwrapping data key: %w", err)
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return fmt.Errorf("unmarshaling payload: %w", err)
+	}
+
+	for _, field := range strings.Split(fieldList, ",") {
+		raw, ok := payload[field]
+		if !ok {
+			continue
+		}
+		var sealed string
+		if err := json.Unmarshal(raw, &sealed); err != nil {
+			return fmt.Errorf("reading %s: %w", field, err)
+		}
+		plain, err := openSealed(fieldKeys, sealed, []byte(field))
+		if err != nil {
+			return fmt.Errorf("decrypting %s: %w", field, err)
+		}
+		payload[field] = plain
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshaling payload: %w", err)
+	}
+
+	msg.Payload = data
+	delete(msg.Metadata, MetadataEncryptionKeyID)
+	delete(msg.Metadata, MetadataEncryptedKey)
+	delete(msg.Metadata, MetadataEncryptedFields)
+	return nil
+}
+
+// openSealed opens a value keys sealed. Unlike Keyring.Open, it rejects
+// values that aren't sealed: everything it's given was.
+func openSealed(keys *crypto.Keyring, sealed string, aad []byte) ([]byte, error) {
+	if !crypto.IsSealed(sealed) {
+		return nil, errors.New("value isn't sealed")
+	}
+	return keys.Open(sealed, aad)
+}
@@ -0,0 +1,81 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/crypto"
+	"github.com/synapse/synapse/internal/pipeline"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestFieldEncryptor_RoundTrip(t *testing.T) {
+	keys, err := pipeline.NewStaticKeyProvider("k1:"+testKey('a'), "")
+	require.NoError(t, err)
+	encryptor := pipeline.NewFieldEncryptor(keys, nil)
+
+	capture := &capturePublisher{}
+	payload := []byte(`{"orderId":"order-1","customerId":"cust-1","shippingAddress":{"city":"Berlin"},"totalAmount":10}`)
+	require.NoError(t, encryptor.Publisher(capture).Publish("orders.ingest", message.NewMessage(watermill.NewUUID(), payload)))
+
+	published := capture.messages[0]
+	assert.NotContains(t, string(published.Payload), "cust-1")
+	assert.NotContains(t, string(published.Payload), "Berlin")
+	assert.Contains(t, string(published.Payload), "order-1", "non-PII fields stay readable")
+	assert.Equal(t, "k1", published.Metadata.Get(pipeline.MetadataEncryptionKeyID))
+	assert.Equal(t, "customerId,shippingAddress", published.Metadata.Get(pipeline.MetadataEncryptedFields))
+
+	var order map[string]any
+	handler := encryptor.Decrypt(func(msg *message.Message) ([]*message.Message, error) {
+		return nil, json.Unmarshal(msg.Payload, &order)
+	})
+	_, err = handler(published)
+	require.NoError(t, err)
+
+	assert.Equal(t, "cust-1", order["customerId"])
+	assert.Equal(t, map[string]any{"city": "Berlin"}, order["shippingAddress"])
+	assert.Empty(t, published.Metadata.Get(pipeline.MetadataEncryptedFields))
+}
+
+func TestStaticKeyProvider_Rotation(t *testing.T) {
+	ctx := context.Background()
+
+	old, err := pipeline.NewStaticKeyProvider("k1:"+testKey('a'), "")
+	require.NoError(t, err)
+	wrapped, keyID, err := old.WrapKey(ctx, []byte("data-key"))
+	require.NoError(t, err)
+
+	rotated, err := pipeline.NewStaticKeyProvider("k1:"+testKey('a')+",k2:"+testKey('b'), "k2")
+	require.NoError(t, err)
+
+	dataKey, err := rotated.UnwrapKey(ctx, keyID, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data-key"), dataKey)
+
+	rewrapped, newKeyID, err := rotated.WrapKey(ctx, []byte("data-key"))
+	require.NoError(t, err)
+	assert.Equal(t, "k2", newKeyID)
+
+	_, err = old.UnwrapKey(ctx, newKeyID, rewrapped)
+	assert.ErrorIs(t, err, crypto.ErrUnknownKey)
+	_, err = rotated.UnwrapKey(ctx, keyID, []byte("data-key"))
+	assert.Error(t, err, "unsealed keys are rejected")
+}
+
+func TestStaticKeyProvider_RejectsInvalidKeys(t *testing.T) {
+	_, err := pipeline.NewStaticKeyProvider("k1:"+base64.StdEncoding.EncodeToString([]byte("short")), "")
+	assert.Error(t, err)
+
+	_, err = pipeline.NewStaticKeyProvider("k1:"+testKey('a'), "missing")
+	assert.Error(t, err)
+}
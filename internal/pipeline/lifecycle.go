@@ -0,0 +1,179 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/synapse/synapse/internal/generated"
+)
+
+// Stage lifecycle errors
+var (
+	ErrStageNotFound    = errors.New("stage not found")
+	ErrStageRunning     = errors.New("stage is already running")
+	ErrStageStopped     = errors.New("stage is already stopped")
+	ErrLastRunningStage = errors.New("cannot stop the last running stage")
+	ErrRouterNotRunning = errors.New("pipeline is not running")
+)
+
+// stageDef describes how a stage is wired into the router so it can be re-added.
+// Terminal stages leave publishTopic empty.
+type stageDef struct {
+	id             string
+	handlerName    string
+	subscribeTopic string
+	publishTopic   string
+	handler        message.HandlerFunc
+}
+
+func (r *Runner) stageDef(stageID string) (stageDef, bool) {
+	for _, def := range r.stageDefs {
+		if def.id == stageID {
+			return def, true
+		}
+	}
+	return stageDef{}, false
+}
+
+// stageForHandler returns the stage whose router handler is named name
+func (r *Runner) stageForHandler(name string) (stageDef, bool) {
+	for _, def := range r.stageDefs {
+		if def.handlerName == name {
+			return def, true
+		}
+	}
+	return stageDef{}, false
+}
+
+// addStageHandler registers the stage's handler with the router. Callers
+// must hold r.mu unless the Runner is still being constructed.
+func (r *Runner) addStageHandler(def stageDef) {
+	handler := r.stageHandler(def)
+	if def.publishTopic == "" {
+		r.handlers[def.id] = r.router.AddConsumerHandler(
+			def.handlerName,
+			def.subscribeTopic,
+			r.subscriber,
+			func(msg *message.Message) error {
+				_, err := handler(msg)
+				return err
+			},
+		)
+		return
+	}
+	r.handlers[def.id] = r.router.AddHandler(
+		def.handlerName,
+		def.subscribeTopic,
+		r.subscriber,
+		def.publishTopic,
+		stagePublisher{r.publisher},
+		handler,
+	)
+}
+
+// stagePublisher keeps the router from closing the shared publisher when a
+// single stage stops; the Runner closes the pub/sub itself in Close
+type stagePublisher struct {
+	message.Publisher
+}
+
+// Close is a no-op
+func (stagePublisher) Close() error {
+	return nil
+}
+
+// StartStage starts a stopped stage. If the pipeline is already running,
+// the stage begins consuming immediately; otherwise it starts with Run.
+func (r *Runner) StartStage(ctx context.Context, stageID string) (err error) {
+	r.lifecycleMu.Lock()
+	defer r.lifecycleMu.Unlock()
+	defer func() {
+		if err == nil {
+			r.stageChanged(ctx, stageID)
+		}
+	}()
+
+	def, ok := r.stageDef(stageID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrStageNotFound, stageID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, running := r.handlers[stageID]; running {
+		return fmt.Errorf("%w: %s", ErrStageRunning, stageID)
+	}
+
+	r.addStageHandler(def)
+	if r.router.IsRunning() {
+		if err := r.router.RunHandlers(r.runCtx); err != nil {
+			delete(r.handlers, stageID)
+			return fmt.Errorf("running stage %s: %w", stageID, err)
+		}
+	}
+
+	r.stages[stageID].Status = generated.StageStatusHealthy
+	return nil
+}
+
+// StopStage stops a running stage and waits for in-flight messages to finish
+// or ctx to expire. The stage is reported as paused until it is started again.
+func (r *Runner) StopStage(ctx context.Context, stageID string) error {
+	r.lifecycleMu.Lock()
+	defer r.lifecycleMu.Unlock()
+
+	if _, ok := r.stageDef(stageID); !ok {
+		return fmt.Errorf("%w: %s", ErrStageNotFound, stageID)
+	}
+
+	r.mu.RLock()
+	h, running := r.handlers[stageID]
+	remaining := len(r.handlers)
+	r.mu.RUnlock()
+
+	if !running {
+		return fmt.Errorf("%w: %s", ErrStageStopped, stageID)
+	}
+	// The router closes itself once every handler has stopped
+	if remaining == 1 {
+		return ErrLastRunningStage
+	}
+	if !r.router.IsRunning() {
+		return ErrRouterNotRunning
+	}
+
+	// r.mu is not held while waiting: in-flight messages record metrics under it
+	h.Stop()
+	select {
+	case <-h.Stopped():
+	case <-ctx.Done():
+		return fmt.Errorf("stopping stage %s: %w", stageID, ctx.Err())
+	}
+
+	r.mu.Lock()
+	delete(r.handlers, stageID)
+	r.stages[stageID].Status = generated.StageStatusPaused
+	r.mu.Unlock()
+	r.stageChanged(ctx, stageID)
+	return nil
+}
+
+// RestartStage stops and starts a stage, e.g. to pick up new configuration
+func (r *Runner) RestartStage(ctx context.Context, stageID string) error {
+	if err := r.StopStage(ctx, stageID); err != nil && !errors.Is(err, ErrStageStopped) {
+		return err
+	}
+	return r.StartStage(ctx, stageID)
+}
+
+// StageRunning reports whether the stage's handler is registered with the router
+func (r *Runner) StageRunning(stageID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.handlers[stageID]
+	return ok
+}
@@ -0,0 +1,159 @@
+package pipeline_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+)
+
+func TestRunner_StageLifecycle(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runner, err := pipeline.New(ctx, &config.Config{RetryMaxAttempts: 1, RetryBackoffMs: 10}, nil)
+	require.NoError(t, err)
+
+	go func() {
+		if err := runner.Run(ctx); err != nil && ctx.Err() == nil {
+			t.Logf("pipeline error: %v", err)
+		}
+	}()
+	defer runner.Close()
+	<-runner.Running()
+
+	require.NoError(t, runner.StopStage(ctx, "enrich"))
+	assert.False(t, runner.StageRunning("enrich"))
+	assert.Equal(t, generated.StageStatusPaused, runner.GetStage("enrich").Status)
+	assert.ErrorIs(t, runner.StopStage(ctx, "enrich"), pipeline.ErrStageStopped)
+
+	require.NoError(t, runner.StartStage(ctx, "enrich"))
+	assert.True(t, runner.StageRunning("enrich"))
+	assert.Equal(t, generated.StageStatusHealthy, runner.GetStage("enrich").Status)
+	assert.ErrorIs(t, runner.StartStage(ctx, "enrich"), pipeline.ErrStageRunning)
+
+	require.NoError(t, runner.RestartStage(ctx, "route"))
+	assert.True(t, runner.StageRunning("route"))
+
+	assert.ErrorIs(t, runner.RestartStage(ctx, "unknown"), pipeline.ErrStageNotFound)
+}
+
+func TestRunner_StopStageKeepsLastStageRunning(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runner, err := pipeline.New(ctx, &config.Config{RetryMaxAttempts: 1, RetryBackoffMs: 10}, nil)
+	require.NoError(t, err)
+
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	require.NoError(t, runner.StopStage(ctx, "validate"))
+	require.NoError(t, runner.StopStage(ctx, "enrich"))
+	assert.ErrorIs(t, runner.StopStage(ctx, "route"), pipeline.ErrLastRunningStage)
+}
+
+func TestRunner_UpdateStage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runner, err := pipeline.New(ctx, &config.Config{PipelineConcurrency: 10, RetryMaxAttempts: 1, RetryBackoffMs: 10}, nil)
+	require.NoError(t, err)
+
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	// Stages start with the configured defaults
+	stage := runner.GetStage("enrich")
+	assert.Equal(t, generated.StageConfig{
+		Concurrency: 10,
+		RetryPolicy: &generated.RetryPolicy{MaxAttempts: 1, BackoffMs: 10},
+	}, stage.Config)
+	assert.True(t, stage.UpdatedAt.IsZero())
+
+	paused, concurrency, timeout := true, 4, 90*time.Second
+	require.NoError(t, runner.UpdateStage(ctx, "enrich", pipeline.StageUpdate{
+		Paused:      &paused,
+		Concurrency: &concurrency,
+		RetryPolicy: &generated.RetryPolicy{MaxAttempts: 5, BackoffMs: 100, BackoffMultiplier: 2},
+		Timeout:     &timeout,
+	}))
+	assert.False(t, runner.StageRunning("enrich"))
+	stage = runner.GetStage("enrich")
+	assert.Equal(t, generated.StageStatusPaused, stage.Status)
+	assert.Equal(t, generated.StageConfig{
+		Concurrency: 4,
+		RetryPolicy: &generated.RetryPolicy{MaxAttempts: 5, BackoffMs: 100, BackoffMultiplier: 2},
+		Timeout:     "90s",
+	}, stage.Config)
+	assert.False(t, stage.UpdatedAt.IsZero())
+
+	// Pausing again is not an error; fields left out are kept
+	require.NoError(t, runner.UpdateStage(ctx, "enrich", pipeline.StageUpdate{Paused: &paused}))
+	assert.Equal(t, 4, runner.GetStage("enrich").Config.Concurrency)
+
+	paused, timeout = false, 0
+	require.NoError(t, runner.UpdateStage(ctx, "enrich", pipeline.StageUpdate{Paused: &paused, Timeout: &timeout}))
+	assert.True(t, runner.StageRunning("enrich"))
+	stage = runner.GetStage("enrich")
+	assert.Equal(t, generated.StageStatusHealthy, stage.Status)
+	assert.Empty(t, stage.Config.Timeout)
+
+	// Other stages are unaffected
+	assert.Equal(t, 10, runner.GetStage("route").Config.Concurrency)
+
+	paused = true
+	require.NoError(t, runner.UpdateStage(ctx, "validate", pipeline.StageUpdate{Paused: &paused}))
+	require.NoError(t, runner.UpdateStage(ctx, "enrich", pipeline.StageUpdate{Paused: &paused}))
+	assert.ErrorIs(t, runner.UpdateStage(ctx, "route", pipeline.StageUpdate{Paused: &paused}), pipeline.ErrLastRunningStage)
+	assert.ErrorIs(t, runner.UpdateStage(ctx, "unknown", pipeline.StageUpdate{Concurrency: &concurrency}), pipeline.ErrStageNotFound)
+}
+
+// stageChanges records the stages a ChangeListener is told about
+type stageChanges struct {
+	mu     sync.Mutex
+	stages []string
+}
+
+func (c *stageChanges) OrderChanged(context.Context, string) {}
+
+func (c *stageChanges) StageChanged(_ context.Context, stageID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stages = append(c.stages, stageID)
+}
+
+func TestRunner_ChangeListener(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runner, err := pipeline.New(ctx, &config.Config{RetryMaxAttempts: 1, RetryBackoffMs: 10}, nil)
+	require.NoError(t, err)
+
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	changes := &stageChanges{}
+	runner.AddChangeListener(changes)
+
+	require.NoError(t, runner.StopStage(ctx, "enrich"))
+	require.NoError(t, runner.StartStage(ctx, "enrich"))
+	concurrency := 5
+	require.NoError(t, runner.UpdateStage(ctx, "route", pipeline.StageUpdate{Concurrency: &concurrency}))
+
+	// Failed changes aren't reported
+	assert.ErrorIs(t, runner.StartStage(ctx, "enrich"), pipeline.ErrStageRunning)
+
+	changes.mu.Lock()
+	defer changes.mu.Unlock()
+	assert.Equal(t, []string{"enrich", "enrich", "route"}, changes.stages)
+}
@@ -0,0 +1,11 @@
+package pipeline_test
+
+import (
+	"testing"
+
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.RunWithContainers(m, nil)
+}
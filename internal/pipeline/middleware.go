@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// Use adds middleware wrapping every stage handler, in the order given.
+// It runs inside the built-in middleware (retries, decompression, decryption,
+// upcasting), so it sees plaintext payloads at the current schema version.
+// Middleware takes effect immediately, including for running stages.
+func (r *Runner) Use(mw ...message.HandlerMiddleware) {
+	r.mwMu.Lock()
+	defer r.mwMu.Unlock()
+
+	r.middleware = append(r.middleware, mw...)
+	clear(r.chains)
+}
+
+// UseStage adds middleware wrapping a single stage's handler. It runs inside
+// any middleware added with Use.
+func (r *Runner) UseStage(stageID string, mw ...message.HandlerMiddleware) error {
+	if _, ok := r.stageDef(stageID); !ok {
+		return fmt.Errorf("%w: %s", ErrStageNotFound, stageID)
+	}
+
+	r.mwMu.Lock()
+	defer r.mwMu.Unlock()
+
+	r.stageMiddleware[stageID] = append(r.stageMiddleware[stageID], mw...)
+	delete(r.chains, stageID)
+	return nil
+}
+
+// stageHandler returns the handler registered with the router for a stage.
+// The middleware chain and the stage's settings are resolved per message so
+// Use and UpdateStage apply to running stages. Each attempt's outcome is
+// published for monitoring.
+func (r *Runner) stageHandler(def stageDef) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		settings := r.settingsFor(def.id)
+		release, err := settings.acquire(msg.Context())
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		if settings.timeout > 0 {
+			parent := msg.Context()
+			ctx, cancel := context.WithTimeout(parent, settings.timeout)
+			msg.SetContext(ctx)
+			defer func() {
+				cancel()
+				msg.SetContext(parent)
+			}()
+		}
+
+		start := r.clock.Now()
+		out, err := r.middlewareChain(def)(msg)
+		r.publishStageEvent(def, msg, start, out, err)
+		return out, err
+	}
+}
+
+// middlewareChain returns the stage handler wrapped in its middleware,
+// composing and caching it on first use
+func (r *Runner) middlewareChain(def stageDef) message.HandlerFunc {
+	r.mwMu.RLock()
+	h, ok := r.chains[def.id]
+	r.mwMu.RUnlock()
+	if ok {
+		return h
+	}
+
+	r.mwMu.Lock()
+	defer r.mwMu.Unlock()
+
+	if h, ok := r.chains[def.id]; ok {
+		return h
+	}
+
+	mws := append(append([]message.HandlerMiddleware{}, r.middleware...), r.stageMiddleware[def.id]...)
+	h = def.handler
+	// First added middleware is outermost
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	r.chains[def.id] = h
+	return h
+}
@@ -0,0 +1,61 @@
+package pipeline_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+)
+
+func TestRunner_Use(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runner, err := pipeline.New(ctx, &config.Config{RetryMaxAttempts: 1, RetryBackoffMs: 10}, nil)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) message.HandlerMiddleware {
+		return func(h message.HandlerFunc) message.HandlerFunc {
+			return func(msg *message.Message) ([]*message.Message, error) {
+				mu.Lock()
+				calls = append(calls, name)
+				mu.Unlock()
+				return h(msg)
+			}
+		}
+	}
+
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	// Middleware added after Run still applies to running stages
+	runner.Use(record("global"))
+	require.NoError(t, runner.UseStage("route", record("route")))
+	assert.ErrorIs(t, runner.UseStage("unknown", record("unknown")), pipeline.ErrStageNotFound)
+
+	err = runner.IngestOrder(ctx, "order-1", &generated.OrderCreateRequest{
+		CustomerId:  "cust-1",
+		TotalAmount: 10,
+		Currency:    "USD",
+		Items:       []generated.OrderItem{{Sku: "SKU-1", Quantity: 1, UnitPrice: 10}},
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return runner.RoutingStats().TotalRouted == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"global", "global", "global", "route"}, calls)
+}
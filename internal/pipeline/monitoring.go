@@ -0,0 +1,134 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// NATS subjects of the pipeline's monitoring events
+const (
+	// TopicStageCompleteAll matches every stage's completion subject
+	TopicStageCompleteAll = "pipeline.stage.*.complete"
+	TopicPipelineErrors   = "pipeline.errors"
+)
+
+// StageCompleteSubject returns the NATS subject a stage's completions are
+// published on
+func StageCompleteSubject(stageID string) string {
+	return "pipeline.stage." + stageID + ".complete"
+}
+
+// Stage completion statuses
+const (
+	StageCompleteSuccess = "success"
+	// StageCompleteSkipped means the stage dropped the message, e.g. because
+	// the order was cancelled
+	StageCompleteSkipped = "skipped"
+)
+
+// Pipeline error types
+const (
+	ErrorTypeValidation      = "validation"
+	ErrorTypeEnrichment      = "enrichment"
+	ErrorTypeTimeout         = "timeout"
+	ErrorTypeExternalService = "external-service"
+	ErrorTypeUnknown         = "unknown"
+)
+
+// ErrorTypes lists every pipeline error type
+var ErrorTypes = []string{ErrorTypeValidation, ErrorTypeEnrichment, ErrorTypeTimeout, ErrorTypeExternalService, ErrorTypeUnknown}
+
+// HasStage reports whether the pipeline has a stage with the given ID
+func (r *Runner) HasStage(stageID string) bool {
+	_, ok := r.stageDef(stageID)
+	return ok
+}
+
+// publishStageEvent announces a stage's outcome for a message: StageComplete
+// when it succeeded, PipelineError when it failed. Like status updates these
+// are best effort and only published when NATS is available. With a
+// database, PipelineErrors are recorded too, for the pipeline error list.
+func (r *Runner) publishStageEvent(def stageDef, msg *message.Message, start time.Time, out []*message.Message, err error) {
+	subject := StageCompleteSubject(def.id)
+	var payload any
+	if err != nil {
+		pipelineErr := generated.PipelineErrorPayload{
+			ErrorId:   uuid.NewString(),
+			EventId:   msg.UUID,
+			StageId:   def.id,
+			ErrorType: errorType(def.id, err),
+			Message:   err.Error(),
+			Timestamp: r.clock.Now().UTC(),
+		}
+		r.recordPipelineError(msg, &pipelineErr)
+		subject, payload = TopicPipelineErrors, pipelineErr
+	} else {
+		status := StageCompleteSuccess
+		if def.publishTopic != "" && len(out) == 0 {
+			status = StageCompleteSkipped
+		}
+		payload = generated.StageCompletePayload{
+			StageId:    def.id,
+			EventId:    msg.UUID,
+			DurationMs: int(r.clock.Since(start).Milliseconds()),
+			Status:     status,
+		}
+	}
+
+	if r.infra == nil || r.infra.NATS == nil {
+		return
+	}
+	data, mErr := json.Marshal(payload)
+	if mErr != nil {
+		r.log.Warn("encoding pipeline event", "stage", def.id, "error", mErr)
+		return
+	}
+	if pErr := r.infra.PublishNATS(msg.Context(), subject, data); pErr != nil {
+		r.log.Warn("publishing pipeline event", "stage", def.id, "subject", subject, "error", pErr)
+	}
+}
+
+// recordPipelineError stores a stage failure, when the pipeline has a
+// database. The message's context may have expired with the stage's timeout,
+// so the store call doesn't inherit its cancellation.
+func (r *Runner) recordPipelineError(msg *message.Message, e *generated.PipelineErrorPayload) {
+	if r.orders == nil {
+		return
+	}
+	ctx := context.WithoutCancel(msg.Context())
+	err := r.orders.SavePipelineError(ctx, &store.PipelineError{
+		ID:         e.ErrorId,
+		EventID:    e.EventId,
+		OrderID:    msg.Metadata.Get("correlationId"),
+		Stage:      e.StageId,
+		ErrorType:  e.ErrorType,
+		Message:    e.Message,
+		OccurredAt: e.Timestamp,
+	})
+	if err != nil {
+		r.log.WarnContext(ctx, "recording pipeline error", "stage", e.StageId, "eventId", e.EventId, "error", err)
+	}
+}
+
+// errorType classifies a stage failure
+func errorType(stageID string, err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTypeTimeout
+	case stageID == "validate":
+		return ErrorTypeValidation
+	case stageID == "enrich":
+		return ErrorTypeEnrichment
+	case stageID == "emit":
+		return ErrorTypeExternalService
+	default:
+		return ErrorTypeUnknown
+	}
+}
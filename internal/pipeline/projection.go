@@ -0,0 +1,265 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// Order event types, named after the AsyncAPI messages they correspond to
+const (
+	EventOrderReceived  = "OrderReceived"
+	EventOrderValidated = "OrderValidated"
+	EventOrderEnriched  = "OrderEnriched"
+	EventOrderRouted    = "OrderRouted"
+	EventOrderCancelled = "OrderCancelled"
+	EventOrderFailed    = "OrderFailed"
+)
+
+// Order event statuses
+const (
+	EventStatusCompleted = "completed"
+	EventStatusFailed    = "failed"
+)
+
+// OrderStore is where the stages keep the order projection and event history,
+// and the pipeline its dead letters, errors, stage settings and stored
+// webhook subscriptions. *store.Store keeps them in Postgres.
+type OrderStore interface {
+	CreateOrder(ctx context.Context, o *store.Order) error
+	MarkValidated(ctx context.Context, orderID string, at time.Time) error
+	MarkEnriched(ctx context.Context, orderID string, at time.Time, enrichment json.RawMessage) error
+	MarkRouted(ctx context.Context, orderID string, at time.Time, destination, reason string) error
+	AppendEvent(ctx context.Context, e *store.Event) error
+	SaveDLQItem(ctx context.Context, item *store.DLQItem) error
+	SavePipelineError(ctx context.Context, e *store.PipelineError) error
+	SaveStageOverride(ctx context.Context, o *store.StageOverride) error
+	ListStageOverrides(ctx context.Context) ([]store.StageOverride, error)
+	ListWebhookSubscriptions(ctx context.Context, activeOnly bool) ([]store.WebhookSubscription, error)
+	RecordWebhookDelivery(ctx context.Context, d *store.WebhookDelivery) error
+}
+
+var _ OrderStore = (*store.Store)(nil)
+
+// recordAccepted inserts a newly ingested order into the order projection
+func (r *Runner) recordAccepted(ctx context.Context, order *orderEvent, start time.Time) error {
+	if r.orders == nil {
+		return nil
+	}
+
+	items, err := json.Marshal(order.Items)
+	if err != nil {
+		return fmt.Errorf("marshaling items: %w", err)
+	}
+	var shippingAddress, billingAddress, metadata []byte
+	if order.ShippingAddress != nil {
+		if shippingAddress, err = json.Marshal(order.ShippingAddress); err != nil {
+			return fmt.Errorf("marshaling shipping address: %w", err)
+		}
+	}
+	if order.BillingAddress != nil {
+		if billingAddress, err = json.Marshal(order.BillingAddress); err != nil {
+			return fmt.Errorf("marshaling billing address: %w", err)
+		}
+	}
+	if order.Metadata != nil {
+		if metadata, err = json.Marshal(order.Metadata); err != nil {
+			return fmt.Errorf("marshaling metadata: %w", err)
+		}
+	}
+
+	if err := r.orders.CreateOrder(ctx, &store.Order{
+		ID:              order.OrderID,
+		CustomerID:      order.CustomerID,
+		Status:          string(generated.OrderStatusAccepted),
+		TotalAmount:     order.TotalAmount,
+		Currency:        order.Currency,
+		ItemCount:       len(order.Items),
+		Items:           items,
+		ShippingAddress: shippingAddress,
+		BillingAddress:  billingAddress,
+		Metadata:        metadata,
+		CreatedAt:       order.CreatedAt,
+	}); err != nil {
+		return err
+	}
+	return r.appendEvent(ctx, order.OrderID, EventOrderReceived, "ingest", EventStatusCompleted, order.CreatedAt, start, map[string]any{
+		"itemCount":   len(order.Items),
+		"totalAmount": order.TotalAmount,
+		"currency":    order.Currency,
+	})
+}
+
+// recordValidated marks an order as validated in the order projection
+func (r *Runner) recordValidated(ctx context.Context, order *orderEvent, start time.Time) error {
+	if r.orders == nil {
+		return nil
+	}
+	if err := r.orders.MarkValidated(ctx, order.OrderID, *order.ValidatedAt); err != nil {
+		return err
+	}
+	return r.appendEvent(ctx, order.OrderID, EventOrderValidated, "validate", EventStatusCompleted, *order.ValidatedAt, start, map[string]any{
+		"isValid":  order.ValidationResult.IsValid,
+		"warnings": order.ValidationResult.Warnings,
+	})
+}
+
+// recordValidationFailed records a rejected order in its event history
+func (r *Runner) recordValidationFailed(ctx context.Context, order *orderEvent, start time.Time, cause error) error {
+	if r.orders == nil {
+		return nil
+	}
+	data, err := json.Marshal(map[string]string{
+		"code":    "validation-failed",
+		"message": cause.Error(),
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling event error: %w", err)
+	}
+
+	now := r.clock.Now().UTC()
+	return r.recordEvent(ctx, &store.Event{
+		ID:         uuid.NewString(),
+		OrderID:    order.OrderID,
+		Type:       EventOrderFailed,
+		Stage:      "validate",
+		Status:     EventStatusFailed,
+		OccurredAt: now,
+		DurationMs: int(now.Sub(start).Milliseconds()),
+		Error:      data,
+	})
+}
+
+// recordEnriched stores enrichment data in the shape of the API's OrderEnrichment
+func (r *Runner) recordEnriched(ctx context.Context, order *orderEvent, start time.Time) error {
+	if r.orders == nil {
+		return nil
+	}
+
+	var enrichment generated.OrderEnrichment
+	if c := order.Customer; c != nil {
+		enrichment.Customer = map[string]any{
+			"tier":           c.Tier,
+			"accountAgeDays": c.AccountAge,
+			"lifetimeValue":  c.LifetimeValue,
+		}
+	}
+	if f := order.FraudScore; f != nil {
+		enrichment.Fraud = map[string]any{
+			"score":     f.Score,
+			"riskLevel": f.RiskLevel,
+			"signals":   f.Signals,
+		}
+	}
+
+	data, err := json.Marshal(enrichment)
+	if err != nil {
+		return fmt.Errorf("marshaling enrichment: %w", err)
+	}
+	if err := r.orders.MarkEnriched(ctx, order.OrderID, *order.EnrichedAt, data); err != nil {
+		return err
+	}
+
+	metadata := map[string]any{}
+	if order.Customer != nil {
+		metadata["customerTier"] = order.Customer.Tier
+	}
+	if order.FraudScore != nil {
+		metadata["fraudScore"] = order.FraudScore.Score
+	}
+	return r.appendEvent(ctx, order.OrderID, EventOrderEnriched, "enrich", EventStatusCompleted, *order.EnrichedAt, start, metadata)
+}
+
+// recordRouted stores an order's routing decision in the order projection
+func (r *Runner) recordRouted(ctx context.Context, order *orderEvent, start time.Time) error {
+	if r.orders == nil {
+		return nil
+	}
+	if err := r.orders.MarkRouted(ctx, order.OrderID, *order.RoutedAt, order.Destination, order.RoutingReason); err != nil {
+		return err
+	}
+	return r.appendEvent(ctx, order.OrderID, EventOrderRouted, "route", EventStatusCompleted, *order.RoutedAt, start, map[string]any{
+		"destination": order.Destination,
+		"reason":      order.RoutingReason,
+	})
+}
+
+// appendEvent adds a completed stage to an order's event history. Stages
+// complete at most once per order, so the event ID is derived from the order
+// and stage, making redelivered messages idempotent.
+func (r *Runner) appendEvent(ctx context.Context, orderID, eventType, stage, status string, at, start time.Time, metadata map[string]any) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("marshaling event metadata: %w", err)
+	}
+	return r.recordEvent(ctx, &store.Event{
+		ID:         stageEventID(orderID, stage),
+		OrderID:    orderID,
+		Type:       eventType,
+		Stage:      stage,
+		Status:     status,
+		OccurredAt: at,
+		DurationMs: int(at.Sub(start).Milliseconds()),
+		Metadata:   data,
+	})
+}
+
+// recordEvent appends an event to an order's history and announces the
+// order's new status
+func (r *Runner) recordEvent(ctx context.Context, e *store.Event) error {
+	if err := r.orders.AppendEvent(ctx, e); err != nil {
+		return err
+	}
+	r.orderChanged(ctx, e.OrderID)
+	r.publishStatus(ctx, e)
+	return nil
+}
+
+func stageEventID(orderID, stage string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("synapse:order:"+orderID+":"+stage)).String()
+}
+
+// dropCancelled acknowledges messages for orders that were cancelled (or are
+// unknown to the projection) instead of passing them downstream
+func (r *Runner) dropCancelled(order *orderEvent, err error) ([]*message.Message, error) {
+	if errors.Is(err, store.ErrNotFound) {
+		r.log.Info("dropping cancelled order", "orderId", order.OrderID)
+		return nil, nil
+	}
+	return nil, err
+}
+
+// PublishCancellation records an order's cancellation in its event history and
+// announces it so downstream consumers can stop working on it
+func (r *Runner) PublishCancellation(ctx context.Context, orderID, previousStatus string, cancelledAt time.Time) error {
+	if r.orders != nil {
+		if err := r.appendEvent(ctx, orderID, EventOrderCancelled, "cancel", EventStatusCompleted, cancelledAt, cancelledAt, map[string]any{
+			"previousStatus": previousStatus,
+		}); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(generated.OrderCancelledPayload{
+		OrderId:        orderID,
+		PreviousStatus: previousStatus,
+		CancelledAt:    cancelledAt,
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling cancellation: %w", err)
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), data)
+	msg.Metadata.Set("correlationId", orderID)
+
+	return r.publisher.Publish(TopicOrdersCancelled, msg)
+}
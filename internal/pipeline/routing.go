@@ -0,0 +1,95 @@
+package pipeline
+
+import (
+	"sort"
+	"time"
+
+	"github.com/synapse/synapse/internal/generated"
+)
+
+// Routing destinations
+const (
+	DestinationFulfillment  = "fulfillment"
+	DestinationManualReview = "manual-review"
+	DestinationRejected     = "rejected"
+)
+
+// routingDestinations lists destinations in the order they are reported
+var routingDestinations = []string{DestinationFulfillment, DestinationManualReview, DestinationRejected}
+
+// routingStats counts routed orders per destination and reason. Callers must
+// hold the Runner's mu.
+type routingStats struct {
+	since   time.Time
+	total   int
+	counts  map[string]int
+	reasons map[string]map[string]int
+}
+
+func newRoutingStats(since time.Time) *routingStats {
+	return &routingStats{
+		since:   since,
+		counts:  make(map[string]int),
+		reasons: make(map[string]map[string]int),
+	}
+}
+
+func (s *routingStats) record(destination, reason string) {
+	s.total++
+	s.counts[destination]++
+	if s.reasons[destination] == nil {
+		s.reasons[destination] = make(map[string]int)
+	}
+	s.reasons[destination][reason]++
+}
+
+// destinations returns a copy of the per-destination counts
+func (s *routingStats) destinations() map[string]int {
+	counts := make(map[string]int, len(routingDestinations))
+	for _, dest := range routingDestinations {
+		counts[dest] = s.counts[dest]
+	}
+	return counts
+}
+
+// recordRouting counts a routing decision
+func (r *Runner) recordRouting(destination, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.routing.record(destination, reason)
+}
+
+// RoutingStats returns how many orders were routed to each destination, with
+// the most common reasons first
+func (r *Runner) RoutingStats() generated.RoutingStatsResponse {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	resp := generated.RoutingStatsResponse{
+		Since:        r.routing.since,
+		TotalRouted:  r.routing.total,
+		Destinations: make([]generated.RoutingDestinationStats, 0, len(routingDestinations)),
+	}
+	for _, dest := range routingDestinations {
+		stats := generated.RoutingDestinationStats{
+			Destination: dest,
+			Count:       r.routing.counts[dest],
+			Reasons:     make([]generated.RoutingReasonCount, 0, len(r.routing.reasons[dest])),
+		}
+		if r.routing.total > 0 {
+			stats.Share = float64(stats.Count) / float64(r.routing.total)
+		}
+		for reason, count := range r.routing.reasons[dest] {
+			stats.Reasons = append(stats.Reasons, generated.RoutingReasonCount{Reason: reason, Count: count})
+		}
+		sort.Slice(stats.Reasons, func(i, j int) bool {
+			if stats.Reasons[i].Count != stats.Reasons[j].Count {
+				return stats.Reasons[i].Count > stats.Reasons[j].Count
+			}
+			return stats.Reasons[i].Reason < stats.Reasons[j].Reason
+		})
+		resp.Destinations = append(resp.Destinations, stats)
+	}
+	return resp
+}
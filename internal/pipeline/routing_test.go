@@ -0,0 +1,121 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+)
+
+func TestRunner_RoutingStats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runner, err := pipeline.New(ctx, &config.Config{RetryMaxAttempts: 1, RetryBackoffMs: 10}, nil)
+	require.NoError(t, err)
+
+	go func() { _ = runner.Run(ctx) }()
+	defer runner.Close()
+	<-runner.Running()
+
+	stats := runner.RoutingStats()
+	assert.Zero(t, stats.TotalRouted)
+	require.Len(t, stats.Destinations, 3, "every destination is reported, even when empty")
+
+	err = runner.IngestOrder(ctx, "order-1", &generated.OrderCreateRequest{
+		CustomerId:  "cust-1",
+		TotalAmount: 10,
+		Currency:    "USD",
+		Items:       []generated.OrderItem{{Sku: "SKU-1", Quantity: 1, UnitPrice: 10}},
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return runner.RoutingStats().TotalRouted == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stats = runner.RoutingStats()
+	fulfillment := stats.Destinations[0]
+	assert.Equal(t, pipeline.DestinationFulfillment, fulfillment.Destination)
+	assert.Equal(t, 1, fulfillment.Count)
+	assert.InDelta(t, 1.0, fulfillment.Share, 0.0001)
+	assert.Equal(t, []generated.RoutingReasonCount{{Reason: "All checks passed", Count: 1}}, fulfillment.Reasons)
+
+	route := runner.GetStage("route")
+	require.NotNil(t, route)
+	assert.Equal(t, 1, route.Metrics.Destinations[pipeline.DestinationFulfillment])
+}
+
+func TestRunner_RoutesByFraudScore(t *testing.T) {
+	tests := []struct {
+		name        string
+		score       float64
+		destination string
+		reason      string
+	}{
+		{"low score", 15, pipeline.DestinationFulfillment, "All checks passed"},
+		{"review threshold", 50, pipeline.DestinationFulfillment, "All checks passed"},
+		{"high score", 65, pipeline.DestinationManualReview, "High fraud score requires manual review"},
+		{"reject threshold", 80, pipeline.DestinationManualReview, "High fraud score requires manual review"},
+		{"very high score", 95, pipeline.DestinationRejected, "Fraud score exceeds threshold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			runner, err := pipeline.New(ctx, &config.Config{RetryMaxAttempts: 1, RetryBackoffMs: 10}, nil)
+			require.NoError(t, err)
+
+			// Enrichment always scores 15, so the score is replaced on the
+			// way into the route stage
+			require.NoError(t, runner.UseStage("route", func(h message.HandlerFunc) message.HandlerFunc {
+				return func(msg *message.Message) ([]*message.Message, error) {
+					var order map[string]any
+					if err := json.Unmarshal(msg.Payload, &order); err != nil {
+						return nil, err
+					}
+					order["fraudScore"] = map[string]any{"score": tt.score, "signals": []string{}}
+					payload, err := json.Marshal(order)
+					if err != nil {
+						return nil, err
+					}
+					msg.Payload = payload
+					return h(msg)
+				}
+			}))
+
+			go func() { _ = runner.Run(ctx) }()
+			defer runner.Close()
+			<-runner.Running()
+
+			err = runner.IngestOrder(ctx, "order-1", &generated.OrderCreateRequest{
+				CustomerId:  "cust-1",
+				TotalAmount: 10,
+				Currency:    "USD",
+				Items:       []generated.OrderItem{{Sku: "SKU-1", Quantity: 1, UnitPrice: 10}},
+			})
+			require.NoError(t, err)
+
+			require.Eventually(t, func() bool {
+				return runner.RoutingStats().TotalRouted == 1
+			}, 5*time.Second, 10*time.Millisecond)
+
+			for _, d := range runner.RoutingStats().Destinations {
+				if d.Destination != tt.destination {
+					assert.Zero(t, d.Count, d.Destination)
+					continue
+				}
+				assert.Equal(t, 1, d.Count)
+				assert.Equal(t, []generated.RoutingReasonCount{{Reason: tt.reason, Count: 1}}, d.Reasons)
+			}
+		})
+	}
+}
@@ -5,15 +5,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
 	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/synapse/synapse/internal/clock"
 	"github.com/synapse/synapse/internal/config"
 	"github.com/synapse/synapse/internal/generated"
 	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/logging"
 )
 
 // Topics
@@ -23,6 +26,7 @@ const (
 	TopicOrdersEnriched  = "orders.enriched"
 	TopicOrdersRouted    = "orders.routed"
 	TopicOrdersDLQ       = "orders.dlq"
+	TopicOrdersCancelled = "orders.cancelled"
 )
 
 // Runner manages the event pipeline
@@ -31,8 +35,32 @@ type Runner struct {
 	infra     *infra.Infra
 	router    *message.Router
 	publisher message.Publisher
-	logger    watermill.LoggerAdapter
-	stages    map[string]*StageMetrics
+	// requeue publishes dead-lettered messages as they were received
+	requeue    message.Publisher
+	subscriber message.Subscriber
+	log        *slog.Logger
+	logger     watermill.LoggerAdapter
+	clock      clock.Clock
+	stages     map[string]*StageMetrics
+	settings   map[string]*stageSettings
+	stageDefs  []stageDef
+	handlers   map[string]*message.Handler
+	keys       KeyProvider
+	upcasters  *UpcasterRegistry
+	routing    *routingStats
+	webhooks   WebhookSubscribers
+	emitter    *WebhookEmitter
+	orders     OrderStore
+	listeners  []ChangeListener
+
+	middleware      []message.HandlerMiddleware
+	stageMiddleware map[string][]message.HandlerMiddleware
+	chains          map[string]message.HandlerFunc
+
+	mu          sync.RWMutex
+	lifecycleMu sync.Mutex
+	mwMu        sync.RWMutex
+	runCtx      context.Context
 }
 
 // StageMetrics tracks metrics for a pipeline stage
@@ -47,92 +75,236 @@ type StageMetrics struct {
 	LastProcessedAt time.Time             `json:"lastProcessedAt,omitempty"`
 }
 
+// Option customizes a Runner at construction time
+type Option func(*Runner)
+
+// WithKeyProvider sets the key provider used for payload field encryption,
+// e.g. a KMS-backed implementation. It enables encryption even when no
+// static keys are configured.
+func WithKeyProvider(kp KeyProvider) Option {
+	return func(r *Runner) {
+		r.keys = kp
+	}
+}
+
+// WithLogger sets the logger of the pipeline's records, by default the
+// "pipeline" module's
+func WithLogger(log *slog.Logger) Option {
+	return func(r *Runner) {
+		r.log = log
+	}
+}
+
+// WithClock sets the clock the pipeline's timestamps, latencies and webhook
+// backoff are taken from, by default the Infra's
+func WithClock(c clock.Clock) Option {
+	return func(r *Runner) {
+		r.clock = c
+	}
+}
+
+// WithOrderStore sets the store the stages keep the order projection in, by
+// default the Infra's database when it has one
+func WithOrderStore(s OrderStore) Option {
+	return func(r *Runner) {
+		r.orders = s
+	}
+}
+
+// WithUpcasters sets the registry used to upcast older payload schema versions
+func WithUpcasters(reg *UpcasterRegistry) Option {
+	return func(r *Runner) {
+		r.upcasters = reg
+	}
+}
+
+// WithWebhookSubscribers enables the emit stage, which POSTs routed orders
+// to the given subscribers
+func WithWebhookSubscribers(subs WebhookSubscribers) Option {
+	return func(r *Runner) {
+		r.webhooks = subs
+	}
+}
+
 // New creates a new pipeline Runner
-func New(ctx context.Context, cfg *config.Config, infra *infra.Infra) (*Runner, error) {
-	logger := watermill.NewSlogLogger(slog.Default())
+func New(ctx context.Context, cfg *config.Config, infra *infra.Infra, opts ...Option) (*Runner, error) {
+	r := &Runner{
+		config: cfg,
+		infra:  infra,
+		log:    logging.Module("pipeline"),
+		stages: map[string]*StageMetrics{
+			"validate": {StageId: "validate", Status: generated.StageStatusHealthy},
+			"enrich":   {StageId: "enrich", Status: generated.StageStatusHealthy},
+			"route":    {StageId: "route", Status: generated.StageStatusHealthy},
+		},
+		handlers:        make(map[string]*message.Handler),
+		upcasters:       NewUpcasterRegistry(CurrentSchemaVersion),
+		stageMiddleware: make(map[string][]message.HandlerMiddleware),
+		chains:          make(map[string]message.HandlerFunc),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.clock == nil && infra != nil {
+		r.clock = infra.Clock
+	}
+	r.clock = clock.Or(r.clock)
+	r.routing = newRoutingStats(r.clock.Now().UTC())
+	logger := watermill.NewSlogLogger(r.log)
+	r.logger = logger
+
+	// Stages keep the order projection up to date when a store or database is
+	// available
+	if r.orders == nil && infra != nil && infra.DB != nil {
+		r.orders = infra.Store()
+	}
 
 	// For now, use in-memory pub/sub (will switch to NATS for production)
 	pubSub := gochannel.NewGoChannel(gochannel.Config{}, logger)
 
+	// Large payloads are compressed on publish and restored by Decompress
+	publisher, err := CompressPublisher(pubSub, cfg.PipelineCompression, cfg.PipelineCompressionMinBytes)
+	if err != nil {
+		return nil, fmt.Errorf("configuring compression: %w", err)
+	}
+
+	// PII fields are encrypted before compression and decrypted after it
+	if r.keys == nil && cfg.PipelineEncryptionKeys != "" {
+		r.keys, err = NewStaticKeyProvider(cfg.PipelineEncryptionKeys, cfg.PipelineEncryptionKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("configuring encryption keys: %w", err)
+		}
+	}
+	consumeMiddleware := []message.HandlerMiddleware{Decompress}
+	if r.keys != nil {
+		encryptor := NewFieldEncryptor(r.keys, cfg.PipelineEncryptedFields)
+		publisher = encryptor.Publisher(publisher)
+		consumeMiddleware = append(consumeMiddleware, encryptor.Decrypt)
+	}
+
+	// Older payload versions are upcast once plaintext is restored
+	publisher = r.upcasters.Publisher(publisher)
+	consumeMiddleware = append(consumeMiddleware, r.upcasters.Middleware)
+	r.publisher = publisher
+
 	router, err := message.NewRouter(message.RouterConfig{}, logger)
 	if err != nil {
 		return nil, fmt.Errorf("creating router: %w", err)
 	}
+	r.router = router
 
-	// Add middleware
+	// Add middleware; retries follow the consuming stage's retry policy, and
+	// messages still failing after them are dead-lettered
 	router.AddMiddleware(
 		middleware.CorrelationID,
-		middleware.Retry{
-			MaxRetries:      cfg.RetryMaxAttempts,
-			InitialInterval: time.Duration(cfg.RetryBackoffMs) * time.Millisecond,
-			Logger:          logger,
-		}.Middleware,
+		r.deadLetter,
+		r.retry,
 		middleware.Recoverer,
 	)
+	router.AddMiddleware(consumeMiddleware...)
 
-	r := &Runner{
-		config:    cfg,
-		infra:     infra,
-		router:    router,
-		publisher: pubSub,
-		logger:    logger,
-		stages: map[string]*StageMetrics{
-			"validate": {StageId: "validate", Status: generated.StageStatusHealthy},
-			"enrich":   {StageId: "enrich", Status: generated.StageStatusHealthy},
-			"route":    {StageId: "route", Status: generated.StageStatusHealthy},
-		},
+	// Register handlers
+	r.subscriber = pubSub
+	r.requeue = pubSub
+	r.stageDefs = []stageDef{
+		{id: "validate", handlerName: "validate_order", subscribeTopic: TopicOrdersIngest, publishTopic: TopicOrdersValidated, handler: r.handleValidate},
+		{id: "enrich", handlerName: "enrich_order", subscribeTopic: TopicOrdersValidated, publishTopic: TopicOrdersEnriched, handler: r.handleEnrich},
+		{id: "route", handlerName: "route_order", subscribeTopic: TopicOrdersEnriched, publishTopic: TopicOrdersRouted, handler: r.handleRoute},
 	}
 
-	// Register handlers
-	router.AddHandler(
-		"validate_order",
-		TopicOrdersIngest,
-		pubSub,
-		TopicOrdersValidated,
-		pubSub,
-		r.handleValidate,
-	)
+	// Routed orders are pushed to webhook subscribers when any are configured
+	// or, with a database, to the subscriptions managed through the API
+	if r.webhooks == nil && cfg.WebhookSubscribers != "" {
+		r.webhooks, err = ParseWebhookSubscribers(cfg.WebhookSubscribers)
+		if err != nil {
+			return nil, fmt.Errorf("configuring webhook subscribers: %w", err)
+		}
+	}
+	var deliveries WebhookDeliveryRecorder
+	if r.orders != nil && cfg.WebhookSubscriptionsEnabled {
+		stored := storedWebhooks{r.orders}
+		deliveries = stored
+		if r.webhooks != nil {
+			r.webhooks = webhookSources{r.webhooks, stored}
+		} else {
+			r.webhooks = stored
+		}
+	}
+	if r.webhooks != nil {
+		r.emitter = NewWebhookEmitter(r.webhooks, publisher, WebhookEmitterConfig{
+			Timeout:     time.Duration(cfg.WebhookTimeoutMs) * time.Millisecond,
+			MaxAttempts: cfg.WebhookMaxAttempts,
+			Backoff:     time.Duration(cfg.WebhookBackoffMs) * time.Millisecond,
+			Recorder:    deliveries,
+			Logger:      r.log,
+			Clock:       r.clock,
+		})
+		r.stages["emit"] = &StageMetrics{StageId: "emit", Status: generated.StageStatusHealthy}
+		r.stageDefs = append(r.stageDefs, stageDef{id: "emit", handlerName: "emit_webhooks", subscribeTopic: TopicOrdersRouted, handler: r.handleEmit})
+	}
 
-	router.AddHandler(
-		"enrich_order",
-		TopicOrdersValidated,
-		pubSub,
-		TopicOrdersEnriched,
-		pubSub,
-		r.handleEnrich,
-	)
+	// Stages start with the configured defaults, then any configuration
+	// saved through UpdateStage
+	r.settings = make(map[string]*stageSettings, len(r.stageDefs))
+	for _, def := range r.stageDefs {
+		r.settings[def.id] = defaultStageSettings(cfg)
+	}
+	var paused map[string]bool
+	if r.orders != nil {
+		if paused, err = r.loadStageOverrides(ctx); err != nil {
+			return nil, fmt.Errorf("loading stage overrides: %w", err)
+		}
+	}
 
-	router.AddHandler(
-		"route_order",
-		TopicOrdersEnriched,
-		pubSub,
-		TopicOrdersRouted,
-		pubSub,
-		r.handleRoute,
-	)
+	for _, def := range r.stageDefs {
+		if !paused[def.id] {
+			r.addStageHandler(def)
+		}
+	}
 
 	return r, nil
 }
 
 // Run starts the pipeline router
 func (r *Runner) Run(ctx context.Context) error {
+	r.mu.Lock()
+	r.runCtx = ctx
+	r.mu.Unlock()
 	return r.router.Run(ctx)
 }
 
+// Running is closed once the pipeline router has started all stages
+func (r *Runner) Running() chan struct{} {
+	return r.router.Running()
+}
+
+// Subscriber returns the subscriber the stages consume from, e.g. for tests
+// to observe the topics they publish to
+func (r *Runner) Subscriber() message.Subscriber {
+	return r.subscriber
+}
+
 // Close stops the pipeline
 func (r *Runner) Close() error {
-	return r.router.Close()
+	if err := r.router.Close(); err != nil {
+		return err
+	}
+	return r.subscriber.Close()
 }
 
 // IngestOrder publishes an order to the pipeline
 func (r *Runner) IngestOrder(ctx context.Context, orderID string, req *generated.OrderCreateRequest) error {
-	payload := map[string]any{
-		"orderId":     orderID,
-		"customerId":  req.CustomerId,
-		"items":       req.Items,
-		"totalAmount": req.TotalAmount,
-		"currency":    req.Currency,
-		"createdAt":   time.Now().UTC(),
+	start := r.clock.Now()
+	payload := orderEvent{
+		OrderID:         orderID,
+		CustomerID:      req.CustomerId,
+		Items:           req.Items,
+		TotalAmount:     req.TotalAmount,
+		Currency:        req.Currency,
+		ShippingAddress: req.ShippingAddress,
+		BillingAddress:  req.BillingAddress,
+		Metadata:        req.Metadata,
+		CreatedAt:       r.clock.Now().UTC(),
 	}
 
 	data, err := json.Marshal(payload)
@@ -140,6 +312,10 @@ func (r *Runner) IngestOrder(ctx context.Context, orderID string, req *generated
 		return fmt.Errorf("marshaling order: %w", err)
 	}
 
+	if err := r.recordAccepted(ctx, &payload, start); err != nil {
+		return err
+	}
+
 	msg := message.NewMessage(watermill.NewUUID(), data)
 	msg.Metadata.Set("correlationId", orderID)
 
@@ -148,11 +324,16 @@ func (r *Runner) IngestOrder(ctx context.Context, orderID string, req *generated
 
 // GetStages returns current stage metrics
 func (r *Runner) GetStages() []generated.PipelineStageSummary {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	stages := make([]generated.PipelineStageSummary, 0, len(r.stages))
-	for _, s := range r.stages {
+	for _, def := range r.stageDefs {
+		s := r.stages[def.id]
 		stages = append(stages, generated.PipelineStageSummary{
 			StageId: s.StageId,
 			Status:  s.Status,
+			Metrics: r.stageMetrics(s),
 		})
 	}
 	return stages
@@ -160,133 +341,173 @@ func (r *Runner) GetStages() []generated.PipelineStageSummary {
 
 // GetStage returns a specific stage's metrics
 func (r *Runner) GetStage(stageID string) *generated.PipelineStageResponse {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	s, ok := r.stages[stageID]
 	if !ok {
 		return nil
 	}
+	settings := r.settings[stageID]
 	return &generated.PipelineStageResponse{
-		StageId: s.StageId,
-		Status:  s.Status,
+		StageId:   s.StageId,
+		Status:    s.Status,
+		Config:    settings.stageConfig(),
+		Metrics:   r.stageMetrics(s),
+		UpdatedAt: settings.updatedAt,
+	}
+}
+
+// stageMetrics converts tracked metrics to the API representation. Callers
+// must hold r.mu.
+func (r *Runner) stageMetrics(s *StageMetrics) generated.StageMetrics {
+	m := generated.StageMetrics{
+		ProcessedTotal:    int(s.ProcessedTotal),
+		ProcessedLastHour: int(s.ProcessedLastHr),
+		ErrorRate:         s.ErrorRate,
+		AvgLatencyMs:      s.AvgLatencyMs,
+		QueueDepth:        s.QueueDepth,
+	}
+	if s.StageId == "route" {
+		m.Destinations = r.routing.destinations()
 	}
+	return m
 }
 
 // handleValidate validates incoming orders
 func (r *Runner) handleValidate(msg *message.Message) ([]*message.Message, error) {
-	start := time.Now()
+	start := r.clock.Now()
 	defer r.recordMetrics("validate", start)
 
-	var order map[string]any
-	if err := json.Unmarshal(msg.Payload, &order); err != nil {
-		return nil, fmt.Errorf("unmarshaling order: %w", err)
+	order, err := decodeOrder(msg)
+	if err != nil {
+		return nil, err
 	}
 
-	slog.Info("validating order", "orderId", order["orderId"])
+	r.log.Debug("validating order", "orderId", order.OrderID)
 
 	// Validation logic
-	if order["customerId"] == nil || order["customerId"] == "" {
-		return nil, fmt.Errorf("customerId is required")
+	var invalid error
+	if order.CustomerID == "" {
+		invalid = fmt.Errorf("customerId is required")
+	} else if len(order.Items) == 0 {
+		invalid = fmt.Errorf("at least one item is required")
 	}
-
-	items, ok := order["items"].([]any)
-	if !ok || len(items) == 0 {
-		return nil, fmt.Errorf("at least one item is required")
+	if invalid != nil {
+		if err := r.recordValidationFailed(msg.Context(), order, start, invalid); err != nil {
+			r.log.Error("recording validation failure", "orderId", order.OrderID, "error", err)
+		}
+		return nil, invalid
 	}
 
 	// Add validation result
-	order["validatedAt"] = time.Now().UTC()
-	order["validationResult"] = map[string]any{
-		"isValid":  true,
-		"warnings": []string{},
+	validatedAt := r.clock.Now().UTC()
+	order.ValidatedAt = &validatedAt
+	order.ValidationResult = &validationResult{
+		IsValid:  true,
+		Warnings: []string{},
 	}
 
-	data, _ := json.Marshal(order)
-	outMsg := message.NewMessage(watermill.NewUUID(), data)
-	outMsg.Metadata = msg.Metadata
+	if err := r.recordValidated(msg.Context(), order, start); err != nil {
+		return r.dropCancelled(order, err)
+	}
 
-	return []*message.Message{outMsg}, nil
+	return order.next(msg)
 }
 
 // handleEnrich enriches orders with customer and fraud data
 func (r *Runner) handleEnrich(msg *message.Message) ([]*message.Message, error) {
-	start := time.Now()
+	start := r.clock.Now()
 	defer r.recordMetrics("enrich", start)
 
-	var order map[string]any
-	if err := json.Unmarshal(msg.Payload, &order); err != nil {
-		return nil, fmt.Errorf("unmarshaling order: %w", err)
+	order, err := decodeOrder(msg)
+	if err != nil {
+		return nil, err
 	}
 
-	slog.Info("enriching order", "orderId", order["orderId"])
+	r.log.Debug("enriching order", "orderId", order.OrderID)
 
 	// Simulate customer data enrichment
-	order["enrichedAt"] = time.Now().UTC()
-	order["customer"] = map[string]any{
-		"tier":          "gold",
-		"accountAge":    365,
-		"lifetimeValue": 1500.00,
+	enrichedAt := r.clock.Now().UTC()
+	order.EnrichedAt = &enrichedAt
+	order.Customer = &customerData{
+		Tier:          "gold",
+		AccountAge:    365,
+		LifetimeValue: 1500.00,
 	}
 
 	// Simulate fraud scoring
-	order["fraudScore"] = map[string]any{
-		"score":     15,
-		"riskLevel": "low",
-		"signals":   []string{},
+	order.FraudScore = &fraudScore{
+		Score:     15,
+		RiskLevel: "low",
+		Signals:   []string{},
 	}
 
-	data, _ := json.Marshal(order)
-	outMsg := message.NewMessage(watermill.NewUUID(), data)
-	outMsg.Metadata = msg.Metadata
+	if err := r.recordEnriched(msg.Context(), order, start); err != nil {
+		return r.dropCancelled(order, err)
+	}
 
-	return []*message.Message{outMsg}, nil
+	return order.next(msg)
 }
 
 // handleRoute determines the routing destination
 func (r *Runner) handleRoute(msg *message.Message) ([]*message.Message, error) {
-	start := time.Now()
+	start := r.clock.Now()
 	defer r.recordMetrics("route", start)
 
-	var order map[string]any
-	if err := json.Unmarshal(msg.Payload, &order); err != nil {
-		return nil, fmt.Errorf("unmarshaling order: %w", err)
+	order, err := decodeOrder(msg)
+	if err != nil {
+		return nil, err
 	}
 
-	slog.Info("routing order", "orderId", order["orderId"])
+	r.log.Debug("routing order", "orderId", order.OrderID)
 
 	// Determine routing based on fraud score
 	fraudScore := 0.0
-	if fs, ok := order["fraudScore"].(map[string]any); ok {
-		if score, ok := fs["score"].(float64); ok {
-			fraudScore = score
-		}
+	if order.FraudScore != nil {
+		fraudScore = order.FraudScore.Score
 	}
 
-	destination := "fulfillment"
+	destination := DestinationFulfillment
 	reason := "All checks passed"
 
-	if fraudScore > 50 {
-		destination = "manual-review"
-		reason = "High fraud score requires manual review"
-	} else if fraudScore > 80 {
-		destination = "rejected"
+	if fraudScore > 80 {
+		destination = DestinationRejected
 		reason = "Fraud score exceeds threshold"
+	} else if fraudScore > 50 {
+		destination = DestinationManualReview
+		reason = "High fraud score requires manual review"
 	}
 
-	order["routedAt"] = time.Now().UTC()
-	order["destination"] = destination
-	order["routingReason"] = reason
+	routedAt := r.clock.Now().UTC()
+	order.RoutedAt = &routedAt
+	order.Destination = destination
+	order.RoutingReason = reason
 
-	data, _ := json.Marshal(order)
-	outMsg := message.NewMessage(watermill.NewUUID(), data)
-	outMsg.Metadata = msg.Metadata
+	if err := r.recordRouted(msg.Context(), order, start); err != nil {
+		return r.dropCancelled(order, err)
+	}
+	r.recordRouting(destination, reason)
 
-	return []*message.Message{outMsg}, nil
+	return order.next(msg)
+}
+
+// handleEmit notifies webhook subscribers about routed orders
+func (r *Runner) handleEmit(msg *message.Message) ([]*message.Message, error) {
+	start := r.clock.Now()
+	defer r.recordMetrics("emit", start)
+
+	return r.emitter.Handle(msg)
 }
 
 func (r *Runner) recordMetrics(stage string, start time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if s, ok := r.stages[stage]; ok {
 		s.ProcessedTotal++
-		s.LastProcessedAt = time.Now()
-		latency := float64(time.Since(start).Milliseconds())
+		s.LastProcessedAt = r.clock.Now()
+		latency := float64(r.clock.Since(start).Milliseconds())
 		// Simple moving average
 		s.AvgLatencyMs = (s.AvgLatencyMs*float64(s.ProcessedTotal-1) + latency) / float64(s.ProcessedTotal)
 	}
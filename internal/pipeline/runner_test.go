@@ -2,6 +2,7 @@ package pipeline_test
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/synapse/synapse/internal/generated"
 	"github.com/synapse/synapse/internal/pipeline"
 	"github.com/synapse/synapse/internal/testutil"
+	"github.com/synapse/synapse/internal/testutil/factory"
 )
 
 func TestPipeline_IngestOrder(t *testing.T) {
@@ -20,46 +22,42 @@ func TestPipeline_IngestOrder(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	// Start test containers
-	tc, err := testutil.StartContainers(ctx, t, nil)
+	// Use the package's shared test containers
+	tc, err := testutil.SharedContainers(ctx, t)
 	require.NoError(t, err, "failed to start containers")
 
-	// Create test infrastructure
+	// Create test infrastructure, showing the pipeline's logs if the test fails
 	infra, cfg := testutil.TestInfra(ctx, t, tc)
+	testutil.CaptureLogs(t)
 
 	// Create pipeline
 	runner, err := pipeline.New(ctx, cfg, infra)
 	require.NoError(t, err, "failed to create pipeline")
 
+	// Capture routed orders before any are published
+	routed := testutil.CaptureTopic(t, runner.Subscriber(), pipeline.TopicOrdersRouted)
+
 	// Start pipeline in background
 	go func() {
 		if err := runner.Run(ctx); err != nil && ctx.Err() == nil {
 			t.Logf("pipeline error: %v", err)
 		}
 	}()
-
-	// Give pipeline time to start
-	time.Sleep(100 * time.Millisecond)
+	<-runner.Running()
 
 	// Test ingesting an order
-	orderReq := &generated.OrderCreateRequest{
-		CustomerId:  "test-customer-123",
-		TotalAmount: 99.99,
-		Currency:    "USD",
-		Items: []generated.OrderItem{
-			{
-				Sku:       "TEST-SKU-001",
-				Quantity:  2,
-				UnitPrice: 49.99,
-			},
-		},
-	}
-
-	err = runner.IngestOrder(ctx, "test-order-123", orderReq)
+	err = runner.IngestOrder(ctx, "test-order-123", factory.Order().Build())
 	require.NoError(t, err, "failed to ingest order")
 
-	// Give pipeline time to process
-	time.Sleep(500 * time.Millisecond)
+	// Wait for it to pass every stage
+	msgs := routed.WaitForN(1)
+	var order struct {
+		OrderID     string `json:"orderId"`
+		Destination string `json:"destination"`
+	}
+	require.NoError(t, json.Unmarshal(msgs[0].Payload, &order))
+	assert.Equal(t, "test-order-123", order.OrderID)
+	assert.Equal(t, pipeline.DestinationFulfillment, order.Destination)
 
 	// Verify stages are healthy
 	stages := runner.GetStages()
@@ -83,7 +81,7 @@ func TestPipeline_GetStages(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	tc, err := testutil.StartContainers(ctx, t, nil)
+	tc, err := testutil.SharedContainers(ctx, t)
 	require.NoError(t, err)
 
 	infra, cfg := testutil.TestInfra(ctx, t, tc)
@@ -103,3 +101,36 @@ func TestPipeline_GetStages(t *testing.T) {
 	assert.True(t, stageIds["enrich"], "should have enrich stage")
 	assert.True(t, stageIds["route"], "should have route stage")
 }
+
+func TestPipeline_KeepsBillingAddressAndMetadata(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fake := testutil.FakeInfra(t)
+	runner, err := pipeline.New(ctx, fake.Config, fake.Infra, pipeline.WithOrderStore(fake.Store))
+	require.NoError(t, err)
+	routed := testutil.CaptureTopic(t, runner.Subscriber(), pipeline.TopicOrdersRouted)
+	go runner.Run(ctx)
+	defer runner.Close()
+	<-runner.Running()
+
+	billing := factory.Address()
+	req := factory.Order().BillTo(billing).Metadata("channel", "web").Build()
+	require.NoError(t, runner.IngestOrder(ctx, req.OrderId, req))
+
+	// They reach the last stage, and webhooks, and are stored
+	var order struct {
+		BillingAddress *generated.Address `json:"billingAddress"`
+		Metadata       map[string]any     `json:"metadata"`
+	}
+	require.NoError(t, json.Unmarshal(routed.WaitForN(1)[0].Payload, &order))
+	assert.Equal(t, billing, order.BillingAddress)
+	assert.Equal(t, map[string]any{"channel": "web"}, order.Metadata)
+
+	stored, err := fake.Store.GetOrder(ctx, req.OrderId)
+	require.NoError(t, err)
+	wantBilling, err := json.Marshal(billing)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(wantBilling), string(stored.BillingAddress))
+	assert.JSONEq(t, `{"channel":"web"}`, string(stored.Metadata))
+}
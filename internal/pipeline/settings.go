@@ -0,0 +1,222 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// StageUpdate changes a stage's configuration. Nil fields are left as they are.
+type StageUpdate struct {
+	// Paused stops the stage when true and starts it again when false
+	Paused      *bool
+	Concurrency *int
+	// RetryPolicy replaces the stage's retry policy as a whole
+	RetryPolicy *generated.RetryPolicy
+	// Timeout bounds the processing of each message; zero removes the bound
+	Timeout *time.Duration
+}
+
+// stageSettings is a stage's runtime configuration
+type stageSettings struct {
+	concurrency int
+	retry       generated.RetryPolicy
+	timeout     time.Duration
+	// slots holds a token per message being processed; nil when concurrency
+	// is unlimited. Resizing replaces the channel, so messages in flight
+	// release the slot they took.
+	slots     chan struct{}
+	updatedAt time.Time
+}
+
+// defaultStageSettings returns the configured settings every stage starts with
+func defaultStageSettings(cfg *config.Config) *stageSettings {
+	s := &stageSettings{
+		retry: generated.RetryPolicy{
+			MaxAttempts: cfg.RetryMaxAttempts,
+			BackoffMs:   cfg.RetryBackoffMs,
+		},
+	}
+	s.setConcurrency(cfg.PipelineConcurrency)
+	return s
+}
+
+func (s *stageSettings) setConcurrency(n int) {
+	s.concurrency = n
+	s.slots = nil
+	if n > 0 {
+		s.slots = make(chan struct{}, n)
+	}
+}
+
+// acquire waits for a processing slot of the stage. The returned func
+// releases it.
+func (s *stageSettings) acquire(ctx context.Context) (func(), error) {
+	slots := s.slots
+	if slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// stageConfig converts the settings to the API representation
+func (s *stageSettings) stageConfig() generated.StageConfig {
+	retry := s.retry
+	c := generated.StageConfig{
+		Concurrency: s.concurrency,
+		RetryPolicy: &retry,
+	}
+	if s.timeout > 0 {
+		c.Timeout = formatTimeout(s.timeout)
+	}
+	return c
+}
+
+// formatTimeout renders a timeout as the API's "30s" or "5m"
+func formatTimeout(d time.Duration) string {
+	if d%time.Minute == 0 {
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%ds", int64(math.Ceil(d.Seconds())))
+}
+
+// settingsFor returns a copy of a stage's settings
+func (r *Runner) settingsFor(stageID string) stageSettings {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return *r.settings[stageID]
+}
+
+// UpdateStage applies u to a stage. Pausing and resuming behave like
+// StopStage and StartStage, except that pausing a paused stage or resuming a
+// running one is not an error. Other settings apply to messages the stage
+// takes from then on. With a database, the stage's resulting configuration is
+// saved and reapplied when the pipeline starts.
+func (r *Runner) UpdateStage(ctx context.Context, stageID string, u StageUpdate) error {
+	if _, ok := r.stageDef(stageID); !ok {
+		return fmt.Errorf("%w: %s", ErrStageNotFound, stageID)
+	}
+
+	if u.Paused != nil {
+		if *u.Paused {
+			if err := r.StopStage(ctx, stageID); err != nil && !errors.Is(err, ErrStageStopped) {
+				return err
+			}
+		} else {
+			if err := r.StartStage(ctx, stageID); err != nil && !errors.Is(err, ErrStageRunning) {
+				return err
+			}
+		}
+	}
+
+	r.mu.Lock()
+	s := r.settings[stageID]
+	if u.Concurrency != nil {
+		s.setConcurrency(*u.Concurrency)
+	}
+	if u.RetryPolicy != nil {
+		s.retry = *u.RetryPolicy
+	}
+	if u.Timeout != nil {
+		s.timeout = *u.Timeout
+	}
+	s.updatedAt = r.clock.Now().UTC()
+	_, running := r.handlers[stageID]
+	stageConfig := s.stageConfig()
+	updatedAt := s.updatedAt
+	r.mu.Unlock()
+	r.stageChanged(ctx, stageID)
+
+	if r.orders == nil {
+		return nil
+	}
+	data, err := json.Marshal(stageConfig)
+	if err != nil {
+		return fmt.Errorf("encoding stage %s config: %w", stageID, err)
+	}
+	return r.orders.SaveStageOverride(ctx, &store.StageOverride{
+		StageID:   stageID,
+		Paused:    !running,
+		Config:    data,
+		UpdatedAt: updatedAt,
+	})
+}
+
+// loadStageOverrides applies the stage configuration saved by UpdateStage.
+// It runs before the stage handlers are registered, so paused stages are
+// simply left out, as long as one stage is left to run.
+func (r *Runner) loadStageOverrides(ctx context.Context) (paused map[string]bool, err error) {
+	overrides, err := r.orders.ListStageOverrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	paused = make(map[string]bool)
+	for _, o := range overrides {
+		s, ok := r.settings[o.StageID]
+		if !ok {
+			continue
+		}
+		var stageConfig generated.StageConfig
+		if err := json.Unmarshal(o.Config, &stageConfig); err != nil {
+			return nil, fmt.Errorf("decoding stage %s config: %w", o.StageID, err)
+		}
+		s.setConcurrency(stageConfig.Concurrency)
+		if stageConfig.RetryPolicy != nil {
+			s.retry = *stageConfig.RetryPolicy
+		}
+		s.timeout = 0
+		if stageConfig.Timeout != "" {
+			if s.timeout, err = time.ParseDuration(stageConfig.Timeout); err != nil {
+				return nil, fmt.Errorf("decoding stage %s timeout: %w", o.StageID, err)
+			}
+		}
+		s.updatedAt = o.UpdatedAt
+		if o.Paused && len(paused) < len(r.stageDefs)-1 {
+			paused[o.StageID] = true
+			r.stages[o.StageID].Status = generated.StageStatusPaused
+		}
+	}
+	return paused, nil
+}
+
+// retry is router middleware that retries failed messages with the retry
+// policy of the stage that consumed them
+func (r *Runner) retry(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		var policy generated.RetryPolicy
+		if def, ok := r.stageForHandler(message.HandlerNameFromCtx(msg.Context())); ok {
+			policy = r.settingsFor(def.id).retry
+		}
+
+		multiplier := policy.BackoffMultiplier
+		if multiplier < 1 {
+			multiplier = 1
+		}
+		maxInterval := time.Duration(math.MaxInt64)
+		if policy.MaxBackoffMs > 0 {
+			maxInterval = time.Duration(policy.MaxBackoffMs) * time.Millisecond
+		}
+		return middleware.Retry{
+			MaxRetries:      policy.MaxAttempts,
+			InitialInterval: time.Duration(policy.BackoffMs) * time.Millisecond,
+			MaxInterval:     maxInterval,
+			Multiplier:      multiplier,
+			Logger:          r.logger,
+		}.Middleware(h)(msg)
+	}
+}
@@ -0,0 +1,72 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// TopicOrderStatusPrefix prefixes the per-order NATS subjects that status
+// updates are published on
+const TopicOrderStatusPrefix = "orders.status."
+
+// OrderStatusSubject returns the NATS subject of an order's status updates
+func OrderStatusSubject(orderID string) string {
+	return TopicOrderStatusPrefix + orderID
+}
+
+// eventOrderStatus is the order status each event type leaves an order in
+var eventOrderStatus = map[string]generated.OrderStatus{
+	EventOrderReceived:  generated.OrderStatusAccepted,
+	EventOrderValidated: generated.OrderStatusValidated,
+	EventOrderEnriched:  generated.OrderStatusEnriched,
+	EventOrderRouted:    generated.OrderStatusRouted,
+	EventOrderCancelled: generated.OrderStatusCancelled,
+	EventOrderFailed:    generated.OrderStatusFailed,
+}
+
+// IsFinalStatus reports whether an order in status won't change again
+func IsFinalStatus(status string) bool {
+	switch generated.OrderStatus(status) {
+	case generated.OrderStatusRouted, generated.OrderStatusFailed, generated.OrderStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// publishStatus announces an event on the order's status subject, so live
+// views don't have to poll. Updates are best effort: the event history is
+// the source of truth, so failures are logged rather than failing the stage.
+func (r *Runner) publishStatus(ctx context.Context, e *store.Event) {
+	if r.infra == nil || r.infra.NATS == nil {
+		return
+	}
+
+	update := generated.OrderStatusUpdatePayload{
+		OrderId:     e.OrderID,
+		Status:      string(eventOrderStatus[e.Type]),
+		EventId:     e.ID,
+		EventType:   e.Type,
+		Stage:       e.Stage,
+		StageStatus: e.Status,
+		Timestamp:   e.OccurredAt,
+		DurationMs:  e.DurationMs,
+	}
+	if len(e.Metadata) > 0 {
+		_ = json.Unmarshal(e.Metadata, &update.Metadata)
+	}
+	if len(e.Error) > 0 {
+		_ = json.Unmarshal(e.Error, &update.Error)
+	}
+	data, err := json.Marshal(update)
+	if err != nil {
+		r.log.Warn("encoding order status update", "orderId", e.OrderID, "error", err)
+		return
+	}
+	if err := r.infra.PublishNATS(ctx, OrderStatusSubject(e.OrderID), data); err != nil {
+		r.log.Warn("publishing order status update", "orderId", e.OrderID, "error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// MetadataSchemaVersion carries the payload schema version of a pipeline message
+const MetadataSchemaVersion = "schemaVersion"
+
+// CurrentSchemaVersion is the payload schema version the stage handlers expect.
+// Bump it together with the AsyncAPI payload schemas and register an upcaster
+// from the previous version for every affected topic.
+const CurrentSchemaVersion = 1
+
+// Upcaster converts a payload from one schema version to the next
+type Upcaster func(payload []byte) ([]byte, error)
+
+// UpcasterRegistry holds upcasters keyed by topic and source version
+type UpcasterRegistry struct {
+	mu        sync.RWMutex
+	current   int
+	upcasters map[string]map[int]Upcaster
+}
+
+// NewUpcasterRegistry creates a registry that upcasts payloads to current
+func NewUpcasterRegistry(current int) *UpcasterRegistry {
+	return &UpcasterRegistry{
+		current:   current,
+		upcasters: make(map[string]map[int]Upcaster),
+	}
+}
+
+// Register adds an upcaster converting payloads on topic from version "from" to from+1
+func (r *UpcasterRegistry) Register(topic string, from int, fn Upcaster) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.upcasters[topic] == nil {
+		r.upcasters[topic] = make(map[int]Upcaster)
+	}
+	r.upcasters[topic][from] = fn
+}
+
+// Current returns the version payloads are upcast to
+func (r *UpcasterRegistry) Current() int {
+	return r.current
+}
+
+// Upcast converts payload on topic from version to the current version
+func (r *UpcasterRegistry) Upcast(topic string, version int, payload []byte) ([]byte, error) {
+	if version > r.current {
+		return nil, fmt.Errorf("schema version %d is newer than supported version %d", version, r.current)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for v := version; v < r.current; v++ {
+		fn, ok := r.upcasters[topic][v]
+		if !ok {
+			return nil, fmt.Errorf("no upcaster for %s from version %d", topic, v)
+		}
+		var err error
+		if payload, err = fn(payload); err != nil {
+			return nil, fmt.Errorf("upcasting %s from version %d: %w", topic, v, err)
+		}
+	}
+
+	return payload, nil
+}
+
+// Middleware is a router middleware that upcasts incoming payloads to the
+// current schema version. Messages without a version are treated as version 1.
+func (r *UpcasterRegistry) Middleware(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		version := 1
+		if v := msg.Metadata.Get(MetadataSchemaVersion); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid schema version %q: %w", v, err)
+			}
+			version = parsed
+		}
+
+		if version != r.current {
+			topic := message.SubscribeTopicFromCtx(msg.Context())
+			payload, err := r.Upcast(topic, version, msg.Payload)
+			if err != nil {
+				return nil, err
+			}
+			msg.Payload = payload
+		}
+		msg.Metadata.Set(MetadataSchemaVersion, strconv.Itoa(r.current))
+
+		return h(msg)
+	}
+}
+
+// Publisher wraps pub so that outgoing messages are stamped with the current
+// schema version unless they already carry one
+func (r *UpcasterRegistry) Publisher(pub message.Publisher) message.Publisher {
+	return &versionedPublisher{Publisher: pub, version: strconv.Itoa(r.current)}
+}
+
+type versionedPublisher struct {
+	message.Publisher
+	version string
+}
+
+// Publish stamps each message with the schema version and forwards it
+func (p *versionedPublisher) Publish(topic string, msgs ...*message.Message) error {
+	for _, msg := range msgs {
+		if msg.Metadata.Get(MetadataSchemaVersion) == "" {
+			msg.Metadata.Set(MetadataSchemaVersion, p.version)
+		}
+	}
+	return p.Publisher.Publish(topic, msgs...)
+}
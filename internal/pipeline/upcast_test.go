@@ -0,0 +1,42 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/pipeline"
+)
+
+func TestUpcasterRegistry_UpcastsToCurrentVersion(t *testing.T) {
+	reg := pipeline.NewUpcasterRegistry(3)
+	reg.Register(pipeline.TopicOrdersIngest, 1, func(p []byte) ([]byte, error) {
+		return bytes.Replace(p, []byte(`"accountAge"`), []byte(`"accountAgeDays"`), 1), nil
+	})
+	reg.Register(pipeline.TopicOrdersIngest, 2, func(p []byte) ([]byte, error) {
+		return bytes.Replace(p, []byte(`"amount"`), []byte(`"totalAmount"`), 1), nil
+	})
+
+	msg := message.NewMessage(watermill.NewUUID(), []byte(`{"accountAge":30,"amount":10}`))
+
+	upcast, err := reg.Upcast(pipeline.TopicOrdersIngest, 1, msg.Payload)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"accountAgeDays":30,"totalAmount":10}`, string(upcast))
+
+	_, err = reg.Upcast(pipeline.TopicOrdersValidated, 1, msg.Payload)
+	assert.Error(t, err, "missing upcaster should fail")
+
+	_, err = reg.Upcast(pipeline.TopicOrdersIngest, 4, msg.Payload)
+	assert.Error(t, err, "future versions should fail")
+}
+
+func TestUpcasterRegistry_PublisherStampsVersion(t *testing.T) {
+	capture := &capturePublisher{}
+	reg := pipeline.NewUpcasterRegistry(pipeline.CurrentSchemaVersion)
+
+	require.NoError(t, reg.Publisher(capture).Publish(pipeline.TopicOrdersIngest, message.NewMessage(watermill.NewUUID(), []byte(`{}`))))
+	assert.Equal(t, "1", capture.messages[0].Metadata.Get(pipeline.MetadataSchemaVersion))
+}
@@ -0,0 +1,497 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/internal/clock"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/logging"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// TopicWebhookDLQPrefix prefixes the per-subscriber dead letter topics
+// (webhooks.dlq.<subscriberId>)
+const TopicWebhookDLQPrefix = "webhooks.dlq."
+
+// Webhook request headers
+const (
+	HeaderWebhookSignature = "X-Synapse-Signature"
+	HeaderWebhookEventID   = "X-Synapse-Event-Id"
+	HeaderWebhookEventType = "X-Synapse-Event-Type"
+	// HeaderWebhookTest is set to "true" on test notifications
+	HeaderWebhookTest = "X-Synapse-Test"
+)
+
+// Webhook event types. Orders routed to the rejected destination are
+// announced as order.rejected, all other routed orders as order.routed.
+const (
+	WebhookEventOrderRouted   = "order.routed"
+	WebhookEventOrderRejected = "order.rejected"
+)
+
+// Webhook delivery attempt statuses
+const (
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// MetadataSubscriberID identifies the subscriber of a webhook DLQ message
+const MetadataSubscriberID = "subscriberId"
+
+// WebhookSubscriber receives routed-order notifications
+type WebhookSubscriber struct {
+	ID     string
+	URL    string
+	Secret string
+	// EventTypes limits the notifications sent; empty means all of them
+	EventTypes []string
+}
+
+// Subscribes reports whether the subscriber wants notifications of eventType
+func (s WebhookSubscriber) Subscribes(eventType string) bool {
+	return len(s.EventTypes) == 0 || slices.Contains(s.EventTypes, eventType)
+}
+
+// WebhookSubscribers lists the subscribers to notify for each routed order
+type WebhookSubscribers interface {
+	Subscribers(ctx context.Context) ([]WebhookSubscriber, error)
+}
+
+// WebhookDelivery describes one attempt to deliver a notification
+type WebhookDelivery struct {
+	ID           string
+	SubscriberID string
+	EventID      string
+	EventType    string
+	Attempt      int
+	Status       string
+	// StatusCode is the subscriber's response status, 0 if there was none
+	StatusCode  int
+	Error       string
+	Duration    time.Duration
+	AttemptedAt time.Time
+}
+
+// WebhookDeliveryRecorder keeps the history of delivery attempts
+type WebhookDeliveryRecorder interface {
+	RecordDelivery(ctx context.Context, d WebhookDelivery) error
+}
+
+// StaticWebhookSubscribers is a fixed subscriber list, e.g. from configuration
+type StaticWebhookSubscribers []WebhookSubscriber
+
+// Subscribers returns the configured subscribers
+func (s StaticWebhookSubscribers) Subscribers(context.Context) ([]WebhookSubscriber, error) {
+	return s, nil
+}
+
+// webhookSources combines several subscriber lists
+type webhookSources []WebhookSubscribers
+
+// Subscribers returns the subscribers of every source
+func (s webhookSources) Subscribers(ctx context.Context) ([]WebhookSubscriber, error) {
+	var subs []WebhookSubscriber
+	for _, source := range s {
+		more, err := source.Subscribers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, more...)
+	}
+	return subs, nil
+}
+
+// storedWebhooks are the active subscriptions managed through the API. It
+// records their delivery attempts.
+type storedWebhooks struct {
+	store OrderStore
+}
+
+// Subscribers returns the active stored subscriptions
+func (s storedWebhooks) Subscribers(ctx context.Context) ([]WebhookSubscriber, error) {
+	stored, err := s.store.ListWebhookSubscriptions(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]WebhookSubscriber, 0, len(stored))
+	for _, sub := range stored {
+		subs = append(subs, WebhookSubscriber{ID: sub.ID, URL: sub.URL, Secret: sub.Secret, EventTypes: sub.EventTypes})
+	}
+	return subs, nil
+}
+
+// RecordDelivery stores a delivery attempt
+func (s storedWebhooks) RecordDelivery(ctx context.Context, d WebhookDelivery) error {
+	return s.store.RecordWebhookDelivery(ctx, &store.WebhookDelivery{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriberID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Attempt:        d.Attempt,
+		Status:         d.Status,
+		StatusCode:     d.StatusCode,
+		Error:          d.Error,
+		DurationMs:     int(d.Duration.Milliseconds()),
+		AttemptedAt:    d.AttemptedAt,
+	})
+}
+
+// ParseWebhookSubscribers parses a subscriber spec of the form
+// "id|url|secret,id2|url2|secret2"
+func ParseWebhookSubscribers(spec string) (StaticWebhookSubscribers, error) {
+	var subs StaticWebhookSubscribers
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, "|")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid subscriber entry %q: expected id|url|secret", entry)
+		}
+		if seen[parts[0]] {
+			return nil, fmt.Errorf("duplicate subscriber %s", parts[0])
+		}
+		seen[parts[0]] = true
+		subs = append(subs, WebhookSubscriber{ID: parts[0], URL: parts[1], Secret: parts[2]})
+	}
+	return subs, nil
+}
+
+// webhookSecretPrefix marks generated webhook signing secrets
+const webhookSecretPrefix = "whsec_"
+
+// GenerateWebhookSecret returns a new random signing secret
+func GenerateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generating webhook secret: %w", err)
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// SignWebhook returns the signature header value for body sent at timestamp:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">"
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + ts + ",v1=" + webhookMAC(secret, ts, body)
+}
+
+// VerifyWebhookSignature checks a signature header produced by SignWebhook and
+// rejects timestamps older than tolerance to prevent replays
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig = value
+		}
+	}
+	if ts == "" || sig == "" {
+		return errors.New("malformed webhook signature")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid signature timestamp: %w", err)
+	}
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)) > tolerance {
+		return errors.New("webhook signature expired")
+	}
+	if !hmac.Equal([]byte(sig), []byte(webhookMAC(secret, ts, body))) {
+		return errors.New("webhook signature mismatch")
+	}
+	return nil
+}
+
+func webhookMAC(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookEmitterConfig configures webhook delivery
+type WebhookEmitterConfig struct {
+	Timeout     time.Duration
+	MaxAttempts int
+	Backoff     time.Duration
+	// Recorder, if set, records every delivery attempt
+	Recorder WebhookDeliveryRecorder
+	// Logger logs failed deliveries; the "pipeline" module's by default
+	Logger *slog.Logger
+	// Clock times deliveries and their backoff; the system's by default
+	Clock clock.Clock
+}
+
+// WebhookEmitter POSTs routed-order notifications to subscribers. Deliveries
+// that still fail after retrying are published to the subscriber's DLQ topic
+// so one unavailable subscriber does not hold up the others.
+type WebhookEmitter struct {
+	subscribers WebhookSubscribers
+	publisher   message.Publisher
+	recorder    WebhookDeliveryRecorder
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	log         *slog.Logger
+	clock       clock.Clock
+}
+
+// NewWebhookEmitter creates an emitter that publishes failed deliveries to pub
+func NewWebhookEmitter(subs WebhookSubscribers, pub message.Publisher, cfg WebhookEmitterConfig) *WebhookEmitter {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.Module("pipeline")
+	}
+	return &WebhookEmitter{
+		subscribers: subs,
+		publisher:   pub,
+		recorder:    cfg.Recorder,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     cfg.Backoff,
+		log:         cfg.Logger,
+		clock:       clock.Or(cfg.Clock),
+	}
+}
+
+// Handle notifies the subscribers of the order's event type about a routed
+// order
+func (e *WebhookEmitter) Handle(msg *message.Message) ([]*message.Message, error) {
+	ctx := msg.Context()
+
+	var order map[string]any
+	if err := json.Unmarshal(msg.Payload, &order); err != nil {
+		return nil, fmt.Errorf("unmarshaling order: %w", err)
+	}
+	eventType := WebhookEventOrderRouted
+	if order["destination"] == DestinationRejected {
+		eventType = WebhookEventOrderRejected
+	}
+
+	all, err := e.subscribers.Subscribers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook subscribers: %w", err)
+	}
+	var subs []WebhookSubscriber
+	for _, sub := range all {
+		if sub.Subscribes(eventType) {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) == 0 {
+		return nil, nil
+	}
+
+	notification := generated.WebhookNotification{
+		EventId:    msg.UUID,
+		EventType:  eventType,
+		OccurredAt: e.clock.Now().UTC(),
+		Data:       order,
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling webhook notification: %w", err)
+	}
+
+	for _, sub := range subs {
+		attempts, err := e.deliver(ctx, sub, msg.UUID, eventType, body)
+		if err == nil {
+			continue
+		}
+		e.log.Warn("webhook delivery failed", "subscriberId", sub.ID, "eventId", msg.UUID, "attempts", attempts, "error", err)
+		if err := e.deadLetter(msg, sub, notification, attempts, err); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// deliver POSTs body to the subscriber, retrying transient failures with
+// exponential backoff. It returns the number of attempts made.
+func (e *WebhookEmitter) deliver(ctx context.Context, sub WebhookSubscriber, eventID, eventType string, body []byte) (int, error) {
+	var lastErr error
+	for attempt := 1; attempt <= e.maxAttempts; attempt++ {
+		start := e.clock.Now()
+		statusCode, retryable, err := e.post(ctx, sub, eventID, eventType, body)
+		e.record(ctx, WebhookDelivery{
+			SubscriberID: sub.ID,
+			EventID:      eventID,
+			EventType:    eventType,
+			Attempt:      attempt,
+			StatusCode:   statusCode,
+			Duration:     e.clock.Since(start),
+			AttemptedAt:  start.UTC(),
+		}, err)
+		if err == nil {
+			return attempt, nil
+		}
+		lastErr = err
+		if !retryable || attempt == e.maxAttempts {
+			return attempt, lastErr
+		}
+
+		select {
+		case <-e.clock.After(e.backoff << (attempt - 1)):
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		}
+	}
+	return e.maxAttempts, lastErr
+}
+
+// post makes one delivery attempt and returns the subscriber's response
+// status, or 0 if there was no response
+func (e *WebhookEmitter) post(ctx context.Context, sub WebhookSubscriber, eventID, eventType string, body []byte) (statusCode int, retryable bool, err error) {
+	req, err := newWebhookRequest(ctx, sub, eventID, eventType, body, e.clock.Now())
+	if err != nil {
+		return 0, false, err
+	}
+	return e.send(req)
+}
+
+// newWebhookRequest creates the request delivering body to sub, signed at
+// signedAt
+func newWebhookRequest(ctx context.Context, sub WebhookSubscriber, eventID, eventType string, body []byte, signedAt time.Time) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookEventID, eventID)
+	req.Header.Set(HeaderWebhookEventType, eventType)
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(sub.Secret, signedAt, body))
+	return req, nil
+}
+
+// send makes a delivery request and returns the subscriber's response
+// status, or 0 if there was no response
+func (e *WebhookEmitter) send(req *http.Request) (statusCode int, retryable bool, err error) {
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, true, fmt.Errorf("posting webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+	retryable = resp.StatusCode >= 500 ||
+		resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusRequestTimeout
+	return resp.StatusCode, retryable, fmt.Errorf("subscriber responded %d", resp.StatusCode)
+}
+
+// Test makes a single delivery of notification to sub, marked with the
+// X-Synapse-Test header, and returns the attempt. Test deliveries aren't
+// retried, recorded or dead-lettered, so subscribers can be tried out before
+// they are activated.
+func (e *WebhookEmitter) Test(ctx context.Context, sub WebhookSubscriber, notification generated.WebhookNotification) (WebhookDelivery, error) {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return WebhookDelivery{}, fmt.Errorf("marshaling webhook notification: %w", err)
+	}
+	req, err := newWebhookRequest(ctx, sub, notification.EventId, notification.EventType, body, e.clock.Now())
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	req.Header.Set(HeaderWebhookTest, "true")
+
+	start := e.clock.Now()
+	statusCode, _, err := e.send(req)
+	d := WebhookDelivery{
+		ID:           uuid.NewString(),
+		SubscriberID: sub.ID,
+		EventID:      notification.EventId,
+		EventType:    notification.EventType,
+		Attempt:      1,
+		Status:       WebhookDeliverySucceeded,
+		StatusCode:   statusCode,
+		Duration:     e.clock.Since(start),
+		AttemptedAt:  start.UTC(),
+	}
+	if err != nil {
+		d.Status = WebhookDeliveryFailed
+		d.Error = err.Error()
+	}
+	return d, nil
+}
+
+// record saves a delivery attempt's outcome when a recorder is configured.
+// Failing to record doesn't fail the delivery.
+func (e *WebhookEmitter) record(ctx context.Context, d WebhookDelivery, deliveryErr error) {
+	if e.recorder == nil {
+		return
+	}
+	d.ID = uuid.NewString()
+	d.Status = WebhookDeliverySucceeded
+	if deliveryErr != nil {
+		d.Status = WebhookDeliveryFailed
+		d.Error = deliveryErr.Error()
+	}
+	if err := e.recorder.RecordDelivery(context.WithoutCancel(ctx), d); err != nil {
+		e.log.Warn("recording webhook delivery", "subscriberId", d.SubscriberID, "eventId", d.EventID, "error", err)
+	}
+}
+
+// deadLetter publishes the failed delivery to the subscriber's DLQ. Of the
+// order, the notification keeps its ID and destination: the DLQ is outside
+// the pipeline's field encryption, so customer data isn't copied to it.
+func (e *WebhookEmitter) deadLetter(msg *message.Message, sub WebhookSubscriber, notification generated.WebhookNotification, attempts int, deliveryErr error) error {
+	notification.Data = map[string]any{
+		"orderId":     notification.Data["orderId"],
+		"destination": notification.Data["destination"],
+	}
+	payload, err := json.Marshal(generated.WebhookDeliveryFailedPayload{
+		SubscriberId: sub.ID,
+		Url:          sub.URL,
+		EventId:      msg.UUID,
+		Attempts:     attempts,
+		Error:        deliveryErr.Error(),
+		FailedAt:     e.clock.Now().UTC(),
+		Notification: notification,
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling webhook DLQ entry: %w", err)
+	}
+
+	dlqMsg := message.NewMessage(watermill.NewUUID(), payload)
+	for k, v := range msg.Metadata {
+		dlqMsg.Metadata.Set(k, v)
+	}
+	dlqMsg.Metadata.Set(MetadataSubscriberID, sub.ID)
+
+	if err := e.publisher.Publish(TopicWebhookDLQPrefix+sub.ID, dlqMsg); err != nil {
+		return fmt.Errorf("publishing webhook DLQ entry: %w", err)
+	}
+	return nil
+}
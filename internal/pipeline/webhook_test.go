@@ -0,0 +1,247 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/clock"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+)
+
+func TestWebhookEmitter_DeliversSignedNotifications(t *testing.T) {
+	var calls atomic.Int32
+	var received generated.WebhookNotification
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempt to exercise retries
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := pipeline.VerifyWebhookSignature("secret-a", r.Header.Get(pipeline.HeaderWebhookSignature), body, time.Minute); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ok.Close()
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+
+	subs, err := pipeline.ParseWebhookSubscribers("ok|" + ok.URL + "|secret-a,rejecting|" + rejecting.URL + "|secret-b")
+	require.NoError(t, err)
+
+	dlq := &capturePublisher{}
+	emitter := pipeline.NewWebhookEmitter(subs, dlq, pipeline.WebhookEmitterConfig{
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	})
+
+	msg := message.NewMessage(watermill.NewUUID(), []byte(`{"orderId":"order-1","destination":"fulfillment",`+
+		`"customerId":"cust-secret-1","shippingAddress":{"street":"1 Main St","country":"US"}}`))
+	_, err = emitter.Handle(msg)
+	require.NoError(t, err, "failed deliveries go to the DLQ instead of failing the stage")
+
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, msg.UUID, received.EventId)
+	assert.Equal(t, pipeline.WebhookEventOrderRouted, received.EventType)
+	assert.Equal(t, "order-1", received.Data["orderId"])
+
+	require.Len(t, dlq.messages, 1)
+	assert.Equal(t, pipeline.TopicWebhookDLQPrefix+"rejecting", dlq.topics[0])
+	assert.Equal(t, "rejecting", dlq.messages[0].Metadata.Get(pipeline.MetadataSubscriberID))
+
+	var failed generated.WebhookDeliveryFailedPayload
+	require.NoError(t, json.Unmarshal(dlq.messages[0].Payload, &failed))
+	assert.Equal(t, 1, failed.Attempts, "client errors are not retried")
+	assert.Equal(t, msg.UUID, failed.Notification.EventId)
+
+	// Subscribers get the order, the DLQ only what identifies it
+	assert.Equal(t, "cust-secret-1", received.Data["customerId"])
+	assert.Equal(t, map[string]any{"orderId": "order-1", "destination": "fulfillment"}, failed.Notification.Data)
+	assert.NotContains(t, string(dlq.messages[0].Payload), "cust-secret-1")
+	assert.NotContains(t, string(dlq.messages[0].Payload), "Main St")
+}
+
+// deliveryLog is a WebhookDeliveryRecorder that keeps attempts in memory
+type deliveryLog struct {
+	mu         sync.Mutex
+	deliveries []pipeline.WebhookDelivery
+}
+
+func (l *deliveryLog) RecordDelivery(_ context.Context, d pipeline.WebhookDelivery) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.deliveries = append(l.deliveries, d)
+	return nil
+}
+
+func TestWebhookEmitter_FiltersByEventTypeAndRecordsAttempts(t *testing.T) {
+	var eventTypes []string
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		eventTypes = append(eventTypes, r.Header.Get(pipeline.HeaderWebhookEventType))
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	subs := pipeline.StaticWebhookSubscribers{
+		{ID: "rejections", URL: srv.URL, Secret: "s", EventTypes: []string{pipeline.WebhookEventOrderRejected}},
+		{ID: "routed", URL: srv.URL, Secret: "s", EventTypes: []string{pipeline.WebhookEventOrderRouted}},
+	}
+	log := &deliveryLog{}
+	emitter := pipeline.NewWebhookEmitter(subs, &capturePublisher{}, pipeline.WebhookEmitterConfig{
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		Recorder:    log,
+	})
+
+	msg := message.NewMessage(watermill.NewUUID(), []byte(`{"orderId":"order-1","destination":"rejected"}`))
+	_, err := emitter.Handle(msg)
+	require.NoError(t, err)
+
+	// Only the rejections subscriber is notified, after one retry
+	assert.Equal(t, []string{pipeline.WebhookEventOrderRejected, pipeline.WebhookEventOrderRejected}, eventTypes)
+	require.Len(t, log.deliveries, 2)
+
+	first, second := log.deliveries[0], log.deliveries[1]
+	assert.Equal(t, "rejections", first.SubscriberID)
+	assert.Equal(t, msg.UUID, first.EventID)
+	assert.Equal(t, pipeline.WebhookEventOrderRejected, first.EventType)
+	assert.Equal(t, 1, first.Attempt)
+	assert.Equal(t, pipeline.WebhookDeliveryFailed, first.Status)
+	assert.Equal(t, http.StatusBadGateway, first.StatusCode)
+	assert.NotEmpty(t, first.Error)
+
+	assert.Equal(t, 2, second.Attempt)
+	assert.Equal(t, pipeline.WebhookDeliverySucceeded, second.Status)
+	assert.Equal(t, http.StatusOK, second.StatusCode)
+	assert.Empty(t, second.Error)
+	assert.NotEqual(t, first.ID, second.ID)
+}
+
+// stoppedClock is a clock stopped at now, whose waits return at once and
+// are recorded
+type stoppedClock struct {
+	clock.Clock
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *stoppedClock) Now() time.Time { return c.now }
+
+func (c *stoppedClock) Since(t time.Time) time.Duration { return c.now.Sub(t) }
+
+func (c *stoppedClock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now.Add(d)
+	return ch
+}
+
+func TestWebhookEmitter_BacksOffOnItsClock(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	clk := &stoppedClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	dlq := &capturePublisher{}
+	emitter := pipeline.NewWebhookEmitter(pipeline.StaticWebhookSubscribers{{ID: "down", URL: srv.URL, Secret: "s"}}, dlq, pipeline.WebhookEmitterConfig{
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		Backoff:     time.Hour,
+		Clock:       clk,
+	})
+
+	msg := message.NewMessage(watermill.NewUUID(), []byte(`{"orderId":"order-1","destination":"fulfillment"}`))
+	_, err := emitter.Handle(msg)
+	require.NoError(t, err)
+
+	assert.Equal(t, []time.Duration{time.Hour, 2 * time.Hour}, clk.waits, "the backoff doubles without sleeping")
+	require.Len(t, dlq.messages, 1)
+	var failed generated.WebhookDeliveryFailedPayload
+	require.NoError(t, json.Unmarshal(dlq.messages[0].Payload, &failed))
+	assert.Equal(t, 3, failed.Attempts)
+	assert.Equal(t, clk.now, failed.FailedAt)
+	assert.Equal(t, clk.now, failed.Notification.OccurredAt)
+}
+
+func TestWebhookEmitter_TestSendsOneSignedAttempt(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(pipeline.HeaderWebhookTest) != "true" ||
+			pipeline.VerifyWebhookSignature("secret", r.Header.Get(pipeline.HeaderWebhookSignature), body, time.Minute) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	log := &deliveryLog{}
+	emitter := pipeline.NewWebhookEmitter(nil, nil, pipeline.WebhookEmitterConfig{
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		Recorder:    log,
+	})
+	d, err := emitter.Test(context.Background(), pipeline.WebhookSubscriber{ID: "sub", URL: srv.URL, Secret: "secret"}, generated.WebhookNotification{
+		EventId:    "evt-1",
+		EventType:  pipeline.WebhookEventOrderRouted,
+		OccurredAt: time.Now().UTC(),
+		Data:       map[string]any{"orderId": "order-1"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), calls.Load(), "test deliveries are not retried")
+	assert.Equal(t, pipeline.WebhookDeliveryFailed, d.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, d.StatusCode)
+	assert.Equal(t, "evt-1", d.EventID)
+	assert.Equal(t, 1, d.Attempt)
+	assert.NotEmpty(t, d.Error)
+	assert.Empty(t, log.deliveries, "test deliveries are not recorded")
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"eventId":"evt-1"}`)
+	sig := pipeline.SignWebhook("secret", time.Now(), body)
+
+	assert.NoError(t, pipeline.VerifyWebhookSignature("secret", sig, body, time.Minute))
+	assert.Error(t, pipeline.VerifyWebhookSignature("other", sig, body, time.Minute))
+	assert.Error(t, pipeline.VerifyWebhookSignature("secret", sig, []byte(`{}`), time.Minute))
+
+	stale := pipeline.SignWebhook("secret", time.Now().Add(-time.Hour), body)
+	assert.Error(t, pipeline.VerifyWebhookSignature("secret", stale, body, time.Minute))
+}
+
+func TestParseWebhookSubscribers_RejectsInvalidEntries(t *testing.T) {
+	_, err := pipeline.ParseWebhookSubscribers("missing-secret|http://example.com")
+	assert.Error(t, err)
+
+	_, err = pipeline.ParseWebhookSubscribers("a|http://x|s,a|http://y|s")
+	assert.Error(t, err)
+}
@@ -0,0 +1,338 @@
+// Package problem defines the API's error catalog. Each error maps to an HTTP
+// status, a problem type URI and an RFC 9457 problem+json body; handlers return
+// them and Write renders them.
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/logging"
+	"github.com/synapse/synapse/internal/requestid"
+)
+
+// logger logs the package's records
+var logger = logging.Module("problem")
+
+// BaseURI prefixes every problem type
+const BaseURI = "https://synapse.example.com/problems/"
+
+// Problem types
+const (
+	TypeInvalidJSON        = "invalid-json"
+	TypeInvalidParameter   = "invalid-parameter"
+	TypeValidation         = "validation-error"
+	TypeUnsupportedMedia   = "unsupported-media-type"
+	TypePayloadTooLarge    = "payload-too-large"
+	TypeUnauthorized       = "unauthorized"
+	TypeTokenExpired       = "token-expired"
+	TypeForbidden          = "forbidden"
+	TypeNotFound           = "not-found"
+	TypeMethodNotAllowed   = "method-not-allowed"
+	TypeUpgradeRequired    = "upgrade-required"
+	TypePreconditionFailed = "precondition-failed"
+	TypeRateLimited        = "rate-limit-exceeded"
+	TypeServiceUnavailable = "service-unavailable"
+	TypeGatewayTimeout     = "gateway-timeout"
+	TypeInternal           = "internal-error"
+)
+
+// Error is an API error rendered as problem details
+type Error struct {
+	Status int
+	// Type is the problem type, relative to BaseURI
+	Type   string
+	Title  string
+	Detail string
+	// Errors lists field-level validation failures
+	Errors []generated.ValidationError
+	// Extensions are additional members of the problem body
+	Extensions map[string]any
+	// RetryAfter is sent as the Retry-After header when set
+	RetryAfter time.Duration
+	// Challenge is sent as the WWW-Authenticate header when set
+	Challenge string
+	// ETag is sent as the ETag header when set
+	ETag string
+
+	cause error
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Title, e.Detail, e.cause)
+	}
+	return e.Title + ": " + e.Detail
+}
+
+// Unwrap returns the underlying cause, if any
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// With adds an extension member to the problem body
+func (e *Error) With(key string, value any) *Error {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]any)
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+// InvalidJSON reports a request body that isn't valid JSON
+func InvalidJSON(err error) *Error {
+	return &Error{
+		Status: http.StatusBadRequest,
+		Type:   TypeInvalidJSON,
+		Title:  "Invalid JSON",
+		Detail: fmt.Sprintf("Request body contains invalid JSON: %v", err),
+		cause:  err,
+	}
+}
+
+// InvalidParameter reports a malformed query or path parameter
+func InvalidParameter(detail string) *Error {
+	return &Error{
+		Status: http.StatusBadRequest,
+		Type:   TypeInvalidParameter,
+		Title:  "Invalid Parameter",
+		Detail: detail,
+	}
+}
+
+// Validation reports a request that fails validation, listing each invalid field
+func Validation(detail string, errs ...generated.ValidationError) *Error {
+	return &Error{
+		Status: http.StatusBadRequest,
+		Type:   TypeValidation,
+		Title:  "Validation Error",
+		Detail: detail,
+		Errors: errs,
+	}
+}
+
+// UnsupportedMediaType reports a request body with an unaccepted Content-Type
+func UnsupportedMediaType(detail string) *Error {
+	return &Error{
+		Status: http.StatusUnsupportedMediaType,
+		Type:   TypeUnsupportedMedia,
+		Title:  "Unsupported Media Type",
+		Detail: detail,
+	}
+}
+
+// PayloadTooLarge reports a request body over the limit of maxBytes
+func PayloadTooLarge(maxBytes int64) *Error {
+	e := &Error{
+		Status: http.StatusRequestEntityTooLarge,
+		Type:   TypePayloadTooLarge,
+		Title:  "Content Too Large",
+		Detail: fmt.Sprintf("The request body exceeds the limit of %d bytes", maxBytes),
+	}
+	return e.With("maxBytes", maxBytes)
+}
+
+// Unauthorized reports missing or invalid credentials
+func Unauthorized(detail string) *Error {
+	return &Error{
+		Status:    http.StatusUnauthorized,
+		Type:      TypeUnauthorized,
+		Title:     "Unauthorized",
+		Detail:    detail,
+		Challenge: `Bearer realm="synapse"`,
+	}
+}
+
+// TokenExpired reports an expired bearer token
+func TokenExpired() *Error {
+	return &Error{
+		Status:    http.StatusUnauthorized,
+		Type:      TypeTokenExpired,
+		Title:     "Token Expired",
+		Detail:    "The provided access token has expired",
+		Challenge: `Bearer realm="synapse", error="invalid_token", error_description="Token expired"`,
+	}
+}
+
+// Forbidden reports credentials that lack the permission for a request
+func Forbidden(detail string) *Error {
+	return &Error{
+		Status: http.StatusForbidden,
+		Type:   TypeForbidden,
+		Title:  "Forbidden",
+		Detail: detail,
+	}
+}
+
+// NotFound reports a missing resource
+func NotFound(format string, args ...any) *Error {
+	return &Error{
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Title:  "Not Found",
+		Detail: fmt.Sprintf(format, args...),
+	}
+}
+
+// MethodNotAllowed reports a request method the resource doesn't support
+func MethodNotAllowed(method string) *Error {
+	return &Error{
+		Status: http.StatusMethodNotAllowed,
+		Type:   TypeMethodNotAllowed,
+		Title:  "Method Not Allowed",
+		Detail: fmt.Sprintf("Method %s is not allowed for this resource", method),
+	}
+}
+
+// UpgradeRequired reports a plain HTTP request to an endpoint that only
+// speaks WebSocket
+func UpgradeRequired(detail string) *Error {
+	return &Error{
+		Status: http.StatusUpgradeRequired,
+		Type:   TypeUpgradeRequired,
+		Title:  "Upgrade Required",
+		Detail: detail,
+	}
+}
+
+// Conflict reports a request that conflicts with the resource's current
+// state. problemType and title identify the specific conflict.
+func Conflict(problemType, title, detail string) *Error {
+	return &Error{
+		Status: http.StatusConflict,
+		Type:   problemType,
+		Title:  title,
+		Detail: detail,
+	}
+}
+
+// PreconditionFailed reports a conditional request, e.g. one with If-Match,
+// whose precondition doesn't hold for the resource at currentETag
+func PreconditionFailed(detail, currentETag string) *Error {
+	e := &Error{
+		Status: http.StatusPreconditionFailed,
+		Type:   TypePreconditionFailed,
+		Title:  "Precondition Failed",
+		Detail: detail,
+		ETag:   currentETag,
+	}
+	return e.With("currentETag", currentETag)
+}
+
+// RateLimited reports an exceeded rate limit; clients may retry after retryAfter
+func RateLimited(detail string, retryAfter time.Duration) *Error {
+	e := &Error{
+		Status:     http.StatusTooManyRequests,
+		Type:       TypeRateLimited,
+		Title:      "Too Many Requests",
+		Detail:     detail,
+		RetryAfter: retryAfter,
+	}
+	return e.With("retryAfter", retryAfterSeconds(retryAfter))
+}
+
+// Upstream reports that a dependency (e.g. "postgres", "nats") failed. The
+// cause is logged but not exposed to clients.
+func Upstream(dependency string, err error) *Error {
+	return &Error{
+		Status:     http.StatusServiceUnavailable,
+		Type:       TypeServiceUnavailable,
+		Title:      "Service Unavailable",
+		Detail:     fmt.Sprintf("Dependency %s is unavailable. Please try again later.", dependency),
+		RetryAfter: 5 * time.Second,
+		cause:      err,
+	}
+}
+
+// GatewayTimeout reports a request that didn't complete within its route's
+// timeout
+func GatewayTimeout() *Error {
+	return &Error{
+		Status: http.StatusGatewayTimeout,
+		Type:   TypeGatewayTimeout,
+		Title:  "Gateway Timeout",
+		Detail: "The request didn't complete in time. Please try again later.",
+	}
+}
+
+// Internal reports an unexpected error. The cause is logged but not exposed.
+func Internal(err error) *Error {
+	return &Error{
+		Status: http.StatusInternalServerError,
+		Type:   TypeInternal,
+		Title:  "Internal Server Error",
+		Detail: "An unexpected error occurred. Please try again later.",
+		cause:  err,
+	}
+}
+
+// From returns err as an *Error, treating anything outside the catalog as internal
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal(err)
+}
+
+// Write renders err as a problem+json response for r. Server errors are
+// logged. The body carries r's request ID, if any, so it can be quoted when
+// reporting the failure. Server errors of requests whose deadline passed,
+// e.g. a query cancelled by it, are reported as GatewayTimeout.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+	if e.Status >= http.StatusInternalServerError && errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		e = GatewayTimeout()
+	}
+	if e.Status >= http.StatusInternalServerError {
+		logger.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "status", e.Status, "error", err)
+	}
+
+	if e.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(e.RetryAfter)))
+	}
+	if e.Challenge != "" {
+		w.Header().Set("WWW-Authenticate", e.Challenge)
+	}
+	if e.ETag != "" {
+		w.Header().Set("ETag", e.ETag)
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(e.Status)
+	_ = json.NewEncoder(w).Encode(e.body(r.URL.Path, requestid.FromContext(r.Context())))
+}
+
+// body builds the problem details object, with extensions as top-level members
+func (e *Error) body(instance, requestID string) map[string]any {
+	body := make(map[string]any, len(e.Extensions)+7)
+	for k, v := range e.Extensions {
+		body[k] = v
+	}
+
+	body["type"] = BaseURI + e.Type
+	body["title"] = e.Title
+	body["status"] = e.Status
+	if e.Detail != "" {
+		body["detail"] = e.Detail
+	}
+	body["instance"] = instance
+	if requestID != "" {
+		body["requestId"] = requestID
+	}
+
+	if len(e.Errors) > 0 {
+		body["errors"] = e.Errors
+	}
+	return body
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,122 @@
+package problem_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/requestid"
+)
+
+func write(t *testing.T, err error) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	problem.Write(rec, httptest.NewRequest("GET", "/api/v1/orders/123", nil), err)
+
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec, body
+}
+
+func TestWrite_Catalog(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		typ    string
+	}{
+		{"unauthorized", problem.Unauthorized("API key required"), http.StatusUnauthorized, "unauthorized"},
+		{"token expired", problem.TokenExpired(), http.StatusUnauthorized, "token-expired"},
+		{"forbidden", problem.Forbidden("admin scope required"), http.StatusForbidden, "forbidden"},
+		{"not found", problem.NotFound("Order with ID %s not found", "123"), http.StatusNotFound, "not-found"},
+		{"method not allowed", problem.MethodNotAllowed("PUT"), http.StatusMethodNotAllowed, "method-not-allowed"},
+		{"upgrade required", problem.UpgradeRequired("connect with WebSocket"), http.StatusUpgradeRequired, "upgrade-required"},
+		{"conflict", problem.Conflict("order-not-cancellable", "Order Cannot Be Cancelled", "routed"), http.StatusConflict, "order-not-cancellable"},
+		{"precondition failed", problem.PreconditionFailed("modified", `"2"`), http.StatusPreconditionFailed, "precondition-failed"},
+		{"validation", problem.Validation("bad request"), http.StatusBadRequest, "validation-error"},
+		{"invalid parameter", problem.InvalidParameter("limit must be positive"), http.StatusBadRequest, "invalid-parameter"},
+		{"payload too large", problem.PayloadTooLarge(1 << 20), http.StatusRequestEntityTooLarge, "payload-too-large"},
+		{"rate limited", problem.RateLimited("slow down", time.Minute), http.StatusTooManyRequests, "rate-limit-exceeded"},
+		{"upstream", problem.Upstream("postgres", errors.New("connection refused")), http.StatusServiceUnavailable, "service-unavailable"},
+		{"gateway timeout", problem.GatewayTimeout(), http.StatusGatewayTimeout, "gateway-timeout"},
+		{"wrapped", fmt.Errorf("loading order: %w", problem.NotFound("gone")), http.StatusNotFound, "not-found"},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, "internal-error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, body := write(t, tt.err)
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, problem.BaseURI+tt.typ, body["type"])
+			assert.Equal(t, float64(tt.status), body["status"])
+			assert.Equal(t, "/api/v1/orders/123", body["instance"])
+		})
+	}
+}
+
+func TestWrite_RequestID(t *testing.T) {
+	_, body := write(t, problem.NotFound("gone"))
+	assert.NotContains(t, body, "requestId")
+
+	req := httptest.NewRequest("GET", "/api/v1/orders/123", nil)
+	req = req.WithContext(requestid.NewContext(req.Context(), "7c4d89e0-3b4a-4f2a-9c1d-8e7f6a5b4c3d"))
+	rec := httptest.NewRecorder()
+	problem.Write(rec, req, problem.NotFound("gone"))
+	var withID map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &withID))
+	assert.Equal(t, "7c4d89e0-3b4a-4f2a-9c1d-8e7f6a5b4c3d", withID["requestId"])
+}
+
+func TestWrite_ServerErrorsPastDeadlineAreTimeouts(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	req := httptest.NewRequest("GET", "/api/v1/orders/123", nil).WithContext(ctx)
+
+	rec := httptest.NewRecorder()
+	problem.Write(rec, req, problem.Upstream("postgres", errors.New("pq: canceling statement due to user request")))
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+
+	// Client errors are reported as they are
+	rec = httptest.NewRecorder()
+	problem.Write(rec, req, problem.NotFound("gone"))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestWrite_HidesInternalCauses(t *testing.T) {
+	_, body := write(t, errors.New("pq: password authentication failed"))
+	assert.NotContains(t, body["detail"], "password")
+
+	_, body = write(t, problem.Upstream("postgres", errors.New("dial tcp 10.0.0.5:5432")))
+	assert.NotContains(t, body["detail"], "10.0.0.5")
+}
+
+func TestWrite_ExtensionsAndHeaders(t *testing.T) {
+	rec, body := write(t, problem.RateLimited("slow down", 90*time.Second))
+	assert.Equal(t, "90", rec.Header().Get("Retry-After"))
+	assert.Equal(t, float64(90), body["retryAfter"])
+
+	rec, _ = write(t, problem.Unauthorized("API key required"))
+	assert.Equal(t, `Bearer realm="synapse"`, rec.Header().Get("WWW-Authenticate"))
+
+	_, body = write(t, problem.Conflict("order-not-cancellable", "Order Cannot Be Cancelled", "routed").
+		With("currentStatus", "routed"))
+	assert.Equal(t, "routed", body["currentStatus"])
+
+	rec, body = write(t, problem.PreconditionFailed("modified", `"3"`))
+	assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+	assert.Equal(t, `"3"`, body["currentETag"])
+
+	_, body = write(t, problem.Validation("bad", generated.ValidationError{Field: "items[0].quantity", Code: "min_value", Message: "too small"}))
+	require.Len(t, body["errors"], 1)
+}
@@ -0,0 +1,110 @@
+// Package ratelimit implements per-client token-bucket rate limits shared
+// across replicas through Redis.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/synapse/synapse/internal/keyspace"
+)
+
+// Result is the outcome of taking a token from a client's bucket
+type Result struct {
+	Allowed bool
+	// Limit is the bucket size (the burst)
+	Limit int
+	// Remaining is the number of whole tokens left
+	Remaining int
+	// RetryAfter is how long until a token is available, when not allowed
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// Limiter rate-limits requests by client key
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// tokenBucket refills KEYS[1] at ARGV[1] tokens per second up to ARGV[2]
+// tokens and takes one if available. Redis' clock is used so replicas agree.
+// Returns {allowed, tokens left}.
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// Redis is a Limiter storing token buckets in Redis
+type Redis struct {
+	client redis.UniversalClient
+	keys   keyspace.Keyspace
+	rate   float64
+	burst  int
+}
+
+// NewRedis creates a limiter allowing rate requests per second per client,
+// with bursts of up to burst requests, keeping the buckets in keys
+func NewRedis(client redis.UniversalClient, keys keyspace.Keyspace, rate float64, burst int) *Redis {
+	return &Redis{client: client, keys: keys, rate: rate, burst: burst}
+}
+
+// Allow takes a token from key's bucket
+func (l *Redis) Allow(ctx context.Context, key string) (Result, error) {
+	res, err := tokenBucket.Run(ctx, l.client, []string{l.keys.Key("ratelimit", key)}, l.rate, l.burst).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("running rate limit script: %w", err)
+	}
+	if len(res) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", res)
+	}
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("parsing remaining tokens %q: %w", tokensStr, err)
+	}
+	return l.result(allowed == 1, tokens), nil
+}
+
+func (l *Redis) result(allowed bool, tokens float64) Result {
+	r := Result{
+		Allowed:    allowed,
+		Limit:      l.burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: l.refillTime(float64(l.burst) - tokens),
+	}
+	if !allowed {
+		r.RetryAfter = l.refillTime(1 - tokens)
+	}
+	return r
+}
+
+// refillTime is how long it takes to refill n tokens
+func (l *Redis) refillTime(n float64) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(n / l.rate * float64(time.Second))
+}
@@ -0,0 +1,54 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/ratelimit"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestRedis_TokenBucket(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.StartContainers(ctx, t, &testutil.ContainerConfig{Services: []testutil.Service{testutil.ServiceRedis}})
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	// 1 token per second, bursts of 3
+	limiter := ratelimit.NewRedis(infra.Redis, infra.Keyspace(), 1, 3)
+
+	for i := 2; i >= 0; i-- {
+		res, err := limiter.Allow(ctx, "client-a")
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, err := limiter.Allow(ctx, "client-a")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+	assert.LessOrEqual(t, res.RetryAfter, time.Second)
+
+	// Other clients are unaffected
+	res, err = limiter.Allow(ctx, "client-b")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// The bucket refills over time
+	require.NoError(t, testutil.WaitForCondition(ctx, func() bool {
+		res, err = limiter.Allow(ctx, "client-a")
+		return err == nil && res.Allowed
+	}), "bucket refills")
+}
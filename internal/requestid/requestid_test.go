@@ -0,0 +1,40 @@
+package requestid_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/requestid"
+)
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, requestid.FromContext(ctx))
+	assert.Equal(t, "req-1", requestid.FromContext(requestid.NewContext(ctx, "req-1")))
+}
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(requestid.NewLogHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+
+	logged := func() map[string]any {
+		t.Helper()
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+		buf.Reset()
+		return rec
+	}
+
+	logger.InfoContext(requestid.NewContext(context.Background(), "req-1"), "handled")
+	rec := logged()
+	assert.Equal(t, "req-1", rec["requestId"])
+	assert.Equal(t, "test", rec["component"])
+
+	logger.InfoContext(context.Background(), "background work")
+	assert.NotContains(t, logged(), "requestId")
+}
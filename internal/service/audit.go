@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// auditMethods are the HTTP methods of audited calls
+var auditMethods = []string{"POST", "PUT", "PATCH", "DELETE"}
+
+// ListAuditParams selects a page of audit entries
+type ListAuditParams struct {
+	Filter store.AuditFilter
+	Limit  int
+	Offset int
+	After  *store.Cursor
+}
+
+// AuditPage is a page of audit entries
+type AuditPage struct {
+	Entries []generated.AuditEntry
+	Total   int
+	HasMore bool
+	// NextCursor is the cursor of the following page, empty on the last one
+	NextCursor string
+}
+
+// RecordAudit stores an audit entry, giving it an ID
+func (s *Service) RecordAudit(ctx context.Context, e *store.AuditEntry) error {
+	e.ID = uuid.NewString()
+	if err := s.orders.RecordAudit(ctx, e); err != nil {
+		return problem.Upstream("postgres", err)
+	}
+	return nil
+}
+
+// ListAuditEntries returns a page of audit entries matching p.Filter, newest
+// first
+func (s *Service) ListAuditEntries(ctx context.Context, p ListAuditParams) (*AuditPage, error) {
+	if err := ValidateAuditFilter(p.Filter); err != nil {
+		return nil, problem.InvalidParameter(err.Error())
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	entries, err := s.orders.ListAuditEntries(ctx, store.ListAuditParams{
+		AuditFilter: p.Filter,
+		Limit:       p.Limit + 1,
+		Offset:      p.Offset,
+		After:       p.After,
+	})
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	page := &AuditPage{HasMore: len(entries) > p.Limit}
+	if page.HasMore {
+		entries = entries[:p.Limit]
+		last := entries[len(entries)-1]
+		page.NextCursor = EncodeCursor(store.Cursor{Time: last.OccurredAt, Key: strconv.FormatInt(last.Seq, 10)})
+	}
+
+	if page.Total, err = s.orders.CountAuditEntries(ctx, p.Filter); err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	page.Entries = make([]generated.AuditEntry, 0, len(entries))
+	for i := range entries {
+		page.Entries = append(page.Entries, auditEntry(&entries[i]))
+	}
+	return page, nil
+}
+
+// ValidateAuditFilter checks the values of an audit log filter
+func ValidateAuditFilter(f store.AuditFilter) error {
+	for _, method := range f.Methods {
+		if !slices.Contains(auditMethods, method) {
+			return fmt.Errorf("method must be one of POST, PUT, PATCH, DELETE")
+		}
+	}
+	switch generated.AuditOutcome(f.Outcome) {
+	case "", generated.AuditOutcomeSuccess, generated.AuditOutcomeFailure:
+	default:
+		return fmt.Errorf("outcome must be one of success, failure")
+	}
+	if f.OccurredAfter != nil && f.OccurredBefore != nil && !f.OccurredAfter.Before(*f.OccurredBefore) {
+		return fmt.Errorf("occurredAfter must be before occurredBefore")
+	}
+	return nil
+}
+
+func auditEntry(e *store.AuditEntry) generated.AuditEntry {
+	entry := generated.AuditEntry{
+		AuditId:    e.ID,
+		RequestId:  e.RequestID,
+		Method:     e.Method,
+		Route:      e.Route,
+		Path:       e.Path,
+		Params:     e.Params,
+		Query:      e.Query,
+		BodyBytes:  e.BodyBytes,
+		StatusCode: e.StatusCode,
+		Outcome:    generated.AuditOutcomeSuccess,
+		DurationMs: e.DurationMs,
+		OccurredAt: e.OccurredAt,
+	}
+	if e.StatusCode >= 400 {
+		entry.Outcome = generated.AuditOutcomeFailure
+	}
+	if e.ActorID != "" {
+		entry.Actor = &generated.AuditActor{Id: e.ActorID, Method: e.ActorMethod, Tenant: e.Tenant}
+	}
+	if entry.Params == nil {
+		entry.Params = map[string]string{}
+	}
+	return entry
+}
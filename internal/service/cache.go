@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+
+	"github.com/synapse/synapse/internal/cache"
+)
+
+// Response cache keys
+const cacheKeyStages = "stages"
+
+func stageCacheKey(stageID string) string { return "stage:" + stageID }
+func orderCacheKey(orderID string) string { return "order:" + orderID }
+
+// cacheInvalidator evicts cached reads when the pipeline changes what they
+// were read from
+type cacheInvalidator struct {
+	cache *cache.Cache
+}
+
+func (c cacheInvalidator) OrderChanged(ctx context.Context, orderID string) {
+	c.cache.Delete(ctx, orderCacheKey(orderID))
+}
+
+func (c cacheInvalidator) StageChanged(ctx context.Context, stageID string) {
+	c.cache.Delete(ctx, cacheKeyStages, stageCacheKey(stageID))
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/synapse/synapse/internal/store"
+)
+
+// cursorToken is the JSON form of an opaque page cursor
+type cursorToken struct {
+	Time time.Time `json:"t"`
+	Key  string    `json:"k"`
+}
+
+// EncodeCursor returns the opaque cursor for the page after c
+func EncodeCursor(c store.Cursor) string {
+	data, _ := json.Marshal(cursorToken{Time: c.Time, Key: c.Key})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by EncodeCursor
+func DecodeCursor(s string) (*store.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("cursor is malformed")
+	}
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil || token.Time.IsZero() || token.Key == "" {
+		return nil, errors.New("cursor is malformed")
+	}
+	return &store.Cursor{Time: token.Time, Key: token.Key}, nil
+}
@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// dlqStages are the values accepted by the DLQ stage filter. The emit stage
+// is included even when webhooks are off, as its messages stay in the DLQ.
+var dlqStages = []string{"validate", "enrich", "route", "emit"}
+
+// dlqJobBatchSize is how many DLQ items a bulk retry requeues between
+// progress saves
+const dlqJobBatchSize = 100
+
+// typeDLQItemRequeued is the problem type of a retry that lost the race to
+// another one
+const typeDLQItemRequeued = "dlq-item-requeued"
+
+// ListDLQParams selects a page of dead-lettered messages
+type ListDLQParams struct {
+	Filter store.DLQFilter
+	Limit  int
+	Offset int
+	After  *store.Cursor
+}
+
+// DLQPage is a page of dead-lettered messages
+type DLQPage struct {
+	Items   []generated.DLQItem
+	Total   int
+	HasMore bool
+	// NextCursor is the cursor of the following page, empty on the last one
+	NextCursor string
+}
+
+// ListDLQItems returns a page of dead-lettered messages matching p.Filter,
+// most recent failure first
+func (s *Service) ListDLQItems(ctx context.Context, p ListDLQParams) (*DLQPage, error) {
+	if err := ValidateDLQFilter(p.Filter); err != nil {
+		return nil, problem.InvalidParameter(err.Error())
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	items, err := s.orders.ListDLQItems(ctx, store.ListDLQParams{
+		DLQFilter: p.Filter,
+		Limit:     p.Limit + 1,
+		Offset:    p.Offset,
+		After:     p.After,
+	})
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	page := &DLQPage{HasMore: len(items) > p.Limit}
+	if page.HasMore {
+		items = items[:p.Limit]
+		last := items[len(items)-1]
+		page.NextCursor = EncodeCursor(store.Cursor{Time: last.FailedAt, Key: last.EventID})
+	}
+
+	if page.Total, err = s.orders.CountDLQItems(ctx, p.Filter); err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	page.Items = make([]generated.DLQItem, 0, len(items))
+	for i := range items {
+		page.Items = append(page.Items, dlqItem(&items[i]))
+	}
+	return page, nil
+}
+
+// RetryDLQItem requeues a dead-lettered message to the stage it failed at,
+// or to fromStage when given
+func (s *Service) RetryDLQItem(ctx context.Context, eventID string, req *generated.DLQRetryRequest) (*generated.DLQRetryResponse, error) {
+	if req.FromStage != "" && !s.pipeline.HasStage(req.FromStage) {
+		return nil, problem.Validation("The retry request is invalid", generated.ValidationError{
+			Field:         "fromStage",
+			Code:          "invalid_value",
+			Message:       "fromStage must be a pipeline stage",
+			RejectedValue: req.FromStage,
+		})
+	}
+
+	item, err := s.orders.GetDLQItem(ctx, eventID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, problem.NotFound("DLQ item %s not found", eventID)
+	}
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	if !pipeline.Retryable(item.ErrorType) {
+		return nil, problem.Conflict("dlq-item-not-retryable", "DLQ Item Cannot Be Retried",
+			fmt.Sprintf("Message %s failed with a %s error and would fail again", eventID, item.ErrorType))
+	}
+
+	stage := item.Stage
+	if req.FromStage != "" {
+		stage = req.FromStage
+	}
+	if err := s.requeueDLQItem(ctx, item, stage); err != nil {
+		return nil, err
+	}
+	return &generated.DLQRetryResponse{
+		EventId:   item.EventID,
+		OrderId:   item.OrderID,
+		Status:    "requeued",
+		FromStage: stage,
+		Message:   "Message requeued to the " + stage + " stage",
+	}, nil
+}
+
+// requeueDLQItem takes a message off the DLQ and publishes it to stage,
+// putting it back if publishing fails. It is taken off first so that, should
+// the stage dead-letter it again straight away, it stays on the DLQ.
+func (s *Service) requeueDLQItem(ctx context.Context, item *store.DLQItem, stage string) error {
+	err := s.orders.RequeueDLQItem(ctx, item.EventID, s.clock.Now().UTC())
+	if errors.Is(err, store.ErrNotFound) {
+		return problem.Conflict(typeDLQItemRequeued, "DLQ Item Already Requeued",
+			fmt.Sprintf("Message %s has already been requeued", item.EventID))
+	}
+	if err != nil {
+		return problem.Upstream("postgres", err)
+	}
+
+	err = s.pipeline.Requeue(stage, item.EventID, item.Payload, item.Metadata)
+	if err == nil {
+		return nil
+	}
+	if restoreErr := s.orders.RestoreDLQItem(context.WithoutCancel(ctx), item.EventID, item.LastRetryAt); restoreErr != nil {
+		logger.ErrorContext(ctx, "restoring DLQ item", "eventId", item.EventID, "error", restoreErr)
+	}
+	if errors.Is(err, pipeline.ErrStageNotRunning) {
+		return problem.Conflict("stage-not-running", "Stage Not Running",
+			fmt.Sprintf("Stage %s is paused; resume it before retrying", stage))
+	}
+	return problem.Upstream("pipeline", err)
+}
+
+// StartDLQRetry starts a job requeuing the DLQ items matching f to the
+// stages they failed at. Items that would fail again are skipped. One retry
+// job per filter runs at a time across replicas; items another retry
+// requeued first are skipped.
+func (s *Service) StartDLQRetry(ctx context.Context, f store.DLQFilter) (*generated.DLQJob, error) {
+	lock, err := s.lockDLQRetry(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	job, err := s.createDLQJob(ctx, generated.DLQJobActionRetry, f)
+	var resp *generated.DLQJob
+	if err == nil {
+		resp, err = dlqJobResponse(job)
+	}
+	if err != nil {
+		if lock != nil {
+			s.unlock(context.WithoutCancel(ctx), lock)
+		}
+		return nil, err
+	}
+	go s.runDLQJob(context.WithoutCancel(ctx), job, func(ctx context.Context) error {
+		if lock == nil {
+			return s.retryDLQItems(ctx, job, f)
+		}
+		defer s.unlock(ctx, lock)
+		ctx, cancel := lock.Guard(ctx)
+		defer cancel()
+		err := s.retryDLQItems(ctx, job, f)
+		if errors.Is(context.Cause(ctx), infra.ErrLockLost) {
+			return fmt.Errorf("retry stopped: %w", infra.ErrLockLost)
+		}
+		return err
+	})
+	return resp, nil
+}
+
+// lockDLQRetry takes the lock held by a running DLQ retry job of f; without
+// a locker it returns no lock
+func (s *Service) lockDLQRetry(ctx context.Context, f store.DLQFilter) (*infra.Lock, error) {
+	if s.locker == nil {
+		return nil, nil
+	}
+	data, _ := json.Marshal(dlqFilter(f))
+	sum := sha256.Sum256(data)
+	lock, err := s.locker.Acquire(ctx, "dlq-retry:"+hex.EncodeToString(sum[:16]))
+	if errors.Is(err, infra.ErrLocked) {
+		return nil, problem.Conflict("dlq-retry-in-progress", "DLQ Retry In Progress",
+			"A DLQ retry with the same filter is running; poll its job and retry once it has completed")
+	}
+	if err != nil {
+		return nil, problem.Upstream("redis", err)
+	}
+	return lock, nil
+}
+
+// unlock releases a lock, logging a failure: the lock then expires by itself
+func (s *Service) unlock(ctx context.Context, lock *infra.Lock) {
+	if err := lock.Release(ctx); err != nil {
+		logger.WarnContext(ctx, "releasing lock", "lock", lock.Name, "error", err)
+	}
+}
+
+// StartDLQPurge starts a job deleting the DLQ items matching f. As a purge
+// can't be undone, the caller must present the confirmation token for f,
+// which a first, unconfirmed call returns alongside the number of items
+// that would be deleted.
+func (s *Service) StartDLQPurge(ctx context.Context, f store.DLQFilter, confirmationToken string) (*generated.DLQJob, error) {
+	if err := ValidateDLQFilter(f); err != nil {
+		return nil, problem.InvalidParameter(err.Error())
+	}
+	if token := DLQPurgeToken(f); confirmationToken != token {
+		matched, err := s.orders.CountDLQItems(ctx, f)
+		if err != nil {
+			return nil, problem.Upstream("postgres", err)
+		}
+		detail := fmt.Sprintf("Purging deletes %d DLQ items; repeat the request with confirmationToken=%s to confirm", matched, token)
+		if confirmationToken != "" {
+			detail = "The confirmation token doesn't match the filter. " + detail
+		}
+		return nil, problem.Conflict("dlq-purge-unconfirmed", "DLQ Purge Not Confirmed", detail).
+			With("confirmationToken", token).
+			With("matched", matched)
+	}
+
+	job, err := s.createDLQJob(ctx, generated.DLQJobActionPurge, f)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := dlqJobResponse(job)
+	if err != nil {
+		return nil, err
+	}
+	go s.runDLQJob(context.WithoutCancel(ctx), job, func(ctx context.Context) error {
+		n, err := s.orders.DeleteDLQItems(ctx, f)
+		job.Processed, job.Succeeded = n, n
+		return err
+	})
+	return resp, nil
+}
+
+// DLQPurgeToken returns the token confirming a purge of the DLQ items
+// matching f. It only depends on the filter, so it guards against purging
+// by accident rather than authorizing the purge.
+func DLQPurgeToken(f store.DLQFilter) string {
+	data, _ := json.Marshal(dlqFilter(f))
+	sum := sha256.Sum256(append([]byte("dlq-purge:"), data...))
+	return hex.EncodeToString(sum[:16])
+}
+
+// DLQJob returns a bulk DLQ job's progress
+func (s *Service) DLQJob(ctx context.Context, jobID string) (*generated.DLQJob, error) {
+	job, err := s.orders.GetDLQJob(ctx, jobID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, problem.NotFound("DLQ job %s not found", jobID)
+	}
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	return dlqJobResponse(job)
+}
+
+// createDLQJob validates f and records a job for it
+func (s *Service) createDLQJob(ctx context.Context, action generated.DLQJobAction, f store.DLQFilter) (*store.DLQJob, error) {
+	if err := ValidateDLQFilter(f); err != nil {
+		return nil, problem.InvalidParameter(err.Error())
+	}
+	filter, err := json.Marshal(dlqFilter(f))
+	if err != nil {
+		return nil, problem.Internal(fmt.Errorf("encoding DLQ filter: %w", err))
+	}
+	matched, err := s.orders.CountDLQItems(ctx, f)
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+
+	now := s.clock.Now().UTC()
+	job := &store.DLQJob{
+		ID:        uuid.New().String(),
+		Action:    string(action),
+		Status:    string(generated.DLQJobStatusProcessing),
+		Filter:    filter,
+		Matched:   matched,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.orders.CreateDLQJob(ctx, job); err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	return job, nil
+}
+
+// runDLQJob runs a job's work and records its outcome
+func (s *Service) runDLQJob(ctx context.Context, job *store.DLQJob, work func(context.Context) error) {
+	runErr := work(ctx)
+
+	now := s.clock.Now().UTC()
+	job.Status = string(generated.DLQJobStatusCompleted)
+	if runErr != nil {
+		job.Status = string(generated.DLQJobStatusFailed)
+		job.Error = runErr.Error()
+		logger.ErrorContext(ctx, "DLQ job failed", "jobId", job.ID, "action", job.Action, "error", runErr)
+	} else {
+		logger.InfoContext(ctx, "DLQ job completed", "jobId", job.ID, "action", job.Action,
+			"succeeded", job.Succeeded, "skipped", job.Skipped, "failed", job.Failed)
+	}
+	job.UpdatedAt = now
+	job.CompletedAt = &now
+	if err := s.orders.UpdateDLQJob(ctx, job); err != nil {
+		logger.ErrorContext(ctx, "saving DLQ job", "jobId", job.ID, "error", err)
+	}
+}
+
+// retryDLQItems requeues the DLQ items matching f a batch at a time, saving
+// the job's progress after each batch. Items dead-lettered again while the
+// job runs fail later than its first page, so they aren't retried twice.
+func (s *Service) retryDLQItems(ctx context.Context, job *store.DLQJob, f store.DLQFilter) error {
+	var after *store.Cursor
+	for {
+		items, err := s.orders.ListDLQItems(ctx, store.ListDLQParams{DLQFilter: f, Limit: dlqJobBatchSize, After: after})
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			item := &items[i]
+			job.Processed++
+			if !pipeline.Retryable(item.ErrorType) {
+				job.Skipped++
+				continue
+			}
+			switch err := s.requeueDLQItem(ctx, item, item.Stage); {
+			case err == nil:
+				job.Succeeded++
+			case problem.From(err).Type == typeDLQItemRequeued:
+				job.Skipped++
+			default:
+				job.Failed++
+				logger.WarnContext(ctx, "requeuing DLQ item", "jobId", job.ID, "eventId", item.EventID, "error", err)
+			}
+		}
+		if len(items) < dlqJobBatchSize {
+			return nil
+		}
+
+		last := items[len(items)-1]
+		after = &store.Cursor{Time: last.FailedAt, Key: last.EventID}
+		job.UpdatedAt = s.clock.Now().UTC()
+		if err := s.orders.UpdateDLQJob(ctx, job); err != nil {
+			logger.WarnContext(ctx, "saving DLQ job progress", "jobId", job.ID, "error", err)
+		}
+	}
+}
+
+// ValidateDLQFilter checks the values of a DLQ list filter
+func ValidateDLQFilter(f store.DLQFilter) error {
+	for _, stage := range f.Stages {
+		if !slices.Contains(dlqStages, stage) {
+			return fmt.Errorf("failedStage %q is not a pipeline stage", stage)
+		}
+	}
+	for _, errorType := range f.ErrorTypes {
+		if !slices.Contains(pipeline.ErrorTypes, errorType) {
+			return fmt.Errorf("errorType %q is not a pipeline error type", errorType)
+		}
+	}
+	if f.FailedAfter != nil && f.FailedBefore != nil && !f.FailedAfter.Before(*f.FailedBefore) {
+		return fmt.Errorf("failedAfter must be before failedBefore")
+	}
+	return nil
+}
+
+// dlqItem converts a stored DLQ item to the API representation
+func dlqItem(item *store.DLQItem) generated.DLQItem {
+	return generated.DLQItem{
+		CanRetry: pipeline.Retryable(item.ErrorType),
+		Error: map[string]any{
+			"code":    item.ErrorType,
+			"message": item.ErrorMessage,
+		},
+		EventId:        item.EventID,
+		FailedAt:       item.FailedAt,
+		FailedStage:    item.Stage,
+		LastRetryAt:    item.LastRetryAt,
+		OrderId:        item.OrderID,
+		PayloadPreview: item.Preview,
+		RetryCount:     item.RetryCount,
+	}
+}
+
+// dlqFilter converts a DLQ filter to the API representation
+func dlqFilter(f store.DLQFilter) generated.DLQFilter {
+	return generated.DLQFilter{
+		ErrorType:    f.ErrorTypes,
+		FailedAfter:  f.FailedAfter,
+		FailedBefore: f.FailedBefore,
+		FailedStage:  f.Stages,
+	}
+}
+
+// dlqJobResponse converts a stored DLQ job to the API representation
+func dlqJobResponse(j *store.DLQJob) (*generated.DLQJob, error) {
+	resp := &generated.DLQJob{
+		Action:      generated.DLQJobAction(j.Action),
+		CompletedAt: j.CompletedAt,
+		CreatedAt:   j.CreatedAt,
+		Error:       j.Error,
+		Failed:      j.Failed,
+		JobId:       j.ID,
+		Matched:     j.Matched,
+		Processed:   j.Processed,
+		Skipped:     j.Skipped,
+		Status:      generated.DLQJobStatus(j.Status),
+		Succeeded:   j.Succeeded,
+		UpdatedAt:   j.UpdatedAt,
+	}
+	if len(j.Filter) > 0 {
+		if err := json.Unmarshal(j.Filter, &resp.Filter); err != nil {
+			return nil, problem.Internal(fmt.Errorf("decoding filter of DLQ job %s: %w", j.ID, err))
+		}
+	}
+	return resp, nil
+}
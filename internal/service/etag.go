@@ -0,0 +1,33 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+)
+
+// OrderETag returns the strong entity tag of an order at version
+func OrderETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ParseIfMatch returns the order versions an If-Match header value accepts.
+// An empty value or "*" accepts any version and yields nil. If-Match compares
+// strongly (RFC 9110 §13.1.1), so weak tags and tags that aren't order ETags
+// accept no version.
+func ParseIfMatch(header string) []int64 {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil
+	}
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64); err == nil && v > 0 {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
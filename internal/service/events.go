@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// ListEventsParams selects a page of an order's events
+type ListEventsParams struct {
+	Limit      int
+	Offset     int
+	Descending bool
+	After      *store.Cursor
+}
+
+// EventPage is a page of an order's events
+type EventPage struct {
+	Events  []generated.OrderEvent
+	Total   int
+	HasMore bool
+	// NextCursor is the cursor of the following page, empty on the last one
+	NextCursor string
+}
+
+// ListOrderEvents returns a page of an order's event history, oldest first
+// unless p.Descending is set
+func (s *Service) ListOrderEvents(ctx context.Context, orderID string, p ListEventsParams) (*EventPage, error) {
+	if _, err := s.orders.GetOrder(ctx, orderID); errors.Is(err, store.ErrNotFound) {
+		return nil, problem.NotFound("Order with ID %s not found", orderID)
+	} else if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	events, err := s.orders.ListEvents(ctx, orderID, store.ListEventsParams{
+		Limit:      p.Limit + 1,
+		Offset:     p.Offset,
+		Descending: p.Descending,
+		After:      p.After,
+	})
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	page := &EventPage{HasMore: len(events) > p.Limit}
+	if page.HasMore {
+		events = events[:p.Limit]
+		last := events[len(events)-1]
+		page.NextCursor = EncodeCursor(store.Cursor{
+			Time: last.OccurredAt,
+			Key:  strconv.FormatInt(last.Seq, 10),
+		})
+	}
+
+	if page.Total, err = s.orders.CountEvents(ctx, orderID); err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+
+	page.Events = make([]generated.OrderEvent, 0, len(events))
+	for i := range events {
+		event, err := orderEvent(&events[i])
+		if err != nil {
+			return nil, err
+		}
+		page.Events = append(page.Events, event)
+	}
+	return page, nil
+}
+
+// orderEvent converts an event history row to its API representation
+func orderEvent(e *store.Event) (generated.OrderEvent, error) {
+	event := generated.OrderEvent{
+		EventId:    e.ID,
+		EventType:  e.Type,
+		Stage:      e.Stage,
+		Status:     e.Status,
+		Timestamp:  e.OccurredAt,
+		DurationMs: e.DurationMs,
+	}
+	if len(e.Metadata) > 0 {
+		if err := json.Unmarshal(e.Metadata, &event.Metadata); err != nil {
+			return event, fmt.Errorf("decoding metadata of event %s: %w", e.ID, err)
+		}
+	}
+	if len(e.Error) > 0 {
+		if err := json.Unmarshal(e.Error, &event.Error); err != nil {
+			return event, fmt.Errorf("decoding error of event %s: %w", e.ID, err)
+		}
+	}
+	return event, nil
+}
@@ -0,0 +1,362 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// exportBatchSize is how many rows streamed exports fetch at a time
+const exportBatchSize = 500
+
+// Formats of background order exports
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// DefaultExportRetention is how long exported files can be downloaded, when
+// infra.Config doesn't set it
+const DefaultExportRetention = 24 * time.Hour
+
+// PurgeExpiredExports deletes the exports past their retention, then again
+// every interval until ctx is done. One replica is enough to run it.
+func (s *Service) PurgeExpiredExports(ctx context.Context, interval time.Duration) {
+	for {
+		if n, err := s.orders.DeleteExpiredExportJobs(ctx, s.clock.Now().UTC()); err != nil {
+			if ctx.Err() == nil {
+				logger.WarnContext(ctx, "deleting expired order exports", "error", err)
+			}
+		} else if n > 0 {
+			logger.InfoContext(ctx, "expired order exports deleted", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(interval):
+		}
+	}
+}
+
+// OrderCSVHeader is the header row of order CSV exports
+var OrderCSVHeader = []string{"orderId", "customerId", "status", "totalAmount", "currency", "itemCount", "createdAt"}
+
+// DLQCSVHeader is the header row of DLQ CSV exports
+var DLQCSVHeader = []string{"eventId", "orderId", "failedStage", "errorCode", "errorMessage", "retryCount", "canRetry", "failedAt", "lastRetryAt"}
+
+// EachOrder calls fn with every order matching filter, newest first. Orders
+// are fetched in batches, so no more than one batch is held at a time. It
+// stops at the first error fn returns.
+func (s *Service) EachOrder(ctx context.Context, filter store.OrderFilter, fn func(*generated.OrderSummary) error) error {
+	if err := ValidateOrderFilter(filter); err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+
+	var after *store.Cursor
+	for {
+		orders, err := s.orders.ListOrders(ctx, store.ListOrdersParams{OrderFilter: filter, Limit: exportBatchSize, After: after})
+		if err != nil {
+			return listError(err)
+		}
+		for i := range orders {
+			summary := orderSummary(&orders[i])
+			if err := fn(&summary); err != nil {
+				return err
+			}
+		}
+		if len(orders) < exportBatchSize {
+			return nil
+		}
+		last := orders[len(orders)-1]
+		after = &store.Cursor{Time: last.CreatedAt, Key: last.ID}
+	}
+}
+
+// EachDLQItem calls fn with every dead-lettered message matching filter, most
+// recent failure first, fetching them in batches like EachOrder
+func (s *Service) EachDLQItem(ctx context.Context, filter store.DLQFilter, fn func(*generated.DLQItem) error) error {
+	if err := ValidateDLQFilter(filter); err != nil {
+		return problem.InvalidParameter(err.Error())
+	}
+
+	var after *store.Cursor
+	for {
+		items, err := s.orders.ListDLQItems(ctx, store.ListDLQParams{DLQFilter: filter, Limit: exportBatchSize, After: after})
+		if err != nil {
+			return problem.Upstream("postgres", err)
+		}
+		for i := range items {
+			item := dlqItem(&items[i])
+			if err := fn(&item); err != nil {
+				return err
+			}
+		}
+		if len(items) < exportBatchSize {
+			return nil
+		}
+		last := items[len(items)-1]
+		after = &store.Cursor{Time: last.FailedAt, Key: last.EventID}
+	}
+}
+
+// OrderCSVRow renders an order as a row of an order CSV export
+func OrderCSVRow(o *generated.OrderSummary) []string {
+	return []string{
+		o.OrderId,
+		csvText(o.CustomerId),
+		string(o.Status),
+		strconv.FormatFloat(o.TotalAmount, 'f', -1, 64),
+		csvText(o.Currency),
+		strconv.Itoa(o.ItemCount),
+		csvTime(&o.CreatedAt),
+	}
+}
+
+// DLQCSVRow renders a DLQ item as a row of a DLQ CSV export
+func DLQCSVRow(item *generated.DLQItem) []string {
+	code, _ := item.Error["code"].(string)
+	message, _ := item.Error["message"].(string)
+	return []string{
+		item.EventId,
+		item.OrderId,
+		item.FailedStage,
+		code,
+		csvText(message),
+		strconv.Itoa(item.RetryCount),
+		strconv.FormatBool(item.CanRetry),
+		csvTime(&item.FailedAt),
+		csvTime(item.LastRetryAt),
+	}
+}
+
+// csvText escapes text that spreadsheets would otherwise evaluate as a
+// formula
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// StartOrderExport starts a job exporting the orders matching f, newest
+// first, as CSV or NDJSON (format defaults to CSV). Once the job completes its
+// file can be downloaded until it expires; expired exports are deleted as new
+// ones start.
+func (s *Service) StartOrderExport(ctx context.Context, f store.OrderFilter, format string) (*generated.OrderExportJob, error) {
+	if format == "" {
+		format = ExportFormatCSV
+	}
+	if format != ExportFormatCSV && format != ExportFormatNDJSON {
+		return nil, problem.InvalidParameter(fmt.Sprintf("format must be one of %s, %s", ExportFormatCSV, ExportFormatNDJSON))
+	}
+	if err := ValidateOrderFilter(f); err != nil {
+		return nil, problem.InvalidParameter(err.Error())
+	}
+	filter, err := json.Marshal(orderExportFilter(f))
+	if err != nil {
+		return nil, problem.Internal(fmt.Errorf("encoding order filter: %w", err))
+	}
+
+	now := s.clock.Now().UTC()
+	matched, err := s.orders.CountOrders(ctx, f)
+	if err != nil {
+		return nil, listError(err)
+	}
+	job := &store.ExportJob{
+		ID:        uuid.New().String(),
+		Status:    string(generated.OrderExportJobStatusProcessing),
+		Format:    format,
+		Filter:    filter,
+		Matched:   matched,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.orders.CreateExportJob(ctx, job); err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	resp, err := exportJobResponse(job)
+	if err != nil {
+		return nil, err
+	}
+	go s.runOrderExport(context.WithoutCancel(ctx), job, f)
+	return resp, nil
+}
+
+// OrderExportJob returns an order export's progress
+func (s *Service) OrderExportJob(ctx context.Context, jobID string) (*generated.OrderExportJob, error) {
+	job, err := s.exportJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return exportJobResponse(job)
+}
+
+// OrderExportFile returns the file a completed order export exported, and
+// its format
+func (s *Service) OrderExportFile(ctx context.Context, jobID string) ([]byte, string, error) {
+	job, err := s.exportJob(ctx, jobID)
+	if err != nil {
+		return nil, "", err
+	}
+	switch generated.OrderExportJobStatus(job.Status) {
+	case generated.OrderExportJobStatusProcessing:
+		return nil, "", problem.Conflict("export-not-ready", "Export Not Ready",
+			fmt.Sprintf("Export job %s is still processing", jobID))
+	case generated.OrderExportJobStatusFailed:
+		return nil, "", problem.Conflict("export-failed", "Export Failed",
+			fmt.Sprintf("Export job %s failed: %s", jobID, job.Error))
+	}
+
+	content, err := s.orders.ExportFile(ctx, jobID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, "", problem.NotFound("Export job %s not found", jobID)
+	}
+	if err != nil {
+		return nil, "", problem.Upstream("postgres", err)
+	}
+	return content, job.Format, nil
+}
+
+// exportJob returns an order export, treating expired ones as deleted
+func (s *Service) exportJob(ctx context.Context, jobID string) (*store.ExportJob, error) {
+	job, err := s.orders.GetExportJob(ctx, jobID)
+	if errors.Is(err, store.ErrNotFound) || err == nil && job.ExpiresAt != nil && !s.clock.Now().Before(*job.ExpiresAt) {
+		return nil, problem.NotFound("Export job %s not found", jobID)
+	}
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	return job, nil
+}
+
+// runOrderExport exports the orders matching f and saves the file, which is
+// built in memory, with the job
+func (s *Service) runOrderExport(ctx context.Context, job *store.ExportJob, f store.OrderFilter) {
+	var buf bytes.Buffer
+	runErr := s.writeOrderExport(ctx, &buf, job, f)
+
+	now := s.clock.Now().UTC()
+	job.UpdatedAt = now
+	job.CompletedAt = &now
+	if runErr != nil {
+		job.Status = string(generated.OrderExportJobStatusFailed)
+		job.Error = runErr.Error()
+		logger.ErrorContext(ctx, "order export failed", "jobId", job.ID, "error", runErr)
+		if err := s.orders.UpdateExportJob(ctx, job); err != nil {
+			logger.ErrorContext(ctx, "saving export job", "jobId", job.ID, "error", err)
+		}
+		return
+	}
+
+	expiresAt := now.Add(s.exportRetention)
+	job.Status = string(generated.OrderExportJobStatusCompleted)
+	job.SizeBytes = int64(buf.Len())
+	job.ExpiresAt = &expiresAt
+	logger.InfoContext(ctx, "order export completed", "jobId", job.ID, "format", job.Format,
+		"exported", job.Exported, "bytes", job.SizeBytes)
+	if err := s.orders.SaveExportFile(ctx, job, buf.Bytes()); err != nil {
+		logger.ErrorContext(ctx, "saving export job", "jobId", job.ID, "error", err)
+	}
+}
+
+// writeOrderExport writes the orders matching f to w in the job's format,
+// saving the job's progress after every batch
+func (s *Service) writeOrderExport(ctx context.Context, w io.Writer, job *store.ExportJob, f store.OrderFilter) error {
+	var (
+		cw    *csv.Writer
+		write func(*generated.OrderSummary) error
+	)
+	if job.Format == ExportFormatNDJSON {
+		enc := json.NewEncoder(w)
+		write = func(o *generated.OrderSummary) error { return enc.Encode(o) }
+	} else {
+		cw = csv.NewWriter(w)
+		if err := cw.Write(OrderCSVHeader); err != nil {
+			return err
+		}
+		write = func(o *generated.OrderSummary) error { return cw.Write(OrderCSVRow(o)) }
+	}
+
+	err := s.EachOrder(ctx, f, func(o *generated.OrderSummary) error {
+		if err := write(o); err != nil {
+			return err
+		}
+		job.Exported++
+		if job.Exported%exportBatchSize == 0 {
+			job.UpdatedAt = s.clock.Now().UTC()
+			if err := s.orders.UpdateExportJob(ctx, job); err != nil {
+				logger.WarnContext(ctx, "saving export job progress", "jobId", job.ID, "error", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if cw != nil {
+		cw.Flush()
+		return cw.Error()
+	}
+	return nil
+}
+
+// orderExportFilter converts an order filter to the API representation
+func orderExportFilter(f store.OrderFilter) generated.OrderExportFilter {
+	return generated.OrderExportFilter{
+		CreatedAfter:    f.CreatedAfter,
+		CreatedBefore:   f.CreatedBefore,
+		Currency:        f.Currency,
+		CustomerId:      f.CustomerID,
+		CustomerTier:    f.CustomerTier,
+		Destination:     f.Destination,
+		MaxAmount:       f.MaxAmount,
+		MinAmount:       f.MinAmount,
+		Q:               f.Text,
+		ShippingCountry: f.ShippingCountry,
+		Sku:             f.SKU,
+		Status:          f.Statuses,
+	}
+}
+
+// exportJobResponse converts a stored export job to the API representation
+func exportJobResponse(j *store.ExportJob) (*generated.OrderExportJob, error) {
+	resp := &generated.OrderExportJob{
+		CompletedAt: j.CompletedAt,
+		CreatedAt:   j.CreatedAt,
+		Error:       j.Error,
+		ExpiresAt:   j.ExpiresAt,
+		Exported:    j.Exported,
+		Format:      j.Format,
+		JobId:       j.ID,
+		Matched:     j.Matched,
+		SizeBytes:   j.SizeBytes,
+		Status:      generated.OrderExportJobStatus(j.Status),
+		UpdatedAt:   j.UpdatedAt,
+	}
+	if len(j.Filter) > 0 {
+		if err := json.Unmarshal(j.Filter, &resp.Filter); err != nil {
+			return nil, problem.Internal(fmt.Errorf("decoding filter of export job %s: %w", j.ID, err))
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// orderStatuses are the values accepted by the status filter
+var orderStatuses = map[generated.OrderStatus]bool{
+	generated.OrderStatusAccepted:   true,
+	generated.OrderStatusValidating: true,
+	generated.OrderStatusValidated:  true,
+	generated.OrderStatusEnriching:  true,
+	generated.OrderStatusEnriched:   true,
+	generated.OrderStatusRouting:    true,
+	generated.OrderStatusRouted:     true,
+	generated.OrderStatusFailed:     true,
+	generated.OrderStatusCancelled:  true,
+}
+
+// routingDestinations are the values accepted by the destination filter
+var routingDestinations = map[string]bool{
+	pipeline.DestinationFulfillment:  true,
+	pipeline.DestinationManualReview: true,
+	pipeline.DestinationRejected:     true,
+}
+
+// customerTiers are the values accepted by the customerTier search criterion
+var customerTiers = map[string]bool{
+	"bronze":   true,
+	"silver":   true,
+	"gold":     true,
+	"platinum": true,
+}
+
+var (
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	countryPattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// Longest accepted SKU and free-text search criteria, in characters
+const (
+	maxSKULength        = 50
+	maxSearchTextLength = 200
+)
+
+// ListOrdersParams selects a page of orders
+type ListOrdersParams struct {
+	Filter store.OrderFilter
+	Limit  int
+	Offset int
+	After  *store.Cursor
+}
+
+// OrderPage is a page of orders
+type OrderPage struct {
+	Orders  []generated.OrderSummary
+	Total   int
+	HasMore bool
+	// NextCursor is the cursor of the following page, empty on the last one
+	NextCursor string
+}
+
+// IngestOrder assigns the order an ID and publishes it to the pipeline
+func (s *Service) IngestOrder(ctx context.Context, req *generated.OrderCreateRequest) (*generated.OrderAcceptedResponse, error) {
+	orderID := uuid.New().String()
+
+	if err := s.pipeline.IngestOrder(ctx, orderID, req); err != nil {
+		return nil, problem.Upstream("pipeline", err)
+	}
+	return &generated.OrderAcceptedResponse{
+		OrderId: orderID,
+		Status:  "accepted",
+		Message: "Order accepted for processing",
+	}, nil
+}
+
+// GetOrder returns an order's current state. It may come from the cache,
+// from which the pipeline evicts the order whenever it changes.
+func (s *Service) GetOrder(ctx context.Context, orderID string) (*generated.OrderResponse, error) {
+	var resp *generated.OrderResponse
+	if s.cache.Get(ctx, orderCacheKey(orderID), &resp) {
+		return resp, nil
+	}
+	order, err := s.orders.GetOrder(ctx, orderID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, problem.NotFound("Order with ID %s not found", orderID)
+	}
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	if resp, err = orderResponse(order); err != nil {
+		return nil, err
+	}
+	s.cache.Set(ctx, orderCacheKey(orderID), resp, s.ordersTTL)
+	return resp, nil
+}
+
+// ListOrders returns a page of orders matching p.Filter, newest first
+func (s *Service) ListOrders(ctx context.Context, p ListOrdersParams) (*OrderPage, error) {
+	orders, page, err := s.listOrders(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	page.Orders = make([]generated.OrderSummary, 0, len(orders))
+	for i := range orders {
+		page.Orders = append(page.Orders, orderSummary(&orders[i]))
+	}
+	return page, nil
+}
+
+// ListOrderDetails is ListOrders with each order's full representation
+// instead of its summary. The page's Orders are left empty.
+func (s *Service) ListOrderDetails(ctx context.Context, p ListOrdersParams) ([]*generated.OrderResponse, *OrderPage, error) {
+	orders, page, err := s.listOrders(ctx, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	details := make([]*generated.OrderResponse, 0, len(orders))
+	for i := range orders {
+		order, err := orderResponse(&orders[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		details = append(details, order)
+	}
+	return details, page, nil
+}
+
+// listOrders fetches the projection rows of a page of orders
+func (s *Service) listOrders(ctx context.Context, p ListOrdersParams) ([]store.Order, *OrderPage, error) {
+	if err := ValidateOrderFilter(p.Filter); err != nil {
+		return nil, nil, problem.InvalidParameter(err.Error())
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	orders, err := s.orders.ListOrders(ctx, store.ListOrdersParams{
+		OrderFilter: p.Filter,
+		Limit:       p.Limit + 1,
+		Offset:      p.Offset,
+		After:       p.After,
+	})
+	if err != nil {
+		return nil, nil, listError(err)
+	}
+	page := &OrderPage{HasMore: len(orders) > p.Limit}
+	if page.HasMore {
+		orders = orders[:p.Limit]
+		last := orders[len(orders)-1]
+		page.NextCursor = EncodeCursor(store.Cursor{Time: last.CreatedAt, Key: last.ID})
+	}
+
+	if page.Total, err = s.orders.CountOrders(ctx, p.Filter); err != nil {
+		return nil, nil, listError(err)
+	}
+	return orders, page, nil
+}
+
+// listError is the problem listing or counting orders failed with
+func listError(err error) error {
+	if errors.Is(err, store.ErrUnsupportedFilter) {
+		return problem.InvalidParameter(err.Error())
+	}
+	return problem.Upstream("postgres", err)
+}
+
+// CancelOrder cancels an order that hasn't been routed yet. Cancelling an
+// already cancelled order succeeds without publishing a second cancellation.
+// With ifVersions set, as returned by ParseIfMatch, an order at any other
+// version isn't cancelled and the precondition fails.
+func (s *Service) CancelOrder(ctx context.Context, orderID string, ifVersions []int64) (*generated.OrderCancelledResponse, error) {
+	order, cancelled, err := s.orders.CancelOrder(ctx, orderID, s.clock.Now().UTC(), ifVersions)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return nil, problem.NotFound("Order with ID %s not found", orderID)
+	case errors.Is(err, store.ErrVersionMismatch):
+		etag := OrderETag(order.Version)
+		return nil, problem.PreconditionFailed(
+			fmt.Sprintf("Order has been modified; its current ETag is %s", etag), etag)
+	case errors.Is(err, store.ErrNotCancellable):
+		return nil, problem.Conflict("order-not-cancellable", "Order Cannot Be Cancelled",
+			fmt.Sprintf("Order is %s and can no longer be cancelled", order.Status)).
+			With("currentStatus", order.Status)
+	case err != nil:
+		return nil, problem.Upstream("postgres", err)
+	}
+	s.cache.Delete(ctx, orderCacheKey(orderID))
+
+	message := "Order was already cancelled"
+	if cancelled {
+		message = "Order cancelled"
+		if err := s.pipeline.PublishCancellation(ctx, order.ID, order.PreviousStatus, *order.CancelledAt); err != nil {
+			return nil, problem.Upstream("pipeline", err)
+		}
+	}
+	return &generated.OrderCancelledResponse{
+		OrderId:        order.ID,
+		Status:         "cancelled",
+		PreviousStatus: order.PreviousStatus,
+		CancelledAt:    *order.CancelledAt,
+		Message:        message,
+	}, nil
+}
+
+// ValidateOrderFilter checks the values of an order list filter
+func ValidateOrderFilter(f store.OrderFilter) error {
+	for _, status := range f.Statuses {
+		if !orderStatuses[generated.OrderStatus(status)] {
+			return fmt.Errorf("status %q is not a valid order status", status)
+		}
+	}
+	if f.CustomerID != "" {
+		if _, err := uuid.Parse(f.CustomerID); err != nil {
+			return fmt.Errorf("customerId must be a UUID")
+		}
+	}
+	if f.Destination != "" && !routingDestinations[f.Destination] {
+		return fmt.Errorf("destination must be one of fulfillment, manual-review, rejected")
+	}
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && !f.CreatedAfter.Before(*f.CreatedBefore) {
+		return fmt.Errorf("createdAfter must be before createdBefore")
+	}
+
+	if utf8.RuneCountInString(f.SKU) > maxSKULength {
+		return fmt.Errorf("sku must be at most %d characters", maxSKULength)
+	}
+	if f.MinAmount != nil && *f.MinAmount < 0 {
+		return fmt.Errorf("minAmount must not be negative")
+	}
+	if f.MaxAmount != nil && *f.MaxAmount < 0 {
+		return fmt.Errorf("maxAmount must not be negative")
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil && *f.MinAmount > *f.MaxAmount {
+		return fmt.Errorf("minAmount must not be greater than maxAmount")
+	}
+	if f.Currency != "" && !currencyPattern.MatchString(f.Currency) {
+		return fmt.Errorf("currency must be an ISO 4217 code")
+	}
+	if f.CustomerTier != "" && !customerTiers[f.CustomerTier] {
+		return fmt.Errorf("customerTier must be one of bronze, silver, gold, platinum")
+	}
+	if f.ShippingCountry != "" && !countryPattern.MatchString(f.ShippingCountry) {
+		return fmt.Errorf("shippingCountry must be an ISO 3166-1 alpha-2 code")
+	}
+	if utf8.RuneCountInString(f.Text) > maxSearchTextLength {
+		return fmt.Errorf("q must be at most %d characters", maxSearchTextLength)
+	}
+	return nil
+}
+
+// orderLinks returns the hypermedia links of an order resource
+func orderLinks(orderID string) *generated.OrderLinks {
+	self := "/api/v1/orders/" + orderID
+	return &generated.OrderLinks{
+		Self:   self,
+		Events: self + "/events",
+	}
+}
+
+// orderSummary converts a projection row to its list representation
+func orderSummary(o *store.Order) generated.OrderSummary {
+	return generated.OrderSummary{
+		OrderId:     o.ID,
+		CustomerId:  o.CustomerID,
+		Status:      generated.OrderStatus(o.Status),
+		TotalAmount: o.TotalAmount,
+		Currency:    o.Currency,
+		ItemCount:   o.ItemCount,
+		CreatedAt:   o.CreatedAt,
+		Links:       orderLinks(o.ID),
+	}
+}
+
+// orderResponse converts a projection row to the full order representation
+func orderResponse(o *store.Order) (*generated.OrderResponse, error) {
+	resp := &generated.OrderResponse{
+		OrderId:      o.ID,
+		CustomerId:   o.CustomerID,
+		Status:       generated.OrderStatus(o.Status),
+		CurrentStage: o.CurrentStage,
+		TotalAmount:  o.TotalAmount,
+		Currency:     o.Currency,
+		CreatedAt:    o.CreatedAt,
+		UpdatedAt:    o.UpdatedAt,
+		Version:      o.Version,
+		Links:        orderLinks(o.ID),
+	}
+
+	if err := json.Unmarshal(o.Items, &resp.Items); err != nil {
+		return nil, fmt.Errorf("decoding items of order %s: %w", o.ID, err)
+	}
+	if len(o.ShippingAddress) > 0 {
+		if err := json.Unmarshal(o.ShippingAddress, &resp.ShippingAddress); err != nil {
+			return nil, fmt.Errorf("decoding shipping address of order %s: %w", o.ID, err)
+		}
+	}
+	if len(o.BillingAddress) > 0 {
+		if err := json.Unmarshal(o.BillingAddress, &resp.BillingAddress); err != nil {
+			return nil, fmt.Errorf("decoding billing address of order %s: %w", o.ID, err)
+		}
+	}
+	if len(o.Metadata) > 0 {
+		if err := json.Unmarshal(o.Metadata, &resp.Metadata); err != nil {
+			return nil, fmt.Errorf("decoding metadata of order %s: %w", o.ID, err)
+		}
+	}
+	if len(o.Enrichment) > 0 {
+		if err := json.Unmarshal(o.Enrichment, &resp.Enrichment); err != nil {
+			return nil, fmt.Errorf("decoding enrichment of order %s: %w", o.ID, err)
+		}
+	}
+	if o.RoutedAt != nil {
+		resp.Routing = &generated.OrderRouting{
+			Destination: o.Destination,
+			Reason:      o.RoutingReason,
+			RoutedAt:    *o.RoutedAt,
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// ListPipelineErrorsParams selects a page of pipeline errors
+type ListPipelineErrorsParams struct {
+	Filter store.PipelineErrorFilter
+	Limit  int
+	Offset int
+	After  *store.Cursor
+}
+
+// PipelineErrorPage is a page of pipeline errors
+type PipelineErrorPage struct {
+	Items   []generated.PipelineError
+	Total   int
+	HasMore bool
+	// NextCursor is the cursor of the following page, empty on the last one
+	NextCursor string
+}
+
+// ListPipelineErrors returns a page of the stage failures matching p.Filter,
+// most recent first. Each links to the order it concerns and, while the
+// failed message is on the DLQ, to its retry.
+func (s *Service) ListPipelineErrors(ctx context.Context, p ListPipelineErrorsParams) (*PipelineErrorPage, error) {
+	if err := ValidatePipelineErrorFilter(p.Filter); err != nil {
+		return nil, problem.InvalidParameter(err.Error())
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	errs, err := s.orders.ListPipelineErrors(ctx, store.ListPipelineErrorsParams{
+		PipelineErrorFilter: p.Filter,
+		Limit:               p.Limit + 1,
+		Offset:              p.Offset,
+		After:               p.After,
+	})
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	page := &PipelineErrorPage{HasMore: len(errs) > p.Limit}
+	if page.HasMore {
+		errs = errs[:p.Limit]
+		last := errs[len(errs)-1]
+		page.NextCursor = EncodeCursor(store.Cursor{Time: last.OccurredAt, Key: last.ID})
+	}
+
+	if page.Total, err = s.orders.CountPipelineErrors(ctx, p.Filter); err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	page.Items = make([]generated.PipelineError, 0, len(errs))
+	for i := range errs {
+		page.Items = append(page.Items, pipelineError(&errs[i]))
+	}
+	return page, nil
+}
+
+// ValidatePipelineErrorFilter checks the values of a pipeline error filter.
+// Errors occur at the stages messages can be dead-lettered at.
+func ValidatePipelineErrorFilter(f store.PipelineErrorFilter) error {
+	for _, stage := range f.Stages {
+		if !slices.Contains(dlqStages, stage) {
+			return fmt.Errorf("stage %q is not a pipeline stage", stage)
+		}
+	}
+	for _, errorType := range f.ErrorTypes {
+		if !slices.Contains(pipeline.ErrorTypes, errorType) {
+			return fmt.Errorf("errorType %q is not a pipeline error type", errorType)
+		}
+	}
+	if f.OccurredAfter != nil && f.OccurredBefore != nil && !f.OccurredAfter.Before(*f.OccurredBefore) {
+		return fmt.Errorf("occurredAfter must be before occurredBefore")
+	}
+	return nil
+}
+
+// pipelineError converts a stored pipeline error to the API representation
+func pipelineError(e *store.PipelineError) generated.PipelineError {
+	resp := generated.PipelineError{
+		ErrorId:    e.ID,
+		ErrorType:  e.ErrorType,
+		EventId:    e.EventID,
+		InDlq:      e.InDLQ,
+		Message:    e.Message,
+		OccurredAt: e.OccurredAt,
+		OrderId:    e.OrderID,
+		StageId:    e.Stage,
+	}
+	if e.OrderID != "" {
+		resp.Links.Order = "/api/v1/orders/" + e.OrderID
+	}
+	if e.InDLQ {
+		resp.Links.DlqRetry = "/api/v1/pipeline/dlq/" + e.EventID + "/retry"
+	}
+	return resp
+}
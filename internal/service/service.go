@@ -0,0 +1,82 @@
+// Package service implements the order and pipeline operations shared by the
+// HTTP, gRPC and GraphQL APIs. Operations return problem errors; each transport
+// renders them its own way.
+package service
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/synapse/synapse/internal/cache"
+	"github.com/synapse/synapse/internal/clock"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/logging"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// logger logs the package's records
+var logger = logging.Module("service")
+
+// Pagination limits for order lists
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Store is the data the service reads and writes. *store.Store keeps it in
+// Postgres.
+type Store interface {
+	store.OrderRepository
+	store.EventRepository
+	store.DLQRepository
+	store.AuditRepository
+	store.ExportRepository
+	store.StatsRepository
+}
+
+var _ Store = (*store.Store)(nil)
+
+// Service runs orders through the pipeline and queries their projection
+type Service struct {
+	nats     *nats.Conn
+	pipeline *pipeline.Runner
+	orders   Store
+	// cache holds recent stage and order reads; nil when caching is off
+	cache     *cache.Cache
+	stagesTTL time.Duration
+	ordersTTL time.Duration
+	// exportRetention is how long exported files can be downloaded
+	exportRetention time.Duration
+	// locker keeps bulk DLQ retries from overlapping; nil without infra
+	locker *infra.Locker
+	// clock stamps changes and schedules the export purge
+	clock clock.Clock
+}
+
+// New creates a Service. Order status updates can only be watched when
+// infra has a NATS connection. When infra.Config enables the response cache,
+// stage and order reads are cached in Redis until they expire or the
+// pipeline changes them. Order exports can be downloaded for
+// DefaultExportRetention unless infra.Config sets it.
+func New(infra *infra.Infra, pipeline *pipeline.Runner, orders Store) *Service {
+	s := &Service{pipeline: pipeline, orders: orders, exportRetention: DefaultExportRetention, clock: clock.System}
+	if infra == nil {
+		return s
+	}
+	s.clock = clock.Or(infra.Clock)
+	s.nats = infra.NATS
+	s.locker = infra.Locker()
+	if cfg := infra.Config; cfg != nil && cfg.ExportRetentionHours > 0 {
+		s.exportRetention = time.Duration(cfg.ExportRetentionHours) * time.Hour
+	}
+	if cfg := infra.Config; cfg != nil && cfg.ResponseCacheEnabled && pipeline != nil {
+		s.cache = cache.New(infra.Redis, infra.Keyspace())
+		s.stagesTTL = time.Duration(cfg.ResponseCacheStagesTTLSeconds) * time.Second
+		s.ordersTTL = time.Duration(cfg.ResponseCacheOrdersTTLSeconds) * time.Second
+		if s.cache != nil {
+			pipeline.AddChangeListener(cacheInvalidator{s.cache})
+		}
+	}
+	return s
+}
@@ -0,0 +1,285 @@
+package service_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/service"
+	"github.com/synapse/synapse/internal/store"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	c := store.Cursor{Time: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC), Key: "order-1"}
+	got, err := service.DecodeCursor(service.EncodeCursor(c))
+	require.NoError(t, err)
+	assert.True(t, c.Time.Equal(got.Time))
+	assert.Equal(t, c.Key, got.Key)
+
+	_, err = service.DecodeCursor("not a cursor")
+	assert.Error(t, err)
+}
+
+func TestParseIfMatch(t *testing.T) {
+	assert.Equal(t, `"7"`, service.OrderETag(7))
+	assert.Nil(t, service.ParseIfMatch(""))
+	assert.Nil(t, service.ParseIfMatch("*"))
+	assert.Equal(t, []int64{7}, service.ParseIfMatch(service.OrderETag(7)))
+	assert.Equal(t, []int64{3, 4}, service.ParseIfMatch(`"3", W/"5", "4"`))
+	assert.Empty(t, service.ParseIfMatch(`W/"5"`))
+	assert.NotNil(t, service.ParseIfMatch(`"abc"`))
+}
+
+func TestValidateOrderFilter(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	low, high, negative := 10.0, 100.0, -1.0
+
+	tests := []struct {
+		name    string
+		filter  store.OrderFilter
+		wantErr string
+	}{
+		{name: "empty", filter: store.OrderFilter{}},
+		{name: "valid", filter: store.OrderFilter{
+			Statuses:      []string{"routed", "failed"},
+			CustomerID:    "550e8400-e29b-41d4-a716-446655440000",
+			Destination:   "manual-review",
+			CreatedAfter:  &earlier,
+			CreatedBefore: &now,
+		}},
+		{name: "unknown status", filter: store.OrderFilter{Statuses: []string{"shipped"}}, wantErr: `status "shipped" is not a valid order status`},
+		{name: "customer not a UUID", filter: store.OrderFilter{CustomerID: "cust-1"}, wantErr: "customerId must be a UUID"},
+		{name: "unknown destination", filter: store.OrderFilter{Destination: "warehouse"}, wantErr: "destination must be one of"},
+		{name: "empty range", filter: store.OrderFilter{CreatedAfter: &now, CreatedBefore: &earlier}, wantErr: "createdAfter must be before createdBefore"},
+		{name: "valid search", filter: store.OrderFilter{
+			SKU:             "WIDGET-001",
+			MinAmount:       &low,
+			MaxAmount:       &high,
+			Currency:        "EUR",
+			CustomerTier:    "gold",
+			ShippingCountry: "DE",
+			Text:            `"blue widget" -refurbished`,
+		}},
+		{name: "long SKU", filter: store.OrderFilter{SKU: strings.Repeat("X", 51)}, wantErr: "sku must be at most 50 characters"},
+		{name: "negative amount", filter: store.OrderFilter{MinAmount: &negative}, wantErr: "minAmount must not be negative"},
+		{name: "empty amount range", filter: store.OrderFilter{MinAmount: &high, MaxAmount: &low}, wantErr: "minAmount must not be greater than maxAmount"},
+		{name: "lowercase currency", filter: store.OrderFilter{Currency: "usd"}, wantErr: "currency must be an ISO 4217 code"},
+		{name: "unknown tier", filter: store.OrderFilter{CustomerTier: "diamond"}, wantErr: "customerTier must be one of"},
+		{name: "country name", filter: store.OrderFilter{ShippingCountry: "Germany"}, wantErr: "shippingCountry must be an ISO 3166-1 alpha-2 code"},
+		{name: "long text", filter: store.OrderFilter{Text: strings.Repeat("x", 201)}, wantErr: "q must be at most 200 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidateOrderFilter(tt.filter)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateDLQFilter(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		filter  store.DLQFilter
+		wantErr string
+	}{
+		{name: "empty", filter: store.DLQFilter{}},
+		{name: "valid", filter: store.DLQFilter{
+			Stages:       []string{"enrich", "emit"},
+			ErrorTypes:   []string{"timeout", "external-service"},
+			FailedAfter:  &earlier,
+			FailedBefore: &now,
+		}},
+		{name: "unknown stage", filter: store.DLQFilter{Stages: []string{"ship"}}, wantErr: `failedStage "ship" is not a pipeline stage`},
+		{name: "unknown error type", filter: store.DLQFilter{ErrorTypes: []string{"crash"}}, wantErr: `errorType "crash" is not a pipeline error type`},
+		{name: "empty range", filter: store.DLQFilter{FailedAfter: &now, FailedBefore: &earlier}, wantErr: "failedAfter must be before failedBefore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidateDLQFilter(tt.filter)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidatePipelineErrorFilter(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		filter  store.PipelineErrorFilter
+		wantErr string
+	}{
+		{name: "empty", filter: store.PipelineErrorFilter{}},
+		{name: "valid", filter: store.PipelineErrorFilter{
+			Stages:         []string{"validate", "emit"},
+			ErrorTypes:     []string{"validation", "timeout"},
+			OccurredAfter:  &earlier,
+			OccurredBefore: &now,
+		}},
+		{name: "unknown stage", filter: store.PipelineErrorFilter{Stages: []string{"ship"}}, wantErr: `stage "ship" is not a pipeline stage`},
+		{name: "unknown error type", filter: store.PipelineErrorFilter{ErrorTypes: []string{"crash"}}, wantErr: `errorType "crash" is not a pipeline error type`},
+		{name: "empty range", filter: store.PipelineErrorFilter{OccurredAfter: &now, OccurredBefore: &earlier}, wantErr: "occurredAfter must be before occurredBefore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidatePipelineErrorFilter(tt.filter)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateAuditFilter(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		filter  store.AuditFilter
+		wantErr string
+	}{
+		{name: "empty", filter: store.AuditFilter{}},
+		{name: "valid", filter: store.AuditFilter{
+			Methods:        []string{"DELETE", "POST"},
+			Route:          "/api/v1/orders/{orderId}",
+			Outcome:        "failure",
+			OccurredAfter:  &earlier,
+			OccurredBefore: &now,
+		}},
+		{name: "read method", filter: store.AuditFilter{Methods: []string{"GET"}}, wantErr: "method must be one of"},
+		{name: "unknown outcome", filter: store.AuditFilter{Outcome: "error"}, wantErr: "outcome must be one of success, failure"},
+		{name: "empty range", filter: store.AuditFilter{OccurredAfter: &now, OccurredBefore: &earlier}, wantErr: "occurredAfter must be before occurredBefore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidateAuditFilter(tt.filter)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestDLQPurgeToken(t *testing.T) {
+	f := store.DLQFilter{Stages: []string{"enrich"}, ErrorTypes: []string{"timeout"}}
+
+	assert.Equal(t, service.DLQPurgeToken(f), service.DLQPurgeToken(store.DLQFilter{
+		Stages:     []string{"enrich"},
+		ErrorTypes: []string{"timeout"},
+	}))
+	assert.NotEqual(t, service.DLQPurgeToken(f), service.DLQPurgeToken(store.DLQFilter{Stages: []string{"enrich"}}))
+	assert.NotEqual(t, service.DLQPurgeToken(f), service.DLQPurgeToken(store.DLQFilter{}))
+}
+
+func TestOrderCSVRow(t *testing.T) {
+	row := service.OrderCSVRow(&generated.OrderSummary{
+		OrderId:     "550e8400-e29b-41d4-a716-446655440000",
+		CustomerId:  "=HYPERLINK(\"x\")",
+		Status:      generated.OrderStatusRouted,
+		TotalAmount: 59.98,
+		Currency:    "USD",
+		ItemCount:   2,
+		CreatedAt:   time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+	})
+	require.Len(t, row, len(service.OrderCSVHeader))
+	assert.Equal(t, []string{
+		"550e8400-e29b-41d4-a716-446655440000", "'=HYPERLINK(\"x\")", "routed", "59.98", "USD", "2", "2024-01-15T10:30:00Z",
+	}, row)
+}
+
+func TestStartOrderExport_RejectsUnknownFormat(t *testing.T) {
+	svc := service.New(nil, nil, nil)
+	_, err := svc.StartOrderExport(t.Context(), store.OrderFilter{}, "xlsx")
+	assert.Equal(t, http.StatusBadRequest, problem.From(err).Status)
+}
+
+func TestPipelineStats_RejectsInvalidWindows(t *testing.T) {
+	svc := service.New(nil, nil, nil)
+	to := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	from := to.Add(-time.Hour)
+	week := to.Add(-7 * 24 * time.Hour)
+
+	for name, p := range map[string]service.StatsParams{
+		"from after to":     {From: &to, To: &from},
+		"bucket too narrow": {From: &from, To: &to, Bucket: 30 * time.Second},
+		"fractional bucket": {From: &from, To: &to, Bucket: 90500 * time.Millisecond},
+		"too many buckets":  {From: &week, To: &to, Bucket: time.Minute},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.PipelineStats(t.Context(), p)
+			assert.Equal(t, http.StatusBadRequest, problem.From(err).Status)
+		})
+	}
+}
+
+func TestValidateStageUpdate(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        generated.PipelineStageUpdateRequest
+		wantFields []string
+	}{
+		{name: "pause", req: generated.PipelineStageUpdateRequest{Status: "paused"}},
+		{name: "all fields", req: generated.PipelineStageUpdateRequest{
+			Status:      "active",
+			Concurrency: 100,
+			RetryPolicy: &generated.RetryPolicy{MaxAttempts: 10, BackoffMs: 100, BackoffMultiplier: 2, MaxBackoffMs: 1000},
+			Timeout:     "5m",
+		}},
+		{name: "no retries", req: generated.PipelineStageUpdateRequest{RetryPolicy: &generated.RetryPolicy{}}},
+		{name: "empty", wantFields: []string{}},
+		{name: "unknown status", req: generated.PipelineStageUpdateRequest{Status: "stopped"}, wantFields: []string{"status"}},
+		{name: "concurrency out of range", req: generated.PipelineStageUpdateRequest{Concurrency: 101}, wantFields: []string{"concurrency"}},
+		{name: "timeout format", req: generated.PipelineStageUpdateRequest{Timeout: "1h"}, wantFields: []string{"timeout"}},
+		{
+			name: "retry policy",
+			req: generated.PipelineStageUpdateRequest{RetryPolicy: &generated.RetryPolicy{
+				MaxAttempts: 11, BackoffMs: 500, BackoffMultiplier: 0.5, MaxBackoffMs: 100,
+			}},
+			wantFields: []string{"retryPolicy.maxAttempts", "retryPolicy.backoffMultiplier", "retryPolicy.maxBackoffMs"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ValidateStageUpdate(&tt.req)
+			if tt.wantFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var perr *problem.Error
+			require.ErrorAs(t, err, &perr)
+			assert.Equal(t, http.StatusBadRequest, perr.Status)
+			var fields []string
+			for _, e := range perr.Errors {
+				fields = append(fields, e.Field)
+			}
+			assert.ElementsMatch(t, tt.wantFields, fields)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/problem"
+)
+
+// Limits of a stage update, as in the API specification
+const (
+	maxStageConcurrency = 100
+	maxRetryAttempts    = 10
+)
+
+// stageTimeoutPattern is the API's timeout format, e.g. "30s" or "5m"
+var stageTimeoutPattern = regexp.MustCompile(`^[0-9]+(s|m)$`)
+
+// PipelineStages returns the pipeline's stages with their metrics. Metrics
+// may be cached for a few seconds.
+func (s *Service) PipelineStages(ctx context.Context) []generated.PipelineStageSummary {
+	var stages []generated.PipelineStageSummary
+	if s.cache.Get(ctx, cacheKeyStages, &stages) {
+		return stages
+	}
+	stages = s.pipeline.GetStages()
+	s.cache.Set(ctx, cacheKeyStages, stages, s.stagesTTL)
+	return stages
+}
+
+// PipelineStage returns a pipeline stage's configuration and metrics.
+// Metrics may be cached for a few seconds.
+func (s *Service) PipelineStage(ctx context.Context, stageID string) (*generated.PipelineStageResponse, error) {
+	var stage *generated.PipelineStageResponse
+	if s.cache.Get(ctx, stageCacheKey(stageID), &stage) {
+		return stage, nil
+	}
+	stage = s.pipeline.GetStage(stageID)
+	if stage == nil {
+		return nil, problem.NotFound("Pipeline stage %s not found", stageID)
+	}
+	s.cache.Set(ctx, stageCacheKey(stageID), stage, s.stagesTTL)
+	return stage, nil
+}
+
+// UpdatePipelineStage pauses or resumes a stage and changes its concurrency,
+// retry policy or timeout, returning the updated stage
+func (s *Service) UpdatePipelineStage(ctx context.Context, stageID string, req *generated.PipelineStageUpdateRequest) (*generated.PipelineStageResponse, error) {
+	update, err := ValidateStageUpdate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.pipeline.UpdateStage(ctx, stageID, update)
+	switch {
+	case errors.Is(err, pipeline.ErrStageNotFound):
+		return nil, problem.NotFound("Pipeline stage %s not found", stageID)
+	case errors.Is(err, pipeline.ErrLastRunningStage):
+		return nil, problem.Conflict("stage-not-pausable", "Stage Cannot Be Paused",
+			fmt.Sprintf("Stage %s is the only one running; resume another stage first", stageID))
+	case err != nil:
+		return nil, problem.Upstream("pipeline", err)
+	}
+	return s.PipelineStage(ctx, stageID)
+}
+
+// ValidateStageUpdate checks a stage update request and converts it for the
+// pipeline
+func ValidateStageUpdate(req *generated.PipelineStageUpdateRequest) (pipeline.StageUpdate, error) {
+	var u pipeline.StageUpdate
+	if req.Status == "" && req.Concurrency == 0 && req.RetryPolicy == nil && req.Timeout == "" {
+		return u, problem.Validation("At least one field must be updated")
+	}
+
+	var errs []generated.ValidationError
+	invalid := func(field, message string, value any) {
+		errs = append(errs, generated.ValidationError{Field: field, Code: "invalid_value", Message: message, RejectedValue: value})
+	}
+	switch req.Status {
+	case "":
+	case "active", "paused":
+		paused := req.Status == "paused"
+		u.Paused = &paused
+	default:
+		invalid("status", "status must be active or paused", req.Status)
+	}
+	if req.Concurrency != 0 {
+		if req.Concurrency < 1 || req.Concurrency > maxStageConcurrency {
+			invalid("concurrency", fmt.Sprintf("concurrency must be between 1 and %d", maxStageConcurrency), req.Concurrency)
+		}
+		u.Concurrency = &req.Concurrency
+	}
+	if p := req.RetryPolicy; p != nil {
+		if p.MaxAttempts < 0 || p.MaxAttempts > maxRetryAttempts {
+			invalid("retryPolicy.maxAttempts", fmt.Sprintf("maxAttempts must be between 0 and %d", maxRetryAttempts), p.MaxAttempts)
+		}
+		if p.BackoffMs < 0 {
+			invalid("retryPolicy.backoffMs", "backoffMs must not be negative", p.BackoffMs)
+		}
+		if p.BackoffMultiplier != 0 && p.BackoffMultiplier < 1 {
+			invalid("retryPolicy.backoffMultiplier", "backoffMultiplier must be at least 1", p.BackoffMultiplier)
+		}
+		if p.MaxBackoffMs != 0 && p.MaxBackoffMs < p.BackoffMs {
+			invalid("retryPolicy.maxBackoffMs", "maxBackoffMs must not be less than backoffMs", p.MaxBackoffMs)
+		}
+		u.RetryPolicy = p
+	}
+	if req.Timeout != "" {
+		if !stageTimeoutPattern.MatchString(req.Timeout) {
+			invalid("timeout", `timeout must be a number of seconds or minutes, e.g. "30s" or "5m"`, req.Timeout)
+		} else {
+			timeout, _ := time.ParseDuration(req.Timeout)
+			u.Timeout = &timeout
+		}
+	}
+	if len(errs) > 0 {
+		return pipeline.StageUpdate{}, problem.Validation("Invalid stage update", errs...)
+	}
+	return u, nil
+}
+
+// RoutingStats returns how many orders were routed to each destination
+func (s *Service) RoutingStats() generated.RoutingStatsResponse {
+	return s.pipeline.RoutingStats()
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// Pipeline statistics limits
+const (
+	DefaultStatsWindow = time.Hour
+	MinStatsBucket     = time.Minute
+	MaxStatsBuckets    = 1440
+	// defaultStatsBuckets is about how many buckets a window is split into
+	// when no bucket width is given
+	defaultStatsBuckets = 60
+)
+
+// statsStages are the stages that record order events, in pipeline order
+var statsStages = []string{"ingest", "validate", "enrich", "route"}
+
+// StatsParams selects the window of pipeline statistics. To defaults to now
+// and From to DefaultStatsWindow before To; a zero Bucket splits the window
+// into about 60 buckets.
+type StatsParams struct {
+	From   *time.Time
+	To     *time.Time
+	Bucket time.Duration
+}
+
+// PipelineStats returns the pipeline's throughput, failures and stage
+// latency percentiles over a window, computed from the orders' event
+// history. The window's start is rounded down to a whole multiple of the
+// bucket width since the Unix epoch. Each bucket lists every stage, so charts
+// have no gaps, and counts the messages dead-lettered in it; dlqDepth is the
+// DLQ's current depth.
+func (s *Service) PipelineStats(ctx context.Context, p StatsParams) (*generated.PipelineStatsResponse, error) {
+	to := s.clock.Now().UTC()
+	if p.To != nil {
+		to = p.To.UTC()
+	}
+	from := to.Add(-DefaultStatsWindow)
+	if p.From != nil {
+		from = p.From.UTC()
+	}
+	if !from.Before(to) {
+		return nil, problem.InvalidParameter("from must be before to")
+	}
+	bucket := p.Bucket
+	if bucket == 0 {
+		bucket = max(to.Sub(from)/defaultStatsBuckets, MinStatsBucket).Round(MinStatsBucket)
+	}
+	if bucket < MinStatsBucket || bucket%time.Second != 0 {
+		return nil, problem.InvalidParameter(fmt.Sprintf("bucket must be a whole number of seconds, at least %s", MinStatsBucket))
+	}
+	// Align to the Unix epoch, as the store's buckets are
+	secs := int64(bucket / time.Second)
+	from = time.Unix(from.Unix()-from.Unix()%secs, 0).UTC()
+	n := int((to.Sub(from) + bucket - 1) / bucket)
+	if n > MaxStatsBuckets {
+		return nil, problem.InvalidParameter(fmt.Sprintf("the window spans %d buckets; at most %d are allowed, widen bucket or narrow the window", n, MaxStatsBuckets))
+	}
+
+	stats, err := s.orders.PipelineStats(ctx, from, to, bucket, statsStages)
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	arrivals, err := s.orders.DLQArrivals(ctx, from, to, bucket)
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+	depth, err := s.orders.CountDLQItems(ctx, store.DLQFilter{})
+	if err != nil {
+		return nil, problem.Upstream("postgres", err)
+	}
+
+	resp := &generated.PipelineStatsResponse{
+		BucketSeconds: int(secs),
+		Buckets:       make([]generated.PipelineStatsBucket, n),
+		DlqDepth:      depth,
+		From:          from,
+		To:            to,
+	}
+	for i := range resp.Buckets {
+		b := &resp.Buckets[i]
+		b.Start = from.Add(time.Duration(i) * bucket)
+		b.Stages = make([]generated.StageStats, len(statsStages))
+		for j, stage := range statsStages {
+			b.Stages[j].Stage = stage
+		}
+	}
+	index := func(t time.Time) (int, bool) {
+		i := int(t.Sub(from) / bucket)
+		return i, i >= 0 && i < n
+	}
+	for _, st := range stats {
+		i, ok := index(st.Bucket)
+		if !ok {
+			continue
+		}
+		for j := range resp.Buckets[i].Stages {
+			stage := &resp.Buckets[i].Stages[j]
+			if stage.Stage != st.Stage {
+				continue
+			}
+			stage.Completed, stage.Failed = st.Completed, st.Failed
+			if st.Completed > 0 {
+				stage.LatencyMs = &generated.LatencyPercentiles{P50: st.P50, P95: st.P95, P99: st.P99}
+			}
+		}
+	}
+	for _, c := range arrivals {
+		if i, ok := index(c.Bucket); ok {
+			resp.Buckets[i].DlqAdded = c.Count
+		}
+	}
+	return resp, nil
+}
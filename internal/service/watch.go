@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/problem"
+	"github.com/synapse/synapse/internal/store"
+)
+
+// orderWatchBuffer is how many status updates are buffered per watch.
+// Updates beyond it are dropped for a watcher that doesn't keep up.
+const orderWatchBuffer = 64
+
+// MaxOrderWait is the longest WaitForOrder holds a request
+const MaxOrderWait = 60 * time.Second
+
+// OrderWatch is a subscription to an order's status updates
+type OrderWatch struct {
+	// Snapshot is the order's status when the watch started
+	Snapshot generated.OrderStatusUpdatePayload
+
+	sub     *nats.Subscription
+	updates chan *nats.Msg
+}
+
+// WatchOrder subscribes to an order's status updates. The caller must Close
+// the watch.
+func (s *Service) WatchOrder(ctx context.Context, orderID string) (*OrderWatch, error) {
+	if s.nats == nil {
+		return nil, problem.Upstream("nats", errors.New("not connected"))
+	}
+
+	// Subscribe before reading the order so no update falls between the
+	// snapshot and the stream
+	w := &OrderWatch{updates: make(chan *nats.Msg, orderWatchBuffer)}
+	sub, err := s.nats.ChanSubscribe(pipeline.OrderStatusSubject(orderID), w.updates)
+	if err != nil {
+		return nil, problem.Upstream("nats", err)
+	}
+	w.sub = sub
+
+	order, err := s.orders.GetOrder(ctx, orderID)
+	if err != nil {
+		w.Close()
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, problem.NotFound("Order with ID %s not found", orderID)
+		}
+		return nil, problem.Upstream("postgres", err)
+	}
+
+	w.Snapshot = generated.OrderStatusUpdatePayload{
+		OrderId:   order.ID,
+		Status:    order.Status,
+		Timestamp: order.UpdatedAt,
+	}
+	return w, nil
+}
+
+// WaitForOrder returns an order once it reaches a final status (routed,
+// failed or cancelled), or as it stands when timeout elapses first. It lets
+// clients that can't stream long-poll for the outcome.
+func (s *Service) WaitForOrder(ctx context.Context, orderID string, timeout time.Duration) (*generated.OrderResponse, error) {
+	watch, err := s.WatchOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer watch.Close()
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	status := watch.Snapshot.Status
+	for !pipeline.IsFinalStatus(status) {
+		update, err := watch.Next(waitCtx)
+		if err != nil {
+			break
+		}
+		status = update.Status
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// The pipeline evicts the cached order before announcing its update
+	return s.GetOrder(ctx, orderID)
+}
+
+// Next waits for the order's next status update. Updates that can't be
+// decoded are skipped.
+func (w *OrderWatch) Next(ctx context.Context) (generated.OrderStatusUpdatePayload, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return generated.OrderStatusUpdatePayload{}, ctx.Err()
+		case msg := <-w.updates:
+			var update generated.OrderStatusUpdatePayload
+			if err := json.Unmarshal(msg.Data, &update); err == nil {
+				return update, nil
+			}
+		}
+	}
+}
+
+// Close ends the subscription
+func (w *OrderWatch) Close() {
+	_ = w.sub.Unsubscribe()
+}
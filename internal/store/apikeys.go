@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// APIKey is a stored API key. The key itself is never stored, only its hash.
+type APIKey struct {
+	ID         string
+	Name       string
+	Prefix     string
+	Hash       string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+const apiKeyColumns = `key_id, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at`
+
+// CreateAPIKey inserts an API key
+func (s *Store) CreateAPIKey(ctx context.Context, k *APIKey) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO api_keys (key_id, name, prefix, key_hash, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		k.ID, k.Name, k.Prefix, k.Hash, k.Scopes, k.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting API key %s: %w", k.ID, err)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash returns the active key with the given hash, or ErrNotFound.
+// Revoked keys are not returned.
+func (s *Store) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL`, hash)
+	return scanAPIKey(row)
+}
+
+// ListAPIKeys returns the active API keys, newest first
+func (s *Store) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE revoked_at IS NULL
+		ORDER BY created_at DESC, key_id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("listing API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes an active key and returns it, or ErrNotFound
+func (s *Store) RevokeAPIKey(ctx context.Context, keyID string, at time.Time) (*APIKey, error) {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE api_keys SET revoked_at = $2
+		WHERE key_id = $1 AND revoked_at IS NULL
+		RETURNING `+apiKeyColumns, keyID, at)
+	return scanAPIKey(row)
+}
+
+// TouchAPIKey records that a key was used at the given time
+func (s *Store) TouchAPIKey(ctx context.Context, keyID string, at time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE key_id = $1`, keyID, at); err != nil {
+		return fmt.Errorf("touching API key %s: %w", keyID, err)
+	}
+	return nil
+}
+
+func scanAPIKey(row scanner) (*APIKey, error) {
+	var k APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, textArray(&k.Scopes), &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning API key: %w", err)
+	}
+	if k.Scopes == nil {
+		k.Scopes = []string{}
+	}
+	return &k, nil
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// AuditEntry records a mutating API call
+type AuditEntry struct {
+	ID        string
+	Seq       int64
+	RequestID string
+	// ActorID and ActorMethod identify the authenticated client; both are
+	// empty for anonymous calls
+	ActorID     string
+	ActorMethod string
+	Tenant      string
+	Method      string
+	// Route is the matched route pattern, Path the request path
+	Route string
+	Path  string
+	// Params are the route's path parameters, e.g. orderId
+	Params     map[string]string
+	Query      string
+	BodyBytes  int64
+	StatusCode int
+	DurationMs int
+	OccurredAt time.Time
+}
+
+// AuditFilter selects audit entries
+type AuditFilter struct {
+	ActorID string
+	Methods []string
+	Route   string
+	Path    string
+	// Outcome is "success" for 2xx and 3xx status codes, "failure" otherwise
+	Outcome        string
+	OccurredAfter  *time.Time // inclusive
+	OccurredBefore *time.Time // exclusive
+}
+
+// conditions renders the filter as SQL conditions, adding their arguments to args
+func (f AuditFilter) conditions(args *queryArgs) []string {
+	var conds []string
+	if f.ActorID != "" {
+		conds = append(conds, "actor_id = "+args.add(f.ActorID))
+	}
+	if len(f.Methods) > 0 {
+		conds = append(conds, "method IN ("+placeholders(args, f.Methods)+")")
+	}
+	if f.Route != "" {
+		conds = append(conds, "route = "+args.add(f.Route))
+	}
+	if f.Path != "" {
+		conds = append(conds, "path = "+args.add(f.Path))
+	}
+	switch f.Outcome {
+	case "success":
+		conds = append(conds, "status_code < 400")
+	case "failure":
+		conds = append(conds, "status_code >= 400")
+	}
+	if f.OccurredAfter != nil {
+		conds = append(conds, "occurred_at >= "+args.add(*f.OccurredAfter))
+	}
+	if f.OccurredBefore != nil {
+		conds = append(conds, "occurred_at < "+args.add(*f.OccurredBefore))
+	}
+	return conds
+}
+
+// ListAuditParams selects a page of audit entries, newest first. When After
+// is set the page starts after that position and Offset is ignored; the
+// cursor key is the entry's Seq.
+type ListAuditParams struct {
+	AuditFilter
+	Limit  int
+	Offset int
+	After  *Cursor
+}
+
+// RecordAudit inserts an audit entry
+func (s *Store) RecordAudit(ctx context.Context, e *AuditEntry) error {
+	params, err := json.Marshal(e.Params)
+	if err != nil {
+		return fmt.Errorf("encoding params of audit entry %s: %w", e.ID, err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO audit_log (audit_id, request_id, actor_id, actor_method, tenant, method, route, path,
+			params, query, body_bytes, status_code, duration_ms, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		e.ID, e.RequestID, e.ActorID, e.ActorMethod, e.Tenant, e.Method, e.Route, e.Path,
+		params, e.Query, e.BodyBytes, e.StatusCode, e.DurationMs, e.OccurredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting audit entry %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+// ListAuditEntries returns a page of audit entries matching p.AuditFilter
+func (s *Store) ListAuditEntries(ctx context.Context, p ListAuditParams) ([]AuditEntry, error) {
+	var args queryArgs
+	conds := p.conditions(&args)
+	offset := p.Offset
+	if p.After != nil {
+		seq, err := strconv.ParseInt(p.After.Key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit cursor key %q: %w", p.After.Key, err)
+		}
+		conds = append(conds, "(occurred_at, seq) < ("+args.add(p.After.Time)+", "+args.add(seq)+")")
+		offset = 0
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT audit_id, seq, request_id, actor_id, actor_method, tenant, method, route, path,
+			params, query, body_bytes, status_code, duration_ms, occurred_at
+		FROM audit_log
+		`+where(conds)+`
+		ORDER BY occurred_at DESC, seq DESC
+		LIMIT `+args.add(p.Limit)+` OFFSET `+args.add(offset),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var (
+			e      AuditEntry
+			params []byte
+		)
+		if err := rows.Scan(&e.ID, &e.Seq, &e.RequestID, &e.ActorID, &e.ActorMethod, &e.Tenant, &e.Method,
+			&e.Route, &e.Path, &params, &e.Query, &e.BodyBytes, &e.StatusCode, &e.DurationMs, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("scanning audit entry: %w", err)
+		}
+		if err := json.Unmarshal(params, &e.Params); err != nil {
+			return nil, fmt.Errorf("decoding params of audit entry %s: %w", e.ID, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+// CountAuditEntries returns the number of audit entries matching f
+func (s *Store) CountAuditEntries(ctx context.Context, f AuditFilter) (int, error) {
+	var args queryArgs
+	conds := f.conditions(&args)
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM audit_log `+where(conds), args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting audit entries: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// db is a Store's database handle. Each query it runs is cancelled with its
+// context, or once it has run for the timeout, whichever comes first, so a
+// runaway query can't hold a connection; a transaction's queries share the
+// timeout.
+type db struct {
+	*sql.DB
+	timeout time.Duration
+}
+
+// bound returns ctx limited to the timeout, unless its own deadline is sooner
+func (d *db) bound(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.timeout <= 0 {
+		return ctx, func() {}
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d.timeout {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d.timeout)
+}
+
+func (d *db) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, cancel := d.bound(ctx)
+	defer cancel()
+	return d.DB.ExecContext(ctx, query, args...)
+}
+
+func (d *db) QueryContext(ctx context.Context, query string, args ...any) (*rows, error) {
+	ctx, cancel := d.bound(ctx)
+	r, err := d.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &rows{Rows: r, cancel: cancel}, nil
+}
+
+func (d *db) QueryRowContext(ctx context.Context, query string, args ...any) *row {
+	ctx, cancel := d.bound(ctx)
+	return &row{Row: d.DB.QueryRowContext(ctx, query, args...), cancel: cancel}
+}
+
+func (d *db) BeginTx(ctx context.Context, opts *sql.TxOptions) (*tx, error) {
+	ctx, cancel := d.bound(ctx)
+	t, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &tx{Tx: t, cancel: cancel}, nil
+}
+
+// rows are a query's rows, which hold its timeout until closed
+type rows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+}
+
+func (r *rows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+// row is a single-row query's result, which holds its timeout until scanned
+type row struct {
+	*sql.Row
+	cancel context.CancelFunc
+}
+
+func (r *row) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.Row.Scan(dest...)
+}
+
+// tx is a transaction, which holds its timeout until committed or rolled back
+type tx struct {
+	*sql.Tx
+	cancel context.CancelFunc
+}
+
+func (t *tx) Commit() error {
+	defer t.cancel()
+	return t.Tx.Commit()
+}
+
+func (t *tx) Rollback() error {
+	defer t.cancel()
+	return t.Tx.Rollback()
+}
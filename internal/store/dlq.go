@@ -0,0 +1,304 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DLQItem is a message a pipeline stage gave up on
+type DLQItem struct {
+	EventID      string
+	OrderID      string
+	Stage        string
+	Topic        string
+	ErrorType    string
+	ErrorMessage string
+	// Payload and Metadata are the message as the stage received it
+	Payload  []byte
+	Metadata map[string]string
+	// Preview is a short, readable excerpt of the payload
+	Preview     string
+	RetryCount  int
+	FailedAt    time.Time
+	LastRetryAt *time.Time
+}
+
+// DLQFilter selects dead-lettered messages. Requeued messages are never
+// selected.
+type DLQFilter struct {
+	Stages       []string
+	ErrorTypes   []string
+	FailedAfter  *time.Time // inclusive
+	FailedBefore *time.Time // exclusive
+}
+
+// conditions renders the filter as SQL conditions, adding their arguments to args
+func (f DLQFilter) conditions(args *queryArgs) []string {
+	conds := []string{"NOT requeued"}
+	if len(f.Stages) > 0 {
+		conds = append(conds, "stage IN ("+placeholders(args, f.Stages)+")")
+	}
+	if len(f.ErrorTypes) > 0 {
+		conds = append(conds, "error_type IN ("+placeholders(args, f.ErrorTypes)+")")
+	}
+	if f.FailedAfter != nil {
+		conds = append(conds, "failed_at >= "+args.add(*f.FailedAfter))
+	}
+	if f.FailedBefore != nil {
+		conds = append(conds, "failed_at < "+args.add(*f.FailedBefore))
+	}
+	return conds
+}
+
+// ListDLQParams selects a page of dead-lettered messages, most recent failure
+// first. When After is set the page starts after that position and Offset is
+// ignored; the cursor key is the event ID.
+type ListDLQParams struct {
+	DLQFilter
+	Limit  int
+	Offset int
+	After  *Cursor
+}
+
+const dlqColumns = `event_id, order_id, stage, topic, error_type, error_message, payload, metadata,
+	preview, retry_count, failed_at, last_retry_at`
+
+// SaveDLQItem records a dead-lettered message. A message dead-lettered again,
+// e.g. after a retry, replaces its earlier record, adding up the retries and
+// keeping the time it was last retried.
+func (s *Store) SaveDLQItem(ctx context.Context, item *DLQItem) error {
+	metadata, err := json.Marshal(item.Metadata)
+	if err != nil {
+		return fmt.Errorf("encoding metadata of DLQ item %s: %w", item.EventID, err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO dlq_items (`+dlqColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (event_id) DO UPDATE
+		SET order_id = EXCLUDED.order_id, stage = EXCLUDED.stage, topic = EXCLUDED.topic,
+			error_type = EXCLUDED.error_type, error_message = EXCLUDED.error_message,
+			payload = EXCLUDED.payload, metadata = EXCLUDED.metadata, preview = EXCLUDED.preview,
+			retry_count = dlq_items.retry_count + EXCLUDED.retry_count, failed_at = EXCLUDED.failed_at,
+			requeued = false`,
+		item.EventID, item.OrderID, item.Stage, item.Topic, item.ErrorType, item.ErrorMessage,
+		item.Payload, metadata, item.Preview, item.RetryCount, item.FailedAt, item.LastRetryAt,
+	)
+	if err != nil {
+		return fmt.Errorf("saving DLQ item %s: %w", item.EventID, err)
+	}
+	return nil
+}
+
+// GetDLQItem returns a dead-lettered message that hasn't been requeued, or
+// ErrNotFound
+func (s *Store) GetDLQItem(ctx context.Context, eventID string) (*DLQItem, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+dlqColumns+`
+		FROM dlq_items
+		WHERE event_id = $1 AND NOT requeued`, eventID)
+	return scanDLQItem(row)
+}
+
+// RequeueDLQItem marks a dead-lettered message as requeued at the given
+// time, taking it off the DLQ. It returns ErrNotFound when the message isn't
+// on the DLQ, e.g. because it is being requeued concurrently.
+func (s *Store) RequeueDLQItem(ctx context.Context, eventID string, at time.Time) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE dlq_items
+		SET requeued = true, last_retry_at = $2
+		WHERE event_id = $1 AND NOT requeued`,
+		eventID, at,
+	)
+	if err != nil {
+		return fmt.Errorf("requeuing DLQ item %s: %w", eventID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("DLQ item %s: %w", eventID, ErrNotFound)
+	}
+	return nil
+}
+
+// RestoreDLQItem puts a message RequeueDLQItem took off the DLQ back on it,
+// with the lastRetryAt it had before
+func (s *Store) RestoreDLQItem(ctx context.Context, eventID string, lastRetryAt *time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE dlq_items
+		SET requeued = false, last_retry_at = $2
+		WHERE event_id = $1`,
+		eventID, lastRetryAt,
+	)
+	if err != nil {
+		return fmt.Errorf("restoring DLQ item %s: %w", eventID, err)
+	}
+	return nil
+}
+
+// DeleteDLQItems deletes the dead-lettered messages matching f, returning
+// how many were deleted
+func (s *Store) DeleteDLQItems(ctx context.Context, f DLQFilter) (int, error) {
+	var args queryArgs
+	conds := f.conditions(&args)
+	res, err := s.db.ExecContext(ctx, `DELETE FROM dlq_items `+where(conds), args...)
+	if err != nil {
+		return 0, fmt.Errorf("deleting DLQ items: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("deleting DLQ items: %w", err)
+	}
+	return int(n), nil
+}
+
+// ListDLQItems returns a page of dead-lettered messages
+func (s *Store) ListDLQItems(ctx context.Context, p ListDLQParams) ([]DLQItem, error) {
+	var args queryArgs
+	conds := p.conditions(&args)
+	offset := p.Offset
+	if p.After != nil {
+		conds = append(conds, "(failed_at, event_id) < ("+args.add(p.After.Time)+", "+args.add(p.After.Key)+")")
+		offset = 0
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+dlqColumns+`
+		FROM dlq_items
+		`+where(conds)+`
+		ORDER BY failed_at DESC, event_id DESC
+		LIMIT `+args.add(p.Limit)+` OFFSET `+args.add(offset),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing DLQ items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []DLQItem
+	for rows.Next() {
+		item, err := scanDLQItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing DLQ items: %w", err)
+	}
+	return items, nil
+}
+
+// CountDLQItems returns the number of dead-lettered messages matching f
+func (s *Store) CountDLQItems(ctx context.Context, f DLQFilter) (int, error) {
+	var args queryArgs
+	conds := f.conditions(&args)
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM dlq_items `+where(conds), args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting DLQ items: %w", err)
+	}
+	return n, nil
+}
+
+func scanDLQItem(row scanner) (*DLQItem, error) {
+	var (
+		item     DLQItem
+		metadata []byte
+	)
+	if err := row.Scan(
+		&item.EventID, &item.OrderID, &item.Stage, &item.Topic, &item.ErrorType, &item.ErrorMessage,
+		&item.Payload, &metadata, &item.Preview, &item.RetryCount, &item.FailedAt, &item.LastRetryAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("scanning DLQ item: %w", err)
+	}
+	if err := json.Unmarshal(metadata, &item.Metadata); err != nil {
+		return nil, fmt.Errorf("decoding metadata of DLQ item %s: %w", item.EventID, err)
+	}
+	return &item, nil
+}
+
+// DLQJob is a bulk retry or purge of DLQ items and its progress
+type DLQJob struct {
+	ID     string
+	Action string
+	Status string
+	// Filter is the filter the job was started with, as given to the API
+	Filter      json.RawMessage
+	Matched     int
+	Processed   int
+	Succeeded   int
+	Skipped     int
+	Failed      int
+	Error       string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+const dlqJobColumns = `job_id, action, status, filter, matched, processed, succeeded, skipped, failed, error,
+	created_at, updated_at, completed_at`
+
+// CreateDLQJob inserts a DLQ job
+func (s *Store) CreateDLQJob(ctx context.Context, j *DLQJob) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO dlq_jobs (job_id, action, status, filter, matched, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)`,
+		j.ID, j.Action, j.Status, jsonObject(j.Filter), j.Matched, j.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting DLQ job %s: %w", j.ID, err)
+	}
+	return nil
+}
+
+// UpdateDLQJob saves a DLQ job's status and progress
+func (s *Store) UpdateDLQJob(ctx context.Context, j *DLQJob) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE dlq_jobs
+		SET status = $2, matched = $3, processed = $4, succeeded = $5, skipped = $6, failed = $7,
+			error = NULLIF($8, ''), updated_at = $9, completed_at = $10
+		WHERE job_id = $1`,
+		j.ID, j.Status, j.Matched, j.Processed, j.Succeeded, j.Skipped, j.Failed,
+		j.Error, j.UpdatedAt, j.CompletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("updating DLQ job %s: %w", j.ID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("DLQ job %s: %w", j.ID, ErrNotFound)
+	}
+	return nil
+}
+
+// GetDLQJob returns a DLQ job, or ErrNotFound
+func (s *Store) GetDLQJob(ctx context.Context, jobID string) (*DLQJob, error) {
+	var (
+		j       DLQJob
+		errText sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT `+dlqJobColumns+`
+		FROM dlq_jobs
+		WHERE job_id = $1`, jobID).Scan(
+		&j.ID, &j.Action, &j.Status, &j.Filter, &j.Matched, &j.Processed, &j.Succeeded, &j.Skipped, &j.Failed,
+		&errText, &j.CreatedAt, &j.UpdatedAt, &j.CompletedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting DLQ job %s: %w", jobID, err)
+	}
+	j.Error = errText.String
+	return &j, nil
+}
+
+func jsonObject(data json.RawMessage) []byte {
+	if len(data) == 0 {
+		return []byte("{}")
+	}
+	return data
+}
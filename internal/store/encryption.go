@@ -0,0 +1,221 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/synapse/synapse/internal/crypto"
+)
+
+// Order columns that can be encrypted at rest
+const (
+	ColumnCustomerID      = "customer_id"
+	ColumnShippingAddress = "shipping_address"
+	ColumnBillingAddress  = "billing_address"
+)
+
+// EncryptableColumns are the order columns WithEncryption can encrypt
+var EncryptableColumns = []string{ColumnCustomerID, ColumnShippingAddress, ColumnBillingAddress}
+
+// addressColumns are the encryptable columns holding addresses
+var addressColumns = []string{ColumnShippingAddress, ColumnBillingAddress}
+
+// DefaultEncryptedColumns are the columns encrypted when none are
+// configured: those orders can still be filtered by
+var DefaultEncryptedColumns = []string{ColumnCustomerID}
+
+// encryption seals the configured order columns. Columns are sealed with
+// their name as additional data, so a value can't be moved to another one.
+type encryption struct {
+	keys    *crypto.Keyring
+	columns []string
+}
+
+// WithEncryption returns a Store that encrypts the given order columns, or
+// DefaultEncryptedColumns, under keys before writing them. Whatever the
+// columns, values sealed by keys are opened when read, and those written
+// before encryption was turned on are read as they are.
+//
+// customer_id is sealed deterministically, so orders can still be filtered
+// by customer. Addresses are sealed as JSON strings with a random
+// nonce, so when it is encrypted, listing orders by shipping country or text
+// fails with ErrUnsupportedFilter rather than matching none.
+func (s *Store) WithEncryption(keys *crypto.Keyring, columns []string) *Store {
+	if keys == nil {
+		return s
+	}
+	if len(columns) == 0 {
+		columns = DefaultEncryptedColumns
+	}
+	c := *s
+	c.encryption = &encryption{keys: keys, columns: columns}
+	return &c
+}
+
+func (e *encryption) encrypts(column string) bool {
+	return e != nil && slices.Contains(e.columns, column)
+}
+
+func columnAAD(column string) []byte {
+	return []byte("orders." + column)
+}
+
+// sealCustomerID returns the customer_id to store
+func (e *encryption) sealCustomerID(customerID string) string {
+	if !e.encrypts(ColumnCustomerID) || customerID == "" {
+		return customerID
+	}
+	return e.keys.SealDeterministic([]byte(customerID), columnAAD(ColumnCustomerID))
+}
+
+// customerIDForms returns the stored forms of customerID an order may have:
+// sealed under each key, or in plaintext from before encryption
+func (e *encryption) customerIDForms(customerID string) []string {
+	if !e.encrypts(ColumnCustomerID) {
+		return nil
+	}
+	return append(e.keys.SealedForms([]byte(customerID), columnAAD(ColumnCustomerID)), customerID)
+}
+
+// sealAddress returns the address to store in column
+func (e *encryption) sealAddress(column string, address json.RawMessage) (json.RawMessage, error) {
+	if !e.encrypts(column) || len(address) == 0 || string(address) == "null" {
+		return address, nil
+	}
+	sealed, err := e.keys.Seal(address, columnAAD(column))
+	if err != nil {
+		return nil, fmt.Errorf("encrypting %s: %w", strings.ReplaceAll(column, "_", " "), err)
+	}
+	return json.Marshal(sealed)
+}
+
+// address returns o's address stored in column
+func (o *Order) address(column string) *json.RawMessage {
+	if column == ColumnBillingAddress {
+		return &o.BillingAddress
+	}
+	return &o.ShippingAddress
+}
+
+// open restores the order's encrypted columns
+func (e *encryption) open(o *Order) error {
+	if e == nil {
+		return nil
+	}
+	customerID, err := e.keys.Open(o.CustomerID, columnAAD(ColumnCustomerID))
+	if err != nil {
+		return fmt.Errorf("decrypting customer ID of order %s: %w", o.ID, err)
+	}
+	o.CustomerID = string(customerID)
+
+	for _, column := range addressColumns {
+		var sealed string
+		address := o.address(column)
+		if json.Unmarshal(*address, &sealed) == nil && crypto.IsSealed(sealed) {
+			opened, err := e.keys.Open(sealed, columnAAD(column))
+			if err != nil {
+				return fmt.Errorf("decrypting %s of order %s: %w", strings.ReplaceAll(column, "_", " "), o.ID, err)
+			}
+			*address = opened
+		}
+	}
+	return nil
+}
+
+// sealFilter rewrites f's customer filter to match the customer's stored
+// forms, and rejects the filters encrypted columns can't match
+func (e *encryption) sealFilter(f OrderFilter) (OrderFilter, error) {
+	if e.encrypts(ColumnShippingAddress) && (f.ShippingCountry != "" || f.Text != "") {
+		return f, fmt.Errorf("%w: shipping addresses are encrypted, so orders can't be filtered by shipping country or searched by text", ErrUnsupportedFilter)
+	}
+	if f.CustomerID != "" {
+		f.customerIDs = e.customerIDForms(f.CustomerID)
+	}
+	return f, nil
+}
+
+// ReencryptOrders encrypts the configured columns of up to limit orders under
+// the active key, where they are stored in plaintext or under an older key,
+// and returns how many orders it updated. Orders keep their version, as
+// their content is unchanged. After rotating keys or turning
+// encryption on, run it until it returns 0; a key can be removed from the
+// keyring once nothing is sealed under it.
+func (s *Store) ReencryptOrders(ctx context.Context, limit int) (int, error) {
+	e := s.encryption
+	if e == nil {
+		return 0, nil
+	}
+	var args queryArgs
+	active := args.add(e.keys.ActivePrefix())
+	var stale []string
+	if e.encrypts(ColumnCustomerID) {
+		stale = append(stale, "customer_id <> '' AND left(customer_id, length("+active+")) <> "+active)
+	}
+	for _, column := range addressColumns {
+		if e.encrypts(column) {
+			stale = append(stale, column+` IS NOT NULL AND (jsonb_typeof(`+column+`) <> 'string'
+			OR left(`+column+` #>> '{}', length(`+active+`)) <> `+active+`)`)
+		}
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("beginning re-encryption: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+orderColumns+` FROM orders
+		WHERE (`+strings.Join(stale, ") OR (")+`)
+		LIMIT `+args.add(limit)+`
+		FOR UPDATE SKIP LOCKED`,
+		args...,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("selecting orders to re-encrypt: %w", err)
+	}
+	var orders []*Order
+	for rows.Next() {
+		o, err := s.scanOrder(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orders = append(orders, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("selecting orders to re-encrypt: %w", err)
+	}
+
+	// Only the encrypted columns are written, so the others keep their form
+	for _, o := range orders {
+		update := queryArgs{o.ID}
+		var set []string
+		if e.encrypts(ColumnCustomerID) {
+			set = append(set, "customer_id = "+update.add(e.sealCustomerID(o.CustomerID)))
+		}
+		for _, column := range addressColumns {
+			if e.encrypts(column) {
+				address, err := e.sealAddress(column, *o.address(column))
+				if err != nil {
+					return 0, err
+				}
+				set = append(set, column+" = "+update.add(nullJSON(address)))
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE orders SET `+strings.Join(set, ", ")+` WHERE order_id = $1`, update...); err != nil {
+			return 0, fmt.Errorf("re-encrypting order %s: %w", o.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing re-encryption: %w", err)
+	}
+	return len(orders), nil
+}
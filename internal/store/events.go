@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Event is an entry in an order's per-stage event history
+type Event struct {
+	ID         string
+	Seq        int64
+	OrderID    string
+	Type       string
+	Stage      string
+	Status     string
+	OccurredAt time.Time
+	DurationMs int
+	Metadata   json.RawMessage
+	Error      json.RawMessage
+}
+
+// ListEventsParams selects a page of an order's events. When After is set
+// the page starts after that position and Offset is ignored; the cursor key
+// is the event's Seq.
+type ListEventsParams struct {
+	Limit      int
+	Offset     int
+	Descending bool
+	After      *Cursor
+}
+
+// AppendEvent records an order event. Appending an event ID that already
+// exists is a no-op so redelivered messages don't duplicate history.
+func (s *Store) AppendEvent(ctx context.Context, e *Event) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO order_events (event_id, order_id, event_type, stage, status, occurred_at, duration_ms, metadata, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (event_id) DO NOTHING`,
+		e.ID, e.OrderID, e.Type, e.Stage, e.Status, e.OccurredAt, e.DurationMs,
+		nullJSON(e.Metadata), nullJSON(e.Error),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting event %s for order %s: %w", e.ID, e.OrderID, err)
+	}
+	return nil
+}
+
+// ListEvents returns a page of an order's events, oldest first unless
+// p.Descending is set
+func (s *Store) ListEvents(ctx context.Context, orderID string, p ListEventsParams) ([]Event, error) {
+	direction, cmp := "ASC", ">"
+	if p.Descending {
+		direction, cmp = "DESC", "<"
+	}
+
+	var args queryArgs
+	conds := []string{"order_id = " + args.add(orderID)}
+	offset := p.Offset
+	if p.After != nil {
+		seq, err := strconv.ParseInt(p.After.Key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event cursor key %q: %w", p.After.Key, err)
+		}
+		conds = append(conds, "(occurred_at, seq) "+cmp+" ("+args.add(p.After.Time)+", "+args.add(seq)+")")
+		offset = 0
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT event_id, seq, order_id, event_type, stage, status, occurred_at, duration_ms, metadata, error
+		FROM order_events
+		`+where(conds)+`
+		ORDER BY occurred_at `+direction+`, seq `+direction+`
+		LIMIT `+args.add(p.Limit)+` OFFSET `+args.add(offset),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing events for order %s: %w", orderID, err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var (
+			e                 Event
+			metadata, errData []byte
+		)
+		if err := rows.Scan(&e.ID, &e.Seq, &e.OrderID, &e.Type, &e.Stage, &e.Status, &e.OccurredAt, &e.DurationMs, &metadata, &errData); err != nil {
+			return nil, fmt.Errorf("scanning event: %w", err)
+		}
+		e.Metadata = metadata
+		e.Error = errData
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing events for order %s: %w", orderID, err)
+	}
+	return events, nil
+}
+
+// CountEvents returns the number of events recorded for an order
+func (s *Store) CountEvents(ctx context.Context, orderID string) (int, error) {
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM order_events WHERE order_id = $1`, orderID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting events for order %s: %w", orderID, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ExportJob is a background order export and its progress. The exported file
+// isn't loaded with the job; see ExportFile.
+type ExportJob struct {
+	ID     string
+	Status string
+	Format string
+	// Filter is the filter the export was started with, as given to the API
+	Filter      json.RawMessage
+	Matched     int
+	Exported    int
+	SizeBytes   int64
+	Error       string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+	// ExpiresAt is when the exported file is deleted, once the export completed
+	ExpiresAt *time.Time
+}
+
+const exportJobColumns = `job_id, status, format, filter, matched, exported, size_bytes, error,
+	created_at, updated_at, completed_at, expires_at`
+
+// CreateExportJob inserts an export job
+func (s *Store) CreateExportJob(ctx context.Context, j *ExportJob) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO export_jobs (job_id, status, format, filter, matched, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)`,
+		j.ID, j.Status, j.Format, jsonObject(j.Filter), j.Matched, j.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting export job %s: %w", j.ID, err)
+	}
+	return nil
+}
+
+// UpdateExportJob saves an export job's status and progress
+func (s *Store) UpdateExportJob(ctx context.Context, j *ExportJob) error {
+	return s.updateExportJob(ctx, j, nil)
+}
+
+// SaveExportFile saves an export job's status along with the exported file
+func (s *Store) SaveExportFile(ctx context.Context, j *ExportJob, content []byte) error {
+	if content == nil {
+		content = []byte{}
+	}
+	return s.updateExportJob(ctx, j, content)
+}
+
+// updateExportJob saves an export job, and its file unless content is nil
+func (s *Store) updateExportJob(ctx context.Context, j *ExportJob, content []byte) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE export_jobs
+		SET status = $2, matched = $3, exported = $4, size_bytes = $5, error = NULLIF($6, ''),
+			updated_at = $7, completed_at = $8, expires_at = $9, content = COALESCE($10, content)
+		WHERE job_id = $1`,
+		j.ID, j.Status, j.Matched, j.Exported, j.SizeBytes, j.Error,
+		j.UpdatedAt, j.CompletedAt, j.ExpiresAt, content,
+	)
+	if err != nil {
+		return fmt.Errorf("updating export job %s: %w", j.ID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("export job %s: %w", j.ID, ErrNotFound)
+	}
+	return nil
+}
+
+// GetExportJob returns an export job, or ErrNotFound
+func (s *Store) GetExportJob(ctx context.Context, jobID string) (*ExportJob, error) {
+	var (
+		j       ExportJob
+		errText sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT `+exportJobColumns+`
+		FROM export_jobs
+		WHERE job_id = $1`, jobID).Scan(
+		&j.ID, &j.Status, &j.Format, &j.Filter, &j.Matched, &j.Exported, &j.SizeBytes, &errText,
+		&j.CreatedAt, &j.UpdatedAt, &j.CompletedAt, &j.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting export job %s: %w", jobID, err)
+	}
+	j.Error = errText.String
+	return &j, nil
+}
+
+// ExportFile returns the file an export job exported, or ErrNotFound if the
+// job doesn't exist or hasn't saved one
+func (s *Store) ExportFile(ctx context.Context, jobID string) ([]byte, error) {
+	var content []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT content
+		FROM export_jobs
+		WHERE job_id = $1 AND content IS NOT NULL`, jobID).Scan(&content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting file of export job %s: %w", jobID, err)
+	}
+	return content, nil
+}
+
+// DeleteExpiredExportJobs deletes the export jobs that expired before now,
+// returning how many it deleted
+func (s *Store) DeleteExpiredExportJobs(ctx context.Context, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM export_jobs WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired export jobs: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired export jobs: %w", err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ImportJob is a bulk order import and its progress
+type ImportJob struct {
+	ID          string
+	Status      string
+	Format      string
+	Processed   int
+	Accepted    int
+	Rejected    int
+	Skipped     int
+	Errors      json.RawMessage
+	Error       string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+const importJobColumns = `job_id, status, format, processed, accepted, rejected, skipped, errors, error, created_at, updated_at, completed_at`
+
+// CreateImportJob inserts an import job
+func (s *Store) CreateImportJob(ctx context.Context, j *ImportJob) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO import_jobs (job_id, status, format, errors, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)`,
+		j.ID, j.Status, j.Format, jsonArray(j.Errors), j.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting import job %s: %w", j.ID, err)
+	}
+	return nil
+}
+
+// UpdateImportJob saves an import job's status and progress
+func (s *Store) UpdateImportJob(ctx context.Context, j *ImportJob) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE import_jobs
+		SET status = $2, processed = $3, accepted = $4, rejected = $5, skipped = $6,
+			errors = $7, error = NULLIF($8, ''), updated_at = $9, completed_at = $10
+		WHERE job_id = $1`,
+		j.ID, j.Status, j.Processed, j.Accepted, j.Rejected, j.Skipped,
+		jsonArray(j.Errors), j.Error, j.UpdatedAt, j.CompletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("updating import job %s: %w", j.ID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("import job %s: %w", j.ID, ErrNotFound)
+	}
+	return nil
+}
+
+// GetImportJob returns an import job, or ErrNotFound
+func (s *Store) GetImportJob(ctx context.Context, jobID string) (*ImportJob, error) {
+	var (
+		j       ImportJob
+		errText sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT `+importJobColumns+`
+		FROM import_jobs
+		WHERE job_id = $1`, jobID).Scan(
+		&j.ID, &j.Status, &j.Format, &j.Processed, &j.Accepted, &j.Rejected, &j.Skipped,
+		&j.Errors, &errText, &j.CreatedAt, &j.UpdatedAt, &j.CompletedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting import job %s: %w", jobID, err)
+	}
+	j.Error = errText.String
+	return &j, nil
+}
+
+func jsonArray(data json.RawMessage) []byte {
+	if len(data) == 0 {
+		return []byte("[]")
+	}
+	return data
+}
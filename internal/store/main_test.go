@@ -0,0 +1,12 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+// The store's tests only need Postgres
+func TestMain(m *testing.M) {
+	testutil.RunWithContainers(m, &testutil.ContainerConfig{Services: []testutil.Service{testutil.ServicePostgres}})
+}
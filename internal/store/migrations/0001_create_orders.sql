@@ -0,0 +1,22 @@
+-- Order projection maintained by the pipeline and served by the orders API
+CREATE TABLE orders (
+    order_id         TEXT PRIMARY KEY,
+    customer_id      TEXT NOT NULL,
+    status           TEXT NOT NULL,
+    current_stage    TEXT NOT NULL DEFAULT '',
+    total_amount     DOUBLE PRECISION NOT NULL,
+    currency         TEXT NOT NULL,
+    item_count       INTEGER NOT NULL,
+    items            JSONB NOT NULL,
+    shipping_address JSONB,
+    enrichment       JSONB,
+    destination      TEXT NOT NULL DEFAULT '',
+    routing_reason   TEXT NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ NOT NULL,
+    updated_at       TIMESTAMPTZ NOT NULL,
+    validated_at     TIMESTAMPTZ,
+    enriched_at      TIMESTAMPTZ,
+    routed_at        TIMESTAMPTZ
+);
+
+CREATE INDEX orders_created_at_idx ON orders (created_at DESC, order_id DESC);
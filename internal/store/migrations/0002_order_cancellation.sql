@@ -0,0 +1,4 @@
+-- Cancellation bookkeeping; previous_status records where the order stood when cancelled
+ALTER TABLE orders
+    ADD COLUMN cancelled_at    TIMESTAMPTZ,
+    ADD COLUMN previous_status TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+-- Per-stage event history of each order, served by the order events API.
+-- seq breaks ties between events recorded at the same instant.
+CREATE TABLE order_events (
+    event_id    TEXT PRIMARY KEY,
+    seq         BIGSERIAL NOT NULL,
+    order_id    TEXT NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    event_type  TEXT NOT NULL,
+    stage       TEXT NOT NULL,
+    status      TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    metadata    JSONB,
+    error       JSONB
+);
+
+CREATE INDEX order_events_order_idx ON order_events (order_id, occurred_at, seq);
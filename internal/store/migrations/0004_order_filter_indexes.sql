@@ -0,0 +1,4 @@
+-- Indexes backing the order list filters; each keeps the list's newest-first order
+CREATE INDEX orders_status_created_at_idx ON orders (status, created_at DESC, order_id DESC);
+CREATE INDEX orders_customer_created_at_idx ON orders (customer_id, created_at DESC, order_id DESC);
+CREATE INDEX orders_destination_created_at_idx ON orders (destination, created_at DESC, order_id DESC);
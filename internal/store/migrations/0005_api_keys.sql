@@ -0,0 +1,14 @@
+-- API keys used to authenticate /api/v1 requests. Only the SHA-256 hash of
+-- each key is stored; prefix is its non-secret leading part, shown in listings.
+CREATE TABLE api_keys (
+    key_id       TEXT PRIMARY KEY,
+    name         TEXT NOT NULL,
+    prefix       TEXT NOT NULL,
+    key_hash     TEXT NOT NULL UNIQUE,
+    scopes       TEXT[] NOT NULL DEFAULT '{}',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX api_keys_active_idx ON api_keys (created_at DESC) WHERE revoked_at IS NULL;
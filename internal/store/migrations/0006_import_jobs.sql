@@ -0,0 +1,16 @@
+-- Bulk order imports. Counters are updated as the import streams in, so
+-- clients can poll a job's progress; errors holds the first rejected rows.
+CREATE TABLE import_jobs (
+    job_id       TEXT PRIMARY KEY,
+    status       TEXT NOT NULL,
+    format       TEXT NOT NULL,
+    processed    INTEGER NOT NULL DEFAULT 0,
+    accepted     INTEGER NOT NULL DEFAULT 0,
+    rejected     INTEGER NOT NULL DEFAULT 0,
+    skipped      INTEGER NOT NULL DEFAULT 0,
+    errors       JSONB NOT NULL DEFAULT '[]',
+    error        TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ
+);
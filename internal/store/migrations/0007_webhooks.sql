@@ -0,0 +1,29 @@
+-- Webhook subscriptions managed through the API. The secret signs every
+-- delivery, so unlike API keys it is stored as is.
+CREATE TABLE webhook_subscriptions (
+    subscription_id TEXT PRIMARY KEY,
+    url             TEXT NOT NULL,
+    event_types     TEXT[] NOT NULL,
+    secret          TEXT NOT NULL,
+    description     TEXT NOT NULL DEFAULT '',
+    active          BOOLEAN NOT NULL DEFAULT true,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One row per delivery attempt; seq orders attempts made in the same instant
+CREATE TABLE webhook_deliveries (
+    seq             BIGSERIAL PRIMARY KEY,
+    delivery_id     TEXT NOT NULL UNIQUE,
+    subscription_id TEXT NOT NULL REFERENCES webhook_subscriptions ON DELETE CASCADE,
+    event_id        TEXT NOT NULL,
+    event_type      TEXT NOT NULL,
+    attempt         INTEGER NOT NULL,
+    status          TEXT NOT NULL,
+    status_code     INTEGER,
+    error           TEXT,
+    duration_ms     INTEGER NOT NULL DEFAULT 0,
+    attempted_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, attempted_at DESC, seq DESC);
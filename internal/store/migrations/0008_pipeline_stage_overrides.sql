@@ -0,0 +1,8 @@
+-- Pipeline stage configuration changed through the API, reapplied at startup.
+-- config holds the stage's StageConfig as returned by the API.
+CREATE TABLE pipeline_stage_overrides (
+    stage_id   TEXT PRIMARY KEY,
+    paused     BOOLEAN NOT NULL DEFAULT false,
+    config     JSONB NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
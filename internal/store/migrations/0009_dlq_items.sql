@@ -0,0 +1,20 @@
+-- Messages a pipeline stage gave up on once its retry policy was exhausted.
+-- payload and metadata are kept as received, still compressed and encrypted,
+-- so the message can be republished to the stage's topic unchanged.
+CREATE TABLE dlq_items (
+    event_id      TEXT PRIMARY KEY,
+    order_id      TEXT NOT NULL DEFAULT '',
+    stage         TEXT NOT NULL,
+    topic         TEXT NOT NULL,
+    error_type    TEXT NOT NULL,
+    error_message TEXT NOT NULL,
+    payload       BYTEA NOT NULL,
+    metadata      JSONB NOT NULL DEFAULT '{}',
+    preview       TEXT NOT NULL DEFAULT '',
+    retry_count   INTEGER NOT NULL DEFAULT 0,
+    failed_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_retry_at TIMESTAMPTZ
+);
+
+CREATE INDEX dlq_items_failed_at_idx ON dlq_items (failed_at DESC, event_id DESC);
+CREATE INDEX dlq_items_stage_idx ON dlq_items (stage, error_type);
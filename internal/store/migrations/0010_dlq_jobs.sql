@@ -0,0 +1,21 @@
+-- A requeued DLQ item keeps its row, and with it lastRetryAt, but is no
+-- longer listed; dead-lettering the message again clears the flag.
+ALTER TABLE dlq_items ADD COLUMN requeued BOOLEAN NOT NULL DEFAULT false;
+
+-- Bulk DLQ retries and purges, run in the background. filter holds the
+-- filter the job was started with, as given to the API.
+CREATE TABLE dlq_jobs (
+    job_id       TEXT PRIMARY KEY,
+    action       TEXT NOT NULL,
+    status       TEXT NOT NULL,
+    filter       JSONB NOT NULL DEFAULT '{}',
+    matched      INTEGER NOT NULL DEFAULT 0,
+    processed    INTEGER NOT NULL DEFAULT 0,
+    succeeded    INTEGER NOT NULL DEFAULT 0,
+    skipped      INTEGER NOT NULL DEFAULT 0,
+    failed       INTEGER NOT NULL DEFAULT 0,
+    error        TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ
+);
@@ -0,0 +1,13 @@
+-- Indexes backing the order search. The JSONB columns are searched by
+-- containment (@>), which jsonb_path_ops indexes compactly.
+CREATE INDEX orders_items_idx ON orders USING GIN (items jsonb_path_ops);
+CREATE INDEX orders_enrichment_idx ON orders USING GIN (enrichment jsonb_path_ops);
+CREATE INDEX orders_shipping_address_idx ON orders USING GIN (shipping_address jsonb_path_ops);
+CREATE INDEX orders_total_amount_idx ON orders (total_amount);
+
+-- Free-text search over the items, shipping address and routing reason; the
+-- expression must match orderSearchDocument in store.go
+CREATE INDEX orders_search_idx ON orders USING GIN (
+    (to_tsvector('simple', items) || to_tsvector('simple', coalesce(shipping_address, '{}'))
+        || to_tsvector('simple', routing_reason))
+);
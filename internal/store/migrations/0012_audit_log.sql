@@ -0,0 +1,24 @@
+-- Mutating API calls, for compliance review. params holds the route's path
+-- parameters; request bodies aren't kept, as they may carry secrets.
+-- seq orders entries recorded in the same instant.
+CREATE TABLE audit_log (
+    seq          BIGSERIAL PRIMARY KEY,
+    audit_id     TEXT NOT NULL UNIQUE,
+    request_id   TEXT NOT NULL DEFAULT '',
+    actor_id     TEXT NOT NULL DEFAULT '',
+    actor_method TEXT NOT NULL DEFAULT '',
+    tenant       TEXT NOT NULL DEFAULT '',
+    method       TEXT NOT NULL,
+    route        TEXT NOT NULL,
+    path         TEXT NOT NULL,
+    params       JSONB NOT NULL DEFAULT '{}',
+    query        TEXT NOT NULL DEFAULT '',
+    body_bytes   BIGINT NOT NULL DEFAULT 0,
+    status_code  INTEGER NOT NULL,
+    duration_ms  INTEGER NOT NULL DEFAULT 0,
+    occurred_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_log_occurred_at_idx ON audit_log (occurred_at DESC, seq DESC);
+CREATE INDEX audit_log_actor_idx ON audit_log (actor_id, occurred_at DESC);
+CREATE INDEX audit_log_route_idx ON audit_log (route, method, occurred_at DESC);
@@ -0,0 +1,14 @@
+-- version counts an order's changes and backs its ETag. The trigger bumps it
+-- on every update, so conditional requests see changes made by any writer.
+ALTER TABLE orders ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+CREATE FUNCTION orders_bump_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER orders_version
+    BEFORE UPDATE ON orders
+    FOR EACH ROW EXECUTE FUNCTION orders_bump_version();
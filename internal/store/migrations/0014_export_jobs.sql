@@ -0,0 +1,20 @@
+-- Background order exports. filter holds the filter the export was started
+-- with, as given to the API; content holds the finished file, which can be
+-- downloaded until expires_at.
+CREATE TABLE export_jobs (
+    job_id       TEXT PRIMARY KEY,
+    status       TEXT NOT NULL,
+    format       TEXT NOT NULL,
+    filter       JSONB NOT NULL DEFAULT '{}',
+    matched      INTEGER NOT NULL DEFAULT 0,
+    exported     INTEGER NOT NULL DEFAULT 0,
+    size_bytes   BIGINT NOT NULL DEFAULT 0,
+    content      BYTEA,
+    error        TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ,
+    expires_at   TIMESTAMPTZ
+);
+
+CREATE INDEX export_jobs_expires_at_idx ON export_jobs (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Pipeline statistics aggregate the order events of a time window
+CREATE INDEX order_events_occurred_at_idx ON order_events (occurred_at);
@@ -0,0 +1,15 @@
+-- Every failed attempt of a pipeline stage, as announced on pipeline.errors.
+-- event_id is the failed message's ID, shared with its DLQ item once the
+-- stage gives up on it.
+CREATE TABLE pipeline_errors (
+    error_id    TEXT PRIMARY KEY,
+    event_id    TEXT NOT NULL,
+    order_id    TEXT NOT NULL DEFAULT '',
+    stage       TEXT NOT NULL,
+    error_type  TEXT NOT NULL,
+    message     TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX pipeline_errors_occurred_at_idx ON pipeline_errors (occurred_at DESC, error_id DESC);
+CREATE INDEX pipeline_errors_stage_idx ON pipeline_errors (stage, error_type);
@@ -0,0 +1,4 @@
+-- The billing address and custom metadata orders are created with
+ALTER TABLE orders
+    ADD COLUMN billing_address JSONB,
+    ADD COLUMN metadata        JSONB;
@@ -0,0 +1,13 @@
+-- Re-encrypting an order rewrites how its customer ID and addresses are
+-- stored, not the order, so it keeps its version and ETag. Every change to an
+-- order sets updated_at, so one touching only these columns is re-encryption.
+CREATE OR REPLACE FUNCTION orders_bump_version() RETURNS trigger AS $$
+BEGIN
+    IF to_jsonb(NEW) - ARRAY['customer_id', 'shipping_address', 'billing_address']
+        = to_jsonb(OLD) - ARRAY['customer_id', 'shipping_address', 'billing_address'] THEN
+        RETURN NEW;
+    END IF;
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PipelineError is a failed attempt of a pipeline stage to process a message
+type PipelineError struct {
+	ID        string
+	EventID   string
+	OrderID   string
+	Stage     string
+	ErrorType string
+	Message   string
+	// InDLQ reports whether the message is on the DLQ, under EventID. It is
+	// set by ListPipelineErrors.
+	InDLQ      bool
+	OccurredAt time.Time
+}
+
+// PipelineErrorFilter selects pipeline errors
+type PipelineErrorFilter struct {
+	Stages         []string
+	ErrorTypes     []string
+	OccurredAfter  *time.Time // inclusive
+	OccurredBefore *time.Time // exclusive
+}
+
+// conditions renders the filter as SQL conditions on the pipeline_errors
+// table aliased as e, adding their arguments to args
+func (f PipelineErrorFilter) conditions(args *queryArgs) []string {
+	var conds []string
+	if len(f.Stages) > 0 {
+		conds = append(conds, "e.stage IN ("+placeholders(args, f.Stages)+")")
+	}
+	if len(f.ErrorTypes) > 0 {
+		conds = append(conds, "e.error_type IN ("+placeholders(args, f.ErrorTypes)+")")
+	}
+	if f.OccurredAfter != nil {
+		conds = append(conds, "e.occurred_at >= "+args.add(*f.OccurredAfter))
+	}
+	if f.OccurredBefore != nil {
+		conds = append(conds, "e.occurred_at < "+args.add(*f.OccurredBefore))
+	}
+	return conds
+}
+
+// ListPipelineErrorsParams selects a page of pipeline errors, most recent
+// first. When After is set the page starts after that position and Offset is
+// ignored; the cursor key is the error ID.
+type ListPipelineErrorsParams struct {
+	PipelineErrorFilter
+	Limit  int
+	Offset int
+	After  *Cursor
+}
+
+// SavePipelineError records a pipeline error. Saving an error ID that
+// already exists is a no-op.
+func (s *Store) SavePipelineError(ctx context.Context, e *PipelineError) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO pipeline_errors (error_id, event_id, order_id, stage, error_type, message, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (error_id) DO NOTHING`,
+		e.ID, e.EventID, e.OrderID, e.Stage, e.ErrorType, e.Message, e.OccurredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting pipeline error %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+// ListPipelineErrors returns a page of pipeline errors, noting which of the
+// failed messages are on the DLQ
+func (s *Store) ListPipelineErrors(ctx context.Context, p ListPipelineErrorsParams) ([]PipelineError, error) {
+	var args queryArgs
+	conds := p.conditions(&args)
+	offset := p.Offset
+	if p.After != nil {
+		conds = append(conds, "(e.occurred_at, e.error_id) < ("+args.add(p.After.Time)+", "+args.add(p.After.Key)+")")
+		offset = 0
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.error_id, e.event_id, e.order_id, e.stage, e.error_type, e.message, e.occurred_at,
+			d.event_id IS NOT NULL
+		FROM pipeline_errors e
+		LEFT JOIN dlq_items d ON d.event_id = e.event_id AND d.stage = e.stage AND NOT d.requeued
+		`+where(conds)+`
+		ORDER BY e.occurred_at DESC, e.error_id DESC
+		LIMIT `+args.add(p.Limit)+` OFFSET `+args.add(offset),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing pipeline errors: %w", err)
+	}
+	defer rows.Close()
+
+	var errs []PipelineError
+	for rows.Next() {
+		var e PipelineError
+		if err := rows.Scan(&e.ID, &e.EventID, &e.OrderID, &e.Stage, &e.ErrorType, &e.Message, &e.OccurredAt, &e.InDLQ); err != nil {
+			return nil, fmt.Errorf("scanning pipeline error: %w", err)
+		}
+		errs = append(errs, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing pipeline errors: %w", err)
+	}
+	return errs, nil
+}
+
+// CountPipelineErrors returns the number of pipeline errors matching f
+func (s *Store) CountPipelineErrors(ctx context.Context, f PipelineErrorFilter) (int, error) {
+	var args queryArgs
+	conds := f.conditions(&args)
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM pipeline_errors e `+where(conds), args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting pipeline errors: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Cursor is a keyset pagination position: the sort key of the last row of the
+// previous page. Key breaks ties between rows with the same Time.
+type Cursor struct {
+	Time time.Time
+	Key  string
+}
+
+// queryArgs collects positional query arguments
+type queryArgs []any
+
+// add appends v and returns its placeholder
+func (a *queryArgs) add(v any) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// placeholders adds values to args and returns their placeholders, comma separated
+func placeholders(args *queryArgs, values []string) string {
+	p := make([]string, len(values))
+	for i, v := range values {
+		p[i] = args.add(v)
+	}
+	return strings.Join(p, ", ")
+}
+
+// where joins conditions into a WHERE clause, or returns "" if there are none
+func where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conds, " AND ")
+}
+
+// textArray scans a text[] column into dst. Slices are passed as query
+// arguments as they are.
+func textArray(dst *[]string) sql.Scanner {
+	return pgtype.NewMap().SQLScanner(dst)
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// OrderRepository reads and writes the order projection
+type OrderRepository interface {
+	CreateOrder(ctx context.Context, o *Order) error
+	MarkValidated(ctx context.Context, orderID string, at time.Time) error
+	MarkEnriched(ctx context.Context, orderID string, at time.Time, enrichment json.RawMessage) error
+	MarkRouted(ctx context.Context, orderID string, at time.Time, destination, reason string) error
+	CancelOrder(ctx context.Context, orderID string, at time.Time, ifVersions []int64) (*Order, bool, error)
+	GetOrder(ctx context.Context, orderID string) (*Order, error)
+	ListOrders(ctx context.Context, p ListOrdersParams) ([]Order, error)
+	CountOrders(ctx context.Context, f OrderFilter) (int, error)
+}
+
+// EventRepository reads and appends the orders' event history
+type EventRepository interface {
+	AppendEvent(ctx context.Context, e *Event) error
+	ListEvents(ctx context.Context, orderID string, p ListEventsParams) ([]Event, error)
+	CountEvents(ctx context.Context, orderID string) (int, error)
+}
+
+// DLQRepository reads and writes the dead-lettered messages and the jobs
+// retrying or purging them
+type DLQRepository interface {
+	SaveDLQItem(ctx context.Context, item *DLQItem) error
+	GetDLQItem(ctx context.Context, eventID string) (*DLQItem, error)
+	RequeueDLQItem(ctx context.Context, eventID string, at time.Time) error
+	RestoreDLQItem(ctx context.Context, eventID string, lastRetryAt *time.Time) error
+	DeleteDLQItems(ctx context.Context, f DLQFilter) (int, error)
+	ListDLQItems(ctx context.Context, p ListDLQParams) ([]DLQItem, error)
+	CountDLQItems(ctx context.Context, f DLQFilter) (int, error)
+	CreateDLQJob(ctx context.Context, j *DLQJob) error
+	UpdateDLQJob(ctx context.Context, j *DLQJob) error
+	GetDLQJob(ctx context.Context, jobID string) (*DLQJob, error)
+}
+
+// AuditRepository records and reads the audit log
+type AuditRepository interface {
+	RecordAudit(ctx context.Context, e *AuditEntry) error
+	ListAuditEntries(ctx context.Context, p ListAuditParams) ([]AuditEntry, error)
+	CountAuditEntries(ctx context.Context, f AuditFilter) (int, error)
+}
+
+// ExportRepository reads and writes order export jobs and their files
+type ExportRepository interface {
+	CreateExportJob(ctx context.Context, j *ExportJob) error
+	UpdateExportJob(ctx context.Context, j *ExportJob) error
+	SaveExportFile(ctx context.Context, j *ExportJob, content []byte) error
+	GetExportJob(ctx context.Context, jobID string) (*ExportJob, error)
+	ExportFile(ctx context.Context, jobID string) ([]byte, error)
+	DeleteExpiredExportJobs(ctx context.Context, now time.Time) (int, error)
+}
+
+// ImportRepository reads and writes order import jobs
+type ImportRepository interface {
+	CreateImportJob(ctx context.Context, j *ImportJob) error
+	UpdateImportJob(ctx context.Context, j *ImportJob) error
+	GetImportJob(ctx context.Context, jobID string) (*ImportJob, error)
+}
+
+// WebhookRepository reads and writes webhook subscriptions and their
+// delivery attempts
+type WebhookRepository interface {
+	CreateWebhookSubscription(ctx context.Context, sub *WebhookSubscription) error
+	GetWebhookSubscription(ctx context.Context, id string) (*WebhookSubscription, error)
+	ListWebhookSubscriptions(ctx context.Context, activeOnly bool) ([]WebhookSubscription, error)
+	UpdateWebhookSubscription(ctx context.Context, sub *WebhookSubscription) error
+	DeleteWebhookSubscription(ctx context.Context, id string) error
+	RecordWebhookDelivery(ctx context.Context, d *WebhookDelivery) error
+	ListWebhookDeliveries(ctx context.Context, subscriptionID string, p ListDeliveriesParams) ([]WebhookDelivery, error)
+}
+
+// StatsRepository reads the pipeline's recorded errors and the statistics
+// derived from the event history and dead letters
+type StatsRepository interface {
+	ListPipelineErrors(ctx context.Context, p ListPipelineErrorsParams) ([]PipelineError, error)
+	CountPipelineErrors(ctx context.Context, f PipelineErrorFilter) (int, error)
+	PipelineStats(ctx context.Context, from, to time.Time, bucket time.Duration, stages []string) ([]StageStats, error)
+	DLQArrivals(ctx context.Context, from, to time.Time, bucket time.Duration) ([]BucketCount, error)
+}
+
+var (
+	_ OrderRepository   = (*Store)(nil)
+	_ EventRepository   = (*Store)(nil)
+	_ DLQRepository     = (*Store)(nil)
+	_ AuditRepository   = (*Store)(nil)
+	_ ExportRepository  = (*Store)(nil)
+	_ ImportRepository  = (*Store)(nil)
+	_ WebhookRepository = (*Store)(nil)
+	_ StatsRepository   = (*Store)(nil)
+)
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// StageOverride is a pipeline stage's configuration as changed through the API
+type StageOverride struct {
+	StageID   string
+	Paused    bool
+	Config    json.RawMessage
+	UpdatedAt time.Time
+}
+
+// SaveStageOverride inserts or replaces a stage's override
+func (s *Store) SaveStageOverride(ctx context.Context, o *StageOverride) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO pipeline_stage_overrides (stage_id, paused, config, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (stage_id) DO UPDATE
+		SET paused = EXCLUDED.paused, config = EXCLUDED.config, updated_at = EXCLUDED.updated_at`,
+		o.StageID, o.Paused, []byte(o.Config), o.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("saving stage override %s: %w", o.StageID, err)
+	}
+	return nil
+}
+
+// ListStageOverrides returns every saved stage override
+func (s *Store) ListStageOverrides(ctx context.Context) ([]StageOverride, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT stage_id, paused, config, updated_at
+		FROM pipeline_stage_overrides
+		ORDER BY stage_id`)
+	if err != nil {
+		return nil, fmt.Errorf("listing stage overrides: %w", err)
+	}
+	defer rows.Close()
+
+	var overrides []StageOverride
+	for rows.Next() {
+		var o StageOverride
+		if err := rows.Scan(&o.StageID, &o.Paused, &o.Config, &o.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning stage override: %w", err)
+		}
+		overrides = append(overrides, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing stage overrides: %w", err)
+	}
+	return overrides, nil
+}
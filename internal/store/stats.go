@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StageStats is a pipeline stage's activity in one time bucket
+type StageStats struct {
+	Bucket    time.Time
+	Stage     string
+	Completed int
+	Failed    int
+	// Latency percentiles of the stage's completed events, in milliseconds;
+	// zero when none completed
+	P50, P95, P99 float64
+}
+
+// BucketCount is a count in one time bucket
+type BucketCount struct {
+	Bucket time.Time
+	Count  int
+}
+
+// bucketExpr renders column truncated to buckets of width seconds, aligned to
+// the Unix epoch
+func bucketExpr(column, width string) string {
+	return "to_timestamp(floor(extract(epoch FROM " + column + ") / " + width + ") * " + width + ")"
+}
+
+// PipelineStats aggregates the events the given stages recorded in
+// [from, to) into buckets of width bucket, aligned to the Unix epoch. Buckets
+// without events are left out. Rows are ordered by bucket, then stage.
+func (s *Store) PipelineStats(ctx context.Context, from, to time.Time, bucket time.Duration, stages []string) ([]StageStats, error) {
+	var args queryArgs
+	width := args.add(bucket.Seconds())
+	conds := []string{
+		"occurred_at >= " + args.add(from),
+		"occurred_at < " + args.add(to),
+		"stage IN (" + placeholders(&args, stages) + ")",
+	}
+	rows, err := s.reader().QueryContext(ctx, `
+		SELECT `+bucketExpr("occurred_at", width+"::float8")+` AS bucket, stage,
+			count(*) FILTER (WHERE status = 'completed'),
+			count(*) FILTER (WHERE status = 'failed'),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY duration_ms) FILTER (WHERE status = 'completed'),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY duration_ms) FILTER (WHERE status = 'completed'),
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY duration_ms) FILTER (WHERE status = 'completed')
+		FROM order_events
+		`+where(conds)+`
+		GROUP BY bucket, stage
+		ORDER BY bucket, stage`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("aggregating pipeline stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []StageStats
+	for rows.Next() {
+		var (
+			st            StageStats
+			p50, p95, p99 sql.NullFloat64
+		)
+		if err := rows.Scan(&st.Bucket, &st.Stage, &st.Completed, &st.Failed, &p50, &p95, &p99); err != nil {
+			return nil, fmt.Errorf("scanning pipeline stats: %w", err)
+		}
+		st.Bucket = st.Bucket.UTC()
+		st.P50, st.P95, st.P99 = p50.Float64, p95.Float64, p99.Float64
+		stats = append(stats, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("aggregating pipeline stats: %w", err)
+	}
+	return stats, nil
+}
+
+// DLQArrivals counts the messages dead-lettered in [from, to) per bucket of
+// width bucket, aligned like PipelineStats. Messages since requeued are
+// counted; purged ones aren't.
+func (s *Store) DLQArrivals(ctx context.Context, from, to time.Time, bucket time.Duration) ([]BucketCount, error) {
+	rows, err := s.reader().QueryContext(ctx, `
+		SELECT `+bucketExpr("failed_at", "$1::float8")+` AS bucket, count(*)
+		FROM dlq_items
+		WHERE failed_at >= $2 AND failed_at < $3
+		GROUP BY bucket
+		ORDER BY bucket`, bucket.Seconds(), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("counting DLQ arrivals: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []BucketCount
+	for rows.Next() {
+		var c BucketCount
+		if err := rows.Scan(&c.Bucket, &c.Count); err != nil {
+			return nil, fmt.Errorf("scanning DLQ arrivals: %w", err)
+		}
+		c.Bucket = c.Bucket.UTC()
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("counting DLQ arrivals: %w", err)
+	}
+	return counts, nil
+}
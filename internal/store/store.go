@@ -0,0 +1,405 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Store errors
+var (
+	// ErrNotFound is returned when a requested record does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrNotCancellable is returned when an order has progressed too far to cancel
+	ErrNotCancellable = errors.New("order cannot be cancelled")
+	// ErrVersionMismatch is returned when a conditional write finds the record
+	// at a version other than the expected ones
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrUnsupportedFilter is returned when an order filter can't match the
+	// orders as they are stored, e.g. encrypted
+	ErrUnsupportedFilter = errors.New("unsupported filter")
+)
+
+// Store reads and writes the order projection in PostgreSQL
+type Store struct {
+	db *db
+	// replica returns the handle of the lists and statistics, which may lag
+	// behind db; nil to read them from db
+	replica func() *sql.DB
+	// encryption seals order columns at rest; nil stores them in plaintext
+	encryption *encryption
+}
+
+// New creates a Store on handle, whose queries run until their context ends.
+// Call Migrate before first use.
+func New(handle *sql.DB) *Store {
+	return &Store{db: &db{DB: handle}}
+}
+
+// WithReplica returns a Store that runs order lists, counts and statistics,
+// which tolerate some replication lag, on the handle replica returns, e.g. a
+// read replica's while it is healthy. Everything else still runs on the
+// Store's own handle.
+func (s *Store) WithReplica(replica func() *sql.DB) *Store {
+	c := *s
+	c.replica = replica
+	return &c
+}
+
+// WithQueryTimeout returns a Store whose queries are cancelled once they have
+// run for timeout, even if their context goes on; 0 leaves them to their
+// context
+func (s *Store) WithQueryTimeout(timeout time.Duration) *Store {
+	c := *s
+	c.db = &db{DB: s.db.DB, timeout: timeout}
+	return &c
+}
+
+// reader returns the handle lag-tolerant queries run on
+func (s *Store) reader() *db {
+	if s.replica != nil {
+		if handle := s.replica(); handle != nil {
+			return &db{DB: handle, timeout: s.db.timeout}
+		}
+	}
+	return s.db
+}
+
+// Order is a row of the order projection
+type Order struct {
+	ID              string
+	CustomerID      string
+	Status          string
+	CurrentStage    string
+	TotalAmount     float64
+	Currency        string
+	ItemCount       int
+	Items           json.RawMessage
+	ShippingAddress json.RawMessage
+	BillingAddress  json.RawMessage
+	Metadata        json.RawMessage
+	Enrichment      json.RawMessage
+	Destination     string
+	RoutingReason   string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	ValidatedAt     *time.Time
+	EnrichedAt      *time.Time
+	RoutedAt        *time.Time
+	CancelledAt     *time.Time
+	PreviousStatus  string
+	// Version is incremented on every change to the order
+	Version int64
+}
+
+// OrderFilter narrows the orders returned by ListOrders and CountOrders.
+// Zero-valued fields don't filter.
+type OrderFilter struct {
+	Statuses      []string
+	CustomerID    string
+	Destination   string
+	CreatedAfter  *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+	// customerIDs are CustomerID's stored forms when it is encrypted
+	customerIDs []string
+
+	// Search criteria, used by the order search
+
+	SKU             string   // an item has this SKU
+	MinAmount       *float64 // inclusive
+	MaxAmount       *float64 // inclusive
+	Currency        string
+	CustomerTier    string // set once the order is enriched
+	ShippingCountry string
+	// Text matches whole words of the items, shipping address and routing
+	// reason, in web search syntax
+	Text string
+}
+
+// conditions renders the filter as SQL conditions, adding their arguments to args
+func (f OrderFilter) conditions(args *queryArgs) []string {
+	var conds []string
+	if len(f.Statuses) > 0 {
+		conds = append(conds, "status IN ("+placeholders(args, f.Statuses)+")")
+	}
+	if len(f.customerIDs) > 0 {
+		conds = append(conds, "customer_id IN ("+placeholders(args, f.customerIDs)+")")
+	} else if f.CustomerID != "" {
+		conds = append(conds, "customer_id = "+args.add(f.CustomerID))
+	}
+	if f.Destination != "" {
+		conds = append(conds, "destination = "+args.add(f.Destination))
+	}
+	if f.CreatedAfter != nil {
+		conds = append(conds, "created_at >= "+args.add(*f.CreatedAfter))
+	}
+	if f.CreatedBefore != nil {
+		conds = append(conds, "created_at < "+args.add(*f.CreatedBefore))
+	}
+
+	// JSONB containment, so the GIN indexes on these columns apply
+	if f.SKU != "" {
+		conds = append(conds, "items @> "+args.add(jsonContains([]map[string]string{{"sku": f.SKU}})))
+	}
+	if f.CustomerTier != "" {
+		conds = append(conds, "enrichment @> "+args.add(jsonContains(map[string]any{"customer": map[string]string{"tier": f.CustomerTier}})))
+	}
+	if f.ShippingCountry != "" {
+		conds = append(conds, "shipping_address @> "+args.add(jsonContains(map[string]string{"country": f.ShippingCountry})))
+	}
+	if f.MinAmount != nil {
+		conds = append(conds, "total_amount >= "+args.add(*f.MinAmount))
+	}
+	if f.MaxAmount != nil {
+		conds = append(conds, "total_amount <= "+args.add(*f.MaxAmount))
+	}
+	if f.Currency != "" {
+		conds = append(conds, "currency = "+args.add(f.Currency))
+	}
+	if f.Text != "" {
+		conds = append(conds, orderSearchDocument+" @@ websearch_to_tsquery('simple', "+args.add(f.Text)+")")
+	}
+	return conds
+}
+
+// orderSearchDocument is the text searched by OrderFilter.Text. It must match
+// the expression of the orders_search_idx index.
+const orderSearchDocument = `(to_tsvector('simple', items) || to_tsvector('simple', coalesce(shipping_address, '{}'))
+	|| to_tsvector('simple', routing_reason))`
+
+// jsonContains encodes the right-hand side of a JSONB containment condition
+func jsonContains(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// ListOrdersParams selects a page of orders, newest first. When After is set
+// the page starts after that position and Offset is ignored.
+type ListOrdersParams struct {
+	OrderFilter
+	Limit  int
+	Offset int
+	After  *Cursor
+}
+
+const orderColumns = `order_id, customer_id, status, current_stage, total_amount, currency,
+	item_count, items, shipping_address, enrichment, destination, routing_reason,
+	created_at, updated_at, validated_at, enriched_at, routed_at, cancelled_at, previous_status, version,
+	billing_address, metadata`
+
+// CreateOrder inserts a newly accepted order. Re-inserting an existing order
+// is a no-op so ingestion can be retried safely.
+func (s *Store) CreateOrder(ctx context.Context, o *Order) error {
+	shippingAddress, err := s.encryption.sealAddress(ColumnShippingAddress, o.ShippingAddress)
+	if err != nil {
+		return err
+	}
+	billingAddress, err := s.encryption.sealAddress(ColumnBillingAddress, o.BillingAddress)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO orders (order_id, customer_id, status, current_stage, total_amount, currency,
+			item_count, items, shipping_address, billing_address, metadata, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)
+		ON CONFLICT (order_id) DO NOTHING`,
+		o.ID, s.encryption.sealCustomerID(o.CustomerID), o.Status, o.CurrentStage, o.TotalAmount, o.Currency,
+		o.ItemCount, []byte(o.Items), nullJSON(shippingAddress), nullJSON(billingAddress), nullJSON(o.Metadata), o.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting order %s: %w", o.ID, err)
+	}
+	return nil
+}
+
+// MarkValidated records that an order passed validation
+func (s *Store) MarkValidated(ctx context.Context, orderID string, at time.Time) error {
+	return s.advance(ctx, orderID, `status = 'validated', current_stage = 'validate', validated_at = $2, updated_at = $2`, at)
+}
+
+// MarkEnriched records an order's enrichment data
+func (s *Store) MarkEnriched(ctx context.Context, orderID string, at time.Time, enrichment json.RawMessage) error {
+	return s.advance(ctx, orderID, `status = 'enriched', current_stage = 'enrich', enriched_at = $2, updated_at = $2, enrichment = $3`, at, []byte(enrichment))
+}
+
+// MarkRouted records an order's routing decision
+func (s *Store) MarkRouted(ctx context.Context, orderID string, at time.Time, destination, reason string) error {
+	return s.advance(ctx, orderID, `status = 'routed', current_stage = 'route', routed_at = $2, updated_at = $2, destination = $3, routing_reason = $4`, at, destination, reason)
+}
+
+// advance applies a stage transition. Cancelled orders are left untouched and
+// reported as ErrNotFound, so stages can drop them.
+func (s *Store) advance(ctx context.Context, orderID, set string, args ...any) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE orders SET `+set+` WHERE order_id = $1 AND status <> 'cancelled'`,
+		append([]any{orderID}, args...)...,
+	)
+	if err != nil {
+		return fmt.Errorf("updating order %s: %w", orderID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("order %s: %w", orderID, ErrNotFound)
+	}
+	return nil
+}
+
+// cancellableStatuses are the states from which an order may be cancelled
+var cancellableStatuses = map[string]bool{
+	"accepted":   true,
+	"validating": true,
+	"validated":  true,
+	"enriching":  true,
+	"enriched":   true,
+	"routing":    true,
+}
+
+// CancelOrder cancels an order that has not been routed yet and returns it,
+// reporting whether this call cancelled it. Cancelling an already cancelled
+// order returns it unchanged. Orders past the point of cancellation are
+// returned together with ErrNotCancellable. With ifVersions set, orders at any
+// other version are returned together with ErrVersionMismatch; the check holds
+// the order's row lock, so it can't race a concurrent update.
+func (s *Store) CancelOrder(ctx context.Context, orderID string, at time.Time, ifVersions []int64) (*Order, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("beginning cancellation: %w", err)
+	}
+	defer tx.Rollback()
+
+	o, err := s.scanOrder(tx.QueryRowContext(ctx,
+		`SELECT `+orderColumns+` FROM orders WHERE order_id = $1 FOR UPDATE`, orderID))
+	if err != nil {
+		return nil, false, err
+	}
+	// An order already cancelled is in the requested state whatever version
+	// the caller expected, so retried cancellations still succeed
+	if o.Status == "cancelled" {
+		return o, false, nil
+	}
+	if ifVersions != nil && !slices.Contains(ifVersions, o.Version) {
+		return o, false, ErrVersionMismatch
+	}
+	if !cancellableStatuses[o.Status] {
+		return o, false, ErrNotCancellable
+	}
+
+	o, err = s.scanOrder(tx.QueryRowContext(ctx, `
+		UPDATE orders
+		SET status = 'cancelled', previous_status = status, cancelled_at = $2, updated_at = $2
+		WHERE order_id = $1
+		RETURNING `+orderColumns,
+		orderID, at,
+	))
+	if err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("committing cancellation: %w", err)
+	}
+	return o, true, nil
+}
+
+// GetOrder returns a single order, or ErrNotFound
+func (s *Store) GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+orderColumns+` FROM orders WHERE order_id = $1`, orderID)
+	return s.scanOrder(row)
+}
+
+// ListOrders returns a page of orders, newest first
+func (s *Store) ListOrders(ctx context.Context, p ListOrdersParams) ([]Order, error) {
+	f, err := s.encryption.sealFilter(p.OrderFilter)
+	if err != nil {
+		return nil, err
+	}
+	var args queryArgs
+	conds := f.conditions(&args)
+	offset := p.Offset
+	if p.After != nil {
+		conds = append(conds, "(created_at, order_id) < ("+args.add(p.After.Time)+", "+args.add(p.After.Key)+")")
+		offset = 0
+	}
+	rows, err := s.reader().QueryContext(ctx, `
+		SELECT `+orderColumns+`
+		FROM orders
+		`+where(conds)+`
+		ORDER BY created_at DESC, order_id DESC
+		LIMIT `+args.add(p.Limit)+` OFFSET `+args.add(offset),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		o, err := s.scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing orders: %w", err)
+	}
+	return orders, nil
+}
+
+// CountOrders returns the number of orders matching f
+func (s *Store) CountOrders(ctx context.Context, f OrderFilter) (int, error) {
+	f, err := s.encryption.sealFilter(f)
+	if err != nil {
+		return 0, err
+	}
+	var args queryArgs
+	conds := f.conditions(&args)
+	var n int
+	if err := s.reader().QueryRowContext(ctx, `SELECT count(*) FROM orders `+where(conds), args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting orders: %w", err)
+	}
+	return n, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanOrder scans an order of orderColumns, decrypting its encrypted columns
+func (s *Store) scanOrder(row scanner) (*Order, error) {
+	var (
+		o                                                            Order
+		items, shippingAddress, enrichment, billingAddress, metadata []byte
+	)
+	if err := row.Scan(
+		&o.ID, &o.CustomerID, &o.Status, &o.CurrentStage, &o.TotalAmount, &o.Currency,
+		&o.ItemCount, &items, &shippingAddress, &enrichment, &o.Destination, &o.RoutingReason,
+		&o.CreatedAt, &o.UpdatedAt, &o.ValidatedAt, &o.EnrichedAt, &o.RoutedAt, &o.CancelledAt, &o.PreviousStatus, &o.Version,
+		&billingAddress, &metadata,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("scanning order: %w", err)
+	}
+	o.Items = items
+	o.ShippingAddress = shippingAddress
+	o.BillingAddress = billingAddress
+	o.Metadata = metadata
+	o.Enrichment = enrichment
+	if err := s.encryption.open(&o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// nullJSON maps empty JSON to SQL NULL
+func nullJSON(data json.RawMessage) any {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	return []byte(data)
+}
@@ -0,0 +1,1053 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/crypto"
+	"github.com/synapse/synapse/internal/store"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestStore_OrderProjection(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	// Migrations are idempotent
+	require.NoError(t, store.Migrate(ctx, infra.DB))
+
+	s := store.New(infra.DB)
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.CreateOrder(ctx, &store.Order{
+			ID:          fmt.Sprintf("order-%d", i),
+			CustomerID:  "cust-1",
+			Status:      "accepted",
+			TotalAmount: 10,
+			Currency:    "USD",
+			ItemCount:   1,
+			Items:       json.RawMessage(`[{"sku":"SKU-1","quantity":1,"unitPrice":10}]`),
+			CreatedAt:   base.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	require.NoError(t, s.MarkValidated(ctx, "order-2", base.Add(time.Hour)))
+	require.NoError(t, s.MarkRouted(ctx, "order-2", base.Add(2*time.Hour), "fulfillment", "All checks passed"))
+
+	total, err := s.CountOrders(ctx, store.OrderFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	after := base.Add(time.Minute)
+	filter := store.OrderFilter{Statuses: []string{"accepted"}, CustomerID: "cust-1", CreatedAfter: &after}
+	total, err = s.CountOrders(ctx, filter)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total, "only order-1 is accepted and created at or after base+1m")
+
+	page, err := s.ListOrders(ctx, store.ListOrdersParams{OrderFilter: store.OrderFilter{Destination: "fulfillment"}, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "order-2", page[0].ID)
+
+	page, err = s.ListOrders(ctx, store.ListOrdersParams{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "order-2", page[0].ID, "newest orders come first")
+	assert.Equal(t, "routed", page[0].Status)
+	assert.Equal(t, "fulfillment", page[0].Destination)
+	require.NotNil(t, page[0].ValidatedAt)
+
+	page, err = s.ListOrders(ctx, store.ListOrdersParams{Limit: 2, Offset: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "order-0", page[0].ID)
+
+	// Keyset pages are unaffected by orders created after the first page
+	first, err := s.ListOrders(ctx, store.ListOrdersParams{Limit: 2})
+	require.NoError(t, err)
+	require.NoError(t, s.CreateOrder(ctx, &store.Order{
+		ID: "order-new", CustomerID: "cust-1", Status: "accepted", Currency: "USD",
+		Items: json.RawMessage(`[]`), CreatedAt: base.Add(time.Hour),
+	}))
+	last := first[len(first)-1]
+	page, err = s.ListOrders(ctx, store.ListOrdersParams{Limit: 2, After: &store.Cursor{Time: last.CreatedAt, Key: last.ID}})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "order-0", page[0].ID)
+}
+
+func TestStore_ReadsFromReplica(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	// A closed handle stands for a replica that fails every query
+	replica := stdlib.OpenDBFromPool(infra.Pool)
+	require.NoError(t, replica.Close())
+	s := store.New(infra.DB).WithReplica(func() *sql.DB { return replica })
+	require.NoError(t, s.CreateOrder(ctx, &store.Order{
+		ID: "order-1", CustomerID: "cust-1", Status: "accepted", Currency: "USD",
+		Items: json.RawMessage(`[]`), CreatedAt: time.Now().UTC(),
+	}))
+	_, err = s.GetOrder(ctx, "order-1")
+	require.NoError(t, err, "single orders are read from the primary")
+	_, err = s.ListOrders(ctx, store.ListOrdersParams{Limit: 10})
+	assert.ErrorContains(t, err, "database is closed", "lists are read from the replica")
+
+	// Without a healthy replica, lists are read from the primary
+	s = store.New(infra.DB).WithReplica(func() *sql.DB { return nil })
+	orders, err := s.ListOrders(ctx, store.ListOrdersParams{Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, orders, 1)
+}
+
+func TestStore_SearchOrders(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	orders := []struct {
+		items, address string
+		amount         float64
+	}{
+		{`[{"sku":"WIDGET-001","productName":"Blue Widget","quantity":1,"unitPrice":25}]`, `{"street":"Hauptstr. 1","city":"Berlin","country":"DE"}`, 25},
+		{`[{"sku":"GADGET-002","productName":"Gadget","quantity":2,"unitPrice":250}]`, `{"street":"1 Main St","city":"Austin","country":"US"}`, 500},
+		{`[{"sku":"WIDGET-001","quantity":4,"unitPrice":25},{"sku":"GADGET-002","quantity":1,"unitPrice":250}]`, `{"street":"2 Main St","city":"Austin","country":"US"}`, 350},
+	}
+	for i, o := range orders {
+		require.NoError(t, s.CreateOrder(ctx, &store.Order{
+			ID:              fmt.Sprintf("order-%d", i),
+			CustomerID:      "cust-1",
+			Status:          "accepted",
+			TotalAmount:     o.amount,
+			Currency:        "USD",
+			ItemCount:       1,
+			Items:           json.RawMessage(o.items),
+			ShippingAddress: json.RawMessage(o.address),
+			CreatedAt:       base.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	require.NoError(t, s.MarkEnriched(ctx, "order-1", base.Add(time.Hour), json.RawMessage(`{"customer":{"tier":"gold"}}`)))
+
+	search := func(f store.OrderFilter) []string {
+		t.Helper()
+		page, err := s.ListOrders(ctx, store.ListOrdersParams{OrderFilter: f, Limit: 10})
+		require.NoError(t, err)
+		ids := []string{}
+		for _, o := range page {
+			ids = append(ids, o.ID)
+		}
+		return ids
+	}
+	minAmount, maxAmount := 100.0, 400.0
+
+	assert.Equal(t, []string{"order-2", "order-0"}, search(store.OrderFilter{SKU: "WIDGET-001"}))
+	assert.Equal(t, []string{"order-2"}, search(store.OrderFilter{SKU: "WIDGET-001", MinAmount: &minAmount}))
+	assert.Equal(t, []string{"order-2", "order-0"}, search(store.OrderFilter{MaxAmount: &maxAmount}))
+	assert.Equal(t, []string{"order-1"}, search(store.OrderFilter{CustomerTier: "gold"}))
+	assert.Equal(t, []string{"order-0"}, search(store.OrderFilter{ShippingCountry: "DE"}))
+	assert.Empty(t, search(store.OrderFilter{Currency: "EUR"}))
+	assert.Equal(t, []string{"order-0"}, search(store.OrderFilter{Text: "blue widget"}))
+	assert.Equal(t, []string{"order-2", "order-1"}, search(store.OrderFilter{Text: "austin"}))
+	assert.Equal(t, []string{"order-1"}, search(store.OrderFilter{Text: "austin -widget"}))
+
+	total, err := s.CountOrders(ctx, store.OrderFilter{SKU: "GADGET-002", ShippingCountry: "US"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+}
+
+func TestStore_CancelOrder(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	for _, id := range []string{"cancel-me", "routed"} {
+		require.NoError(t, s.CreateOrder(ctx, &store.Order{
+			ID:         id,
+			CustomerID: "cust-1",
+			Status:     "accepted",
+			Currency:   "USD",
+			Items:      json.RawMessage(`[]`),
+			CreatedAt:  base,
+		}))
+	}
+	require.NoError(t, s.MarkValidated(ctx, "cancel-me", base.Add(time.Minute)))
+	require.NoError(t, s.MarkRouted(ctx, "routed", base.Add(time.Minute), "fulfillment", "All checks passed"))
+
+	// Every update bumps the version, so a stale one fails the precondition
+	o, cancelled, err := s.CancelOrder(ctx, "cancel-me", base.Add(time.Hour), []int64{1})
+	assert.ErrorIs(t, err, store.ErrVersionMismatch)
+	assert.False(t, cancelled)
+	assert.Equal(t, int64(2), o.Version)
+
+	o, cancelled, err = s.CancelOrder(ctx, "cancel-me", base.Add(time.Hour), []int64{2})
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	assert.Equal(t, "cancelled", o.Status)
+	assert.Equal(t, "validated", o.PreviousStatus)
+	assert.Equal(t, int64(3), o.Version)
+	require.NotNil(t, o.CancelledAt)
+
+	// Cancelling again is idempotent, whatever version was expected
+	o, cancelled, err = s.CancelOrder(ctx, "cancel-me", base.Add(2*time.Hour), []int64{2})
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+	assert.True(t, o.CancelledAt.Equal(base.Add(time.Hour)))
+
+	// Later stages drop cancelled orders
+	assert.ErrorIs(t, s.MarkEnriched(ctx, "cancel-me", base.Add(3*time.Hour), nil), store.ErrNotFound)
+
+	o, _, err = s.CancelOrder(ctx, "routed", base.Add(time.Hour), nil)
+	assert.ErrorIs(t, err, store.ErrNotCancellable)
+	assert.Equal(t, "routed", o.Status)
+
+	_, _, err = s.CancelOrder(ctx, "missing", base, nil)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestStore_Encryption(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	key1 := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32)))
+	key2 := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 32)))
+	k1, err := crypto.ParseKeyring("k1:"+key1, "")
+	require.NoError(t, err)
+	plain := store.New(infra.DB)
+	s := plain.WithEncryption(k1, store.EncryptableColumns)
+
+	address := json.RawMessage(`{"street":"1 Main St","country":"US"}`)
+	order := func(id string) *store.Order {
+		return &store.Order{
+			ID: id, CustomerID: "cust-1", Status: "accepted", Currency: "USD",
+			Items: json.RawMessage(`[]`), ShippingAddress: address, BillingAddress: address,
+			Metadata: json.RawMessage(`{"channel":"web"}`), CreatedAt: time.Now().UTC(),
+		}
+	}
+	require.NoError(t, plain.CreateOrder(ctx, order("before")))
+	require.NoError(t, s.CreateOrder(ctx, order("after")))
+
+	var customerID, shippingAddress, billingAddress string
+	require.NoError(t, infra.DB.QueryRowContext(ctx,
+		`SELECT customer_id, shipping_address::text, billing_address::text FROM orders WHERE order_id = 'after'`,
+	).Scan(&customerID, &shippingAddress, &billingAddress))
+	assert.True(t, crypto.IsSealed(customerID))
+	assert.NotContains(t, shippingAddress, "Main St")
+	assert.NotContains(t, billingAddress, "Main St")
+
+	o, err := s.GetOrder(ctx, "after")
+	require.NoError(t, err)
+	assert.Equal(t, "cust-1", o.CustomerID)
+	assert.JSONEq(t, string(address), string(o.ShippingAddress))
+	assert.JSONEq(t, string(address), string(o.BillingAddress))
+	assert.JSONEq(t, `{"channel":"web"}`, string(o.Metadata))
+
+	// Orders are filtered by customer whether they were encrypted or not
+	n, err := s.CountOrders(ctx, store.OrderFilter{CustomerID: "cust-1"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// After a rotation, the old key still reads, and re-encryption moves
+	// every order to the new one without changing their versions
+	k2, err := crypto.ParseKeyring("k1:"+key1+",k2:"+key2, "k2")
+	require.NoError(t, err)
+	rotated := plain.WithEncryption(k2, store.EncryptableColumns)
+	n, err = rotated.ReencryptOrders(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = rotated.ReencryptOrders(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = rotated.ReencryptOrders(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	reencrypted, err := rotated.GetOrder(ctx, "after")
+	require.NoError(t, err)
+	assert.Equal(t, o.Version, reencrypted.Version)
+
+	k2Only, err := crypto.ParseKeyring("k2:"+key2, "")
+	require.NoError(t, err)
+	orders, err := plain.WithEncryption(k2Only, store.EncryptableColumns).ListOrders(ctx, store.ListOrdersParams{OrderFilter: store.OrderFilter{CustomerID: "cust-1"}, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	for _, o := range orders {
+		assert.Equal(t, "cust-1", o.CustomerID)
+		assert.JSONEq(t, string(address), string(o.ShippingAddress))
+		assert.JSONEq(t, string(address), string(o.BillingAddress))
+	}
+
+	// By default only customer IDs are encrypted, so orders can still be
+	// filtered by shipping country; with shipping addresses encrypted, the
+	// filter is refused rather than matching nothing
+	byDefault := plain.WithEncryption(k2, nil)
+	require.NoError(t, byDefault.CreateOrder(ctx, order("default")))
+	require.NoError(t, infra.DB.QueryRowContext(ctx,
+		`SELECT customer_id FROM orders WHERE order_id = 'default'`).Scan(&customerID))
+	assert.True(t, crypto.IsSealed(customerID))
+	orders, err = byDefault.ListOrders(ctx, store.ListOrdersParams{OrderFilter: store.OrderFilter{ShippingCountry: "US"}, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "default", orders[0].ID)
+
+	_, err = rotated.ListOrders(ctx, store.ListOrdersParams{OrderFilter: store.OrderFilter{ShippingCountry: "US"}, Limit: 10})
+	assert.ErrorIs(t, err, store.ErrUnsupportedFilter)
+	_, err = rotated.CountOrders(ctx, store.OrderFilter{Text: "Main"})
+	assert.ErrorIs(t, err, store.ErrUnsupportedFilter)
+}
+
+func TestStore_OrderEvents(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, s.CreateOrder(ctx, &store.Order{
+		ID:         "order-events",
+		CustomerID: "cust-1",
+		Status:     "accepted",
+		Currency:   "USD",
+		Items:      json.RawMessage(`[]`),
+		CreatedAt:  base,
+	}))
+
+	for i, stage := range []string{"ingest", "validate", "enrich"} {
+		require.NoError(t, s.AppendEvent(ctx, &store.Event{
+			ID:         "evt-" + stage,
+			OrderID:    "order-events",
+			Type:       "Order" + stage,
+			Stage:      stage,
+			Status:     "completed",
+			OccurredAt: base.Add(time.Duration(i) * time.Second),
+			Metadata:   json.RawMessage(`{"step":` + fmt.Sprint(i) + `}`),
+		}))
+	}
+	// Redelivered events are ignored
+	require.NoError(t, s.AppendEvent(ctx, &store.Event{
+		ID: "evt-validate", OrderID: "order-events", Type: "OrderValidated",
+		Stage: "validate", Status: "completed", OccurredAt: base.Add(time.Hour),
+	}))
+
+	total, err := s.CountEvents(ctx, "order-events")
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	events, err := s.ListEvents(ctx, "order-events", store.ListEventsParams{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "ingest", events[0].Stage)
+	assert.JSONEq(t, `{"step":0}`, string(events[0].Metadata))
+
+	events, err = s.ListEvents(ctx, "order-events", store.ListEventsParams{Limit: 2, Offset: 1, Descending: true})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "validate", events[0].Stage)
+	assert.Equal(t, "ingest", events[1].Stage)
+}
+
+func TestStore_APIKeys(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	created := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, s.CreateAPIKey(ctx, &store.APIKey{
+		ID:        "3f1c2b9e-8d4a-4c7e-9f0b-2a6d5e8c1b47",
+		Name:      "checkout",
+		Prefix:    "syn_abcdefgh",
+		Hash:      "hash-1",
+		Scopes:    []string{"admin"},
+		CreatedAt: created,
+	}))
+
+	k, err := s.GetAPIKeyByHash(ctx, "hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, "checkout", k.Name)
+	assert.Equal(t, []string{"admin"}, k.Scopes)
+	assert.Nil(t, k.LastUsedAt)
+
+	require.NoError(t, s.TouchAPIKey(ctx, k.ID, created.Add(time.Minute)))
+	keys, err := s.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NotNil(t, keys[0].LastUsedAt)
+
+	revoked, err := s.RevokeAPIKey(ctx, k.ID, created.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "hash-1", revoked.Hash)
+
+	// Revoked keys no longer authenticate and can't be revoked again
+	_, err = s.GetAPIKeyByHash(ctx, "hash-1")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	_, err = s.RevokeAPIKey(ctx, k.ID, created.Add(time.Hour))
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	keys, err = s.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestStore_ImportJobs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	created := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	job := &store.ImportJob{
+		ID:        "9b2e4f71-3c5d-4a8e-b6f0-1d7c8e2a4b35",
+		Status:    "processing",
+		Format:    "csv",
+		CreatedAt: created,
+	}
+	require.NoError(t, s.CreateImportJob(ctx, job))
+
+	got, err := s.GetImportJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "processing", got.Status)
+	assert.JSONEq(t, `[]`, string(got.Errors))
+	assert.Empty(t, got.Error)
+	assert.Nil(t, got.CompletedAt)
+
+	completed := created.Add(time.Minute)
+	job.Status = "failed"
+	job.Processed, job.Accepted, job.Rejected, job.Skipped = 10, 7, 2, 1
+	job.Errors = json.RawMessage(`[{"row":3,"errors":[{"field":"currency","code":"pattern","message":"bad"}]}]`)
+	job.Error = "ingesting order on line 11: nats: connection closed"
+	job.UpdatedAt = completed
+	job.CompletedAt = &completed
+	require.NoError(t, s.UpdateImportJob(ctx, job))
+
+	got, err = s.GetImportJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 7, got.Accepted)
+	assert.Equal(t, job.Error, got.Error)
+	assert.JSONEq(t, string(job.Errors), string(got.Errors))
+	require.NotNil(t, got.CompletedAt)
+	assert.True(t, completed.Equal(*got.CompletedAt))
+
+	_, err = s.GetImportJob(ctx, "missing")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	assert.ErrorIs(t, s.UpdateImportJob(ctx, &store.ImportJob{ID: "missing"}), store.ErrNotFound)
+}
+
+func TestStore_ExportJobs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	created := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	job := &store.ExportJob{
+		ID:        "d3a7c9e2-5b1f-4c8d-9e6a-7f2b4a1c8e53",
+		Status:    "processing",
+		Format:    "csv",
+		Filter:    json.RawMessage(`{"status":["routed"]}`),
+		Matched:   2,
+		CreatedAt: created,
+	}
+	require.NoError(t, s.CreateExportJob(ctx, job))
+
+	got, err := s.GetExportJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "processing", got.Status)
+	assert.JSONEq(t, `{"status":["routed"]}`, string(got.Filter))
+	assert.Nil(t, got.ExpiresAt)
+	_, err = s.ExportFile(ctx, job.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound, "no file before the export completes")
+
+	job.Exported = 1
+	require.NoError(t, s.UpdateExportJob(ctx, job))
+
+	completed := created.Add(time.Minute)
+	expires := completed.Add(24 * time.Hour)
+	content := []byte("orderId\n1\n2\n")
+	job.Status = "completed"
+	job.Exported = 2
+	job.SizeBytes = int64(len(content))
+	job.UpdatedAt = completed
+	job.CompletedAt = &completed
+	job.ExpiresAt = &expires
+	require.NoError(t, s.SaveExportFile(ctx, job, content))
+
+	got, err = s.GetExportJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Exported)
+	assert.Equal(t, int64(len(content)), got.SizeBytes)
+	require.NotNil(t, got.ExpiresAt)
+	assert.True(t, expires.Equal(*got.ExpiresAt))
+	file, err := s.ExportFile(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, content, file)
+
+	n, err := s.DeleteExpiredExportJobs(ctx, expires.Add(-time.Second))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	n, err = s.DeleteExpiredExportJobs(ctx, expires)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = s.GetExportJob(ctx, job.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	assert.ErrorIs(t, s.UpdateExportJob(ctx, &store.ExportJob{ID: "missing"}), store.ErrNotFound)
+}
+
+func TestStore_WebhookSubscriptions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	created := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	sub := &store.WebhookSubscription{
+		ID:         "3f6c1a2e-8d4b-4e7f-9a1c-5b2d7e8f0a13",
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: []string{"order.routed"},
+		Secret:     "whsec_test",
+		Active:     true,
+		CreatedAt:  created,
+		UpdatedAt:  created,
+	}
+	require.NoError(t, s.CreateWebhookSubscription(ctx, sub))
+
+	sub.EventTypes = []string{"order.routed", "order.rejected"}
+	sub.Active = false
+	sub.UpdatedAt = created.Add(time.Minute)
+	require.NoError(t, s.UpdateWebhookSubscription(ctx, sub))
+
+	got, err := s.GetWebhookSubscription(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sub.EventTypes, got.EventTypes)
+	assert.False(t, got.Active)
+
+	all, err := s.ListWebhookSubscriptions(ctx, false)
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+	active, err := s.ListWebhookSubscriptions(ctx, true)
+	require.NoError(t, err)
+	assert.Empty(t, active, "paused subscriptions aren't active")
+
+	// Attempts are listed newest first; ones for unknown subscribers are dropped
+	for i, status := range []string{"failed", "succeeded"} {
+		require.NoError(t, s.RecordWebhookDelivery(ctx, &store.WebhookDelivery{
+			ID:             fmt.Sprintf("delivery-%d", i+1),
+			SubscriptionID: sub.ID,
+			EventID:        "event-1",
+			EventType:      "order.routed",
+			Attempt:        i + 1,
+			Status:         status,
+			StatusCode:     []int{503, 200}[i],
+			AttemptedAt:    created.Add(time.Duration(i) * time.Second),
+		}))
+	}
+	require.NoError(t, s.RecordWebhookDelivery(ctx, &store.WebhookDelivery{
+		ID: "delivery-static", SubscriptionID: "static", EventID: "event-1", EventType: "order.routed", Attempt: 1, Status: "succeeded", AttemptedAt: created,
+	}))
+
+	deliveries, err := s.ListWebhookDeliveries(ctx, sub.ID, store.ListDeliveriesParams{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "succeeded", deliveries[0].Status)
+	assert.Equal(t, 200, deliveries[0].StatusCode)
+
+	last := deliveries[0]
+	deliveries, err = s.ListWebhookDeliveries(ctx, sub.ID, store.ListDeliveriesParams{
+		Limit: 10,
+		After: &store.Cursor{Time: last.AttemptedAt, Key: strconv.FormatInt(last.Seq, 10)},
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, 1, deliveries[0].Attempt)
+	assert.Equal(t, 503, deliveries[0].StatusCode)
+
+	// Deleting a subscription deletes its history
+	require.NoError(t, s.DeleteWebhookSubscription(ctx, sub.ID))
+	deliveries, err = s.ListWebhookDeliveries(ctx, sub.ID, store.ListDeliveriesParams{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+
+	_, err = s.GetWebhookSubscription(ctx, sub.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	assert.ErrorIs(t, s.DeleteWebhookSubscription(ctx, sub.ID), store.ErrNotFound)
+	assert.ErrorIs(t, s.UpdateWebhookSubscription(ctx, sub), store.ErrNotFound)
+}
+
+func TestStore_StageOverrides(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	updated := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, s.SaveStageOverride(ctx, &store.StageOverride{
+		StageID:   "route",
+		Config:    json.RawMessage(`{"concurrency":5}`),
+		UpdatedAt: updated,
+	}))
+	// Saving again replaces the override
+	require.NoError(t, s.SaveStageOverride(ctx, &store.StageOverride{
+		StageID:   "enrich",
+		Paused:    true,
+		Config:    json.RawMessage(`{"concurrency":2}`),
+		UpdatedAt: updated,
+	}))
+	require.NoError(t, s.SaveStageOverride(ctx, &store.StageOverride{
+		StageID:   "enrich",
+		Config:    json.RawMessage(`{"concurrency":3}`),
+		UpdatedAt: updated.Add(time.Minute),
+	}))
+
+	overrides, err := s.ListStageOverrides(ctx)
+	require.NoError(t, err)
+	require.Len(t, overrides, 2)
+	assert.Equal(t, "enrich", overrides[0].StageID)
+	assert.False(t, overrides[0].Paused)
+	assert.JSONEq(t, `{"concurrency":3}`, string(overrides[0].Config))
+	assert.True(t, overrides[0].UpdatedAt.Equal(updated.Add(time.Minute)))
+	assert.Equal(t, "route", overrides[1].StageID)
+}
+
+func TestStore_DLQItems(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	stages := []string{"validate", "enrich", "enrich", "route", "enrich"}
+	for i, stage := range stages {
+		errorType := "timeout"
+		if stage == "validate" {
+			errorType = "validation"
+		}
+		require.NoError(t, s.SaveDLQItem(ctx, &store.DLQItem{
+			EventID:      fmt.Sprintf("event-%d", i),
+			OrderID:      fmt.Sprintf("order-%d", i),
+			Stage:        stage,
+			Topic:        "orders.validated",
+			ErrorType:    errorType,
+			ErrorMessage: "context deadline exceeded",
+			Payload:      []byte(`{"orderId":"order-` + strconv.Itoa(i) + `"}`),
+			Metadata:     map[string]string{"correlationId": fmt.Sprintf("order-%d", i)},
+			Preview:      `{"orderId":"order-` + strconv.Itoa(i) + `"}`,
+			RetryCount:   3,
+			FailedAt:     base.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	// Dead-lettering a message again replaces its record
+	require.NoError(t, s.SaveDLQItem(ctx, &store.DLQItem{
+		EventID:      "event-4",
+		OrderID:      "order-4",
+		Stage:        "enrich",
+		Topic:        "orders.validated",
+		ErrorType:    "external-service",
+		ErrorMessage: "connection refused",
+		Payload:      []byte(`{}`),
+		Metadata:     map[string]string{},
+		FailedAt:     base.Add(10 * time.Minute),
+	}))
+
+	after := base.Add(time.Minute)
+	filter := store.DLQFilter{Stages: []string{"enrich"}, FailedAfter: &after}
+	total, err := s.CountDLQItems(ctx, filter)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	// Most recent failure first, paging with a cursor
+	page, err := s.ListDLQItems(ctx, store.ListDLQParams{DLQFilter: filter, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "event-4", page[0].EventID)
+	assert.Equal(t, "external-service", page[0].ErrorType)
+	assert.Empty(t, page[0].Metadata)
+	assert.Equal(t, "event-2", page[1].EventID)
+	assert.Equal(t, map[string]string{"correlationId": "order-2"}, page[1].Metadata)
+	assert.JSONEq(t, `{"orderId":"order-2"}`, string(page[1].Payload))
+	assert.Nil(t, page[1].LastRetryAt)
+
+	last := page[1]
+	page, err = s.ListDLQItems(ctx, store.ListDLQParams{
+		DLQFilter: filter,
+		Limit:     2,
+		After:     &store.Cursor{Time: last.FailedAt, Key: last.EventID},
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "event-1", page[0].EventID)
+
+	total, err = s.CountDLQItems(ctx, store.DLQFilter{ErrorTypes: []string{"validation"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
+
+func TestStore_DLQRequeueAndPurge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	item := &store.DLQItem{
+		EventID:      "event-1",
+		OrderID:      "order-1",
+		Stage:        "enrich",
+		Topic:        "orders.validated",
+		ErrorType:    "timeout",
+		ErrorMessage: "deadline exceeded",
+		Payload:      []byte(`{}`),
+		Metadata:     map[string]string{},
+		RetryCount:   3,
+		FailedAt:     base,
+	}
+	require.NoError(t, s.SaveDLQItem(ctx, item))
+
+	// A requeued item is off the DLQ until it is dead-lettered again
+	retried := base.Add(time.Hour)
+	require.NoError(t, s.RequeueDLQItem(ctx, "event-1", retried))
+	assert.ErrorIs(t, s.RequeueDLQItem(ctx, "event-1", retried), store.ErrNotFound)
+	_, err = s.GetDLQItem(ctx, "event-1")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	item.FailedAt = retried.Add(time.Minute)
+	require.NoError(t, s.SaveDLQItem(ctx, item))
+	got, err := s.GetDLQItem(ctx, "event-1")
+	require.NoError(t, err)
+	assert.Equal(t, 6, got.RetryCount)
+	require.NotNil(t, got.LastRetryAt)
+	assert.True(t, retried.Equal(*got.LastRetryAt))
+
+	// Restoring puts an item back as it was
+	require.NoError(t, s.RequeueDLQItem(ctx, "event-1", retried.Add(time.Hour)))
+	require.NoError(t, s.RestoreDLQItem(ctx, "event-1", got.LastRetryAt))
+	got, err = s.GetDLQItem(ctx, "event-1")
+	require.NoError(t, err)
+	assert.True(t, retried.Equal(*got.LastRetryAt))
+
+	item.EventID, item.Stage = "event-2", "route"
+	require.NoError(t, s.SaveDLQItem(ctx, item))
+	n, err := s.DeleteDLQItems(ctx, store.DLQFilter{Stages: []string{"enrich"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	total, err := s.CountDLQItems(ctx, store.DLQFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	job := &store.DLQJob{
+		ID:        "4f8a2c1e-7b3d-4e9f-a6c5-2d1b8e7f3a90",
+		Action:    "retry",
+		Status:    "processing",
+		Filter:    json.RawMessage(`{"failedStage":["route"]}`),
+		Matched:   1,
+		CreatedAt: base,
+	}
+	require.NoError(t, s.CreateDLQJob(ctx, job))
+
+	completed := base.Add(time.Minute)
+	job.Status = "completed"
+	job.Processed, job.Succeeded = 1, 1
+	job.UpdatedAt = completed
+	job.CompletedAt = &completed
+	require.NoError(t, s.UpdateDLQJob(ctx, job))
+
+	gotJob, err := s.GetDLQJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", gotJob.Status)
+	assert.Equal(t, 1, gotJob.Succeeded)
+	assert.Empty(t, gotJob.Error)
+	assert.JSONEq(t, string(job.Filter), string(gotJob.Filter))
+	require.NotNil(t, gotJob.CompletedAt)
+	assert.True(t, completed.Equal(*gotJob.CompletedAt))
+
+	_, err = s.GetDLQJob(ctx, "missing")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestStore_PipelineStats(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, s.CreateOrder(ctx, &store.Order{
+		ID: "order-1", CustomerID: "c1", Status: "received", TotalAmount: 10, Currency: "USD",
+		Items: json.RawMessage(`[]`), CreatedAt: start,
+	}))
+	for i, e := range []store.Event{
+		{Stage: "validate", Status: "completed", OccurredAt: start.Add(time.Minute), DurationMs: 10},
+		{Stage: "validate", Status: "completed", OccurredAt: start.Add(2 * time.Minute), DurationMs: 30},
+		{Stage: "validate", Status: "failed", OccurredAt: start.Add(3 * time.Minute), DurationMs: 5},
+		{Stage: "enrich", Status: "completed", OccurredAt: start.Add(6 * time.Minute), DurationMs: 100},
+		{Stage: "cancel", Status: "completed", OccurredAt: start.Add(6 * time.Minute)},
+	} {
+		e.ID = fmt.Sprintf("event-%d", i)
+		e.OrderID = "order-1"
+		e.Type = "test"
+		require.NoError(t, s.AppendEvent(ctx, &e))
+	}
+
+	stats, err := s.PipelineStats(ctx, start, start.Add(10*time.Minute), 5*time.Minute, []string{"validate", "enrich"})
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.True(t, start.Equal(stats[0].Bucket))
+	assert.Equal(t, "validate", stats[0].Stage)
+	assert.Equal(t, 2, stats[0].Completed)
+	assert.Equal(t, 1, stats[0].Failed)
+	assert.InDelta(t, 20, stats[0].P50, 0.001)
+	assert.True(t, start.Add(5*time.Minute).Equal(stats[1].Bucket))
+	assert.Equal(t, "enrich", stats[1].Stage)
+	assert.InDelta(t, 100, stats[1].P99, 0.001)
+
+	require.NoError(t, s.SaveDLQItem(ctx, &store.DLQItem{
+		EventID: "dlq-1", OrderID: "order-1", Stage: "enrich", Topic: "orders.validated",
+		ErrorType: "timeout", Payload: []byte(`{}`), FailedAt: start.Add(7 * time.Minute),
+	}))
+	arrivals, err := s.DLQArrivals(ctx, start, start.Add(10*time.Minute), 5*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, arrivals, 1)
+	assert.True(t, start.Add(5*time.Minute).Equal(arrivals[0].Bucket))
+	assert.Equal(t, 1, arrivals[0].Count)
+}
+
+func TestStore_PipelineErrors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	for i, stage := range []string{"enrich", "enrich", "validate"} {
+		errorType := "timeout"
+		if stage == "validate" {
+			errorType = "validation"
+		}
+		require.NoError(t, s.SavePipelineError(ctx, &store.PipelineError{
+			ID:         fmt.Sprintf("error-%d", i),
+			EventID:    fmt.Sprintf("event-%d", i/2),
+			OrderID:    "order-1",
+			Stage:      stage,
+			ErrorType:  errorType,
+			Message:    "failed",
+			OccurredAt: base.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	// The second enrich attempt gave up on event-0
+	require.NoError(t, s.SaveDLQItem(ctx, &store.DLQItem{
+		EventID: "event-0", OrderID: "order-1", Stage: "enrich", Topic: "orders.validated",
+		ErrorType: "timeout", ErrorMessage: "failed", Payload: []byte(`{}`), FailedAt: base.Add(time.Minute),
+	}))
+
+	errs, err := s.ListPipelineErrors(ctx, store.ListPipelineErrorsParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, errs, 3)
+	assert.Equal(t, "error-2", errs[0].ID, "most recent first")
+	assert.False(t, errs[0].InDLQ)
+	assert.True(t, errs[1].InDLQ)
+	assert.True(t, errs[2].InDLQ)
+
+	f := store.PipelineErrorFilter{Stages: []string{"enrich"}, OccurredAfter: &base}
+	errs, err = s.ListPipelineErrors(ctx, store.ListPipelineErrorsParams{PipelineErrorFilter: f, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "error-1", errs[0].ID)
+	errs, err = s.ListPipelineErrors(ctx, store.ListPipelineErrorsParams{
+		PipelineErrorFilter: f,
+		Limit:               1,
+		After:               &store.Cursor{Time: errs[0].OccurredAt, Key: errs[0].ID},
+	})
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "error-0", errs[0].ID)
+
+	n, err := s.CountPipelineErrors(ctx, f)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	require.NoError(t, s.RequeueDLQItem(ctx, "event-0", base.Add(time.Hour)))
+	errs, err = s.ListPipelineErrors(ctx, store.ListPipelineErrorsParams{Limit: 10})
+	require.NoError(t, err)
+	for _, e := range errs {
+		assert.False(t, e.InDLQ, "requeued messages are off the DLQ")
+	}
+}
+
+func TestStore_AuditLog(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	s := store.New(infra.DB)
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	entries := []store.AuditEntry{
+		{ID: "audit-1", ActorID: "key-1", ActorMethod: "api_key", Method: "DELETE", Route: "/api/v1/orders/{orderId}",
+			Path: "/api/v1/orders/order-1", Params: map[string]string{"orderId": "order-1"}, StatusCode: 200, OccurredAt: base},
+		{ID: "audit-2", ActorID: "key-2", ActorMethod: "api_key", Method: "POST", Route: "/api/v1/pipeline/dlq/{eventId}/retry",
+			Path: "/api/v1/pipeline/dlq/event-1/retry", Params: map[string]string{"eventId": "event-1"}, BodyBytes: 20, StatusCode: 202, OccurredAt: base.Add(time.Minute)},
+		{ID: "audit-3", ActorID: "key-1", ActorMethod: "api_key", Method: "DELETE", Route: "/api/v1/orders/{orderId}",
+			Path: "/api/v1/orders/order-2", Params: map[string]string{"orderId": "order-2"}, StatusCode: 409, OccurredAt: base.Add(2 * time.Minute)},
+	}
+	for i := range entries {
+		require.NoError(t, s.RecordAudit(ctx, &entries[i]))
+	}
+
+	all, err := s.ListAuditEntries(ctx, store.ListAuditParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, "audit-3", all[0].ID, "newest first")
+	assert.Equal(t, map[string]string{"eventId": "event-1"}, all[1].Params)
+	assert.Equal(t, int64(20), all[1].BodyBytes)
+
+	cancellations := store.AuditFilter{Methods: []string{"DELETE"}, Route: "/api/v1/orders/{orderId}"}
+	got, err := s.ListAuditEntries(ctx, store.ListAuditParams{AuditFilter: cancellations, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+	n, err := s.CountAuditEntries(ctx, cancellations)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	got, err = s.ListAuditEntries(ctx, store.ListAuditParams{AuditFilter: store.AuditFilter{Outcome: "failure"}, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "audit-3", got[0].ID)
+
+	got, err = s.ListAuditEntries(ctx, store.ListAuditParams{AuditFilter: store.AuditFilter{ActorID: "key-2"}, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "audit-2", got[0].ID)
+
+	// Keyset pagination continues after the cursor
+	after := &store.Cursor{Time: all[0].OccurredAt, Key: strconv.FormatInt(all[0].Seq, 10)}
+	got, err = s.ListAuditEntries(ctx, store.ListAuditParams{Limit: 10, After: after})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "audit-2", got[0].ID)
+}
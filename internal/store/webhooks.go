@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// WebhookSubscription is a webhook subscriber managed through the API
+type WebhookSubscription struct {
+	ID          string
+	URL         string
+	EventTypes  []string
+	Secret      string
+	Description string
+	Active      bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// WebhookDelivery is one attempt to deliver a notification to a subscription
+type WebhookDelivery struct {
+	ID             string
+	Seq            int64
+	SubscriptionID string
+	EventID        string
+	EventType      string
+	Attempt        int
+	Status         string
+	StatusCode     int
+	Error          string
+	DurationMs     int
+	AttemptedAt    time.Time
+}
+
+// ListDeliveriesParams selects a page of a subscription's deliveries, newest
+// first. When After is set the page starts after that position and Offset is
+// ignored; the cursor key is the delivery's Seq.
+type ListDeliveriesParams struct {
+	Limit  int
+	Offset int
+	After  *Cursor
+}
+
+const webhookSubscriptionColumns = `subscription_id, url, event_types, secret, description, active, created_at, updated_at`
+
+// CreateWebhookSubscription inserts a webhook subscription
+func (s *Store) CreateWebhookSubscription(ctx context.Context, sub *WebhookSubscription) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_subscriptions (`+webhookSubscriptionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		sub.ID, sub.URL, sub.EventTypes, sub.Secret, sub.Description, sub.Active, sub.CreatedAt, sub.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting webhook subscription %s: %w", sub.ID, err)
+	}
+	return nil
+}
+
+// GetWebhookSubscription returns a webhook subscription, or ErrNotFound
+func (s *Store) GetWebhookSubscription(ctx context.Context, id string) (*WebhookSubscription, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+webhookSubscriptionColumns+`
+		FROM webhook_subscriptions
+		WHERE subscription_id = $1`, id)
+	return scanWebhookSubscription(row)
+}
+
+// ListWebhookSubscriptions returns the webhook subscriptions, oldest first.
+// With activeOnly set, paused subscriptions are left out.
+func (s *Store) ListWebhookSubscriptions(ctx context.Context, activeOnly bool) ([]WebhookSubscription, error) {
+	var conds []string
+	if activeOnly {
+		conds = append(conds, "active")
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+webhookSubscriptionColumns+`
+		FROM webhook_subscriptions
+		`+where(conds)+`
+		ORDER BY created_at, subscription_id`)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []WebhookSubscription
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// UpdateWebhookSubscription saves a subscription's settings, or returns
+// ErrNotFound
+func (s *Store) UpdateWebhookSubscription(ctx context.Context, sub *WebhookSubscription) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET url = $2, event_types = $3, secret = $4, description = $5, active = $6, updated_at = $7
+		WHERE subscription_id = $1`,
+		sub.ID, sub.URL, sub.EventTypes, sub.Secret, sub.Description, sub.Active, sub.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("updating webhook subscription %s: %w", sub.ID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("webhook subscription %s: %w", sub.ID, ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteWebhookSubscription deletes a subscription and its delivery history,
+// or returns ErrNotFound
+func (s *Store) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE subscription_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("deleting webhook subscription %s: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("webhook subscription %s: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// RecordWebhookDelivery records a delivery attempt. Attempts for subscribers
+// that aren't stored, e.g. ones from configuration, are ignored.
+func (s *Store) RecordWebhookDelivery(ctx context.Context, d *WebhookDelivery) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (delivery_id, subscription_id, event_id, event_type, attempt, status, status_code, error, duration_ms, attempted_at)
+		SELECT $1, $2, $3, $4, $5, $6, NULLIF($7, 0), NULLIF($8, ''), $9, $10
+		WHERE EXISTS (SELECT 1 FROM webhook_subscriptions WHERE subscription_id = $2)`,
+		d.ID, d.SubscriptionID, d.EventID, d.EventType, d.Attempt, d.Status, d.StatusCode, d.Error, d.DurationMs, d.AttemptedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting delivery %s of event %s: %w", d.ID, d.EventID, err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns a page of a subscription's delivery attempts,
+// newest first
+func (s *Store) ListWebhookDeliveries(ctx context.Context, subscriptionID string, p ListDeliveriesParams) ([]WebhookDelivery, error) {
+	var args queryArgs
+	conds := []string{"subscription_id = " + args.add(subscriptionID)}
+	offset := p.Offset
+	if p.After != nil {
+		seq, err := strconv.ParseInt(p.After.Key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delivery cursor key %q: %w", p.After.Key, err)
+		}
+		conds = append(conds, "(attempted_at, seq) < ("+args.add(p.After.Time)+", "+args.add(seq)+")")
+		offset = 0
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT delivery_id, seq, subscription_id, event_id, event_type, attempt, status, status_code, error, duration_ms, attempted_at
+		FROM webhook_deliveries
+		`+where(conds)+`
+		ORDER BY attempted_at DESC, seq DESC
+		LIMIT `+args.add(p.Limit)+` OFFSET `+args.add(offset),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing deliveries of webhook subscription %s: %w", subscriptionID, err)
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var (
+			d          WebhookDelivery
+			statusCode sql.NullInt64
+			errText    sql.NullString
+		)
+		if err := rows.Scan(&d.ID, &d.Seq, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Attempt, &d.Status,
+			&statusCode, &errText, &d.DurationMs, &d.AttemptedAt); err != nil {
+			return nil, fmt.Errorf("scanning webhook delivery: %w", err)
+		}
+		d.StatusCode = int(statusCode.Int64)
+		d.Error = errText.String
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing deliveries of webhook subscription %s: %w", subscriptionID, err)
+	}
+	return deliveries, nil
+}
+
+func scanWebhookSubscription(row scanner) (*WebhookSubscription, error) {
+	var sub WebhookSubscription
+	err := row.Scan(&sub.ID, &sub.URL, textArray(&sub.EventTypes), &sub.Secret, &sub.Description, &sub.Active, &sub.CreatedAt, &sub.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning webhook subscription: %w", err)
+	}
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+	return &sub, nil
+}
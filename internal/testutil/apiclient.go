@@ -0,0 +1,190 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/internal/auth"
+)
+
+// IdempotencyKeyHeader lets clients retry requests safely
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// APIClient calls an API served for a test, adding the headers
+// authentication and idempotency need to every request. Its With methods
+// return a copy, so one server can be called as several clients:
+//
+//	api := testutil.NewAPIClient(t, router)
+//	admin := api.WithToken(issuer.Token(t, "admin", "acme", auth.ScopeAdmin))
+//	accepted := testutil.Decode[generated.OrderAcceptedResponse](admin.Post("/api/v1/orders", order), http.StatusAccepted)
+type APIClient struct {
+	// URL is the server's base URL
+	URL string
+
+	t      testing.TB
+	client *http.Client
+	header http.Header
+	// idempotent sends a fresh Idempotency-Key with every request
+	idempotent bool
+}
+
+// NewAPIClient serves h until t finishes, and returns a client of it
+func NewAPIClient(t testing.TB, h http.Handler) *APIClient {
+	t.Helper()
+
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return &APIClient{URL: srv.URL, t: t, client: srv.Client(), header: make(http.Header)}
+}
+
+func (c *APIClient) with(key, value string) *APIClient {
+	clone := *c
+	clone.header = c.header.Clone()
+	clone.header.Set(key, value)
+	return &clone
+}
+
+// WithT returns a client failing t rather than the test it was created
+// for, e.g. to call the server from subtests
+func (c *APIClient) WithT(t testing.TB) *APIClient {
+	clone := *c
+	clone.t = t
+	return &clone
+}
+
+// WithHeader returns a client also sending header key
+func (c *APIClient) WithHeader(key, value string) *APIClient {
+	return c.with(key, value)
+}
+
+// WithAPIKey returns a client authenticating with an API key
+func (c *APIClient) WithAPIKey(key string) *APIClient {
+	return c.with(auth.APIKeyHeader, key)
+}
+
+// WithToken returns a client authenticating with a bearer token
+func (c *APIClient) WithToken(token string) *APIClient {
+	return c.with("Authorization", "Bearer "+token)
+}
+
+// AsTenant returns a client authenticating as subject of tenant, with a
+// token of issuer granting scopes
+func (c *APIClient) AsTenant(issuer *Issuer, subject, tenant string, scopes ...string) *APIClient {
+	c.t.Helper()
+	return c.WithToken(issuer.Token(c.t, subject, tenant, scopes...))
+}
+
+// WithIdempotencyKey returns a client sending key as every request's
+// Idempotency-Key, e.g. to replay a request
+func (c *APIClient) WithIdempotencyKey(key string) *APIClient {
+	clone := c.with(IdempotencyKeyHeader, key)
+	clone.idempotent = false
+	return clone
+}
+
+// Idempotent returns a client sending a fresh Idempotency-Key with every
+// request
+func (c *APIClient) Idempotent() *APIClient {
+	clone := *c
+	clone.header = c.header.Clone()
+	clone.header.Del(IdempotencyKeyHeader)
+	clone.idempotent = true
+	return &clone
+}
+
+// Get sends a GET request for path
+func (c *APIClient) Get(path string) *APIResponse {
+	c.t.Helper()
+	return c.Do(http.MethodGet, path, nil)
+}
+
+// Post sends body as JSON to path
+func (c *APIClient) Post(path string, body any) *APIResponse {
+	c.t.Helper()
+	return c.Do(http.MethodPost, path, body)
+}
+
+// Patch sends body as a JSON patch of path
+func (c *APIClient) Patch(path string, body any) *APIResponse {
+	c.t.Helper()
+	return c.Do(http.MethodPatch, path, body)
+}
+
+// Delete sends a DELETE request for path
+func (c *APIClient) Delete(path string) *APIResponse {
+	c.t.Helper()
+	return c.Do(http.MethodDelete, path, nil)
+}
+
+// Do sends a request for path with body, which is sent as is when []byte
+// or string and as JSON otherwise. The response body is read in full.
+func (c *APIClient) Do(method, path string, body any) *APIResponse {
+	c.t.Helper()
+
+	var reqBody io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reqBody = bytes.NewReader(b)
+	case string:
+		reqBody = bytes.NewReader([]byte(b))
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			c.t.Fatalf("encoding %s %s body: %v", method, path, err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.URL+path, reqBody)
+	if err != nil {
+		c.t.Fatalf("creating %s %s request: %v", method, path, err)
+	}
+	maps.Copy(req.Header, c.header.Clone())
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.idempotent {
+		req.Header.Set(IdempotencyKeyHeader, uuid.NewString())
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("reading %s %s response: %v", method, path, err)
+	}
+	return &APIResponse{Response: resp, Body: data, t: c.t}
+}
+
+// APIResponse is a response with its body read
+type APIResponse struct {
+	*http.Response
+	Body []byte
+
+	t testing.TB
+}
+
+// Decode decodes resp's JSON body into T, failing the test unless resp has
+// status
+func Decode[T any](resp *APIResponse, status int) T {
+	resp.t.Helper()
+
+	var v T
+	if resp.StatusCode != status {
+		resp.t.Fatalf("%s %s: got status %d, want %d: %s", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, status, resp.Body)
+	}
+	if err := json.Unmarshal(resp.Body, &v); err != nil {
+		resp.t.Fatalf("decoding %s %s response as %T: %v", resp.Request.Method, resp.Request.URL.Path, v, err)
+	}
+	return v
+}
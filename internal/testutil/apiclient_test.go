@@ -0,0 +1,65 @@
+package testutil_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/handler"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestAPIClient_Headers(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"apiKey":         r.Header.Get("X-API-Key"),
+			"idempotencyKey": r.Header.Get("Idempotency-Key"),
+			"contentType":    r.Header.Get("Content-Type"),
+		})
+	})
+	api := testutil.NewAPIClient(t, echo)
+
+	got := testutil.Decode[map[string]string](api.WithAPIKey("key-1").Post("/", map[string]int{"n": 1}), http.StatusOK)
+	assert.Equal(t, "key-1", got["apiKey"])
+	assert.Equal(t, "application/json", got["contentType"])
+	assert.Empty(t, got["idempotencyKey"])
+
+	got = testutil.Decode[map[string]string](api.Get("/"), http.StatusOK)
+	assert.Empty(t, got["apiKey"], "With methods return a copy")
+
+	idempotent := api.Idempotent()
+	first := testutil.Decode[map[string]string](idempotent.Get("/"), http.StatusOK)
+	second := testutil.Decode[map[string]string](idempotent.Get("/"), http.StatusOK)
+	assert.NotEmpty(t, first["idempotencyKey"])
+	assert.NotEqual(t, first["idempotencyKey"], second["idempotencyKey"], "a key per request")
+
+	replay := api.WithIdempotencyKey("retry-1")
+	assert.Equal(t, "retry-1", testutil.Decode[map[string]string](replay.Get("/"), http.StatusOK)["idempotencyKey"])
+}
+
+func TestAPIClient_Auth(t *testing.T) {
+	issuer := testutil.NewIssuer(t)
+	cfg := &config.Config{OpenAPISpecPath: "../../openapi/openapi.yaml"}
+	issuer.Configure(cfg)
+
+	h := handler.New(&infra.Infra{Config: cfg}, nil)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	api := testutil.NewAPIClient(t, r)
+
+	// The request is authenticated before its parameters are checked
+	problem := testutil.Decode[generated.ProblemDetails](api.Get("/api/v1/orders?limit=many"), http.StatusUnauthorized)
+	assert.Equal(t, http.StatusUnauthorized, problem.Status)
+
+	acme := api.AsTenant(issuer, "user-1", "acme")
+	problem = testutil.Decode[generated.ProblemDetails](acme.Get("/api/v1/orders?limit=many"), http.StatusBadRequest)
+	assert.Equal(t, "Validation Error", problem.Title)
+
+	expired := api.WithToken(issuer.Sign(t, map[string]any{"iss": issuer.URL, "sub": "user-1", "exp": 1}))
+	testutil.Decode[generated.ProblemDetails](expired.Get("/api/v1/orders"), http.StatusUnauthorized)
+}
@@ -0,0 +1,97 @@
+package testutil
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/handler"
+	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/pipeline"
+)
+
+// BenchContainersEnv opts benchmarks into running against test containers,
+// e.g. BENCH_CONTAINERS=1 go test -run ^$ -bench . ./internal/pipeline
+const BenchContainersEnv = "BENCH_CONTAINERS"
+
+// BenchEnv is the infrastructure a benchmark runs on
+type BenchEnv struct {
+	Infra  *infra.Infra
+	Config *config.Config
+	// Store keeps orders when Infra has no database, and is nil otherwise
+	Store *MemStore
+	// Containers is whether Infra is connected to test containers
+	Containers bool
+}
+
+// NewBenchEnv returns infrastructure for b. It is held in memory, as by
+// FakeInfra, so benchmarks run quickly and repeatably without Docker, unless
+// BENCH_CONTAINERS is set, in which case it is connected to the package's
+// shared containers.
+// Logging is discarded while b runs, as it would dominate the measurements.
+func NewBenchEnv(b *testing.B) *BenchEnv {
+	b.Helper()
+
+	logger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.Cleanup(func() { slog.SetDefault(logger) })
+
+	if os.Getenv(BenchContainersEnv) == "" {
+		fake := FakeInfra(b)
+		return &BenchEnv{Infra: fake.Infra, Config: fake.Config, Store: fake.Store}
+	}
+
+	ctx := context.Background()
+	tc, err := SharedContainers(ctx, b)
+	if err != nil {
+		b.Fatalf("starting containers: %v", err)
+	}
+	i, cfg := TestInfra(ctx, b, tc)
+	i.Config = cfg
+	return &BenchEnv{Infra: i, Config: cfg, Containers: true}
+}
+
+// Pipeline creates a pipeline on the environment and runs it until b
+// finishes. The pipeline keeps orders in Store unless there's a database.
+func (e *BenchEnv) Pipeline(b *testing.B, opts ...pipeline.Option) *pipeline.Runner {
+	b.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if e.Store != nil {
+		opts = append([]pipeline.Option{pipeline.WithOrderStore(e.Store)}, opts...)
+	}
+	runner, err := pipeline.New(ctx, e.Config, e.Infra, opts...)
+	if err != nil {
+		cancel()
+		b.Fatalf("creating pipeline: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = runner.Run(ctx)
+	}()
+	b.Cleanup(func() {
+		cancel()
+		<-done
+		_ = runner.Close()
+	})
+	<-runner.Running()
+	return runner
+}
+
+// Handler returns the API's handler on the environment, publishing to runner.
+// It reads and writes Store unless there's a database.
+func (e *BenchEnv) Handler(runner *pipeline.Runner) http.Handler {
+	var opts []handler.Option
+	if e.Store != nil {
+		opts = append(opts, handler.WithStore(e.Store))
+	}
+	r := chi.NewRouter()
+	handler.New(e.Infra, runner, opts...).RegisterRoutes(r)
+	return r
+}
@@ -0,0 +1,140 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// DefaultCaptureTimeout is how long a Capture waits for messages by default
+const DefaultCaptureTimeout = 10 * time.Second
+
+// Capture records the messages published to a topic, so tests can wait for
+// them rather than sleep:
+//
+//	routed := testutil.CaptureTopic(t, runner.Subscriber(), pipeline.TopicOrdersRouted)
+//	runner.IngestOrder(ctx, "order-1", order)
+//	msgs := routed.WaitForN(1)
+type Capture struct {
+	// Topic is the captured topic
+	Topic string
+	// Timeout bounds the waits, DefaultCaptureTimeout by default
+	Timeout time.Duration
+
+	t  testing.TB
+	mu sync.Mutex
+	// changed is closed, and replaced, when a message arrives
+	changed chan struct{}
+	msgs    []*message.Message
+}
+
+// CaptureTopic subscribes to topic until t finishes, recording the messages
+// published from then on. Messages are acked once recorded.
+func CaptureTopic(t testing.TB, sub message.Subscriber, topic string) *Capture {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs, err := sub.Subscribe(ctx, topic)
+	if err != nil {
+		cancel()
+		t.Fatalf("subscribing to %s: %v", topic, err)
+	}
+	c := &Capture{Topic: topic, Timeout: DefaultCaptureTimeout, t: t, changed: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range msgs {
+			c.record(msg)
+			msg.Ack()
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return c
+}
+
+func (c *Capture) record(msg *message.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = append(c.msgs, msg.Copy())
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Messages returns the messages captured so far, in order of arrival
+func (c *Capture) Messages() []*message.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*message.Message(nil), c.msgs...)
+}
+
+// Len returns the number of messages captured so far
+func (c *Capture) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.msgs)
+}
+
+// Reset forgets the messages captured so far
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = nil
+}
+
+// WaitUntil waits until cond holds for the messages captured, and returns
+// them. The test fails, mentioning what, if that takes longer than Timeout.
+func (c *Capture) WaitUntil(what string, cond func([]*message.Message) bool) []*message.Message {
+	c.t.Helper()
+
+	timeout := time.NewTimer(c.Timeout)
+	defer timeout.Stop()
+	for {
+		c.mu.Lock()
+		msgs := append([]*message.Message(nil), c.msgs...)
+		changed := c.changed
+		c.mu.Unlock()
+		if cond(msgs) {
+			return msgs
+		}
+
+		select {
+		case <-changed:
+		case <-timeout.C:
+			c.t.Fatalf("timed out after %s waiting for %s on %s; captured %d messages", c.Timeout, what, c.Topic, len(msgs))
+			return nil
+		}
+	}
+}
+
+// WaitForN waits until at least n messages have been captured, and returns
+// the first n
+func (c *Capture) WaitForN(n int) []*message.Message {
+	c.t.Helper()
+	msgs := c.WaitUntil(fmt.Sprintf("%d messages", n), func(msgs []*message.Message) bool {
+		return len(msgs) >= n
+	})
+	return msgs[:n]
+}
+
+// WaitFor waits for a message matching match, and returns the first one
+func (c *Capture) WaitFor(what string, match func(*message.Message) bool) *message.Message {
+	c.t.Helper()
+	var found *message.Message
+	c.WaitUntil(what, func(msgs []*message.Message) bool {
+		for _, msg := range msgs {
+			if match(msg) {
+				found = msg
+				return true
+			}
+		}
+		return false
+	})
+	return found
+}
@@ -0,0 +1,39 @@
+package testutil_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestCaptureTopic(t *testing.T) {
+	pubSub := gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{})
+	defer pubSub.Close()
+
+	routed := testutil.CaptureTopic(t, pubSub, "orders.routed")
+	go func() {
+		for _, id := range []string{"order-1", "order-2", "order-3"} {
+			time.Sleep(time.Millisecond)
+			_ = pubSub.Publish("orders.routed", message.NewMessage(watermill.NewUUID(), []byte(id)))
+		}
+	}()
+
+	msgs := routed.WaitForN(2)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "order-1", string(msgs[0].Payload))
+
+	last := routed.WaitFor("order-3", func(msg *message.Message) bool {
+		return string(msg.Payload) == "order-3"
+	})
+	assert.Equal(t, "order-3", string(last.Payload))
+	assert.Equal(t, 3, routed.Len())
+
+	routed.Reset()
+	assert.Empty(t, routed.Messages())
+}
@@ -0,0 +1,208 @@
+package testutil
+
+import (
+	"sync"
+	"time"
+
+	"github.com/synapse/synapse/internal/clock"
+)
+
+// FakeClock is a clock.Clock whose time only moves when the test advances
+// it. Waits and tickers fire, in time order, as their times are passed, so
+// backoff schedules, TTLs and periodic jobs run without sleeping. Pass it to
+// pipeline.WithClock, NewMemRedis, infra.WithClock or infra.Infra's Clock.
+type FakeClock struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	waits   []*fakeWait
+	tickers []*fakeTicker
+}
+
+var _ clock.Clock = (*FakeClock)(nil)
+
+// fakeWait is a pending After
+type fakeWait struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewFakeClock returns a clock stopped at now, or at FixtureTime if now is
+// zero
+func NewFakeClock(now time.Time) *FakeClock {
+	if now.IsZero() {
+		now = FixtureTime
+	}
+	c := &FakeClock{now: now}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the clock's time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed on the clock since t
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After returns a channel receiving the clock's time once it has advanced
+// by d
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waits = append(c.waits, &fakeWait{at: c.now.Add(d), c: ch})
+	c.changed.Broadcast()
+	return ch
+}
+
+// NewTicker returns a ticker ticking every d of the clock's time
+func (c *FakeClock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("testutil: non-positive interval for FakeClock.NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTicker{clock: c, period: d, next: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.tickers = append(c.tickers, t)
+	c.changed.Broadcast()
+	return t
+}
+
+// Advance moves the clock forward by d, firing the waits and ticks due on
+// the way at their own times. Like a time.Ticker's, ticks the ticker's
+// receiver hasn't kept up with are dropped.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advanceTo(c.now.Add(d))
+}
+
+// AdvanceToNext moves the clock to the next wait or tick, firing it, and
+// returns how far it moved. It returns false if nothing is waiting.
+func (c *FakeClock) AdvanceToNext() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	next, ok := c.next()
+	if !ok {
+		return 0, false
+	}
+	d := next.Sub(c.now)
+	c.advanceTo(next)
+	return d, true
+}
+
+// Tick fires every ticker at the current time, without advancing the clock
+func (c *FakeClock) Tick() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range c.tickers {
+		t.send(c.now)
+	}
+}
+
+// Waiters returns how many Afters and tickers are pending
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waits) + len(c.tickers)
+}
+
+// BlockUntil waits until n Afters and tickers are pending, so a test can
+// advance the clock once the code under test is waiting on it
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waits)+len(c.tickers) < n {
+		c.changed.Wait()
+	}
+}
+
+// next returns the time of the earliest wait or tick
+func (c *FakeClock) next() (time.Time, bool) {
+	var next time.Time
+	for _, w := range c.waits {
+		if next.IsZero() || w.at.Before(next) {
+			next = w.at
+		}
+	}
+	for _, t := range c.tickers {
+		if next.IsZero() || t.next.Before(next) {
+			next = t.next
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// advanceTo moves the clock to target, firing what is due in time order
+func (c *FakeClock) advanceTo(target time.Time) {
+	for {
+		next, ok := c.next()
+		if !ok || next.After(target) {
+			break
+		}
+		c.now = next
+
+		waits := c.waits[:0]
+		for _, w := range c.waits {
+			if w.at.After(next) {
+				waits = append(waits, w)
+				continue
+			}
+			w.c <- next
+		}
+		c.waits = waits
+		for _, t := range c.tickers {
+			if !t.next.After(next) {
+				t.send(next)
+				t.next = t.next.Add(t.period)
+			}
+		}
+	}
+	c.now = target
+	c.changed.Broadcast()
+}
+
+// fakeTicker ticks on its FakeClock's time
+type fakeTicker struct {
+	clock  *FakeClock
+	period time.Duration
+	next   time.Time
+	c      chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+// Stop stops the ticker
+func (t *fakeTicker) Stop() {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.tickers {
+		if other == t {
+			c.tickers = append(c.tickers[:i], c.tickers[i+1:]...)
+			break
+		}
+	}
+	c.changed.Broadcast()
+}
+
+// send delivers a tick unless the last one is still unreceived
+func (t *fakeTicker) send(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
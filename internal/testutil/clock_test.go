@@ -0,0 +1,84 @@
+package testutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestFakeClock(t *testing.T) {
+	clk := testutil.NewFakeClock(time.Time{})
+	start := clk.Now()
+	assert.Equal(t, testutil.FixtureTime, start)
+
+	after := clk.After(time.Minute)
+	ticker := clk.NewTicker(25 * time.Second)
+	defer ticker.Stop()
+	assert.Equal(t, 2, clk.Waiters())
+
+	clk.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(25*time.Second), <-ticker.C(), "ticks at its own time")
+	select {
+	case <-after:
+		t.Fatal("fired early")
+	default:
+	}
+
+	// The ticks at 50s and 75s come due, but the second is dropped as the
+	// first wasn't received
+	clk.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Minute), <-after)
+	assert.Equal(t, start.Add(50*time.Second), <-ticker.C())
+	assert.Equal(t, 90*time.Second, clk.Since(start))
+
+	d, ok := clk.AdvanceToNext()
+	require.True(t, ok)
+	assert.Equal(t, 10*time.Second, d)
+	assert.Equal(t, start.Add(100*time.Second), <-ticker.C())
+
+	clk.Tick()
+	assert.Equal(t, start.Add(100*time.Second), <-ticker.C())
+
+	ticker.Stop()
+	_, ok = clk.AdvanceToNext()
+	assert.False(t, ok, "nothing is waiting")
+}
+
+func TestFakeClock_BlockUntil(t *testing.T) {
+	clk := testutil.NewFakeClock(time.Time{})
+	done := make(chan time.Duration)
+	go func() {
+		// A backoff doubling from a second
+		var waited time.Duration
+		for backoff := time.Second; backoff <= 4*time.Second; backoff *= 2 {
+			<-clk.After(backoff)
+			waited += backoff
+		}
+		done <- waited
+	}()
+
+	for range 3 {
+		clk.BlockUntil(1)
+		_, ok := clk.AdvanceToNext()
+		require.True(t, ok)
+	}
+	assert.Equal(t, 7*time.Second, <-done)
+	assert.Equal(t, testutil.FixtureTime.Add(7*time.Second), clk.Now())
+}
+
+func TestFakeClock_MemRedisTTLs(t *testing.T) {
+	clk := testutil.NewFakeClock(time.Time{})
+	rdb := testutil.NewMemRedis(clk)
+	defer rdb.Close()
+	ctx := context.Background()
+
+	require.NoError(t, rdb.Set(ctx, "idempotency:key-1", "order-1", time.Hour).Err())
+	clk.Advance(59 * time.Minute)
+	assert.Equal(t, "order-1", rdb.Get(ctx, "idempotency:key-1").Val())
+	clk.Advance(time.Minute)
+	assert.Zero(t, rdb.Exists(ctx, "idempotency:key-1").Val(), "expired")
+}
@@ -0,0 +1,39 @@
+package testutil
+
+import (
+	"os"
+	"testing"
+
+	"github.com/synapse/synapse/internal/conformance"
+)
+
+// ConformanceReportDirEnv names the directory the conformance report of a
+// test run is written to, e.g. for CI to publish
+const ConformanceReportDirEnv = "CONFORMANCE_REPORT_DIR"
+
+// conformanceReport collects the contract test suites of the test run
+var conformanceReport = conformance.NewReport()
+
+// ReportOpenAPI adds the results of suite, testing the OpenAPI spec at
+// specPath, to the run's conformance report
+func ReportOpenAPI(t testing.TB, specPath string, suite *conformance.ContractTestSuite) {
+	conformanceReport.AddOpenAPI(t.Name(), specPath, suite)
+}
+
+// ReportAsyncAPI adds the results of suite, testing the AsyncAPI spec at
+// specPath, to the run's conformance report
+func ReportAsyncAPI(t testing.TB, specPath string, suite *conformance.EventContractTestSuite) {
+	conformanceReport.AddAsyncAPI(t.Name(), specPath, suite)
+}
+
+// WriteConformanceReport writes the run's conformance report, as JUnit XML,
+// JSON and HTML with the specs' coverage, to CONFORMANCE_REPORT_DIR if it is
+// set and suites were reported. RunWithContainers calls it once the tests
+// have run.
+func WriteConformanceReport() error {
+	dir := os.Getenv(ConformanceReportDirEnv)
+	if dir == "" || conformanceReport.Empty() {
+		return nil
+	}
+	return conformanceReport.WriteDir(dir)
+}
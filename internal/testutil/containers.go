@@ -1,110 +1,410 @@
 package testutil
 
 import (
+	"cmp"
 	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
 	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/nats"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/modules/redis"
+	"github.com/testcontainers/testcontainers-go/network"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
-// TestContainers holds references to all test containers
+// TestContainers holds references to all test containers; those not
+// started are nil
 type TestContainers struct {
 	NATS     *nats.NATSContainer
 	Postgres *postgres.PostgresContainer
 	Redis    *redis.RedisContainer
+
+	// Network is the containers' own network, on which they are reachable
+	// by their Service name
+	Network *testcontainers.DockerNetwork
+
+	// shared is set on SharedContainers, whose tests each get a database
+	shared bool
+	// proxies, when set, are connected to instead of the containers
+	proxies *Toxiproxy
 }
 
-// ContainerConfig holds configuration for test containers
+// Default images of the test containers
+const (
+	DefaultNATSImage      = "nats:2.10-alpine"
+	DefaultPostgresImage  = "postgres:16-alpine"
+	DefaultRedisImage     = "redis:7-alpine"
+	DefaultToxiproxyImage = "ghcr.io/shopify/toxiproxy:2.9.0"
+	DefaultS3Image        = "minio/minio:RELEASE.2024-01-16T16-07-38Z"
+)
+
+// Service is one of the test containers
+type Service string
+
+// Services StartContainers can start
+const (
+	ServiceNATS     Service = "nats"
+	ServicePostgres Service = "postgres"
+	ServiceRedis    Service = "redis"
+)
+
+// ContainerConfig holds configuration for test containers. Fields left
+// empty take DefaultConfig's values.
 type ContainerConfig struct {
+	// Services are the containers to start, e.g. only Postgres for store
+	// tests; all when empty
+	Services []Service
+
 	PostgresUser     string
 	PostgresPassword string
 	PostgresDB       string
+
+	// Images of the containers
+	NATSImage     string
+	PostgresImage string
+	RedisImage    string
+
+	// JetStream storage of the NATS server: where streams are stored, and
+	// how many bytes memory and file streams may take in all
+	JetStreamStoreDir   string
+	JetStreamMaxMemory  int64
+	JetStreamMaxStorage int64
 }
 
-// DefaultConfig returns sensible defaults for testing
+// DefaultConfig returns sensible defaults for testing. The images can be
+// overridden with TEST_NATS_IMAGE, TEST_POSTGRES_IMAGE and TEST_REDIS_IMAGE,
+// e.g. to test another Postgres version, and Docker Hub images are pulled
+// through the registry TEST_REGISTRY_MIRROR names, if set.
 func DefaultConfig() *ContainerConfig {
 	return &ContainerConfig{
 		PostgresUser:     "synapse",
 		PostgresPassword: "synapse",
 		PostgresDB:       "synapse_test",
+		NATSImage:        image("TEST_NATS_IMAGE", DefaultNATSImage),
+		PostgresImage:    image("TEST_POSTGRES_IMAGE", DefaultPostgresImage),
+		RedisImage:       image("TEST_REDIS_IMAGE", DefaultRedisImage),
+
+		JetStreamStoreDir:   "/data/jetstream",
+		JetStreamMaxMemory:  256 << 20,
+		JetStreamMaxStorage: 1 << 30,
 	}
 }
 
-// StartContainers starts all required test containers
-func StartContainers(ctx context.Context, t *testing.T, cfg *ContainerConfig) (*TestContainers, error) {
+// image returns the image the environment variable env names, or fallback,
+// pulled through TEST_REGISTRY_MIRROR if it is a Docker Hub image
+func image(env, fallback string) string {
+	img := os.Getenv(env)
+	if img == "" {
+		img = fallback
+	}
+	mirror := strings.TrimSuffix(os.Getenv("TEST_REGISTRY_MIRROR"), "/")
+	if mirror == "" {
+		return img
+	}
+	first, _, ok := strings.Cut(img, "/")
+	switch {
+	case !ok:
+		// Official images are under library/
+		return mirror + "/library/" + img
+	case strings.ContainsAny(first, ".:") || first == "localhost":
+		// Not on Docker Hub
+		return img
+	default:
+		return mirror + "/" + img
+	}
+}
+
+// withDefaults returns a copy of cfg with DefaultConfig's values in its
+// empty fields
+func (cfg ContainerConfig) withDefaults() *ContainerConfig {
+	defaults := DefaultConfig()
+	cfg.PostgresUser = cmp.Or(cfg.PostgresUser, defaults.PostgresUser)
+	cfg.PostgresPassword = cmp.Or(cfg.PostgresPassword, defaults.PostgresPassword)
+	cfg.PostgresDB = cmp.Or(cfg.PostgresDB, defaults.PostgresDB)
+	cfg.NATSImage = cmp.Or(cfg.NATSImage, defaults.NATSImage)
+	cfg.PostgresImage = cmp.Or(cfg.PostgresImage, defaults.PostgresImage)
+	cfg.RedisImage = cmp.Or(cfg.RedisImage, defaults.RedisImage)
+	cfg.JetStreamStoreDir = cmp.Or(cfg.JetStreamStoreDir, defaults.JetStreamStoreDir)
+	cfg.JetStreamMaxMemory = cmp.Or(cfg.JetStreamMaxMemory, defaults.JetStreamMaxMemory)
+	cfg.JetStreamMaxStorage = cmp.Or(cfg.JetStreamMaxStorage, defaults.JetStreamMaxStorage)
+	return &cfg
+}
+
+// natsConfig is the NATS server's configuration file, enabling JetStream
+// with cfg's storage
+func (cfg *ContainerConfig) natsConfig() string {
+	return fmt.Sprintf("jetstream {\n  store_dir: %q\n  max_memory_store: %d\n  max_file_store: %d\n}\n",
+		cfg.JetStreamStoreDir, cfg.JetStreamMaxMemory, cfg.JetStreamMaxStorage)
+}
+
+// starts reports whether cfg starts the container of s
+func (cfg *ContainerConfig) starts(s Service) bool {
+	return len(cfg.Services) == 0 || slices.Contains(cfg.Services, s)
+}
+
+// StartContainers starts the test containers for t alone, on a network of
+// their own and with unique names, and terminates them when it finishes,
+// reporting their logs first if t failed. Tests doing so can run in
+// parallel. Tests that don't need containers of their own should use
+// SharedContainers.
+func StartContainers(ctx context.Context, t testing.TB, cfg *ContainerConfig) (*TestContainers, error) {
 	t.Helper()
 
 	if cfg == nil {
 		cfg = DefaultConfig()
 	}
 
-	tc := &TestContainers{}
-
-	// Start NATS
-	natsContainer, err := nats.Run(ctx, "nats:2.10-alpine")
+	tc, err := startContainers(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("starting NATS container: %w", err)
+		return nil, err
 	}
-	tc.NATS = natsContainer
 	t.Cleanup(func() {
-		if err := natsContainer.Terminate(ctx); err != nil {
-			t.Logf("failed to terminate NATS container: %v", err)
+		if err := tc.Terminate(context.WithoutCancel(ctx)); err != nil {
+			t.Logf("failed to terminate containers: %v", err)
 		}
 	})
+	reportContainerLogs(t, tc.containers())
+	return tc, nil
+}
 
-	// Start PostgreSQL
-	postgresContainer, err := postgres.Run(ctx,
-		"postgres:16-alpine",
-		postgres.WithDatabase(cfg.PostgresDB),
-		postgres.WithUsername(cfg.PostgresUser),
-		postgres.WithPassword(cfg.PostgresPassword),
-		testcontainers.WithWaitStrategy(
-			wait.ForLog("database system is ready to accept connections").
-				WithOccurrence(2),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("starting Postgres container: %w", err)
+var shared struct {
+	once sync.Once
+	tc   *TestContainers
+	err  error
+}
+
+// SharedContainers returns containers started by the first test to ask for
+// them and shared by every test in the package, which saves starting them
+// per test. TestInfra gives each test a Postgres database and a Redis
+// database of its own on them, so tests sharing them can run in parallel;
+// NATS is shared, so they should publish to subjects of their own, e.g.
+// named after the orders they create. They are removed by
+// the testcontainers reaper when the test binary exits, or once the tests
+// finish in packages using RunWithContainers, which starts them instead.
+// Tests failing report the containers' logs.
+func SharedContainers(ctx context.Context, t testing.TB) (*TestContainers, error) {
+	t.Helper()
+
+	startShared(ctx, DefaultConfig())
+	if shared.err == nil {
+		reportContainerLogs(t, shared.tc.containers())
 	}
-	tc.Postgres = postgresContainer
-	t.Cleanup(func() {
-		if err := postgresContainer.Terminate(ctx); err != nil {
-			t.Logf("failed to terminate Postgres container: %v", err)
+	return shared.tc, shared.err
+}
+
+// startShared starts the shared containers unless already started
+func startShared(ctx context.Context, cfg *ContainerConfig) {
+	shared.once.Do(func() {
+		// testcontainers panics when it finds no Docker
+		defer func() {
+			if r := recover(); r != nil {
+				shared.err = fmt.Errorf("%v", r)
+			}
+		}()
+		shared.tc, shared.err = startContainers(ctx, cfg)
+		if shared.err == nil {
+			shared.tc.shared = true
 		}
 	})
+}
 
-	// Start Redis
-	redisContainer, err := redis.Run(ctx, "redis:7-alpine")
+// RunWithContainers runs a package's tests with the containers cfg asks
+// for, or all of them if nil, started once before the tests and terminated
+// after them, then exits with the tests' status. Packages opt in from
+// TestMain:
+//
+//	func TestMain(m *testing.M) { testutil.RunWithContainers(m, nil) }
+//
+// The containers aren't started under -short. Tests get them from
+// Containers or SharedContainers; if they failed to start, those tests fail
+// and the others still run. The conformance report is written after the
+// tests, see WriteConformanceReport.
+func RunWithContainers(m *testing.M, cfg *ContainerConfig) {
+	os.Exit(runWithContainers(m, cfg))
+}
+
+func runWithContainers(m *testing.M, cfg *ContainerConfig) int {
+	flag.Parse()
+	if !testing.Short() {
+		if cfg == nil {
+			cfg = DefaultConfig()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		startShared(ctx, cfg)
+		cancel()
+		if shared.err != nil {
+			fmt.Fprintf(os.Stderr, "starting containers: %v\n", shared.err)
+		} else {
+			defer func() {
+				if err := shared.tc.Terminate(context.Background()); err != nil {
+					fmt.Fprintf(os.Stderr, "terminating containers: %v\n", err)
+				}
+			}()
+		}
+	}
+	code := m.Run()
+	if err := WriteConformanceReport(); err != nil {
+		fmt.Fprintf(os.Stderr, "writing conformance report: %v\n", err)
+		code = cmp.Or(code, 1)
+	}
+	return code
+}
+
+// Containers returns the package's shared containers, failing t if they
+// couldn't be started
+func Containers(t testing.TB) *TestContainers {
+	t.Helper()
+
+	tc, err := SharedContainers(context.Background(), t)
 	if err != nil {
-		return nil, fmt.Errorf("starting Redis container: %w", err)
+		t.Fatalf("starting containers: %v", err)
 	}
-	tc.Redis = redisContainer
-	t.Cleanup(func() {
-		if err := redisContainer.Terminate(ctx); err != nil {
-			t.Logf("failed to terminate Redis container: %v", err)
+	return tc
+}
+
+// startContainers starts the test containers cfg asks for, terminating
+// those already started if one fails to start
+func startContainers(ctx context.Context, cfg *ContainerConfig) (*TestContainers, error) {
+	cfg = cfg.withDefaults()
+
+	nw, err := network.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating network: %w", err)
+	}
+	tc := &TestContainers{Network: nw}
+	fail := func(err error) (*TestContainers, error) {
+		return nil, errors.Join(err, tc.Terminate(context.WithoutCancel(ctx)))
+	}
+
+	// Names are unique to these containers, which join the network under
+	// their service's name
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return fail(err)
+	}
+	join := func(s Service) testcontainers.CustomizeRequestOption {
+		return func(req *testcontainers.GenericContainerRequest) error {
+			if err := testcontainers.WithName(fmt.Sprintf("synapse-test-%x-%s", id, s))(req); err != nil {
+				return err
+			}
+			return network.WithNetwork([]string{string(s)}, nw)(req)
 		}
-	})
+	}
+
+	// Start NATS
+	if cfg.starts(ServiceNATS) {
+		natsContainer, err := nats.Run(ctx, cfg.NATSImage, join(ServiceNATS),
+			nats.WithConfigFile(strings.NewReader(cfg.natsConfig())))
+		if err != nil {
+			return fail(fmt.Errorf("starting NATS container: %w", err))
+		}
+		tc.NATS = natsContainer
+	}
+
+	// Start PostgreSQL
+	if cfg.starts(ServicePostgres) {
+		postgresContainer, err := postgres.Run(ctx,
+			cfg.PostgresImage,
+			join(ServicePostgres),
+			postgres.WithDatabase(cfg.PostgresDB),
+			postgres.WithUsername(cfg.PostgresUser),
+			postgres.WithPassword(cfg.PostgresPassword),
+			testcontainers.WithWaitStrategy(
+				wait.ForLog("database system is ready to accept connections").
+					WithOccurrence(2),
+			),
+		)
+		if err != nil {
+			return fail(fmt.Errorf("starting Postgres container: %w", err))
+		}
+		tc.Postgres = postgresContainer
+	}
+
+	// Start Redis
+	if cfg.starts(ServiceRedis) {
+		redisContainer, err := redis.Run(ctx, cfg.RedisImage, join(ServiceRedis))
+		if err != nil {
+			return fail(fmt.Errorf("starting Redis container: %w", err))
+		}
+		tc.Redis = redisContainer
+	}
 
 	return tc, nil
 }
 
+// Terminate terminates the started containers, and removes their network
+func (tc *TestContainers) Terminate(ctx context.Context) error {
+	var errs []error
+	if tc.Redis != nil {
+		if err := tc.Redis.Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("terminating Redis container: %w", err))
+		}
+	}
+	if tc.Postgres != nil {
+		if err := tc.Postgres.Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("terminating Postgres container: %w", err))
+		}
+	}
+	if tc.NATS != nil {
+		if err := tc.NATS.Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("terminating NATS container: %w", err))
+		}
+	}
+	if tc.Network != nil {
+		if err := tc.Network.Remove(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("removing network: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // NATSConnectionString returns the NATS connection string
 func (tc *TestContainers) NATSConnectionString(ctx context.Context) (string, error) {
+	if tc.NATS == nil {
+		return "", notStarted(ServiceNATS)
+	}
+	if tc.proxies != nil {
+		return "nats://" + tc.proxies.NATS.Addr, nil
+	}
 	return tc.NATS.ConnectionString(ctx)
 }
 
 // PostgresConnectionString returns the PostgreSQL connection string
 func (tc *TestContainers) PostgresConnectionString(ctx context.Context) (string, error) {
-	return tc.Postgres.ConnectionString(ctx, "sslmode=disable")
+	if tc.Postgres == nil {
+		return "", notStarted(ServicePostgres)
+	}
+	dsn, err := tc.Postgres.ConnectionString(ctx, "sslmode=disable")
+	if err != nil || tc.proxies == nil {
+		return dsn, err
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	u.Host = tc.proxies.Postgres.Addr
+	return u.String(), nil
 }
 
 // RedisConnectionString returns the Redis connection string
 func (tc *TestContainers) RedisConnectionString(ctx context.Context) (string, error) {
+	if tc.Redis == nil {
+		return "", notStarted(ServiceRedis)
+	}
+	if tc.proxies != nil {
+		return tc.proxies.Redis.Addr, nil
+	}
 	host, err := tc.Redis.Host(ctx)
 	if err != nil {
 		return "", err
@@ -115,3 +415,7 @@ func (tc *TestContainers) RedisConnectionString(ctx context.Context) (string, er
 	}
 	return fmt.Sprintf("%s:%s", host, port.Port()), nil
 }
+
+func notStarted(s Service) error {
+	return fmt.Errorf("%s container not started", s)
+}
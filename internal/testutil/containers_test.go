@@ -0,0 +1,52 @@
+package testutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestDefaultConfig_Images(t *testing.T) {
+	cfg := testutil.DefaultConfig()
+	assert.Equal(t, testutil.DefaultPostgresImage, cfg.PostgresImage)
+
+	t.Setenv("TEST_POSTGRES_IMAGE", "postgres:17-alpine")
+	t.Setenv("TEST_NATS_IMAGE", "registry.internal:5000/nats:2.10")
+	t.Setenv("TEST_REDIS_IMAGE", "bitnami/redis:7.2")
+	t.Setenv("TEST_REGISTRY_MIRROR", "mirror.example.com/hub/")
+
+	cfg = testutil.DefaultConfig()
+	assert.Equal(t, "mirror.example.com/hub/library/postgres:17-alpine", cfg.PostgresImage)
+	assert.Equal(t, "registry.internal:5000/nats:2.10", cfg.NATSImage, "only Docker Hub images are mirrored")
+	assert.Equal(t, "mirror.example.com/hub/bitnami/redis:7.2", cfg.RedisImage)
+}
+
+func TestStartContainers_Subset(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.StartContainers(ctx, t, &testutil.ContainerConfig{
+		Services: []testutil.Service{testutil.ServicePostgres},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, tc.NATS)
+	assert.Nil(t, tc.Redis)
+	require.NotNil(t, tc.Network)
+	_, err = tc.RedisConnectionString(ctx)
+	assert.Error(t, err)
+
+	infra, cfg := testutil.TestInfra(ctx, t, tc)
+	require.NoError(t, infra.DB.PingContext(ctx))
+	assert.Nil(t, infra.NATS)
+	assert.Nil(t, infra.Redis)
+	assert.Empty(t, cfg.RedisAddr)
+}
@@ -0,0 +1,52 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ResetDB empties every table of db's current schema but the migrations
+// bookkeeping, and restarts their sequences, so tests reusing a database,
+// e.g. one TestInfra set up in TestMain, each start from the migrated schema
+// alone. Tables are truncated in one statement, which foreign keys between
+// them don't stop. The store writes through *sql.DB rather than a
+// transaction a test could roll back, hence truncating. Call it before each
+// test using the database; tests doing so can't run in parallel.
+func ResetDB(t testing.TB, db *sql.DB) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT tablename FROM pg_tables
+		WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'
+		ORDER BY tablename`)
+	if err != nil {
+		t.Fatalf("listing tables: %v", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			t.Fatalf("listing tables: %v", err)
+		}
+		tables = append(tables, pgx.Identifier{table}.Sanitize())
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("listing tables: %v", err)
+	}
+	if len(tables) == 0 {
+		return
+	}
+
+	if _, err := db.ExecContext(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatalf("truncating tables: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package testutil_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/store"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestResetDB(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+	s := store.New(infra.DB)
+
+	createOrder := func() {
+		require.NoError(t, s.CreateOrder(ctx, &store.Order{
+			ID: "order-1", CustomerID: "cust-1", Status: "accepted", Currency: "USD",
+			Items: json.RawMessage(`[]`), CreatedAt: time.Now().UTC(),
+		}))
+		require.NoError(t, s.AppendEvent(ctx, &store.Event{
+			ID: "event-1", OrderID: "order-1", Type: "order.received", Stage: "ingest", Status: "completed",
+			OccurredAt: time.Now().UTC(),
+		}))
+	}
+	createOrder()
+
+	// Orders are truncated despite the events referencing them
+	testutil.ResetDB(t, infra.DB)
+	count, err := s.CountOrders(ctx, store.OrderFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	count, err = s.CountEvents(ctx, "order-1")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// The migrations stay applied, and the same rows can be created again
+	require.NoError(t, store.Migrate(ctx, infra.DB))
+	createOrder()
+	events, err := s.ListEvents(ctx, "order-1", store.ListEventsParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.EqualValues(t, 1, events[0].Seq, "sequences restart")
+}
@@ -0,0 +1,332 @@
+// Package factory builds test data of the API's and pipeline's types. Each
+// builder starts from randomized values that are valid against the OpenAPI
+// and AsyncAPI specs, so tests set only the fields they care about:
+//
+//	order := factory.Order().Customer("cust-1").Build()
+//	item := factory.DLQItem().Stage("enrich").Build()
+package factory
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/store"
+)
+
+var (
+	products = []string{"Widget", "Gadget", "Keyboard", "Monitor", "Cable", "Headphones", "Webcam", "Dock"}
+	cities   = []struct{ city, country string }{
+		{"Berlin", "DE"}, {"Lyon", "FR"}, {"Austin", "US"}, {"Leeds", "GB"}, {"Osaka", "JP"},
+	}
+)
+
+// cents rounds an amount to the cent
+func cents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// ItemBuilder builds an order item
+type ItemBuilder struct {
+	item generated.OrderItem
+}
+
+// Item returns a builder of an item of 1 to 5 units of a random product,
+// priced 1.00 to 100.00
+func Item() *ItemBuilder {
+	product := products[rand.IntN(len(products))]
+	return &ItemBuilder{item: generated.OrderItem{
+		Sku:         fmt.Sprintf("%s-%04d", product[:3], rand.IntN(10000)),
+		ProductName: product,
+		Quantity:    1 + rand.IntN(5),
+		UnitPrice:   cents(1 + rand.Float64()*99),
+	}}
+}
+
+// SKU sets the item's SKU
+func (b *ItemBuilder) SKU(sku string) *ItemBuilder {
+	b.item.Sku = sku
+	return b
+}
+
+// Name sets the item's product name
+func (b *ItemBuilder) Name(name string) *ItemBuilder {
+	b.item.ProductName = name
+	return b
+}
+
+// Quantity sets the number of units
+func (b *ItemBuilder) Quantity(quantity int) *ItemBuilder {
+	b.item.Quantity = quantity
+	return b
+}
+
+// UnitPrice sets the price of a unit
+func (b *ItemBuilder) UnitPrice(price float64) *ItemBuilder {
+	b.item.UnitPrice = price
+	return b
+}
+
+// Build returns the item
+func (b *ItemBuilder) Build() generated.OrderItem {
+	return b.item
+}
+
+// Address returns a random address
+func Address() *generated.Address {
+	c := cities[rand.IntN(len(cities))]
+	return &generated.Address{
+		Street:     fmt.Sprintf("%d Main Street", 1+rand.IntN(999)),
+		City:       c.city,
+		PostalCode: fmt.Sprintf("%05d", rand.IntN(100000)),
+		Country:    c.country,
+	}
+}
+
+// OrderBuilder builds an order
+type OrderBuilder struct {
+	req       generated.OrderCreateRequest
+	createdAt time.Time
+}
+
+// Order returns a builder of an order in USD of one to three random items
+// by a random customer, with a random ID and shipping address. Its total is
+// the sum of its items'.
+func Order() *OrderBuilder {
+	items := make([]generated.OrderItem, 1+rand.IntN(3))
+	for i := range items {
+		items[i] = Item().Build()
+	}
+	b := &OrderBuilder{
+		req: generated.OrderCreateRequest{
+			OrderId:         uuid.NewString(),
+			CustomerId:      uuid.NewString(),
+			Currency:        "USD",
+			ShippingAddress: Address(),
+		},
+		createdAt: time.Now().UTC(),
+	}
+	return b.Items(items...)
+}
+
+// ID sets the order's ID
+func (b *OrderBuilder) ID(id string) *OrderBuilder {
+	b.req.OrderId = id
+	return b
+}
+
+// Customer sets the ID of the customer placing the order
+func (b *OrderBuilder) Customer(id string) *OrderBuilder {
+	b.req.CustomerId = id
+	return b
+}
+
+// Currency sets the order's ISO 4217 currency code
+func (b *OrderBuilder) Currency(currency string) *OrderBuilder {
+	b.req.Currency = currency
+	return b
+}
+
+// Items replaces the order's items, and sets its total to their sum
+func (b *OrderBuilder) Items(items ...generated.OrderItem) *OrderBuilder {
+	b.req.Items = items
+	total := 0.0
+	for _, item := range items {
+		total += float64(item.Quantity) * item.UnitPrice
+	}
+	b.req.TotalAmount = cents(total)
+	return b
+}
+
+// Total sets the order's total, whatever its items' sum
+func (b *OrderBuilder) Total(amount float64) *OrderBuilder {
+	b.req.TotalAmount = amount
+	return b
+}
+
+// ShipTo sets the order's shipping address; nil removes it
+func (b *OrderBuilder) ShipTo(addr *generated.Address) *OrderBuilder {
+	b.req.ShippingAddress = addr
+	return b
+}
+
+// BillTo sets the order's billing address; nil removes it
+func (b *OrderBuilder) BillTo(addr *generated.Address) *OrderBuilder {
+	b.req.BillingAddress = addr
+	return b
+}
+
+// Metadata sets a metadata key of the order
+func (b *OrderBuilder) Metadata(key, value string) *OrderBuilder {
+	if b.req.Metadata == nil {
+		b.req.Metadata = make(map[string]any)
+	}
+	b.req.Metadata[key] = value
+	return b
+}
+
+// CreatedAt sets when the order was received, for its event payloads
+func (b *OrderBuilder) CreatedAt(t time.Time) *OrderBuilder {
+	b.createdAt = t
+	return b
+}
+
+// Build returns the order as a create request
+func (b *OrderBuilder) Build() *generated.OrderCreateRequest {
+	req := b.req
+	return &req
+}
+
+// JSON returns the create request's body
+func (b *OrderBuilder) JSON() []byte {
+	return mustMarshal(b.req)
+}
+
+// Received returns the OrderReceived event of the order
+func (b *OrderBuilder) Received() *generated.OrderReceivedPayload {
+	return &generated.OrderReceivedPayload{
+		OrderId:         b.req.OrderId,
+		CustomerId:      b.req.CustomerId,
+		Items:           b.req.Items,
+		TotalAmount:     b.req.TotalAmount,
+		Currency:        b.req.Currency,
+		ShippingAddress: b.req.ShippingAddress,
+		BillingAddress:  b.req.BillingAddress,
+		Metadata:        b.req.Metadata,
+		CreatedAt:       b.createdAt,
+	}
+}
+
+// Payload returns the OrderReceived event's JSON, as the pipeline's first
+// stage receives it
+func (b *OrderBuilder) Payload() []byte {
+	return mustMarshal(b.Received())
+}
+
+// Failed returns the OrderFailed event of the order failing at stage
+func (b *OrderBuilder) Failed(stage, errorType, message string) *generated.OrderFailedPayload {
+	var original map[string]any
+	_ = json.Unmarshal(b.Payload(), &original)
+	return &generated.OrderFailedPayload{
+		OrderId:         b.req.OrderId,
+		OriginalPayload: original,
+		FailedAt:        time.Now().UTC(),
+		FailureStage:    stage,
+		Error:           map[string]any{"code": errorType, "message": message},
+		RetryCount:      3,
+	}
+}
+
+// Cancelled returns the OrderCancelled event of the order cancelled in
+// previousStatus
+func (b *OrderBuilder) Cancelled(previousStatus string) *generated.OrderCancelledPayload {
+	return &generated.OrderCancelledPayload{
+		OrderId:        b.req.OrderId,
+		PreviousStatus: previousStatus,
+		CancelledAt:    time.Now().UTC(),
+	}
+}
+
+// PipelineError returns the PipelineError event of a random message failing
+// at stage
+func PipelineError(stage, errorType, message string) *generated.PipelineErrorPayload {
+	return &generated.PipelineErrorPayload{
+		ErrorId:   uuid.NewString(),
+		EventId:   uuid.NewString(),
+		StageId:   stage,
+		ErrorType: errorType,
+		Message:   message,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// stageTopics are the topics the stages receive their messages on
+var stageTopics = map[string]string{
+	"validate": pipeline.TopicOrdersIngest,
+	"enrich":   pipeline.TopicOrdersValidated,
+	"route":    pipeline.TopicOrdersEnriched,
+	"emit":     pipeline.TopicOrdersRouted,
+}
+
+// DLQItemBuilder builds a dead-lettered message
+type DLQItemBuilder struct {
+	item store.DLQItem
+}
+
+// DLQItem returns a builder of a random order's message dead-lettered by
+// the validate stage after three attempts
+func DLQItem() *DLQItemBuilder {
+	b := &DLQItemBuilder{item: store.DLQItem{
+		EventID:      uuid.NewString(),
+		ErrorType:    pipeline.ErrorTypeValidation,
+		ErrorMessage: "validation failed",
+		RetryCount:   3,
+		FailedAt:     time.Now().UTC(),
+	}}
+	return b.Stage("validate").Order(Order())
+}
+
+// EventID sets the dead-lettered message's ID
+func (b *DLQItemBuilder) EventID(id string) *DLQItemBuilder {
+	b.item.EventID = id
+	return b
+}
+
+// Order sets the order the message was about, and its payload
+func (b *DLQItemBuilder) Order(order *OrderBuilder) *DLQItemBuilder {
+	b.item.OrderID = order.req.OrderId
+	b.item.Payload = order.Payload()
+	b.item.Metadata = map[string]string{"correlationId": order.req.OrderId}
+	// Like the pipeline's, the preview is the payload's first 256 bytes
+	b.item.Preview = string(b.item.Payload)
+	if len(b.item.Preview) > 256 {
+		b.item.Preview = b.item.Preview[:256] + "…"
+	}
+	return b
+}
+
+// Stage sets the stage that gave up on the message, and the topic it was
+// received on
+func (b *DLQItemBuilder) Stage(stage string) *DLQItemBuilder {
+	b.item.Stage = stage
+	b.item.Topic = stageTopics[stage]
+	return b
+}
+
+// Error sets why the message failed
+func (b *DLQItemBuilder) Error(errorType, message string) *DLQItemBuilder {
+	b.item.ErrorType = errorType
+	b.item.ErrorMessage = message
+	return b
+}
+
+// RetryCount sets the number of attempts made
+func (b *DLQItemBuilder) RetryCount(n int) *DLQItemBuilder {
+	b.item.RetryCount = n
+	return b
+}
+
+// FailedAt sets when the message was dead-lettered
+func (b *DLQItemBuilder) FailedAt(t time.Time) *DLQItemBuilder {
+	b.item.FailedAt = t
+	return b
+}
+
+// Build returns the dead-lettered message
+func (b *DLQItemBuilder) Build() *store.DLQItem {
+	item := b.item
+	return &item
+}
+
+func mustMarshal(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("factory: marshaling %T: %v", v, err))
+	}
+	return data
+}
@@ -0,0 +1,66 @@
+package factory_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/conformance"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/testutil/factory"
+)
+
+func TestOrder_ConformsToSpecs(t *testing.T) {
+	openAPI, err := conformance.NewOpenAPIValidator("../../../openapi/openapi.yaml")
+	require.NoError(t, err)
+	asyncAPI, err := conformance.NewAsyncAPIValidator("../../../asyncapi/asyncapi.yaml")
+	require.NoError(t, err)
+
+	for range 50 {
+		order := factory.Order()
+		require.NoError(t, openAPI.ValidateResponse("OrderCreateRequest", order.JSON()))
+		require.NoError(t, asyncAPI.ValidateMessage("OrderReceivedPayload", order.Payload()))
+
+		failed, err := json.Marshal(order.Failed("validate", pipeline.ErrorTypeValidation, "invalid"))
+		require.NoError(t, err)
+		require.NoError(t, asyncAPI.ValidateMessage("OrderFailedPayload", failed))
+		cancelled, err := json.Marshal(order.Cancelled("accepted"))
+		require.NoError(t, err)
+		require.NoError(t, asyncAPI.ValidateMessage("OrderCancelledPayload", cancelled))
+	}
+
+	pipelineErr, err := json.Marshal(factory.PipelineError("enrich", pipeline.ErrorTypeTimeout, "timed out"))
+	require.NoError(t, err)
+	require.NoError(t, asyncAPI.ValidateMessage("PipelineErrorPayload", pipelineErr))
+}
+
+func TestOrder_Builder(t *testing.T) {
+	order := factory.Order().
+		ID("order-1").
+		Customer("cust-1").
+		Items(
+			factory.Item().SKU("SKU-1").Quantity(2).UnitPrice(29.99).Build(),
+			factory.Item().SKU("SKU-2").Quantity(1).UnitPrice(0.03).Build(),
+		).
+		Metadata("channel", "web").
+		Build()
+
+	assert.Equal(t, "order-1", order.OrderId)
+	assert.Equal(t, "cust-1", order.CustomerId)
+	assert.Len(t, order.Items, 2)
+	assert.Equal(t, 60.01, order.TotalAmount, "the items' sum, to the cent")
+	assert.Equal(t, map[string]any{"channel": "web"}, order.Metadata)
+}
+
+func TestDLQItem(t *testing.T) {
+	order := factory.Order().ID("order-1")
+	item := factory.DLQItem().Order(order).Stage("enrich").Error(pipeline.ErrorTypeTimeout, "timed out").Build()
+
+	assert.NotEmpty(t, item.EventID)
+	assert.Equal(t, "order-1", item.OrderID)
+	assert.Equal(t, pipeline.TopicOrdersValidated, item.Topic)
+	assert.Equal(t, "order-1", item.Metadata["correlationId"])
+	assert.JSONEq(t, string(order.Payload()), string(item.Payload))
+	assert.LessOrEqual(t, len(item.Preview), 256+len("…"))
+}
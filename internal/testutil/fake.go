@@ -0,0 +1,59 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/synapse/synapse/internal/config"
+	"github.com/synapse/synapse/internal/infra"
+)
+
+// Fake is infrastructure held in memory, for tests that can't run containers
+type Fake struct {
+	Infra  *infra.Infra
+	Config *config.Config
+	// Store keeps what would be stored in the database. Infra has none, so
+	// pass it to pipeline.New with pipeline.WithOrderStore and to handler.New
+	// with handler.WithStore.
+	Store *MemStore
+	// NATS is the server of Infra.NATS; connect to it for more connections
+	NATS *MemNATS
+}
+
+// FakeInfra creates infrastructure without Docker: Infra's NATS connection
+// and Redis client are served in memory, and what the store keeps is kept in
+// a MemStore. Features needing JetStream or Redis scripts aren't available.
+func FakeInfra(t testing.TB) *Fake {
+	t.Helper()
+
+	cfg := &config.Config{
+		HTTPPort:            8080,
+		NATSURL:             "memory",
+		RedisAddr:           "memory",
+		PipelineConcurrency: 10,
+		RetryMaxAttempts:    3,
+		RetryBackoffMs:      100,
+	}
+
+	server := NewMemNATS()
+	nc, err := server.Connect()
+	if err != nil {
+		t.Fatalf("connecting to NATS: %v", err)
+	}
+	t.Cleanup(func() { nc.Close() })
+
+	rdb := NewMemRedis(nil)
+	t.Cleanup(func() { rdb.Close() })
+
+	return &Fake{
+		Infra: &infra.Infra{
+			NATS:      nc,
+			Redis:     rdb,
+			Config:    cfg,
+			StartedAt: time.Now().UTC(),
+		},
+		Config: cfg,
+		Store:  NewMemStore(),
+		NATS:   server,
+	}
+}
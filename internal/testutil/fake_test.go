@@ -0,0 +1,216 @@
+package testutil_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/auth"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/handler"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/testutil"
+	"github.com/synapse/synapse/internal/testutil/factory"
+)
+
+var (
+	_ pipeline.OrderStore = (*testutil.MemStore)(nil)
+	_ handler.Store       = (*testutil.MemStore)(nil)
+)
+
+func TestFakeInfra_RunsThePipeline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fake := testutil.FakeInfra(t)
+	runner, err := pipeline.New(ctx, fake.Config, fake.Infra, pipeline.WithOrderStore(fake.Store))
+	require.NoError(t, err)
+	go runner.Run(ctx)
+	defer runner.Close()
+	<-runner.Running()
+
+	// Status updates reach other connections to the server
+	watcher, err := fake.NATS.Connect()
+	require.NoError(t, err)
+	defer watcher.Close()
+	updates := make(chan *nats.Msg, 16)
+	sub, err := watcher.ChanSubscribe(pipeline.OrderStatusSubject("order-1"), updates)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+	require.NoError(t, watcher.Flush())
+
+	require.NoError(t, runner.IngestOrder(ctx, "order-1", &generated.OrderCreateRequest{
+		CustomerId:  "cust-1",
+		TotalAmount: 99.99,
+		Currency:    "USD",
+		Items:       []generated.OrderItem{{Sku: "SKU-1", Quantity: 2, UnitPrice: 49.99}},
+	}))
+
+	assert.Eventually(t, func() bool {
+		o, err := fake.Store.GetOrder(ctx, "order-1")
+		return err == nil && o.Status == "routed"
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.NotEmpty(t, fake.Store.Events("order-1"))
+
+	select {
+	case <-updates:
+	case <-ctx.Done():
+		t.Fatal("no status update received")
+	}
+}
+
+func TestFakeInfra_ServesTheAPI(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fake := testutil.FakeInfra(t)
+	runner, err := pipeline.New(ctx, fake.Config, fake.Infra, pipeline.WithOrderStore(fake.Store))
+	require.NoError(t, err)
+	go runner.Run(ctx)
+	defer runner.Close()
+	<-runner.Running()
+
+	r := chi.NewRouter()
+	handler.New(fake.Infra, runner, handler.WithStore(fake.Store)).RegisterRoutes(r)
+	api := testutil.NewAPIClient(t, r)
+
+	accepted := testutil.Decode[generated.OrderAcceptedResponse](api.Post("/api/v1/orders", factory.Order().JSON()), http.StatusAccepted)
+	require.Eventually(t, func() bool {
+		o, err := fake.Store.GetOrder(ctx, accepted.OrderId)
+		return err == nil && o.Status == "routed"
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// Reads are served from the MemStore
+	order := testutil.Decode[generated.OrderResponse](api.Get("/api/v1/orders/"+accepted.OrderId), http.StatusOK)
+	assert.EqualValues(t, "routed", order.Status)
+	list := testutil.Decode[generated.OrderListResponse](api.Get("/api/v1/orders?status=routed"), http.StatusOK)
+	require.Len(t, list.Orders, 1)
+	assert.Equal(t, accepted.OrderId, list.Orders[0].OrderId)
+	events := testutil.Decode[generated.OrderEventsResponse](api.Get("/api/v1/orders/"+accepted.OrderId+"/events"), http.StatusOK)
+	assert.NotEmpty(t, events.Events)
+	assert.Equal(t, http.StatusNotFound, api.Get("/api/v1/orders/missing").StatusCode)
+}
+
+func TestFakeInfra_Clock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fake := testutil.FakeInfra(t)
+	clk := testutil.NewFakeClock(time.Time{})
+	fake.Infra.Clock, fake.Infra.StartedAt = clk, clk.Now()
+	runner, err := pipeline.New(ctx, fake.Config, fake.Infra, pipeline.WithOrderStore(fake.Store))
+	require.NoError(t, err)
+	go runner.Run(ctx)
+	defer runner.Close()
+	<-runner.Running()
+
+	// Auth is off, so requests are made as an admin
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			admin := &auth.Principal{ID: "admin", Scopes: []string{auth.ScopeAdmin}}
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), admin)))
+		})
+	})
+	handler.New(fake.Infra, runner, handler.WithStore(fake.Store)).RegisterRoutes(r)
+	api := testutil.NewAPIClient(t, r)
+
+	// Uptime and the times the API records come from the Infra's clock
+	clk.Advance(90 * time.Second)
+	// FakeInfra has no database, so the service reports itself unhealthy
+	var health generated.HealthResponse
+	require.NoError(t, json.Unmarshal(api.Get("/health").Body, &health))
+	assert.Equal(t, 90, health.Uptime)
+	created := testutil.Decode[generated.WebhookSubscriptionCreatedResponse](api.Post("/api/v1/webhooks", generated.WebhookSubscriptionCreateRequest{
+		Url:        "https://hooks.example.com/orders",
+		EventTypes: []generated.WebhookEventType{generated.WebhookEventTypeOrderRouted},
+	}), http.StatusCreated)
+	assert.True(t, created.CreatedAt.Equal(clk.Now()))
+}
+
+func TestMemNATS(t *testing.T) {
+	server := testutil.NewMemNATS()
+	a, err := server.Connect()
+	require.NoError(t, err)
+	defer a.Close()
+	b, err := server.Connect()
+	require.NoError(t, err)
+	defer b.Close()
+
+	// Wildcards and headers
+	sub, err := b.SubscribeSync("orders.*.status")
+	require.NoError(t, err)
+	require.NoError(t, b.Flush())
+	msg := nats.NewMsg("orders.o1.status")
+	msg.Header.Set("Nats-Msg-Id", "m1")
+	msg.Data = []byte("routed")
+	require.NoError(t, a.PublishMsg(msg))
+	got, err := sub.NextMsg(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "routed", string(got.Data))
+	assert.Equal(t, "m1", got.Header.Get("Nats-Msg-Id"))
+
+	// Request-reply, and requests nobody answers
+	_, err = b.Subscribe("echo", func(m *nats.Msg) { m.Respond(m.Data) })
+	require.NoError(t, err)
+	require.NoError(t, b.Flush())
+	reply, err := a.Request("echo", []byte("hi"), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "hi", string(reply.Data))
+	_, err = a.Request("nobody", nil, time.Second)
+	assert.ErrorIs(t, err, nats.ErrNoResponders)
+
+	// Each queue group gets a message once
+	received := make(chan string, 4)
+	for range 2 {
+		_, err := b.QueueSubscribe("work", "workers", func(m *nats.Msg) { received <- string(m.Data) })
+		require.NoError(t, err)
+	}
+	require.NoError(t, b.Flush())
+	require.NoError(t, a.Publish("work", []byte("job")))
+	require.NoError(t, a.Flush())
+	assert.Equal(t, "job", <-received)
+	select {
+	case <-received:
+		t.Fatal("queue group received the message twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMemRedis(t *testing.T) {
+	ctx := context.Background()
+	rdb := testutil.NewMemRedis(nil)
+	defer rdb.Close()
+
+	require.NoError(t, rdb.Ping(ctx).Err())
+	assert.ErrorIs(t, rdb.Get(ctx, "k").Err(), redis.Nil)
+	require.NoError(t, rdb.Set(ctx, "k", "v", 0).Err())
+	assert.Equal(t, "v", rdb.Get(ctx, "k").Val())
+
+	ok, err := rdb.SetNX(ctx, "k", "other", time.Minute).Result()
+	require.NoError(t, err)
+	assert.False(t, ok)
+	rdb.Incr(ctx, "n")
+	assert.Equal(t, int64(2), rdb.Incr(ctx, "n").Val())
+
+	require.NoError(t, rdb.Set(ctx, "short", "v", time.Millisecond).Err())
+	time.Sleep(5 * time.Millisecond)
+	assert.ErrorIs(t, rdb.Get(ctx, "short").Err(), redis.Nil, "keys expire")
+
+	_, err = rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, "k")
+		p.Set(ctx, "p", "1", 0)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), rdb.Exists(ctx, "k", "p").Val())
+
+	assert.Error(t, rdb.Eval(ctx, "return 1", nil).Err(), "scripts aren't supported")
+}
@@ -0,0 +1,405 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/synapse/synapse/internal/generated"
+	"github.com/synapse/synapse/internal/pipeline"
+	"github.com/synapse/synapse/internal/store"
+	"gopkg.in/yaml.v3"
+)
+
+// FixtureTime is when the first seeded order is created, unless its fixture
+// sets a time; each following one is created a minute later
+var FixtureTime = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+// Fixtures are records for Seed to insert, written in Go or loaded from YAML
+// with LoadFixtures. Fields left empty get realistic values, valid against
+// the API schemas, so a fixture only sets what its test is about.
+type Fixtures struct {
+	Orders   []OrderFixture   `yaml:"orders"`
+	Events   []EventFixture   `yaml:"events"`
+	DLQItems []DLQItemFixture `yaml:"dlqItems"`
+}
+
+// OrderFixture is an order, seeded through the pipeline's stages up to its
+// status along with the events they record
+type OrderFixture struct {
+	OrderID    string `yaml:"orderId"`
+	CustomerID string `yaml:"customerId"`
+	// Status is accepted, validated, enriched, routed or cancelled; accepted
+	// when empty. Cancelled orders are cancelled once accepted.
+	Status   string        `yaml:"status"`
+	Items    []ItemFixture `yaml:"items"`
+	Currency string        `yaml:"currency"`
+	// TotalAmount is the items' total when zero
+	TotalAmount     float64           `yaml:"totalAmount"`
+	ShippingAddress map[string]string `yaml:"shippingAddress"`
+	CreatedAt       time.Time         `yaml:"createdAt"`
+	// CustomerTier and FraudScore are the enrichment of enriched and routed orders
+	CustomerTier string  `yaml:"customerTier"`
+	FraudScore   float64 `yaml:"fraudScore"`
+	// Destination and RoutingReason are the routing of routed orders
+	Destination   string `yaml:"destination"`
+	RoutingReason string `yaml:"routingReason"`
+}
+
+// ItemFixture is an order item
+type ItemFixture struct {
+	SKU         string  `yaml:"sku"`
+	ProductName string  `yaml:"productName"`
+	Quantity    int     `yaml:"quantity"`
+	UnitPrice   float64 `yaml:"unitPrice"`
+}
+
+// EventFixture is an event in an order's history, in addition to those
+// recorded for its stages
+type EventFixture struct {
+	EventID    string         `yaml:"eventId"`
+	OrderID    string         `yaml:"orderId"`
+	Type       string         `yaml:"type"`
+	Stage      string         `yaml:"stage"`
+	Status     string         `yaml:"status"`
+	OccurredAt time.Time      `yaml:"occurredAt"`
+	DurationMs int            `yaml:"durationMs"`
+	Metadata   map[string]any `yaml:"metadata"`
+	Error      map[string]any `yaml:"error"`
+}
+
+// DLQItemFixture is a message a stage dead-lettered. Its payload is the
+// order's as the stage received it.
+type DLQItemFixture struct {
+	EventID      string    `yaml:"eventId"`
+	OrderID      string    `yaml:"orderId"`
+	Stage        string    `yaml:"stage"`
+	ErrorType    string    `yaml:"errorType"`
+	ErrorMessage string    `yaml:"errorMessage"`
+	RetryCount   int       `yaml:"retryCount"`
+	FailedAt     time.Time `yaml:"failedAt"`
+}
+
+// stageTopics are the topics each stage consumes
+var stageTopics = map[string]string{
+	"validate": pipeline.TopicOrdersIngest,
+	"enrich":   pipeline.TopicOrdersValidated,
+	"route":    pipeline.TopicOrdersEnriched,
+}
+
+// LoadFixtures reads fixtures from a YAML file
+func LoadFixtures(path string) (Fixtures, error) {
+	var f Fixtures
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return f, fmt.Errorf("reading fixtures: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("parsing fixtures %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Seed inserts fixtures into the migrated database db: orders first, then
+// events and DLQ items, which may refer to them
+func Seed(ctx context.Context, db *sql.DB, fixtures ...Fixtures) error {
+	s := store.New(db)
+	n := 0
+	for _, f := range fixtures {
+		for _, o := range f.Orders {
+			if err := seedOrder(ctx, s, o, FixtureTime.Add(time.Duration(n)*time.Minute)); err != nil {
+				return err
+			}
+			n++
+		}
+	}
+	for _, f := range fixtures {
+		for _, e := range f.Events {
+			if err := seedEvent(ctx, s, e); err != nil {
+				return err
+			}
+		}
+		for _, item := range f.DLQItems {
+			if err := seedDLQItem(ctx, s, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// seedableStatuses are the statuses an OrderFixture may have
+var seedableStatuses = map[string]bool{
+	"accepted":  true,
+	"validated": true,
+	"enriched":  true,
+	"routed":    true,
+	"cancelled": true,
+}
+
+// orderStages are the stages orders go through, with the status each leaves
+// them in
+var orderStages = []struct{ stage, status, event string }{
+	{"ingest", "accepted", pipeline.EventOrderReceived},
+	{"validate", "validated", pipeline.EventOrderValidated},
+	{"enrich", "enriched", pipeline.EventOrderEnriched},
+	{"route", "routed", pipeline.EventOrderRouted},
+}
+
+func seedOrder(ctx context.Context, s *store.Store, f OrderFixture, createdAt time.Time) error {
+	f = f.withDefaults(createdAt)
+	if !seedableStatuses[f.Status] {
+		return fmt.Errorf("seeding order %s: can't seed status %q", f.OrderID, f.Status)
+	}
+	items := orderItems(f.Items)
+	itemsJSON, _ := json.Marshal(items)
+	addressJSON, _ := json.Marshal(f.ShippingAddress)
+	if err := s.CreateOrder(ctx, &store.Order{
+		ID:              f.OrderID,
+		CustomerID:      f.CustomerID,
+		Status:          string(generated.OrderStatusAccepted),
+		TotalAmount:     f.TotalAmount,
+		Currency:        f.Currency,
+		ItemCount:       len(items),
+		Items:           itemsJSON,
+		ShippingAddress: addressJSON,
+		CreatedAt:       f.CreatedAt,
+	}); err != nil {
+		return fmt.Errorf("seeding order %s: %w", f.OrderID, err)
+	}
+
+	target := f.Status
+	if target == "cancelled" {
+		target = "accepted"
+	}
+	at := f.CreatedAt
+	for _, st := range orderStages {
+		var err error
+		switch st.stage {
+		case "validate":
+			err = s.MarkValidated(ctx, f.OrderID, at)
+		case "enrich":
+			enrichment, _ := json.Marshal(generated.OrderEnrichment{
+				Customer: map[string]any{"tier": f.CustomerTier, "accountAgeDays": 365, "lifetimeValue": 1500.00},
+				Fraud:    map[string]any{"score": f.FraudScore, "riskLevel": riskLevel(f.FraudScore), "signals": []string{}},
+			})
+			err = s.MarkEnriched(ctx, f.OrderID, at, enrichment)
+		case "route":
+			err = s.MarkRouted(ctx, f.OrderID, at, f.Destination, f.RoutingReason)
+		}
+		if err == nil {
+			err = s.AppendEvent(ctx, &store.Event{
+				ID:         uuid.NewString(),
+				OrderID:    f.OrderID,
+				Type:       st.event,
+				Stage:      st.stage,
+				Status:     pipeline.EventStatusCompleted,
+				OccurredAt: at,
+				DurationMs: 12,
+				Metadata:   json.RawMessage(`{}`),
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("seeding order %s at %s: %w", f.OrderID, st.stage, err)
+		}
+		if st.status == target {
+			break
+		}
+		at = at.Add(time.Second)
+	}
+
+	if f.Status == "cancelled" {
+		at = at.Add(time.Second)
+		if _, _, err := s.CancelOrder(ctx, f.OrderID, at, nil); err != nil {
+			return fmt.Errorf("seeding cancelled order %s: %w", f.OrderID, err)
+		}
+		metadata, _ := json.Marshal(map[string]string{"previousStatus": "accepted"})
+		if err := s.AppendEvent(ctx, &store.Event{
+			ID:         uuid.NewString(),
+			OrderID:    f.OrderID,
+			Type:       pipeline.EventOrderCancelled,
+			Stage:      "cancel",
+			Status:     pipeline.EventStatusCompleted,
+			OccurredAt: at,
+			Metadata:   metadata,
+		}); err != nil {
+			return fmt.Errorf("seeding cancelled order %s: %w", f.OrderID, err)
+		}
+	}
+	return nil
+}
+
+// withDefaults fills in the fields the fixture leaves empty
+func (f OrderFixture) withDefaults(createdAt time.Time) OrderFixture {
+	if f.OrderID == "" {
+		f.OrderID = uuid.NewString()
+	}
+	if f.CustomerID == "" {
+		f.CustomerID = "cust-001"
+	}
+	if f.Status == "" {
+		f.Status = string(generated.OrderStatusAccepted)
+	}
+	if len(f.Items) == 0 {
+		f.Items = []ItemFixture{{SKU: "SKU-001", ProductName: "Widget", Quantity: 2, UnitPrice: 24.99}}
+	}
+	for i := range f.Items {
+		if f.Items[i].Quantity == 0 {
+			f.Items[i].Quantity = 1
+		}
+	}
+	if f.Currency == "" {
+		f.Currency = "USD"
+	}
+	if f.TotalAmount == 0 {
+		for _, item := range f.Items {
+			f.TotalAmount += float64(item.Quantity) * item.UnitPrice
+		}
+	}
+	if f.ShippingAddress == nil {
+		f.ShippingAddress = map[string]string{
+			"street":     "1 Market St",
+			"city":       "San Francisco",
+			"state":      "CA",
+			"postalCode": "94105",
+			"country":    "US",
+		}
+	}
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = createdAt
+	}
+	if f.CustomerTier == "" {
+		f.CustomerTier = "gold"
+	}
+	if f.Destination == "" {
+		f.Destination = pipeline.DestinationFulfillment
+	}
+	if f.RoutingReason == "" {
+		f.RoutingReason = "All checks passed"
+	}
+	return f
+}
+
+// orderItems converts item fixtures to the API's items
+func orderItems(fixtures []ItemFixture) []generated.OrderItem {
+	items := make([]generated.OrderItem, len(fixtures))
+	for i, item := range fixtures {
+		items[i] = generated.OrderItem{Sku: item.SKU, ProductName: item.ProductName, Quantity: item.Quantity, UnitPrice: item.UnitPrice}
+	}
+	return items
+}
+
+// riskLevel is the fraud risk level of a score
+func riskLevel(score float64) string {
+	switch {
+	case score > 80:
+		return "high"
+	case score > 50:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+func seedEvent(ctx context.Context, s *store.Store, f EventFixture) error {
+	if f.EventID == "" {
+		f.EventID = uuid.NewString()
+	}
+	if f.Type == "" {
+		f.Type = pipeline.EventOrderValidated
+	}
+	if f.Stage == "" {
+		f.Stage = "validate"
+	}
+	if f.Status == "" {
+		f.Status = pipeline.EventStatusCompleted
+		if f.Error != nil {
+			f.Status = pipeline.EventStatusFailed
+		}
+	}
+	if f.OccurredAt.IsZero() {
+		f.OccurredAt = FixtureTime
+	}
+	e := &store.Event{
+		ID:         f.EventID,
+		OrderID:    f.OrderID,
+		Type:       f.Type,
+		Stage:      f.Stage,
+		Status:     f.Status,
+		OccurredAt: f.OccurredAt,
+		DurationMs: f.DurationMs,
+	}
+	if f.Metadata != nil {
+		e.Metadata, _ = json.Marshal(f.Metadata)
+	}
+	if f.Error != nil {
+		e.Error, _ = json.Marshal(f.Error)
+	}
+	if err := s.AppendEvent(ctx, e); err != nil {
+		return fmt.Errorf("seeding event %s: %w", f.EventID, err)
+	}
+	return nil
+}
+
+func seedDLQItem(ctx context.Context, s *store.Store, f DLQItemFixture) error {
+	if f.EventID == "" {
+		f.EventID = uuid.NewString()
+	}
+	if f.OrderID == "" {
+		f.OrderID = uuid.NewString()
+	}
+	if f.Stage == "" {
+		f.Stage = "enrich"
+	}
+	if f.ErrorType == "" {
+		f.ErrorType = pipeline.ErrorTypeExternalService
+	}
+	if f.ErrorMessage == "" {
+		f.ErrorMessage = "customer service unavailable"
+	}
+	if f.RetryCount == 0 {
+		f.RetryCount = 3
+	}
+	if f.FailedAt.IsZero() {
+		f.FailedAt = FixtureTime
+	}
+
+	order := OrderFixture{OrderID: f.OrderID}.withDefaults(f.FailedAt)
+	payload, _ := json.Marshal(map[string]any{
+		"orderId":     order.OrderID,
+		"customerId":  order.CustomerID,
+		"items":       orderItems(order.Items),
+		"totalAmount": order.TotalAmount,
+		"currency":    order.Currency,
+		"createdAt":   order.CreatedAt,
+	})
+	preview := string(payload)
+	if len(preview) > 256 {
+		preview = preview[:256]
+	}
+	metadata := map[string]string{
+		"correlationId":              f.OrderID,
+		pipeline.MetadataFailedStage: f.Stage,
+		pipeline.MetadataErrorType:   f.ErrorType,
+	}
+	if err := s.SaveDLQItem(ctx, &store.DLQItem{
+		EventID:      f.EventID,
+		OrderID:      f.OrderID,
+		Stage:        f.Stage,
+		Topic:        stageTopics[f.Stage],
+		ErrorType:    f.ErrorType,
+		ErrorMessage: f.ErrorMessage,
+		Payload:      payload,
+		Metadata:     metadata,
+		Preview:      preview,
+		RetryCount:   f.RetryCount,
+		FailedAt:     f.FailedAt,
+	}); err != nil {
+		return fmt.Errorf("seeding DLQ item %s: %w", f.EventID, err)
+	}
+	return nil
+}
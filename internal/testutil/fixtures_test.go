@@ -0,0 +1,89 @@
+package testutil_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/store"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	f, err := testutil.LoadFixtures("testdata/fixtures.yaml")
+	require.NoError(t, err)
+
+	require.Len(t, f.Orders, 4)
+	assert.Equal(t, "routed", f.Orders[1].Status)
+	assert.Equal(t, []testutil.ItemFixture{{SKU: "SKU-100", ProductName: "Keyboard", Quantity: 1, UnitPrice: 89.5}}, f.Orders[1].Items)
+	assert.Equal(t, time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC), f.Orders[3].CreatedAt)
+	require.Len(t, f.Events, 1)
+	assert.Equal(t, "validation-failed", f.Events[0].Error["code"])
+	require.Len(t, f.DLQItems, 1)
+	assert.Equal(t, "timeout", f.DLQItems[0].ErrorType)
+}
+
+func TestSeed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, tc)
+
+	f, err := testutil.LoadFixtures("testdata/fixtures.yaml")
+	require.NoError(t, err)
+	require.NoError(t, testutil.Seed(ctx, infra.DB, f, testutil.Fixtures{
+		Orders: []testutil.OrderFixture{{OrderID: "order-go", Status: "enriched"}},
+	}))
+
+	s := store.New(infra.DB)
+	routed, err := s.GetOrder(ctx, "order-routed")
+	require.NoError(t, err)
+	assert.Equal(t, "routed", routed.Status)
+	assert.Equal(t, "cust-002", routed.CustomerID)
+	assert.InDelta(t, 89.5, routed.TotalAmount, 0.001)
+	assert.Equal(t, "fulfillment", routed.Destination)
+	assert.Equal(t, testutil.FixtureTime.Add(time.Minute), routed.CreatedAt.UTC())
+
+	review, err := s.GetOrder(ctx, "order-review")
+	require.NoError(t, err)
+	assert.Equal(t, "manual-review", review.Destination)
+	var enrichment map[string]map[string]any
+	require.NoError(t, json.Unmarshal(review.Enrichment, &enrichment))
+	assert.Equal(t, "medium", enrichment["fraud"]["riskLevel"])
+
+	cancelled, err := s.GetOrder(ctx, "order-cancelled")
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", cancelled.Status)
+	assert.Equal(t, "accepted", cancelled.PreviousStatus)
+
+	enriched, err := s.GetOrder(ctx, "order-go")
+	require.NoError(t, err)
+	assert.Equal(t, "enriched", enriched.Status)
+
+	events, err := s.ListEvents(ctx, "order-routed", store.ListEventsParams{Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, events, 4, "an event per stage")
+	events, err = s.ListEvents(ctx, "order-accepted", store.ListEventsParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "failed", events[1].Status)
+
+	item, err := s.GetDLQItem(ctx, "dlq-1")
+	require.NoError(t, err)
+	assert.Equal(t, "orders.validated", item.Topic)
+	assert.Equal(t, 3, item.RetryCount)
+	assert.Contains(t, string(item.Payload), `"orderId":"order-accepted"`)
+
+	assert.Error(t, testutil.Seed(ctx, infra.DB, testutil.Fixtures{
+		Orders: []testutil.OrderFixture{{Status: "shipped"}},
+	}))
+}
@@ -0,0 +1,104 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// updateGolden rewrites golden files with what the tests got, e.g.
+// go test ./internal/conformance -update
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+var (
+	goldenUUID      = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	goldenTimestamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+)
+
+// AssertGoldenJSON asserts that got, JSON or a value marshaled to JSON,
+// matches the golden file testdata/golden/<name>.json of the test's package.
+// Both are compared normalized: indented with sorted keys, with timestamps
+// replaced by <timestamp> and UUIDs by <uuid-N>, numbered in order of
+// appearance so that repeated IDs still match. Run the tests with -update to
+// write the golden files.
+func AssertGoldenJSON(t testing.TB, name string, got any) bool {
+	t.Helper()
+
+	normalized, err := normalizeGoldenJSON(got)
+	if err != nil {
+		t.Fatalf("normalizing %s: %v", name, err)
+	}
+
+	path := filepath.Join("testdata", "golden", filepath.FromSlash(name)+".json")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("creating golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, normalized, 0o644); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+		return true
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v (run with -update to create it)", err)
+	}
+	return assert.Equal(t, string(want), string(normalized), "%s differs from its golden file; run with -update if the change is intended", name)
+}
+
+// normalizeGoldenJSON renders v as indented JSON with its timestamps and
+// UUIDs replaced
+func normalizeGoldenJSON(v any) ([]byte, error) {
+	var data []byte
+	switch v := v.(type) {
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+
+	// Decoding and encoding again sorts the keys
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("parsing JSON: data after the document")
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	uuids := make(map[string]string)
+	out := goldenUUID.ReplaceAllFunc(buf.Bytes(), func(id []byte) []byte {
+		key := string(bytes.ToLower(id))
+		if _, ok := uuids[key]; !ok {
+			uuids[key] = fmt.Sprintf("<uuid-%d>", len(uuids)+1)
+		}
+		return []byte(uuids[key])
+	})
+	return goldenTimestamp.ReplaceAll(out, []byte("<timestamp>")), nil
+}
@@ -0,0 +1,28 @@
+package testutil_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestAssertGoldenJSON(t *testing.T) {
+	orderID := "550E8400-E29B-41D4-A716-446655440000"
+	got := map[string]any{
+		"orderId":   orderID,
+		"status":    "routed",
+		"createdAt": time.Now().UTC(),
+		"links":     map[string]any{"self": "/api/v1/orders/550e8400-e29b-41d4-a716-446655440000"},
+		"events": []map[string]any{
+			{"eventId": "a1b2c3d4-e5f6-7890-abcd-ef1234567890", "timestamp": "2024-01-15T10:30:00.123+01:00"},
+		},
+		"total": 59.98,
+	}
+	testutil.AssertGoldenJSON(t, "order", got)
+
+	// JSON is compared regardless of its formatting and key order
+	testutil.AssertGoldenJSON(t, "order", `{"total":59.98,"status":"routed","orderId":"`+orderID+`",
+		"links":{"self":"/api/v1/orders/`+orderID+`"},"events":[{"timestamp":"2025-06-01T00:00:00Z","eventId":"a1b2c3d4-e5f6-7890-abcd-ef1234567891"}],
+		"createdAt":"2025-06-01T00:00:00Z"}`)
+}
@@ -0,0 +1,93 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// JetStreamState inspects the streams and consumers of a NATS server, e.g.
+// for tests to check what was stored, acknowledged or dead-lettered
+type JetStreamState struct {
+	js jetstream.JetStream
+}
+
+// InspectJetStream returns the JetStream state of the server nc is
+// connected to
+func InspectJetStream(t testing.TB, nc *nats.Conn) *JetStreamState {
+	t.Helper()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("opening JetStream: %v", err)
+	}
+	return &JetStreamState{js: js}
+}
+
+// Stream returns the configuration and state of stream
+func (s *JetStreamState) Stream(ctx context.Context, stream string) (*jetstream.StreamInfo, error) {
+	str, err := s.js.Stream(ctx, stream)
+	if err != nil {
+		return nil, fmt.Errorf("reading stream %s: %w", stream, err)
+	}
+	return str.CachedInfo(), nil
+}
+
+// Consumer returns the configuration and state of the durable consumer of
+// stream, e.g. how many messages are pending or awaiting acknowledgement
+func (s *JetStreamState) Consumer(ctx context.Context, stream, consumer string) (*jetstream.ConsumerInfo, error) {
+	c, err := s.js.Consumer(ctx, stream, consumer)
+	if err != nil {
+		return nil, fmt.Errorf("reading consumer %s of stream %s: %w", consumer, stream, err)
+	}
+	return c.CachedInfo(), nil
+}
+
+// Messages returns the messages stored in stream on subject, which may have
+// wildcards, in order; on all its subjects when subject is empty
+func (s *JetStreamState) Messages(ctx context.Context, stream, subject string) ([]*jetstream.RawStreamMsg, error) {
+	str, err := s.js.Stream(ctx, stream)
+	if err != nil {
+		return nil, fmt.Errorf("reading stream %s: %w", stream, err)
+	}
+	if subject == "" {
+		subject = ">"
+	}
+
+	var msgs []*jetstream.RawStreamMsg
+	for seq := uint64(1); ; {
+		msg, err := str.GetMsg(ctx, seq, jetstream.WithGetMsgSubject(subject))
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			return msgs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading message %d of stream %s: %w", seq, stream, err)
+		}
+		msgs = append(msgs, msg)
+		seq = msg.Sequence + 1
+	}
+}
+
+// WaitForConsumer waits until cond holds for the state of the durable
+// consumer of stream, e.g. for it to have acknowledged every message, under
+// the same terms as WaitForCondition
+func (s *JetStreamState) WaitForConsumer(ctx context.Context, stream, consumer string, cond func(*jetstream.ConsumerInfo) bool) error {
+	err := poll(ctx, func(ctx context.Context) error {
+		info, err := s.Consumer(ctx, stream, consumer)
+		if err != nil {
+			return err
+		}
+		if !cond(info) {
+			return errNotYet
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for consumer %s of stream %s: %w", consumer, stream, err)
+	}
+	return nil
+}
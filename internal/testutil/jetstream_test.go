@@ -0,0 +1,96 @@
+package testutil_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestJetStreamState(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.StartContainers(ctx, t, &testutil.ContainerConfig{
+		Services:            []testutil.Service{testutil.ServiceNATS},
+		JetStreamMaxMemory:  64 << 20,
+		JetStreamMaxStorage: 128 << 20,
+	})
+	require.NoError(t, err)
+
+	// The server runs with the storage asked for
+	monitoring, err := tc.NATS.PortEndpoint(ctx, "8222/tcp", "http")
+	require.NoError(t, err)
+	resp, err := http.Get(monitoring + "/jsz")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var jsz struct {
+		Config struct {
+			MaxMemory  int64  `json:"max_memory"`
+			MaxStorage int64  `json:"max_storage"`
+			StoreDir   string `json:"store_dir"`
+		} `json:"config"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&jsz))
+	assert.EqualValues(t, 64<<20, jsz.Config.MaxMemory)
+	assert.EqualValues(t, 128<<20, jsz.Config.MaxStorage)
+	assert.Contains(t, jsz.Config.StoreDir, testutil.DefaultConfig().JetStreamStoreDir)
+
+	url, err := tc.NATSConnectionString(ctx)
+	require.NoError(t, err)
+	nc, err := nats.Connect(url)
+	require.NoError(t, err)
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}})
+	require.NoError(t, err)
+	for _, subject := range []string{"orders.ingest", "orders.dlq", "orders.ingest"} {
+		_, err := js.Publish(ctx, subject, []byte(subject))
+		require.NoError(t, err)
+	}
+	consumer, err := stream.CreateConsumer(ctx, jetstream.ConsumerConfig{Durable: "validateOrder", FilterSubject: "orders.ingest"})
+	require.NoError(t, err)
+
+	state := testutil.InspectJetStream(t, nc)
+	info, err := state.Stream(ctx, "ORDERS")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, info.State.Msgs)
+
+	dead, err := state.Messages(ctx, "ORDERS", "orders.dlq")
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.EqualValues(t, 2, dead[0].Sequence)
+	all, err := state.Messages(ctx, "ORDERS", "")
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	ci, err := state.Consumer(ctx, "ORDERS", "validateOrder")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, ci.NumPending)
+
+	batch, err := consumer.Fetch(2)
+	require.NoError(t, err)
+	for msg := range batch.Messages() {
+		require.NoError(t, msg.Ack())
+	}
+	require.NoError(t, state.WaitForConsumer(ctx, "ORDERS", "validateOrder", func(ci *jetstream.ConsumerInfo) bool {
+		return ci.AckFloor.Consumer == 2 && ci.NumAckPending == 0
+	}))
+
+	_, err = state.Consumer(ctx, "ORDERS", "missing")
+	assert.ErrorIs(t, err, jetstream.ErrConsumerNotFound)
+}
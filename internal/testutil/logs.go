@@ -0,0 +1,136 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// ArtifactsDirEnv names a directory the logs of failed tests are written to
+// in full, under a directory per test, instead of their last lines to the
+// test output, e.g. for CI to keep
+const ArtifactsDirEnv = "TEST_ARTIFACTS_DIR"
+
+// LogTailLines is how many of the last lines of each log a failed test shows
+const LogTailLines = 50
+
+// containers returns tc's started containers by service
+func (tc *TestContainers) containers() map[string]testcontainers.Container {
+	containers := make(map[string]testcontainers.Container)
+	if tc.NATS != nil {
+		containers[string(ServiceNATS)] = tc.NATS
+	}
+	if tc.Postgres != nil {
+		containers[string(ServicePostgres)] = tc.Postgres
+	}
+	if tc.Redis != nil {
+		containers[string(ServiceRedis)] = tc.Redis
+	}
+	return containers
+}
+
+// reportContainerLogs reports the logs of containers if t fails. Cleanups
+// run last first, so it's called after the containers' termination is
+// registered, for their logs to be read before.
+func reportContainerLogs(t testing.TB, containers map[string]testcontainers.Container) {
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for _, name := range slices.Sorted(maps.Keys(containers)) {
+			logs, err := containerLogs(ctx, containers[name])
+			if err != nil {
+				t.Logf("reading %s container logs: %v", name, err)
+				continue
+			}
+			reportLogs(t, name, logs)
+		}
+	})
+}
+
+func containerLogs(ctx context.Context, c testcontainers.Container) ([]byte, error) {
+	r, err := c.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// CaptureLogs sends what the service logs through slog's default logger to
+// a buffer while t runs, and reports it like container logs if t fails.
+// Module loggers follow the default logger, but loggers made from it before
+// the call keep logging where they did. As the default logger is global, tests capturing logs can't run in parallel.
+func CaptureLogs(t testing.TB) {
+	t.Helper()
+
+	buf := &logBuffer{}
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() {
+		slog.SetDefault(prev)
+		if t.Failed() {
+			reportLogs(t, "service", buf.Bytes())
+		}
+	})
+}
+
+// logBuffer is written to by the goroutines logging
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// reportLogs writes the logs named name to the artifacts directory, if
+// there is one, and shows their last lines otherwise
+func reportLogs(t testing.TB, name string, logs []byte) {
+	if dir := os.Getenv(ArtifactsDirEnv); dir != "" {
+		path := filepath.Join(dir, unsafePathChars.ReplaceAllString(t.Name(), "_"), name+".log")
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, logs, 0o644)
+		}
+		if err == nil {
+			t.Logf("%s logs written to %s", name, path)
+			return
+		}
+		t.Logf("writing %s logs: %v", name, err)
+	}
+
+	if len(logs) == 0 {
+		t.Logf("%s logs are empty", name)
+		return
+	}
+	lines := bytes.Split(bytes.TrimRight(logs, "\n"), []byte("\n"))
+	if len(lines) > LogTailLines {
+		lines = lines[len(lines)-LogTailLines:]
+	}
+	t.Logf("last %d lines of %s logs:\n%s", len(lines), name, bytes.Join(lines, []byte("\n")))
+}
@@ -0,0 +1,76 @@
+package testutil_test
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+// fakeTB is a test whose failure and cleanup the test controls
+type fakeTB struct {
+	testing.TB
+	failed   bool
+	logs     []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper()      {}
+func (f *fakeTB) Name() string { return "TestOrders/sub case" }
+func (f *fakeTB) Failed() bool { return f.failed }
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+func (f *fakeTB) Logf(format string, args ...any) {
+	f.logs = append(f.logs, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestCaptureLogs(t *testing.T) {
+	prev := slog.Default()
+
+	// Passing tests report nothing
+	passed := &fakeTB{TB: t}
+	testutil.CaptureLogs(passed)
+	slog.Info("validating order", "orderId", "order-1")
+	passed.finish()
+	assert.Empty(t, passed.logs)
+	assert.Same(t, prev, slog.Default(), "the default logger is restored")
+
+	// Failed ones show the last lines
+	failed := &fakeTB{TB: t}
+	testutil.CaptureLogs(failed)
+	for i := range testutil.LogTailLines + 10 {
+		slog.Debug("routing order", "n", i)
+	}
+	failed.failed = true
+	failed.finish()
+	require.Len(t, failed.logs, 1)
+	assert.True(t, strings.HasPrefix(failed.logs[0], fmt.Sprintf("last %d lines of service logs:", testutil.LogTailLines)))
+	assert.NotContains(t, failed.logs[0], "n=9\n")
+	assert.Contains(t, failed.logs[0], fmt.Sprintf("n=%d", testutil.LogTailLines+9))
+
+	// Or write them to the artifacts directory
+	dir := t.TempDir()
+	t.Setenv(testutil.ArtifactsDirEnv, dir)
+	failed = &fakeTB{TB: t, failed: true}
+	testutil.CaptureLogs(failed)
+	slog.Warn("order failed", "orderId", "order-2")
+	failed.finish()
+	path := filepath.Join(dir, "TestOrders_sub_case", "service.log")
+	assert.Equal(t, []string{"service logs written to " + path}, failed.logs)
+	logs, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(logs), "orderId=order-2")
+}
@@ -0,0 +1,285 @@
+package testutil
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// memNATSInfo is the INFO the in-memory server greets clients with
+const memNATSInfo = `INFO {"server_id":"memory","server_name":"memory","version":"2.10.0","proto":1,"headers":true,"max_payload":8388608}` + "\r\n"
+
+// noRespondersHeader is the status a request gets when nobody subscribes to
+// its subject
+const noRespondersHeader = "NATS/1.0 503\r\n\r\n"
+
+// MemNATS is an in-memory NATS server, which routes messages between the
+// connections made to it with Connect. It supports core NATS: subjects with
+// wildcards, queue groups, headers and request-reply, but not JetStream.
+type MemNATS struct {
+	mu    sync.Mutex
+	conns map[*memNATSConn]struct{}
+}
+
+// NewMemNATS creates an in-memory NATS server
+func NewMemNATS() *MemNATS {
+	return &MemNATS{conns: make(map[*memNATSConn]struct{})}
+}
+
+// Connect makes another connection to the server
+func (s *MemNATS) Connect(opts ...nats.Option) (*nats.Conn, error) {
+	opts = append([]nats.Option{nats.InProcessServer(s), nats.NoReconnect()}, opts...)
+	nc, err := nats.Connect("", opts...)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to in-memory NATS: %w", err)
+	}
+	return nc, nil
+}
+
+// InProcessConn implements nats.InProcessConnProvider
+func (s *MemNATS) InProcessConn() (net.Conn, error) {
+	client, server := net.Pipe()
+	c := &memNATSConn{server: s, conn: server, subs: make(map[string]*memNATSSub)}
+	s.mu.Lock()
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	go c.serve()
+	return client, nil
+}
+
+// memNATSConn is the server's end of a client connection
+type memNATSConn struct {
+	server *MemNATS
+	conn   net.Conn
+	// noResponders is set when the client wants to be told its request
+	// found no subscriber
+	noResponders bool
+
+	// subs are guarded by the server's mutex
+	subs map[string]*memNATSSub
+
+	writeMu sync.Mutex
+}
+
+type memNATSSub struct {
+	conn    *memNATSConn
+	sid     string
+	subject string
+	queue   string
+	// max is the number of messages after which it is unsubscribed, or 0
+	max       int
+	delivered int
+}
+
+// serve reads the client's protocol operations until it disconnects
+func (c *memNATSConn) serve() {
+	defer c.close()
+	if c.write([]byte(memNATSInfo)) != nil {
+		return
+	}
+	r := bufio.NewReader(c.conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		op, rest, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		args := strings.Fields(rest)
+
+		switch strings.ToUpper(op) {
+		case "CONNECT":
+			var opts struct {
+				Headers      bool `json:"headers"`
+				NoResponders bool `json:"no_responders"`
+			}
+			if json.Unmarshal([]byte(rest), &opts) == nil {
+				c.noResponders = opts.Headers && opts.NoResponders
+			}
+		case "PING":
+			err = c.write([]byte("PONG\r\n"))
+		case "PONG":
+		case "SUB":
+			err = c.subscribe(args)
+		case "UNSUB":
+			err = c.unsubscribe(args)
+		case "PUB", "HPUB":
+			err = c.publish(r, strings.ToUpper(op) == "HPUB", args)
+		default:
+			err = fmt.Errorf("unknown protocol operation %q", op)
+		}
+		if err != nil {
+			c.write([]byte("-ERR '" + err.Error() + "'\r\n"))
+			return
+		}
+	}
+}
+
+// subscribe handles SUB <subject> [queue group] <sid>
+func (c *memNATSConn) subscribe(args []string) error {
+	if len(args) != 2 && len(args) != 3 {
+		return errors.New("invalid SUB arguments")
+	}
+	sub := &memNATSSub{conn: c, subject: args[0], sid: args[len(args)-1]}
+	if len(args) == 3 {
+		sub.queue = args[1]
+	}
+	c.server.mu.Lock()
+	c.subs[sub.sid] = sub
+	c.server.mu.Unlock()
+	return nil
+}
+
+// unsubscribe handles UNSUB <sid> [max msgs]
+func (c *memNATSConn) unsubscribe(args []string) error {
+	if len(args) != 1 && len(args) != 2 {
+		return errors.New("invalid UNSUB arguments")
+	}
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	sub, ok := c.subs[args[0]]
+	if !ok {
+		return nil
+	}
+	if len(args) == 2 {
+		max, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid UNSUB max: %w", err)
+		}
+		if sub.delivered < max {
+			sub.max = max
+			return nil
+		}
+	}
+	delete(c.subs, sub.sid)
+	return nil
+}
+
+// publish handles PUB <subject> [reply-to] <size> and
+// HPUB <subject> [reply-to] <header size> <total size>, followed by the message
+func (c *memNATSConn) publish(r *bufio.Reader, headers bool, args []string) error {
+	sizes := 1
+	if headers {
+		sizes = 2
+	}
+	if len(args) != 1+sizes && len(args) != 2+sizes {
+		return errors.New("invalid PUB arguments")
+	}
+	subject, reply := args[0], ""
+	if len(args) == 2+sizes {
+		reply = args[1]
+	}
+	hdrLen := 0
+	size, err := strconv.Atoi(args[len(args)-1])
+	if err == nil && headers {
+		hdrLen, err = strconv.Atoi(args[len(args)-2])
+	}
+	if err != nil {
+		return fmt.Errorf("invalid PUB size: %w", err)
+	}
+
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if !c.server.route(subject, reply, hdrLen, data[:size]) && reply != "" && c.noResponders {
+		c.server.route(reply, "", len(noRespondersHeader), []byte(noRespondersHeader))
+	}
+	return nil
+}
+
+// route delivers a message to the subscriptions matching subject, one per
+// queue group, and reports whether any did
+func (s *MemNATS) route(subject, reply string, hdrLen int, data []byte) bool {
+	s.mu.Lock()
+	var targets []*memNATSSub
+	groups := make(map[string][]*memNATSSub)
+	for c := range s.conns {
+		for _, sub := range c.subs {
+			if !subjectMatches(sub.subject, subject) {
+				continue
+			}
+			if sub.queue == "" {
+				targets = append(targets, sub)
+			} else {
+				groups[sub.queue] = append(groups[sub.queue], sub)
+			}
+		}
+	}
+	for _, members := range groups {
+		targets = append(targets, members[rand.IntN(len(members))])
+	}
+	for _, sub := range targets {
+		sub.delivered++
+		if sub.max > 0 && sub.delivered >= sub.max {
+			delete(sub.conn.subs, sub.sid)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sub := range targets {
+		sub.conn.deliver(sub.sid, subject, reply, hdrLen, data)
+	}
+	return len(targets) > 0
+}
+
+// deliver writes a MSG, or HMSG when the message has headers
+func (c *memNATSConn) deliver(sid, subject, reply string, hdrLen int, data []byte) {
+	fields := []string{"MSG", subject, sid}
+	if hdrLen > 0 {
+		fields[0] = "HMSG"
+	}
+	if reply != "" {
+		fields = append(fields, reply)
+	}
+	if hdrLen > 0 {
+		fields = append(fields, strconv.Itoa(hdrLen))
+	}
+	fields = append(fields, strconv.Itoa(len(data)))
+
+	msg := make([]byte, 0, len(data)+64)
+	msg = append(msg, strings.Join(fields, " ")...)
+	msg = append(msg, "\r\n"...)
+	msg = append(msg, data...)
+	msg = append(msg, "\r\n"...)
+	c.write(msg)
+}
+
+func (c *memNATSConn) write(b []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.conn.Write(b)
+	return err
+}
+
+// close drops the connection and its subscriptions
+func (c *memNATSConn) close() {
+	c.server.mu.Lock()
+	delete(c.server.conns, c)
+	c.server.mu.Unlock()
+	c.conn.Close()
+}
+
+// subjectMatches reports whether subject matches pattern, which may use
+// NATS wildcards: * for one token and > for the remaining ones
+func subjectMatches(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
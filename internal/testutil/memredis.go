@@ -0,0 +1,263 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/synapse/synapse/internal/clock"
+)
+
+// NewMemRedis returns a Redis client whose commands run against an in-memory
+// keyspace instead of a server. It supports the string commands the service
+// uses (GET, SET with its options, SETNX, DEL, EXISTS, INCR, EXPIRE, TTL),
+// pipelines and transactions; other commands, including scripts, fail. Keys
+// expire on c, the system clock when nil.
+func NewMemRedis(c clock.Clock) *redis.Client {
+	rdb := redis.NewClient(&redis.Options{Addr: "memory", MaxRetries: -1})
+	rdb.AddHook(&memRedis{clock: clock.Or(c), keys: make(map[string]memRedisValue)})
+	return rdb
+}
+
+// memRedis answers the commands of the client it is hooked into, so they
+// never reach the network
+type memRedis struct {
+	clock clock.Clock
+
+	mu   sync.Mutex
+	keys map[string]memRedisValue
+}
+
+type memRedisValue struct {
+	value string
+	// expiresAt is zero for keys that don't expire
+	expiresAt time.Time
+}
+
+func (m *memRedis) DialHook(redis.DialHook) redis.DialHook {
+	return func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("in-memory Redis doesn't dial")
+	}
+}
+
+func (m *memRedis) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(_ context.Context, cmd redis.Cmder) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.process(cmd)
+		return cmd.Err()
+	}
+}
+
+func (m *memRedis) ProcessPipelineHook(redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(_ context.Context, cmds []redis.Cmder) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		var first error
+		for _, cmd := range cmds {
+			m.process(cmd)
+			if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) && first == nil {
+				first = err
+			}
+		}
+		return first
+	}
+}
+
+// get returns a key's value, dropping it if it has expired
+func (m *memRedis) get(key string) (memRedisValue, bool) {
+	v, ok := m.keys[key]
+	if ok && !v.expiresAt.IsZero() && !m.clock.Now().Before(v.expiresAt) {
+		delete(m.keys, key)
+		return memRedisValue{}, false
+	}
+	return v, ok
+}
+
+// process runs cmd, setting its result
+func (m *memRedis) process(cmd redis.Cmder) {
+	args := make([]string, len(cmd.Args()))
+	for i, arg := range cmd.Args() {
+		args[i] = memRedisArg(arg)
+	}
+	name := strings.ToLower(args[0])
+	args = args[1:]
+
+	switch name {
+	case "ping":
+		setResult(cmd, "PONG")
+	case "multi", "exec":
+		setResult(cmd, "OK")
+	case "flushdb", "flushall":
+		clear(m.keys)
+		setResult(cmd, "OK")
+	case "get":
+		if v, ok := m.get(args[0]); ok {
+			setResult(cmd, v.value)
+		} else {
+			cmd.SetErr(redis.Nil)
+		}
+	case "set":
+		m.set(cmd, args)
+	case "setnx":
+		if _, ok := m.get(args[0]); ok {
+			setResult(cmd, false)
+		} else {
+			m.keys[args[0]] = memRedisValue{value: args[1]}
+			setResult(cmd, true)
+		}
+	case "del", "unlink":
+		var n int64
+		for _, key := range args {
+			if _, ok := m.get(key); ok {
+				delete(m.keys, key)
+				n++
+			}
+		}
+		setResult(cmd, n)
+	case "exists":
+		var n int64
+		for _, key := range args {
+			if _, ok := m.get(key); ok {
+				n++
+			}
+		}
+		setResult(cmd, n)
+	case "incr", "incrby":
+		by := int64(1)
+		if name == "incrby" {
+			by, _ = strconv.ParseInt(args[1], 10, 64)
+		}
+		v, _ := m.get(args[0])
+		n := int64(0)
+		if v.value != "" {
+			var err error
+			if n, err = strconv.ParseInt(v.value, 10, 64); err != nil {
+				cmd.SetErr(errors.New("ERR value is not an integer or out of range"))
+				return
+			}
+		}
+		n += by
+		v.value = strconv.FormatInt(n, 10)
+		m.keys[args[0]] = v
+		setResult(cmd, n)
+	case "expire", "pexpire":
+		v, ok := m.get(args[0])
+		if !ok {
+			setResult(cmd, false)
+			return
+		}
+		n, _ := strconv.ParseInt(args[1], 10, 64)
+		unit := time.Second
+		if name == "pexpire" {
+			unit = time.Millisecond
+		}
+		v.expiresAt = m.clock.Now().Add(time.Duration(n) * unit)
+		m.keys[args[0]] = v
+		setResult(cmd, true)
+	case "ttl", "pttl":
+		v, ok := m.get(args[0])
+		switch {
+		case !ok:
+			setResult(cmd, time.Duration(-2))
+		case v.expiresAt.IsZero():
+			setResult(cmd, time.Duration(-1))
+		default:
+			setResult(cmd, v.expiresAt.Sub(m.clock.Now()))
+		}
+	default:
+		cmd.SetErr(fmt.Errorf("in-memory Redis doesn't support %s", strings.ToUpper(name)))
+	}
+}
+
+// set runs SET key value [NX|XX] [EX s|PX ms|KEEPTTL]
+func (m *memRedis) set(cmd redis.Cmder, args []string) {
+	key := args[0]
+	v := memRedisValue{value: args[1]}
+	var nx, xx, keepTTL bool
+	for i := 2; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "keepttl":
+			keepTTL = true
+		case "ex", "px":
+			unit := time.Second
+			if strings.EqualFold(args[i], "px") {
+				unit = time.Millisecond
+			}
+			i++
+			n, _ := strconv.ParseInt(args[i], 10, 64)
+			v.expiresAt = m.clock.Now().Add(time.Duration(n) * unit)
+		}
+	}
+
+	old, exists := m.get(key)
+	if (nx && exists) || (xx && !exists) {
+		// SetNX reports false where SET ... NX replies nil
+		if _, ok := cmd.(*redis.BoolCmd); ok {
+			setResult(cmd, false)
+		} else {
+			cmd.SetErr(redis.Nil)
+		}
+		return
+	}
+	if keepTTL {
+		v.expiresAt = old.expiresAt
+	}
+	m.keys[key] = v
+	setResult(cmd, "OK")
+}
+
+// setResult sets the reply of cmd, converting it to the command's type
+func setResult(cmd redis.Cmder, v any) {
+	switch cmd := cmd.(type) {
+	case *redis.StatusCmd:
+		cmd.SetVal(fmt.Sprint(v))
+	case *redis.StringCmd:
+		cmd.SetVal(fmt.Sprint(v))
+	case *redis.BoolCmd:
+		b, ok := v.(bool)
+		if !ok {
+			b = v == "OK"
+		}
+		cmd.SetVal(b)
+	case *redis.IntCmd:
+		n, _ := v.(int64)
+		cmd.SetVal(n)
+	case *redis.DurationCmd:
+		d, _ := v.(time.Duration)
+		cmd.SetVal(d)
+	case *redis.Cmd:
+		cmd.SetVal(v)
+	default:
+		cmd.SetErr(fmt.Errorf("in-memory Redis can't reply to %T", cmd))
+	}
+}
+
+// memRedisArg renders a command argument as Redis receives it
+func memRedisArg(arg any) string {
+	switch arg := arg.(type) {
+	case string:
+		return arg
+	case []byte:
+		return string(arg)
+	case bool:
+		if arg {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return arg.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(arg)
+	}
+}
@@ -0,0 +1,1079 @@
+package testutil
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/synapse/synapse/internal/store"
+)
+
+// MemStore keeps what the store keeps in memory, following the rules of the
+// Postgres store: re-inserting an order or event is a no-op, cancelled
+// orders don't advance, every change bumps an order's version, and lists
+// are sorted and paged the same way. It implements the store's repositories
+// and pipeline.OrderStore, so handlers, services and the pipeline can run
+// without a database, and adds accessors to check what was stored.
+//
+// Text search matches orders containing every word of the query; the other
+// web search operators aren't supported.
+type MemStore struct {
+	mu             sync.Mutex
+	orders         map[string]*store.Order
+	events         []store.Event
+	dlq            map[string]*store.DLQItem
+	requeued       map[string]bool
+	dlqJobs        map[string]store.DLQJob
+	pipelineErrors []store.PipelineError
+	overrides      map[string]store.StageOverride
+	webhooks       map[string]store.WebhookSubscription
+	deliveries     []store.WebhookDelivery
+	audit          []store.AuditEntry
+	exportJobs     map[string]store.ExportJob
+	exportFiles    map[string][]byte
+	importJobs     map[string]store.ImportJob
+	apiKeys        map[string]store.APIKey
+}
+
+var (
+	_ store.OrderRepository   = (*MemStore)(nil)
+	_ store.EventRepository   = (*MemStore)(nil)
+	_ store.DLQRepository     = (*MemStore)(nil)
+	_ store.AuditRepository   = (*MemStore)(nil)
+	_ store.ExportRepository  = (*MemStore)(nil)
+	_ store.ImportRepository  = (*MemStore)(nil)
+	_ store.WebhookRepository = (*MemStore)(nil)
+	_ store.StatsRepository   = (*MemStore)(nil)
+)
+
+// NewMemStore creates an empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		orders:      make(map[string]*store.Order),
+		dlq:         make(map[string]*store.DLQItem),
+		requeued:    make(map[string]bool),
+		dlqJobs:     make(map[string]store.DLQJob),
+		overrides:   make(map[string]store.StageOverride),
+		webhooks:    make(map[string]store.WebhookSubscription),
+		exportJobs:  make(map[string]store.ExportJob),
+		exportFiles: make(map[string][]byte),
+		importJobs:  make(map[string]store.ImportJob),
+		apiKeys:     make(map[string]store.APIKey),
+	}
+}
+
+// CreateOrder inserts a newly accepted order, unless it exists
+func (m *MemStore) CreateOrder(_ context.Context, o *store.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.orders[o.ID]; ok {
+		return nil
+	}
+	stored := *o
+	stored.UpdatedAt = o.CreatedAt
+	stored.Version = 1
+	m.orders[o.ID] = &stored
+	return nil
+}
+
+// MarkValidated records that an order passed validation
+func (m *MemStore) MarkValidated(_ context.Context, orderID string, at time.Time) error {
+	return m.advance(orderID, func(o *store.Order) {
+		o.Status, o.CurrentStage, o.ValidatedAt = "validated", "validate", &at
+	}, at)
+}
+
+// MarkEnriched records an order's enrichment data
+func (m *MemStore) MarkEnriched(_ context.Context, orderID string, at time.Time, enrichment json.RawMessage) error {
+	return m.advance(orderID, func(o *store.Order) {
+		o.Status, o.CurrentStage, o.EnrichedAt, o.Enrichment = "enriched", "enrich", &at, enrichment
+	}, at)
+}
+
+// MarkRouted records an order's routing decision
+func (m *MemStore) MarkRouted(_ context.Context, orderID string, at time.Time, destination, reason string) error {
+	return m.advance(orderID, func(o *store.Order) {
+		o.Status, o.CurrentStage, o.RoutedAt = "routed", "route", &at
+		o.Destination, o.RoutingReason = destination, reason
+	}, at)
+}
+
+// advance applies a stage transition, or returns store.ErrNotFound for
+// missing and cancelled orders
+func (m *MemStore) advance(orderID string, apply func(*store.Order), at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.orders[orderID]
+	if !ok || o.Status == "cancelled" {
+		return fmt.Errorf("order %s: %w", orderID, store.ErrNotFound)
+	}
+	apply(o)
+	o.UpdatedAt = at
+	o.Version++
+	return nil
+}
+
+// GetOrder returns a copy of an order, or store.ErrNotFound
+func (m *MemStore) GetOrder(_ context.Context, orderID string) (*store.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.orders[orderID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	c := *o
+	return &c, nil
+}
+
+// cancellableStatuses are the states from which an order may be cancelled
+var cancellableStatuses = map[string]bool{
+	"accepted":   true,
+	"validating": true,
+	"validated":  true,
+	"enriching":  true,
+	"enriched":   true,
+	"routing":    true,
+}
+
+// CancelOrder cancels an order that has not been routed yet and returns it,
+// reporting whether this call cancelled it, like the Postgres store
+func (m *MemStore) CancelOrder(_ context.Context, orderID string, at time.Time, ifVersions []int64) (*store.Order, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.orders[orderID]
+	if !ok {
+		return nil, false, store.ErrNotFound
+	}
+	if o.Status == "cancelled" {
+		c := *o
+		return &c, false, nil
+	}
+	if ifVersions != nil && !slices.Contains(ifVersions, o.Version) {
+		c := *o
+		return &c, false, store.ErrVersionMismatch
+	}
+	if !cancellableStatuses[o.Status] {
+		c := *o
+		return &c, false, store.ErrNotCancellable
+	}
+	o.PreviousStatus, o.Status = o.Status, "cancelled"
+	o.CancelledAt, o.UpdatedAt = &at, at
+	o.Version++
+	c := *o
+	return &c, true, nil
+}
+
+// ListOrders returns a page of orders, newest first
+func (m *MemStore) ListOrders(_ context.Context, p store.ListOrdersParams) ([]store.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	orders := m.matchingOrders(p.OrderFilter)
+	slices.SortFunc(orders, func(a, b store.Order) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+	})
+	offset := p.Offset
+	if p.After != nil {
+		orders = slices.DeleteFunc(orders, func(o store.Order) bool {
+			return !before(o.CreatedAt, o.ID, p.After)
+		})
+		offset = 0
+	}
+	return page(orders, offset, p.Limit), nil
+}
+
+// CountOrders returns the number of orders matching f
+func (m *MemStore) CountOrders(_ context.Context, f store.OrderFilter) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.matchingOrders(f)), nil
+}
+
+// matchingOrders returns copies of the orders matching f
+func (m *MemStore) matchingOrders(f store.OrderFilter) []store.Order {
+	var orders []store.Order
+	for _, o := range m.orders {
+		if orderMatches(o, f) {
+			orders = append(orders, *o)
+		}
+	}
+	return orders
+}
+
+// orderMatches reports whether o matches every criterion of f
+func orderMatches(o *store.Order, f store.OrderFilter) bool {
+	switch {
+	case len(f.Statuses) > 0 && !slices.Contains(f.Statuses, o.Status),
+		f.CustomerID != "" && o.CustomerID != f.CustomerID,
+		f.Destination != "" && o.Destination != f.Destination,
+		f.CreatedAfter != nil && o.CreatedAt.Before(*f.CreatedAfter),
+		f.CreatedBefore != nil && !o.CreatedAt.Before(*f.CreatedBefore),
+		f.MinAmount != nil && o.TotalAmount < *f.MinAmount,
+		f.MaxAmount != nil && o.TotalAmount > *f.MaxAmount,
+		f.Currency != "" && o.Currency != f.Currency:
+		return false
+	}
+	if f.SKU != "" {
+		var items []struct {
+			SKU string `json:"sku"`
+		}
+		_ = json.Unmarshal(o.Items, &items)
+		if !slices.ContainsFunc(items, func(item struct {
+			SKU string `json:"sku"`
+		}) bool {
+			return item.SKU == f.SKU
+		}) {
+			return false
+		}
+	}
+	if f.CustomerTier != "" {
+		var enrichment struct {
+			Customer struct {
+				Tier string `json:"tier"`
+			} `json:"customer"`
+		}
+		_ = json.Unmarshal(o.Enrichment, &enrichment)
+		if enrichment.Customer.Tier != f.CustomerTier {
+			return false
+		}
+	}
+	if f.ShippingCountry != "" {
+		var address struct {
+			Country string `json:"country"`
+		}
+		_ = json.Unmarshal(o.ShippingAddress, &address)
+		if address.Country != f.ShippingCountry {
+			return false
+		}
+	}
+	if f.Text != "" {
+		words := make(map[string]bool)
+		for _, doc := range []json.RawMessage{o.Items, o.ShippingAddress} {
+			var v any
+			_ = json.Unmarshal(doc, &v)
+			addWords(words, v)
+		}
+		addWords(words, o.RoutingReason)
+		for _, w := range splitWords(f.Text) {
+			if !words[w] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// addWords adds the words of the strings in a decoded JSON value to words
+func addWords(words map[string]bool, v any) {
+	switch v := v.(type) {
+	case string:
+		for _, w := range splitWords(v) {
+			words[w] = true
+		}
+	case []any:
+		for _, e := range v {
+			addWords(words, e)
+		}
+	case map[string]any:
+		for _, e := range v {
+			addWords(words, e)
+		}
+	}
+}
+
+// splitWords returns the lowercased words of s
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// AppendEvent records an order event, unless its ID was recorded
+func (m *MemStore) AppendEvent(_ context.Context, e *store.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if slices.ContainsFunc(m.events, func(stored store.Event) bool { return stored.ID == e.ID }) {
+		return nil
+	}
+	stored := *e
+	stored.Seq = int64(len(m.events) + 1)
+	m.events = append(m.events, stored)
+	return nil
+}
+
+// ListEvents returns a page of an order's events, oldest first unless
+// p.Descending is set
+func (m *MemStore) ListEvents(_ context.Context, orderID string, p store.ListEventsParams) ([]store.Event, error) {
+	events := m.Events(orderID)
+	if p.Descending {
+		slices.Reverse(events)
+	}
+	offset := p.Offset
+	if p.After != nil {
+		seq, err := strconv.ParseInt(p.After.Key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event cursor key %q: %w", p.After.Key, err)
+		}
+		events = slices.DeleteFunc(events, func(e store.Event) bool {
+			c := cmp.Or(e.OccurredAt.Compare(p.After.Time), cmp.Compare(e.Seq, seq))
+			if p.Descending {
+				return c >= 0
+			}
+			return c <= 0
+		})
+		offset = 0
+	}
+	return page(events, offset, p.Limit), nil
+}
+
+// CountEvents returns the number of events recorded for an order
+func (m *MemStore) CountEvents(_ context.Context, orderID string) (int, error) {
+	return len(m.Events(orderID)), nil
+}
+
+// Events returns an order's events, oldest first
+func (m *MemStore) Events(orderID string) []store.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var events []store.Event
+	for _, e := range m.events {
+		if e.OrderID == orderID {
+			events = append(events, e)
+		}
+	}
+	slices.SortStableFunc(events, func(a, b store.Event) int {
+		return cmp.Or(a.OccurredAt.Compare(b.OccurredAt), cmp.Compare(a.Seq, b.Seq))
+	})
+	return events
+}
+
+// SaveDLQItem records a dead-lettered message, replacing an earlier record
+// of it, adding up the retries and keeping the time it was last retried
+func (m *MemStore) SaveDLQItem(_ context.Context, item *store.DLQItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *item
+	if earlier, ok := m.dlq[item.EventID]; ok {
+		stored.RetryCount += earlier.RetryCount
+		stored.LastRetryAt = earlier.LastRetryAt
+	}
+	m.dlq[item.EventID] = &stored
+	delete(m.requeued, item.EventID)
+	return nil
+}
+
+// GetDLQItem returns a dead-lettered message that hasn't been requeued, or
+// store.ErrNotFound
+func (m *MemStore) GetDLQItem(_ context.Context, eventID string) (*store.DLQItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.dlq[eventID]
+	if !ok || m.requeued[eventID] {
+		return nil, store.ErrNotFound
+	}
+	c := *item
+	return &c, nil
+}
+
+// RequeueDLQItem marks a dead-lettered message as requeued at the given
+// time, or returns store.ErrNotFound when it isn't on the DLQ
+func (m *MemStore) RequeueDLQItem(_ context.Context, eventID string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.dlq[eventID]
+	if !ok || m.requeued[eventID] {
+		return fmt.Errorf("DLQ item %s: %w", eventID, store.ErrNotFound)
+	}
+	m.requeued[eventID] = true
+	item.LastRetryAt = &at
+	return nil
+}
+
+// RestoreDLQItem puts a message RequeueDLQItem took off the DLQ back on it,
+// with the lastRetryAt it had before
+func (m *MemStore) RestoreDLQItem(_ context.Context, eventID string, lastRetryAt *time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if item, ok := m.dlq[eventID]; ok {
+		delete(m.requeued, eventID)
+		item.LastRetryAt = lastRetryAt
+	}
+	return nil
+}
+
+// DeleteDLQItems deletes the dead-lettered messages matching f, returning
+// how many were deleted
+func (m *MemStore) DeleteDLQItems(_ context.Context, f store.DLQFilter) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for id, item := range m.dlq {
+		if m.dlqMatches(item, f) {
+			delete(m.dlq, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ListDLQItems returns a page of dead-lettered messages, most recent failure
+// first
+func (m *MemStore) ListDLQItems(_ context.Context, p store.ListDLQParams) ([]store.DLQItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	items := m.matchingDLQItems(p.DLQFilter)
+	slices.SortFunc(items, func(a, b store.DLQItem) int {
+		return cmp.Or(b.FailedAt.Compare(a.FailedAt), cmp.Compare(b.EventID, a.EventID))
+	})
+	offset := p.Offset
+	if p.After != nil {
+		items = slices.DeleteFunc(items, func(item store.DLQItem) bool {
+			return !before(item.FailedAt, item.EventID, p.After)
+		})
+		offset = 0
+	}
+	return page(items, offset, p.Limit), nil
+}
+
+// CountDLQItems returns the number of dead-lettered messages matching f
+func (m *MemStore) CountDLQItems(_ context.Context, f store.DLQFilter) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.matchingDLQItems(f)), nil
+}
+
+// matchingDLQItems returns copies of the dead-lettered messages matching f
+func (m *MemStore) matchingDLQItems(f store.DLQFilter) []store.DLQItem {
+	var items []store.DLQItem
+	for _, item := range m.dlq {
+		if m.dlqMatches(item, f) {
+			items = append(items, *item)
+		}
+	}
+	return items
+}
+
+// dlqMatches reports whether item is on the DLQ and matches f
+func (m *MemStore) dlqMatches(item *store.DLQItem, f store.DLQFilter) bool {
+	return !m.requeued[item.EventID] &&
+		(len(f.Stages) == 0 || slices.Contains(f.Stages, item.Stage)) &&
+		(len(f.ErrorTypes) == 0 || slices.Contains(f.ErrorTypes, item.ErrorType)) &&
+		(f.FailedAfter == nil || !item.FailedAt.Before(*f.FailedAfter)) &&
+		(f.FailedBefore == nil || item.FailedAt.Before(*f.FailedBefore))
+}
+
+// CreateDLQJob inserts a DLQ job
+func (m *MemStore) CreateDLQJob(_ context.Context, j *store.DLQJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.dlqJobs[j.ID]; ok {
+		return fmt.Errorf("DLQ job %s already exists", j.ID)
+	}
+	stored := *j
+	stored.UpdatedAt = j.CreatedAt
+	m.dlqJobs[j.ID] = stored
+	return nil
+}
+
+// UpdateDLQJob saves a DLQ job's status and progress
+func (m *MemStore) UpdateDLQJob(_ context.Context, j *store.DLQJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.dlqJobs[j.ID]
+	if !ok {
+		return fmt.Errorf("DLQ job %s: %w", j.ID, store.ErrNotFound)
+	}
+	stored.Status, stored.Matched, stored.Processed = j.Status, j.Matched, j.Processed
+	stored.Succeeded, stored.Skipped, stored.Failed = j.Succeeded, j.Skipped, j.Failed
+	stored.Error, stored.UpdatedAt, stored.CompletedAt = j.Error, j.UpdatedAt, j.CompletedAt
+	m.dlqJobs[j.ID] = stored
+	return nil
+}
+
+// GetDLQJob returns a DLQ job, or store.ErrNotFound
+func (m *MemStore) GetDLQJob(_ context.Context, jobID string) (*store.DLQJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.dlqJobs[jobID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &j, nil
+}
+
+// DLQItems returns the messages on the DLQ, most recent failure first
+func (m *MemStore) DLQItems() []store.DLQItem {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	items := make([]store.DLQItem, 0, len(m.dlq))
+	for _, item := range m.dlq {
+		if !m.requeued[item.EventID] {
+			items = append(items, *item)
+		}
+	}
+	slices.SortFunc(items, func(a, b store.DLQItem) int {
+		return cmp.Or(b.FailedAt.Compare(a.FailedAt), cmp.Compare(a.EventID, b.EventID))
+	})
+	return items
+}
+
+// SavePipelineError records a pipeline error, unless its ID was recorded
+func (m *MemStore) SavePipelineError(_ context.Context, e *store.PipelineError) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !slices.ContainsFunc(m.pipelineErrors, func(stored store.PipelineError) bool { return stored.ID == e.ID }) {
+		m.pipelineErrors = append(m.pipelineErrors, *e)
+	}
+	return nil
+}
+
+// PipelineErrors returns the recorded pipeline errors, in the order they were
+// saved
+func (m *MemStore) PipelineErrors() []store.PipelineError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.pipelineErrors)
+}
+
+// ListPipelineErrors returns a page of pipeline errors, most recent first,
+// noting which of the failed messages are on the DLQ
+func (m *MemStore) ListPipelineErrors(_ context.Context, p store.ListPipelineErrorsParams) ([]store.PipelineError, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	errs := m.matchingPipelineErrors(p.PipelineErrorFilter)
+	for i, e := range errs {
+		item, ok := m.dlq[e.EventID]
+		errs[i].InDLQ = ok && item.Stage == e.Stage && !m.requeued[e.EventID]
+	}
+	slices.SortFunc(errs, func(a, b store.PipelineError) int {
+		return cmp.Or(b.OccurredAt.Compare(a.OccurredAt), cmp.Compare(b.ID, a.ID))
+	})
+	offset := p.Offset
+	if p.After != nil {
+		errs = slices.DeleteFunc(errs, func(e store.PipelineError) bool {
+			return !before(e.OccurredAt, e.ID, p.After)
+		})
+		offset = 0
+	}
+	return page(errs, offset, p.Limit), nil
+}
+
+// CountPipelineErrors returns the number of pipeline errors matching f
+func (m *MemStore) CountPipelineErrors(_ context.Context, f store.PipelineErrorFilter) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.matchingPipelineErrors(f)), nil
+}
+
+// matchingPipelineErrors returns copies of the pipeline errors matching f
+func (m *MemStore) matchingPipelineErrors(f store.PipelineErrorFilter) []store.PipelineError {
+	var errs []store.PipelineError
+	for _, e := range m.pipelineErrors {
+		if (len(f.Stages) == 0 || slices.Contains(f.Stages, e.Stage)) &&
+			(len(f.ErrorTypes) == 0 || slices.Contains(f.ErrorTypes, e.ErrorType)) &&
+			(f.OccurredAfter == nil || !e.OccurredAt.Before(*f.OccurredAfter)) &&
+			(f.OccurredBefore == nil || e.OccurredAt.Before(*f.OccurredBefore)) {
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
+
+// PipelineStats aggregates the events the given stages recorded in
+// [from, to) into buckets of width bucket, aligned to the Unix epoch, like
+// the Postgres store
+func (m *MemStore) PipelineStats(_ context.Context, from, to time.Time, bucket time.Duration, stages []string) ([]store.StageStats, error) {
+	type key struct {
+		bucket time.Time
+		stage  string
+	}
+	m.mu.Lock()
+	groups := make(map[key][]store.Event)
+	for _, e := range m.events {
+		if e.OccurredAt.Before(from) || !e.OccurredAt.Before(to) || !slices.Contains(stages, e.Stage) {
+			continue
+		}
+		k := key{bucketStart(e.OccurredAt, bucket), e.Stage}
+		groups[k] = append(groups[k], e)
+	}
+	m.mu.Unlock()
+
+	stats := make([]store.StageStats, 0, len(groups))
+	for k, events := range groups {
+		st := store.StageStats{Bucket: k.bucket, Stage: k.stage}
+		var durations []float64
+		for _, e := range events {
+			switch e.Status {
+			case "completed":
+				st.Completed++
+				durations = append(durations, float64(e.DurationMs))
+			case "failed":
+				st.Failed++
+			}
+		}
+		slices.Sort(durations)
+		st.P50, st.P95, st.P99 = percentile(durations, 0.5), percentile(durations, 0.95), percentile(durations, 0.99)
+		stats = append(stats, st)
+	}
+	slices.SortFunc(stats, func(a, b store.StageStats) int {
+		return cmp.Or(a.Bucket.Compare(b.Bucket), cmp.Compare(a.Stage, b.Stage))
+	})
+	if len(stats) == 0 {
+		return nil, nil
+	}
+	return stats, nil
+}
+
+// DLQArrivals counts the messages dead-lettered in [from, to) per bucket of
+// width bucket, aligned like PipelineStats. Messages since requeued are
+// counted; purged ones aren't.
+func (m *MemStore) DLQArrivals(_ context.Context, from, to time.Time, bucket time.Duration) ([]store.BucketCount, error) {
+	m.mu.Lock()
+	counts := make(map[time.Time]int)
+	for _, item := range m.dlq {
+		if !item.FailedAt.Before(from) && item.FailedAt.Before(to) {
+			counts[bucketStart(item.FailedAt, bucket)]++
+		}
+	}
+	m.mu.Unlock()
+
+	var arrivals []store.BucketCount
+	for _, b := range slices.SortedFunc(maps.Keys(counts), time.Time.Compare) {
+		arrivals = append(arrivals, store.BucketCount{Bucket: b, Count: counts[b]})
+	}
+	return arrivals, nil
+}
+
+// bucketStart returns the start of t's bucket of width bucket, aligned to the
+// Unix epoch
+func bucketStart(t time.Time, bucket time.Duration) time.Time {
+	width := bucket.Seconds()
+	start := math.Floor(float64(t.UnixNano())/1e9/width) * width
+	return time.Unix(0, int64(start*1e9)).UTC()
+}
+
+// percentile interpolates the p-th percentile of sorted values, as
+// percentile_cont does; 0 when there are none
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := p * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[i]
+	}
+	return sorted[i] + (sorted[i+1]-sorted[i])*(pos-float64(i))
+}
+
+// SaveStageOverride inserts or replaces a stage's override
+func (m *MemStore) SaveStageOverride(_ context.Context, o *store.StageOverride) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.overrides[o.StageID] = *o
+	return nil
+}
+
+// ListStageOverrides returns every saved stage override
+func (m *MemStore) ListStageOverrides(context.Context) ([]store.StageOverride, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var overrides []store.StageOverride
+	for _, id := range slices.Sorted(maps.Keys(m.overrides)) {
+		overrides = append(overrides, m.overrides[id])
+	}
+	return overrides, nil
+}
+
+// CreateWebhookSubscription inserts a webhook subscription
+func (m *MemStore) CreateWebhookSubscription(_ context.Context, sub *store.WebhookSubscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.webhooks[sub.ID]; ok {
+		return fmt.Errorf("webhook subscription %s already exists", sub.ID)
+	}
+	m.webhooks[sub.ID] = *sub
+	return nil
+}
+
+// GetWebhookSubscription returns a webhook subscription, or
+// store.ErrNotFound
+func (m *MemStore) GetWebhookSubscription(_ context.Context, id string) (*store.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.webhooks[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &sub, nil
+}
+
+// UpdateWebhookSubscription saves a subscription's settings, or returns
+// store.ErrNotFound
+func (m *MemStore) UpdateWebhookSubscription(_ context.Context, sub *store.WebhookSubscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.webhooks[sub.ID]
+	if !ok {
+		return fmt.Errorf("webhook subscription %s: %w", sub.ID, store.ErrNotFound)
+	}
+	stored.URL, stored.EventTypes, stored.Secret = sub.URL, sub.EventTypes, sub.Secret
+	stored.Description, stored.Active, stored.UpdatedAt = sub.Description, sub.Active, sub.UpdatedAt
+	m.webhooks[sub.ID] = stored
+	return nil
+}
+
+// DeleteWebhookSubscription deletes a subscription and its delivery history,
+// or returns store.ErrNotFound
+func (m *MemStore) DeleteWebhookSubscription(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.webhooks[id]; !ok {
+		return fmt.Errorf("webhook subscription %s: %w", id, store.ErrNotFound)
+	}
+	delete(m.webhooks, id)
+	m.deliveries = slices.DeleteFunc(m.deliveries, func(d store.WebhookDelivery) bool {
+		return d.SubscriptionID == id
+	})
+	return nil
+}
+
+// ListWebhookSubscriptions returns the webhook subscriptions, oldest first.
+// With activeOnly set, paused subscriptions are left out.
+func (m *MemStore) ListWebhookSubscriptions(_ context.Context, activeOnly bool) ([]store.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var subs []store.WebhookSubscription
+	for _, sub := range m.webhooks {
+		if sub.Active || !activeOnly {
+			subs = append(subs, sub)
+		}
+	}
+	slices.SortFunc(subs, func(a, b store.WebhookSubscription) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return subs, nil
+}
+
+// RecordWebhookDelivery records a delivery attempt to a stored subscription
+func (m *MemStore) RecordWebhookDelivery(_ context.Context, d *store.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.webhooks[d.SubscriptionID]; !ok {
+		return nil
+	}
+	stored := *d
+	stored.Seq = 1
+	if len(m.deliveries) > 0 {
+		stored.Seq = m.deliveries[len(m.deliveries)-1].Seq + 1
+	}
+	m.deliveries = append(m.deliveries, stored)
+	return nil
+}
+
+// WebhookDeliveries returns a subscription's delivery attempts, oldest first
+func (m *MemStore) WebhookDeliveries(subscriptionID string) []store.WebhookDelivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var deliveries []store.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries
+}
+
+// ListWebhookDeliveries returns a page of a subscription's delivery attempts,
+// newest first
+func (m *MemStore) ListWebhookDeliveries(_ context.Context, subscriptionID string, p store.ListDeliveriesParams) ([]store.WebhookDelivery, error) {
+	deliveries := m.WebhookDeliveries(subscriptionID)
+	slices.SortFunc(deliveries, func(a, b store.WebhookDelivery) int {
+		return cmp.Or(b.AttemptedAt.Compare(a.AttemptedAt), cmp.Compare(b.Seq, a.Seq))
+	})
+	offset := p.Offset
+	if p.After != nil {
+		seq, err := strconv.ParseInt(p.After.Key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delivery cursor key %q: %w", p.After.Key, err)
+		}
+		deliveries = slices.DeleteFunc(deliveries, func(d store.WebhookDelivery) bool {
+			return cmp.Or(d.AttemptedAt.Compare(p.After.Time), cmp.Compare(d.Seq, seq)) >= 0
+		})
+		offset = 0
+	}
+	return page(deliveries, offset, p.Limit), nil
+}
+
+// RecordAudit inserts an audit entry
+func (m *MemStore) RecordAudit(_ context.Context, e *store.AuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *e
+	stored.Seq = int64(len(m.audit) + 1)
+	m.audit = append(m.audit, stored)
+	return nil
+}
+
+// ListAuditEntries returns a page of audit entries matching p.AuditFilter,
+// newest first
+func (m *MemStore) ListAuditEntries(_ context.Context, p store.ListAuditParams) ([]store.AuditEntry, error) {
+	m.mu.Lock()
+	entries := m.matchingAuditEntries(p.AuditFilter)
+	m.mu.Unlock()
+	slices.SortFunc(entries, func(a, b store.AuditEntry) int {
+		return cmp.Or(b.OccurredAt.Compare(a.OccurredAt), cmp.Compare(b.Seq, a.Seq))
+	})
+	offset := p.Offset
+	if p.After != nil {
+		seq, err := strconv.ParseInt(p.After.Key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit cursor key %q: %w", p.After.Key, err)
+		}
+		entries = slices.DeleteFunc(entries, func(e store.AuditEntry) bool {
+			return cmp.Or(e.OccurredAt.Compare(p.After.Time), cmp.Compare(e.Seq, seq)) >= 0
+		})
+		offset = 0
+	}
+	return page(entries, offset, p.Limit), nil
+}
+
+// CountAuditEntries returns the number of audit entries matching f
+func (m *MemStore) CountAuditEntries(_ context.Context, f store.AuditFilter) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.matchingAuditEntries(f)), nil
+}
+
+// matchingAuditEntries returns the audit entries matching f
+func (m *MemStore) matchingAuditEntries(f store.AuditFilter) []store.AuditEntry {
+	var entries []store.AuditEntry
+	for _, e := range m.audit {
+		switch {
+		case f.ActorID != "" && e.ActorID != f.ActorID,
+			len(f.Methods) > 0 && !slices.Contains(f.Methods, e.Method),
+			f.Route != "" && e.Route != f.Route,
+			f.Path != "" && e.Path != f.Path,
+			f.Outcome == "success" && e.StatusCode >= 400,
+			f.Outcome == "failure" && e.StatusCode < 400,
+			f.OccurredAfter != nil && e.OccurredAt.Before(*f.OccurredAfter),
+			f.OccurredBefore != nil && !e.OccurredAt.Before(*f.OccurredBefore):
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// CreateExportJob inserts an export job
+func (m *MemStore) CreateExportJob(_ context.Context, j *store.ExportJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.exportJobs[j.ID]; ok {
+		return fmt.Errorf("export job %s already exists", j.ID)
+	}
+	stored := *j
+	stored.UpdatedAt = j.CreatedAt
+	m.exportJobs[j.ID] = stored
+	return nil
+}
+
+// UpdateExportJob saves an export job's status and progress
+func (m *MemStore) UpdateExportJob(_ context.Context, j *store.ExportJob) error {
+	return m.updateExportJob(j, nil)
+}
+
+// SaveExportFile saves an export job's status along with the exported file
+func (m *MemStore) SaveExportFile(_ context.Context, j *store.ExportJob, content []byte) error {
+	if content == nil {
+		content = []byte{}
+	}
+	return m.updateExportJob(j, content)
+}
+
+// updateExportJob saves an export job, and its file unless content is nil
+func (m *MemStore) updateExportJob(j *store.ExportJob, content []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.exportJobs[j.ID]
+	if !ok {
+		return fmt.Errorf("export job %s: %w", j.ID, store.ErrNotFound)
+	}
+	stored.Status, stored.Matched, stored.Exported, stored.SizeBytes = j.Status, j.Matched, j.Exported, j.SizeBytes
+	stored.Error, stored.UpdatedAt, stored.CompletedAt, stored.ExpiresAt = j.Error, j.UpdatedAt, j.CompletedAt, j.ExpiresAt
+	m.exportJobs[j.ID] = stored
+	if content != nil {
+		m.exportFiles[j.ID] = slices.Clone(content)
+	}
+	return nil
+}
+
+// GetExportJob returns an export job, or store.ErrNotFound
+func (m *MemStore) GetExportJob(_ context.Context, jobID string) (*store.ExportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.exportJobs[jobID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &j, nil
+}
+
+// ExportFile returns the file an export job exported, or store.ErrNotFound
+// if the job doesn't exist or hasn't saved one
+func (m *MemStore) ExportFile(_ context.Context, jobID string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	content, ok := m.exportFiles[jobID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return slices.Clone(content), nil
+}
+
+// DeleteExpiredExportJobs deletes the export jobs that expired before now,
+// returning how many it deleted
+func (m *MemStore) DeleteExpiredExportJobs(_ context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for id, j := range m.exportJobs {
+		if j.ExpiresAt != nil && !j.ExpiresAt.After(now) {
+			delete(m.exportJobs, id)
+			delete(m.exportFiles, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// CreateImportJob inserts an import job
+func (m *MemStore) CreateImportJob(_ context.Context, j *store.ImportJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.importJobs[j.ID]; ok {
+		return fmt.Errorf("import job %s already exists", j.ID)
+	}
+	stored := *j
+	stored.UpdatedAt = j.CreatedAt
+	m.importJobs[j.ID] = stored
+	return nil
+}
+
+// UpdateImportJob saves an import job's status and progress
+func (m *MemStore) UpdateImportJob(_ context.Context, j *store.ImportJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.importJobs[j.ID]
+	if !ok {
+		return fmt.Errorf("import job %s: %w", j.ID, store.ErrNotFound)
+	}
+	stored.Status, stored.Processed, stored.Accepted, stored.Rejected, stored.Skipped = j.Status, j.Processed, j.Accepted, j.Rejected, j.Skipped
+	stored.Errors, stored.Error, stored.UpdatedAt, stored.CompletedAt = j.Errors, j.Error, j.UpdatedAt, j.CompletedAt
+	m.importJobs[j.ID] = stored
+	return nil
+}
+
+// GetImportJob returns an import job, or store.ErrNotFound
+func (m *MemStore) GetImportJob(_ context.Context, jobID string) (*store.ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.importJobs[jobID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &j, nil
+}
+
+// CreateAPIKey inserts an API key
+func (m *MemStore) CreateAPIKey(_ context.Context, k *store.APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.apiKeys[k.ID]; ok {
+		return fmt.Errorf("API key %s already exists", k.ID)
+	}
+	m.apiKeys[k.ID] = *k
+	return nil
+}
+
+// GetAPIKeyByHash returns the active key with the given hash, or
+// store.ErrNotFound
+func (m *MemStore) GetAPIKeyByHash(_ context.Context, hash string) (*store.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.apiKeys {
+		if k.Hash == hash && k.RevokedAt == nil {
+			return &k, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+// ListAPIKeys returns the active API keys, newest first
+func (m *MemStore) ListAPIKeys(context.Context) ([]store.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []store.APIKey
+	for _, k := range m.apiKeys {
+		if k.RevokedAt == nil {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b store.APIKey) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+	})
+	return keys, nil
+}
+
+// RevokeAPIKey revokes an active key and returns it, or store.ErrNotFound
+func (m *MemStore) RevokeAPIKey(_ context.Context, keyID string, at time.Time) (*store.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.apiKeys[keyID]
+	if !ok || k.RevokedAt != nil {
+		return nil, store.ErrNotFound
+	}
+	k.RevokedAt = &at
+	m.apiKeys[keyID] = k
+	return &k, nil
+}
+
+// TouchAPIKey records that a key was used at the given time
+func (m *MemStore) TouchAPIKey(_ context.Context, keyID string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if k, ok := m.apiKeys[keyID]; ok {
+		k.LastUsedAt = &at
+		m.apiKeys[keyID] = k
+	}
+	return nil
+}
+
+// before reports whether a row sorted by (t, key) descending comes after the
+// cursor c, i.e. (t, key) < (c.Time, c.Key)
+func before(t time.Time, key string, c *store.Cursor) bool {
+	return cmp.Or(t.Compare(c.Time), cmp.Compare(key, c.Key)) < 0
+}
+
+// page returns at most limit items from offset, as LIMIT and OFFSET would
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return items
+}
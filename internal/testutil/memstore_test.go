@@ -0,0 +1,99 @@
+package testutil_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/store"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestMemStore_Orders(t *testing.T) {
+	ctx := context.Background()
+	m := testutil.NewMemStore()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"o1", "o2", "o3"} {
+		require.NoError(t, m.CreateOrder(ctx, &store.Order{
+			ID: id, CustomerID: "cust-1", Status: "accepted", Currency: "USD", TotalAmount: float64(10 * (i + 1)),
+			Items:           json.RawMessage(`[{"sku":"SKU-` + id + `","name":"Blue widget"}]`),
+			ShippingAddress: json.RawMessage(`{"country":"US","city":"Springfield"}`),
+			CreatedAt:       base.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	require.NoError(t, m.MarkValidated(ctx, "o2", base))
+
+	// Newest first, paged by offset or keyset
+	orders, err := m.ListOrders(ctx, store.ListOrdersParams{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, []string{"o3", "o2"}, []string{orders[0].ID, orders[1].ID})
+	orders, err = m.ListOrders(ctx, store.ListOrdersParams{Limit: 2, After: &store.Cursor{Time: orders[1].CreatedAt, Key: orders[1].ID}})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "o1", orders[0].ID)
+
+	minAmount := 20.0
+	for _, tt := range []struct {
+		filter store.OrderFilter
+		want   int
+	}{
+		{store.OrderFilter{Statuses: []string{"validated"}}, 1},
+		{store.OrderFilter{MinAmount: &minAmount}, 2},
+		{store.OrderFilter{SKU: "SKU-o1"}, 1},
+		{store.OrderFilter{ShippingCountry: "US"}, 3},
+		{store.OrderFilter{ShippingCountry: "CA"}, 0},
+		{store.OrderFilter{Text: "blue springfield"}, 3},
+		{store.OrderFilter{Text: "red"}, 0},
+	} {
+		n, err := m.CountOrders(ctx, tt.filter)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, n, "%+v", tt.filter)
+	}
+
+	// Cancellation follows the Postgres store's rules
+	_, _, err = m.CancelOrder(ctx, "o1", base, []int64{7})
+	assert.ErrorIs(t, err, store.ErrVersionMismatch)
+	o, cancelled, err := m.CancelOrder(ctx, "o1", base, nil)
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	assert.Equal(t, "accepted", o.PreviousStatus)
+	assert.EqualValues(t, 2, o.Version)
+	_, cancelled, err = m.CancelOrder(ctx, "o1", base, nil)
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+	assert.ErrorIs(t, m.MarkValidated(ctx, "o1", base), store.ErrNotFound)
+}
+
+func TestMemStore_DLQ(t *testing.T) {
+	ctx := context.Background()
+	m := testutil.NewMemStore()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, m.SaveDLQItem(ctx, &store.DLQItem{EventID: "e1", Stage: "validate", ErrorType: "validation", FailedAt: now}))
+	require.NoError(t, m.SaveDLQItem(ctx, &store.DLQItem{EventID: "e2", Stage: "enrich", ErrorType: "timeout", FailedAt: now.Add(time.Minute)}))
+
+	// Requeued messages leave the DLQ until they are restored
+	require.NoError(t, m.RequeueDLQItem(ctx, "e1", now))
+	assert.ErrorIs(t, m.RequeueDLQItem(ctx, "e1", now), store.ErrNotFound)
+	_, err := m.GetDLQItem(ctx, "e1")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	n, err := m.CountDLQItems(ctx, store.DLQFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.NoError(t, m.RestoreDLQItem(ctx, "e1", nil))
+	items, err := m.ListDLQItems(ctx, store.ListDLQParams{Limit: 10, DLQFilter: store.DLQFilter{Stages: []string{"validate"}}})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "e1", items[0].EventID)
+
+	// Purged messages aren't counted as arrivals
+	deleted, err := m.DeleteDLQItems(ctx, store.DLQFilter{ErrorTypes: []string{"timeout"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	arrivals, err := m.DLQArrivals(ctx, now, now.Add(time.Hour), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []store.BucketCount{{Bucket: now, Count: 1}}, arrivals)
+}
@@ -0,0 +1,131 @@
+package testutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+	tcexec "github.com/testcontainers/testcontainers-go/exec"
+	"github.com/testcontainers/testcontainers-go/modules/minio"
+	"github.com/testcontainers/testcontainers-go/network"
+)
+
+// S3Region is the region S3 clients of the test object store sign for
+const S3Region = "us-east-1"
+
+// S3 is an S3-compatible object store for tests, served by a MinIO
+// container, e.g. for order exports and archives
+type S3 struct {
+	// Endpoint is the store's URL
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+
+	container *minio.MinioContainer
+}
+
+// StartS3 starts a MinIO container, on tc's network as "s3" when tc is
+// set, and terminates it when t finishes. TEST_S3_IMAGE overrides its image.
+// Buckets are created with CreateBucket or Bucket.
+func StartS3(ctx context.Context, t testing.TB, tc *TestContainers) (*S3, error) {
+	t.Helper()
+
+	var opts []testcontainers.ContainerCustomizer
+	if tc != nil && tc.Network != nil {
+		opts = append(opts, network.WithNetwork([]string{"s3"}, tc.Network))
+	}
+	container, err := minio.Run(ctx, image("TEST_S3_IMAGE", DefaultS3Image), opts...)
+	if container != nil {
+		t.Cleanup(func() {
+			if err := container.Terminate(context.WithoutCancel(ctx)); err != nil {
+				t.Logf("failed to terminate S3 container: %v", err)
+			}
+		})
+		reportContainerLogs(t, map[string]testcontainers.Container{"s3": container})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("starting S3 container: %w", err)
+	}
+
+	addr, err := container.ConnectionString(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &S3{
+		Endpoint:  "http://" + addr,
+		AccessKey: container.Username,
+		SecretKey: container.Password,
+		container: container,
+	}, nil
+}
+
+// CreateBucket creates bucket, unless it exists
+func (s *S3) CreateBucket(ctx context.Context, bucket string) error {
+	_, err := s.mc(ctx, "mb", "--ignore-existing", "local/"+bucket)
+	return err
+}
+
+// Bucket creates a bucket for t, named uniquely, and returns its name
+func (s *S3) Bucket(ctx context.Context, t testing.TB) string {
+	t.Helper()
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatalf("naming bucket: %v", err)
+	}
+	bucket := fmt.Sprintf("synapse-test-%x", id)
+	if err := s.CreateBucket(ctx, bucket); err != nil {
+		t.Fatalf("creating bucket: %v", err)
+	}
+	return bucket
+}
+
+// Objects returns the keys of the objects in bucket, in lexical order
+func (s *S3) Objects(ctx context.Context, bucket string) ([]string, error) {
+	out, err := s.mc(ctx, "ls", "--recursive", "--json", "local/"+bucket)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	lines := bufio.NewScanner(bytes.NewReader(out))
+	for lines.Scan() {
+		var entry struct {
+			Status string `json:"status"`
+			Key    string `json:"key"`
+		}
+		if err := json.Unmarshal(lines.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("parsing object listing: %w", err)
+		}
+		if entry.Status == "success" {
+			keys = append(keys, entry.Key)
+		}
+	}
+	return keys, lines.Err()
+}
+
+// mc runs the MinIO client in the container, as the root user of the store
+// it calls local
+func (s *S3) mc(ctx context.Context, args ...string) ([]byte, error) {
+	code, r, err := s.container.Exec(ctx, append([]string{"mc", "--quiet"}, args...),
+		tcexec.Multiplexed(),
+		tcexec.WithEnv([]string{fmt.Sprintf("MC_HOST_local=http://%s:%s@localhost:9000", s.AccessKey, s.SecretKey)}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("running mc %s: %w", args[0], err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading mc %s output: %w", args[0], err)
+	}
+	if code != 0 {
+		return nil, fmt.Errorf("mc %s: exit code %d: %s", args[0], code, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
@@ -0,0 +1,34 @@
+package testutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestS3(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	s3, err := testutil.StartS3(ctx, t, nil)
+	require.NoError(t, err)
+	require.NoError(t, testutil.WaitForReady(ctx, s3.Endpoint+"/minio/health/live"))
+
+	bucket := s3.Bucket(ctx, t)
+	require.NoError(t, s3.CreateBucket(ctx, bucket), "creating an existing bucket is a no-op")
+	keys, err := s3.Objects(ctx, bucket)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, err = s3.Objects(ctx, "missing")
+	assert.Error(t, err)
+}
@@ -2,75 +2,249 @@ package testutil
 
 import (
 	"context"
-	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5"
 	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
 	"github.com/synapse/synapse/internal/config"
 	"github.com/synapse/synapse/internal/infra"
+	"github.com/synapse/synapse/internal/store"
 )
 
-// TestInfra creates infrastructure connected to test containers
-func TestInfra(ctx context.Context, t *testing.T, tc *TestContainers) (*infra.Infra, *config.Config) {
-	t.Helper()
+// InfraOption customizes the infrastructure TestInfra sets up
+type InfraOption func(*infraOptions)
 
-	natsURL, err := tc.NATSConnectionString(ctx)
-	if err != nil {
-		t.Fatalf("getting NATS connection string: %v", err)
+type infraOptions struct {
+	schema     bool
+	migrations bool
+}
+
+// WithSchema gives the test a Postgres schema of its own, dropped when it
+// finishes, which its connections use as their search_path. Tests can then
+// share a database, without SharedContainers' database per test.
+func WithSchema() InfraOption {
+	return func(o *infraOptions) {
+		o.schema = true
 	}
+}
 
-	postgresURL, err := tc.PostgresConnectionString(ctx)
-	if err != nil {
-		t.Fatalf("getting Postgres connection string: %v", err)
+// WithoutMigrations leaves the test's database empty, e.g. for tests of the
+// migrations themselves
+func WithoutMigrations() InfraOption {
+	return func(o *infraOptions) {
+		o.migrations = false
 	}
+}
 
-	redisAddr, err := tc.RedisConnectionString(ctx)
-	if err != nil {
-		t.Fatalf("getting Redis connection string: %v", err)
+// TestInfra creates infrastructure connected to test containers, with the
+// store's migrations applied to the database. Only the containers started
+// are connected to; the others' connections are nil.
+func TestInfra(ctx context.Context, t testing.TB, tc *TestContainers, opts ...InfraOption) (*infra.Infra, *config.Config) {
+	t.Helper()
+
+	o := infraOptions{migrations: true}
+	for _, opt := range opts {
+		opt(&o)
 	}
 
 	cfg := &config.Config{
 		HTTPPort:            8080,
-		NATSURL:             natsURL,
-		RedisAddr:           redisAddr,
 		RedisPassword:       "",
 		RedisDB:             0,
 		PipelineConcurrency: 10,
 		RetryMaxAttempts:    3,
 		RetryBackoffMs:      100,
 	}
+	i := &infra.Infra{StartedAt: time.Now().UTC()}
 
 	// Connect to NATS
-	nc, err := nats.Connect(natsURL)
-	if err != nil {
-		t.Fatalf("connecting to NATS: %v", err)
+	if tc.NATS != nil {
+		natsURL, err := tc.NATSConnectionString(ctx)
+		if err != nil {
+			t.Fatalf("getting NATS connection string: %v", err)
+		}
+		cfg.NATSURL = natsURL
+
+		nc, err := nats.Connect(natsURL)
+		if err != nil {
+			t.Fatalf("connecting to NATS: %v", err)
+		}
+		t.Cleanup(func() { nc.Close() })
+		i.NATS = nc
 	}
-	t.Cleanup(func() { nc.Close() })
 
 	// Connect to PostgreSQL
-	db, err := sql.Open("postgres", postgresURL)
+	if tc.Postgres != nil {
+		postgresURL, err := tc.PostgresConnectionString(ctx)
+		if err != nil {
+			t.Fatalf("getting Postgres connection string: %v", err)
+		}
+		if tc.shared {
+			postgresURL = createDatabase(ctx, t, postgresURL)
+		}
+		if o.schema {
+			postgresURL = createSchema(ctx, t, postgresURL)
+		}
+
+		pool, db, err := infra.OpenPostgres(ctx, postgresURL, cfg)
+		if err != nil {
+			t.Fatalf("connecting to Postgres: %v", err)
+		}
+		t.Cleanup(func() {
+			db.Close()
+			pool.Close()
+		})
+		if o.migrations {
+			if err := store.Migrate(ctx, db); err != nil {
+				t.Fatalf("migrating Postgres: %v", err)
+			}
+		}
+		i.Pool, i.DB = pool, db
+	}
+
+	// Connect to Redis
+	if tc.Redis != nil {
+		redisAddr, err := tc.RedisConnectionString(ctx)
+		if err != nil {
+			t.Fatalf("getting Redis connection string: %v", err)
+		}
+		cfg.RedisAddr = redisAddr
+		if tc.shared {
+			cfg.RedisDB = acquireRedisDB(ctx, t, redisAddr)
+		}
+
+		rdb := redis.NewClient(&redis.Options{
+			Addr: redisAddr,
+			DB:   cfg.RedisDB,
+		})
+		if err := rdb.Ping(ctx).Err(); err != nil {
+			t.Fatalf("pinging Redis: %v", err)
+		}
+		t.Cleanup(func() { rdb.Close() })
+		i.Redis = rdb
+	}
+
+	return i, cfg
+}
+
+// isolated counts the databases and schemas created for tests
+var isolated atomic.Int64
+
+// redisDBs are the shared Redis server's databases not in use by a test
+var redisDBs = func() chan int {
+	dbs := make(chan int, sharedRedisDBs)
+	for db := range sharedRedisDBs {
+		dbs <- db
+	}
+	return dbs
+}()
+
+// sharedRedisDBs is how many databases Redis has by default, and so how many
+// tests can use the shared server at once
+const sharedRedisDBs = 16
+
+// acquireRedisDB returns a database of the shared Redis server at
+// redisAddr for t alone, waiting for one if every database is in use. It is
+// emptied when t finishes, for the next test to have it.
+func acquireRedisDB(ctx context.Context, t testing.TB, redisAddr string) int {
+	t.Helper()
+
+	var db int
+	select {
+	case db = <-redisDBs:
+	case <-ctx.Done():
+		t.Fatalf("waiting for a Redis database: %v", ctx.Err())
+	}
+	t.Cleanup(func() {
+		rdb := redis.NewClient(&redis.Options{Addr: redisAddr, DB: db})
+		defer rdb.Close()
+		if err := rdb.FlushDB(context.WithoutCancel(ctx)).Err(); err != nil {
+			// Left out of the pool, so no test gets its keys
+			t.Logf("failed to empty Redis database %d: %v", db, err)
+			return
+		}
+		redisDBs <- db
+	})
+	return db
+}
+
+// createDatabase creates an empty database for t on the server postgresURL
+// points to, drops it when t finishes, and returns its URL
+func createDatabase(ctx context.Context, t testing.TB, postgresURL string) string {
+	t.Helper()
+
+	conn, err := pgx.Connect(ctx, postgresURL)
 	if err != nil {
 		t.Fatalf("connecting to Postgres: %v", err)
 	}
-	if err := db.PingContext(ctx); err != nil {
-		t.Fatalf("pinging Postgres: %v", err)
-	}
-	t.Cleanup(func() { db.Close() })
+	defer conn.Close(ctx)
 
-	// Connect to Redis
-	rdb := redis.NewClient(&redis.Options{
-		Addr: redisAddr,
+	name := fmt.Sprintf("test_%d_%d", os.Getpid(), isolated.Add(1))
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+		t.Fatalf("creating database %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		ctx := context.WithoutCancel(ctx)
+		conn, err := pgx.Connect(ctx, postgresURL)
+		if err != nil {
+			t.Logf("failed to drop database %s: %v", name, err)
+			return
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)"); err != nil {
+			t.Logf("failed to drop database %s: %v", name, err)
+		}
 	})
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		t.Fatalf("pinging Redis: %v", err)
+
+	u, err := url.Parse(postgresURL)
+	if err != nil {
+		t.Fatalf("parsing Postgres connection string: %v", err)
 	}
-	t.Cleanup(func() { rdb.Close() })
+	u.Path = "/" + name
+	return u.String()
+}
+
+// createSchema creates an empty schema for t in the database postgresURL
+// points to, drops it when t finishes, and returns a URL whose connections
+// use it as their search_path
+func createSchema(ctx context.Context, t testing.TB, postgresURL string) string {
+	t.Helper()
+
+	conn, err := pgx.Connect(ctx, postgresURL)
+	if err != nil {
+		t.Fatalf("connecting to Postgres: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	name := fmt.Sprintf("test_%d_%d", os.Getpid(), isolated.Add(1))
+	if _, err := conn.Exec(ctx, "CREATE SCHEMA "+name); err != nil {
+		t.Fatalf("creating schema %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		ctx := context.WithoutCancel(ctx)
+		conn, err := pgx.Connect(ctx, postgresURL)
+		if err != nil {
+			t.Logf("failed to drop schema %s: %v", name, err)
+			return
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Exec(ctx, "DROP SCHEMA IF EXISTS "+name+" CASCADE"); err != nil {
+			t.Logf("failed to drop schema %s: %v", name, err)
+		}
+	})
 
-	return &infra.Infra{
-		NATS:  nc,
-		DB:    db,
-		Redis: rdb,
-	}, cfg
+	u, err := url.Parse(postgresURL)
+	if err != nil {
+		t.Fatalf("parsing Postgres connection string: %v", err)
+	}
+	q := u.Query()
+	q.Set("search_path", name)
+	u.RawQuery = q.Encode()
+	return u.String()
 }
@@ -0,0 +1,81 @@
+package testutil_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestTestInfra_Schemas(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.StartContainers(ctx, t, nil)
+	require.NoError(t, err)
+
+	// Each schema is migrated, and sees only its own rows
+	first, _ := testutil.TestInfra(ctx, t, tc, testutil.WithSchema())
+	second, _ := testutil.TestInfra(ctx, t, tc, testutil.WithSchema())
+	require.NoError(t, testutil.Seed(ctx, first.DB, testutil.Fixtures{
+		Orders: []testutil.OrderFixture{{OrderID: "order-1"}},
+	}))
+	var n int
+	require.NoError(t, first.DB.QueryRowContext(ctx, `SELECT count(*) FROM orders`).Scan(&n))
+	assert.Equal(t, 1, n)
+	require.NoError(t, second.DB.QueryRowContext(ctx, `SELECT count(*) FROM orders`).Scan(&n))
+	assert.Equal(t, 0, n)
+
+	empty, _ := testutil.TestInfra(ctx, t, tc, testutil.WithSchema(), testutil.WithoutMigrations())
+	var exists bool
+	require.NoError(t, empty.DB.QueryRowContext(ctx, `SELECT to_regclass('orders') IS NOT NULL`).Scan(&exists))
+	assert.False(t, exists)
+}
+
+func TestTestInfra_SharedContainersInParallel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.SharedContainers(ctx, t)
+	require.NoError(t, err)
+
+	// Both tests write the same keys and rows before either reads them
+	var written sync.WaitGroup
+	written.Add(2)
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var once sync.Once
+			done := func() { once.Do(written.Done) }
+			// The other test isn't left waiting if this one fails first
+			defer done()
+
+			infra, _ := testutil.TestInfra(ctx, t, tc)
+			require.NoError(t, infra.Redis.Set(ctx, "owner", name, 0).Err())
+			require.NoError(t, testutil.Seed(ctx, infra.DB, testutil.Fixtures{
+				Orders: []testutil.OrderFixture{{OrderID: "order-" + name}},
+			}))
+			done()
+			written.Wait()
+
+			owner, err := infra.Redis.Get(ctx, "owner").Result()
+			require.NoError(t, err)
+			assert.Equal(t, name, owner, "no other test's Redis keys are seen")
+			var n int
+			require.NoError(t, infra.DB.QueryRowContext(ctx, `SELECT count(*) FROM orders`).Scan(&n))
+			assert.Equal(t, 1, n, "no other test's rows are seen")
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/network"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Ports the Toxiproxy container listens on: its API, and a proxy per
+// test container
+const (
+	toxiproxyAPIPort      nat.Port = "8474/tcp"
+	toxiproxyNATSPort     nat.Port = "8666/tcp"
+	toxiproxyPostgresPort nat.Port = "8667/tcp"
+	toxiproxyRedisPort    nat.Port = "8668/tcp"
+)
+
+// Toxiproxy routes connections to the test containers through a Toxiproxy
+// container, whose proxies can be slowed down, cut off or reset to test how
+// the service copes with an unreliable network. Containers not started have
+// no proxy.
+type Toxiproxy struct {
+	NATS     *Proxy
+	Postgres *Proxy
+	Redis    *Proxy
+}
+
+// Proxy is a Toxiproxy proxy in front of one container. Clients connect to
+// Addr instead of the container.
+type Proxy struct {
+	Name string
+	// Addr is the host:port clients connect to
+	Addr string
+
+	api string
+}
+
+// StartToxiproxy starts a Toxiproxy container on tc's network proxying its
+// containers, and terminates it when t finishes. It returns the proxies and
+// a copy of tc whose connection strings, and so TestInfra's connections, go
+// through them. TEST_TOXIPROXY_IMAGE overrides its image.
+func StartToxiproxy(ctx context.Context, t testing.TB, tc *TestContainers) (*Toxiproxy, *TestContainers, error) {
+	t.Helper()
+
+	container, err := testcontainers.Run(ctx, image("TEST_TOXIPROXY_IMAGE", DefaultToxiproxyImage),
+		testcontainers.WithExposedPorts(string(toxiproxyAPIPort), string(toxiproxyNATSPort), string(toxiproxyPostgresPort), string(toxiproxyRedisPort)),
+		testcontainers.WithWaitStrategy(wait.ForHTTP("/version").WithPort(toxiproxyAPIPort)),
+		network.WithNetwork([]string{"toxiproxy"}, tc.Network),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("starting Toxiproxy container: %w", err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(context.WithoutCancel(ctx)); err != nil {
+			t.Logf("failed to terminate Toxiproxy container: %v", err)
+		}
+	})
+	reportContainerLogs(t, map[string]testcontainers.Container{"toxiproxy": container})
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	endpoint := func(port nat.Port) (string, error) {
+		mapped, err := container.MappedPort(ctx, port)
+		if err != nil {
+			return "", err
+		}
+		return net.JoinHostPort(host, mapped.Port()), nil
+	}
+	api, err := endpoint(toxiproxyAPIPort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tp := &Toxiproxy{}
+	for _, p := range []struct {
+		proxy        **Proxy
+		service      Service
+		started      bool
+		port         nat.Port
+		upstreamPort string
+	}{
+		{&tp.NATS, ServiceNATS, tc.NATS != nil, toxiproxyNATSPort, "4222"},
+		{&tp.Postgres, ServicePostgres, tc.Postgres != nil, toxiproxyPostgresPort, "5432"},
+		{&tp.Redis, ServiceRedis, tc.Redis != nil, toxiproxyRedisPort, "6379"},
+	} {
+		if !p.started {
+			continue
+		}
+		addr, err := endpoint(p.port)
+		if err != nil {
+			return nil, nil, err
+		}
+		proxy := &Proxy{Name: string(p.service), Addr: addr, api: "http://" + api}
+		if err := proxy.call(ctx, http.MethodPost, "/proxies", map[string]any{
+			"name":     proxy.Name,
+			"listen":   "0.0.0.0:" + p.port.Port(),
+			"upstream": net.JoinHostPort(string(p.service), p.upstreamPort),
+			"enabled":  true,
+		}); err != nil {
+			return nil, nil, fmt.Errorf("creating %s proxy: %w", p.service, err)
+		}
+		*p.proxy = proxy
+	}
+
+	proxied := *tc
+	proxied.proxies = tp
+	return tp, &proxied, nil
+}
+
+// AddLatency delays the data the container sends by latency, give or take
+// jitter
+func (p *Proxy) AddLatency(ctx context.Context, latency, jitter time.Duration) error {
+	return p.addToxic(ctx, "latency", map[string]any{
+		"latency": latency.Milliseconds(),
+		"jitter":  jitter.Milliseconds(),
+	})
+}
+
+// ResetPeer resets connections through the proxy after timeout, with a TCP
+// RST rather than an orderly close; 0 resets them as soon as data arrives
+func (p *Proxy) ResetPeer(ctx context.Context, timeout time.Duration) error {
+	return p.addToxic(ctx, "reset_peer", map[string]any{"timeout": timeout.Milliseconds()})
+}
+
+// Partition cuts the container off: open connections are closed, and new
+// ones refused, until Heal
+func (p *Proxy) Partition(ctx context.Context) error {
+	return p.call(ctx, http.MethodPost, "/proxies/"+p.Name, map[string]any{"enabled": false})
+}
+
+// Heal ends a partition
+func (p *Proxy) Heal(ctx context.Context) error {
+	return p.call(ctx, http.MethodPost, "/proxies/"+p.Name, map[string]any{"enabled": true})
+}
+
+// RemoveToxics removes the latency and resets added to the proxy
+func (p *Proxy) RemoveToxics(ctx context.Context) error {
+	var toxics []struct {
+		Name string `json:"name"`
+	}
+	if err := p.get(ctx, "/proxies/"+p.Name+"/toxics", &toxics); err != nil {
+		return err
+	}
+	for _, toxic := range toxics {
+		if err := p.call(ctx, http.MethodDelete, "/proxies/"+p.Name+"/toxics/"+url.PathEscape(toxic.Name), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addToxic adds a downstream toxic of the given type, replacing one added
+// before
+func (p *Proxy) addToxic(ctx context.Context, toxicType string, attributes map[string]any) error {
+	path := "/proxies/" + p.Name + "/toxics"
+	// Toxics are named after their type, so one of each type can be set
+	if err := p.call(ctx, http.MethodDelete, path+"/"+toxicType, nil); err != nil && !isNotFound(err) {
+		return err
+	}
+	return p.call(ctx, http.MethodPost, path, map[string]any{
+		"name":       toxicType,
+		"type":       toxicType,
+		"stream":     "downstream",
+		"attributes": attributes,
+	})
+}
+
+// toxiproxyError is a failed Toxiproxy API call
+type toxiproxyError struct {
+	status int
+	body   string
+}
+
+func (e *toxiproxyError) Error() string {
+	return fmt.Sprintf("toxiproxy: %d %s", e.status, e.body)
+}
+
+func isNotFound(err error) bool {
+	var te *toxiproxyError
+	return errors.As(err, &te) && te.status == http.StatusNotFound
+}
+
+func (p *Proxy) get(ctx context.Context, path string, v any) error {
+	return p.do(ctx, http.MethodGet, path, nil, v)
+}
+
+func (p *Proxy) call(ctx context.Context, method, path string, body any) error {
+	return p.do(ctx, method, path, body, nil)
+}
+
+// do calls the Toxiproxy API, decoding its response into v when set
+func (p *Proxy) do(ctx context.Context, method, path string, body, v any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.api+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling toxiproxy: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return &toxiproxyError{status: resp.StatusCode, body: string(bytes.TrimSpace(data))}
+	}
+	if v != nil {
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package testutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestToxiproxy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	tc, err := testutil.StartContainers(ctx, t, nil)
+	require.NoError(t, err)
+	proxies, proxied, err := testutil.StartToxiproxy(ctx, t, tc)
+	require.NoError(t, err)
+	infra, _ := testutil.TestInfra(ctx, t, proxied)
+
+	// Latency
+	require.NoError(t, proxies.Redis.AddLatency(ctx, 300*time.Millisecond, 0))
+	start := time.Now()
+	require.NoError(t, infra.Redis.Ping(ctx).Err())
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+	require.NoError(t, proxies.Redis.RemoveToxics(ctx))
+	start = time.Now()
+	require.NoError(t, infra.Redis.Ping(ctx).Err())
+	assert.Less(t, time.Since(start), 300*time.Millisecond)
+
+	// Partitions
+	require.NoError(t, proxies.Postgres.Partition(ctx))
+	pingCtx, cancelPing := context.WithTimeout(ctx, time.Second)
+	assert.Error(t, infra.DB.PingContext(pingCtx))
+	cancelPing()
+	require.NoError(t, proxies.Postgres.Heal(ctx))
+	assert.Eventually(t, func() bool {
+		return infra.DB.PingContext(ctx) == nil
+	}, 10*time.Second, 100*time.Millisecond)
+
+	// Resets
+	require.NoError(t, proxies.NATS.ResetPeer(ctx, 100*time.Millisecond))
+	assert.Eventually(t, func() bool {
+		return !infra.NATS.IsConnected()
+	}, 10*time.Second, 50*time.Millisecond, "the connection is reset")
+	require.NoError(t, proxies.NATS.RemoveToxics(ctx))
+	assert.Eventually(t, infra.NATS.IsConnected, 30*time.Second, 100*time.Millisecond, "and reconnects")
+}
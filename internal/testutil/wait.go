@@ -0,0 +1,96 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Polling backs off from the first interval to the last, and gives up after
+// DefaultWaitTimeout unless the context has a deadline
+const (
+	DefaultWaitTimeout = 30 * time.Second
+
+	minPollInterval = 10 * time.Millisecond
+	maxPollInterval = 500 * time.Millisecond
+)
+
+// errNotYet is what conditions not holding yet report
+var errNotYet = errors.New("condition not met")
+
+// WaitForCondition calls cond until it returns true, backing off between
+// calls, rather than sleeping for a time that is too long on fast machines
+// and too short on slow CI ones. It returns ctx's error if cond doesn't hold
+// before ctx is done, or DefaultWaitTimeout if ctx has no deadline.
+func WaitForCondition(ctx context.Context, cond func() bool) error {
+	return poll(ctx, func(context.Context) error {
+		if !cond() {
+			return errNotYet
+		}
+		return nil
+	})
+}
+
+// WaitForReady waits until a GET of url answers with a 2xx status, e.g. the
+// service's /health/ready, under the same terms as WaitForCondition. The
+// error says why the last attempt failed.
+func WaitForReady(ctx context.Context, url string) error {
+	err := poll(ctx, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %s: %w", url, err)
+	}
+	return nil
+}
+
+// poll calls try until it succeeds, backing off between calls. Once ctx is
+// done it returns ctx's error, with try's last one.
+func poll(ctx context.Context, try func(context.Context) error) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultWaitTimeout)
+		defer cancel()
+	}
+
+	interval := minPollInterval
+	var last error
+	for {
+		err := try(ctx)
+		if err == nil {
+			return nil
+		}
+		// Attempts cut short by ctx say nothing new
+		if ctx.Err() == nil && !errors.Is(err, errNotYet) {
+			last = err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if last == nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("%w; last attempt: %w", ctx.Err(), last)
+		case <-timer.C:
+		}
+		interval = min(2*interval, maxPollInterval)
+	}
+}
@@ -0,0 +1,53 @@
+package testutil_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synapse/synapse/internal/testutil"
+)
+
+func TestWaitForCondition(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var calls atomic.Int32
+	require.NoError(t, testutil.WaitForCondition(ctx, func() bool { return calls.Add(1) == 3 }))
+	assert.Equal(t, int32(3), calls.Load())
+
+	short, cancelShort := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelShort()
+	err := testutil.WaitForCondition(short, func() bool { return false })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWaitForReady(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var ready atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	time.AfterFunc(50*time.Millisecond, func() { ready.Store(true) })
+	require.NoError(t, testutil.WaitForReady(ctx, srv.URL+"/health/ready"))
+
+	ready.Store(false)
+	short, cancelShort := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelShort()
+	err := testutil.WaitForReady(short, srv.URL+"/health/ready")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "status 503")
+}